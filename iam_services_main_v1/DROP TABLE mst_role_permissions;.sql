@@ -1,6 +0,0 @@
-
-
-
-
-TRUNCATE TABLE tnt_resources;
-TRUNCATE TABLE tnt_resources_metadata;
\ No newline at end of file
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/bulkimport"
+	"net/http"
+	"os"
+	"time"
+)
+
+// bulkimport imports users, groups and bindings from CSV files into a tenant
+// of a running service.
+func main() {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	err := bulkimport.RunCommand(context.Background(), client, os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, bulkimport.ErrUsage) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bulkimport:", err)
+		os.Exit(1)
+	}
+}
@@ -1,13 +1,26 @@
 package main
 
 import (
+	"context"
 	config "iam_services_main_v1/config"
 	"iam_services_main_v1/gql"
 	"iam_services_main_v1/gql/generated"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/authz"
+	"iam_services_main_v1/internal/breakglass"
+	"iam_services_main_v1/internal/bulkimport"
+	"iam_services_main_v1/internal/idempotency"
+	"iam_services_main_v1/internal/invitations"
 	"iam_services_main_v1/internal/middlewares"
+	"iam_services_main_v1/internal/migrations"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/scim"
+	"iam_services_main_v1/internal/siem"
 	"iam_services_main_v1/pkg/logger"
 	"log"
+	"os"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/playground"
@@ -29,12 +42,88 @@ func main() {
 	// Initialize database connection
 	db := config.InitDB()
 
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// "server migrate up|down|status" manages the schema instead of serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrations.RunCommand(context.Background(), migrator, os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// "server scim-token issue|revoke|list" manages the tokens of SCIM clients
+	scimTokens := scim.NewTokenStore(db)
+	if len(os.Args) > 1 && os.Args[1] == "scim-token" {
+		if err := scim.RunCommand(context.Background(), scimTokens, repository.NewGormStore(db), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// The schema is migrated explicitly, never at startup
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("database schema is %d migration(s) behind; run \"server migrate up\"", len(pending))
+	}
+
 	//Initialize permit
 	pc := permit.NewPermitClient()
 
+	// Mail invitations through the mailer configured in the environment
+	invitationSettings, err := invitations.SettingsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Initialize resolver and GraphQL server
-	resolver := &gql.Resolver{DB: db, PC: pc}
-	gqlServer := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: resolver}))
+	resolver := &gql.Resolver{DB: db, PC: pc, Invitations: invitationSettings}
+	gqlServer := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+		Resolvers: resolver,
+		Directives: generated.DirectiveRoot{
+			HasPermission: authz.HasPermission(&authz.BindingPolicyEngine{DB: db}),
+		},
+	}))
+
+	// Export audit events to the SIEM sinks configured in the environment
+	auditExport, err := siem.NewDispatcherFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := auditExport.Close(ctx); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	// Remove lapsed break-glass bindings from Permit; they stop counting at
+	// their expiry even while the sweeper is down. Expiries are exported to
+	// the SIEM like the grants themselves
+	sweepCtx, stopSweep := context.WithCancel(context.Background())
+	defer stopSweep()
+	go breakglass.RunExpirySweeper(sweepCtx, db, pc, time.Minute, auditExport)
+
+	// Replay the stored result of mutations retried with the same idempotency
+	// key. It runs outside the audit log so that replays are not recorded
+	// as new changes
+	idempotencyTTL, err := time.ParseDuration(config.GetEnvDefault("IDEMPOTENCY_KEY_TTL", idempotency.DefaultTTL.String()))
+	if err != nil {
+		log.Fatal(err)
+	}
+	idempotencyKeys := idempotency.NewStore(db, idempotencyTTL)
+	go idempotency.RunPurger(sweepCtx, idempotencyKeys, time.Hour)
+	gqlServer.AroundFields(idempotency.FieldMiddleware(idempotencyKeys))
+
+	// Record every mutation in the audit log
+	gqlServer.AroundFields(audit.FieldMiddleware(db, auditExport))
 
 	// Set custom error formatting globally
 	// gqlServer.SetErrorPresenter(func(ctx context.Context, err error) *gqlerror.Error {
@@ -45,15 +134,29 @@ func main() {
 
 	r.GET("/playground", gin.WrapH(playground.Handler("GraphQL Playground", "/graphql")))
 
-	r.Use(middlewares.AuthMiddleware())
+	// SCIM clients authenticate with a token of their tenant instead of the
+	// headers of the GraphQL API
+	store := repository.NewGormStore(db)
+	scim.Register(r.Group("/scim/v2",
+		middlewares.RequestLogger(),
+		scim.Authenticate(scimTokens, store),
+		middlewares.GinContextToContextMiddleware(),
+	), &scim.Handler{Store: store, PC: pc, DB: db, Publishers: []audit.Publisher{auditExport}})
+
+	r.Use(middlewares.RequestLogger())
+	r.Use(middlewares.AuthMiddleware(middlewares.PermissionImpersonationAuthorizer(db)))
 	r.Use(middlewares.GinContextToContextMiddleware())
-	// r.Use(middlewares.RequestLogger())
 
 	r.POST("/graphql", func(ctx *gin.Context) {
 		ctx.Writer.Header().Set("Content-Type", "application/json")
 		gin.WrapH(gqlServer)(ctx)
 	})
 
+	// CSV imports are uploaded outside GraphQL so that large files are
+	// streamed; their progress is queried with importJob
+	imports := &bulkimport.Handler{DB: db, Publishers: []audit.Publisher{auditExport}, Store: store, PC: pc, Policy: &authz.BindingPolicyEngine{DB: db}}
+	r.POST("/imports", imports.Upload)
+
 	// Start server
 	if err := r.Run(":8080"); err != nil {
 		//logger.AddContext(err).Fatal("Server failed to start")
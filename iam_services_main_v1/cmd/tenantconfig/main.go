@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/tenantconfig"
+	"net/http"
+	"os"
+	"time"
+)
+
+// tenantconfig plans and applies tenant configuration documents against a
+// running service, for use from deployment pipelines.
+func main() {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	err := tenantconfig.RunCommand(context.Background(), client, os.Args[1:], os.Stdin, os.Stdout)
+	if errors.Is(err, tenantconfig.ErrUsage) {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tenantconfig:", err)
+		os.Exit(1)
+	}
+}
@@ -25,3 +25,11 @@ func GetEnv(key string) string {
 	}
 	return value
 }
+
+// GetEnvDefault returns an environment variable, or fallback if it is not set
+func GetEnvDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -1,8 +1,18 @@
 package config
 
+// Supported DB_DRIVER values
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
 // Database configuration variables
 const (
 	DBLoc       = "Local"
 	DBParseTime = "True"
 	DBCharset   = "utf8mb4"
+
+	DBSSLMode             = "disable"
+	DBSQLiteBusyTimeoutMs = 5000
 )
@@ -2,29 +2,34 @@ package config
 
 import (
 	"fmt"
-	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
 
 	// Import your custom logger
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
 func InitDB() *gorm.DB {
-	dsn := GetDSN()
+	dialector, err := GetDialector()
+	if err != nil {
+		panic(err)
+	}
 	// Initialize the custom GORM logger
 	// customLogger := gormlogger.NewGORMLogger()
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		// Logger: customLogger,
 	})
 	if err != nil {
 		panic("failed to connect database")
 	}
 
-	err = db.AutoMigrate(&dto.TenantResource{}, &dto.TenantMetadata{}, &dto.TNTRole{}, &dto.TNTRolePermission{}, &dto.MstRole{}, &dto.MstPermission{}, &dto.MstRolePermission{})
-	if err != nil {
+	// Isolate tenant-scoped tables per request tenant
+	if err := db.Use(tenancy.Plugin{}); err != nil {
 		panic(err)
 	}
 
@@ -33,6 +38,20 @@ func InitDB() *gorm.DB {
 	return db
 }
 
+// GetDialector returns the GORM dialector for the DB_DRIVER setting.
+func GetDialector() (gorm.Dialector, error) {
+	switch driver := GetEnvDefault("DB_DRIVER", DriverMySQL); driver {
+	case DriverMySQL:
+		return mysql.Open(GetDSN()), nil
+	case DriverPostgres:
+		return postgres.Open(GetPostgresDSN()), nil
+	case DriverSQLite:
+		return sqlite.Open(GetSQLiteDSN()), nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER %q: use %s, %s or %s", driver, DriverMySQL, DriverPostgres, DriverSQLite)
+	}
+}
+
 func GetDSN() string {
 	return fmt.Sprintf(
 		"%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=%s&loc=%s",
@@ -40,6 +59,19 @@ func GetDSN() string {
 	)
 }
 
+func GetPostgresDSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		GetEnv("DB_HOST"), GetEnv("DB_PORT"), GetEnv("DB_USERNAME"), GetEnv("DB_PASSWORD"), GetEnv("DB_NAME"), GetEnvDefault("DB_SSLMODE", DBSSLMode),
+	)
+}
+
+// GetSQLiteDSN opens the database file named by DB_NAME. Writers wait for
+// each other instead of failing with SQLITE_BUSY.
+func GetSQLiteDSN() string {
+	return fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)", GetEnv("DB_NAME"), DBSQLiteBusyTimeoutMs)
+}
+
 func GetDB() *gorm.DB {
 	return DB
 }
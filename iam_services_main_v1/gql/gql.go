@@ -3,9 +3,24 @@ package gql
 import (
 	"iam_services_main_v1/gormlogger"
 	"iam_services_main_v1/gql/generated"
+	"iam_services_main_v1/internal/accessreviews"
 	"iam_services_main_v1/internal/accounts"
+	"iam_services_main_v1/internal/approvals"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/authz"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/breakglass"
+	"iam_services_main_v1/internal/bulkimport"
+	"iam_services_main_v1/internal/invitations"
+	"iam_services_main_v1/internal/labels"
+	"iam_services_main_v1/internal/permissions"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/resource"
+	"iam_services_main_v1/internal/resourcetypes"
 	"iam_services_main_v1/internal/roles"
+	"iam_services_main_v1/internal/tenantarchive"
+	"iam_services_main_v1/internal/tenantconfig"
 	"iam_services_main_v1/internal/tenants"
 
 	"gorm.io/gorm"
@@ -16,16 +31,25 @@ type Resolver struct {
 	DB     *gorm.DB
 	PC     *permit.PermitClient
 	Logger *gormlogger.GORMLogger
+	// Invitations configures inviteUser; nil disables it.
+	Invitations *invitations.Settings
 }
 
 // Query returns the root query resolvers, delegating to feature-based resolvers
 func (r *Resolver) Query() generated.QueryResolver {
 	return &queryResolver{
-		TenantQueryResolver: &tenants.TenantQueryResolver{DB: r.DB, PC: r.PC},
+		TenantQueryResolver:       &tenants.TenantQueryResolver{Store: repository.NewGormStore(r.DB), PC: r.PC},
+		AuditQueryResolver:        &audit.AuditQueryResolver{DB: r.DB},
+		AccessReviewQueryResolver: &accessreviews.AccessReviewQueryResolver{DB: r.DB},
+		ApprovalQueryResolver:     &approvals.ApprovalQueryResolver{DB: r.DB},
+		BreakGlassQueryResolver:   &breakglass.BreakGlassQueryResolver{DB: r.DB},
+		ImportJobQueryResolver:    &bulkimport.ImportJobQueryResolver{DB: r.DB},
 		// AccountQueryResolver:                &accounts.AccountQueryResolver{DB: r.DB, PC: r.PC},
 		// ClientOrganizationUnitQueryResolver: &clientorganizationunits.ClientOrganizationUnitQueryResolver{DB: r.DB},
-		RoleQueryResolver: &roles.RoleQueryResolver{DB: r.DB},
-		// PermissionQueryResolver:             &permissions.PermissionQueryResolver{DB: r.DB},
+		RoleQueryResolver:          &roles.RoleQueryResolver{Store: repository.NewGormStore(r.DB)},
+		ResourceTypeQueryResolver:  &resourcetypes.ResourceTypeQueryResolver{DB: r.DB},
+		PermissionQueryResolver:    &permissions.PermissionQueryResolver{DB: r.DB, Permit: r.PC},
+		TenantArchiveQueryResolver: &tenantarchive.TenantArchiveQueryResolver{Store: repository.NewGormStore(r.DB)},
 		// BindingsQueryResolver:               &bindings.BindingsQueryResolver{DB: r.DB},
 		// ResourceQueryResolver:               &resources.ResourceQueryResolver{DB: r.DB},
 		// GroupQueryResolver:                  &groups.GroupQueryResolver{DB: r.DB},
@@ -38,12 +62,22 @@ func (r *Resolver) Query() generated.QueryResolver {
 func (r *Resolver) Mutation() generated.MutationResolver {
 	return &mutationResolver{
 
-		TenantMutationResolver: &tenants.TenantMutationResolver{DB: r.DB, PermitClient: r.PC},
+		TenantMutationResolver:       &tenants.TenantMutationResolver{Store: repository.NewGormStore(r.DB), PermitClient: r.PC},
+		AccessReviewMutationResolver: &accessreviews.AccessReviewMutationResolver{DB: r.DB, PC: r.PC},
+		ApprovalMutationResolver:     &approvals.ApprovalMutationResolver{DB: r.DB, PC: r.PC},
+		BreakGlassMutationResolver:   &breakglass.BreakGlassMutationResolver{DB: r.DB, PC: r.PC},
+		InvitationMutationResolver:   &invitations.InvitationMutationResolver{DB: r.DB, PC: r.PC, Settings: r.Invitations},
 		// AccountMutationResolver:                &accounts.AccountMutationResolver{DB: r.DB, PC: r.PC},
 		// ClientOrganizationUnitMutationResolver: &clientorganizationunits.ClientOrganizationUnitMutationResolver{r.DB},
-		RoleMutationResolver: &roles.RoleMutationResolver{DB: r.DB},
-		// PermissionMutationResolver:             &permissions.PermissionMutationResolver{DB: r.DB},
+		RoleMutationResolver:         &roles.RoleMutationResolver{Store: repository.NewGormStore(r.DB), PC: r.PC},
+		ResourceTypeMutationResolver: &resourcetypes.ResourceTypeMutationResolver{DB: r.DB, PC: r.PC},
+		LabelMutationResolver:        &labels.LabelMutationResolver{DB: r.DB, PC: r.PC},
+		PermissionMutationResolver:   &permissions.PermissionMutationResolver{DB: r.DB, Permit: r.PC},
 		// BindingsMutationResolver:               &bindings.BindingsMutationResolver{DB: r.DB},
+		AssignmentMutationResolver:    &bindings.AssignmentMutationResolver{Store: repository.NewGormStore(r.DB), PC: r.PC, Policy: &authz.BindingPolicyEngine{DB: r.DB}},
+		ResourceMutationResolver:      &resource.ResourceMutationResolver{Store: repository.NewGormStore(r.DB), PC: r.PC},
+		TenantArchiveMutationResolver: &tenantarchive.TenantArchiveMutationResolver{Store: repository.NewGormStore(r.DB), PC: r.PC},
+		TenantConfigMutationResolver:  &tenantconfig.TenantConfigMutationResolver{Store: repository.NewGormStore(r.DB), PC: r.PC},
 		// RootMutationResolver:                   &root.RootMutationResolver{DB: r.DB},
 	}
 }
@@ -53,15 +87,27 @@ func (r *Resolver) Account() generated.AccountResolver {
 	return &accounts.AccountFieldResolver{DB: r.DB}
 }
 
+// Role resolves fields for the Role type
+func (r *Resolver) Role() generated.RoleResolver {
+	return &roles.RoleFieldResolver{Store: repository.NewGormStore(r.DB)}
+}
+
 type AccountResolver struct{ *Resolver }
 
 // Root resolvers for Query and Mutation
 type queryResolver struct {
 	*tenants.TenantQueryResolver
+	*audit.AuditQueryResolver
+	*accessreviews.AccessReviewQueryResolver
+	*approvals.ApprovalQueryResolver
+	*breakglass.BreakGlassQueryResolver
+	*bulkimport.ImportJobQueryResolver
 	// *accounts.AccountQueryResolver
 	*roles.RoleQueryResolver
+	*resourcetypes.ResourceTypeQueryResolver
 	// *clientorganizationunits.ClientOrganizationUnitQueryResolver
-	// *permissions.PermissionQueryResolver
+	*permissions.PermissionQueryResolver
+	*tenantarchive.TenantArchiveQueryResolver
 	// *bindings.BindingsQueryResolver
 	// *resources.ResourceQueryResolver
 	// *groups.GroupQueryResolver
@@ -71,10 +117,20 @@ type queryResolver struct {
 
 type mutationResolver struct {
 	*tenants.TenantMutationResolver
+	*accessreviews.AccessReviewMutationResolver
+	*approvals.ApprovalMutationResolver
+	*breakglass.BreakGlassMutationResolver
+	*invitations.InvitationMutationResolver
 	// *accounts.AccountMutationResolver
 	// *clientorganizationunits.ClientOrganizationUnitMutationResolver
 	*roles.RoleMutationResolver
-	// *permissions.PermissionMutationResolver
+	*resourcetypes.ResourceTypeMutationResolver
+	*labels.LabelMutationResolver
+	*permissions.PermissionMutationResolver
 	// *bindings.BindingsMutationResolver
+	*bindings.AssignmentMutationResolver
+	*resource.ResourceMutationResolver
+	*tenantarchive.TenantArchiveMutationResolver
+	*tenantconfig.TenantConfigMutationResolver
 	// *root.RootMutationResolver
 }
@@ -70,12 +70,18 @@ type Principal interface {
 // Represents a Resource entity
 type Resource interface {
 	IsResource()
+	// Custom attributes of the resource
+	GetAttributes() *string
 	// Timestamp of creation
 	GetCreatedAt() string
 	// Identifier of the user who created the record
 	GetCreatedBy() uuid.UUID
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	GetEtag() string
 	// Unique identifier of the resource
 	GetID() uuid.UUID
+	// Labels attached to the resource
+	GetLabels() []*Label
 	// Name of the resource
 	GetName() string
 	// Timestamp of last update
@@ -93,8 +99,152 @@ type Response interface {
 	GetMessage() string
 }
 
+// Represents a request for a role on a scope
+type AccessRequest struct {
+	// Binding created when the request was approved
+	BindingID *uuid.UUID `json:"bindingId,omitempty"`
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Timestamp when the request was approved or denied
+	DecidedAt *string `json:"decidedAt,omitempty"`
+	// Decisions taken by approvers so far
+	Decisions []*AccessRequestDecision `json:"decisions"`
+	// Requested access duration in seconds, empty for permanent access
+	Duration *int `json:"duration,omitempty"`
+	// Unique identifier of the request
+	ID uuid.UUID `json:"id"`
+	// Reason given by the requester
+	Justification string `json:"justification"`
+	// Identifier of the requesting user
+	RequesterID uuid.UUID `json:"requesterId"`
+	// Requested role
+	RoleID uuid.UUID `json:"roleId"`
+	// Resource the role is requested on
+	ScopeID uuid.UUID `json:"scopeId"`
+	// Status of the request
+	Status AccessRequestStatus `json:"status"`
+	// Tenant the request belongs to
+	TenantID *uuid.UUID `json:"tenantId,omitempty"`
+}
+
+func (AccessRequest) IsData() {}
+
+// Represents an approver's decision on an access request
+type AccessRequestDecision struct {
+	// Identifier of the approver
+	ApproverID uuid.UUID `json:"approverId"`
+	// Identifier of the user who recorded the decision, who differs from the approver when impersonating them
+	ActorID uuid.UUID `json:"actorId"`
+	// Approver's comment
+	Comment *string `json:"comment,omitempty"`
+	// Timestamp of the decision
+	CreatedAt string `json:"createdAt"`
+	// APPROVED or DENIED
+	Decision string `json:"decision"`
+}
+
+// Defines input fields for approving or denying an access request
+type AccessRequestDecisionInput struct {
+	// Comment on the decision
+	Comment *string `json:"comment,omitempty"`
+	// Unique identifier of the request
+	ID uuid.UUID `json:"id"`
+}
+
+// Represents a campaign certifying who has which role within a scope
+type AccessReviewCampaign struct {
+	// Timestamp when the campaign was closed
+	ClosedAt *string `json:"closedAt,omitempty"`
+	// Identifier of the user who closed the campaign
+	ClosedBy *uuid.UUID `json:"closedBy,omitempty"`
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who created the record
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Date by which reviewers are expected to decide
+	DueAt *string `json:"dueAt,omitempty"`
+	// Unique identifier of the campaign
+	ID uuid.UUID `json:"id"`
+	// Review items of the campaign
+	Items []*AccessReviewItem `json:"items"`
+	// Name of the campaign
+	Name string `json:"name"`
+	// Unique identifier of the reviewed scope
+	ScopeID uuid.UUID `json:"scopeId"`
+	// Type of the reviewed scope
+	ScopeType AccessReviewScopeType `json:"scopeType"`
+	// Status of the campaign
+	Status AccessReviewStatus `json:"status"`
+	// Tenant the campaign belongs to
+	TenantID *uuid.UUID `json:"tenantId,omitempty"`
+}
+
+func (AccessReviewCampaign) IsData() {}
+
+// Defines input fields for deciding on an access review item
+type AccessReviewDecisionInput struct {
+	// Justification for the decision
+	Comment *string `json:"comment,omitempty"`
+	// Unique identifier of the item
+	ID uuid.UUID `json:"id"`
+}
+
+// Represents a binding under review
+type AccessReviewItem struct {
+	// Indicates if the binding was revoked because nobody reviewed it before the campaign closed
+	AutoRevoked bool `json:"autoRevoked"`
+	// Unique identifier of the reviewed binding
+	BindingID uuid.UUID `json:"bindingId"`
+	// Name of the reviewed binding
+	BindingName *string `json:"bindingName,omitempty"`
+	// Unique identifier of the campaign
+	CampaignID uuid.UUID `json:"campaignId"`
+	// Reviewer's justification for the decision
+	Comment *string `json:"comment,omitempty"`
+	// Timestamp of the decision
+	DecidedAt *string `json:"decidedAt,omitempty"`
+	// Identifier of the user who decided, empty for automatic revocations
+	DecidedBy *uuid.UUID `json:"decidedBy,omitempty"`
+	// Decision taken on the binding
+	Decision AccessReviewDecision `json:"decision"`
+	// Unique identifier of the item
+	ID uuid.UUID `json:"id"`
+	// Principal holding the role
+	PrincipalID uuid.UUID `json:"principalId"`
+	// Identifier of the user assigned to review the item
+	ReviewerID uuid.UUID `json:"reviewerId"`
+	// Role granted by the binding
+	RoleID uuid.UUID `json:"roleId"`
+	// Tenant of the binding
+	TenantID *uuid.UUID `json:"tenantId,omitempty"`
+}
+
+func (AccessReviewItem) IsData() {}
+
+// Represents the outcome of an access review campaign
+type AccessReviewReport struct {
+	// Number of bindings certified
+	Approved int `json:"approved"`
+	// Number of bindings revoked automatically at close
+	AutoRevoked int `json:"autoRevoked"`
+	// The reported campaign, including its items
+	Campaign *AccessReviewCampaign `json:"campaign"`
+	// Percentage of items decided by a reviewer
+	CompletionRate float64 `json:"completionRate"`
+	// Number of bindings awaiting a decision
+	Pending int `json:"pending"`
+	// Number of bindings revoked, including automatic revocations
+	Revoked int `json:"revoked"`
+	// Total number of items
+	Total int `json:"total"`
+}
+
+func (AccessReviewReport) IsData() {}
+
 // Represents an Account entity
 type Account struct {
+	// Custom attributes of the resource
+	Attributes *string `json:"attributes,omitempty"`
 	// Billing Info entity
 	BillingInfo *BillingInfo `json:"billingInfo,omitempty"`
 	// Timestamp of creation
@@ -103,8 +253,12 @@ type Account struct {
 	CreatedBy uuid.UUID `json:"createdBy"`
 	// Description of the account
 	Description *string `json:"description,omitempty"`
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	Etag string `json:"etag"`
 	// Unique identifier of the account
 	ID uuid.UUID `json:"id"`
+	// Labels attached to the resource
+	Labels []*Label `json:"labels"`
 	// Name of the account
 	Name string `json:"name"`
 	// Parent organization
@@ -145,12 +299,30 @@ func (this Account) GetUpdatedBy() uuid.UUID { return this.UpdatedBy }
 
 func (Account) IsResource() {}
 
+// Custom attributes of the resource
+func (this Account) GetAttributes() *string { return this.Attributes }
+
 // Timestamp of creation
 
 // Identifier of the user who created the record
 
+// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+func (this Account) GetEtag() string { return this.Etag }
+
 // Unique identifier of the resource
 
+// Labels attached to the resource
+func (this Account) GetLabels() []*Label {
+	if this.Labels == nil {
+		return nil
+	}
+	interfaceSlice := make([]*Label, 0, len(this.Labels))
+	for _, concrete := range this.Labels {
+		interfaceSlice = append(interfaceSlice, concrete)
+	}
+	return interfaceSlice
+}
+
 // Name of the resource
 
 // Timestamp of last update
@@ -185,6 +357,110 @@ type AddressInput struct {
 	ZipCode *string `json:"zipCode,omitempty"`
 }
 
+// Represents the approvers required to grant a role
+type ApprovalPolicy struct {
+	// Users allowed to approve requests
+	ApproverIds []uuid.UUID `json:"approverIds"`
+	// Role whose holders on the requested scope may approve requests
+	ApproverRoleID *uuid.UUID `json:"approverRoleId,omitempty"`
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who created the record
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Unique identifier of the policy
+	ID uuid.UUID `json:"id"`
+	// Longest access duration in seconds that may be granted, empty for no limit
+	MaxDuration *int `json:"maxDuration,omitempty"`
+	// Number of distinct approvals needed
+	RequiredApprovals int `json:"requiredApprovals"`
+	// Role the policy applies to
+	RoleID uuid.UUID `json:"roleId"`
+	// Timestamp of last update
+	UpdatedAt string `json:"updatedAt"`
+	// Identifier of the user who last updated the record
+	UpdatedBy uuid.UUID `json:"updatedBy"`
+}
+
+func (ApprovalPolicy) IsData() {}
+
+// Represents the result of verifying the audit hash chain
+type AuditChainVerification struct {
+	// Sequence of the first event whose hash does not match, if any
+	BrokenAtSequence *int `json:"brokenAtSequence,omitempty"`
+	// Number of events checked
+	CheckedEvents int `json:"checkedEvents"`
+	// Indicates if the chain is intact
+	Valid bool `json:"valid"`
+}
+
+func (AuditChainVerification) IsData() {}
+
+// Represents an immutable entry of the audit log
+type AuditEvent struct {
+	// Identifier of the user who performed the operation
+	ActorID *string `json:"actorId,omitempty"`
+	// Snapshot of the target resource after the operation
+	After *string `json:"after,omitempty"`
+	// Snapshot of the target resource before the operation
+	Before *string `json:"before,omitempty"`
+	// IP address of the client that issued the request
+	ClientIP *string `json:"clientIp,omitempty"`
+	// Error message reported by a failed operation
+	ErrorMessage *string `json:"errorMessage,omitempty"`
+	// Hash of this event, covering its content and the previous hash
+	Hash string `json:"hash"`
+	// Unique identifier of the event
+	ID uuid.UUID `json:"id"`
+	// Identifier of the user the actor was impersonating
+	ImpersonatedUserID *string `json:"impersonatedUserId,omitempty"`
+	// Timestamp of the operation
+	OccurredAt string `json:"occurredAt"`
+	// Name of the mutation that was executed
+	Operation string `json:"operation"`
+	// Outcome of the operation
+	Outcome AuditOutcome `json:"outcome"`
+	// Hash of the preceding event in the chain
+	PrevHash string `json:"prevHash"`
+	// Identifier of the request that triggered the operation
+	RequestID *string `json:"requestId,omitempty"`
+	// Position of the event in the chain
+	Sequence int `json:"sequence"`
+	// Identifier of the resource the operation targeted
+	TargetResourceID *string `json:"targetResourceId,omitempty"`
+	// Tenant in whose context the operation was executed
+	TenantID *string `json:"tenantId,omitempty"`
+}
+
+// Defines filters for querying audit events
+type AuditEventFilter struct {
+	// Only return events performed by this user
+	ActorID *uuid.UUID `json:"actorId,omitempty"`
+	// Only return events that occurred at or after this time
+	From *string `json:"from,omitempty"`
+	// Only return events of this mutation
+	Operation *string `json:"operation,omitempty"`
+	// Only return events with this outcome
+	Outcome *AuditOutcome `json:"outcome,omitempty"`
+	// Only return events targeting this resource
+	TargetResourceID *uuid.UUID `json:"targetResourceId,omitempty"`
+	// Only return events executed in this tenant
+	TenantID *uuid.UUID `json:"tenantId,omitempty"`
+	// Only return events that occurred before this time
+	To *string `json:"to,omitempty"`
+}
+
+// Represents a page of audit events, newest first
+type AuditEventPage struct {
+	// Cursor of the last event in the page
+	EndCursor *string `json:"endCursor,omitempty"`
+	// Audit events in the page
+	Events []*AuditEvent `json:"events"`
+	// Indicates if older events are available after endCursor
+	HasNextPage bool `json:"hasNextPage"`
+}
+
+func (AuditEventPage) IsData() {}
+
 // Represents a billing address entity associated to account
 type BillingAddress struct {
 	// Name of the city associated to billing address
@@ -239,16 +515,72 @@ type Binding struct {
 
 func (Binding) IsData() {}
 
+// Represents emergency access granted to a registered break-glass principal
+type BreakGlassGrant struct {
+	// Binding created for the emergency access
+	BindingID uuid.UUID `json:"bindingId"`
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Timestamp after which the access is no longer honoured
+	ExpiresAt string `json:"expiresAt"`
+	// Unique identifier of the grant
+	ID uuid.UUID `json:"id"`
+	// Principal that broke glass
+	PrincipalID uuid.UUID `json:"principalId"`
+	// Reason given for the emergency access
+	Reason string `json:"reason"`
+	// Timestamp when the binding was removed
+	RevokedAt *string `json:"revokedAt,omitempty"`
+	// Role granted
+	RoleID uuid.UUID `json:"roleId"`
+	// Root or tenant resource the role was granted on
+	ScopeID uuid.UUID `json:"scopeId"`
+}
+
+func (BreakGlassGrant) IsData() {}
+
+// Input for breaking glass
+type BreakGlassInput struct {
+	// Reason for the emergency access
+	Reason string `json:"reason"`
+	// Root or tenant resource to gain access on
+	ScopeID uuid.UUID `json:"scopeId"`
+}
+
+// Represents a principal allowed to break glass on a scope
+type BreakGlassPrincipal struct {
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who registered the principal
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Unique identifier of the registration
+	ID uuid.UUID `json:"id"`
+	// Registered principal
+	PrincipalID uuid.UUID `json:"principalId"`
+	// Role granted when the principal breaks glass
+	RoleID uuid.UUID `json:"roleId"`
+	// Root or tenant resource the principal may break glass on
+	ScopeID uuid.UUID `json:"scopeId"`
+}
+
+func (BreakGlassPrincipal) IsData() {}
+
 // Represents a Client Organization Unit entity
 type ClientOrganizationUnit struct {
+	// Custom attributes of the resource
+	Attributes *string `json:"attributes,omitempty"`
 	// Timestamp of creation
 	CreatedAt string `json:"createdAt"`
 	// Identifier of the user who created the record
 	CreatedBy uuid.UUID `json:"createdBy"`
 	// Description of the client organization unit
 	Description *string `json:"description,omitempty"`
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	Etag string `json:"etag"`
 	// Unique identifier of the client organization unit
 	ID uuid.UUID `json:"id"`
+	// Labels attached to the resource
+	Labels []*Label `json:"labels"`
 	// Name of the client organization unit
 	Name string `json:"name"`
 	// Parent organization
@@ -291,18 +623,42 @@ func (this ClientOrganizationUnit) GetUpdatedBy() uuid.UUID { return this.Update
 
 func (ClientOrganizationUnit) IsResource() {}
 
+// Custom attributes of the resource
+func (this ClientOrganizationUnit) GetAttributes() *string { return this.Attributes }
+
 // Timestamp of creation
 
 // Identifier of the user who created the record
 
+// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+func (this ClientOrganizationUnit) GetEtag() string { return this.Etag }
+
 // Unique identifier of the resource
 
+// Labels attached to the resource
+func (this ClientOrganizationUnit) GetLabels() []*Label {
+	if this.Labels == nil {
+		return nil
+	}
+	interfaceSlice := make([]*Label, 0, len(this.Labels))
+	for _, concrete := range this.Labels {
+		interfaceSlice = append(interfaceSlice, concrete)
+	}
+	return interfaceSlice
+}
+
 // Name of the resource
 
 // Timestamp of last update
 
 // Identifier of the user who last updated the record
 
+// Defines input fields for closing an access review campaign
+type CloseAccessReviewCampaignInput struct {
+	// Unique identifier of the campaign
+	ID uuid.UUID `json:"id"`
+}
+
 // Represents contact information
 type ContactInfo struct {
 	// Address of the contact
@@ -323,6 +679,20 @@ type ContactInfoInput struct {
 	PhoneNumber *string `json:"phoneNumber,omitempty"`
 }
 
+// Defines input fields for creating an access review campaign
+type CreateAccessReviewCampaignInput struct {
+	// Date by which reviewers are expected to decide
+	DueAt *string `json:"dueAt,omitempty"`
+	// Name of the campaign
+	Name string `json:"name"`
+	// Users reviewing the bindings; items are spread across them, never assigning a reviewer their own access when avoidable
+	ReviewerIds []uuid.UUID `json:"reviewerIds"`
+	// Unique identifier of the scope to review
+	ScopeID uuid.UUID `json:"scopeId"`
+	// Type of the scope to review
+	ScopeType AccessReviewScopeType `json:"scopeType"`
+}
+
 // Defines input fields for creating an account
 type CreateAccountInput struct {
 	// Scope of billing info
@@ -409,6 +779,8 @@ type CreatePermissionInput struct {
 type CreateRoleInput struct {
 	// Assignable scope reference ID
 	AssignableScopeRef uuid.UUID `json:"assignableScopeRef"`
+	// Client-chosen key making the mutation idempotent, like the Idempotency-Key header. A retry with the same key and input returns the first result
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
 	// Description of the role
 	Description *string `json:"description,omitempty"`
 	// Unique identifier of the role
@@ -433,6 +805,10 @@ type CreateRootInput struct {
 
 // Defines input fields for creating a tenant
 type CreateTenantInput struct {
+	// Custom attributes validated against the resource type schema
+	Attributes *string `json:"attributes,omitempty"`
+	// Client-chosen key making the mutation idempotent, like the Idempotency-Key header. A retry with the same key and input returns the first result
+	ClientMutationID *string `json:"clientMutationId,omitempty"`
 	// Contact information of the tenant
 	ContactInfo *ContactInfoInput `json:"contactInfo,omitempty"`
 	// Description of the tenant
@@ -447,12 +823,26 @@ type CreateTenantInput struct {
 
 // Defines input fields for deleting a resource
 type DeleteInput struct {
+	// Etag the resource must still have. The deletion fails with a CONFLICT error when the resource was modified since. Ignored for records that are not resources
+	ExpectedEtag *string `json:"expectedEtag,omitempty"`
 	// Unique identifier of the resource
 	ID uuid.UUID `json:"id"`
 }
 
+// Represents a change of a single field between two revisions
+type FieldChange struct {
+	// Name of the changed field
+	Field string `json:"field"`
+	// Value in the older revision
+	From *string `json:"from,omitempty"`
+	// Value in the newer revision
+	To *string `json:"to,omitempty"`
+}
+
 // Represents a Group entity
 type Group struct {
+	// Custom attributes of the resource
+	Attributes *string `json:"attributes,omitempty"`
 	// Timestamp of creation
 	CreatedAt string `json:"createdAt"`
 	// Identifier of the user who created the record
@@ -461,8 +851,12 @@ type Group struct {
 	Description *string `json:"description,omitempty"`
 	// Email of the group
 	Email string `json:"email"`
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	Etag string `json:"etag"`
 	// Unique identifier of the group
 	ID uuid.UUID `json:"id"`
+	// Labels attached to the resource
+	Labels []*Label `json:"labels"`
 	// Members of the group
 	Members []*User `json:"members"`
 	// Name of the group
@@ -493,14 +887,32 @@ func (this Group) GetTenant() *Tenant { return this.Tenant }
 
 func (Group) IsResource() {}
 
+// Custom attributes of the resource
+func (this Group) GetAttributes() *string { return this.Attributes }
+
 // Timestamp of creation
 func (this Group) GetCreatedAt() string { return this.CreatedAt }
 
 // Identifier of the user who created the record
 func (this Group) GetCreatedBy() uuid.UUID { return this.CreatedBy }
 
+// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+func (this Group) GetEtag() string { return this.Etag }
+
 // Unique identifier of the resource
 
+// Labels attached to the resource
+func (this Group) GetLabels() []*Label {
+	if this.Labels == nil {
+		return nil
+	}
+	interfaceSlice := make([]*Label, 0, len(this.Labels))
+	for _, concrete := range this.Labels {
+		interfaceSlice = append(interfaceSlice, concrete)
+	}
+	return interfaceSlice
+}
+
 // Name of the resource
 
 // Timestamp of last update
@@ -509,6 +921,102 @@ func (this Group) GetUpdatedAt() string { return this.UpdatedAt }
 // Identifier of the user who last updated the record
 func (this Group) GetUpdatedBy() uuid.UUID { return this.UpdatedBy }
 
+// Represents the import of a CSV file of users, groups and role bindings into a tenant
+type ImportJob struct {
+	// Number of valid rows committed together
+	ChunkSize int `json:"chunkSize"`
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who uploaded the file
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Why the job stopped, when it failed
+	Error *string `json:"error,omitempty"`
+	// Number of valid rows that could not be committed
+	FailedRows int `json:"failedRows"`
+	// Name of the uploaded file
+	FileName string `json:"fileName"`
+	// Timestamp when the last chunk was committed
+	FinishedAt *string `json:"finishedAt,omitempty"`
+	// Unique identifier of the job
+	ID uuid.UUID `json:"id"`
+	// Number of rows committed so far
+	ImportedRows int `json:"importedRows"`
+	// Number of rows that failed validation
+	InvalidRows int `json:"invalidRows"`
+	// Rows that were not imported, in file order
+	Rows []*ImportRowReport `json:"rows"`
+	// Status of the job
+	Status ImportJobStatus `json:"status"`
+	// Identifier of the tenant the rows are imported into
+	TenantID uuid.UUID `json:"tenantId"`
+	// Number of data rows of the file
+	TotalRows int `json:"totalRows"`
+	// Number of rows that passed validation
+	ValidRows int `json:"validRows"`
+}
+
+func (ImportJob) IsData() {}
+
+// Represents a row of a bulk import that was not imported
+type ImportRowReport struct {
+	// Why the row was not imported
+	Errors []string `json:"errors"`
+	// Name given in the row
+	Name string `json:"name"`
+	// Line of the row in the file, the header being line 1
+	Row int `json:"row"`
+	// Whether the row failed validation or could not be committed
+	Status ImportRowStatus `json:"status"`
+	// Type given in the row: user, group or binding
+	Type string `json:"type"`
+}
+
+// Represents the invitation of a user into a tenant with a role
+type Invitation struct {
+	// Timestamp when the invitation was accepted
+	AcceptedAt *string `json:"acceptedAt,omitempty"`
+	// Binding granting the role, active once the invitation is accepted
+	BindingID uuid.UUID `json:"bindingId"`
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who sent the invitation
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Email the invitation was sent to
+	Email string `json:"email"`
+	// Timestamp after which the invitation can no longer be accepted
+	ExpiresAt string `json:"expiresAt"`
+	// Unique identifier of the invitation
+	ID uuid.UUID `json:"id"`
+	// Principal of the invited user, active once the invitation is accepted
+	PrincipalID uuid.UUID `json:"principalId"`
+	// Role granted to the invited user
+	RoleID uuid.UUID `json:"roleId"`
+	// Resource the role is granted on
+	ScopeID uuid.UUID `json:"scopeId"`
+	// Status of the invitation
+	Status InvitationStatus `json:"status"`
+	// Tenant the user is invited into
+	TenantID uuid.UUID `json:"tenantId"`
+}
+
+func (Invitation) IsData() {}
+
+// Represents a key/value label attached to a resource
+type Label struct {
+	// Label key
+	Key string `json:"key"`
+	// Label value
+	Value string `json:"value"`
+}
+
+// Defines input fields for a label
+type LabelInput struct {
+	// Label key
+	Key string `json:"key"`
+	// Label value
+	Value string `json:"value"`
+}
+
 // Represents a Permission entity
 type Permission struct {
 	// Action associated with the permission
@@ -531,6 +1039,90 @@ type Permission struct {
 
 func (Permission) IsData() {}
 
+// Input for registering a break-glass principal
+type RegisterBreakGlassPrincipalInput struct {
+	// Principal allowed to break glass
+	PrincipalID uuid.UUID `json:"principalId"`
+	// Role granted when the principal breaks glass
+	RoleID uuid.UUID `json:"roleId"`
+	// Root or tenant resource the principal may break glass on
+	ScopeID uuid.UUID `json:"scopeId"`
+}
+
+// Defines input fields for registering a resource type
+type RegisterResourceTypeInput struct {
+	// Action keys defined for the resource type
+	Actions []string `json:"actions"`
+	// Resource types that may act as parent of this resource type
+	AllowedParentTypes []uuid.UUID `json:"allowedParentTypes,omitempty"`
+	// JSON Schema that the metadata of resources of this type must satisfy
+	AttributeSchema *string `json:"attributeSchema,omitempty"`
+	// Unique identifier of the resource type
+	ID uuid.UUID `json:"id"`
+	// Name of the resource type
+	Name string `json:"name"`
+	// Identifier of the service that owns the resource type
+	ServiceID uuid.UUID `json:"serviceId"`
+}
+
+// Defines input fields for removing labels from a resource
+type RemoveLabelsInput struct {
+	// Etag the resource must still have. The change fails with a CONFLICT error when the resource was modified since
+	ExpectedEtag *string `json:"expectedEtag,omitempty"`
+	// Keys of the labels to remove
+	Keys []string `json:"keys"`
+	// Unique identifier of the resource
+	ResourceID uuid.UUID `json:"resourceId"`
+}
+
+// Defines input fields for requesting access
+type RequestAccessInput struct {
+	// Requested access duration in seconds, omit for permanent access
+	Duration *int `json:"duration,omitempty"`
+	// Reason for the request
+	Justification string `json:"justification"`
+	// Requested role
+	RoleID uuid.UUID `json:"roleId"`
+	// Resource the role is requested on
+	ScopeID uuid.UUID `json:"scopeId"`
+}
+
+// Represents the labels of a resource after a label change
+type ResourceLabels struct {
+	// Labels currently attached to the resource
+	Labels []*Label `json:"labels"`
+	// Unique identifier of the resource
+	ResourceID uuid.UUID `json:"resourceId"`
+}
+
+func (ResourceLabels) IsData() {}
+
+// Represents a registered resource type
+type ResourceType struct {
+	// Action keys defined for the resource type
+	Actions []string `json:"actions"`
+	// Resource types that may act as parent of this resource type. Empty means any parent is accepted
+	AllowedParentTypes []uuid.UUID `json:"allowedParentTypes"`
+	// JSON Schema that the metadata of resources of this type must satisfy
+	AttributeSchema *string `json:"attributeSchema,omitempty"`
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who created the record
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Unique identifier of the resource type
+	ID uuid.UUID `json:"id"`
+	// Name of the resource type
+	Name string `json:"name"`
+	// Identifier of the service that owns the resource type
+	ServiceID uuid.UUID `json:"serviceId"`
+	// Timestamp of last update
+	UpdatedAt string `json:"updatedAt"`
+	// Identifier of the user who last updated the record
+	UpdatedBy uuid.UUID `json:"updatedBy"`
+}
+
+func (ResourceType) IsData() {}
+
 // Define ResponseError for error cases
 type ResponseError struct {
 	// Error code representing the type of error.
@@ -572,18 +1164,26 @@ func (this ResponseError) GetSystemMessage() string { return this.SystemMessage
 type Role struct {
 	// Assignable scope of the role
 	AssignableScope Resource `json:"assignableScope"`
+	// Custom attributes of the resource
+	Attributes *string `json:"attributes,omitempty"`
 	// Timestamp of creation
 	CreatedAt string `json:"createdAt"`
 	// Identifier of the user who created the record
 	CreatedBy uuid.UUID `json:"createdBy"`
 	// Description of the role
 	Description *string `json:"description,omitempty"`
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	Etag string `json:"etag"`
 	// Unique identifier of the role
 	ID uuid.UUID `json:"id"`
+	// Labels attached to the resource
+	Labels []*Label `json:"labels"`
 	// Name of the role
 	Name string `json:"name"`
 	// Permissions associated with the role
 	Permissions []*Permission `json:"permissions"`
+	// Immutable revision history of the role, newest first
+	Revisions []*RoleRevision `json:"revisions"`
 	// Type of the role
 	RoleType RoleTypeEnum `json:"roleType"`
 	// Timestamp of last update
@@ -598,15 +1198,33 @@ func (Role) IsData() {}
 
 func (Role) IsResource() {}
 
+// Custom attributes of the resource
+func (this Role) GetAttributes() *string { return this.Attributes }
+
 // Timestamp of creation
 func (this Role) GetCreatedAt() string { return this.CreatedAt }
 
 // Identifier of the user who created the record
 func (this Role) GetCreatedBy() uuid.UUID { return this.CreatedBy }
 
+// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+func (this Role) GetEtag() string { return this.Etag }
+
 // Unique identifier of the resource
 func (this Role) GetID() uuid.UUID { return this.ID }
 
+// Labels attached to the resource
+func (this Role) GetLabels() []*Label {
+	if this.Labels == nil {
+		return nil
+	}
+	interfaceSlice := make([]*Label, 0, len(this.Labels))
+	for _, concrete := range this.Labels {
+		interfaceSlice = append(interfaceSlice, concrete)
+	}
+	return interfaceSlice
+}
+
 // Name of the resource
 func (this Role) GetName() string { return this.Name }
 
@@ -616,16 +1234,72 @@ func (this Role) GetUpdatedAt() string { return this.UpdatedAt }
 // Identifier of the user who last updated the record
 func (this Role) GetUpdatedBy() uuid.UUID { return this.UpdatedBy }
 
+// Represents an immutable snapshot of a Role taken on every change
+type RoleRevision struct {
+	// Timestamp of creation
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who created the revision
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Description of the role at this revision
+	Description *string `json:"description,omitempty"`
+	// Unique identifier of the revision
+	ID uuid.UUID `json:"id"`
+	// Name of the role at this revision
+	Name string `json:"name"`
+	// Permission IDs granted by the role at this revision
+	Permissions []uuid.UUID `json:"permissions"`
+	// Sequential revision number of the role
+	Revision int `json:"revision"`
+	// Identifier of the role
+	RoleID uuid.UUID `json:"roleId"`
+}
+
+func (RoleRevision) IsData() {}
+
+// Represents the differences between two revisions of a Role
+type RoleRevisionDiff struct {
+	// Permission IDs present in the target revision but not in the source revision
+	AddedPermissions []uuid.UUID `json:"addedPermissions"`
+	// Scalar fields that differ between the revisions
+	Changes []*FieldChange `json:"changes"`
+	// Source revision number
+	FromRevision int `json:"fromRevision"`
+	// Permission IDs present in the source revision but not in the target revision
+	RemovedPermissions []uuid.UUID `json:"removedPermissions"`
+	// Identifier of the role
+	RoleID uuid.UUID `json:"roleId"`
+	// Target revision number
+	ToRevision int `json:"toRevision"`
+}
+
+func (RoleRevisionDiff) IsData() {}
+
+// Defines input fields for rolling a role back to an earlier revision
+type RollbackRoleInput struct {
+	// Etag the resource must still have. The change fails with a CONFLICT error when the resource was modified since
+	ExpectedEtag *string `json:"expectedEtag,omitempty"`
+	// Unique identifier of the role
+	ID uuid.UUID `json:"id"`
+	// Revision number to restore
+	Revision int `json:"revision"`
+}
+
 // Represents a Root entity
 type Root struct {
+	// Custom attributes of the resource
+	Attributes *string `json:"attributes,omitempty"`
 	// Timestamp of creation
 	CreatedAt string `json:"createdAt"`
 	// Identifier of the user who created the record
 	CreatedBy uuid.UUID `json:"createdBy"`
 	// Description of the root
 	Description *string `json:"description,omitempty"`
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	Etag string `json:"etag"`
 	// Unique identifier of the root
 	ID uuid.UUID `json:"id"`
+	// Labels attached to the resource
+	Labels []*Label `json:"labels"`
 	// Name of the root
 	Name string `json:"name"`
 	// Parent organization
@@ -666,18 +1340,60 @@ func (this Root) GetUpdatedBy() uuid.UUID { return this.UpdatedBy }
 
 func (Root) IsResource() {}
 
+// Custom attributes of the resource
+func (this Root) GetAttributes() *string { return this.Attributes }
+
 // Timestamp of creation
 
 // Identifier of the user who created the record
 
+// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+func (this Root) GetEtag() string { return this.Etag }
+
 // Unique identifier of the resource
 
+// Labels attached to the resource
+func (this Root) GetLabels() []*Label {
+	if this.Labels == nil {
+		return nil
+	}
+	interfaceSlice := make([]*Label, 0, len(this.Labels))
+	for _, concrete := range this.Labels {
+		interfaceSlice = append(interfaceSlice, concrete)
+	}
+	return interfaceSlice
+}
+
 // Name of the resource
 
 // Timestamp of last update
 
 // Identifier of the user who last updated the record
 
+// Defines input fields for setting the approval policy of a role
+type SetApprovalPolicyInput struct {
+	// Users allowed to approve requests
+	ApproverIds []uuid.UUID `json:"approverIds,omitempty"`
+	// Role whose holders on the requested scope may approve requests
+	ApproverRoleID *uuid.UUID `json:"approverRoleId,omitempty"`
+	// Longest access duration in seconds that may be granted
+	MaxDuration *int `json:"maxDuration,omitempty"`
+	// Number of distinct approvals needed, defaults to 1
+	RequiredApprovals *int `json:"requiredApprovals,omitempty"`
+	// Role the policy applies to
+	RoleID uuid.UUID `json:"roleId"`
+}
+
+// Defines input fields for setting labels on a resource
+type SetLabelsInput struct {
+	// Etag the resource must still have. The change fails with a CONFLICT error when the resource was modified since
+	ExpectedEtag *string `json:"expectedEtag,omitempty"`
+	// Labels to add or overwrite
+	Labels []*LabelInput `json:"labels"`
+	// Unique identifier of the resource
+	ResourceID uuid.UUID `json:"resourceId"`
+}
+
 // Success Response for a generic operation
 type SuccessResponse struct {
 	// The data returned from the operation.
@@ -700,6 +1416,8 @@ func (this SuccessResponse) GetMessage() string { return this.Message }
 
 // Represents a Tenant entity
 type Tenant struct {
+	// Custom attributes of the resource
+	Attributes *string `json:"attributes,omitempty"`
 	// Contact information of the tenant
 	ContactInfo *ContactInfo `json:"contactInfo,omitempty"`
 	// Timestamp of creation
@@ -708,12 +1426,18 @@ type Tenant struct {
 	CreatedBy uuid.UUID `json:"createdBy"`
 	// Description of the tenant
 	Description *string `json:"description,omitempty"`
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	Etag string `json:"etag"`
 	// Unique identifier of the tenant
 	ID uuid.UUID `json:"id"`
+	// Labels attached to the resource
+	Labels []*Label `json:"labels"`
 	// Name of the tenant
 	Name string `json:"name"`
 	// Parent organization
 	ParentOrg Organization `json:"parentOrg,omitempty"`
+	// Lifecycle status of the tenant
+	Status TenantStatus `json:"status"`
 	// Timestamp of last update
 	UpdatedAt string `json:"updatedAt"`
 	// Identifier of the user who last updated the record
@@ -750,18 +1474,132 @@ func (this Tenant) GetUpdatedBy() uuid.UUID { return this.UpdatedBy }
 
 func (Tenant) IsResource() {}
 
+// Custom attributes of the resource
+func (this Tenant) GetAttributes() *string { return this.Attributes }
+
 // Timestamp of creation
 
 // Identifier of the user who created the record
 
+// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+func (this Tenant) GetEtag() string { return this.Etag }
+
 // Unique identifier of the resource
 
+// Labels attached to the resource
+func (this Tenant) GetLabels() []*Label {
+	if this.Labels == nil {
+		return nil
+	}
+	interfaceSlice := make([]*Label, 0, len(this.Labels))
+	for _, concrete := range this.Labels {
+		interfaceSlice = append(interfaceSlice, concrete)
+	}
+	return interfaceSlice
+}
+
 // Name of the resource
 
 // Timestamp of last update
 
 // Identifier of the user who last updated the record
 
+// Represents a portable archive of a tenant
+type TenantArchive struct {
+	// JSON archive of the tenant's resources, metadata, roles, permissions, principals and bindings
+	Archive string `json:"archive"`
+	// Version of the archive format
+	Version int `json:"version"`
+}
+
+func (TenantArchive) IsData() {}
+
+// Represents a change of a tenant configuration plan
+type TenantConfigChange struct {
+	// Change made to the object
+	Action TenantConfigAction `json:"action"`
+	// Fields an update changes
+	Fields []string `json:"fields"`
+	// Identifier of the object. Created objects get a new one, as do updated bindings, which are replaced
+	ID uuid.UUID `json:"id"`
+	// Kind of the object
+	Kind TenantConfigKind `json:"kind"`
+	// Name of the object in the document
+	Name string `json:"name"`
+}
+
+// Represents the changes turning a tenant's configuration into the one a document describes
+type TenantConfigPlan struct {
+	// Whether the changes were made; false for dry runs
+	Applied bool `json:"applied"`
+	// Changes of the plan, in the order they are applied
+	Changes []*TenantConfigChange `json:"changes"`
+}
+
+func (TenantConfigPlan) IsData() {}
+
+// Represents the outcome of importing a tenant archive
+type TenantImport struct {
+	// Objects of the archive that were not imported
+	Conflicts []*TenantImportConflict `json:"conflicts"`
+	// New identifier of each imported object
+	IDMappings []*TenantImportMapping `json:"idMappings"`
+	// Whether the tenant was created; false when the FAIL policy met a conflict
+	Imported bool `json:"imported"`
+	// Identifier of the created tenant
+	TenantID *uuid.UUID `json:"tenantId,omitempty"`
+}
+
+func (TenantImport) IsData() {}
+
+// Represents an object of a tenant archive that cannot be recreated
+type TenantImportConflict struct {
+	// Kind of the object
+	Kind TenantArchiveKind `json:"kind"`
+	// Name of the object in the archive
+	Name string `json:"name"`
+	// Why the object cannot be recreated
+	Reason string `json:"reason"`
+	// Identifier of the object in the archive
+	SourceID uuid.UUID `json:"sourceId"`
+}
+
+// Represents the identifier an imported object was given
+type TenantImportMapping struct {
+	// Identifier of the imported object
+	ID uuid.UUID `json:"id"`
+	// Identifier of the object in the archive
+	SourceID uuid.UUID `json:"sourceId"`
+}
+
+// Represents a change of the lifecycle status of a tenant
+type TenantStatusTransition struct {
+	// Timestamp of the transition
+	CreatedAt string `json:"createdAt"`
+	// Identifier of the user who made the transition
+	CreatedBy uuid.UUID `json:"createdBy"`
+	// Status before the transition, null for the first transition recorded
+	FromStatus *TenantStatus `json:"fromStatus,omitempty"`
+	// Unique identifier of the transition
+	ID uuid.UUID `json:"id"`
+	// Reason given for the transition
+	Reason *string `json:"reason,omitempty"`
+	// Tenant whose status changed
+	TenantID uuid.UUID `json:"tenantId"`
+	// Status after the transition
+	ToStatus TenantStatus `json:"toStatus"`
+}
+
+func (TenantStatusTransition) IsData() {}
+
+// Defines input fields for changing the lifecycle status of a tenant
+type TenantTransitionInput struct {
+	// Unique identifier of the tenant
+	ID uuid.UUID `json:"id"`
+	// Reason for the transition, kept in the status history
+	Reason *string `json:"reason,omitempty"`
+}
+
 // Defines input fields for updating an account
 type UpdateAccountInput struct {
 	// Scope of billing info
@@ -854,6 +1692,8 @@ type UpdateRoleInput struct {
 	AssignableScopeRef uuid.UUID `json:"assignableScopeRef"`
 	// Updated description of the role
 	Description *string `json:"description,omitempty"`
+	// Etag the resource must still have. The change fails with a CONFLICT error when the resource was modified since
+	ExpectedEtag *string `json:"expectedEtag,omitempty"`
 	// Unique identifier of the role
 	ID uuid.UUID `json:"id"`
 	// Updated name of the role
@@ -878,10 +1718,14 @@ type UpdateRootInput struct {
 
 // Defines input fields for updating a tenant
 type UpdateTenantInput struct {
+	// Updated custom attributes validated against the resource type schema
+	Attributes *string `json:"attributes,omitempty"`
 	// Updated contact information of the tenant
 	ContactInfo *ContactInfoInput `json:"contactInfo,omitempty"`
 	// Updated description of the tenant
 	Description *string `json:"description,omitempty"`
+	// Etag the resource must still have. The change fails with a CONFLICT error when the resource was modified since
+	ExpectedEtag *string `json:"expectedEtag,omitempty"`
 	// Unique identifier of the tenant
 	ID uuid.UUID `json:"id"`
 	// Updated name of the tenant
@@ -892,16 +1736,22 @@ type UpdateTenantInput struct {
 
 // Represents a User entity
 type User struct {
+	// Custom attributes of the resource
+	Attributes *string `json:"attributes,omitempty"`
 	// Timestamp of creation
 	CreatedAt string `json:"createdAt"`
 	// Identifier of the user who created the record
 	CreatedBy uuid.UUID `json:"createdBy"`
 	// Email of the user
 	Email string `json:"email"`
+	// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+	Etag string `json:"etag"`
 	// First name of the user
 	FirstName string `json:"firstName"`
 	// Unique identifier of the user
 	ID uuid.UUID `json:"id"`
+	// Labels attached to the resource
+	Labels []*Label `json:"labels"`
 	// Last name of the user
 	LastName string `json:"lastName"`
 	// Name of the user
@@ -932,14 +1782,32 @@ func (this User) GetTenant() *Tenant { return this.Tenant }
 
 func (User) IsResource() {}
 
+// Custom attributes of the resource
+func (this User) GetAttributes() *string { return this.Attributes }
+
 // Timestamp of creation
 func (this User) GetCreatedAt() string { return this.CreatedAt }
 
 // Identifier of the user who created the record
 func (this User) GetCreatedBy() uuid.UUID { return this.CreatedBy }
 
+// Version of the resource, changed by every update. Pass it as expectedEtag to reject concurrent changes
+func (this User) GetEtag() string { return this.Etag }
+
 // Unique identifier of the resource
 
+// Labels attached to the resource
+func (this User) GetLabels() []*Label {
+	if this.Labels == nil {
+		return nil
+	}
+	interfaceSlice := make([]*Label, 0, len(this.Labels))
+	for _, concrete := range this.Labels {
+		interfaceSlice = append(interfaceSlice, concrete)
+	}
+	return interfaceSlice
+}
+
 // Name of the resource
 
 // Timestamp of last update
@@ -948,46 +1816,689 @@ func (this User) GetUpdatedAt() string { return this.UpdatedAt }
 // Identifier of the user who last updated the record
 func (this User) GetUpdatedBy() uuid.UUID { return this.UpdatedBy }
 
-// Defines the role type enumeration
-type RoleTypeEnum string
+// Defines the status of an access request
+type AccessRequestStatus string
 
 const (
-	// Custom role type
-	RoleTypeEnumCustom RoleTypeEnum = "CUSTOM"
-	// Default role type
-	RoleTypeEnumDefault RoleTypeEnum = "DEFAULT"
+	// Request was approved and the binding created
+	AccessRequestStatusApproved AccessRequestStatus = "APPROVED"
+	// Request was denied
+	AccessRequestStatusDenied AccessRequestStatus = "DENIED"
+	// Request is awaiting approval
+	AccessRequestStatusPending AccessRequestStatus = "PENDING"
 )
 
-var AllRoleTypeEnum = []RoleTypeEnum{
-	RoleTypeEnumCustom,
-	RoleTypeEnumDefault,
+var AllAccessRequestStatus = []AccessRequestStatus{
+	AccessRequestStatusApproved,
+	AccessRequestStatusDenied,
+	AccessRequestStatusPending,
 }
 
-func (e RoleTypeEnum) IsValid() bool {
+func (e AccessRequestStatus) IsValid() bool {
 	switch e {
-	case RoleTypeEnumCustom, RoleTypeEnumDefault:
+	case AccessRequestStatusApproved, AccessRequestStatusDenied, AccessRequestStatusPending:
 		return true
 	}
 	return false
 }
 
-func (e RoleTypeEnum) String() string {
+func (e AccessRequestStatus) String() string {
 	return string(e)
 }
 
-func (e *RoleTypeEnum) UnmarshalGQL(v any) error {
+func (e *AccessRequestStatus) UnmarshalGQL(v any) error {
 	str, ok := v.(string)
 	if !ok {
 		return fmt.Errorf("enums must be strings")
 	}
 
-	*e = RoleTypeEnum(str)
+	*e = AccessRequestStatus(str)
 	if !e.IsValid() {
-		return fmt.Errorf("%s is not a valid RoleTypeEnum", str)
+		return fmt.Errorf("%s is not a valid AccessRequestStatus", str)
 	}
 	return nil
 }
 
-func (e RoleTypeEnum) MarshalGQL(w io.Writer) {
+func (e AccessRequestStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the decision taken on an access review item
+type AccessReviewDecision string
+
+const (
+	// Access was certified
+	AccessReviewDecisionApproved AccessReviewDecision = "APPROVED"
+	// Access is awaiting a decision
+	AccessReviewDecisionPending AccessReviewDecision = "PENDING"
+	// Access was revoked
+	AccessReviewDecisionRevoked AccessReviewDecision = "REVOKED"
+)
+
+var AllAccessReviewDecision = []AccessReviewDecision{
+	AccessReviewDecisionApproved,
+	AccessReviewDecisionPending,
+	AccessReviewDecisionRevoked,
+}
+
+func (e AccessReviewDecision) IsValid() bool {
+	switch e {
+	case AccessReviewDecisionApproved, AccessReviewDecisionPending, AccessReviewDecisionRevoked:
+		return true
+	}
+	return false
+}
+
+func (e AccessReviewDecision) String() string {
+	return string(e)
+}
+
+func (e *AccessReviewDecision) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AccessReviewDecision(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AccessReviewDecision", str)
+	}
+	return nil
+}
+
+func (e AccessReviewDecision) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the scopes an access review campaign can cover
+type AccessReviewScopeType string
+
+const (
+	// Bindings granted to a group
+	AccessReviewScopeTypeGroup AccessReviewScopeType = "GROUP"
+	// Bindings of a role
+	AccessReviewScopeTypeRole AccessReviewScopeType = "ROLE"
+	// Bindings within a tenant
+	AccessReviewScopeTypeTenant AccessReviewScopeType = "TENANT"
+)
+
+var AllAccessReviewScopeType = []AccessReviewScopeType{
+	AccessReviewScopeTypeGroup,
+	AccessReviewScopeTypeRole,
+	AccessReviewScopeTypeTenant,
+}
+
+func (e AccessReviewScopeType) IsValid() bool {
+	switch e {
+	case AccessReviewScopeTypeGroup, AccessReviewScopeTypeRole, AccessReviewScopeTypeTenant:
+		return true
+	}
+	return false
+}
+
+func (e AccessReviewScopeType) String() string {
+	return string(e)
+}
+
+func (e *AccessReviewScopeType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AccessReviewScopeType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AccessReviewScopeType", str)
+	}
+	return nil
+}
+
+func (e AccessReviewScopeType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the status of an access review campaign
+type AccessReviewStatus string
+
+const (
+	// Campaign is closed and all decisions are final
+	AccessReviewStatusClosed AccessReviewStatus = "CLOSED"
+	// Campaign is awaiting decisions
+	AccessReviewStatusOpen AccessReviewStatus = "OPEN"
+)
+
+var AllAccessReviewStatus = []AccessReviewStatus{
+	AccessReviewStatusClosed,
+	AccessReviewStatusOpen,
+}
+
+func (e AccessReviewStatus) IsValid() bool {
+	switch e {
+	case AccessReviewStatusClosed, AccessReviewStatusOpen:
+		return true
+	}
+	return false
+}
+
+func (e AccessReviewStatus) String() string {
+	return string(e)
+}
+
+func (e *AccessReviewStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AccessReviewStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AccessReviewStatus", str)
+	}
+	return nil
+}
+
+func (e AccessReviewStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Outcome of an audited mutation
+type AuditOutcome string
+
+const (
+	AuditOutcomeFailure AuditOutcome = "FAILURE"
+	AuditOutcomeSuccess AuditOutcome = "SUCCESS"
+)
+
+var AllAuditOutcome = []AuditOutcome{
+	AuditOutcomeFailure,
+	AuditOutcomeSuccess,
+}
+
+func (e AuditOutcome) IsValid() bool {
+	switch e {
+	case AuditOutcomeFailure, AuditOutcomeSuccess:
+		return true
+	}
+	return false
+}
+
+func (e AuditOutcome) String() string {
+	return string(e)
+}
+
+func (e *AuditOutcome) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditOutcome(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditOutcome", str)
+	}
+	return nil
+}
+
+func (e AuditOutcome) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines how a batch mutation handles the failure of an item
+type BatchMode string
+
+const (
+	// Apply every item or none; one failure fails the whole batch
+	BatchModeAtomic BatchMode = "ATOMIC"
+	// Apply each item on its own; failed items do not affect the others
+	BatchModeBestEffort BatchMode = "BEST_EFFORT"
+)
+
+var AllBatchMode = []BatchMode{
+	BatchModeAtomic,
+	BatchModeBestEffort,
+}
+
+func (e BatchMode) IsValid() bool {
+	switch e {
+	case BatchModeAtomic, BatchModeBestEffort:
+		return true
+	}
+	return false
+}
+
+func (e BatchMode) String() string {
+	return string(e)
+}
+
+func (e *BatchMode) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BatchMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BatchMode", str)
+	}
+	return nil
+}
+
+func (e BatchMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the status of a bulk import job
+type ImportJobStatus string
+
+const (
+	// Every valid row was either imported or reported as failed
+	ImportJobStatusCompleted ImportJobStatus = "COMPLETED"
+	// The job stopped before every valid row was committed
+	ImportJobStatusFailed ImportJobStatus = "FAILED"
+	// Valid rows are being committed
+	ImportJobStatusRunning ImportJobStatus = "RUNNING"
+)
+
+var AllImportJobStatus = []ImportJobStatus{
+	ImportJobStatusCompleted,
+	ImportJobStatusFailed,
+	ImportJobStatusRunning,
+}
+
+func (e ImportJobStatus) IsValid() bool {
+	switch e {
+	case ImportJobStatusCompleted, ImportJobStatusFailed, ImportJobStatusRunning:
+		return true
+	}
+	return false
+}
+
+func (e ImportJobStatus) String() string {
+	return string(e)
+}
+
+func (e *ImportJobStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ImportJobStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ImportJobStatus", str)
+	}
+	return nil
+}
+
+func (e ImportJobStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines why a row of a bulk import was not imported
+type ImportRowStatus string
+
+const (
+	// The row was valid but could not be committed
+	ImportRowStatusFailed ImportRowStatus = "FAILED"
+	// The row failed validation
+	ImportRowStatusInvalid ImportRowStatus = "INVALID"
+)
+
+var AllImportRowStatus = []ImportRowStatus{
+	ImportRowStatusFailed,
+	ImportRowStatusInvalid,
+}
+
+func (e ImportRowStatus) IsValid() bool {
+	switch e {
+	case ImportRowStatusFailed, ImportRowStatusInvalid:
+		return true
+	}
+	return false
+}
+
+func (e ImportRowStatus) String() string {
+	return string(e)
+}
+
+func (e *ImportRowStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ImportRowStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ImportRowStatus", str)
+	}
+	return nil
+}
+
+func (e ImportRowStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Status of an invitation
+type InvitationStatus string
+
+const (
+	InvitationStatusAccepted InvitationStatus = "ACCEPTED"
+	InvitationStatusExpired  InvitationStatus = "EXPIRED"
+	InvitationStatusPending  InvitationStatus = "PENDING"
+)
+
+var AllInvitationStatus = []InvitationStatus{
+	InvitationStatusAccepted,
+	InvitationStatusExpired,
+	InvitationStatusPending,
+}
+
+func (e InvitationStatus) IsValid() bool {
+	switch e {
+	case InvitationStatusAccepted, InvitationStatusExpired, InvitationStatusPending:
+		return true
+	}
+	return false
+}
+
+func (e InvitationStatus) String() string {
+	return string(e)
+}
+
+func (e *InvitationStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = InvitationStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid InvitationStatus", str)
+	}
+	return nil
+}
+
+func (e InvitationStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the role type enumeration
+type RoleTypeEnum string
+
+const (
+	// Custom role type
+	RoleTypeEnumCustom RoleTypeEnum = "CUSTOM"
+	// Default role type
+	RoleTypeEnumDefault RoleTypeEnum = "DEFAULT"
+)
+
+var AllRoleTypeEnum = []RoleTypeEnum{
+	RoleTypeEnumCustom,
+	RoleTypeEnumDefault,
+}
+
+func (e RoleTypeEnum) IsValid() bool {
+	switch e {
+	case RoleTypeEnumCustom, RoleTypeEnumDefault:
+		return true
+	}
+	return false
+}
+
+func (e RoleTypeEnum) String() string {
+	return string(e)
+}
+
+func (e *RoleTypeEnum) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RoleTypeEnum(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RoleTypeEnum", str)
+	}
+	return nil
+}
+
+func (e RoleTypeEnum) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the kinds of objects a tenant archive holds
+type TenantArchiveKind string
+
+const (
+	// Binding of a role to a principal
+	TenantArchiveKindBinding TenantArchiveKind = "BINDING"
+	// User or group
+	TenantArchiveKindPrincipal TenantArchiveKind = "PRINCIPAL"
+	// Resource of the tenant other than a principal or role
+	TenantArchiveKindResource TenantArchiveKind = "RESOURCE"
+	// Custom role
+	TenantArchiveKindRole TenantArchiveKind = "ROLE"
+)
+
+var AllTenantArchiveKind = []TenantArchiveKind{
+	TenantArchiveKindBinding,
+	TenantArchiveKindPrincipal,
+	TenantArchiveKindResource,
+	TenantArchiveKindRole,
+}
+
+func (e TenantArchiveKind) IsValid() bool {
+	switch e {
+	case TenantArchiveKindBinding, TenantArchiveKindPrincipal, TenantArchiveKindResource, TenantArchiveKindRole:
+		return true
+	}
+	return false
+}
+
+func (e TenantArchiveKind) String() string {
+	return string(e)
+}
+
+func (e *TenantArchiveKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TenantArchiveKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TenantArchiveKind", str)
+	}
+	return nil
+}
+
+func (e TenantArchiveKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the change a tenant configuration plan makes to an object
+type TenantConfigAction string
+
+const (
+	// Object is created
+	TenantConfigActionCreate TenantConfigAction = "CREATE"
+	// Object is deleted
+	TenantConfigActionDelete TenantConfigAction = "DELETE"
+	// Object is changed
+	TenantConfigActionUpdate TenantConfigAction = "UPDATE"
+)
+
+var AllTenantConfigAction = []TenantConfigAction{
+	TenantConfigActionCreate,
+	TenantConfigActionDelete,
+	TenantConfigActionUpdate,
+}
+
+func (e TenantConfigAction) IsValid() bool {
+	switch e {
+	case TenantConfigActionCreate, TenantConfigActionDelete, TenantConfigActionUpdate:
+		return true
+	}
+	return false
+}
+
+func (e TenantConfigAction) String() string {
+	return string(e)
+}
+
+func (e *TenantConfigAction) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TenantConfigAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TenantConfigAction", str)
+	}
+	return nil
+}
+
+func (e TenantConfigAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines the kinds of objects a tenant configuration document describes
+type TenantConfigKind string
+
+const (
+	// Binding of a role to a user or group
+	TenantConfigKindBinding TenantConfigKind = "BINDING"
+	// Client organization unit
+	TenantConfigKindClientOrganizationUnit TenantConfigKind = "CLIENT_ORGANIZATION_UNIT"
+	// Group
+	TenantConfigKindGroup TenantConfigKind = "GROUP"
+	// Custom role
+	TenantConfigKindRole TenantConfigKind = "ROLE"
+)
+
+var AllTenantConfigKind = []TenantConfigKind{
+	TenantConfigKindBinding,
+	TenantConfigKindClientOrganizationUnit,
+	TenantConfigKindGroup,
+	TenantConfigKindRole,
+}
+
+func (e TenantConfigKind) IsValid() bool {
+	switch e {
+	case TenantConfigKindBinding, TenantConfigKindClientOrganizationUnit, TenantConfigKindGroup, TenantConfigKindRole:
+		return true
+	}
+	return false
+}
+
+func (e TenantConfigKind) String() string {
+	return string(e)
+}
+
+func (e *TenantConfigKind) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TenantConfigKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TenantConfigKind", str)
+	}
+	return nil
+}
+
+func (e TenantConfigKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Defines how importing a tenant archive handles objects that cannot be recreated
+type TenantImportConflictPolicy string
+
+const (
+	// Nothing is imported when any object conflicts
+	TenantImportConflictPolicyFail TenantImportConflictPolicy = "FAIL"
+	// Conflicting objects, and the objects depending on them, are left out
+	TenantImportConflictPolicySkip TenantImportConflictPolicy = "SKIP"
+)
+
+var AllTenantImportConflictPolicy = []TenantImportConflictPolicy{
+	TenantImportConflictPolicyFail,
+	TenantImportConflictPolicySkip,
+}
+
+func (e TenantImportConflictPolicy) IsValid() bool {
+	switch e {
+	case TenantImportConflictPolicyFail, TenantImportConflictPolicySkip:
+		return true
+	}
+	return false
+}
+
+func (e TenantImportConflictPolicy) String() string {
+	return string(e)
+}
+
+func (e *TenantImportConflictPolicy) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TenantImportConflictPolicy(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TenantImportConflictPolicy", str)
+	}
+	return nil
+}
+
+func (e TenantImportConflictPolicy) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// Lifecycle status of a tenant
+type TenantStatus string
+
+const (
+	TenantStatusActive       TenantStatus = "ACTIVE"
+	TenantStatusArchived     TenantStatus = "ARCHIVED"
+	TenantStatusProvisioning TenantStatus = "PROVISIONING"
+	TenantStatusSuspended    TenantStatus = "SUSPENDED"
+)
+
+var AllTenantStatus = []TenantStatus{
+	TenantStatusActive,
+	TenantStatusArchived,
+	TenantStatusProvisioning,
+	TenantStatusSuspended,
+}
+
+func (e TenantStatus) IsValid() bool {
+	switch e {
+	case TenantStatusActive, TenantStatusArchived, TenantStatusProvisioning, TenantStatusSuspended:
+		return true
+	}
+	return false
+}
+
+func (e TenantStatus) String() string {
+	return string(e)
+}
+
+func (e *TenantStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TenantStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TenantStatus", str)
+	}
+	return nil
+}
+
+func (e TenantStatus) MarshalGQL(w io.Writer) {
 	fmt.Fprint(w, strconv.Quote(e.String()))
 }
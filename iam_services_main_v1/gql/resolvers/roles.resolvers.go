@@ -0,0 +1,22 @@
+package resolvers
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.63
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/gql/generated"
+	"iam_services_main_v1/gql/models"
+)
+
+// Revisions is the resolver for the revisions field.
+func (r *roleResolver) Revisions(ctx context.Context, obj *models.Role) ([]*models.RoleRevision, error) {
+	panic(fmt.Errorf("not implemented: Revisions - revisions"))
+}
+
+// Role returns generated.RoleResolver implementation.
+func (r *Resolver) Role() generated.RoleResolver { return &roleResolver{r} }
+
+type roleResolver struct{ *Resolver }
@@ -13,16 +13,86 @@ import (
 	"github.com/google/uuid"
 )
 
+// ApplyTenantConfig is the resolver for the applyTenantConfig field.
+func (r *mutationResolver) ApplyTenantConfig(ctx context.Context, document string, dryRun *bool) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ApplyTenantConfig - applyTenantConfig"))
+}
+
+// AcceptInvitation is the resolver for the acceptInvitation field.
+func (r *mutationResolver) AcceptInvitation(ctx context.Context, token string) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: AcceptInvitation - acceptInvitation"))
+}
+
+// ApproveAccessRequest is the resolver for the approveAccessRequest field.
+func (r *mutationResolver) ApproveAccessRequest(ctx context.Context, input models1.AccessRequestDecisionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ApproveAccessRequest - approveAccessRequest"))
+}
+
+// ApproveAccessReviewItem is the resolver for the approveAccessReviewItem field.
+func (r *mutationResolver) ApproveAccessReviewItem(ctx context.Context, input models1.AccessReviewDecisionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ApproveAccessReviewItem - approveAccessReviewItem"))
+}
+
+// ArchiveTenant is the resolver for the archiveTenant field.
+func (r *mutationResolver) ArchiveTenant(ctx context.Context, input models1.TenantTransitionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ArchiveTenant - archiveTenant"))
+}
+
+// BreakGlass is the resolver for the breakGlass field.
+func (r *mutationResolver) BreakGlass(ctx context.Context, input models1.BreakGlassInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: BreakGlass - breakGlass"))
+}
+
+// CloseAccessReviewCampaign is the resolver for the closeAccessReviewCampaign field.
+func (r *mutationResolver) CloseAccessReviewCampaign(ctx context.Context, input models1.CloseAccessReviewCampaignInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: CloseAccessReviewCampaign - closeAccessReviewCampaign"))
+}
+
+// CreateAccessReviewCampaign is the resolver for the createAccessReviewCampaign field.
+func (r *mutationResolver) CreateAccessReviewCampaign(ctx context.Context, input models1.CreateAccessReviewCampaignInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: CreateAccessReviewCampaign - createAccessReviewCampaign"))
+}
+
+// CreateBindings is the resolver for the createBindings field.
+func (r *mutationResolver) CreateBindings(ctx context.Context, inputs []*models1.CreateBindingInput, mode *models1.BatchMode) ([]models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: CreateBindings - createBindings"))
+}
+
+// CreatePermission is the resolver for the createPermission field.
+func (r *mutationResolver) CreatePermission(ctx context.Context, input models1.CreatePermissionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: CreatePermission - createPermission"))
+}
+
 // CreateRole is the resolver for the createRole field.
 func (r *mutationResolver) CreateRole(ctx context.Context, input models1.CreateRoleInput) (models1.OperationResult, error) {
 	panic(fmt.Errorf("not implemented: CreateRole - createRole"))
 }
 
+// CreateRoles is the resolver for the createRoles field.
+func (r *mutationResolver) CreateRoles(ctx context.Context, inputs []*models1.CreateRoleInput, mode *models1.BatchMode) ([]models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: CreateRoles - createRoles"))
+}
+
 // CreateTenant is the resolver for the createTenant field.
 func (r *mutationResolver) CreateTenant(ctx context.Context, input models1.CreateTenantInput) (models1.OperationResult, error) {
 	panic(fmt.Errorf("not implemented: CreateTenant - createTenant"))
 }
 
+// DeleteApprovalPolicy is the resolver for the deleteApprovalPolicy field.
+func (r *mutationResolver) DeleteApprovalPolicy(ctx context.Context, input models1.DeleteInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: DeleteApprovalPolicy - deleteApprovalPolicy"))
+}
+
+// DeletePermission is the resolver for the deletePermission field.
+func (r *mutationResolver) DeletePermission(ctx context.Context, input models1.DeleteInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: DeletePermission - deletePermission"))
+}
+
+// DeleteResources is the resolver for the deleteResources field.
+func (r *mutationResolver) DeleteResources(ctx context.Context, ids []uuid.UUID, mode *models1.BatchMode) ([]models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: DeleteResources - deleteResources"))
+}
+
 // DeleteRole is the resolver for the deleteRole field.
 func (r *mutationResolver) DeleteRole(ctx context.Context, input models1.DeleteInput) (models1.OperationResult, error) {
 	panic(fmt.Errorf("not implemented: DeleteRole - deleteRole"))
@@ -33,6 +103,81 @@ func (r *mutationResolver) DeleteTenant(ctx context.Context, input models1.Delet
 	panic(fmt.Errorf("not implemented: DeleteTenant - deleteTenant"))
 }
 
+// DenyAccessRequest is the resolver for the denyAccessRequest field.
+func (r *mutationResolver) DenyAccessRequest(ctx context.Context, input models1.AccessRequestDecisionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: DenyAccessRequest - denyAccessRequest"))
+}
+
+// DeregisterBreakGlassPrincipal is the resolver for the deregisterBreakGlassPrincipal field.
+func (r *mutationResolver) DeregisterBreakGlassPrincipal(ctx context.Context, input models1.DeleteInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: DeregisterBreakGlassPrincipal - deregisterBreakGlassPrincipal"))
+}
+
+// ImportTenant is the resolver for the importTenant field.
+func (r *mutationResolver) ImportTenant(ctx context.Context, archive string, conflictPolicy *models1.TenantImportConflictPolicy) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ImportTenant - importTenant"))
+}
+
+// InviteUser is the resolver for the inviteUser field.
+func (r *mutationResolver) InviteUser(ctx context.Context, email string, roleID uuid.UUID, scopeID *uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: InviteUser - inviteUser"))
+}
+
+// ReactivateTenant is the resolver for the reactivateTenant field.
+func (r *mutationResolver) ReactivateTenant(ctx context.Context, input models1.TenantTransitionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ReactivateTenant - reactivateTenant"))
+}
+
+// RegisterBreakGlassPrincipal is the resolver for the registerBreakGlassPrincipal field.
+func (r *mutationResolver) RegisterBreakGlassPrincipal(ctx context.Context, input models1.RegisterBreakGlassPrincipalInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: RegisterBreakGlassPrincipal - registerBreakGlassPrincipal"))
+}
+
+// RegisterResourceType is the resolver for the registerResourceType field.
+func (r *mutationResolver) RegisterResourceType(ctx context.Context, input models1.RegisterResourceTypeInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: RegisterResourceType - registerResourceType"))
+}
+
+// RemoveLabels is the resolver for the removeLabels field.
+func (r *mutationResolver) RemoveLabels(ctx context.Context, input models1.RemoveLabelsInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: RemoveLabels - removeLabels"))
+}
+
+// RequestAccess is the resolver for the requestAccess field.
+func (r *mutationResolver) RequestAccess(ctx context.Context, input models1.RequestAccessInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: RequestAccess - requestAccess"))
+}
+
+// RevokeAccessReviewItem is the resolver for the revokeAccessReviewItem field.
+func (r *mutationResolver) RevokeAccessReviewItem(ctx context.Context, input models1.AccessReviewDecisionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: RevokeAccessReviewItem - revokeAccessReviewItem"))
+}
+
+// RollbackRole is the resolver for the rollbackRole field.
+func (r *mutationResolver) RollbackRole(ctx context.Context, input models1.RollbackRoleInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: RollbackRole - rollbackRole"))
+}
+
+// SetApprovalPolicy is the resolver for the setApprovalPolicy field.
+func (r *mutationResolver) SetApprovalPolicy(ctx context.Context, input models1.SetApprovalPolicyInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: SetApprovalPolicy - setApprovalPolicy"))
+}
+
+// SetLabels is the resolver for the setLabels field.
+func (r *mutationResolver) SetLabels(ctx context.Context, input models1.SetLabelsInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: SetLabels - setLabels"))
+}
+
+// SuspendTenant is the resolver for the suspendTenant field.
+func (r *mutationResolver) SuspendTenant(ctx context.Context, input models1.TenantTransitionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: SuspendTenant - suspendTenant"))
+}
+
+// UpdatePermission is the resolver for the updatePermission field.
+func (r *mutationResolver) UpdatePermission(ctx context.Context, input models1.UpdatePermissionInput) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: UpdatePermission - updatePermission"))
+}
+
 // UpdateRole is the resolver for the updateRole field.
 func (r *mutationResolver) UpdateRole(ctx context.Context, input models1.UpdateRoleInput) (models1.OperationResult, error) {
 	panic(fmt.Errorf("not implemented: UpdateRole - updateRole"))
@@ -43,13 +188,98 @@ func (r *mutationResolver) UpdateTenant(ctx context.Context, input models1.Updat
 	panic(fmt.Errorf("not implemented: UpdateTenant - updateTenant"))
 }
 
+// AccessRequest is the resolver for the accessRequest field.
+func (r *queryResolver) AccessRequest(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: AccessRequest - accessRequest"))
+}
+
+// AccessRequests is the resolver for the accessRequests field.
+func (r *queryResolver) AccessRequests(ctx context.Context, status *models1.AccessRequestStatus) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: AccessRequests - accessRequests"))
+}
+
+// AccessReviewCampaign is the resolver for the accessReviewCampaign field.
+func (r *queryResolver) AccessReviewCampaign(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: AccessReviewCampaign - accessReviewCampaign"))
+}
+
+// AccessReviewCampaigns is the resolver for the accessReviewCampaigns field.
+func (r *queryResolver) AccessReviewCampaigns(ctx context.Context, status *models1.AccessReviewStatus) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: AccessReviewCampaigns - accessReviewCampaigns"))
+}
+
+// AccessReviewReport is the resolver for the accessReviewReport field.
+func (r *queryResolver) AccessReviewReport(ctx context.Context, campaignID uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: AccessReviewReport - accessReviewReport"))
+}
+
+// ApprovalPolicies is the resolver for the approvalPolicies field.
+func (r *queryResolver) ApprovalPolicies(ctx context.Context) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ApprovalPolicies - approvalPolicies"))
+}
+
+// AuditEvents is the resolver for the auditEvents field.
+func (r *queryResolver) AuditEvents(ctx context.Context, filter *models1.AuditEventFilter, first *int, after *string) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: AuditEvents - auditEvents"))
+}
+
+// BreakGlassGrants is the resolver for the breakGlassGrants field.
+func (r *queryResolver) BreakGlassGrants(ctx context.Context, active *bool) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: BreakGlassGrants - breakGlassGrants"))
+}
+
+// BreakGlassPrincipals is the resolver for the breakGlassPrincipals field.
+func (r *queryResolver) BreakGlassPrincipals(ctx context.Context) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: BreakGlassPrincipals - breakGlassPrincipals"))
+}
+
+// ExportTenant is the resolver for the exportTenant field.
+func (r *queryResolver) ExportTenant(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ExportTenant - exportTenant"))
+}
+
+// VerifyAuditChain is the resolver for the verifyAuditChain field.
+func (r *queryResolver) VerifyAuditChain(ctx context.Context) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: VerifyAuditChain - verifyAuditChain"))
+}
+
+// ImportJob is the resolver for the importJob field.
+func (r *queryResolver) ImportJob(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ImportJob - importJob"))
+}
+
+// Permission is the resolver for the permission field.
+func (r *queryResolver) Permission(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: Permission - permission"))
+}
+
+// Permissions is the resolver for the permissions field.
+func (r *queryResolver) Permissions(ctx context.Context) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: Permissions - permissions"))
+}
+
+// ResourceType is the resolver for the resourceType field.
+func (r *queryResolver) ResourceType(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ResourceType - resourceType"))
+}
+
+// ResourceTypes is the resolver for the resourceTypes field.
+func (r *queryResolver) ResourceTypes(ctx context.Context) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: ResourceTypes - resourceTypes"))
+}
+
+// DiffRoleRevisions is the resolver for the diffRoleRevisions field.
+func (r *queryResolver) DiffRoleRevisions(ctx context.Context, roleID uuid.UUID, a int, b int) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: DiffRoleRevisions - diffRoleRevisions"))
+}
+
 // Role is the resolver for the role field.
 func (r *queryResolver) Role(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
 	panic(fmt.Errorf("not implemented: Role - role"))
 }
 
 // Roles is the resolver for the roles field.
-func (r *queryResolver) Roles(ctx context.Context) (models1.OperationResult, error) {
+func (r *queryResolver) Roles(ctx context.Context, selector *string) (models1.OperationResult, error) {
 	panic(fmt.Errorf("not implemented: Roles - roles"))
 }
 
@@ -59,10 +289,15 @@ func (r *queryResolver) Tenant(ctx context.Context, id uuid.UUID) (models1.Opera
 }
 
 // Tenants is the resolver for the tenants field.
-func (r *queryResolver) Tenants(ctx context.Context) (models1.OperationResult, error) {
+func (r *queryResolver) Tenants(ctx context.Context, selector *string) (models1.OperationResult, error) {
 	panic(fmt.Errorf("not implemented: Tenants - tenants"))
 }
 
+// TenantStatusHistory is the resolver for the tenantStatusHistory field.
+func (r *queryResolver) TenantStatusHistory(ctx context.Context, id uuid.UUID) (models1.OperationResult, error) {
+	panic(fmt.Errorf("not implemented: TenantStatusHistory - tenantStatusHistory"))
+}
+
 // Mutation returns generated1.MutationResolver implementation.
 func (r *Resolver) Mutation() generated1.MutationResolver { return &mutationResolver{r} }
 
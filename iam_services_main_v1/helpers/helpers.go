@@ -75,6 +75,46 @@ func GetUserID(ctx context.Context) (*uuid.UUID, error) {
 	}
 }
 
+// GetImpersonatedUserID returns the user the caller is impersonating, or nil
+// when the request is not impersonated.
+func GetImpersonatedUserID(ctx context.Context) (*uuid.UUID, error) {
+	ginCtx, ok := ctx.Value("GinContextKey").(*gin.Context)
+	if !ok {
+		return nil, fmt.Errorf("gin context not found in the request")
+	}
+	impersonatedUserID, exists := ginCtx.Get("impersonatedUserID")
+	if !exists {
+		return nil, nil
+	}
+
+	switch impersonatedUserID := impersonatedUserID.(type) {
+	case string:
+		parsedUserID, err := uuid.Parse(impersonatedUserID)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing impersonated user id: %w", err)
+		}
+		return &parsedUserID, nil
+	case uuid.UUID:
+		return &impersonatedUserID, nil
+	default:
+		return nil, fmt.Errorf("invalid impersonated user id type")
+	}
+}
+
+// GetPrincipalID returns the principal the request is authorized as: the
+// impersonated user when impersonating, otherwise the caller. Records of who
+// made a change (created_by, updated_by, audit) use GetUserID instead.
+func GetPrincipalID(ctx context.Context) (*uuid.UUID, error) {
+	impersonatedUserID, err := GetImpersonatedUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if impersonatedUserID != nil {
+		return impersonatedUserID, nil
+	}
+	return GetUserID(ctx)
+}
+
 func StructToMap(input interface{}) map[string]interface{} {
 	result := make(map[string]interface{})
 	val := reflect.ValueOf(input)
@@ -0,0 +1,261 @@
+package accessreviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccessReviewMutationResolver handles access review campaigns and decisions.
+type AccessReviewMutationResolver struct {
+	DB *gorm.DB
+	PC *permit.PermitClient
+}
+
+// CreateAccessReviewCampaign snapshots the active bindings of a scope into
+// review items assigned to the given reviewers.
+func (r *AccessReviewMutationResolver) CreateAccessReviewCampaign(ctx context.Context, input models.CreateAccessReviewCampaignInput) (models.OperationResult, error) {
+	r = &AccessReviewMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	tenantID, err := helpers.GetTenantID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid tenant ID", err)
+	}
+
+	if strings.TrimSpace(input.Name) == "" {
+		return handleError("400", "Invalid input", errors.New("campaign name is required"))
+	}
+	if len(input.ReviewerIds) == 0 {
+		return handleError("400", "Invalid input", errors.New("at least one reviewer is required"))
+	}
+	if !input.ScopeType.IsValid() {
+		return handleError("400", "Invalid input", fmt.Errorf("invalid scope type %q", input.ScopeType))
+	}
+	var dueAt *time.Time
+	if input.DueAt != nil {
+		parsed, err := time.Parse(time.RFC3339, *input.DueAt)
+		if err != nil {
+			return handleError("400", "Invalid input", fmt.Errorf("invalid due date: %w", err))
+		}
+		if !parsed.After(time.Now()) {
+			return handleError("400", "Invalid input", errors.New("due date must be in the future"))
+		}
+		dueAt = &parsed
+	}
+
+	scopeType := input.ScopeType.String()
+	if err := validateScope(r.DB, scopeType, input.ScopeID); err != nil {
+		if errors.Is(err, ErrScopeNotFound) {
+			return handleError("404", "Scope not found", err)
+		}
+		return handleError("500", "Error validating scope", err)
+	}
+
+	var openCampaigns int64
+	if err := r.DB.Model(&dto.AccessReviewCampaign{}).
+		Where("scope_type = ? AND scope_id = ? AND status = ?", scopeType, input.ScopeID, dto.AccessReviewStatusOpen).
+		Count(&openCampaigns).Error; err != nil {
+		return handleError("500", "Error checking access review campaigns", err)
+	}
+	if openCampaigns > 0 {
+		return handleError("409", "Access review campaign already exists", ErrCampaignExists)
+	}
+
+	campaign := dto.AccessReviewCampaign{
+		CampaignID: uuid.New(),
+		TenantID:   tenantID,
+		Name:       input.Name,
+		ScopeType:  scopeType,
+		ScopeID:    input.ScopeID,
+		Status:     dto.AccessReviewStatusOpen,
+		DueAt:      dueAt,
+		CreatedBy:  *userID,
+		UpdatedBy:  *userID,
+	}
+	var items []dto.AccessReviewItem
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		assignments, err := scopeAssignments(tx, scopeType, input.ScopeID)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&campaign).Error; err != nil {
+			return fmt.Errorf("failed to create campaign: %w", err)
+		}
+
+		reviewers := assignReviewers(assignments, input.ReviewerIds)
+		for i, assignment := range assignments {
+			items = append(items, dto.AccessReviewItem{
+				ItemID:      uuid.New(),
+				CampaignID:  campaign.CampaignID,
+				BindingID:   assignment.BindingID,
+				BindingName: assignment.Name,
+				PrincipalID: assignment.PrincipalID,
+				RoleID:      assignment.RoleID,
+				TenantID:    assignment.TenantID,
+				ReviewerID:  reviewers[i],
+				Decision:    dto.AccessReviewDecisionPending,
+			})
+		}
+		if len(items) > 0 {
+			if err := tx.Create(&items).Error; err != nil {
+				return fmt.Errorf("failed to create review items: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return handleError("500", "Error creating access review campaign", err)
+	}
+
+	return utils.FormatSuccess([]models.Data{mapToCampaign(&campaign, items)})
+}
+
+// ApproveAccessReviewItem certifies the binding of an item.
+func (r *AccessReviewMutationResolver) ApproveAccessReviewItem(ctx context.Context, input models.AccessReviewDecisionInput) (models.OperationResult, error) {
+	return r.decide(ctx, input, dto.AccessReviewDecisionApproved)
+}
+
+// RevokeAccessReviewItem revokes the binding of an item right away.
+func (r *AccessReviewMutationResolver) RevokeAccessReviewItem(ctx context.Context, input models.AccessReviewDecisionInput) (models.OperationResult, error) {
+	return r.decide(ctx, input, dto.AccessReviewDecisionRevoked)
+}
+
+// CloseAccessReviewCampaign revokes every binding still pending review and
+// closes the campaign. A failed revocation leaves the campaign open so closing
+// can be retried; items revoked so far keep their decision.
+func (r *AccessReviewMutationResolver) CloseAccessReviewCampaign(ctx context.Context, input models.CloseAccessReviewCampaignInput) (models.OperationResult, error) {
+	r = &AccessReviewMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	campaign, err := getCampaign(r.DB, input.ID)
+	if err != nil {
+		return campaignError(err)
+	}
+	if campaign.Status != dto.AccessReviewStatusOpen {
+		return handleError("409", "Access review campaign is closed", ErrCampaignClosed)
+	}
+
+	var pending []dto.AccessReviewItem
+	if err := r.DB.Where("campaign_id = ? AND decision = ?", campaign.CampaignID, dto.AccessReviewDecisionPending).
+		Find(&pending).Error; err != nil {
+		return handleError("500", "Error fetching access review items", err)
+	}
+	for _, item := range pending {
+		if err := bindings.Revoke(ctx, r.DB, r.permitClient(), item.BindingID, *userID); err != nil {
+			return handleError("500", "Error revoking binding", err)
+		}
+		now := time.Now()
+		if err := r.DB.Model(&dto.AccessReviewItem{}).Where("item_id = ?", item.ItemID).Updates(map[string]interface{}{
+			"decision":     dto.AccessReviewDecisionRevoked,
+			"auto_revoked": true,
+			"decided_at":   now,
+		}).Error; err != nil {
+			return handleError("500", "Error updating access review item", err)
+		}
+	}
+
+	now := time.Now()
+	if err := r.DB.Model(&dto.AccessReviewCampaign{}).Where("campaign_id = ?", campaign.CampaignID).Updates(map[string]interface{}{
+		"status":     dto.AccessReviewStatusClosed,
+		"closed_at":  now,
+		"closed_by":  *userID,
+		"updated_by": *userID,
+	}).Error; err != nil {
+		return handleError("500", "Error closing access review campaign", err)
+	}
+
+	campaign, err = getCampaign(r.DB, campaign.CampaignID)
+	if err != nil {
+		return campaignError(err)
+	}
+	items, err := getItems(r.DB, campaign.CampaignID)
+	if err != nil {
+		return handleError("500", "Error fetching access review items", err)
+	}
+	return utils.FormatSuccess([]models.Data{buildReport(campaign, items)})
+}
+
+// Helper Functions
+
+func (r *AccessReviewMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// decide records a reviewer's decision; revocations take effect immediately.
+func (r *AccessReviewMutationResolver) decide(ctx context.Context, input models.AccessReviewDecisionInput, decision string) (models.OperationResult, error) {
+	r = &AccessReviewMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	principalID, err := helpers.GetPrincipalID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	item, err := getItem(r.DB, input.ID)
+	if err != nil {
+		if errors.Is(err, ErrItemNotFound) {
+			return handleError("404", "Access review item not found", err)
+		}
+		return handleError("500", "Error fetching access review item", err)
+	}
+	campaign, err := getCampaign(r.DB, item.CampaignID)
+	if err != nil {
+		return campaignError(err)
+	}
+	if campaign.Status != dto.AccessReviewStatusOpen {
+		return handleError("409", "Access review campaign is closed", ErrCampaignClosed)
+	}
+	if item.ReviewerID != *principalID {
+		return handleError("403", "Not the reviewer of this item", ErrNotReviewer)
+	}
+	if item.Decision != dto.AccessReviewDecisionPending {
+		return handleError("409", "Access review item already decided", ErrItemDecided)
+	}
+
+	if decision == dto.AccessReviewDecisionRevoked {
+		if err := bindings.Revoke(ctx, r.DB, r.permitClient(), item.BindingID, *userID); err != nil {
+			return handleError("500", "Error revoking binding", err)
+		}
+	}
+
+	updates := map[string]interface{}{
+		"decision":   decision,
+		"decided_by": *userID,
+		"decided_at": time.Now(),
+	}
+	if input.Comment != nil {
+		updates["comment"] = *input.Comment
+	}
+	if err := r.DB.Model(&dto.AccessReviewItem{}).Where("item_id = ?", item.ItemID).Updates(updates).Error; err != nil {
+		return handleError("500", "Error updating access review item", err)
+	}
+
+	item, err = getItem(r.DB, item.ItemID)
+	if err != nil {
+		return handleError("500", "Error fetching access review item", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToItem(item)})
+}
@@ -0,0 +1,170 @@
+package accessreviews
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.TenantResource{}, &dto.TenantRoleAssignments{}, &dto.TNTRole{},
+		&dto.AccessReviewCampaign{}, &dto.AccessReviewItem{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// fakePermit records the role assignments removed through the Permit facts API.
+type fakePermit struct {
+	mu       sync.Mutex
+	unassign []map[string]interface{}
+}
+
+func (f *fakePermit) client(t *testing.T) *permit.PermitClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodDelete && req.URL.Path == "/v2/facts/proj/env/role_assignments" {
+			var body map[string]interface{}
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			f.mu.Lock()
+			f.unassign = append(f.unassign, body)
+			f.mu.Unlock()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+}
+
+func userContext(userID, tenantID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	ginCtx.Set("tenantID", tenantID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func seedBinding(t *testing.T, db *gorm.DB, tenantID, principalID, roleID uuid.UUID) uuid.UUID {
+	id := uuid.New()
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: id, ResourceTypeID: uuid.New(), Name: "binding", TenantID: &tenantID, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantRoleAssignments{ResourceID: id, Name: "binding", Version: "V1", PrincipalID: principalID, RoleID: roleID, RowStatus: 1}).Error)
+	return id
+}
+
+func TestAccessReviewCampaignLifecycle(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	fp := &fakePermit{}
+	resolver := &AccessReviewMutationResolver{DB: db, PC: fp.client(t)}
+	queries := &AccessReviewQueryResolver{DB: db}
+
+	tenantID, roleID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: tenantID, ResourceTypeID: uuid.New(), Name: "acme", RowStatus: 1}).Error)
+	reviewer := uuid.New()
+	for i := 0; i < 3; i++ {
+		seedBinding(t, db, tenantID, uuid.New(), roleID)
+	}
+	seedBinding(t, db, uuid.New(), uuid.New(), roleID) // other tenant
+
+	ctx := userContext(reviewer, tenantID)
+	result, err := resolver.CreateAccessReviewCampaign(ctx, models.CreateAccessReviewCampaignInput{
+		Name: "Q1", ScopeType: models.AccessReviewScopeTypeTenant, ScopeID: tenantID, ReviewerIds: []uuid.UUID{reviewer},
+	})
+	require.NoError(t, err)
+	campaign := result.(*models.SuccessResponse).Data[0].(*models.AccessReviewCampaign)
+	require.Len(t, campaign.Items, 3)
+
+	// A second open campaign over the same scope is rejected
+	result, err = resolver.CreateAccessReviewCampaign(ctx, models.CreateAccessReviewCampaignInput{
+		Name: "Q1 again", ScopeType: models.AccessReviewScopeTypeTenant, ScopeID: tenantID, ReviewerIds: []uuid.UUID{reviewer},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "409", result.(*models.ResponseError).ErrorCode)
+
+	// Only the assigned reviewer may decide
+	result, err = resolver.ApproveAccessReviewItem(userContext(uuid.New(), tenantID), models.AccessReviewDecisionInput{ID: campaign.Items[0].ID})
+	require.NoError(t, err)
+	assert.Equal(t, "403", result.(*models.ResponseError).ErrorCode)
+
+	comment := "still needed"
+	result, err = resolver.ApproveAccessReviewItem(ctx, models.AccessReviewDecisionInput{ID: campaign.Items[0].ID, Comment: &comment})
+	require.NoError(t, err)
+	approved := result.(*models.SuccessResponse).Data[0].(*models.AccessReviewItem)
+	assert.Equal(t, models.AccessReviewDecisionApproved, approved.Decision)
+	assert.Equal(t, comment, *approved.Comment)
+
+	result, err = resolver.RevokeAccessReviewItem(ctx, models.AccessReviewDecisionInput{ID: campaign.Items[0].ID})
+	require.NoError(t, err)
+	assert.Equal(t, "409", result.(*models.ResponseError).ErrorCode)
+
+	result, err = resolver.RevokeAccessReviewItem(ctx, models.AccessReviewDecisionInput{ID: campaign.Items[1].ID})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	require.Len(t, fp.unassign, 1)
+	assert.Equal(t, map[string]interface{}{
+		"user":   campaign.Items[1].PrincipalID.String(),
+		"role":   roleID.String(),
+		"tenant": tenantID.String(),
+	}, fp.unassign[0])
+
+	// Closing revokes the unreviewed binding
+	result, err = resolver.CloseAccessReviewCampaign(ctx, models.CloseAccessReviewCampaignInput{ID: campaign.ID})
+	require.NoError(t, err)
+	report := result.(*models.SuccessResponse).Data[0].(*models.AccessReviewReport)
+	assert.Equal(t, models.AccessReviewStatusClosed, report.Campaign.Status)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 1, report.Approved)
+	assert.Equal(t, 2, report.Revoked)
+	assert.Equal(t, 1, report.AutoRevoked)
+	assert.Equal(t, 0, report.Pending)
+	assert.InDelta(t, 66.67, report.CompletionRate, 0.01)
+	assert.Len(t, fp.unassign, 2)
+
+	remaining, err := bindings.ActiveAssignments(db, bindings.TenantColumn+" = ?", tenantID)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, campaign.Items[0].BindingID, remaining[0].BindingID)
+
+	result, err = queries.AccessReviewReport(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.(*models.SuccessResponse).Data[0].(*models.AccessReviewReport).AutoRevoked)
+
+	result, err = resolver.ApproveAccessReviewItem(ctx, models.AccessReviewDecisionInput{ID: campaign.Items[2].ID})
+	require.NoError(t, err)
+	assert.Equal(t, "409", result.(*models.ResponseError).ErrorCode)
+}
+
+func TestAssignReviewersAvoidsSelfReview(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	assignments := []bindings.Assignment{
+		{PrincipalID: alice},
+		{PrincipalID: bob},
+		{PrincipalID: uuid.New()},
+		{PrincipalID: uuid.New()},
+	}
+
+	reviewers := assignReviewers(assignments, []uuid.UUID{alice, bob})
+	assert.Equal(t, []uuid.UUID{bob, alice, bob, alice}, reviewers)
+
+	// A sole reviewer still gets their own binding
+	assert.Equal(t, []uuid.UUID{alice}, assignReviewers(assignments[:1], []uuid.UUID{alice}))
+}
@@ -0,0 +1,82 @@
+package accessreviews
+
+import (
+	"context"
+	"errors"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccessReviewQueryResolver handles access review queries.
+type AccessReviewQueryResolver struct {
+	DB *gorm.DB
+}
+
+// AccessReviewCampaigns lists the campaigns of the current tenant, newest first.
+func (r *AccessReviewQueryResolver) AccessReviewCampaigns(ctx context.Context, status *models.AccessReviewStatus) (models.OperationResult, error) {
+	r = &AccessReviewQueryResolver{DB: tenancy.ForRequest(ctx, r.DB)}
+	tenantID, err := helpers.GetTenantID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid tenant ID", err)
+	}
+
+	query := r.DB.Where("tenant_id = ?", tenantID)
+	if status != nil {
+		query = query.Where("status = ?", status.String())
+	}
+	var campaigns []dto.AccessReviewCampaign
+	if err := query.Order("created_at DESC").Find(&campaigns).Error; err != nil {
+		return handleError("500", "Error fetching access review campaigns", err)
+	}
+
+	data := make([]models.Data, 0, len(campaigns))
+	for i := range campaigns {
+		items, err := getItems(r.DB, campaigns[i].CampaignID)
+		if err != nil {
+			return handleError("500", "Error fetching access review items", err)
+		}
+		data = append(data, mapToCampaign(&campaigns[i], items))
+	}
+	return utils.FormatSuccess(data)
+}
+
+// AccessReviewCampaign resolves a single campaign with its items.
+func (r *AccessReviewQueryResolver) AccessReviewCampaign(ctx context.Context, id uuid.UUID) (models.OperationResult, error) {
+	r = &AccessReviewQueryResolver{DB: tenancy.ForRequest(ctx, r.DB)}
+	campaign, err := getCampaign(r.DB, id)
+	if err != nil {
+		return campaignError(err)
+	}
+	items, err := getItems(r.DB, id)
+	if err != nil {
+		return handleError("500", "Error fetching access review items", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToCampaign(campaign, items)})
+}
+
+// AccessReviewReport summarises the decisions taken in a campaign.
+func (r *AccessReviewQueryResolver) AccessReviewReport(ctx context.Context, campaignID uuid.UUID) (models.OperationResult, error) {
+	r = &AccessReviewQueryResolver{DB: tenancy.ForRequest(ctx, r.DB)}
+	campaign, err := getCampaign(r.DB, campaignID)
+	if err != nil {
+		return campaignError(err)
+	}
+	items, err := getItems(r.DB, campaignID)
+	if err != nil {
+		return handleError("500", "Error fetching access review items", err)
+	}
+	return utils.FormatSuccess([]models.Data{buildReport(campaign, items)})
+}
+
+func campaignError(err error) (models.OperationResult, error) {
+	if errors.Is(err, ErrCampaignNotFound) {
+		return handleError("404", "Access review campaign not found", err)
+	}
+	return handleError("500", "Error fetching access review campaign", err)
+}
@@ -0,0 +1,194 @@
+package accessreviews
+
+import (
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCampaignNotFound = errors.New("access review campaign not found")
+	ErrCampaignClosed   = errors.New("access review campaign is closed")
+	ErrCampaignExists   = errors.New("an open access review campaign already covers this scope")
+	ErrItemNotFound     = errors.New("access review item not found")
+	ErrItemDecided      = errors.New("access review item has already been decided")
+	ErrNotReviewer      = errors.New("user is not the reviewer of this item")
+	ErrScopeNotFound    = errors.New("scope not found")
+)
+
+// scopeAssignments returns the active bindings covered by a campaign scope.
+// Group scopes cover the bindings granted to the group itself.
+func scopeAssignments(db *gorm.DB, scopeType string, scopeID uuid.UUID) ([]bindings.Assignment, error) {
+	switch scopeType {
+	case dto.AccessReviewScopeTenant:
+		return bindings.ActiveAssignments(db, bindings.TenantColumn+" = ?", scopeID)
+	case dto.AccessReviewScopeRole:
+		return bindings.ActiveAssignments(db, "ra.role_id = ?", scopeID)
+	case dto.AccessReviewScopeGroup:
+		return bindings.ActiveAssignments(db, "ra.principal_id = ?", scopeID)
+	default:
+		return nil, fmt.Errorf("unsupported scope type %q", scopeType)
+	}
+}
+
+// validateScope checks that the reviewed scope exists.
+func validateScope(db *gorm.DB, scopeType string, scopeID uuid.UUID) error {
+	query := db.Model(&dto.TenantResource{}).Where("resource_id = ? AND row_status = 1", scopeID)
+	if scopeType == dto.AccessReviewScopeRole {
+		query = db.Model(&dto.TNTRole{}).Where("resource_id = ? AND row_status = 1", scopeID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check scope: %w", err)
+	}
+	if count == 0 {
+		return ErrScopeNotFound
+	}
+	return nil
+}
+
+// assignReviewers spreads the items round robin across the reviewers, passing
+// over reviewers who would otherwise certify their own access.
+func assignReviewers(assignments []bindings.Assignment, reviewers []uuid.UUID) []uuid.UUID {
+	result := make([]uuid.UUID, len(assignments))
+	next := 0
+	for i, assignment := range assignments {
+		reviewer := reviewers[next%len(reviewers)]
+		for offset := 0; offset < len(reviewers); offset++ {
+			candidate := reviewers[(next+offset)%len(reviewers)]
+			if candidate != assignment.PrincipalID {
+				reviewer = candidate
+				next += offset
+				break
+			}
+		}
+		result[i] = reviewer
+		next++
+	}
+	return result
+}
+
+func getCampaign(db *gorm.DB, campaignID uuid.UUID) (*dto.AccessReviewCampaign, error) {
+	var campaign dto.AccessReviewCampaign
+	if err := db.Where("campaign_id = ?", campaignID).First(&campaign).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCampaignNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch campaign: %w", err)
+	}
+	return &campaign, nil
+}
+
+func getItem(db *gorm.DB, itemID uuid.UUID) (*dto.AccessReviewItem, error) {
+	var item dto.AccessReviewItem
+	if err := db.Where("item_id = ?", itemID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrItemNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch item: %w", err)
+	}
+	return &item, nil
+}
+
+func getItems(db *gorm.DB, campaignID uuid.UUID) ([]dto.AccessReviewItem, error) {
+	var items []dto.AccessReviewItem
+	if err := db.Where("campaign_id = ?", campaignID).Order("created_at, item_id").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch items: %w", err)
+	}
+	return items, nil
+}
+
+func mapToCampaign(campaign *dto.AccessReviewCampaign, items []dto.AccessReviewItem) *models.AccessReviewCampaign {
+	result := &models.AccessReviewCampaign{
+		ID:        campaign.CampaignID,
+		TenantID:  campaign.TenantID,
+		Name:      campaign.Name,
+		ScopeType: models.AccessReviewScopeType(campaign.ScopeType),
+		ScopeID:   campaign.ScopeID,
+		Status:    models.AccessReviewStatus(campaign.Status),
+		DueAt:     formatTime(campaign.DueAt),
+		ClosedAt:  formatTime(campaign.ClosedAt),
+		ClosedBy:  campaign.ClosedBy,
+		CreatedAt: campaign.CreatedAt.Format(time.RFC3339),
+		CreatedBy: campaign.CreatedBy,
+		Items:     make([]*models.AccessReviewItem, 0, len(items)),
+	}
+	for i := range items {
+		result.Items = append(result.Items, mapToItem(&items[i]))
+	}
+	return result
+}
+
+func mapToItem(item *dto.AccessReviewItem) *models.AccessReviewItem {
+	result := &models.AccessReviewItem{
+		ID:          item.ItemID,
+		CampaignID:  item.CampaignID,
+		BindingID:   item.BindingID,
+		PrincipalID: item.PrincipalID,
+		RoleID:      item.RoleID,
+		TenantID:    item.TenantID,
+		ReviewerID:  item.ReviewerID,
+		Decision:    models.AccessReviewDecision(item.Decision),
+		AutoRevoked: item.AutoRevoked,
+		DecidedBy:   item.DecidedBy,
+		DecidedAt:   formatTime(item.DecidedAt),
+	}
+	if item.BindingName != "" {
+		result.BindingName = &item.BindingName
+	}
+	if item.Comment != "" {
+		result.Comment = &item.Comment
+	}
+	return result
+}
+
+// buildReport counts the decisions of a campaign.
+func buildReport(campaign *dto.AccessReviewCampaign, items []dto.AccessReviewItem) *models.AccessReviewReport {
+	report := &models.AccessReviewReport{
+		Campaign: mapToCampaign(campaign, items),
+		Total:    len(items),
+	}
+	decided := 0
+	for _, item := range items {
+		switch item.Decision {
+		case dto.AccessReviewDecisionApproved:
+			report.Approved++
+			decided++
+		case dto.AccessReviewDecisionRevoked:
+			report.Revoked++
+			if item.AutoRevoked {
+				report.AutoRevoked++
+			} else {
+				decided++
+			}
+		default:
+			report.Pending++
+		}
+	}
+	if report.Total > 0 {
+		report.CompletionRate = float64(decided) * 100 / float64(report.Total)
+	}
+	return report
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
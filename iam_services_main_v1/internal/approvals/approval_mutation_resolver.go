@@ -0,0 +1,363 @@
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ApprovalMutationResolver handles approval policies and access request decisions.
+type ApprovalMutationResolver struct {
+	DB *gorm.DB
+	PC *permit.PermitClient
+}
+
+func (r *ApprovalMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// SetApprovalPolicy creates or replaces the approval policy of a role in the
+// role's tenant.
+func (r *ApprovalMutationResolver) SetApprovalPolicy(ctx context.Context, input models.SetApprovalPolicyInput) (models.OperationResult, error) {
+	r = &ApprovalMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	if len(input.ApproverIds) == 0 && input.ApproverRoleID == nil {
+		return handleError("400", "Invalid input", ErrInvalidPolicyRule)
+	}
+	requiredApprovals := 1
+	if input.RequiredApprovals != nil {
+		requiredApprovals = *input.RequiredApprovals
+	}
+	if requiredApprovals < 1 {
+		return handleError("400", "Invalid input", errors.New("required approvals must be at least 1"))
+	}
+	if input.MaxDuration != nil && *input.MaxDuration <= 0 {
+		return handleError("400", "Invalid input", errors.New("max duration must be positive"))
+	}
+	tenantID, err := roleTenant(r.DB, input.RoleID)
+	if err != nil {
+		return roleError(err)
+	}
+	if input.ApproverRoleID != nil {
+		if err := roleExists(r.DB, *input.ApproverRoleID); err != nil {
+			return roleError(err)
+		}
+	}
+
+	approvers := input.ApproverIds
+	if approvers == nil {
+		approvers = []uuid.UUID{}
+	}
+	approverIDs, err := json.Marshal(approvers)
+	if err != nil {
+		return handleError("500", "Error encoding approvers", err)
+	}
+
+	// A deleted policy of the role is revived, a tenant keeping one per role
+	query := r.DB.Where("role_id = ? AND tenant_id IS NULL", input.RoleID)
+	if tenantID != nil {
+		query = r.DB.Where("role_id = ? AND tenant_id = ?", input.RoleID, *tenantID)
+	}
+	policy := &dto.ApprovalPolicy{}
+	err = query.First(policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		policy = &dto.ApprovalPolicy{
+			PolicyID:  uuid.New(),
+			TenantID:  tenantID,
+			RoleID:    input.RoleID,
+			CreatedBy: *userID,
+		}
+	} else if err != nil {
+		return handleError("500", "Error fetching approval policy", err)
+	}
+	policy.RowStatus = 1
+	policy.ApproverRoleID = input.ApproverRoleID
+	policy.ApproverIDs = approverIDs
+	policy.RequiredApprovals = requiredApprovals
+	policy.MaxDurationSeconds = input.MaxDuration
+	policy.UpdatedBy = *userID
+	if err := r.DB.Save(policy).Error; err != nil {
+		return handleError("500", "Error saving approval policy", err)
+	}
+
+	result, err := mapToPolicy(policy)
+	if err != nil {
+		return handleError("500", "Error mapping approval policy", err)
+	}
+	return utils.FormatSuccess([]models.Data{result})
+}
+
+// DeleteApprovalPolicy soft deletes an approval policy. Pending requests for
+// the role can no longer be decided until a new policy is set.
+func (r *ApprovalMutationResolver) DeleteApprovalPolicy(ctx context.Context, input models.DeleteInput) (models.OperationResult, error) {
+	r = &ApprovalMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	updates := utils.UpdateDeletedMap()
+	updates["updated_by"] = *userID
+	result := r.DB.Model(&dto.ApprovalPolicy{}).Where("policy_id = ? AND row_status = 1", input.ID).Updates(updates)
+	if result.Error != nil {
+		return handleError("500", "Error deleting approval policy", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return handleError("404", "Approval policy not found", ErrPolicyNotFound)
+	}
+	return utils.FormatSuccess([]models.Data{})
+}
+
+// RequestAccess records a pending request by the current user for a role on
+// a scope. No binding is created until the request is approved.
+func (r *ApprovalMutationResolver) RequestAccess(ctx context.Context, input models.RequestAccessInput) (models.OperationResult, error) {
+	r = &ApprovalMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	principalID, err := helpers.GetPrincipalID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	tenantID, err := helpers.GetTenantID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid tenant ID", err)
+	}
+
+	if strings.TrimSpace(input.Justification) == "" {
+		return handleError("400", "Invalid input", errors.New("justification is required"))
+	}
+	if input.Duration != nil && *input.Duration <= 0 {
+		return handleError("400", "Invalid input", errors.New("duration must be positive"))
+	}
+	if err := roleExists(r.DB, input.RoleID); err != nil {
+		return roleError(err)
+	}
+	if err := scopeExists(r.DB, input.ScopeID); err != nil {
+		if errors.Is(err, ErrScopeNotFound) {
+			return handleError("404", "Scope not found", err)
+		}
+		return handleError("500", "Error fetching scope", err)
+	}
+
+	policy, err := getPolicy(r.DB, input.RoleID)
+	if err != nil {
+		if errors.Is(err, ErrNoApprovalPolicy) {
+			return handleError("400", "Role cannot be requested", err)
+		}
+		return handleError("500", "Error fetching approval policy", err)
+	}
+	if policy.MaxDurationSeconds != nil {
+		if input.Duration == nil {
+			return handleError("400", "Invalid input", errors.New("duration is required for this role"))
+		}
+		if *input.Duration > *policy.MaxDurationSeconds {
+			return handleError("400", "Invalid input", ErrDurationExceeded)
+		}
+	}
+
+	held, err := bindings.HoldsRole(r.DB, *principalID, input.RoleID, input.ScopeID)
+	if err != nil {
+		return handleError("500", "Error checking existing bindings", err)
+	}
+	if held {
+		return handleError("409", "Role already granted", ErrAlreadyGranted)
+	}
+	var pending int64
+	if err := r.DB.Model(&dto.AccessRequest{}).
+		Where("requester_id = ? AND role_id = ? AND scope_id = ? AND status = ?", *principalID, input.RoleID, input.ScopeID, dto.AccessRequestStatusPending).
+		Count(&pending).Error; err != nil {
+		return handleError("500", "Error checking access requests", err)
+	}
+	if pending > 0 {
+		return handleError("409", "Access request already exists", ErrAlreadyRequested)
+	}
+
+	request := dto.AccessRequest{
+		RequestID:       uuid.New(),
+		TenantID:        tenantID,
+		RequesterID:     *principalID,
+		RoleID:          input.RoleID,
+		ScopeID:         input.ScopeID,
+		Justification:   input.Justification,
+		DurationSeconds: input.Duration,
+		Status:          dto.AccessRequestStatusPending,
+	}
+	if err := r.DB.Create(&request).Error; err != nil {
+		return handleError("500", "Error creating access request", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToRequest(&request, nil)})
+}
+
+// ApproveAccessRequest records the current user's approval. Once the policy's
+// required approvals are reached the binding is created, expiring after the
+// requested duration.
+func (r *ApprovalMutationResolver) ApproveAccessRequest(ctx context.Context, input models.AccessRequestDecisionInput) (models.OperationResult, error) {
+	return r.decide(ctx, input, dto.ApprovalDecisionApproved)
+}
+
+// DenyAccessRequest records the current user's denial, which closes the request.
+func (r *ApprovalMutationResolver) DenyAccessRequest(ctx context.Context, input models.AccessRequestDecisionInput) (models.OperationResult, error) {
+	return r.decide(ctx, input, dto.ApprovalDecisionDenied)
+}
+
+func (r *ApprovalMutationResolver) decide(ctx context.Context, input models.AccessRequestDecisionInput, decision string) (models.OperationResult, error) {
+	r = &ApprovalMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	principalID, err := helpers.GetPrincipalID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	request, err := getRequest(r.DB, input.ID)
+	if err != nil {
+		if errors.Is(err, ErrRequestNotFound) {
+			return handleError("404", "Access request not found", err)
+		}
+		return handleError("500", "Error fetching access request", err)
+	}
+	if request.Status != dto.AccessRequestStatusPending {
+		return handleError("409", "Access request already decided", ErrRequestDecided)
+	}
+	// Impersonators cannot decide on their own requests as someone else
+	if request.RequesterID == *principalID || request.RequesterID == *userID {
+		return handleError("403", "Not allowed to decide on this request", ErrSelfApproval)
+	}
+
+	policy, err := getPolicy(r.DB, request.RoleID)
+	if err != nil {
+		if errors.Is(err, ErrNoApprovalPolicy) {
+			return handleError("409", "Role cannot be requested", err)
+		}
+		return handleError("500", "Error fetching approval policy", err)
+	}
+	allowed, err := canApprove(r.DB, policy, request, *principalID)
+	if err != nil {
+		return handleError("500", "Error checking approver", err)
+	}
+	if !allowed {
+		return handleError("403", "Not allowed to decide on this request", ErrNotApprover)
+	}
+
+	decisions, err := getDecisions(r.DB, request.RequestID)
+	if err != nil {
+		return handleError("500", "Error fetching access request decisions", err)
+	}
+	approvals := 0
+	for _, existing := range decisions {
+		// An actor decides once, whoever they decide as
+		decided := existing.ApproverID == *principalID || existing.ActorID == *userID ||
+			existing.ApproverID == *userID || existing.ActorID == *principalID
+		if decided {
+			return handleError("409", "Decision already recorded", ErrAlreadyDecided)
+		}
+		if existing.Decision == dto.ApprovalDecisionApproved {
+			approvals++
+		}
+	}
+
+	record := dto.AccessRequestDecision{
+		DecisionID: uuid.New(),
+		RequestID:  request.RequestID,
+		ApproverID: *principalID,
+		ActorID:    *userID,
+		Decision:   decision,
+	}
+	if input.Comment != nil {
+		record.Comment = *input.Comment
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	switch {
+	case decision == dto.ApprovalDecisionDenied:
+		updates["status"] = dto.AccessRequestStatusDenied
+		updates["decided_at"] = now
+	case approvals+1 >= policy.RequiredApprovals:
+		grant := bindings.Grant{
+			Name:        "access-request",
+			PrincipalID: request.RequesterID,
+			RoleID:      request.RoleID,
+			TenantID:    request.TenantID,
+			ScopeID:     &request.ScopeID,
+		}
+		if request.DurationSeconds != nil {
+			expiresAt := now.Add(time.Duration(*request.DurationSeconds) * time.Second)
+			grant.ExpiresAt = &expiresAt
+		}
+		binding, err := bindings.Create(ctx, r.DB, r.permitClient(), grant, *userID)
+		if err != nil {
+			return handleError("500", "Error creating binding", err)
+		}
+		updates["status"] = dto.AccessRequestStatusApproved
+		updates["decided_at"] = now
+		updates["binding_id"] = binding.BindingID
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to record decision: %w", err)
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		result := tx.Model(&dto.AccessRequest{}).
+			Where("request_id = ? AND status = ?", request.RequestID, dto.AccessRequestStatusPending).
+			Updates(updates)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update access request: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrRequestDecided
+		}
+		return nil
+	})
+	if err != nil {
+		if bindingID, ok := updates["binding_id"].(uuid.UUID); ok {
+			if revokeErr := bindings.Revoke(ctx, r.DB, r.permitClient(), bindingID, *userID); revokeErr != nil {
+				err = errors.Join(err, revokeErr)
+			}
+		}
+		if errors.Is(err, ErrRequestDecided) {
+			return handleError("409", "Access request already decided", err)
+		}
+		return handleError("500", "Error recording decision", err)
+	}
+
+	request, err = getRequest(r.DB, request.RequestID)
+	if err != nil {
+		return handleError("500", "Error fetching access request", err)
+	}
+	decisions, err = getDecisions(r.DB, request.RequestID)
+	if err != nil {
+		return handleError("500", "Error fetching access request decisions", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToRequest(request, decisions)})
+}
+
+func roleError(err error) (models.OperationResult, error) {
+	if errors.Is(err, ErrRoleNotFound) {
+		return handleError("404", "Role not found", err)
+	}
+	return handleError("500", "Error fetching role", err)
+}
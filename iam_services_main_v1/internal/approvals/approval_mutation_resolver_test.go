@@ -0,0 +1,331 @@
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("Failed to register tenancy plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.TenantResource{}, &dto.TenantRoleAssignments{}, &dto.TNTRole{},
+		&dto.ApprovalPolicy{}, &dto.AccessRequest{}, &dto.AccessRequestDecision{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// fakePermit records the role assignments created through the Permit facts API.
+type fakePermit struct {
+	mu     sync.Mutex
+	assign []map[string]interface{}
+}
+
+func (f *fakePermit) client(t *testing.T) *permit.PermitClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPost && req.URL.Path == "/v2/facts/proj/env/role_assignments" {
+			var body map[string]interface{}
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			f.mu.Lock()
+			f.assign = append(f.assign, body)
+			f.mu.Unlock()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+}
+
+func userContext(userID, tenantID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	ginCtx.Set("tenantID", tenantID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+// impersonationContext is a request by actorID acting as userID.
+func impersonationContext(actorID, userID, tenantID uuid.UUID) context.Context {
+	ctx := userContext(actorID, tenantID)
+	ctx.Value("GinContextKey").(*gin.Context).Set("impersonatedUserID", userID.String())
+	return ctx
+}
+
+type fixture struct {
+	db          *gorm.DB
+	permit      *fakePermit
+	mutations   *ApprovalMutationResolver
+	tenantID    uuid.UUID
+	roleID      uuid.UUID
+	adminRoleID uuid.UUID
+	admin       uuid.UUID
+	approver    uuid.UUID
+}
+
+// newFixture seeds a tenant with a requestable role whose policy needs two
+// approvals from a named approver or a TenantAdmin of the tenant.
+func newFixture(t *testing.T) *fixture {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	fp := &fakePermit{}
+	f := &fixture{
+		db: db, permit: fp, mutations: &ApprovalMutationResolver{DB: db, PC: fp.client(t)},
+		tenantID: uuid.New(), roleID: uuid.New(), adminRoleID: uuid.New(), admin: uuid.New(), approver: uuid.New(),
+	}
+	f.createTenant(t, f.tenantID)
+	f.createRole(t, f.tenantID, f.roleID, "Operator")
+	f.createRole(t, f.tenantID, f.adminRoleID, "TenantAdmin")
+	require.NoError(t, db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID)).Create(&dto.TenantRoleAssignments{
+		ResourceID: uuid.New(), Name: "admin", Version: "V1", PrincipalID: f.admin, RoleID: f.adminRoleID,
+		TenantID: &f.tenantID, ScopeID: &f.tenantID, RowStatus: 1,
+	}).Error)
+
+	required, maxDuration := 2, 7200
+	result, err := f.mutations.SetApprovalPolicy(userContext(f.admin, f.tenantID), models.SetApprovalPolicyInput{
+		RoleID: f.roleID, ApproverRoleID: &f.adminRoleID, ApproverIds: []uuid.UUID{f.approver},
+		RequiredApprovals: &required, MaxDuration: &maxDuration,
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	return f
+}
+
+// createTenant stores a tenant resource, which belongs to itself.
+func (f *fixture) createTenant(t *testing.T, tenantID uuid.UUID) {
+	require.NoError(t, f.db.WithContext(tenancy.WithTenant(context.Background(), tenantID)).
+		Create(&dto.TenantResource{ResourceID: tenantID, ResourceTypeID: uuid.New(), Name: "tenant", TenantID: &tenantID, RowStatus: 1}).Error)
+}
+
+// createRole stores a role of tenantID.
+func (f *fixture) createRole(t *testing.T, tenantID, roleID uuid.UUID, name string) {
+	ctx := tenancy.WithTenant(context.Background(), tenantID)
+	require.NoError(t, f.db.WithContext(ctx).Create(&dto.TenantResource{ResourceID: roleID, ResourceTypeID: uuid.New(), Name: name, TenantID: &tenantID, RowStatus: 1}).Error)
+	require.NoError(t, f.db.WithContext(ctx).Create(&dto.TNTRole{ResourceID: roleID, Name: name, RowStatus: 1}).Error)
+}
+
+func (f *fixture) request(t *testing.T, requester uuid.UUID, duration int) *models.AccessRequest {
+	result, err := f.mutations.RequestAccess(userContext(requester, f.tenantID), models.RequestAccessInput{
+		RoleID: f.roleID, ScopeID: f.tenantID, Justification: "incident follow-up", Duration: &duration,
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	return result.(*models.SuccessResponse).Data[0].(*models.AccessRequest)
+}
+
+func errorCode(t *testing.T, result models.OperationResult) string {
+	require.IsType(t, &models.ResponseError{}, result)
+	return result.(*models.ResponseError).ErrorCode
+}
+
+func TestAccessRequestApproval(t *testing.T) {
+	f := newFixture(t)
+	requester := uuid.New()
+	request := f.request(t, requester, 3600)
+	assert.Equal(t, models.AccessRequestStatusPending, request.Status)
+
+	// A second pending request for the same role and scope is rejected
+	duration := 60
+	result, err := f.mutations.RequestAccess(userContext(requester, f.tenantID), models.RequestAccessInput{
+		RoleID: f.roleID, ScopeID: f.tenantID, Justification: "again", Duration: &duration,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "409", errorCode(t, result))
+
+	// Requesters and users outside the policy cannot decide
+	result, err = f.mutations.ApproveAccessRequest(userContext(requester, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "403", errorCode(t, result))
+	result, err = f.mutations.ApproveAccessRequest(userContext(uuid.New(), f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "403", errorCode(t, result))
+
+	// The first approval, by a TenantAdmin holder, leaves the request pending
+	result, err = f.mutations.ApproveAccessRequest(userContext(f.admin, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	pending := result.(*models.SuccessResponse).Data[0].(*models.AccessRequest)
+	assert.Equal(t, models.AccessRequestStatusPending, pending.Status)
+	assert.Len(t, pending.Decisions, 1)
+	assert.Empty(t, f.permit.assign)
+
+	result, err = f.mutations.ApproveAccessRequest(userContext(f.admin, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "409", errorCode(t, result))
+
+	// The second approval creates the expiring binding
+	comment := "approved for the incident"
+	result, err = f.mutations.ApproveAccessRequest(userContext(f.approver, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID, Comment: &comment})
+	require.NoError(t, err)
+	approved := result.(*models.SuccessResponse).Data[0].(*models.AccessRequest)
+	assert.Equal(t, models.AccessRequestStatusApproved, approved.Status)
+	require.NotNil(t, approved.BindingID)
+	require.NotNil(t, approved.DecidedAt)
+	assert.Equal(t, comment, *approved.Decisions[1].Comment)
+
+	require.Len(t, f.permit.assign, 1)
+	assert.Equal(t, map[string]interface{}{
+		"user":   requester.String(),
+		"role":   f.roleID.String(),
+		"tenant": f.tenantID.String(),
+	}, f.permit.assign[0])
+
+	binding, err := bindings.GetAssignment(f.db, *approved.BindingID)
+	require.NoError(t, err)
+	assert.Equal(t, requester, binding.PrincipalID)
+	require.NotNil(t, binding.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *binding.ExpiresAt, time.Minute)
+
+	held, err := bindings.HoldsRole(f.db, requester, f.roleID, f.tenantID)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	// Holders cannot request the role again
+	result, err = f.mutations.RequestAccess(userContext(requester, f.tenantID), models.RequestAccessInput{
+		RoleID: f.roleID, ScopeID: f.tenantID, Justification: "again", Duration: &duration,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "409", errorCode(t, result))
+}
+
+func TestAccessRequestDenial(t *testing.T) {
+	f := newFixture(t)
+	request := f.request(t, uuid.New(), 600)
+
+	// Impersonators decide as the impersonated approver
+	result, err := f.mutations.DenyAccessRequest(impersonationContext(uuid.New(), f.approver, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	denied := result.(*models.SuccessResponse).Data[0].(*models.AccessRequest)
+	assert.Equal(t, models.AccessRequestStatusDenied, denied.Status)
+	assert.Nil(t, denied.BindingID)
+	assert.Equal(t, f.approver, denied.Decisions[0].ApproverID)
+	assert.NotEqual(t, f.approver, denied.Decisions[0].ActorID)
+
+	result, err = f.mutations.ApproveAccessRequest(userContext(f.admin, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "409", errorCode(t, result))
+	assert.Empty(t, f.permit.assign)
+
+	queries := &ApprovalQueryResolver{DB: f.db}
+	status := models.AccessRequestStatusDenied
+	result, err = queries.AccessRequests(userContext(f.admin, f.tenantID), &status)
+	require.NoError(t, err)
+	assert.Len(t, result.(*models.SuccessResponse).Data, 1)
+}
+
+func TestImpersonatorsDecideAsThemselves(t *testing.T) {
+	f := newFixture(t)
+	requester := uuid.New()
+	request := f.request(t, requester, 600)
+
+	// Requesters cannot approve their own request by impersonating an approver
+	result, err := f.mutations.ApproveAccessRequest(impersonationContext(requester, f.approver, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "403", errorCode(t, result))
+
+	// Approvers cannot approve twice, as themselves and as another approver
+	result, err = f.mutations.ApproveAccessRequest(userContext(f.admin, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	pending := result.(*models.SuccessResponse).Data[0].(*models.AccessRequest)
+	assert.Equal(t, f.admin, pending.Decisions[0].ActorID)
+	result, err = f.mutations.ApproveAccessRequest(impersonationContext(f.admin, f.approver, f.tenantID), models.AccessRequestDecisionInput{ID: request.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "409", errorCode(t, result))
+	assert.Empty(t, f.permit.assign)
+}
+
+func TestRequestAccessValidation(t *testing.T) {
+	f := newFixture(t)
+	ctx := userContext(uuid.New(), f.tenantID)
+
+	tooLong := 7201
+	result, err := f.mutations.RequestAccess(ctx, models.RequestAccessInput{
+		RoleID: f.roleID, ScopeID: f.tenantID, Justification: "need it", Duration: &tooLong,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "400", errorCode(t, result))
+
+	result, err = f.mutations.RequestAccess(ctx, models.RequestAccessInput{
+		RoleID: f.roleID, ScopeID: f.tenantID, Justification: "need it",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "400", errorCode(t, result))
+
+	duration := 60
+	result, err = f.mutations.RequestAccess(ctx, models.RequestAccessInput{
+		RoleID: f.adminRoleID, ScopeID: f.tenantID, Justification: "need it", Duration: &duration,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "400", errorCode(t, result), "roles without a policy cannot be requested")
+
+	result, err = f.mutations.RequestAccess(ctx, models.RequestAccessInput{
+		RoleID: f.roleID, ScopeID: uuid.New(), Justification: "need it", Duration: &duration,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "404", errorCode(t, result))
+}
+
+func TestApprovalPoliciesAreTenantScoped(t *testing.T) {
+	f := newFixture(t)
+	otherTenant, otherRole := uuid.New(), uuid.New()
+	f.createTenant(t, otherTenant)
+	f.createRole(t, otherTenant, otherRole, "Operator")
+	admin := userContext(f.admin, f.tenantID)
+
+	// Roles of other tenants cannot be given a policy
+	result, err := f.mutations.SetApprovalPolicy(admin, models.SetApprovalPolicyInput{RoleID: otherRole, ApproverIds: []uuid.UUID{f.approver}})
+	require.NoError(t, err)
+	assert.Equal(t, "404", errorCode(t, result))
+
+	// Nor can they be requested against the policy of this tenant
+	duration := 60
+	result, err = f.mutations.RequestAccess(userContext(uuid.New(), otherTenant), models.RequestAccessInput{
+		RoleID: f.roleID, ScopeID: otherTenant, Justification: "need it", Duration: &duration,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "404", errorCode(t, result))
+
+	queries := &ApprovalQueryResolver{DB: f.db}
+	result, err = queries.ApprovalPolicies(userContext(f.admin, otherTenant))
+	require.NoError(t, err)
+	assert.Empty(t, result.(*models.SuccessResponse).Data)
+
+	// Setting a deleted policy again revives it
+	result, err = queries.ApprovalPolicies(admin)
+	require.NoError(t, err)
+	require.Len(t, result.(*models.SuccessResponse).Data, 1)
+	policy := result.(*models.SuccessResponse).Data[0].(*models.ApprovalPolicy)
+	result, err = f.mutations.DeleteApprovalPolicy(admin, models.DeleteInput{ID: policy.ID})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	result, err = f.mutations.SetApprovalPolicy(admin, models.SetApprovalPolicyInput{RoleID: f.roleID, ApproverIds: []uuid.UUID{f.approver}})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	assert.Equal(t, policy.ID, result.(*models.SuccessResponse).Data[0].(*models.ApprovalPolicy).ID)
+
+	var stored []dto.ApprovalPolicy
+	require.NoError(t, f.db.Scopes(tenancy.WithoutTenantScope).Find(&stored).Error)
+	require.Len(t, stored, 1)
+	assert.Equal(t, &f.tenantID, stored[0].TenantID)
+}
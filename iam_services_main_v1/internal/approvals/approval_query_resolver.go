@@ -0,0 +1,83 @@
+package approvals
+
+import (
+	"context"
+	"errors"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ApprovalQueryResolver handles access request and approval policy queries.
+type ApprovalQueryResolver struct {
+	DB *gorm.DB
+}
+
+// AccessRequests lists the access requests of the current tenant, newest first.
+func (r *ApprovalQueryResolver) AccessRequests(ctx context.Context, status *models.AccessRequestStatus) (models.OperationResult, error) {
+	r = &ApprovalQueryResolver{DB: tenancy.ForRequest(ctx, r.DB)}
+	tenantID, err := helpers.GetTenantID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid tenant ID", err)
+	}
+
+	query := r.DB.Where("tenant_id = ?", tenantID)
+	if status != nil {
+		query = query.Where("status = ?", status.String())
+	}
+	var requests []dto.AccessRequest
+	if err := query.Order("created_at DESC").Find(&requests).Error; err != nil {
+		return handleError("500", "Error fetching access requests", err)
+	}
+
+	data := make([]models.Data, 0, len(requests))
+	for i := range requests {
+		decisions, err := getDecisions(r.DB, requests[i].RequestID)
+		if err != nil {
+			return handleError("500", "Error fetching access request decisions", err)
+		}
+		data = append(data, mapToRequest(&requests[i], decisions))
+	}
+	return utils.FormatSuccess(data)
+}
+
+// AccessRequest resolves a single access request with its decisions.
+func (r *ApprovalQueryResolver) AccessRequest(ctx context.Context, id uuid.UUID) (models.OperationResult, error) {
+	r = &ApprovalQueryResolver{DB: tenancy.ForRequest(ctx, r.DB)}
+	request, err := getRequest(r.DB, id)
+	if err != nil {
+		if errors.Is(err, ErrRequestNotFound) {
+			return handleError("404", "Access request not found", err)
+		}
+		return handleError("500", "Error fetching access request", err)
+	}
+	decisions, err := getDecisions(r.DB, id)
+	if err != nil {
+		return handleError("500", "Error fetching access request decisions", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToRequest(request, decisions)})
+}
+
+// ApprovalPolicies lists every active approval policy.
+func (r *ApprovalQueryResolver) ApprovalPolicies(ctx context.Context) (models.OperationResult, error) {
+	r = &ApprovalQueryResolver{DB: tenancy.ForRequest(ctx, r.DB)}
+	var policies []dto.ApprovalPolicy
+	if err := r.DB.Where("row_status = 1").Order("created_at").Find(&policies).Error; err != nil {
+		return handleError("500", "Error fetching approval policies", err)
+	}
+
+	data := make([]models.Data, 0, len(policies))
+	for i := range policies {
+		policy, err := mapToPolicy(&policies[i])
+		if err != nil {
+			return handleError("500", "Error mapping approval policy", err)
+		}
+		data = append(data, policy)
+	}
+	return utils.FormatSuccess(data)
+}
@@ -0,0 +1,187 @@
+package approvals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPolicyNotFound    = errors.New("approval policy not found")
+	ErrNoApprovalPolicy  = errors.New("role has no approval policy")
+	ErrRequestNotFound   = errors.New("access request not found")
+	ErrRequestDecided    = errors.New("access request has already been decided")
+	ErrAlreadyRequested  = errors.New("a pending request for this role and scope already exists")
+	ErrAlreadyGranted    = errors.New("principal already holds this role on the scope")
+	ErrSelfApproval      = errors.New("requesters cannot decide on their own requests")
+	ErrNotApprover       = errors.New("user is not an approver for this role and scope")
+	ErrAlreadyDecided    = errors.New("approver has already decided on this request")
+	ErrDurationExceeded  = errors.New("requested duration exceeds the policy maximum")
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrScopeNotFound     = errors.New("scope not found")
+	ErrInvalidPolicyRule = errors.New("policy needs approver IDs or an approver role")
+)
+
+// getPolicy returns the active approval policy of a role.
+func getPolicy(db *gorm.DB, roleID uuid.UUID) (*dto.ApprovalPolicy, error) {
+	var policy dto.ApprovalPolicy
+	if err := db.Where("role_id = ? AND row_status = 1", roleID).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoApprovalPolicy
+		}
+		return nil, fmt.Errorf("failed to fetch approval policy: %w", err)
+	}
+	return &policy, nil
+}
+
+func policyApprovers(policy *dto.ApprovalPolicy) ([]uuid.UUID, error) {
+	approvers := []uuid.UUID{}
+	if len(policy.ApproverIDs) == 0 {
+		return approvers, nil
+	}
+	if err := json.Unmarshal(policy.ApproverIDs, &approvers); err != nil {
+		return nil, fmt.Errorf("failed to decode approvers: %w", err)
+	}
+	return approvers, nil
+}
+
+// canApprove reports whether a user may decide on a request under a policy:
+// either listed as an approver or holding the approver role on the scope.
+func canApprove(db *gorm.DB, policy *dto.ApprovalPolicy, request *dto.AccessRequest, userID uuid.UUID) (bool, error) {
+	approvers, err := policyApprovers(policy)
+	if err != nil {
+		return false, err
+	}
+	for _, approver := range approvers {
+		if approver == userID {
+			return true, nil
+		}
+	}
+	if policy.ApproverRoleID == nil {
+		return false, nil
+	}
+	return bindings.HoldsRole(db, userID, *policy.ApproverRoleID, request.ScopeID)
+}
+
+func getRequest(db *gorm.DB, requestID uuid.UUID) (*dto.AccessRequest, error) {
+	var request dto.AccessRequest
+	if err := db.Where("request_id = ?", requestID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRequestNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch access request: %w", err)
+	}
+	return &request, nil
+}
+
+func getDecisions(db *gorm.DB, requestID uuid.UUID) ([]dto.AccessRequestDecision, error) {
+	var decisions []dto.AccessRequestDecision
+	if err := db.Where("request_id = ?", requestID).Order("created_at, decision_id").Find(&decisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch decisions: %w", err)
+	}
+	return decisions, nil
+}
+
+func mapToRequest(request *dto.AccessRequest, decisions []dto.AccessRequestDecision) *models.AccessRequest {
+	result := &models.AccessRequest{
+		ID:            request.RequestID,
+		TenantID:      request.TenantID,
+		RequesterID:   request.RequesterID,
+		RoleID:        request.RoleID,
+		ScopeID:       request.ScopeID,
+		Justification: request.Justification,
+		Duration:      request.DurationSeconds,
+		Status:        models.AccessRequestStatus(request.Status),
+		BindingID:     request.BindingID,
+		CreatedAt:     request.CreatedAt.Format(time.RFC3339),
+		Decisions:     make([]*models.AccessRequestDecision, 0, len(decisions)),
+	}
+	if request.DecidedAt != nil {
+		decidedAt := request.DecidedAt.Format(time.RFC3339)
+		result.DecidedAt = &decidedAt
+	}
+	for _, decision := range decisions {
+		mapped := &models.AccessRequestDecision{
+			ApproverID: decision.ApproverID,
+			ActorID:    decision.ActorID,
+			Decision:   decision.Decision,
+			CreatedAt:  decision.CreatedAt.Format(time.RFC3339),
+		}
+		if decision.Comment != "" {
+			comment := decision.Comment
+			mapped.Comment = &comment
+		}
+		result.Decisions = append(result.Decisions, mapped)
+	}
+	return result
+}
+
+func mapToPolicy(policy *dto.ApprovalPolicy) (*models.ApprovalPolicy, error) {
+	approvers, err := policyApprovers(policy)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ApprovalPolicy{
+		ID:                policy.PolicyID,
+		RoleID:            policy.RoleID,
+		ApproverRoleID:    policy.ApproverRoleID,
+		ApproverIds:       approvers,
+		RequiredApprovals: policy.RequiredApprovals,
+		MaxDuration:       policy.MaxDurationSeconds,
+		CreatedAt:         policy.CreatedAt.Format(time.RFC3339),
+		CreatedBy:         policy.CreatedBy,
+		UpdatedAt:         policy.UpdatedAt.Format(time.RFC3339),
+		UpdatedBy:         policy.UpdatedBy,
+	}, nil
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
+
+func roleExists(db *gorm.DB, roleID uuid.UUID) error {
+	_, err := roleTenant(db, roleID)
+	return err
+}
+
+// roleTenant returns the tenant of an active role. Roles belong to the tenant
+// of their tnt_resources row, so roles of other tenants are not found.
+func roleTenant(db *gorm.DB, roleID uuid.UUID) (*uuid.UUID, error) {
+	var count int64
+	if err := db.Model(&dto.TNTRole{}).Where("resource_id = ? AND row_status = 1", roleID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch role: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrRoleNotFound
+	}
+	var resource dto.TenantResource
+	if err := db.Where("resource_id = ? AND row_status = 1", roleID).First(&resource).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch role: %w", err)
+	}
+	return resource.TenantID, nil
+}
+
+func scopeExists(db *gorm.DB, scopeID uuid.UUID) error {
+	var count int64
+	if err := db.Model(&dto.TenantResource{}).Where("resource_id = ? AND row_status = 1", scopeID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to fetch scope: %w", err)
+	}
+	if count == 0 {
+		return ErrScopeNotFound
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	OutcomeSuccess = "SUCCESS"
+	OutcomeFailure = "FAILURE"
+)
+
+// highSeverityOperations are exported to SIEM sinks at the highest severity
+// whatever their outcome.
+var highSeverityOperations = map[string]bool{
+	"breakGlass":        true,
+	"breakGlassExpired": true,
+}
+
+// IsHighSeverity reports whether operation is a high-severity operation.
+func IsHighSeverity(operation string) bool {
+	return highSeverityOperations[operation]
+}
+
+// GenesisHash is the previous hash of the first event in the chain.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// maxAppendAttempts bounds the retries when another writer extends the chain
+// between reading its head and inserting the new event.
+const maxAppendAttempts = 5
+
+// appendMu serialises appends within this process; the unique index on
+// prev_hash catches races between processes.
+var appendMu sync.Mutex
+
+// Entry describes a mutation to record in the audit log.
+type Entry struct {
+	ActorID string
+	// ImpersonatedUserID is set when ActorID acted as another user.
+	ImpersonatedUserID string
+	TenantID           string
+	Operation          string
+	TargetResourceID   string
+	Before             string
+	After              string
+	RequestID          string
+	ClientIP           string
+	Outcome            string
+	ErrorMessage       string
+}
+
+// hashedFields is the canonical content covered by an event hash. Its layout
+// must never change, otherwise existing chains stop verifying; fields added
+// later are omitted when empty so earlier events hash as before.
+type hashedFields struct {
+	EventID            string `json:"eventId"`
+	OccurredAt         string `json:"occurredAt"`
+	ActorID            string `json:"actorId"`
+	TenantID           string `json:"tenantId"`
+	Operation          string `json:"operation"`
+	TargetResourceID   string `json:"targetResourceId"`
+	Before             string `json:"before"`
+	After              string `json:"after"`
+	RequestID          string `json:"requestId"`
+	ClientIP           string `json:"clientIp"`
+	Outcome            string `json:"outcome"`
+	ErrorMessage       string `json:"errorMessage"`
+	PrevHash           string `json:"prevHash"`
+	ImpersonatedUserID string `json:"impersonatedUserId,omitempty"`
+}
+
+// Append records an entry at the head of the hash chain.
+func Append(db *gorm.DB, entry Entry) (*dto.AuditEvent, error) {
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	// The chain links the events of every tenant
+	db = tenancy.RootSession(db)
+
+	var lastErr error
+	for attempt := 0; attempt < maxAppendAttempts; attempt++ {
+		event, err := appendOnce(db, entry)
+		if err == nil {
+			return event, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("failed to append audit event: %w", lastErr)
+}
+
+func appendOnce(db *gorm.DB, entry Entry) (*dto.AuditEvent, error) {
+	var event dto.AuditEvent
+	err := db.Transaction(func(tx *gorm.DB) error {
+		prevHash := GenesisHash
+		var head dto.AuditEvent
+		err := tx.Order("sequence DESC").First(&head).Error
+		switch {
+		case err == nil:
+			prevHash = head.Hash
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return fmt.Errorf("failed to fetch chain head: %w", err)
+		}
+
+		event = dto.AuditEvent{
+			EventID:            uuid.New().String(),
+			OccurredAt:         time.Now().UTC().Truncate(time.Millisecond),
+			ActorID:            entry.ActorID,
+			ImpersonatedUserID: entry.ImpersonatedUserID,
+			TenantID:           entry.TenantID,
+			Operation:          entry.Operation,
+			TargetResourceID:   entry.TargetResourceID,
+			Before:             entry.Before,
+			After:              entry.After,
+			RequestID:          entry.RequestID,
+			ClientIP:           entry.ClientIP,
+			Outcome:            entry.Outcome,
+			ErrorMessage:       entry.ErrorMessage,
+			PrevHash:           prevHash,
+		}
+		hash, err := ComputeHash(event)
+		if err != nil {
+			return err
+		}
+		event.Hash = hash
+		return tx.Create(&event).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// ComputeHash returns the hex encoded SHA-256 hash of an event's content and
+// its previous hash.
+func ComputeHash(event dto.AuditEvent) (string, error) {
+	payload, err := json.Marshal(hashedFields{
+		EventID:            event.EventID,
+		OccurredAt:         event.OccurredAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+		ActorID:            event.ActorID,
+		TenantID:           event.TenantID,
+		Operation:          event.Operation,
+		TargetResourceID:   event.TargetResourceID,
+		Before:             event.Before,
+		After:              event.After,
+		RequestID:          event.RequestID,
+		ClientIP:           event.ClientIP,
+		Outcome:            event.Outcome,
+		ErrorMessage:       event.ErrorMessage,
+		PrevHash:           event.PrevHash,
+		ImpersonatedUserID: event.ImpersonatedUserID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verification is the result of walking the hash chain.
+type Verification struct {
+	Valid            bool
+	CheckedEvents    int
+	BrokenAtSequence *uint64
+}
+
+// verifyBatchSize is the number of events loaded per query while verifying.
+const verifyBatchSize = 500
+
+// Verify walks the whole chain in sequence order and reports the first event
+// whose content or link to its predecessor does not match its hash.
+func Verify(db *gorm.DB) (*Verification, error) {
+	db = tenancy.RootSession(db)
+	result := &Verification{Valid: true}
+	prevHash := GenesisHash
+	var after uint64
+
+	for {
+		var batch []dto.AuditEvent
+		if err := db.Where("sequence > ?", after).Order("sequence ASC").Limit(verifyBatchSize).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch audit events: %w", err)
+		}
+		for _, event := range batch {
+			result.CheckedEvents++
+			hash, err := ComputeHash(event)
+			if err != nil {
+				return nil, err
+			}
+			if event.PrevHash != prevHash || event.Hash != hash {
+				sequence := event.Sequence
+				result.Valid = false
+				result.BrokenAtSequence = &sequence
+				return result, nil
+			}
+			prevHash = event.Hash
+			after = event.Sequence
+		}
+		if len(batch) < verifyBatchSize {
+			return result, nil
+		}
+	}
+}
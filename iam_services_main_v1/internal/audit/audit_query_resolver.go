@@ -0,0 +1,196 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// AuditQueryResolver handles audit log queries.
+type AuditQueryResolver struct {
+	DB *gorm.DB
+}
+
+// AuditEvents returns a page of audit events matching filter, newest first.
+// The after cursor is the endCursor of the previous page. Only the events of
+// the request tenant are returned unless the caller holds a global binding.
+func (r *AuditQueryResolver) AuditEvents(ctx context.Context, filter *models.AuditEventFilter, first *int, after *string) (models.OperationResult, error) {
+	limit := defaultPageSize
+	if first != nil {
+		if *first < 1 || *first > maxPageSize {
+			return handleError("400", "Invalid page size", fmt.Errorf("first must be between 1 and %d", maxPageSize))
+		}
+		limit = *first
+	}
+
+	principalID, err := helpers.GetPrincipalID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	global, err := bindings.HoldsGlobalBinding(r.DB.WithContext(ctx), *principalID)
+	if err != nil {
+		return handleError("500", "Error fetching bindings", err)
+	}
+	db := tenancy.RootSession(r.DB.WithContext(ctx))
+	if !global {
+		if _, err := helpers.GetTenantID(ctx); err != nil {
+			return handleError("400", "Invalid tenant ID", err)
+		}
+		db = tenancy.ForRequest(ctx, r.DB)
+	}
+	query, err := applyFilter(db.Model(&dto.AuditEvent{}), filter)
+	if err != nil {
+		return handleError("400", "Invalid filter", err)
+	}
+	if after != nil && *after != "" {
+		sequence, err := decodeCursor(*after)
+		if err != nil {
+			return handleError("400", "Invalid cursor", err)
+		}
+		query = query.Where("sequence < ?", sequence)
+	}
+
+	var events []dto.AuditEvent
+	if err := query.Order("sequence DESC").Limit(limit + 1).Find(&events).Error; err != nil {
+		return handleError("500", "Error fetching audit events", err)
+	}
+
+	page := &models.AuditEventPage{Events: []*models.AuditEvent{}}
+	if len(events) > limit {
+		page.HasNextPage = true
+		events = events[:limit]
+	}
+	for _, event := range events {
+		page.Events = append(page.Events, mapToAuditEvent(event))
+	}
+	if len(events) > 0 {
+		cursor := encodeCursor(events[len(events)-1].Sequence)
+		page.EndCursor = &cursor
+	}
+	return utils.FormatSuccess([]models.Data{page})
+}
+
+// VerifyAuditChain checks that no audit event has been altered or removed.
+func (r *AuditQueryResolver) VerifyAuditChain(ctx context.Context) (models.OperationResult, error) {
+	verification, err := Verify(r.DB)
+	if err != nil {
+		return handleError("500", "Error verifying audit chain", err)
+	}
+
+	result := &models.AuditChainVerification{
+		Valid:         verification.Valid,
+		CheckedEvents: verification.CheckedEvents,
+	}
+	if verification.BrokenAtSequence != nil {
+		sequence := int(*verification.BrokenAtSequence)
+		result.BrokenAtSequence = &sequence
+	}
+	return utils.FormatSuccess([]models.Data{result})
+}
+
+// Helper Functions
+
+func applyFilter(query *gorm.DB, filter *models.AuditEventFilter) (*gorm.DB, error) {
+	if filter == nil {
+		return query, nil
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", filter.ActorID.String())
+	}
+	if filter.TenantID != nil {
+		query = query.Where("tenant_id = ?", filter.TenantID.String())
+	}
+	if filter.TargetResourceID != nil {
+		query = query.Where("target_resource_id = ?", filter.TargetResourceID.String())
+	}
+	if filter.Operation != nil {
+		query = query.Where("operation = ?", *filter.Operation)
+	}
+	if filter.Outcome != nil {
+		query = query.Where("outcome = ?", filter.Outcome.String())
+	}
+	if filter.From != nil {
+		from, err := time.Parse(time.RFC3339, *filter.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+		query = query.Where("occurred_at >= ?", from.UTC())
+	}
+	if filter.To != nil {
+		to, err := time.Parse(time.RFC3339, *filter.To)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to: %w", err)
+		}
+		query = query.Where("occurred_at < ?", to.UTC())
+	}
+	return query, nil
+}
+
+func mapToAuditEvent(event dto.AuditEvent) *models.AuditEvent {
+	eventID, _ := uuid.Parse(event.EventID)
+	return &models.AuditEvent{
+		ID:                 eventID,
+		Sequence:           int(event.Sequence),
+		OccurredAt:         event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		ActorID:            optional(event.ActorID),
+		ImpersonatedUserID: optional(event.ImpersonatedUserID),
+		TenantID:           optional(event.TenantID),
+		Operation:          event.Operation,
+		TargetResourceID:   optional(event.TargetResourceID),
+		Before:             optional(event.Before),
+		After:              optional(event.After),
+		RequestID:          optional(event.RequestID),
+		ClientIP:           optional(event.ClientIP),
+		Outcome:            models.AuditOutcome(event.Outcome),
+		ErrorMessage:       optional(event.ErrorMessage),
+		PrevHash:           event.PrevHash,
+		Hash:               event.Hash,
+	}
+}
+
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func encodeCursor(sequence uint64) string {
+	return base64.StdEncoding.EncodeToString([]byte(strconv.FormatUint(sequence, 10)))
+}
+
+func decodeCursor(cursor string) (uint64, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, errors.New("malformed cursor")
+	}
+	sequence, err := strconv.ParseUint(string(raw), 10, 64)
+	if err != nil {
+		return 0, errors.New("malformed cursor")
+	}
+	return sequence, nil
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
@@ -0,0 +1,272 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("Failed to register tenancy plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.AuditEvent{}, &dto.TNTRole{}, &dto.TNTRolePermission{}, &dto.MstPermission{},
+		&dto.Mst_ResourceTypes{}, &dto.TenantResource{}, &dto.TenantMetadata{}, &dto.TNTResourceLabel{}, &dto.TenantRoleAssignments{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestAppendChainsEvents(t *testing.T) {
+	db := setupTestDB(t)
+
+	first, err := Append(db, Entry{Operation: "createRole", Outcome: OutcomeSuccess})
+	require.NoError(t, err)
+	second, err := Append(db, Entry{Operation: "deleteRole", Outcome: OutcomeFailure})
+	require.NoError(t, err)
+
+	assert.Equal(t, GenesisHash, first.PrevHash)
+	assert.Equal(t, first.Hash, second.PrevHash)
+
+	verification, err := Verify(db)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 2, verification.CheckedEvents)
+}
+
+func TestAuditEventsAreImmutable(t *testing.T) {
+	db := setupTestDB(t)
+	event, err := Append(db, Entry{Operation: "createRole", Outcome: OutcomeSuccess})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, db.Model(event).Update("operation", "deleteRole").Error, dto.ErrImmutableRecord)
+	assert.ErrorIs(t, db.Delete(event).Error, dto.ErrImmutableRecord)
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	db := setupTestDB(t)
+	for i := 0; i < 3; i++ {
+		_, err := Append(db, Entry{Operation: "updateRole", Outcome: OutcomeSuccess})
+		require.NoError(t, err)
+	}
+
+	// Raw SQL bypasses the model hooks, like direct database access would
+	require.NoError(t, db.Exec("UPDATE tnt_audit_events SET actor_id = ? WHERE sequence = 2", uuid.New().String()).Error)
+
+	verification, err := Verify(db)
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+	require.NotNil(t, verification.BrokenAtSequence)
+	assert.Equal(t, uint64(2), *verification.BrokenAtSequence)
+}
+
+func TestVerifyDetectsRemovedEvent(t *testing.T) {
+	db := setupTestDB(t)
+	for i := 0; i < 3; i++ {
+		_, err := Append(db, Entry{Operation: "updateRole", Outcome: OutcomeSuccess})
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Exec("DELETE FROM tnt_audit_events WHERE sequence = 2").Error)
+
+	// The successor of the removed event no longer links to its predecessor
+	verification, err := Verify(db)
+	require.NoError(t, err)
+	assert.False(t, verification.Valid)
+	require.NotNil(t, verification.BrokenAtSequence)
+	assert.Equal(t, uint64(3), *verification.BrokenAtSequence)
+}
+
+func mutationContext(t *testing.T, name string, args map[string]interface{}, userID, tenantID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest("POST", "/graphql", nil)
+	ginCtx.Request.RemoteAddr = "10.0.0.7:5555"
+	ginCtx.Set("userID", userID.String())
+	ginCtx.Set("tenantID", tenantID.String())
+	ginCtx.Set("request_id", "req-1")
+	ctx := context.WithValue(context.Background(), "GinContextKey", ginCtx)
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Object: "Mutation",
+		Field:  graphql.CollectedField{Field: &ast.Field{Name: name}},
+		Args:   args,
+	})
+}
+
+type recordingPublisher struct{ events []dto.AuditEvent }
+
+func (p *recordingPublisher) Publish(event dto.AuditEvent) { p.events = append(p.events, event) }
+
+func TestFieldMiddlewareRecordsMutation(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	userID, tenantID, roleID := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.TNTRole{ResourceID: roleID, Name: "reader", RowStatus: 1}).Error)
+
+	ctx := mutationContext(t, "deleteRole", map[string]interface{}{"input": models.DeleteInput{ID: roleID}}, userID, tenantID)
+	publisher := &recordingPublisher{}
+	res, err := FieldMiddleware(db, publisher)(ctx, func(ctx context.Context) (interface{}, error) {
+		if err := db.Model(&dto.TNTRole{}).Where("resource_id = ?", roleID).Update("row_status", 0).Error; err != nil {
+			return nil, err
+		}
+		return &models.SuccessResponse{IsSuccess: true}, nil
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, res)
+
+	var event dto.AuditEvent
+	require.NoError(t, tenancy.RootSession(db).First(&event).Error)
+	assert.Equal(t, "deleteRole", event.Operation)
+	assert.Equal(t, OutcomeSuccess, event.Outcome)
+	assert.Equal(t, userID.String(), event.ActorID)
+	assert.Equal(t, tenantID.String(), event.TenantID)
+	assert.Equal(t, roleID.String(), event.TargetResourceID)
+	assert.Equal(t, "req-1", event.RequestID)
+	assert.Equal(t, "10.0.0.7", event.ClientIP)
+	assert.Contains(t, event.Before, `"rowStatus":1`)
+	assert.Contains(t, event.After, `"rowStatus":0`)
+
+	require.Len(t, publisher.events, 1)
+	assert.Equal(t, event.Hash, publisher.events[0].Hash)
+}
+
+func TestFieldMiddlewareRecordsFailures(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	details := "Role not found: record not found"
+	ctx := mutationContext(t, "deleteRole", map[string]interface{}{"input": models.DeleteInput{ID: uuid.New()}}, uuid.New(), uuid.New())
+
+	_, err := FieldMiddleware(db)(ctx, func(ctx context.Context) (interface{}, error) {
+		return &models.ResponseError{Message: "Role not found", ErrorCode: "404", ErrorDetails: &details}, nil
+	})
+	require.NoError(t, err)
+
+	var event dto.AuditEvent
+	require.NoError(t, tenancy.RootSession(db).First(&event).Error)
+	assert.Equal(t, OutcomeFailure, event.Outcome)
+	assert.Equal(t, details, event.ErrorMessage)
+	assert.Empty(t, event.Before)
+}
+
+func TestFieldMiddlewareRecordsImpersonation(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	actorID, targetID := uuid.New(), uuid.New()
+	ctx := mutationContext(t, "deleteRole", map[string]interface{}{"input": models.DeleteInput{ID: uuid.New()}}, actorID, uuid.New())
+	ginCtx, err := helpers.GetGinContext(ctx)
+	require.NoError(t, err)
+	ginCtx.Set("impersonatedUserID", targetID.String())
+
+	_, err = FieldMiddleware(db)(ctx, func(ctx context.Context) (interface{}, error) {
+		return &models.SuccessResponse{IsSuccess: true}, nil
+	})
+	require.NoError(t, err)
+
+	var event dto.AuditEvent
+	require.NoError(t, tenancy.RootSession(db).First(&event).Error)
+	assert.Equal(t, actorID.String(), event.ActorID)
+	assert.Equal(t, targetID.String(), event.ImpersonatedUserID)
+
+	verification, err := Verify(db)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+}
+
+// queryContext returns the context of a query by userID in tenantID.
+func queryContext(userID, tenantID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	ginCtx.Set("tenantID", tenantID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func TestAuditEventsPagination(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	actorID, tenantID := uuid.New(), uuid.New()
+	var sequences []int
+	for i := 0; i < 5; i++ {
+		event, err := Append(db, Entry{ActorID: actorID.String(), TenantID: tenantID.String(), Operation: "updateRole", Outcome: OutcomeSuccess})
+		require.NoError(t, err)
+		sequences = append(sequences, int(event.Sequence))
+	}
+	_, err := Append(db, Entry{ActorID: uuid.New().String(), TenantID: tenantID.String(), Operation: "updateRole", Outcome: OutcomeSuccess})
+	require.NoError(t, err)
+
+	resolver := &AuditQueryResolver{DB: db}
+	filter := &models.AuditEventFilter{ActorID: &actorID}
+	first := 3
+	ctx := queryContext(actorID, tenantID)
+
+	result, err := resolver.AuditEvents(ctx, filter, &first, nil)
+	require.NoError(t, err)
+	page := result.(*models.SuccessResponse).Data[0].(*models.AuditEventPage)
+	require.Len(t, page.Events, 3)
+	assert.True(t, page.HasNextPage)
+	assert.Equal(t, sequences[4], page.Events[0].Sequence)
+
+	result, err = resolver.AuditEvents(ctx, filter, &first, page.EndCursor)
+	require.NoError(t, err)
+	page = result.(*models.SuccessResponse).Data[0].(*models.AuditEventPage)
+	require.Len(t, page.Events, 2)
+	assert.False(t, page.HasNextPage)
+	assert.Equal(t, sequences[0], page.Events[1].Sequence)
+
+	bad := "not-a-cursor"
+	result, err = resolver.AuditEvents(ctx, nil, nil, &bad)
+	require.NoError(t, err)
+	assert.Equal(t, "400", result.(*models.ResponseError).ErrorCode)
+}
+
+func TestAuditEventsAreTenantScoped(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	acme, globex := uuid.New(), uuid.New()
+	for _, tenantID := range []uuid.UUID{acme, globex, acme} {
+		_, err := Append(db, Entry{ActorID: uuid.New().String(), TenantID: tenantID.String(), Operation: "updateRole", Outcome: OutcomeSuccess})
+		require.NoError(t, err)
+	}
+	resolver := &AuditQueryResolver{DB: db}
+	tenants := func(t *testing.T, ctx context.Context, filter *models.AuditEventFilter) []string {
+		result, err := resolver.AuditEvents(ctx, filter, nil, nil)
+		require.NoError(t, err)
+		require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+		var tenants []string
+		for _, event := range result.(*models.SuccessResponse).Data[0].(*models.AuditEventPage).Events {
+			tenants = append(tenants, *event.TenantID)
+		}
+		return tenants
+	}
+
+	// A tenant administrator only sees the events of their tenant, even
+	// when filtering by another tenant
+	admin := uuid.New()
+	require.NoError(t, tenancy.RootSession(db).Create(&dto.TenantRoleAssignments{ResourceID: uuid.New(), Name: "admin", Version: "V1", PrincipalID: admin, RoleID: uuid.New(), TenantID: &acme, RowStatus: 1}).Error)
+	ctx := queryContext(admin, acme)
+	assert.Equal(t, []string{acme.String(), acme.String()}, tenants(t, ctx, nil))
+	assert.Empty(t, tenants(t, ctx, &models.AuditEventFilter{TenantID: &globex}))
+
+	// A global binding reaches the events of every tenant
+	operator := uuid.New()
+	require.NoError(t, tenancy.RootSession(db).Create(&dto.TenantRoleAssignments{ResourceID: uuid.New(), Name: "operator", Version: "V1", PrincipalID: operator, RoleID: uuid.New(), RowStatus: 1}).Error)
+	ctx = queryContext(operator, acme)
+	assert.Equal(t, []string{acme.String(), globex.String(), acme.String()}, tenants(t, ctx, nil))
+	assert.Equal(t, []string{globex.String()}, tenants(t, ctx, &models.AuditEventFilter{TenantID: &globex}))
+}
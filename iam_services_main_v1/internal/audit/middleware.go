@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Publisher receives every event once it has been appended to the chain.
+// Publish must not block for long since it runs on the request path.
+type Publisher interface {
+	Publish(event dto.AuditEvent)
+}
+
+// Record appends entry to the hash chain and hands the event to the
+// publishers. Changes made outside GraphQL use it so that they reach the
+// same sinks as mutations.
+func Record(db *gorm.DB, entry Entry, publishers ...Publisher) (*dto.AuditEvent, error) {
+	event, err := Append(db, entry)
+	if err != nil {
+		return nil, err
+	}
+	for _, publisher := range publishers {
+		publisher.Publish(*event)
+	}
+	return event, nil
+}
+
+// FieldMiddleware records an audit event for every root mutation field and
+// hands it to the publishers. The target resource is taken from the mutation
+// input, falling back to the ID of the returned data for creations without a
+// client supplied ID. Failing to write the audit event is logged and never
+// fails the mutation itself.
+func FieldMiddleware(db *gorm.DB, publishers ...Publisher) graphql.FieldMiddleware {
+	return func(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || fc.Object != "Mutation" {
+			return next(ctx)
+		}
+
+		targetID := targetFromArgs(fc.Args)
+		before := snapshotOrLog(db, targetID)
+
+		res, err := next(ctx)
+
+		if targetID == uuid.Nil {
+			targetID = targetFromResult(res)
+		}
+		entry := Entry{
+			Operation: fc.Field.Name,
+			Before:    before,
+			After:     snapshotOrLog(db, targetID),
+			Outcome:   OutcomeSuccess,
+		}
+		if targetID != uuid.Nil {
+			entry.TargetResourceID = targetID.String()
+		}
+		switch {
+		case err != nil:
+			entry.Outcome = OutcomeFailure
+			entry.ErrorMessage = err.Error()
+		case isResponseError(res):
+			responseError := res.(*models.ResponseError)
+			entry.Outcome = OutcomeFailure
+			entry.ErrorMessage = responseError.Message
+			if responseError.ErrorDetails != nil {
+				entry.ErrorMessage = *responseError.ErrorDetails
+			}
+		case failedItems(res) > 0:
+			results := res.([]models.OperationResult)
+			entry.Outcome = OutcomeFailure
+			entry.ErrorMessage = fmt.Sprintf("%d of %d batch items failed", failedItems(res), len(results))
+		}
+		FillRequestInfo(ctx, &entry)
+
+		if _, auditErr := Record(db, entry, publishers...); auditErr != nil {
+			logger.LogError(fmt.Sprintf("Error recording audit event for %s: %v", entry.Operation, auditErr))
+		}
+		return res, err
+	}
+}
+
+// FillRequestInfo sets the actor, tenant and client of the request of ctx on
+// entry.
+func FillRequestInfo(ctx context.Context, entry *Entry) {
+	if userID, err := helpers.GetUserID(ctx); err == nil {
+		entry.ActorID = userID.String()
+	}
+	if impersonatedUserID, err := helpers.GetImpersonatedUserID(ctx); err == nil && impersonatedUserID != nil {
+		entry.ImpersonatedUserID = impersonatedUserID.String()
+	}
+	if tenantID, err := helpers.GetTenantID(ctx); err == nil {
+		entry.TenantID = tenantID.String()
+	}
+	ginCtx, err := helpers.GetGinContext(ctx)
+	if err != nil {
+		return
+	}
+	entry.RequestID = ginCtx.GetString("request_id")
+	if ginCtx.Request != nil {
+		entry.ClientIP = ginCtx.ClientIP()
+	}
+}
+
+func isResponseError(res interface{}) bool {
+	responseError, ok := res.(*models.ResponseError)
+	return ok && responseError != nil
+}
+
+// failedItems counts the errors among the results of a batch mutation.
+func failedItems(res interface{}) int {
+	results, _ := res.([]models.OperationResult)
+	failed := 0
+	for _, result := range results {
+		if isResponseError(result) {
+			failed++
+		}
+	}
+	return failed
+}
+
+func snapshotOrLog(db *gorm.DB, id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	snapshot, err := Snapshot(db, id)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error taking audit snapshot of %s: %v", id, err))
+	}
+	return snapshot
+}
+
+// targetFromArgs looks for the resource ID in the mutation arguments, either
+// directly or inside the input object.
+func targetFromArgs(args map[string]interface{}) uuid.UUID {
+	if id := idFromMap(args); id != uuid.Nil {
+		return id
+	}
+	input, ok := args["input"]
+	if !ok {
+		return uuid.Nil
+	}
+	return idFromMap(toMap(input))
+}
+
+// targetFromResult returns the ID of the first data item of a successful result.
+func targetFromResult(res interface{}) uuid.UUID {
+	success, ok := res.(*models.SuccessResponse)
+	if !ok || success == nil || len(success.Data) == 0 {
+		return uuid.Nil
+	}
+	return idFromMap(toMap(success.Data[0]))
+}
+
+func idFromMap(values map[string]interface{}) uuid.UUID {
+	for _, key := range []string{"id", "resourceId", "roleId"} {
+		switch value := values[key].(type) {
+		case uuid.UUID:
+			return value
+		case string:
+			if id, err := uuid.Parse(value); err == nil {
+				return id
+			}
+		}
+	}
+	return uuid.Nil
+}
+
+func toMap(value interface{}) map[string]interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/labels"
+	"iam_services_main_v1/internal/tenancy"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Snapshot captures the stored state of the entity identified by id as JSON.
+// Soft deleted rows are included so deletions show up in the after snapshot.
+// An empty string is returned when nothing is stored under id.
+func Snapshot(db *gorm.DB, id uuid.UUID) (string, error) {
+	// Snapshots record whatever the mutation touched, whichever its tenant
+	state, err := snapshotState(tenancy.RootSession(db), id)
+	if err != nil || state == nil {
+		return "", err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	return string(data), nil
+}
+
+func snapshotState(db *gorm.DB, id uuid.UUID) (map[string]interface{}, error) {
+	var role dto.TNTRole
+	if found, err := findByID(db, &role, "resource_id = ?", id); err != nil || found {
+		if err != nil {
+			return nil, err
+		}
+		var permissionIDs []uuid.UUID
+		if err := db.Model(&dto.TNTRolePermission{}).
+			Where("role_id = ? AND row_status = 1", id).
+			Order("permission_id").
+			Pluck("permission_id", &permissionIDs).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch role permissions: %w", err)
+		}
+		return map[string]interface{}{"role": role, "permissions": permissionIDs}, nil
+	}
+
+	var permission dto.MstPermission
+	if found, err := findByID(db, &permission, "permission_id = ?", id); err != nil || found {
+		return map[string]interface{}{"permission": permission}, err
+	}
+
+	var resourceType dto.Mst_ResourceTypes
+	if found, err := findByID(db, &resourceType, "resource_type_id = ?", id); err != nil || found {
+		return map[string]interface{}{"resourceType": resourceType}, err
+	}
+
+	var request dto.AccessRequest
+	if found, err := findByID(db, &request, "request_id = ?", id); err != nil || found {
+		if err != nil {
+			return nil, err
+		}
+		var decisions []dto.AccessRequestDecision
+		if err := db.Where("request_id = ?", id).Order("created_at, decision_id").Find(&decisions).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch access request decisions: %w", err)
+		}
+		return map[string]interface{}{"accessRequest": request, "decisions": decisions}, nil
+	}
+
+	var policy dto.ApprovalPolicy
+	if found, err := findByID(db, &policy, "policy_id = ?", id); err != nil || found {
+		return map[string]interface{}{"approvalPolicy": policy}, err
+	}
+
+	var grant dto.BreakGlassGrant
+	if found, err := findByID(db, &grant, "grant_id = ?", id); err != nil || found {
+		return map[string]interface{}{"breakGlassGrant": grant}, err
+	}
+
+	var registration dto.BreakGlassPrincipal
+	if found, err := findByID(db, &registration, "registration_id = ?", id); err != nil || found {
+		return map[string]interface{}{"breakGlassPrincipal": registration}, err
+	}
+
+	var resource dto.TenantResource
+	found, err := findByID(db, &resource, "resource_id = ?", id)
+	if err != nil || !found {
+		return nil, err
+	}
+	state := map[string]interface{}{"resource": resource}
+
+	var metadata dto.TenantMetadata
+	if found, err := findByID(db, &metadata, "resource_id = ? AND row_status = 1", id); err != nil {
+		return nil, err
+	} else if found {
+		state["metadata"] = metadata.Metadata
+	}
+
+	resourceLabels, err := labels.GetLabels(db, id)
+	if err != nil {
+		return nil, err
+	}
+	state["labels"] = resourceLabels
+	return state, nil
+}
+
+func findByID(db *gorm.DB, dest interface{}, query string, id uuid.UUID) (bool, error) {
+	err := db.Where(query, id).Take(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch snapshot: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,149 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PolicyEngine decides whether a principal may perform an action.
+type PolicyEngine interface {
+	// Allowed reports whether principalID may perform action on scopeID, or
+	// within tenantID when no scope is given. Either may be nil.
+	Allowed(ctx context.Context, principalID uuid.UUID, action string, scopeID, tenantID *uuid.UUID) (bool, error)
+}
+
+// ErrTenantInactive is returned by TenantGuard for changes to a tenant that
+// is not active.
+var ErrTenantInactive = errors.New("tenant is not active")
+
+// TenantGuard is implemented by policy engines that refuse changes to
+// tenants that are not active, whoever requests them.
+type TenantGuard interface {
+	// Writable returns an error wrapping ErrTenantInactive when performing
+	// action on scopeID, or within tenantID when no scope is given, changes a
+	// tenant that is not active.
+	Writable(ctx context.Context, action string, scopeID, tenantID *uuid.UUID) error
+}
+
+// lifecycleActions change the lifecycle status of a tenant. They are the
+// only changes allowed to tenants that are not active.
+var lifecycleActions = map[string]bool{
+	"tenant.archive":    true,
+	"tenant.reactivate": true,
+	"tenant.suspend":    true,
+}
+
+// globalActions change what every tenant sees. Like actions on Root
+// resources, they are only granted by bindings outside any tenant, whatever
+// the request's tenant.
+var globalActions = map[string]bool{
+	"permission.create":   true,
+	"permission.delete":   true,
+	"permission.update":   true,
+	"resourceType.create": true,
+	"tenant.create":       true,
+}
+
+// BindingPolicyEngine grants the permissions of the roles bound to a
+// principal, limited to bindings of the scope's tenant or outside any tenant.
+// Bindings of tenants that are not active grant nothing.
+type BindingPolicyEngine struct {
+	DB *gorm.DB
+}
+
+// Allowed implements PolicyEngine. Global actions, and scopes that are Root
+// resources or belong to no tenant, are checked against bindings outside any
+// tenant. A scope that is not a resource is checked against the request's
+// tenant.
+func (e *BindingPolicyEngine) Allowed(ctx context.Context, principalID uuid.UUID, action string, scopeID, tenantID *uuid.UUID) (bool, error) {
+	db := e.DB.WithContext(ctx)
+	tenantID, err := checkedTenant(db, action, scopeID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	if tenantID != nil {
+		state, err := repository.CurrentTenantState(ctx, repository.NewGormStore(e.DB).TenantStates(), *tenantID)
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch tenant state: %w", err)
+		}
+		if state.Status != dto.TenantStatusActive {
+			// Only bindings outside any tenant still apply
+			tenantID = nil
+		}
+	}
+	return bindings.HasPermission(db, principalID, action, scopeID, tenantID)
+}
+
+// Writable implements TenantGuard.
+func (e *BindingPolicyEngine) Writable(ctx context.Context, action string, scopeID, tenantID *uuid.UUID) error {
+	if lifecycleActions[action] {
+		return nil
+	}
+	tenantID, err := checkedTenant(e.DB.WithContext(ctx), action, scopeID, tenantID)
+	if err != nil || tenantID == nil {
+		return err
+	}
+	state, err := repository.CurrentTenantState(ctx, repository.NewGormStore(e.DB).TenantStates(), *tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch tenant state: %w", err)
+	}
+	if state.Status != dto.TenantStatusActive {
+		return fmt.Errorf("%w: tenant %s is %s", ErrTenantInactive, *tenantID, state.Status)
+	}
+	return nil
+}
+
+// checkedTenant returns the tenant a permission is checked in: none for
+// global actions, the scope's tenant, which is none for Root resources and
+// resources outside any tenant, or tenantID without a scope or when the scope
+// is not a resource.
+func checkedTenant(db *gorm.DB, action string, scopeID, tenantID *uuid.UUID) (*uuid.UUID, error) {
+	if globalActions[action] {
+		return nil, nil
+	}
+	if scopeID == nil {
+		return tenantID, nil
+	}
+	scopeTenantID, found, err := scopeTenant(db, *scopeID)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		return scopeTenantID, nil
+	}
+	return tenantID, nil
+}
+
+// scopeTenant returns the tenant a resource belongs to: itself for tenants,
+// none for Root resources and resources outside any tenant.
+func scopeTenant(db *gorm.DB, scopeID uuid.UUID) (*uuid.UUID, bool, error) {
+	// The scope decides the tenant checked, so it is looked up across tenants
+	var resource dto.TenantResource
+	if err := db.Scopes(tenancy.WithoutTenantScope).Where("resource_id = ? AND row_status = 1", scopeID).First(&resource).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to fetch scope: %w", err)
+	}
+
+	var resourceType dto.Mst_ResourceTypes
+	if err := db.Where("resource_type_id = ?", resource.ResourceTypeID).First(&resourceType).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to fetch scope type: %w", err)
+	}
+	switch resourceType.Name {
+	case constants.ResourceTypeTenant:
+		return &resource.ResourceID, true, nil
+	case constants.ResourceTypeRoot:
+		return nil, true, nil
+	}
+	return resource.TenantID, true, nil
+}
@@ -0,0 +1,348 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/pkg/logger"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.Mst_ResourceTypes{}, &dto.TenantResource{}, &dto.TenantRoleAssignments{},
+		&dto.TNTRolePermission{}, &dto.MstPermission{}, &dto.TenantState{}, &dto.TenantStateTransition{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// bindAction binds a new role granting action to principalID, within
+// tenantID when it is set.
+func bindAction(t *testing.T, db *gorm.DB, principalID uuid.UUID, action string, tenantID *uuid.UUID) {
+	bindActionOn(t, db, principalID, action, tenantID, nil)
+}
+
+// bindActionOn is bindAction for a binding on scopeID.
+func bindActionOn(t *testing.T, db *gorm.DB, principalID uuid.UUID, action string, tenantID, scopeID *uuid.UUID) {
+	roleID, permissionID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.MstPermission{PermissionID: permissionID, Name: action, Action: action, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TNTRolePermission{ID: uuid.New(), RoleID: roleID, PermissionID: permissionID, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantRoleAssignments{
+		ResourceID: uuid.New(), Name: "binding", Version: "V1", PrincipalID: principalID, RoleID: roleID, TenantID: tenantID, ScopeID: scopeID, RowStatus: 1,
+	}).Error)
+}
+
+func TestBindingPolicyEngine(t *testing.T) {
+	db := setupTestDB(t)
+	engine := &BindingPolicyEngine{DB: db}
+	ctx := context.Background()
+
+	tenantTypeID := uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: tenantTypeID, Name: constants.ResourceTypeTenant, RowStatus: 1}).Error)
+	tenantA, tenantB := uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{tenantA, tenantB} {
+		require.NoError(t, db.Create(&dto.TenantResource{ResourceID: id, ResourceTypeID: tenantTypeID, Name: "tenant", RowStatus: 1}).Error)
+	}
+	groupID := uuid.New()
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: groupID, ResourceTypeID: uuid.New(), Name: "group", TenantID: &tenantB, RowStatus: 1}).Error)
+
+	reader, admin := uuid.New(), uuid.New()
+	bindAction(t, db, reader, "tenant.*", &tenantA)
+	bindAction(t, db, admin, "*", nil)
+
+	cases := []struct {
+		name      string
+		principal uuid.UUID
+		action    string
+		scopeID   *uuid.UUID
+		tenantID  *uuid.UUID
+		allowed   bool
+	}{
+		{"wildcard on own tenant", reader, "tenant.read", &tenantA, nil, true},
+		{"other tenant", reader, "tenant.read", &tenantB, &tenantA, false},
+		{"resource of other tenant", reader, "tenant.read", &groupID, &tenantA, false},
+		{"unrelated action", reader, "role.read", &tenantA, nil, false},
+		{"request tenant without scope", reader, "tenant.update", nil, &tenantA, true},
+		{"unknown scope falls back to request tenant", reader, "tenant.read", ptr(uuid.New()), &tenantA, true},
+		{"no tenant", reader, "tenant.read", nil, nil, false},
+		{"root-level wildcard", admin, "account.billing.read", &tenantB, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed, err := engine.Allowed(ctx, tc.principal, tc.action, tc.scopeID, tc.tenantID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.allowed, allowed)
+		})
+	}
+}
+
+func TestTenantBindingsDoNotGrantGlobalAccess(t *testing.T) {
+	db := setupTestDB(t)
+	engine := &BindingPolicyEngine{DB: db}
+	ctx := context.Background()
+
+	tenantTypeID, rootTypeID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: tenantTypeID, Name: constants.ResourceTypeTenant, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: rootTypeID, Name: constants.ResourceTypeRoot, RowStatus: 1}).Error)
+	rootID, tenantID, permissionID := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: rootID, ResourceTypeID: rootTypeID, Name: "root", RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: tenantID, ResourceTypeID: tenantTypeID, Name: "tenant", RowStatus: 1}).Error)
+	// A resource outside any tenant, such as a permission
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: permissionID, ResourceTypeID: uuid.New(), Name: "permission", RowStatus: 1}).Error)
+
+	tenantAdmin, globalAdmin := uuid.New(), uuid.New()
+	bindActionOn(t, db, tenantAdmin, "*", &tenantID, &tenantID)
+	bindAction(t, db, globalAdmin, "*", nil)
+
+	checks := []struct {
+		name    string
+		action  string
+		scopeID *uuid.UUID
+	}{
+		{"createPermission", "permission.create", nil},
+		{"updatePermission", "permission.update", nil},
+		{"deletePermission", "permission.delete", nil},
+		{"registerResourceType", "resourceType.create", nil},
+		{"createTenant", "tenant.create", nil},
+		{"global action on the tenant", "permission.update", &tenantID},
+		{"Root scope", "breakGlass.manage", &rootID},
+		{"scope outside any tenant", "label.update", &permissionID},
+	}
+	for _, check := range checks {
+		t.Run(check.name, func(t *testing.T) {
+			allowed, err := engine.Allowed(ctx, tenantAdmin, check.action, check.scopeID, &tenantID)
+			require.NoError(t, err)
+			assert.False(t, allowed, "tenant admin")
+
+			allowed, err = engine.Allowed(ctx, globalAdmin, check.action, check.scopeID, &tenantID)
+			require.NoError(t, err)
+			assert.True(t, allowed, "global admin")
+		})
+	}
+
+	allowed, err := engine.Allowed(ctx, tenantAdmin, "role.create", nil, &tenantID)
+	require.NoError(t, err)
+	assert.True(t, allowed, "tenant action")
+}
+
+func TestScopedBindings(t *testing.T) {
+	db := setupTestDB(t)
+	engine := &BindingPolicyEngine{DB: db}
+	ctx := context.Background()
+
+	tenantTypeID := uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: tenantTypeID, Name: constants.ResourceTypeTenant, RowStatus: 1}).Error)
+	tenantID, parentID, childID, otherID := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: tenantID, ResourceTypeID: tenantTypeID, Name: "tenant", RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: parentID, ResourceTypeID: uuid.New(), Name: "parent", TenantID: &tenantID, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: childID, ParentResourceID: &parentID, ResourceTypeID: uuid.New(), Name: "child", TenantID: &tenantID, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: otherID, ResourceTypeID: uuid.New(), Name: "other", TenantID: &tenantID, RowStatus: 1}).Error)
+
+	owner, tenantWide := uuid.New(), uuid.New()
+	bindActionOn(t, db, owner, "label.update", &tenantID, &parentID)
+	bindActionOn(t, db, tenantWide, "label.update", &tenantID, &tenantID)
+
+	cases := []struct {
+		name      string
+		principal uuid.UUID
+		scopeID   *uuid.UUID
+		allowed   bool
+	}{
+		{"bound scope", owner, &parentID, true},
+		{"descendant of bound scope", owner, &childID, true},
+		{"other resource of the tenant", owner, &otherID, false},
+		{"tenant without scope", owner, nil, false},
+		{"tenant-wide binding", tenantWide, &otherID, true},
+		{"tenant-wide binding without scope", tenantWide, nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			allowed, err := engine.Allowed(ctx, tc.principal, "label.update", tc.scopeID, &tenantID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.allowed, allowed)
+		})
+	}
+}
+
+func ptr(id uuid.UUID) *uuid.UUID { return &id }
+
+// fakeEngine allows the listed actions and records the last check.
+type fakeEngine struct {
+	allow     map[string]bool
+	principal uuid.UUID
+	scopeID   *uuid.UUID
+	tenantID  *uuid.UUID
+}
+
+func (e *fakeEngine) Allowed(ctx context.Context, principalID uuid.UUID, action string, scopeID, tenantID *uuid.UUID) (bool, error) {
+	e.principal, e.scopeID, e.tenantID = principalID, scopeID, tenantID
+	return e.allow[action], nil
+}
+
+func fieldContext(userID, tenantID uuid.UUID, returnType string, args map[string]interface{}) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	ginCtx.Set("tenantID", tenantID.String())
+	ctx := context.WithValue(context.Background(), "GinContextKey", ginCtx)
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Field: graphql.CollectedField{Field: &ast.Field{
+			Name:       "field",
+			Definition: &ast.FieldDefinition{Type: ast.NamedType(returnType, nil)},
+		}},
+		Args: args,
+	})
+}
+
+func TestHasPermissionDirective(t *testing.T) {
+	logger.InitLogger()
+	engine := &fakeEngine{allow: map[string]bool{"tenant.delete": true}}
+	directive := HasPermission(engine)
+	userID, tenantID, targetID := uuid.New(), uuid.New(), uuid.New()
+	scopeArg := "input.id"
+	next := func(ctx context.Context) (interface{}, error) { return "resolved", nil }
+
+	ctx := fieldContext(userID, tenantID, "OperationResult", map[string]interface{}{"input": models.DeleteInput{ID: targetID}})
+	res, err := directive(ctx, nil, next, "tenant.delete", &scopeArg)
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", res)
+	assert.Equal(t, userID, engine.principal)
+	assert.Equal(t, &targetID, engine.scopeID)
+	assert.Equal(t, &tenantID, engine.tenantID)
+
+	res, err = directive(ctx, nil, next, "tenant.update", &scopeArg)
+	require.NoError(t, err)
+	require.IsType(t, &models.ResponseError{}, res)
+	assert.Equal(t, ForbiddenCode, res.(*models.ResponseError).ErrorCode)
+
+	// Impersonated requests are authorized as the impersonated user
+	impersonatedID := uuid.New()
+	ginCtx := ctx.Value("GinContextKey").(*gin.Context)
+	ginCtx.Set("impersonatedUserID", impersonatedID.String())
+	_, err = directive(ctx, nil, next, "tenant.delete", &scopeArg)
+	require.NoError(t, err)
+	assert.Equal(t, impersonatedID, engine.principal)
+
+	// Batch fields resolve to a list holding the error
+	ctx = fieldContext(userID, tenantID, "OperationResult", nil)
+	graphql.GetFieldContext(ctx).Field.Definition.Type = ast.NonNullListType(ast.NonNullNamedType("OperationResult", nil), nil)
+	res, err = directive(ctx, nil, next, "role.create", nil)
+	require.NoError(t, err)
+	require.IsType(t, []models.OperationResult{}, res)
+	assert.Equal(t, ForbiddenCode, res.([]models.OperationResult)[0].(*models.ResponseError).ErrorCode)
+
+	// Fields of other types fail with a FORBIDDEN error
+	ctx = fieldContext(userID, tenantID, "BillingInfo", nil)
+	res, err = directive(ctx, nil, next, "account.billing.read", nil)
+	assert.Nil(t, res)
+	var gqlErr *gqlerror.Error
+	require.ErrorAs(t, err, &gqlErr)
+	assert.Equal(t, ForbiddenCode, gqlErr.Extensions["code"])
+	assert.Nil(t, engine.scopeID)
+}
+
+// setStatus moves tenantID to status.
+func setStatus(t *testing.T, db *gorm.DB, tenantID uuid.UUID, status string) {
+	states := repository.NewGormStore(db).TenantStates()
+	state, err := repository.CurrentTenantState(context.Background(), states, tenantID)
+	require.NoError(t, err)
+	require.NoError(t, states.Transition(context.Background(), state.Revision, &dto.TenantStateTransition{
+		TransitionID: uuid.New(), TenantID: tenantID, FromStatus: state.Status, ToStatus: status,
+	}))
+}
+
+func TestInactiveTenants(t *testing.T) {
+	db := setupTestDB(t)
+	engine := &BindingPolicyEngine{DB: db}
+	ctx := context.Background()
+
+	tenantTypeID := uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: tenantTypeID, Name: constants.ResourceTypeTenant, RowStatus: 1}).Error)
+	tenantID, groupID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: tenantID, ResourceTypeID: tenantTypeID, Name: "tenant", RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: groupID, ResourceTypeID: uuid.New(), Name: "group", TenantID: &tenantID, RowStatus: 1}).Error)
+	member, admin := uuid.New(), uuid.New()
+	bindAction(t, db, member, "group.update", &tenantID)
+	bindAction(t, db, admin, "*", nil)
+
+	// Tenants without a recorded state are active
+	allowed, err := engine.Allowed(ctx, member, "group.update", &groupID, nil)
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	require.NoError(t, engine.Writable(ctx, "group.update", &groupID, nil))
+
+	for _, status := range []string{dto.TenantStatusSuspended, dto.TenantStatusArchived} {
+		setStatus(t, db, tenantID, status)
+
+		allowed, err = engine.Allowed(ctx, member, "group.update", &groupID, nil)
+		require.NoError(t, err)
+		assert.False(t, allowed, "bindings of a %s tenant grant nothing", status)
+		allowed, err = engine.Allowed(ctx, member, "group.update", nil, &tenantID)
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		allowed, err = engine.Allowed(ctx, admin, "tenant.reactivate", &tenantID, nil)
+		require.NoError(t, err)
+		assert.True(t, allowed, "bindings outside any tenant still apply")
+
+		assert.ErrorIs(t, engine.Writable(ctx, "group.update", &groupID, nil), ErrTenantInactive)
+		assert.ErrorIs(t, engine.Writable(ctx, "group.update", nil, &tenantID), ErrTenantInactive)
+		assert.NoError(t, engine.Writable(ctx, "tenant.reactivate", &tenantID, nil))
+		assert.NoError(t, engine.Writable(ctx, "role.create", nil, nil))
+	}
+}
+
+// guardingEngine is a fakeEngine refusing changes to tenants other than
+// writable.
+type guardingEngine struct {
+	fakeEngine
+	writable uuid.UUID
+}
+
+func (e *guardingEngine) Writable(ctx context.Context, action string, scopeID, tenantID *uuid.UUID) error {
+	if tenantID != nil && *tenantID != e.writable {
+		return fmt.Errorf("%w: tenant %s is SUSPENDED", ErrTenantInactive, *tenantID)
+	}
+	return nil
+}
+
+func TestHasPermissionRefusesMutationsOfInactiveTenants(t *testing.T) {
+	logger.InitLogger()
+	userID, tenantID := uuid.New(), uuid.New()
+	engine := &guardingEngine{fakeEngine: fakeEngine{allow: map[string]bool{"group.update": true}}}
+	directive := HasPermission(engine)
+	next := func(ctx context.Context) (interface{}, error) { return "resolved", nil }
+
+	// Queries are not guarded
+	ctx := fieldContext(userID, tenantID, "OperationResult", nil)
+	res, err := directive(ctx, nil, next, "group.update", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", res)
+
+	graphql.GetFieldContext(ctx).Object = "Mutation"
+	res, err = directive(ctx, nil, next, "group.update", nil)
+	require.NoError(t, err)
+	require.IsType(t, &models.ResponseError{}, res)
+	assert.Equal(t, TenantInactiveCode, res.(*models.ResponseError).ErrorCode)
+
+	engine.writable = tenantID
+	res, err = directive(ctx, nil, next, "group.update", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "resolved", res)
+}
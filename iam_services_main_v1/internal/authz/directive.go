@@ -0,0 +1,171 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ForbiddenCode is the error code of requests denied by @hasPermission.
+const ForbiddenCode = "FORBIDDEN"
+
+// TenantInactiveCode is the error code of mutations refused by @hasPermission
+// because they change a tenant that is not active.
+const TenantInactiveCode = "TENANT_INACTIVE"
+
+// HasPermission implements the @hasPermission directive. The caller, or the
+// user it impersonates, must be allowed action by engine before the field
+// resolves. Denied OperationResult fields resolve to a ResponseError with
+// code FORBIDDEN, and lists of them to a list holding that error; other fields
+// resolve to null with a FORBIDDEN error. Mutations are refused likewise,
+// with code TENANT_INACTIVE, when engine is a TenantGuard rejecting them.
+func HasPermission(engine PolicyEngine) func(ctx context.Context, obj any, next graphql.Resolver, action string, scopeArg *string) (any, error) {
+	return func(ctx context.Context, obj any, next graphql.Resolver, action string, scopeArg *string) (any, error) {
+		principalID, err := helpers.GetPrincipalID(ctx)
+		if err != nil {
+			return forbidden(ctx, action, err)
+		}
+		// Requests without a tenant are checked against bindings outside any tenant
+		tenantID, _ := helpers.GetTenantID(ctx)
+
+		var scopeID *uuid.UUID
+		if scopeArg != nil {
+			scopeID, err = scopeFromArgs(graphql.GetFieldContext(ctx), *scopeArg)
+			if err != nil {
+				return forbidden(ctx, action, err)
+			}
+		}
+
+		allowed, err := engine.Allowed(ctx, *principalID, action, scopeID, tenantID)
+		if err != nil {
+			em := fmt.Sprintf("Error checking permission %s: %v", action, err)
+			logger.LogError(em)
+			if result, ok := operationResult(ctx, utils.FormatError(utils.FormatErrorStruct("500", "Error checking permission", em))); ok {
+				return result, nil
+			}
+			return nil, gqlerror.Errorf("error checking permission")
+		}
+		if !allowed {
+			return forbidden(ctx, action, fmt.Errorf("principal %s lacks permission %s", principalID, action))
+		}
+		if guard, ok := engine.(TenantGuard); ok && isMutation(ctx) {
+			if err := guard.Writable(ctx, action, scopeID, tenantID); err != nil {
+				return refused(ctx, action, err)
+			}
+		}
+		return next(ctx)
+	}
+}
+
+func isMutation(ctx context.Context) bool {
+	fc := graphql.GetFieldContext(ctx)
+	return fc != nil && fc.Object == "Mutation"
+}
+
+// refused answers a mutation that engine's TenantGuard rejected with err.
+func refused(ctx context.Context, action string, err error) (any, error) {
+	if !errors.Is(err, ErrTenantInactive) {
+		em := fmt.Sprintf("Error checking tenant state for %s: %v", action, err)
+		logger.LogError(em)
+		if result, ok := operationResult(ctx, utils.FormatError(utils.FormatErrorStruct("500", "Error checking tenant state", em))); ok {
+			return result, nil
+		}
+		return nil, gqlerror.Errorf("error checking tenant state")
+	}
+	em := fmt.Sprintf("Tenant is not active: %v", err)
+	logger.LogWarn(em)
+	if result, ok := operationResult(ctx, utils.FormatError(utils.FormatErrorStruct(TenantInactiveCode, "Tenant is not active", em))); ok {
+		return result, nil
+	}
+	return nil, &gqlerror.Error{
+		Message:    fmt.Sprintf("cannot %s: tenant is not active", action),
+		Extensions: map[string]interface{}{"code": TenantInactiveCode},
+	}
+}
+
+func forbidden(ctx context.Context, action string, err error) (any, error) {
+	em := fmt.Sprintf("Forbidden: %v", err)
+	logger.LogWarn(em)
+	if result, ok := operationResult(ctx, utils.FormatError(utils.FormatErrorStruct(ForbiddenCode, "Forbidden", em))); ok {
+		return result, nil
+	}
+	return nil, &gqlerror.Error{
+		Message:    fmt.Sprintf("not allowed to %s", action),
+		Extensions: map[string]interface{}{"code": ForbiddenCode},
+	}
+}
+
+// operationResult shapes errResult like the field's OperationResult or
+// [OperationResult] type. ok is false for fields of other types.
+func operationResult(ctx context.Context, errResult models.OperationResult) (result any, ok bool) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Field.Definition == nil || fc.Field.Definition.Type == nil {
+		return nil, false
+	}
+	fieldType := fc.Field.Definition.Type
+	if fieldType.Name() != "OperationResult" {
+		return nil, false
+	}
+	if fieldType.Elem != nil {
+		return []models.OperationResult{errResult}, true
+	}
+	return errResult, true
+}
+
+// scopeFromArgs reads the scope named by path, e.g. "id" or "input.scopeId",
+// from the field arguments. A missing or null argument yields no scope.
+func scopeFromArgs(fc *graphql.FieldContext, path string) (*uuid.UUID, error) {
+	if fc == nil {
+		return nil, nil
+	}
+	parts := strings.Split(path, ".")
+	value, ok := fc.Args[parts[0]]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	if len(parts) > 1 {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read argument %s: %w", parts[0], err)
+		}
+		var nested interface{}
+		if err := json.Unmarshal(data, &nested); err != nil {
+			return nil, fmt.Errorf("failed to read argument %s: %w", parts[0], err)
+		}
+		for _, part := range parts[1:] {
+			fields, ok := nested.(map[string]interface{})
+			if !ok {
+				return nil, nil
+			}
+			nested = fields[part]
+		}
+		value = nested
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case uuid.UUID:
+		return &value, nil
+	case *uuid.UUID:
+		return value, nil
+	case string:
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope %s: %w", path, err)
+		}
+		return &parsed, nil
+	default:
+		return nil, fmt.Errorf("invalid scope %s of type %T", path, value)
+	}
+}
@@ -0,0 +1,264 @@
+// Package batch applies the items of a batch mutation to Permit and the
+// database, either atomically or item by item, and reports a result per item.
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"slices"
+)
+
+// ErrorCodeNotApplied is the errorCode of items of an atomic batch left
+// unapplied because another item failed.
+const ErrorCodeNotApplied = "424"
+
+// Error is an item failure reported with code.
+type Error struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string { return fmt.Sprintf("%s: %v", e.Message, e.Err) }
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Fail reports an item failure with code and message.
+func Fail(code, message string, err error) error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Item is a prepared item of a batch.
+type Item struct {
+	// Err is the failure preparing the item. Items with an error are not applied.
+	Err error
+	// Apply writes the item to the database.
+	Apply func(ctx context.Context, tx repository.Store) error
+	// Result describes the applied item.
+	Result func(ctx context.Context) (models.OperationResult, error)
+}
+
+// Sync applies items to Permit. Push and Undo receive the positions of the
+// items to change and return one error per position.
+type Sync struct {
+	Push func(ctx context.Context, items []int) []error
+	// Undo reverts Push for items whose database write failed.
+	Undo func(ctx context.Context, items []int) []error
+}
+
+// Requests returns a Sync sending the Permit request of each item, and its
+// undo request to compensate, with bounded concurrency.
+func Requests(pc *permit.PermitClient, push, undo []permit.Request) Sync {
+	send := func(requests []permit.Request) func(ctx context.Context, items []int) []error {
+		return func(ctx context.Context, items []int) []error {
+			selected := make([]permit.Request, len(items))
+			for k, i := range items {
+				selected[k] = requests[i]
+			}
+			return pc.SendAll(ctx, selected, permit.DefaultConcurrency)
+		}
+	}
+	return Sync{Push: send(push), Undo: send(undo)}
+}
+
+// Step is the Permit side of an item whose requests depend on those of other
+// items. Push and Undo are nil when the item has no Permit side.
+type Step struct {
+	Phase      int
+	Push, Undo *permit.Request
+}
+
+var errNotSent = errors.New("not sent because a request of an earlier phase failed")
+
+// Phases returns a Sync sending the requests of steps phase by phase, lowest
+// first, with bounded concurrency within a phase. Once a request fails, those
+// of later phases are not sent. Undo goes through the phases in reverse.
+func Phases(pc *permit.PermitClient, steps []Step) Sync {
+	send := func(ctx context.Context, items []int, undo bool) []error {
+		phases := make([]int, 0, len(items))
+		for _, i := range items {
+			if !slices.Contains(phases, steps[i].Phase) {
+				phases = append(phases, steps[i].Phase)
+			}
+		}
+		slices.Sort(phases)
+		if undo {
+			slices.Reverse(phases)
+		}
+
+		errs := make([]error, len(items))
+		failed := false
+		for _, phase := range phases {
+			var requests []permit.Request
+			var positions []int
+			for k, i := range items {
+				request := steps[i].Push
+				if undo {
+					request = steps[i].Undo
+				}
+				switch {
+				case steps[i].Phase != phase || request == nil:
+				case failed:
+					errs[k] = errNotSent
+				default:
+					requests = append(requests, *request)
+					positions = append(positions, k)
+				}
+			}
+			for n, err := range pc.SendAll(ctx, requests, permit.DefaultConcurrency) {
+				errs[positions[n]] = err
+				failed = failed || (err != nil && !undo)
+			}
+		}
+		return errs
+	}
+	return Sync{
+		Push: func(ctx context.Context, items []int) []error { return send(ctx, items, false) },
+		Undo: func(ctx context.Context, items []int) []error { return send(ctx, items, true) },
+	}
+}
+
+// Cause returns the position and result of the failure that left a batch run
+// with Phases unapplied. Items of later phases fail once a request of an
+// earlier phase does, so it is the first failure of the earliest phase with
+// one. It returns -1 and nil when no item failed.
+func Cause(results []models.OperationResult, steps []Step) (int, *models.ResponseError) {
+	cause := -1
+	var failure *models.ResponseError
+	for i, result := range results {
+		failed, ok := result.(*models.ResponseError)
+		if !ok || failed.ErrorCode == ErrorCodeNotApplied {
+			continue
+		}
+		if cause < 0 || steps[i].Phase < steps[cause].Phase {
+			cause, failure = i, failed
+		}
+	}
+	return cause, failure
+}
+
+// Run applies items and returns their results in order. Items are pushed to
+// Permit first and then written to the database, where a failed write undoes
+// the item's Permit change. In ATOMIC mode any failure leaves every item
+// unapplied and all writes share one transaction; in BEST_EFFORT mode each
+// item is written in its own transaction. A nil mode is ATOMIC.
+func Run(ctx context.Context, store repository.Store, mode *models.BatchMode, items []Item, sync Sync) []models.OperationResult {
+	atomic := mode == nil || *mode != models.BatchModeBestEffort
+	results := make([]models.OperationResult, len(items))
+
+	var ready []int
+	for i, item := range items {
+		if item.Err != nil {
+			results[i] = failure(i, item.Err)
+			continue
+		}
+		ready = append(ready, i)
+	}
+	if atomic && len(ready) < len(items) {
+		return notApplied(results)
+	}
+
+	var pushed []int
+	for k, err := range sync.Push(ctx, ready) {
+		if err != nil {
+			results[ready[k]] = failure(ready[k], Fail("500", "Error applying item in permit", err))
+			continue
+		}
+		pushed = append(pushed, ready[k])
+	}
+	if atomic && len(pushed) < len(ready) {
+		undo(ctx, sync, pushed)
+		return notApplied(results)
+	}
+
+	var applied []int
+	if atomic {
+		failed := -1
+		err := store.Transaction(ctx, func(tx repository.Store) error {
+			for _, i := range pushed {
+				if err := items[i].Apply(ctx, tx); err != nil {
+					failed = i
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			undo(ctx, sync, pushed)
+			if failed < 0 {
+				// The commit failed
+				for _, i := range pushed {
+					results[i] = failure(i, err)
+				}
+			} else {
+				results[failed] = failure(failed, err)
+			}
+			return notApplied(results)
+		}
+		applied = pushed
+	} else {
+		for _, i := range pushed {
+			err := store.Transaction(ctx, func(tx repository.Store) error {
+				return items[i].Apply(ctx, tx)
+			})
+			if err != nil {
+				undo(ctx, sync, []int{i})
+				results[i] = failure(i, err)
+				continue
+			}
+			applied = append(applied, i)
+		}
+	}
+
+	for _, i := range applied {
+		result, err := items[i].Result(ctx)
+		if err != nil {
+			result = failure(i, Fail("500", "Error reading applied item", err))
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// undo reverts the Permit change of items, logging what could not be reverted.
+func undo(ctx context.Context, sync Sync, items []int) {
+	if len(items) == 0 {
+		return
+	}
+	for k, err := range sync.Undo(ctx, items) {
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error reverting batch item %d in permit: %v", items[k], err))
+		}
+	}
+}
+
+// notApplied reports the items without a result as not applied.
+func notApplied(results []models.OperationResult) []models.OperationResult {
+	for i := range results {
+		if results[i] == nil {
+			results[i] = utils.FormatError(utils.FormatErrorStruct(ErrorCodeNotApplied, "Not applied because another item of the batch failed", ""))
+		}
+	}
+	return results
+}
+
+func failure(i int, err error) models.OperationResult {
+	code, message, cause := "500", "Error applying item", err
+	var itemErr *Error
+	switch {
+	case errors.Is(err, repository.ErrConflict):
+		code, message = constants.ErrorCodeConflict, "Resource was modified concurrently"
+	case errors.As(err, &itemErr):
+		code, message, cause = itemErr.Code, itemErr.Message, itemErr.Err
+	}
+	em := fmt.Sprintf("%s: %v", message, cause)
+	logger.LogError(fmt.Sprintf("Batch item %d failed: %s", i, em))
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em))
+}
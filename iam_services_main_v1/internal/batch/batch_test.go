@@ -0,0 +1,181 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePermit records the items pushed and undone, failing the pushes of failing.
+type fakePermit struct {
+	failing map[int]bool
+	pushed  []int
+	undone  []int
+}
+
+func (f *fakePermit) sync() Sync {
+	return Sync{
+		Push: func(ctx context.Context, items []int) []error {
+			f.pushed = append(f.pushed, items...)
+			errs := make([]error, len(items))
+			for k, i := range items {
+				if f.failing[i] {
+					errs[k] = errors.New("permit unavailable")
+				}
+			}
+			return errs
+		},
+		Undo: func(ctx context.Context, items []int) []error {
+			f.undone = append(f.undone, items...)
+			return make([]error, len(items))
+		},
+	}
+}
+
+// createItem stores a resource with id.
+func createItem(id uuid.UUID) Item {
+	return Item{
+		Apply: func(ctx context.Context, tx repository.Store) error {
+			return tx.Resources().Create(ctx, &dto.TenantResource{ResourceID: id, Name: "item"})
+		},
+		Result: func(ctx context.Context) (models.OperationResult, error) {
+			return utils.FormatSuccess([]models.Data{})
+		},
+	}
+}
+
+func errorCodes(results []models.OperationResult) []string {
+	codes := make([]string, len(results))
+	for i, result := range results {
+		if responseError, ok := result.(*models.ResponseError); ok {
+			codes[i] = responseError.ErrorCode
+		} else {
+			codes[i] = "OK"
+		}
+	}
+	return codes
+}
+
+func TestAtomicBatchIsAllOrNothing(t *testing.T) {
+	logger.InitLogger()
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	existing := uuid.New()
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{ResourceID: existing, Name: "existing"}))
+	first, second := uuid.New(), uuid.New()
+	permit := &fakePermit{}
+
+	results := Run(ctx, store, nil, []Item{createItem(first), createItem(second), createItem(existing)}, permit.sync())
+
+	assert.Equal(t, []string{ErrorCodeNotApplied, ErrorCodeNotApplied, "500"}, errorCodes(results))
+	assert.Equal(t, []int{0, 1, 2}, permit.undone)
+	_, err := store.Resources().Get(ctx, first)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestAtomicBatchWithInvalidItemChangesNothing(t *testing.T) {
+	logger.InitLogger()
+	permit := &fakePermit{}
+	items := []Item{createItem(uuid.New()), {Err: Fail("404", "Role not found", errors.New("missing"))}, createItem(uuid.New())}
+
+	results := Run(context.Background(), repository.NewMemoryStore(), nil, items, permit.sync())
+
+	assert.Equal(t, []string{ErrorCodeNotApplied, "404", ErrorCodeNotApplied}, errorCodes(results))
+	assert.Empty(t, permit.pushed)
+}
+
+func TestAtomicBatchUndoesPermitWhenAPushFails(t *testing.T) {
+	logger.InitLogger()
+	atomic := models.BatchModeAtomic
+	permit := &fakePermit{failing: map[int]bool{1: true}}
+
+	results := Run(context.Background(), repository.NewMemoryStore(), &atomic, []Item{createItem(uuid.New()), createItem(uuid.New())}, permit.sync())
+
+	assert.Equal(t, []string{ErrorCodeNotApplied, "500"}, errorCodes(results))
+	assert.Equal(t, []int{0}, permit.undone)
+}
+
+func TestBestEffortBatchAppliesEachItem(t *testing.T) {
+	logger.InitLogger()
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	existing := uuid.New()
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{ResourceID: existing, Name: "existing"}))
+	applied := uuid.New()
+	bestEffort := models.BatchModeBestEffort
+	permit := &fakePermit{failing: map[int]bool{1: true}}
+	items := []Item{
+		createItem(applied),
+		createItem(uuid.New()),
+		createItem(existing),
+		{Err: Fail("404", "Role not found", errors.New("missing"))},
+	}
+
+	results := Run(ctx, store, &bestEffort, items, permit.sync())
+
+	assert.Equal(t, []string{"OK", "500", "500", "404"}, errorCodes(results))
+	assert.Equal(t, []int{0, 1, 2}, permit.pushed)
+	assert.Equal(t, []int{2}, permit.undone)
+	_, err := store.Resources().Get(ctx, applied)
+	assert.NoError(t, err)
+}
+
+func TestConflictsAreReportedAsConflicts(t *testing.T) {
+	logger.InitLogger()
+	item := Item{
+		Apply: func(ctx context.Context, tx repository.Store) error {
+			return Fail("500", "Error deleting role", repository.ErrConflict)
+		},
+	}
+
+	results := Run(context.Background(), repository.NewMemoryStore(), nil, []Item{item}, (&fakePermit{}).sync())
+
+	assert.Equal(t, []string{"CONFLICT"}, errorCodes(results))
+}
+
+func TestPhasesStopAfterAFailedPhase(t *testing.T) {
+	logger.InitLogger()
+	var mu sync.Mutex
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, r.Method+" "+path.Base(r.URL.Path))
+		if path.Base(r.URL.Path) == "rejected" {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	pc := permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+	request := func(method, endpoint string) *permit.Request {
+		return &permit.Request{Method: method, Endpoint: endpoint}
+	}
+	steps := []Step{
+		{Phase: 2, Push: request("POST", "assignment"), Undo: request("DELETE", "assignment")},
+		{Phase: 1, Push: request("POST", "rejected"), Undo: request("DELETE", "rejected")},
+		{Phase: 0, Push: request("POST", "tenant"), Undo: request("DELETE", "tenant")},
+		{Phase: 1},
+	}
+
+	results := Run(context.Background(), repository.NewMemoryStore(), nil, []Item{createItem(uuid.New()), createItem(uuid.New()), createItem(uuid.New()), createItem(uuid.New())}, Phases(pc, steps))
+
+	assert.Equal(t, []string{"500", "500", ErrorCodeNotApplied, ErrorCodeNotApplied}, errorCodes(results))
+	assert.Equal(t, []string{"POST tenant", "POST rejected", "DELETE tenant"}, sent)
+	cause, _ := Cause(results, steps)
+	assert.Equal(t, 1, cause)
+}
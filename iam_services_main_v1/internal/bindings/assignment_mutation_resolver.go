@@ -0,0 +1,195 @@
+package bindings
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateAction is the permission required on the scope of each binding
+// created.
+const CreateAction = "binding.create"
+
+// PolicyEngine decides whether a principal may perform an action on scopeID,
+// or within tenantID when no scope is given. It is implemented by the
+// policy engines of the authz package, which depends on this one.
+type PolicyEngine interface {
+	Allowed(ctx context.Context, principalID uuid.UUID, action string, scopeID, tenantID *uuid.UUID) (bool, error)
+}
+
+// AssignmentMutationResolver handles batch binding mutations.
+type AssignmentMutationResolver struct {
+	Store repository.Store
+	// PC is the Permit client; when nil one is configured from the environment.
+	PC *permit.PermitClient
+	// Policy decides whether the caller holds CreateAction on the scope of
+	// each binding. @hasPermission only checks it in the request's tenant,
+	// while scopes such as Root resources reach beyond it.
+	Policy PolicyEngine
+}
+
+func (r *AssignmentMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// CreateBindings binds roles to principals within the request's tenant,
+// returning a result per input. The role assignments are sent to Permit
+// through its bulk endpoint before the bindings are stored. Roles with an
+// approval policy are refused; they are only granted through requestAccess.
+func (r *AssignmentMutationResolver) CreateBindings(ctx context.Context, inputs []*models.CreateBindingInput, mode *models.BatchMode) ([]models.OperationResult, error) {
+	items := make([]batch.Item, len(inputs))
+	assignments := make([]permit.RoleAssignment, len(inputs))
+
+	userID, userErr := helpers.GetUserID(ctx)
+	principalID, principalErr := helpers.GetPrincipalID(ctx)
+	tenantID := tenancy.TenantFromContext(ctx)
+	// Root-level requests create bindings outside any tenant
+	ctx = tenancy.AllowRoot(ctx)
+	for i, input := range inputs {
+		if userErr != nil {
+			items[i].Err = batch.Fail("400", "Invalid user ID", userErr)
+			continue
+		}
+		if principalErr != nil {
+			items[i].Err = batch.Fail("400", "Invalid user ID", principalErr)
+			continue
+		}
+		items[i] = r.prepareBinding(ctx, *input, tenantID, *userID, *principalID)
+		assignments[i] = roleAssignment(input.PrincipalID, input.RoleID, tenantID)
+	}
+
+	pc := r.permitClient()
+	bulk := func(send func(context.Context, []permit.RoleAssignment, int) []error) func(context.Context, []int) []error {
+		return func(ctx context.Context, items []int) []error {
+			selected := make([]permit.RoleAssignment, len(items))
+			for k, i := range items {
+				selected[k] = assignments[i]
+			}
+			return send(ctx, selected, permit.DefaultConcurrency)
+		}
+	}
+	sync := batch.Sync{Push: bulk(pc.BulkAssignRoles), Undo: bulk(pc.BulkUnassignRoles)}
+	return batch.Run(ctx, r.Store, mode, items, sync), nil
+}
+
+// prepareBinding validates a binding to create on behalf of principalID and
+// returns the batch item storing it.
+func (r *AssignmentMutationResolver) prepareBinding(ctx context.Context, input models.CreateBindingInput, tenantID *uuid.UUID, userID, principalID uuid.UUID) batch.Item {
+	// Users and groups are resources of the built-in User and Group types
+	principal, err := r.Store.Resources().Get(ctx, input.PrincipalID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Principal not found", err)}
+	}
+	principalType, err := r.Store.Resources().GetType(ctx, principal.ResourceTypeID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Principal type not found", err)}
+	}
+	if principalType.Name != constants.ResourceTypeUser && principalType.Name != constants.ResourceTypeGroup {
+		return batch.Item{Err: batch.Fail("400", "Invalid principal", fmt.Errorf("resource %s is a %s, not a user or group", input.PrincipalID, principalType.Name))}
+	}
+	if _, err := r.Store.Roles().Get(ctx, input.RoleID); err != nil {
+		return batch.Item{Err: batch.Fail("404", "Role not found", err)}
+	}
+	if privileged, err := repository.RequiresApproval(ctx, r.Store.ApprovalPolicies(), input.RoleID); err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error fetching approval policy", err)}
+	} else if privileged {
+		return batch.Item{Err: batch.Fail("403", "Role requires approval", ErrApprovalRequired)}
+	}
+	scope, err := r.Store.Resources().Get(ctx, input.ScopeRefID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Scope not found", err)}
+	}
+	scopeType, err := r.Store.Resources().GetType(ctx, scope.ResourceTypeID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Scope type not found", err)}
+	}
+	allowed, err := r.Policy.Allowed(ctx, principalID, CreateAction, &input.ScopeRefID, tenantID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error checking permission", err)}
+	}
+	if !allowed {
+		return batch.Item{Err: batch.Fail("403", "Forbidden", fmt.Errorf("principal %s lacks permission %s on %s", principalID, CreateAction, input.ScopeRefID))}
+	}
+
+	binding := dto.TenantRoleAssignments{
+		ResourceID:  uuid.New(),
+		Name:        input.Name,
+		Version:     input.Version,
+		PrincipalID: input.PrincipalID,
+		RoleID:      input.RoleID,
+		TenantID:    tenantID,
+		ScopeID:     &input.ScopeRefID,
+		RowStatus:   1,
+		CreatedBy:   userID,
+		UpdatedBy:   userID,
+	}
+	return batch.Item{
+		Apply: func(ctx context.Context, tx repository.Store) error {
+			if err := tx.Assignments().Create(ctx, &binding); err != nil {
+				return batch.Fail("500", "Error creating binding", err)
+			}
+			return nil
+		},
+		Result: func(ctx context.Context) (models.OperationResult, error) {
+			var principalRef models.Principal = &models.User{ID: binding.PrincipalID}
+			if principalType.Name == constants.ResourceTypeGroup {
+				principalRef = &models.Group{ID: binding.PrincipalID}
+			}
+			return utils.FormatSuccess([]models.Data{&models.Binding{
+				ID:        binding.ResourceID,
+				Name:      binding.Name,
+				Version:   binding.Version,
+				Principal: principalRef,
+				Role:      &models.Role{ID: binding.RoleID},
+				ScopeRef:  resourceRef(scopeType.Name, scope.ResourceID),
+				CreatedAt: binding.CreatedAt.Format(time.RFC3339),
+				CreatedBy: binding.CreatedBy,
+				UpdatedAt: binding.UpdatedAt.Format(time.RFC3339),
+				UpdatedBy: binding.UpdatedBy,
+			}})
+		},
+	}
+}
+
+// resourceRef returns a reference to the resource id of the named type.
+// Resources of registered types are referenced as tenants.
+func resourceRef(typeName string, id uuid.UUID) models.Resource {
+	switch typeName {
+	case constants.ResourceTypeRoot:
+		return &models.Root{ID: id}
+	case constants.ResourceTypeRole:
+		return &models.Role{ID: id}
+	case constants.ResourceTypeAccount:
+		return &models.Account{ID: id}
+	case constants.ResourceTypeClientOrganizationUnit:
+		return &models.ClientOrganizationUnit{ID: id}
+	case constants.ResourceTypeUser:
+		return &models.User{ID: id}
+	case constants.ResourceTypeGroup:
+		return &models.Group{ID: id}
+	default:
+		return &models.Tenant{ID: id}
+	}
+}
+
+func roleAssignment(principalID, roleID uuid.UUID, tenantID *uuid.UUID) permit.RoleAssignment {
+	assignment := permit.RoleAssignment{User: principalID.String(), Role: roleID.String()}
+	if tenantID != nil {
+		assignment.Tenant = tenantID.String()
+	}
+	return assignment
+}
@@ -0,0 +1,93 @@
+package bindings
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scopePolicy allows every action on the scopes it holds.
+type scopePolicy map[uuid.UUID]bool
+
+func (p scopePolicy) Allowed(ctx context.Context, principalID uuid.UUID, action string, scopeID, tenantID *uuid.UUID) (bool, error) {
+	return scopeID != nil && p[*scopeID], nil
+}
+
+func TestCreateBindings(t *testing.T) {
+	logger.InitLogger()
+	var assigned []permit.RoleAssignment
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/facts/proj/env/role_assignments/bulk", r.URL.Path)
+		var chunk []permit.RoleAssignment
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&chunk))
+		if r.Method == "POST" {
+			assigned = append(assigned, chunk...)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tenantID, groupTypeID, tenantTypeID := uuid.New(), uuid.New(), uuid.New()
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", uuid.New().String())
+	ginCtx.Set("tenantID", tenantID.String())
+	ctx := context.WithValue(context.Background(), "GinContextKey", ginCtx)
+
+	store := repository.NewMemoryStore()
+	store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: groupTypeID, Name: constants.ResourceTypeGroup, RowStatus: 1})
+	store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: tenantTypeID, Name: constants.ResourceTypeTenant, RowStatus: 1})
+	groupID, roleID, gatedRoleID := uuid.New(), uuid.New(), uuid.New()
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{ResourceID: groupID, ResourceTypeID: groupTypeID, Name: "admins", RowStatus: 1}))
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{ResourceID: tenantID, ResourceTypeID: tenantTypeID, Name: "acme", RowStatus: 1}))
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{ResourceID: roleID, ResourceTypeID: tenantTypeID, Name: "reader", RowStatus: 1}))
+	require.NoError(t, store.Roles().Create(ctx, &dto.TNTRole{ResourceID: roleID, Name: "reader", RowStatus: 1}))
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{ResourceID: gatedRoleID, ResourceTypeID: tenantTypeID, Name: "operator", RowStatus: 1}))
+	require.NoError(t, store.Roles().Create(ctx, &dto.TNTRole{ResourceID: gatedRoleID, Name: "operator", RowStatus: 1}))
+	store.AddApprovalPolicy(dto.ApprovalPolicy{PolicyID: uuid.New(), TenantID: &tenantID, RoleID: gatedRoleID, RequiredApprovals: 1})
+
+	resolver := &AssignmentMutationResolver{
+		Store:  store,
+		PC:     permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second}),
+		Policy: scopePolicy{tenantID: true},
+	}
+	bestEffort := models.BatchModeBestEffort
+	results, err := resolver.CreateBindings(ctx, []*models.CreateBindingInput{
+		{Name: "admins-reader", PrincipalID: groupID, RoleID: roleID, ScopeRefID: tenantID, Version: "1"},
+		{Name: "ghost-reader", PrincipalID: uuid.New(), RoleID: roleID, ScopeRefID: tenantID, Version: "1"},
+		// The caller only holds binding.create on the tenant
+		{Name: "group-reader", PrincipalID: groupID, RoleID: roleID, ScopeRefID: groupID, Version: "1"},
+		// Roles with an approval policy are granted through requestAccess
+		{Name: "admins-operator", PrincipalID: groupID, RoleID: gatedRoleID, ScopeRefID: tenantID, Version: "1"},
+	}, &bestEffort)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.IsType(t, &models.SuccessResponse{}, results[0])
+	binding := results[0].(*models.SuccessResponse).Data[0].(*models.Binding)
+	assert.Equal(t, &models.Group{ID: groupID}, binding.Principal)
+	assert.Equal(t, &models.Tenant{ID: tenantID}, binding.ScopeRef)
+	assert.Equal(t, "404", results[1].(*models.ResponseError).ErrorCode)
+	assert.Equal(t, "403", results[2].(*models.ResponseError).ErrorCode)
+	assert.Equal(t, "403", results[3].(*models.ResponseError).ErrorCode)
+	assert.Contains(t, *results[3].(*models.ResponseError).ErrorDetails, "requestAccess")
+
+	assert.Equal(t, []permit.RoleAssignment{{User: groupID.String(), Role: roleID.String(), Tenant: tenantID.String()}}, assigned)
+	stored, err := store.Assignments().Get(ctx, binding.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &tenantID, stored.TenantID)
+}
@@ -0,0 +1,328 @@
+package bindings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBindingNotFound  = errors.New("binding not found")
+	ErrApprovalRequired = errors.New("the role is only granted through approved access requests; request it with requestAccess")
+)
+
+// TenantColumn is the SQL expression of a binding's tenant in ActiveAssignments queries.
+const TenantColumn = "COALESCE(ra.tenant_id, r.tenant_id)"
+
+// Assignment is an active binding of a role to a principal within a tenant.
+type Assignment struct {
+	BindingID   uuid.UUID
+	Name        string
+	PrincipalID uuid.UUID
+	RoleID      uuid.UUID
+	TenantID    *uuid.UUID
+	ScopeID     *uuid.UUID
+	ExpiresAt   *time.Time
+}
+
+// Grant describes a binding to create.
+type Grant struct {
+	Name        string
+	PrincipalID uuid.UUID
+	RoleID      uuid.UUID
+	TenantID    *uuid.UUID
+	ScopeID     *uuid.UUID
+	// ExpiresAt limits the binding's lifetime; expired bindings are ignored
+	// by every lookup even before they are revoked.
+	ExpiresAt *time.Time
+}
+
+// ActiveAssignments returns the active, unexpired bindings matching query,
+// which is applied to tnt_role_assignments joined with the binding's
+// tnt_resources row (aliased ra and r).
+func ActiveAssignments(db *gorm.DB, query string, args ...interface{}) ([]Assignment, error) {
+	return findAssignments(db.Where("ra.expires_at IS NULL OR ra.expires_at > ?", time.Now()), query, args...)
+}
+
+func findAssignments(db *gorm.DB, query string, args ...interface{}) ([]Assignment, error) {
+	var rows []struct {
+		ResourceID  uuid.UUID
+		Name        string
+		PrincipalID uuid.UUID
+		RoleID      uuid.UUID
+		TenantID    *uuid.UUID
+		ScopeID     *uuid.UUID
+		ExpiresAt   *time.Time
+	}
+	if err := db.Table("tnt_role_assignments AS ra").
+		Select("ra.resource_id, ra.name, ra.principal_id, ra.role_id, "+TenantColumn+" AS tenant_id, ra.scope_id, ra.expires_at").
+		Joins("LEFT JOIN tnt_resources AS r ON r.resource_id = ra.resource_id").
+		Where("ra.row_status = 1").
+		Where(query, args...).
+		Order("ra.created_at, ra.resource_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch bindings: %w", err)
+	}
+
+	assignments := make([]Assignment, 0, len(rows))
+	for _, row := range rows {
+		assignments = append(assignments, Assignment{
+			BindingID:   row.ResourceID,
+			Name:        row.Name,
+			PrincipalID: row.PrincipalID,
+			RoleID:      row.RoleID,
+			TenantID:    row.TenantID,
+			ScopeID:     row.ScopeID,
+			ExpiresAt:   row.ExpiresAt,
+		})
+	}
+	return assignments, nil
+}
+
+// HoldsRole reports whether a principal has an active binding of roleID on
+// scopeID, either directly or through a tenant-wide binding of that tenant.
+func HoldsRole(db *gorm.DB, principalID, roleID, scopeID uuid.UUID) (bool, error) {
+	assignments, err := ActiveAssignments(db,
+		"ra.principal_id = ? AND ra.role_id = ? AND (ra.scope_id = ? OR (ra.scope_id IS NULL AND "+TenantColumn+" = ?))",
+		principalID, roleID, scopeID, scopeID)
+	if err != nil {
+		return false, err
+	}
+	return len(assignments) > 0, nil
+}
+
+// HasPermission reports whether a principal holds, through an active binding,
+// a role granting a permission with the given action or a wildcard covering
+// it ("tenant.*", "*"). With a tenant only bindings of that tenant or outside
+// any tenant count; without one only bindings outside any tenant do. Of those,
+// only bindings without a scope, or on scopeID, one of its ancestors, the
+// tenant or a Root resource count; without scopeID the tenant is the scope.
+func HasPermission(db *gorm.DB, principalID uuid.UUID, action string, scopeID, tenantID *uuid.UUID) (bool, error) {
+	roles := db.Table("tnt_role_permissions AS rp").
+		Select("rp.role_id").
+		Joins("JOIN mst_permissions p ON p.permission_id = rp.permission_id").
+		Where("p.action IN ? AND rp.row_status = 1 AND p.row_status = 1", actionPatterns(action))
+
+	scopes, err := scopeAncestors(db, scopeID)
+	if err != nil {
+		return false, err
+	}
+	if tenantID != nil {
+		scopes = append(scopes, *tenantID)
+	}
+
+	query := "ra.principal_id = ? AND ra.role_id IN (?) AND (ra.scope_id IS NULL OR ra.scope_id IN ? OR ra.scope_id IN (?)) AND " + TenantColumn + " IS NULL"
+	args := []interface{}{principalID, roles, scopes, rootResources(db)}
+	if tenantID != nil {
+		query = "ra.principal_id = ? AND ra.role_id IN (?) AND (ra.scope_id IS NULL OR ra.scope_id IN ? OR ra.scope_id IN (?)) AND (" + TenantColumn + " IS NULL OR " + TenantColumn + " = ?)"
+		args = append(args, *tenantID)
+	}
+	assignments, err := ActiveAssignments(db, query, args...)
+	if err != nil {
+		return false, err
+	}
+	return len(assignments) > 0, nil
+}
+
+// maxScopeDepth bounds the parent chain followed by scopeAncestors.
+const maxScopeDepth = 32
+
+// scopeAncestors returns scopeID followed by its active parents, nearest
+// first, or nothing without a scope.
+func scopeAncestors(db *gorm.DB, scopeID *uuid.UUID) ([]uuid.UUID, error) {
+	var chain []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for id := scopeID; id != nil && !seen[*id] && len(chain) < maxScopeDepth; {
+		chain = append(chain, *id)
+		seen[*id] = true
+		// Scopes of other tenants are followed too; the tenant predicate of
+		// the binding decides whether they count
+		var resource dto.TenantResource
+		err := db.Scopes(tenancy.WithoutTenantScope).Select("parent_resource_id").
+			Where("resource_id = ? AND row_status = 1", *id).First(&resource).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch scope: %w", err)
+		}
+		id = resource.ParentResourceID
+	}
+	return chain, nil
+}
+
+// rootResources selects the ids of the Root resources.
+func rootResources(db *gorm.DB) *gorm.DB {
+	return db.Table("tnt_resources AS s").
+		Select("s.resource_id").
+		Joins("JOIN mst_resource_types t ON t.resource_type_id = s.resource_type_id").
+		Where("t.name = ?", constants.ResourceTypeRoot)
+}
+
+// HoldsGlobalBinding reports whether a principal has an active binding
+// outside any tenant or on a Root resource, either of which reaches beyond a
+// single tenant.
+func HoldsGlobalBinding(db *gorm.DB, principalID uuid.UUID) (bool, error) {
+	assignments, err := ActiveAssignments(db, "ra.principal_id = ? AND ("+TenantColumn+" IS NULL OR ra.scope_id IN (?))", principalID, rootResources(db))
+	if err != nil {
+		return false, err
+	}
+	return len(assignments) > 0, nil
+}
+
+// GrantedActions returns the permission actions of the roles a principal
+// holds through active bindings of tenantID or outside any tenant.
+func GrantedActions(db *gorm.DB, principalID, tenantID uuid.UUID) ([]string, error) {
+	assignments, err := ActiveAssignments(db, "ra.principal_id = ? AND ("+TenantColumn+" IS NULL OR "+TenantColumn+" = ?)", principalID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+	roleIDs := make([]uuid.UUID, len(assignments))
+	for i, assignment := range assignments {
+		roleIDs[i] = assignment.RoleID
+	}
+	return RoleActions(db, roleIDs...)
+}
+
+// RoleActions returns the permission actions granted by the roles.
+func RoleActions(db *gorm.DB, roleIDs ...uuid.UUID) ([]string, error) {
+	var actions []string
+	if err := db.Table("tnt_role_permissions AS rp").
+		Distinct("p.action").
+		Joins("JOIN mst_permissions p ON p.permission_id = rp.permission_id").
+		Where("rp.role_id IN ? AND rp.row_status = 1 AND p.row_status = 1", roleIDs).
+		Order("p.action").
+		Pluck("p.action", &actions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch granted actions: %w", err)
+	}
+	return actions, nil
+}
+
+// Covers reports whether the granted actions hold action itself or a
+// wildcard covering it.
+func Covers(granted []string, action string) bool {
+	for _, pattern := range actionPatterns(action) {
+		if slices.Contains(granted, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// actionPatterns lists the permission actions granting action: the action
+// itself and a wildcard for each of its dotted prefixes.
+func actionPatterns(action string) []string {
+	patterns := []string{action}
+	for i := len(action) - 1; i > 0; i-- {
+		if action[i] == '.' {
+			patterns = append(patterns, action[:i]+".*")
+		}
+	}
+	return append(patterns, "*")
+}
+
+// Create assigns the role in Permit and records the binding. The Permit
+// assignment is removed again if the binding cannot be stored.
+func Create(ctx context.Context, db *gorm.DB, pc *permit.PermitClient, grant Grant, userID uuid.UUID) (*Assignment, error) {
+	payload := assignmentPayload(grant.PrincipalID, grant.RoleID, grant.TenantID)
+	if _, err := pc.SendRequest(ctx, "POST", "role_assignments", payload); err != nil {
+		return nil, fmt.Errorf("failed to assign role in permit: %w", err)
+	}
+
+	binding := dto.TenantRoleAssignments{
+		ResourceID:  uuid.New(),
+		Name:        grant.Name,
+		Version:     "V1",
+		PrincipalID: grant.PrincipalID,
+		RoleID:      grant.RoleID,
+		TenantID:    grant.TenantID,
+		ScopeID:     grant.ScopeID,
+		ExpiresAt:   grant.ExpiresAt,
+		RowStatus:   1,
+		CreatedBy:   userID,
+		UpdatedBy:   userID,
+	}
+	if err := db.Create(&binding).Error; err != nil {
+		if _, rollbackErr := pc.SendRequest(ctx, "DELETE", "role_assignments", payload); rollbackErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to roll back permit role assignment: %w", rollbackErr))
+		}
+		return nil, fmt.Errorf("failed to create binding: %w", err)
+	}
+
+	return &Assignment{
+		BindingID:   binding.ResourceID,
+		Name:        binding.Name,
+		PrincipalID: binding.PrincipalID,
+		RoleID:      binding.RoleID,
+		TenantID:    binding.TenantID,
+		ScopeID:     binding.ScopeID,
+		ExpiresAt:   binding.ExpiresAt,
+	}, nil
+}
+
+// GetAssignment returns an active binding by ID.
+func GetAssignment(db *gorm.DB, bindingID uuid.UUID) (*Assignment, error) {
+	assignments, err := ActiveAssignments(db, "ra.resource_id = ?", bindingID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, ErrBindingNotFound
+	}
+	return &assignments[0], nil
+}
+
+// Revoke removes the role assignment from Permit and soft deletes the binding.
+// Expired bindings are revoked as well; revoking a deleted binding is a no-op.
+func Revoke(ctx context.Context, db *gorm.DB, pc *permit.PermitClient, bindingID, userID uuid.UUID) error {
+	assignments, err := findAssignments(db, "ra.resource_id = ?", bindingID)
+	if err != nil {
+		return err
+	}
+	if len(assignments) == 0 {
+		return nil
+	}
+	assignment := assignments[0]
+
+	payload := assignmentPayload(assignment.PrincipalID, assignment.RoleID, assignment.TenantID)
+	if _, err := pc.SendRequest(ctx, "DELETE", "role_assignments", payload); err != nil {
+		return fmt.Errorf("failed to unassign role in permit: %w", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		deleted := utils.UpdateDeletedMap()
+		deleted["updated_by"] = userID
+		deleted["updated_at"] = time.Now()
+		if err := tx.Model(&dto.TenantRoleAssignments{}).Where("resource_id = ?", bindingID).Updates(deleted).Error; err != nil {
+			return fmt.Errorf("failed to delete binding: %w", err)
+		}
+		if err := tx.Model(&dto.TenantResource{}).Where("resource_id = ?", bindingID).Updates(deleted).Error; err != nil {
+			return fmt.Errorf("failed to delete binding resource: %w", err)
+		}
+		return nil
+	})
+}
+
+func assignmentPayload(principalID, roleID uuid.UUID, tenantID *uuid.UUID) map[string]interface{} {
+	payload := map[string]interface{}{
+		"user": principalID.String(),
+		"role": roleID.String(),
+	}
+	if tenantID != nil {
+		payload["tenant"] = tenantID.String()
+	}
+	return payload
+}
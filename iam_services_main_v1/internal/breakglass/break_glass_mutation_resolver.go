@@ -0,0 +1,230 @@
+package breakglass
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BreakGlassMutationResolver handles break-glass registrations and emergency access.
+type BreakGlassMutationResolver struct {
+	DB *gorm.DB
+	PC *permit.PermitClient
+}
+
+func (r *BreakGlassMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// RegisterBreakGlassPrincipal allows a principal to take a role on a Root or
+// tenant scope through breakGlass. Root scopes require a global binding.
+func (r *BreakGlassMutationResolver) RegisterBreakGlassPrincipal(ctx context.Context, input models.RegisterBreakGlassPrincipalInput) (models.OperationResult, error) {
+	r = &BreakGlassMutationResolver{DB: session(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	tenantID, err := scopeTenant(r.DB, input.ScopeID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			return handleError("400", "Invalid scope", err)
+		}
+		return handleError("500", "Error fetching scope", err)
+	}
+	db, err := r.managedSession(ctx, tenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			return handleError("400", "Invalid scope", err)
+		}
+		if errors.Is(err, ErrGlobalBindingRequired) {
+			return handleError("403", "Forbidden", err)
+		}
+		return handleError("500", "Error checking bindings", err)
+	}
+	if err := roleExists(r.DB, input.RoleID); err != nil {
+		if errors.Is(err, ErrRoleNotFound) {
+			return handleError("404", "Role not found", err)
+		}
+		return handleError("500", "Error fetching role", err)
+	}
+
+	var existing int64
+	if err := db.Model(&dto.BreakGlassPrincipal{}).
+		Where("principal_id = ? AND scope_id = ? AND row_status = 1", input.PrincipalID, input.ScopeID).
+		Count(&existing).Error; err != nil {
+		return handleError("500", "Error checking break-glass registrations", err)
+	}
+	if existing > 0 {
+		return handleError("409", "Principal already registered", ErrAlreadyRegistered)
+	}
+
+	registration := dto.BreakGlassPrincipal{
+		RegistrationID: uuid.New(),
+		TenantID:       tenantID,
+		PrincipalID:    input.PrincipalID,
+		RoleID:         input.RoleID,
+		ScopeID:        input.ScopeID,
+		RowStatus:      1,
+		CreatedBy:      *userID,
+		UpdatedBy:      *userID,
+	}
+	if err := db.Create(&registration).Error; err != nil {
+		return handleError("500", "Error registering break-glass principal", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToPrincipal(&registration)})
+}
+
+// DeregisterBreakGlassPrincipal soft deletes a registration of the request's
+// tenant, or one on a Root scope for callers with a global binding. Access
+// already granted through it still lapses at its expiry.
+func (r *BreakGlassMutationResolver) DeregisterBreakGlassPrincipal(ctx context.Context, input models.DeleteInput) (models.OperationResult, error) {
+	r = &BreakGlassMutationResolver{DB: session(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	var registration dto.BreakGlassPrincipal
+	if err := tenancy.RootSession(r.DB).Where("registration_id = ? AND row_status = 1", input.ID).First(&registration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return handleError("404", "Break-glass registration not found", ErrRegistrationNotFound)
+		}
+		return handleError("500", "Error fetching break-glass registration", err)
+	}
+	db, err := r.managedSession(ctx, registration.TenantID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			return handleError("404", "Break-glass registration not found", ErrRegistrationNotFound)
+		}
+		if errors.Is(err, ErrGlobalBindingRequired) {
+			return handleError("403", "Forbidden", err)
+		}
+		return handleError("500", "Error checking bindings", err)
+	}
+
+	updates := utils.UpdateDeletedMap()
+	updates["updated_by"] = *userID
+	result := db.Model(&dto.BreakGlassPrincipal{}).Where("registration_id = ? AND row_status = 1", input.ID).Updates(updates)
+	if result.Error != nil {
+		return handleError("500", "Error deleting break-glass registration", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return handleError("404", "Break-glass registration not found", ErrRegistrationNotFound)
+	}
+	return utils.FormatSuccess([]models.Data{})
+}
+
+// managedSession returns the session registrations on a scope of tenantID are
+// managed through. Registrations on Root scopes reach beyond any tenant, so
+// they require a global binding.
+func (r *BreakGlassMutationResolver) managedSession(ctx context.Context, tenantID *uuid.UUID) (*gorm.DB, error) {
+	db, err := scopeSession(ctx, r.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if tenantID == nil {
+		if err := requireGlobalBinding(ctx, r.DB); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// BreakGlass grants the current user the registered role on the scope for
+// TTL. The access is recorded as a high-severity audit event.
+func (r *BreakGlassMutationResolver) BreakGlass(ctx context.Context, input models.BreakGlassInput) (models.OperationResult, error) {
+	r = &BreakGlassMutationResolver{DB: session(ctx, r.DB), PC: r.PC}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return handleError("400", "Invalid input", ErrReasonRequired)
+	}
+
+	tenantID, err := scopeTenant(r.DB, input.ScopeID)
+	if err != nil {
+		if errors.Is(err, ErrInvalidScope) {
+			return handleError("400", "Invalid scope", err)
+		}
+		return handleError("500", "Error fetching scope", err)
+	}
+	db, err := scopeSession(ctx, r.DB, tenantID)
+	if err != nil {
+		return handleError("400", "Invalid scope", err)
+	}
+
+	var registration dto.BreakGlassPrincipal
+	if err := db.Where("principal_id = ? AND scope_id = ? AND row_status = 1", *userID, input.ScopeID).
+		First(&registration).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return handleError("403", "Break-glass access denied", ErrNotRegistered)
+		}
+		return handleError("500", "Error fetching break-glass registration", err)
+	}
+
+	now := time.Now()
+	var active int64
+	if err := db.Model(&dto.BreakGlassGrant{}).
+		Where("principal_id = ? AND scope_id = ? AND revoked_at IS NULL AND expires_at > ?", *userID, input.ScopeID, now).
+		Count(&active).Error; err != nil {
+		return handleError("500", "Error checking break-glass grants", err)
+	}
+	if active > 0 {
+		return handleError("409", "Break-glass access already active", ErrActiveGrant)
+	}
+
+	expiresAt := now.Add(TTL)
+	binding, err := bindings.Create(ctx, db, r.permitClient(), bindings.Grant{
+		Name:        "break-glass",
+		PrincipalID: *userID,
+		RoleID:      registration.RoleID,
+		TenantID:    tenantID,
+		ScopeID:     &input.ScopeID,
+		ExpiresAt:   &expiresAt,
+	}, *userID)
+	if err != nil {
+		return handleError("500", "Error creating break-glass binding", err)
+	}
+
+	grant := dto.BreakGlassGrant{
+		GrantID:        uuid.New(),
+		TenantID:       tenantID,
+		RegistrationID: registration.RegistrationID,
+		PrincipalID:    *userID,
+		RoleID:         registration.RoleID,
+		ScopeID:        input.ScopeID,
+		BindingID:      binding.BindingID,
+		Reason:         reason,
+		ExpiresAt:      expiresAt,
+	}
+	if err := db.Create(&grant).Error; err != nil {
+		if revokeErr := bindings.Revoke(ctx, db, r.permitClient(), binding.BindingID, *userID); revokeErr != nil {
+			err = errors.Join(err, revokeErr)
+		}
+		return handleError("500", "Error recording break-glass grant", err)
+	}
+
+	logger.LogWarn(fmt.Sprintf("Break-glass access granted to %s on %s until %s: %s",
+		*userID, input.ScopeID, expiresAt.Format(time.RFC3339), reason))
+	return utils.FormatSuccess([]models.Data{mapToGrant(&grant)})
+}
@@ -0,0 +1,294 @@
+package breakglass
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("Failed to register tenancy plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.Mst_ResourceTypes{}, &dto.TenantResource{}, &dto.TenantRoleAssignments{}, &dto.TNTRole{},
+		&dto.AuditEvent{}, &dto.BreakGlassPrincipal{}, &dto.BreakGlassGrant{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// fakePermit records the role assignments created and removed through the Permit facts API.
+type fakePermit struct {
+	mu       sync.Mutex
+	assign   []map[string]interface{}
+	unassign []map[string]interface{}
+}
+
+func (f *fakePermit) client(t *testing.T) *permit.PermitClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/v2/facts/proj/env/role_assignments" {
+			var body map[string]interface{}
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			f.mu.Lock()
+			if req.Method == http.MethodPost {
+				f.assign = append(f.assign, body)
+			} else {
+				f.unassign = append(f.unassign, body)
+			}
+			f.mu.Unlock()
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+}
+
+func userContext(userID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+// tenantContext returns the context of a request by userID in tenantID.
+func tenantContext(userID, tenantID uuid.UUID) context.Context {
+	ctx := userContext(userID)
+	ctx.Value("GinContextKey").(*gin.Context).Set("tenantID", tenantID.String())
+	return ctx
+}
+
+// seedScope creates a resource of the named built-in type. Tenants own
+// themselves; other resources belong to no tenant.
+func seedScope(t *testing.T, db *gorm.DB, typeName string) uuid.UUID {
+	db = tenancy.RootSession(db)
+	var resourceType dto.Mst_ResourceTypes
+	if err := db.Where("name = ?", typeName).First(&resourceType).Error; err != nil {
+		resourceType = dto.Mst_ResourceTypes{ResourceTypeID: uuid.New(), Name: typeName, RowStatus: 1}
+		require.NoError(t, db.Create(&resourceType).Error)
+	}
+	id := uuid.New()
+	var tenantID *uuid.UUID
+	if typeName == constants.ResourceTypeTenant {
+		tenantID = &id
+	}
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: id, ResourceTypeID: resourceType.ResourceTypeID, Name: typeName, TenantID: tenantID, RowStatus: 1}).Error)
+	return id
+}
+
+// bindGlobally gives principalID a binding outside any tenant.
+func bindGlobally(t *testing.T, db *gorm.DB, principalID uuid.UUID) {
+	require.NoError(t, tenancy.RootSession(db).Create(&dto.TenantRoleAssignments{ResourceID: uuid.New(), Name: "operator", Version: "V1", PrincipalID: principalID, RoleID: uuid.New(), RowStatus: 1}).Error)
+}
+
+func errorCode(t *testing.T, result models.OperationResult) string {
+	require.IsType(t, &models.ResponseError{}, result)
+	return result.(*models.ResponseError).ErrorCode
+}
+
+func TestBreakGlassGrantsShortLivedAccess(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	fp := &fakePermit{}
+	resolver := &BreakGlassMutationResolver{DB: db, PC: fp.client(t)}
+
+	tenantID := seedScope(t, db, constants.ResourceTypeTenant)
+	roleID := uuid.New()
+	require.NoError(t, db.Create(&dto.TNTRole{ResourceID: roleID, Name: "SuperAdmin", RowStatus: 1}).Error)
+	responder, admin := uuid.New(), uuid.New()
+	ctx := userContext(responder)
+
+	// Only registered principals may break glass, and only with a reason
+	result, err := resolver.BreakGlass(ctx, models.BreakGlassInput{Reason: "outage", ScopeID: tenantID})
+	require.NoError(t, err)
+	assert.Equal(t, "403", errorCode(t, result))
+
+	result, err = resolver.RegisterBreakGlassPrincipal(userContext(admin), models.RegisterBreakGlassPrincipalInput{
+		PrincipalID: responder, RoleID: roleID, ScopeID: tenantID,
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+
+	result, err = resolver.BreakGlass(ctx, models.BreakGlassInput{Reason: "  ", ScopeID: tenantID})
+	require.NoError(t, err)
+	assert.Equal(t, "400", errorCode(t, result))
+
+	result, err = resolver.BreakGlass(ctx, models.BreakGlassInput{Reason: "INC-42 database outage", ScopeID: tenantID})
+	require.NoError(t, err)
+	grant := result.(*models.SuccessResponse).Data[0].(*models.BreakGlassGrant)
+	expiresAt, err := time.Parse(time.RFC3339, grant.ExpiresAt)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(TTL), expiresAt, time.Minute)
+	require.Len(t, fp.assign, 1)
+	assert.Equal(t, map[string]interface{}{
+		"user":   responder.String(),
+		"role":   roleID.String(),
+		"tenant": tenantID.String(),
+	}, fp.assign[0])
+
+	held, err := bindings.HoldsRole(db, responder, roleID, tenantID)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	result, err = resolver.BreakGlass(ctx, models.BreakGlassInput{Reason: "again", ScopeID: tenantID})
+	require.NoError(t, err)
+	assert.Equal(t, "409", errorCode(t, result))
+
+	// Once the TTL passes the binding stops counting even before it is swept
+	past := time.Now().Add(-time.Second)
+	require.NoError(t, tenancy.RootSession(db).Model(&dto.TenantRoleAssignments{}).Where("resource_id = ?", grant.BindingID).Update("expires_at", past).Error)
+	require.NoError(t, tenancy.RootSession(db).Model(&dto.BreakGlassGrant{}).Where("grant_id = ?", grant.ID).Update("expires_at", past).Error)
+	held, err = bindings.HoldsRole(db, responder, roleID, tenantID)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	siem := &recordingPublisher{}
+	revoked, err := RevokeExpired(context.Background(), db, resolver.PC, siem)
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+	require.Len(t, fp.unassign, 1)
+	assert.Equal(t, fp.assign[0], fp.unassign[0])
+
+	var stored dto.BreakGlassGrant
+	require.NoError(t, tenancy.RootSession(db).Where("grant_id = ?", grant.ID).First(&stored).Error)
+	assert.NotNil(t, stored.RevokedAt)
+	var events []dto.AuditEvent
+	require.NoError(t, tenancy.RootSession(db).Where("operation = ?", "breakGlassExpired").Find(&events).Error)
+	require.Len(t, events, 1)
+	assert.Equal(t, grant.ID.String(), events[0].TargetResourceID)
+	require.Len(t, siem.events, 1)
+	assert.Equal(t, events[0].EventID, siem.events[0].EventID)
+
+	revoked, err = RevokeExpired(context.Background(), db, resolver.PC)
+	require.NoError(t, err)
+	assert.Zero(t, revoked)
+
+	active := true
+	result, err = (&BreakGlassQueryResolver{DB: db}).BreakGlassGrants(ctx, &active)
+	require.NoError(t, err)
+	assert.Empty(t, result.(*models.SuccessResponse).Data)
+}
+
+// recordingPublisher keeps the audit events handed to it.
+type recordingPublisher struct{ events []dto.AuditEvent }
+
+func (p *recordingPublisher) Publish(event dto.AuditEvent) { p.events = append(p.events, event) }
+
+func TestBreakGlassOnRootScope(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	fp := &fakePermit{}
+	resolver := &BreakGlassMutationResolver{DB: db, PC: fp.client(t)}
+
+	rootID := seedScope(t, db, constants.ResourceTypeRoot)
+	groupID := seedScope(t, db, constants.ResourceTypeGroup)
+	roleID := uuid.New()
+	require.NoError(t, db.Create(&dto.TNTRole{ResourceID: roleID, Name: "SuperAdmin", RowStatus: 1}).Error)
+	responder := uuid.New()
+
+	result, err := resolver.RegisterBreakGlassPrincipal(userContext(uuid.New()), models.RegisterBreakGlassPrincipalInput{
+		PrincipalID: responder, RoleID: roleID, ScopeID: groupID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "400", errorCode(t, result), "only Root and Tenant scopes are allowed")
+
+	// Root registrations reach beyond any tenant
+	result, err = resolver.RegisterBreakGlassPrincipal(userContext(uuid.New()), models.RegisterBreakGlassPrincipalInput{
+		PrincipalID: responder, RoleID: roleID, ScopeID: rootID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "403", errorCode(t, result))
+
+	operator := uuid.New()
+	bindGlobally(t, db, operator)
+	result, err = resolver.RegisterBreakGlassPrincipal(userContext(operator), models.RegisterBreakGlassPrincipalInput{
+		PrincipalID: responder, RoleID: roleID, ScopeID: rootID,
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+
+	result, err = resolver.BreakGlass(userContext(responder), models.BreakGlassInput{Reason: "platform outage", ScopeID: rootID})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	require.Len(t, fp.assign, 1)
+	assert.NotContains(t, fp.assign[0], "tenant")
+
+	held, err := bindings.HoldsRole(db, responder, roleID, rootID)
+	require.NoError(t, err)
+	assert.True(t, held)
+}
+
+func TestBreakGlassRegistrationsAreTenantScoped(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	resolver := &BreakGlassMutationResolver{DB: db, PC: (&fakePermit{}).client(t)}
+
+	rootID := seedScope(t, db, constants.ResourceTypeRoot)
+	acme, globex := seedScope(t, db, constants.ResourceTypeTenant), seedScope(t, db, constants.ResourceTypeTenant)
+	roleID := uuid.New()
+	require.NoError(t, db.Create(&dto.TNTRole{ResourceID: roleID, Name: "SuperAdmin", RowStatus: 1}).Error)
+	admin, operator, responder := uuid.New(), uuid.New(), uuid.New()
+	bindGlobally(t, db, operator)
+	register := func(ctx context.Context, scopeID uuid.UUID) models.OperationResult {
+		result, err := resolver.RegisterBreakGlassPrincipal(ctx, models.RegisterBreakGlassPrincipalInput{PrincipalID: responder, RoleID: roleID, ScopeID: scopeID})
+		require.NoError(t, err)
+		return result
+	}
+	deregister := func(ctx context.Context, id uuid.UUID) models.OperationResult {
+		result, err := resolver.DeregisterBreakGlassPrincipal(ctx, models.DeleteInput{ID: id})
+		require.NoError(t, err)
+		return result
+	}
+	registered := func(result models.OperationResult) uuid.UUID {
+		require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+		return result.(*models.SuccessResponse).Data[0].(*models.BreakGlassPrincipal).ID
+	}
+
+	// A tenant administrator registers on their tenant only
+	adminCtx := tenantContext(admin, acme)
+	acmeRegistration := registered(register(adminCtx, acme))
+	assert.Equal(t, "400", errorCode(t, register(adminCtx, globex)))
+	assert.Equal(t, "403", errorCode(t, register(adminCtx, rootID)))
+	var stored dto.BreakGlassPrincipal
+	require.NoError(t, tenancy.RootSession(db).Where("registration_id = ?", acmeRegistration).First(&stored).Error)
+	assert.Equal(t, acme, *stored.TenantID)
+
+	globexRegistration := registered(register(userContext(operator), globex))
+	rootRegistration := registered(register(tenantContext(operator, acme), rootID))
+
+	result, err := (&BreakGlassQueryResolver{DB: db}).BreakGlassPrincipals(adminCtx)
+	require.NoError(t, err)
+	require.Len(t, result.(*models.SuccessResponse).Data, 1)
+	assert.Equal(t, acmeRegistration, result.(*models.SuccessResponse).Data[0].(*models.BreakGlassPrincipal).ID)
+
+	// Nor do they deregister the registrations of other tenants or Root
+	assert.Equal(t, "404", errorCode(t, deregister(adminCtx, globexRegistration)))
+	assert.Equal(t, "403", errorCode(t, deregister(adminCtx, rootRegistration)))
+	require.IsType(t, &models.SuccessResponse{}, deregister(adminCtx, acmeRegistration))
+	require.IsType(t, &models.SuccessResponse{}, deregister(tenantContext(operator, acme), rootRegistration))
+
+	var active int64
+	require.NoError(t, tenancy.RootSession(db).Model(&dto.BreakGlassPrincipal{}).Where("row_status = 1").Count(&active).Error)
+	assert.Equal(t, int64(1), active)
+}
@@ -0,0 +1,56 @@
+package breakglass
+
+import (
+	"context"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BreakGlassQueryResolver handles break-glass registration and grant queries.
+type BreakGlassQueryResolver struct {
+	DB *gorm.DB
+}
+
+// BreakGlassGrants lists break-glass grants, newest first. Active grants are
+// those neither revoked nor past their expiry.
+func (r *BreakGlassQueryResolver) BreakGlassGrants(ctx context.Context, active *bool) (models.OperationResult, error) {
+	r = &BreakGlassQueryResolver{DB: session(ctx, r.DB)}
+	query := r.DB.Model(&dto.BreakGlassGrant{})
+	if active != nil {
+		now := time.Now()
+		if *active {
+			query = query.Where("revoked_at IS NULL AND expires_at > ?", now)
+		} else {
+			query = query.Where("revoked_at IS NOT NULL OR expires_at <= ?", now)
+		}
+	}
+	var grants []dto.BreakGlassGrant
+	if err := query.Order("created_at DESC").Find(&grants).Error; err != nil {
+		return handleError("500", "Error fetching break-glass grants", err)
+	}
+
+	data := make([]models.Data, 0, len(grants))
+	for i := range grants {
+		data = append(data, mapToGrant(&grants[i]))
+	}
+	return utils.FormatSuccess(data)
+}
+
+// BreakGlassPrincipals lists the active break-glass registrations.
+func (r *BreakGlassQueryResolver) BreakGlassPrincipals(ctx context.Context) (models.OperationResult, error) {
+	r = &BreakGlassQueryResolver{DB: session(ctx, r.DB)}
+	var registrations []dto.BreakGlassPrincipal
+	if err := r.DB.Where("row_status = 1").Order("created_at").Find(&registrations).Error; err != nil {
+		return handleError("500", "Error fetching break-glass principals", err)
+	}
+
+	data := make([]models.Data, 0, len(registrations))
+	for i := range registrations {
+		data = append(data, mapToPrincipal(&registrations[i]))
+	}
+	return utils.FormatSuccess(data)
+}
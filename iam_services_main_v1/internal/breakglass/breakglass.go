@@ -0,0 +1,202 @@
+package breakglass
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TTL is the fixed lifetime of break-glass access. The binding carries the
+// expiry, so it stops counting as soon as it lapses even if the sweeper that
+// removes it from Permit is not running.
+const TTL = time.Hour
+
+// SystemActorID identifies the expiry sweeper in the audit log.
+const SystemActorID = "system:break-glass-sweeper"
+
+var (
+	ErrNotRegistered         = errors.New("principal is not registered for break-glass access on this scope")
+	ErrAlreadyRegistered     = errors.New("principal is already registered on this scope")
+	ErrRegistrationNotFound  = errors.New("break-glass registration not found")
+	ErrActiveGrant           = errors.New("principal already has active break-glass access on this scope")
+	ErrInvalidScope          = errors.New("scope must be a Root or Tenant resource")
+	ErrGlobalBindingRequired = errors.New("registrations on a Root scope are managed by principals with a binding outside any tenant")
+	ErrRoleNotFound          = errors.New("role not found")
+	ErrReasonRequired        = errors.New("reason is required")
+)
+
+// scopeTenant validates that scopeID is an active Root or Tenant resource and
+// returns the tenant bindings on it belong to; Root scopes have none.
+func scopeTenant(db *gorm.DB, scopeID uuid.UUID) (*uuid.UUID, error) {
+	var typeName string
+	err := db.Table("tnt_resources AS r").
+		Select("t.name").
+		Joins("JOIN mst_resource_types t ON t.resource_type_id = r.resource_type_id").
+		Where("r.resource_id = ? AND r.row_status = 1", scopeID).
+		Row().Scan(&typeName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidScope
+		}
+		return nil, fmt.Errorf("failed to fetch scope: %w", err)
+	}
+	switch typeName {
+	case constants.ResourceTypeRoot:
+		return nil, nil
+	case constants.ResourceTypeTenant:
+		return &scopeID, nil
+	default:
+		return nil, ErrInvalidScope
+	}
+}
+
+// session scopes db to the request's tenant. Root-level requests manage the
+// break-glass access of every tenant and of Root.
+func session(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return tenancy.ForRequest(tenancy.AllowRoot(ctx), db)
+}
+
+// scopeSession returns the session of db through which the break-glass rows
+// of a scope in tenantID are read and written. Rows on Root scopes belong to
+// no tenant and are reached outside tenant isolation; scopes of another
+// tenant than the request's are refused as invalid.
+func scopeSession(ctx context.Context, db *gorm.DB, tenantID *uuid.UUID) (*gorm.DB, error) {
+	if tenantID == nil {
+		return tenancy.RootSession(db), nil
+	}
+	if requestTenant := tenancy.TenantFromContext(ctx); requestTenant != nil && *requestTenant != *tenantID {
+		return nil, ErrInvalidScope
+	}
+	return db, nil
+}
+
+// requireGlobalBinding fails with ErrGlobalBindingRequired unless the
+// request's principal holds a binding outside any tenant or on Root.
+func requireGlobalBinding(ctx context.Context, db *gorm.DB) error {
+	principalID, err := helpers.GetPrincipalID(ctx)
+	if err != nil {
+		return err
+	}
+	global, err := bindings.HoldsGlobalBinding(db, *principalID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch bindings: %w", err)
+	}
+	if !global {
+		return ErrGlobalBindingRequired
+	}
+	return nil
+}
+
+func roleExists(db *gorm.DB, roleID uuid.UUID) error {
+	var count int64
+	if err := db.Model(&dto.TNTRole{}).Where("resource_id = ? AND row_status = 1", roleID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to fetch role: %w", err)
+	}
+	if count == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// RevokeExpired removes the bindings of lapsed break-glass grants from Permit
+// and the database and records each revocation in the audit log, handing it
+// to the publishers. Grants that fail to revoke are retried on the next call.
+func RevokeExpired(ctx context.Context, db *gorm.DB, pc *permit.PermitClient, publishers ...audit.Publisher) (int, error) {
+	// The sweeper covers the grants of every tenant
+	db = tenancy.RootSession(db.WithContext(ctx))
+
+	var grants []dto.BreakGlassGrant
+	if err := db.Where("revoked_at IS NULL AND expires_at <= ?", time.Now()).Find(&grants).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch expired break-glass grants: %w", err)
+	}
+
+	revoked := 0
+	var errs []error
+	for _, grant := range grants {
+		if err := bindings.Revoke(ctx, db, pc, grant.BindingID, uuid.Nil); err != nil {
+			errs = append(errs, fmt.Errorf("grant %s: %w", grant.GrantID, err))
+			continue
+		}
+		now := time.Now()
+		if err := db.Model(&dto.BreakGlassGrant{}).Where("grant_id = ?", grant.GrantID).Update("revoked_at", now).Error; err != nil {
+			errs = append(errs, fmt.Errorf("grant %s: failed to mark revoked: %w", grant.GrantID, err))
+			continue
+		}
+		if _, err := audit.Record(db, audit.Entry{
+			ActorID:          SystemActorID,
+			Operation:        "breakGlassExpired",
+			TargetResourceID: grant.GrantID.String(),
+			Outcome:          audit.OutcomeSuccess,
+		}, publishers...); err != nil {
+			errs = append(errs, fmt.Errorf("grant %s: %w", grant.GrantID, err))
+		}
+		revoked++
+	}
+	return revoked, errors.Join(errs...)
+}
+
+// RunExpirySweeper calls RevokeExpired every interval until ctx is done.
+func RunExpirySweeper(ctx context.Context, db *gorm.DB, pc *permit.PermitClient, interval time.Duration, publishers ...audit.Publisher) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := RevokeExpired(ctx, db, pc, publishers...); err != nil {
+				logger.LogError(fmt.Sprintf("Break-glass expiry sweep failed: %v", err))
+			}
+		}
+	}
+}
+
+func mapToGrant(grant *dto.BreakGlassGrant) *models.BreakGlassGrant {
+	result := &models.BreakGlassGrant{
+		ID:          grant.GrantID,
+		BindingID:   grant.BindingID,
+		PrincipalID: grant.PrincipalID,
+		RoleID:      grant.RoleID,
+		ScopeID:     grant.ScopeID,
+		Reason:      grant.Reason,
+		ExpiresAt:   grant.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:   grant.CreatedAt.Format(time.RFC3339),
+	}
+	if grant.RevokedAt != nil {
+		revokedAt := grant.RevokedAt.Format(time.RFC3339)
+		result.RevokedAt = &revokedAt
+	}
+	return result
+}
+
+func mapToPrincipal(registration *dto.BreakGlassPrincipal) *models.BreakGlassPrincipal {
+	return &models.BreakGlassPrincipal{
+		ID:          registration.RegistrationID,
+		PrincipalID: registration.PrincipalID,
+		RoleID:      registration.RoleID,
+		ScopeID:     registration.ScopeID,
+		CreatedAt:   registration.CreatedAt.Format(time.RFC3339),
+		CreatedBy:   registration.CreatedBy,
+	}
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
@@ -0,0 +1,70 @@
+// Package bulkimport imports users, groups and role bindings into a tenant
+// from CSV files. A file is validated as it is read and each row that cannot
+// be imported is reported; an import job then commits the valid rows in
+// chunks, recording its progress for the importJob query.
+//
+// The header row names the columns, in any order and case:
+//
+//	type          user, group or binding (required)
+//	name          the user name, the group name or the binding name; a
+//	              binding is named after its role by default
+//	email         the user's email; on a binding row, the user granted the role
+//	display_name  the user's display name
+//	group         on a user row, a group the user joins; on a binding row, the
+//	              group granted the role
+//	role          the name or ID of the role a binding grants
+//	scope         the ID of the resource a binding applies to; the tenant by
+//	              default
+//
+// Rows may refer to the users and groups of earlier rows and to those of the
+// tenant. A row referring to a row that is not imported is not imported either.
+package bulkimport
+
+import (
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+)
+
+const (
+	// DefaultChunkSize is the number of valid rows committed together when
+	// the upload does not say.
+	DefaultChunkSize = 100
+	// MaxChunkSize bounds the rows committed in one transaction.
+	MaxChunkSize = 1000
+	// MaxRows bounds the data rows of a file, whose valid rows are held in
+	// memory until they are committed.
+	MaxRows = 50000
+)
+
+// Types of rows.
+const (
+	TypeUser    = "user"
+	TypeGroup   = "group"
+	TypeBinding = "binding"
+)
+
+// Columns of an import file.
+const (
+	ColumnType        = "type"
+	ColumnName        = "name"
+	ColumnEmail       = "email"
+	ColumnDisplayName = "display_name"
+	ColumnGroup       = "group"
+	ColumnRole        = "role"
+	ColumnScope       = "scope"
+)
+
+var columns = []string{ColumnType, ColumnName, ColumnEmail, ColumnDisplayName, ColumnGroup, ColumnRole, ColumnScope}
+
+// ErrInvalidFile is returned for files that cannot be imported at all, such
+// as files without a valid header.
+var ErrInvalidFile = errors.New("invalid import file")
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
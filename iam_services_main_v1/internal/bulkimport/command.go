@@ -0,0 +1,227 @@
+package bulkimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Usage describes the bulkimport command.
+const Usage = `usage: bulkimport import [flags]
+
+Uploads a CSV file of users, groups and bindings, waits for the import job
+to finish, then shows the rows that were not imported.
+
+flags:
+  -f file          the CSV file to import, or - for standard input (required)
+  -chunk-size n    the rows committed together (default 100)
+  -server url      the base URL of the service (default $IAM_SERVER)
+  -tenant id       the tenant to import into (default $IAM_TENANT_ID)
+  -user id         the user making the import (default $IAM_USER_ID)
+  -poll duration   the interval between progress checks (default 2s)`
+
+var ErrUsage = errors.New(Usage)
+
+const importJobQuery = `query ($id: UUID!) {
+  importJob(id: $id) {
+    ... on SuccessResponse { data { ...job } }
+    ... on ResponseError { errorCode message systemMessage }
+  }
+}
+
+fragment job on ImportJob {
+  id status error totalRows validRows invalidRows importedRows failedRows
+  rows { row type name status errors }
+}`
+
+// RunCommand runs the bulkimport command given its arguments, uploading the
+// file with client and writing the progress and the row report to out. It
+// fails when rows were not imported.
+func RunCommand(ctx context.Context, client *http.Client, args []string, stdin io.Reader, out io.Writer) error {
+	if len(args) == 0 || args[0] != "import" {
+		return ErrUsage
+	}
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	file := flags.String("f", "", "")
+	chunkSize := flags.Int("chunk-size", DefaultChunkSize, "")
+	server := flags.String("server", os.Getenv("IAM_SERVER"), "")
+	tenant := flags.String("tenant", os.Getenv("IAM_TENANT_ID"), "")
+	user := flags.String("user", os.Getenv("IAM_USER_ID"), "")
+	poll := flags.Duration("poll", 2*time.Second, "")
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() > 0 {
+		return ErrUsage
+	}
+	if *file == "" || *server == "" || *tenant == "" || *user == "" || *chunkSize < 1 || *poll <= 0 {
+		return ErrUsage
+	}
+
+	body, fileName := stdin, ""
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		body, fileName = f, filepath.Base(f.Name())
+	}
+	c := &serviceClient{client: client, server: strings.TrimSuffix(*server, "/"), tenant: *tenant, user: *user}
+
+	query := url.Values{"chunkSize": {strconv.Itoa(*chunkSize)}}
+	if fileName != "" {
+		query.Set("fileName", fileName)
+	}
+	job, err := c.upload(ctx, query, body)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "import job %s: %d of %d rows are valid\n", job.ID, job.ValidRows, job.TotalRows)
+
+	for job.Status == "RUNNING" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(*poll):
+		}
+		imported, failed := job.ImportedRows, job.FailedRows
+		if job, err = c.job(ctx, job.ID); err != nil {
+			return err
+		}
+		if job.ImportedRows != imported || job.FailedRows != failed {
+			fmt.Fprintf(out, "imported %d of %d rows\n", job.ImportedRows, job.ValidRows)
+		}
+	}
+	return writeReport(out, job)
+}
+
+type jobReply struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	Error        string `json:"error"`
+	TotalRows    int    `json:"totalRows"`
+	ValidRows    int    `json:"validRows"`
+	InvalidRows  int    `json:"invalidRows"`
+	ImportedRows int    `json:"importedRows"`
+	FailedRows   int    `json:"failedRows"`
+	Rows         []struct {
+		Row    int      `json:"row"`
+		Type   string   `json:"type"`
+		Name   string   `json:"name"`
+		Status string   `json:"status"`
+		Errors []string `json:"errors"`
+	} `json:"rows"`
+}
+
+// resultReply is the OperationResult of a job.
+type resultReply struct {
+	Data          []jobReply `json:"data"`
+	ErrorCode     string     `json:"errorCode"`
+	Message       string     `json:"message"`
+	SystemMessage string     `json:"systemMessage"`
+}
+
+func (r *resultReply) job() (*jobReply, error) {
+	if r.ErrorCode != "" {
+		return nil, fmt.Errorf("%s %s: %s", r.ErrorCode, r.Message, r.SystemMessage)
+	}
+	if len(r.Data) != 1 {
+		return nil, errors.New("the service returned no import job")
+	}
+	return &r.Data[0], nil
+}
+
+type serviceClient struct {
+	client               *http.Client
+	server, tenant, user string
+}
+
+func (c *serviceClient) do(ctx context.Context, path, contentType string, body io.Reader, reply interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.server+path, body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("X-Tenant-ID", c.tenant)
+	request.Header.Set("userID", c.user)
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if err := json.NewDecoder(response.Body).Decode(reply); err != nil {
+		return fmt.Errorf("%s: %w", response.Status, err)
+	}
+	return nil
+}
+
+// upload sends the file to the upload endpoint, streaming it.
+func (c *serviceClient) upload(ctx context.Context, query url.Values, file io.Reader) (*jobReply, error) {
+	var reply resultReply
+	if err := c.do(ctx, "/imports?"+query.Encode(), "text/csv", file, &reply); err != nil {
+		return nil, err
+	}
+	return reply.job()
+}
+
+func (c *serviceClient) job(ctx context.Context, id string) (*jobReply, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     importJobQuery,
+		"variables": map[string]interface{}{"id": id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var reply struct {
+		Data struct {
+			ImportJob resultReply `json:"importJob"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := c.do(ctx, "/graphql", "application/json", bytes.NewReader(body), &reply); err != nil {
+		return nil, err
+	}
+	if len(reply.Errors) > 0 {
+		return nil, errors.New(reply.Errors[0].Message)
+	}
+	return reply.Data.ImportJob.job()
+}
+
+// writeReport writes a row not imported per line, in file order.
+func writeReport(out io.Writer, job *jobReply) error {
+	if len(job.Rows) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ROW\tTYPE\tNAME\tSTATUS\tERRORS")
+		for _, row := range job.Rows {
+			name := row.Name
+			if name == "" {
+				name = "-"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", row.Row, row.Type, name, row.Status, strings.Join(row.Errors, "; "))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "imported %d of %d rows: %d invalid, %d failed\n", job.ImportedRows, job.TotalRows, job.InvalidRows, job.FailedRows)
+	if job.Status == "FAILED" {
+		return fmt.Errorf("import job %s failed: %s", job.ID, job.Error)
+	}
+	if job.ImportedRows < job.TotalRows {
+		return fmt.Errorf("%d row(s) were not imported", job.TotalRows-job.ImportedRows)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package bulkimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	var uploaded, chunkSize, tenant string
+	var polls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant = r.Header.Get("X-Tenant-ID")
+		switch r.URL.Path {
+		case "/imports":
+			body, _ := io.ReadAll(r.Body)
+			uploaded, chunkSize = string(body), r.URL.Query().Get("chunkSize")
+			_, _ = w.Write([]byte(`{"isSuccess": true, "data": [{"id": "job-1", "status": "RUNNING", "totalRows": 3, "validRows": 2,
+				"rows": [{"row": 4, "type": "user", "name": "bob", "status": "INVALID", "errors": ["invalid email \"bob\""]}]}]}`))
+		case "/graphql":
+			var body struct {
+				Variables map[string]interface{} `json:"variables"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "job-1", body.Variables["id"])
+			polls++
+			status := "RUNNING"
+			if polls > 1 {
+				status = "COMPLETED"
+			}
+			_, _ = w.Write([]byte(`{"data": {"importJob": {"data": [{"id": "job-1", "status": "` + status + `", "totalRows": 3, "validRows": 2,
+				"invalidRows": 1, "importedRows": 2, "rows": [{"row": 4, "type": "user", "name": "bob", "status": "INVALID", "errors": ["invalid email \"bob\""]}]}]}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	file := "type,name,email\nuser,alice,alice@example.com\nuser,bob,bob\n"
+	args := []string{"import", "-f", "-", "-chunk-size", "50", "-poll", "1ms", "-server", srv.URL + "/", "-tenant", "acme", "-user", "ci"}
+	err := RunCommand(context.Background(), srv.Client(), args, strings.NewReader(file), &out)
+	assert.EqualError(t, err, "1 row(s) were not imported")
+	assert.Equal(t, file, uploaded)
+	assert.Equal(t, "50", chunkSize)
+	assert.Equal(t, "acme", tenant)
+	assert.Equal(t, 2, polls)
+	assert.Contains(t, out.String(), "import job job-1: 2 of 3 rows are valid")
+	assert.Contains(t, out.String(), "4    user  bob   INVALID  invalid email \"bob\"")
+	assert.Contains(t, out.String(), "imported 2 of 3 rows: 1 invalid, 0 failed")
+
+	assert.ErrorIs(t, RunCommand(context.Background(), srv.Client(), []string{"import", "-server", srv.URL}, nil, &out), ErrUsage)
+	assert.ErrorIs(t, RunCommand(context.Background(), srv.Client(), []string{"export"}, nil, &out), ErrUsage)
+}
@@ -0,0 +1,217 @@
+package bulkimport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/authz"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateAction is the permission uploading an import file requires.
+const CreateAction = "bulkImport.create"
+
+// Handler serves the upload endpoint of import files.
+type Handler struct {
+	// DB stores the import jobs and the audit log the imported rows are
+	// recorded in; Publishers receive their events.
+	DB         *gorm.DB
+	Publishers []audit.Publisher
+	Store      repository.Store
+	// PC is the Permit client; when nil one is configured from the environment.
+	PC *permit.PermitClient
+	// Policy decides whether the uploader holds CreateAction in the tenant.
+	Policy authz.PolicyEngine
+
+	// jobs tracks the jobs committing rows.
+	jobs sync.WaitGroup
+}
+
+func (h *Handler) permitClient() *permit.PermitClient {
+	if h.PC != nil {
+		return h.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// Upload validates the CSV file of the request into the request's tenant and
+// starts the job committing its valid rows. The file is the request body,
+// or its "file" part for multipart forms; the chunkSize query parameter sets
+// the rows committed together. It answers 202 with the job, whose rows
+// report the invalid rows of the file.
+func (h *Handler) Upload(c *gin.Context) {
+	ctx := c.Request.Context()
+	if _, ok := ctx.Value("GinContextKey").(*gin.Context); !ok {
+		ctx = context.WithValue(ctx, "GinContextKey", c)
+	}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		abort(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	principalID, err := helpers.GetPrincipalID(ctx)
+	if err != nil {
+		abort(c, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	tenantID := tenancy.TenantFromContext(ctx)
+	if tenantID == nil {
+		abort(c, http.StatusBadRequest, "Invalid tenant ID", errors.New("imports are made into the tenant of the request"))
+		return
+	}
+	allowed, err := h.Policy.Allowed(ctx, *principalID, CreateAction, nil, tenantID)
+	if err != nil {
+		abort(c, http.StatusInternalServerError, "Error checking permission", err)
+		return
+	}
+	if !allowed {
+		abortWithCode(c, http.StatusForbidden, authz.ForbiddenCode, "Forbidden", fmt.Errorf("principal %s lacks permission %s", principalID, CreateAction))
+		return
+	}
+	if guard, ok := h.Policy.(authz.TenantGuard); ok {
+		err := guard.Writable(ctx, CreateAction, nil, tenantID)
+		if errors.Is(err, authz.ErrTenantInactive) {
+			abortWithCode(c, http.StatusConflict, authz.TenantInactiveCode, "Tenant is not active", err)
+			return
+		}
+		if err != nil {
+			abort(c, http.StatusInternalServerError, "Error checking tenant state", err)
+			return
+		}
+	}
+
+	chunkSize := DefaultChunkSize
+	if value := c.Query("chunkSize"); value != "" {
+		chunkSize, err = strconv.Atoi(value)
+		if err != nil || chunkSize < 1 || chunkSize > MaxChunkSize {
+			abort(c, http.StatusBadRequest, "Invalid chunk size", fmt.Errorf("chunkSize must be between 1 and %d", MaxChunkSize))
+			return
+		}
+	}
+	file, fileName, err := uploadedFile(c)
+	if err != nil {
+		abort(c, http.StatusBadRequest, "Invalid upload", err)
+		return
+	}
+
+	ctx = tenancy.WithTenant(ctx, *tenantID)
+	if _, err := h.Store.Resources().Get(ctx, *tenantID); err != nil {
+		abort(c, http.StatusNotFound, "Tenant not found", err)
+		return
+	}
+	p, err := newPlanner(ctx, h.Store, *tenantID, *userID)
+	if err == nil {
+		err = p.read(ctx, file)
+	}
+	var itemErr *batch.Error
+	switch {
+	case errors.Is(err, ErrInvalidFile):
+		abort(c, http.StatusBadRequest, "Invalid import file", err)
+		return
+	case errors.As(err, &itemErr):
+		abort(c, http.StatusInternalServerError, itemErr.Message, itemErr.Err)
+		return
+	case err != nil:
+		abort(c, http.StatusBadRequest, "Error reading import file", err)
+		return
+	}
+
+	job := &dto.ImportJob{
+		JobID:     uuid.New(),
+		TenantID:  tenantID,
+		FileName:  fileName,
+		Status:    dto.ImportJobStatusRunning,
+		ChunkSize: chunkSize,
+		TotalRows: p.total,
+		ValidRows: len(p.rows),
+		CreatedBy: *userID,
+	}
+	// The job outlives the request
+	jobCtx := tenancy.WithTenant(context.WithoutCancel(ctx), *tenantID)
+	db := h.DB.WithContext(jobCtx)
+	if err := db.Create(job).Error; err != nil {
+		abort(c, http.StatusInternalServerError, "Error creating import job", err)
+		return
+	}
+	if err := saveProgress(db, job, p.report); err != nil {
+		abort(c, http.StatusInternalServerError, "Error creating import job", err)
+		return
+	}
+	result, err := toImportJob(job)
+	if err != nil {
+		abort(c, http.StatusInternalServerError, "Error reading import job", err)
+		return
+	}
+
+	// The request is read before the job outlives it
+	changes := &changeLog{db: db, publishers: h.Publishers}
+	audit.FillRequestInfo(ctx, &changes.request)
+
+	logger.LogInfo(fmt.Sprintf("Import job %s started: %d of %d rows are valid", job.JobID, job.ValidRows, job.TotalRows))
+	h.jobs.Add(1)
+	go func() {
+		defer h.jobs.Done()
+		commit(jobCtx, db, h.Store, h.permitClient(), job, p.rows, p.report, changes)
+	}()
+
+	response, _ := utils.FormatSuccess([]models.Data{result})
+	c.JSON(http.StatusAccepted, response)
+}
+
+// uploadedFile returns the file of the request and its name, streaming it
+// from the body.
+func uploadedFile(c *gin.Context) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return c.Request.Body, c.Query("fileName"), nil
+	}
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, "", errors.New(`the form has no "file" part`)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if part.FormName() == "file" {
+			return part, part.FileName(), nil
+		}
+	}
+}
+
+func abort(c *gin.Context, status int, message string, err error) {
+	abortWithCode(c, status, strconv.Itoa(status), message, err)
+}
+
+func abortWithCode(c *gin.Context, status int, code, message string, err error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	if status >= http.StatusInternalServerError {
+		logger.LogError(em)
+	} else {
+		logger.LogWarn(em)
+	}
+	c.AbortWithStatusJSON(status, utils.FormatError(utils.FormatErrorStruct(code, message, strings.TrimSpace(em))))
+}
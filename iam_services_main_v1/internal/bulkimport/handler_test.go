@@ -0,0 +1,277 @@
+package bulkimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit/permittest"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakePolicy allows the actions it holds.
+type fakePolicy map[string]bool
+
+func (p fakePolicy) Allowed(ctx context.Context, principalID uuid.UUID, action string, scopeID, tenantID *uuid.UUID) (bool, error) {
+	return p[action], nil
+}
+
+// publisher collects the audit events it receives.
+type publisher struct {
+	mu     sync.Mutex
+	events []dto.AuditEvent
+}
+
+func (p *publisher) Publish(event dto.AuditEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+}
+
+// take returns the events received since the last call.
+func (p *publisher) take() []dto.AuditEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	events := p.events
+	p.events = nil
+	return events
+}
+
+type fixture struct {
+	handler   *Handler
+	router    *gin.Engine
+	permit    *permittest.Server
+	published *publisher
+	store     *repository.MemoryStore
+	db        *gorm.DB
+	tenantID  uuid.UUID
+	userID    uuid.UUID
+	types     map[string]uuid.UUID
+}
+
+func setupImport(t *testing.T) *fixture {
+	logger.InitLogger()
+	gin.SetMode(gin.TestMode)
+	fake := permittest.New(t)
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&dto.ImportJob{}, &dto.AuditEvent{}))
+
+	f := &fixture{permit: fake, published: &publisher{}, store: repository.NewMemoryStore(), db: db, tenantID: uuid.New(), userID: uuid.New(), types: map[string]uuid.UUID{}}
+	for _, name := range []string{constants.ResourceTypeTenant, constants.ResourceTypeGroup, constants.ResourceTypeUser, constants.ResourceTypeRole} {
+		f.types[name] = uuid.New()
+		f.store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: f.types[name], Name: name, RowStatus: 1})
+	}
+	ctx := tenancy.WithTenant(context.Background(), f.tenantID)
+	require.NoError(t, f.store.Resources().Create(ctx, &dto.TenantResource{ResourceID: f.tenantID, ResourceTypeID: f.types[constants.ResourceTypeTenant], Name: "acme", TenantID: &f.tenantID, RowStatus: 1}))
+	f.addRole(t, "reader")
+
+	pc := fake.Client()
+	f.handler = &Handler{DB: db, Publishers: []audit.Publisher{f.published}, Store: f.store, PC: pc, Policy: fakePolicy{CreateAction: true}}
+	f.router = gin.New()
+	f.router.POST("/imports", func(c *gin.Context) {
+		c.Set("userID", f.userID.String())
+		c.Set("tenantID", f.tenantID.String())
+	}, f.handler.Upload)
+	return f
+}
+
+func (f *fixture) addRole(t *testing.T, name string) uuid.UUID {
+	ctx := tenancy.WithTenant(context.Background(), f.tenantID)
+	id := uuid.New()
+	require.NoError(t, f.store.Resources().Create(ctx, &dto.TenantResource{ResourceID: id, ResourceTypeID: f.types[constants.ResourceTypeRole], Name: name, TenantID: &f.tenantID, RowStatus: 1}))
+	require.NoError(t, f.store.Roles().Create(ctx, &dto.TNTRole{ResourceID: id, Name: name, RowStatus: 1}))
+	return id
+}
+
+// upload posts file as a multipart form and waits for its job to finish.
+func (f *fixture) upload(t *testing.T, file, query string) (*httptest.ResponseRecorder, *models.ImportJob) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "users.csv")
+	require.NoError(t, err)
+	_, _ = part.Write([]byte(file))
+	require.NoError(t, form.Close())
+
+	request := httptest.NewRequest(http.MethodPost, "/imports"+query, &body)
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	recorder := httptest.NewRecorder()
+	f.router.ServeHTTP(recorder, request)
+	f.handler.jobs.Wait()
+	if recorder.Code != http.StatusAccepted {
+		return recorder, nil
+	}
+
+	var reply struct {
+		Data []struct {
+			ID uuid.UUID `json:"id"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &reply))
+	require.Len(t, reply.Data, 1)
+	return recorder, f.job(t, reply.Data[0].ID)
+}
+
+func (f *fixture) job(t *testing.T, id uuid.UUID) *models.ImportJob {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", f.userID.String())
+	ginCtx.Set("tenantID", f.tenantID.String())
+	ctx := context.WithValue(context.Background(), "GinContextKey", ginCtx)
+	result, err := (&ImportJobQueryResolver{DB: f.db}).ImportJob(ctx, id)
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	return result.(*models.SuccessResponse).Data[0].(*models.ImportJob)
+}
+
+func statuses(job *models.ImportJob) []string {
+	rows := make([]string, len(job.Rows))
+	for i, row := range job.Rows {
+		rows[i] = fmt.Sprintf("%d %s %v", row.Row, row.Status, row.Errors)
+	}
+	return rows
+}
+
+func TestUpload(t *testing.T) {
+	f := setupImport(t)
+	file := `type,name,email,display_name,group,role,scope
+group,admins,,,,,
+user,alice,alice@example.com,Alice,admins,,
+user,bob,not-an-email,,,,
+binding,,alice@example.com,,,Reader,
+binding,,,,admins,writer,
+binding,,not-an-email,,,reader,
+`
+	recorder, job := f.upload(t, file, "?chunkSize=2")
+	require.Equal(t, http.StatusAccepted, recorder.Code, recorder.Body.String())
+
+	assert.Equal(t, models.ImportJobStatusCompleted, job.Status)
+	assert.Equal(t, "users.csv", job.FileName)
+	assert.Equal(t, 2, job.ChunkSize)
+	assert.Equal(t, 6, job.TotalRows)
+	assert.Equal(t, 3, job.ValidRows)
+	assert.Equal(t, 3, job.InvalidRows)
+	assert.Equal(t, 3, job.ImportedRows)
+	assert.Equal(t, 0, job.FailedRows)
+	assert.NotNil(t, job.FinishedAt)
+	assert.Equal(t, []string{
+		`4 INVALID [invalid email "not-an-email"]`,
+		`6 INVALID [unknown role "writer"]`,
+		`7 INVALID [user "not-an-email" is not imported; see row 4]`,
+	}, statuses(job))
+
+	ctx := tenancy.WithTenant(context.Background(), f.tenantID)
+	alice, err := f.store.Principals().GetByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	members, err := f.store.Members().ListByMember(ctx, alice.ResourceID)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+	bindings, err := f.store.Assignments().ListByPrincipal(ctx, alice.ResourceID)
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	assert.Equal(t, "reader", bindings[0].Name)
+	assert.Equal(t, []string{"POST resource_instances", "POST resource_instances", "POST role_assignments"}, f.permit.Requests())
+
+	// Each imported row is in the audit log and reaches the publishers
+	var events []dto.AuditEvent
+	require.NoError(t, f.db.Order("sequence").Find(&events).Error)
+	assert.Equal(t, events, f.published.take())
+	require.Len(t, events, 3)
+	for i, operation := range []string{"importGroup", "importUser", "importBinding"} {
+		assert.Equal(t, operation, events[i].Operation)
+		assert.Equal(t, audit.OutcomeSuccess, events[i].Outcome)
+		assert.Equal(t, f.userID.String(), events[i].ActorID)
+		assert.Equal(t, f.tenantID.String(), events[i].TenantID)
+	}
+	assert.Equal(t, alice.ResourceID.String(), events[1].TargetResourceID)
+	assert.Equal(t, bindings[0].ResourceID.String(), events[2].TargetResourceID)
+
+	// Importing the file again only reports duplicates
+	_, job = f.upload(t, file, "")
+	assert.Equal(t, 0, job.ValidRows)
+	assert.Equal(t, 0, job.ImportedRows)
+	assert.Contains(t, statuses(job), `2 INVALID [group name "admins" is already used]`)
+	assert.Contains(t, statuses(job), `5 INVALID [the binding already exists]`)
+}
+
+func TestUploadFailedChunks(t *testing.T) {
+	f := setupImport(t)
+	f.permit.Fail("POST resource_instances")
+	file := `type,name,email,group,role
+group,admins,,,
+user,alice,alice@example.com,admins,
+binding,,alice@example.com,,reader
+`
+	_, job := f.upload(t, file, "?chunkSize=1")
+	assert.Equal(t, models.ImportJobStatusCompleted, job.Status)
+	assert.Equal(t, 3, job.ValidRows)
+	assert.Equal(t, 0, job.ImportedRows)
+	assert.Equal(t, 3, job.FailedRows)
+	require.Len(t, job.Rows, 3)
+	assert.Equal(t, models.ImportRowStatusFailed, job.Rows[0].Status)
+	assert.Equal(t, []string{"row 2 was not imported"}, job.Rows[1].Errors)
+	assert.Equal(t, []string{"row 3 was not imported"}, job.Rows[2].Errors)
+
+	_, err := f.store.Resources().GetTypeByName(context.Background(), constants.ResourceTypeGroup)
+	require.NoError(t, err)
+	resources, err := f.store.Resources().ListByTenant(tenancy.WithTenant(context.Background(), f.tenantID), f.tenantID)
+	require.NoError(t, err)
+	assert.Len(t, resources, 2, "only the tenant and its role remain")
+	// Only the row committed is recorded, not the rows depending on it
+	events := f.published.take()
+	require.Len(t, events, 1)
+	assert.Equal(t, "importGroup", events[0].Operation)
+	assert.Equal(t, audit.OutcomeFailure, events[0].Outcome)
+	assert.Equal(t, job.Rows[0].Errors[0], events[0].ErrorMessage)
+
+	// A chunk is committed atomically
+	f.permit.Fail("POST role_assignments")
+	_, job = f.upload(t, file, "?chunkSize=3")
+	assert.Equal(t, 3, job.FailedRows)
+	assert.Equal(t, []string{"row 4 of the same chunk failed"}, job.Rows[0].Errors)
+	events = f.published.take()
+	require.Len(t, events, 3)
+	assert.Equal(t, "row 4 of the same chunk failed", events[0].ErrorMessage)
+	assert.Equal(t, audit.OutcomeFailure, events[2].Outcome)
+	resources, err = f.store.Resources().ListByTenant(tenancy.WithTenant(context.Background(), f.tenantID), f.tenantID)
+	require.NoError(t, err)
+	assert.Len(t, resources, 2)
+}
+
+func TestUploadRejected(t *testing.T) {
+	f := setupImport(t)
+
+	recorder, _ := f.upload(t, "kind,name\nuser,alice\n", "")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `unknown column \"kind\"`)
+
+	recorder, _ = f.upload(t, "type,name\n", "?chunkSize=0")
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	f.handler.Policy = fakePolicy{}
+	recorder, _ = f.upload(t, "type,name\n", "")
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+
+	var count int64
+	require.NoError(t, f.db.Model(&dto.ImportJob{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
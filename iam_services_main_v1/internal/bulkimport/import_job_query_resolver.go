@@ -0,0 +1,33 @@
+package bulkimport
+
+import (
+	"context"
+	"errors"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImportJobQueryResolver handles import job queries.
+type ImportJobQueryResolver struct {
+	DB *gorm.DB
+}
+
+// ImportJob returns the progress and row report of an import job.
+func (r *ImportJobQueryResolver) ImportJob(ctx context.Context, id uuid.UUID) (models.OperationResult, error) {
+	job, err := getJob(tenancy.ForRequest(ctx, r.DB), id)
+	if errors.Is(err, ErrJobNotFound) {
+		return handleError("404", "Import job not found", err)
+	}
+	if err != nil {
+		return handleError("500", "Error fetching import job", err)
+	}
+	result, err := toImportJob(job)
+	if err != nil {
+		return handleError("500", "Error reading import job", err)
+	}
+	return utils.FormatSuccess([]models.Data{result})
+}
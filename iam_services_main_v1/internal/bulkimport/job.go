@@ -0,0 +1,203 @@
+package bulkimport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/pkg/logger"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrJobNotFound is returned for jobs that do not exist in the tenant.
+var ErrJobNotFound = errors.New("import job not found")
+
+func getJob(db *gorm.DB, id uuid.UUID) (*dto.ImportJob, error) {
+	var job dto.ImportJob
+	if err := db.Where("job_id = ?", id).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// saveProgress stores the counts, report and status of a job.
+func saveProgress(db *gorm.DB, job *dto.ImportJob, report []*models.ImportRowReport) error {
+	sort.SliceStable(report, func(i, j int) bool { return report[i].Row < report[j].Row })
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	job.Report = dto.JSON(encoded)
+	return db.Model(job).Select("status", "imported_rows", "failed_rows", "report", "error", "finished_at", "updated_at").Updates(job).Error
+}
+
+// operations name the audit events of the rows of each type.
+var operations = map[string]string{
+	TypeUser:    "importUser",
+	TypeGroup:   "importGroup",
+	TypeBinding: "importBinding",
+}
+
+// changeLog records the rows a job commits in the audit log.
+type changeLog struct {
+	db *gorm.DB
+	// request holds the actor, tenant and client of the upload.
+	request    audit.Entry
+	publishers []audit.Publisher
+}
+
+// record appends the audit event of a row, failed with reason when it is
+// not empty. Failing to record it is logged and never fails the job.
+func (l *changeLog) record(r *row, reason string) {
+	entry := l.request
+	entry.Operation = operations[r.kind]
+	entry.TargetResourceID = r.id.String()
+	entry.Outcome = audit.OutcomeSuccess
+	if reason != "" {
+		entry.Outcome, entry.ErrorMessage = audit.OutcomeFailure, reason
+	}
+	snapshot, err := audit.Snapshot(l.db, r.id)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error taking audit snapshot of %s: %v", r.id, err))
+	}
+	entry.After = snapshot
+	if _, err := audit.Record(l.db, entry, l.publishers...); err != nil {
+		logger.LogError(fmt.Sprintf("Error recording audit event for %s: %v", entry.Operation, err))
+	}
+}
+
+// commit imports the valid rows of a job chunk by chunk. Each chunk is
+// committed atomically, in the database and in Permit; the rows of a chunk
+// that fails, and the rows depending on them, are reported as failed. The
+// rows of each chunk are recorded in changes. db and ctx must be scoped to
+// the job's tenant.
+func commit(ctx context.Context, db *gorm.DB, store repository.Store, pc *permit.PermitClient, job *dto.ImportJob, rows []*row, report []*models.ImportRowReport, changes *changeLog) {
+	failed := map[int]bool{}
+	fail := func(r *row, reason string) {
+		failed[r.line] = true
+		job.FailedRows++
+		report = append(report, &models.ImportRowReport{Row: r.line, Type: r.kind, Name: r.name, Status: models.ImportRowStatusFailed, Errors: []string{reason}})
+	}
+
+	for start := 0; start < len(rows); start += job.ChunkSize {
+		var chunk []*row
+		var items []batch.Item
+		var steps []batch.Step
+		// owners maps each item to its row
+		var owners []*row
+		for _, r := range rows[start:min(start+job.ChunkSize, len(rows))] {
+			if line := failedDependency(r, failed); line > 0 {
+				fail(r, fmt.Sprintf("row %d was not imported", line))
+				continue
+			}
+			chunk = append(chunk, r)
+			items = append(items, r.items...)
+			steps = append(steps, r.steps...)
+			for range r.items {
+				owners = append(owners, r)
+			}
+		}
+
+		if len(items) > 0 {
+			atomic := models.BatchModeAtomic
+			results := batch.Run(ctx, store, &atomic, items, batch.Phases(pc, steps))
+			if i, cause := batch.Cause(results, steps); cause != nil {
+				reason := cause.Message
+				if cause.ErrorDetails != nil {
+					reason = *cause.ErrorDetails
+				}
+				for _, r := range chunk {
+					rowReason := reason
+					if r != owners[i] {
+						rowReason = fmt.Sprintf("row %d of the same chunk failed", owners[i].line)
+					}
+					fail(r, rowReason)
+					changes.record(r, rowReason)
+				}
+			} else {
+				job.ImportedRows += len(chunk)
+				for _, r := range chunk {
+					changes.record(r, "")
+				}
+			}
+		}
+
+		if err := saveProgress(db, job, report); err != nil {
+			// The progress of the job can no longer be followed
+			logger.LogError(fmt.Sprintf("Error saving progress of import job %s: %v", job.JobID, err))
+			finish(db, job, report, fmt.Errorf("saving progress: %w", err))
+			return
+		}
+	}
+	finish(db, job, report, nil)
+}
+
+// finish marks a job completed, or failed with err.
+func finish(db *gorm.DB, job *dto.ImportJob, report []*models.ImportRowReport, err error) {
+	now := time.Now()
+	job.Status, job.FinishedAt = dto.ImportJobStatusCompleted, &now
+	if err != nil {
+		job.Status, job.Error = dto.ImportJobStatusFailed, err.Error()
+	}
+	if err := saveProgress(db, job, report); err != nil {
+		logger.LogError(fmt.Sprintf("Error finishing import job %s: %v", job.JobID, err))
+	}
+}
+
+// failedDependency returns a failed row r depends on, or 0.
+func failedDependency(r *row, failed map[int]bool) int {
+	for _, line := range r.depends {
+		if failed[line] {
+			return line
+		}
+	}
+	return 0
+}
+
+// toImportJob maps a job to its GraphQL representation.
+func toImportJob(job *dto.ImportJob) (*models.ImportJob, error) {
+	rows := []*models.ImportRowReport{}
+	if len(job.Report) > 0 {
+		if err := json.Unmarshal(job.Report, &rows); err != nil {
+			return nil, fmt.Errorf("invalid report of import job %s: %w", job.JobID, err)
+		}
+	}
+	result := &models.ImportJob{
+		ID:           job.JobID,
+		Status:       models.ImportJobStatus(job.Status),
+		FileName:     job.FileName,
+		ChunkSize:    job.ChunkSize,
+		TotalRows:    job.TotalRows,
+		ValidRows:    job.ValidRows,
+		InvalidRows:  job.TotalRows - job.ValidRows,
+		ImportedRows: job.ImportedRows,
+		FailedRows:   job.FailedRows,
+		Rows:         rows,
+		CreatedAt:    job.CreatedAt.Format(time.RFC3339),
+		CreatedBy:    job.CreatedBy,
+	}
+	if job.TenantID != nil {
+		result.TenantID = *job.TenantID
+	}
+	if job.Error != "" {
+		result.Error = &job.Error
+	}
+	if job.FinishedAt != nil {
+		finishedAt := job.FinishedAt.Format(time.RFC3339)
+		result.FinishedAt = &finishedAt
+	}
+	return result, nil
+}
@@ -0,0 +1,657 @@
+package bulkimport
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"io"
+	"net/mail"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Phases order the Permit requests of a chunk: users and groups must exist
+// before the bindings granting them roles.
+const (
+	phaseInstance = iota
+	phaseBinding
+)
+
+// maxNameLength is the size of the name and email columns of resources,
+// principals and bindings.
+const maxNameLength = 45
+
+// version is the version of the bindings an import creates.
+const version = "V1"
+
+// row is a valid row of a file and the batch items committing it.
+type row struct {
+	line int
+	kind string
+	name string
+	// id is the user, group or binding the row creates.
+	id    uuid.UUID
+	items []batch.Item
+	steps []batch.Step
+	// depends lists the earlier rows defining the users and groups it
+	// refers to.
+	depends []int
+}
+
+// principal is a user or group of the tenant or of an earlier row.
+type principal struct {
+	id uuid.UUID
+	// line is the row defining it, 0 for users and groups of the tenant.
+	line int
+}
+
+type bindingKey struct {
+	principalID, roleID, scopeID uuid.UUID
+}
+
+// planner validates the rows of a file against the tenant and the rows
+// before them.
+type planner struct {
+	store    repository.Store
+	tenantID uuid.UUID
+	userID   uuid.UUID
+
+	userType, groupType *dto.Mst_ResourceTypes
+	roles               []dto.TNTRole
+	// scopes holds the resources of the tenant; tenantUsers its users.
+	scopes      map[uuid.UUID]bool
+	tenantUsers map[uuid.UUID]bool
+	// users, emails and groups are keyed by lower-case user name, email and
+	// group name; emails only holds users of the file, as emails of the
+	// tenant's users are looked up.
+	users    map[string]principal
+	emails   map[string]principal
+	groups   map[string]principal
+	bindings map[bindingKey]int
+	// invalid maps the emails and group names of invalid rows, prefixed
+	// with their type, to their row.
+	invalid map[string]int
+
+	total  int
+	rows   []*row
+	report []*models.ImportRowReport
+}
+
+// newPlanner loads what rows are checked against from the tenant of ctx.
+func newPlanner(ctx context.Context, store repository.Store, tenantID, userID uuid.UUID) (*planner, error) {
+	p := &planner{
+		store:       store,
+		tenantID:    tenantID,
+		userID:      userID,
+		scopes:      map[uuid.UUID]bool{tenantID: true},
+		tenantUsers: map[uuid.UUID]bool{},
+		users:       map[string]principal{},
+		emails:      map[string]principal{},
+		groups:      map[string]principal{},
+		bindings:    map[bindingKey]int{},
+		invalid:     map[string]int{},
+	}
+	var err error
+	if p.userType, err = store.Resources().GetTypeByName(ctx, constants.ResourceTypeUser); err != nil {
+		return nil, batch.Fail("500", "Error getting resource type", err)
+	}
+	if p.groupType, err = store.Resources().GetTypeByName(ctx, constants.ResourceTypeGroup); err != nil {
+		return nil, batch.Fail("500", "Error getting resource type", err)
+	}
+	if p.roles, err = store.Roles().List(ctx); err != nil {
+		return nil, batch.Fail("500", "Error listing roles", err)
+	}
+
+	resources, err := store.Resources().ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, batch.Fail("500", "Error listing resources", err)
+	}
+	for _, resource := range resources {
+		p.scopes[resource.ResourceID] = true
+		switch resource.ResourceTypeID {
+		case p.userType.ResourceTypeID:
+			p.tenantUsers[resource.ResourceID] = true
+			p.users[strings.ToLower(resource.Name)] = principal{id: resource.ResourceID}
+		case p.groupType.ResourceTypeID:
+			p.groups[strings.ToLower(resource.Name)] = principal{id: resource.ResourceID}
+		}
+	}
+
+	assignments, err := store.Assignments().ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, batch.Fail("500", "Error listing bindings", err)
+	}
+	for _, assignment := range assignments {
+		scopeID := tenantID
+		if assignment.ScopeID != nil {
+			scopeID = *assignment.ScopeID
+		}
+		p.bindings[bindingKey{assignment.PrincipalID, assignment.RoleID, scopeID}] = 0
+	}
+	return p, nil
+}
+
+// read validates the rows of a CSV file in order. Invalid rows are reported;
+// the error is for files that cannot be read at all.
+func (p *planner) read(ctx context.Context, file io.Reader) error {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	names, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return err
+		}
+		var line int
+		if parseErr != nil {
+			line = parseErr.StartLine
+		} else {
+			line, _ = reader.FieldPos(0)
+		}
+		if err == nil && blank(record) {
+			// Spreadsheets export trailing empty rows
+			continue
+		}
+		if p.total++; p.total > MaxRows {
+			return fmt.Errorf("%w: the file has more than %d rows", ErrInvalidFile, MaxRows)
+		}
+		switch {
+		case parseErr != nil:
+			p.reject(line, "", "", []string{parseErr.Err.Error()})
+		case len(record) != len(names):
+			p.reject(line, "", "", []string{fmt.Sprintf("the row has %d fields, the header %d", len(record), len(names))})
+		default:
+			fields := make(map[string]string, len(names))
+			for i, name := range names {
+				fields[name] = strings.TrimSpace(record[i])
+			}
+			if err := p.plan(ctx, line, fields); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// parseHeader returns the column of each field of the header row.
+func parseHeader(header []string) ([]string, error) {
+	names := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, field := range header {
+		if i == 0 {
+			// Spreadsheets may start files with a byte order mark
+			field = strings.TrimPrefix(field, "\ufeff")
+		}
+		name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(field)), " ", "_")
+		known := false
+		for _, column := range columns {
+			known = known || name == column
+		}
+		switch {
+		case !known:
+			return nil, fmt.Errorf("%w: unknown column %q; the columns are %s", ErrInvalidFile, field, strings.Join(columns, ", "))
+		case seen[name]:
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidFile, field)
+		}
+		seen[name] = true
+		names[i] = name
+	}
+	if !seen[ColumnType] {
+		return nil, fmt.Errorf("%w: the %s column is required", ErrInvalidFile, ColumnType)
+	}
+	return names, nil
+}
+
+func blank(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// reject reports a row that failed validation.
+func (p *planner) reject(line int, kind, name string, problems []string) {
+	p.report = append(p.report, &models.ImportRowReport{
+		Row:    line,
+		Type:   kind,
+		Name:   name,
+		Status: models.ImportRowStatusInvalid,
+		Errors: problems,
+	})
+}
+
+// check collects the problems of a row.
+type check struct {
+	problems []string
+}
+
+func (c *check) fail(format string, args ...interface{}) {
+	c.problems = append(c.problems, fmt.Sprintf(format, args...))
+}
+
+// unused fails for the columns set on a row of kind that does not use them.
+func (c *check) unused(fields map[string]string, kind string, used ...string) {
+	for _, column := range columns {
+		if fields[column] == "" {
+			continue
+		}
+		allowed := column == ColumnType
+		for _, u := range used {
+			allowed = allowed || column == u
+		}
+		if !allowed {
+			c.fail("%s is not used on %s rows", column, kind)
+		}
+	}
+}
+
+// name checks a required name.
+func (c *check) name(label, value string) {
+	switch {
+	case value == "":
+		c.fail("%s is required", label)
+	case len(value) > maxNameLength:
+		c.fail("%s is longer than %d characters", label, maxNameLength)
+	}
+}
+
+// plan validates a row and, when it is valid, plans committing it.
+func (p *planner) plan(ctx context.Context, line int, fields map[string]string) error {
+	c := &check{}
+	kind := strings.ToLower(fields[ColumnType])
+	var planned *row
+	var err error
+	switch kind {
+	case TypeUser:
+		planned, err = p.planUser(ctx, c, line, fields)
+	case TypeGroup:
+		planned = p.planGroup(c, line, fields)
+	case TypeBinding:
+		planned, err = p.planBinding(ctx, c, line, fields)
+	case "":
+		c.fail("type is required")
+	default:
+		c.fail("unknown type %q; the types are %s, %s and %s", fields[ColumnType], TypeUser, TypeGroup, TypeBinding)
+	}
+	if err != nil {
+		return err
+	}
+	if len(c.problems) > 0 {
+		switch kind {
+		case TypeUser:
+			p.markInvalid(TypeUser, fields[ColumnEmail], line)
+		case TypeGroup:
+			p.markInvalid(TypeGroup, fields[ColumnName], line)
+		}
+		p.reject(line, fields[ColumnType], fields[ColumnName], c.problems)
+		return nil
+	}
+	p.rows = append(p.rows, planned)
+	return nil
+}
+
+// markInvalid remembers the invalid row defining a user or group, so that
+// the rows referring to it can say why it is unknown.
+func (p *planner) markInvalid(kind, key string, line int) {
+	key = kind + ":" + strings.ToLower(key)
+	if _, ok := p.invalid[key]; !ok && !strings.HasSuffix(key, ":") {
+		p.invalid[key] = line
+	}
+}
+
+func (p *planner) planUser(ctx context.Context, c *check, line int, fields map[string]string) (*row, error) {
+	name, email, displayName := fields[ColumnName], fields[ColumnEmail], fields[ColumnDisplayName]
+	c.unused(fields, TypeUser, ColumnName, ColumnEmail, ColumnDisplayName, ColumnGroup)
+	c.name("name", name)
+	switch {
+	case email == "":
+		c.fail("email is required")
+	case !validEmail(email):
+		c.fail("invalid email %q", email)
+	case len(email) > maxNameLength:
+		c.fail("email is longer than %d characters", maxNameLength)
+	}
+	if len(displayName) > maxNameLength {
+		c.fail("display_name is longer than %d characters", maxNameLength)
+	}
+
+	if existing, ok := p.users[strings.ToLower(name)]; ok && name != "" {
+		if existing.line > 0 {
+			c.fail("duplicate user name %q; see row %d", name, existing.line)
+		} else {
+			c.fail("user name %q is already used", name)
+		}
+	}
+	if existing, ok := p.emails[strings.ToLower(email)]; ok {
+		c.fail("duplicate email %q; see row %d", email, existing.line)
+	} else if validEmail(email) {
+		_, err := p.store.Principals().GetByEmail(ctx, email)
+		if err == nil {
+			c.fail("email %q is already used", email)
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, batch.Fail("500", "Error getting principal", err)
+		}
+	}
+	var depends []int
+	var group *principal
+	if groupName := fields[ColumnGroup]; groupName != "" {
+		if found, ok := p.group(c, groupName); ok {
+			group = &found
+			depends = dependency(depends, found)
+		}
+	}
+	if len(c.problems) > 0 {
+		return nil, nil
+	}
+
+	id := uuid.New()
+	p.users[strings.ToLower(name)] = principal{id: id, line: line}
+	p.emails[strings.ToLower(email)] = principal{id: id, line: line}
+	apply := func(ctx context.Context, tx repository.Store) error {
+		if err := p.createPrincipal(ctx, tx, id, p.userType, name, displayName, email); err != nil {
+			return err
+		}
+		if group == nil {
+			return nil
+		}
+		if err := tx.Members().Add(ctx, &dto.GroupMember{GroupID: group.id, MemberID: id, CreatedBy: p.userID}); err != nil {
+			return batch.Fail("500", "Error adding group member", err)
+		}
+		return nil
+	}
+	return &row{
+		line:    line,
+		kind:    TypeUser,
+		name:    name,
+		id:      id,
+		items:   []batch.Item{item(apply)},
+		steps:   []batch.Step{instanceStep(p.userType.ResourceTypeID, id, p.tenantID)},
+		depends: depends,
+	}, nil
+}
+
+func (p *planner) planGroup(c *check, line int, fields map[string]string) *row {
+	name := fields[ColumnName]
+	c.unused(fields, TypeGroup, ColumnName)
+	c.name("name", name)
+	if existing, ok := p.groups[strings.ToLower(name)]; ok && name != "" {
+		if existing.line > 0 {
+			c.fail("duplicate group name %q; see row %d", name, existing.line)
+		} else {
+			c.fail("group name %q is already used", name)
+		}
+	}
+	if len(c.problems) > 0 {
+		return nil
+	}
+
+	id := uuid.New()
+	p.groups[strings.ToLower(name)] = principal{id: id, line: line}
+	apply := func(ctx context.Context, tx repository.Store) error {
+		return p.createPrincipal(ctx, tx, id, p.groupType, name, name, "")
+	}
+	return &row{
+		line:  line,
+		kind:  TypeGroup,
+		name:  name,
+		id:    id,
+		items: []batch.Item{item(apply)},
+		steps: []batch.Step{instanceStep(p.groupType.ResourceTypeID, id, p.tenantID)},
+	}
+}
+
+func (p *planner) planBinding(ctx context.Context, c *check, line int, fields map[string]string) (*row, error) {
+	email, groupName, roleRef, scopeRef := fields[ColumnEmail], fields[ColumnGroup], fields[ColumnRole], fields[ColumnScope]
+	c.unused(fields, TypeBinding, ColumnName, ColumnEmail, ColumnGroup, ColumnRole, ColumnScope)
+
+	var who principal
+	var found bool
+	switch {
+	case (email == "") == (groupName == ""):
+		c.fail("exactly one of email and group is required")
+	case email != "":
+		var err error
+		if who, found, err = p.user(ctx, c, email); err != nil {
+			return nil, err
+		}
+	default:
+		who, found = p.group(c, groupName)
+	}
+
+	var role *dto.TNTRole
+	if roleRef == "" {
+		c.fail("role is required")
+	} else if role = p.role(c, roleRef); role != nil {
+		privileged, err := repository.RequiresApproval(ctx, p.store.ApprovalPolicies(), role.ResourceID)
+		if err != nil {
+			return nil, batch.Fail("500", "Error getting approval policy", err)
+		}
+		if privileged {
+			c.fail("role %q is only granted through approved access requests; request it with requestAccess", roleRef)
+		}
+	}
+
+	scopeID := p.tenantID
+	if scopeRef != "" {
+		id, err := uuid.Parse(scopeRef)
+		if err != nil || !p.scopes[id] {
+			c.fail("unknown scope %q; the scope is the ID of a resource of the tenant", scopeRef)
+		}
+		scopeID = id
+	}
+
+	name := fields[ColumnName]
+	if len(name) > maxNameLength {
+		c.fail("name is longer than %d characters", maxNameLength)
+	}
+	if name == "" && role != nil {
+		name = truncate(role.Name, maxNameLength)
+	}
+
+	if found && role != nil {
+		key := bindingKey{who.id, role.ResourceID, scopeID}
+		if existing, ok := p.bindings[key]; ok {
+			if existing > 0 {
+				c.fail("duplicate binding; see row %d", existing)
+			} else {
+				c.fail("the binding already exists")
+			}
+		}
+	}
+	if len(c.problems) > 0 {
+		return nil, nil
+	}
+
+	id, tenantID, roleID := uuid.New(), p.tenantID, role.ResourceID
+	p.bindings[bindingKey{who.id, roleID, scopeID}] = line
+	apply := func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Assignments().Create(ctx, &dto.TenantRoleAssignments{
+			ResourceID:  id,
+			Name:        name,
+			Version:     version,
+			PrincipalID: who.id,
+			RoleID:      roleID,
+			TenantID:    &tenantID,
+			ScopeID:     &scopeID,
+			RowStatus:   1,
+			CreatedBy:   p.userID,
+			UpdatedBy:   p.userID,
+		}); err != nil {
+			return batch.Fail("500", "Error creating binding", err)
+		}
+		return nil
+	}
+	assignment := permit.RoleAssignment{User: who.id.String(), Role: roleID.String(), Tenant: tenantID.String()}
+	return &row{
+		line:  line,
+		kind:  TypeBinding,
+		name:  name,
+		id:    id,
+		items: []batch.Item{item(apply)},
+		steps: []batch.Step{{
+			Phase: phaseBinding,
+			Push:  &permit.Request{Method: "POST", Endpoint: "role_assignments", Payload: assignment},
+			Undo:  &permit.Request{Method: "DELETE", Endpoint: "role_assignments", Payload: assignment},
+		}},
+		depends: dependency(nil, who),
+	}, nil
+}
+
+// user finds the user with email among the rows and the tenant's users.
+// The tenant's user is granted a role even if a row failed to add it again.
+func (p *planner) user(ctx context.Context, c *check, email string) (principal, bool, error) {
+	if found, ok := p.emails[strings.ToLower(email)]; ok {
+		return found, true, nil
+	}
+	existing, err := p.store.Principals().GetByEmail(ctx, email)
+	if err == nil && p.tenantUsers[existing.ResourceID] {
+		return principal{id: existing.ResourceID}, true, nil
+	}
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return principal{}, false, batch.Fail("500", "Error getting principal", err)
+	}
+	if line, ok := p.invalid[TypeUser+":"+strings.ToLower(email)]; ok {
+		c.fail("user %q is not imported; see row %d", email, line)
+	} else {
+		c.fail("unknown user %q", email)
+	}
+	return principal{}, false, nil
+}
+
+// group finds the group named name among the rows and the tenant's groups.
+func (p *planner) group(c *check, name string) (principal, bool) {
+	if found, ok := p.groups[strings.ToLower(name)]; ok {
+		return found, true
+	}
+	if line, ok := p.invalid[TypeGroup+":"+strings.ToLower(name)]; ok {
+		c.fail("group %q is not imported; see row %d", name, line)
+		return principal{}, false
+	}
+	c.fail("unknown group %q", name)
+	return principal{}, false
+}
+
+// role finds the role of the tenant, or predefined role, with ID or name ref.
+func (p *planner) role(c *check, ref string) *dto.TNTRole {
+	if id, err := uuid.Parse(ref); err == nil {
+		for i := range p.roles {
+			if p.roles[i].ResourceID == id {
+				return &p.roles[i]
+			}
+		}
+		c.fail("unknown role %q", ref)
+		return nil
+	}
+	var matches []*dto.TNTRole
+	for i := range p.roles {
+		if strings.EqualFold(p.roles[i].Name, ref) {
+			matches = append(matches, &p.roles[i])
+		}
+	}
+	switch len(matches) {
+	case 0:
+		c.fail("unknown role %q", ref)
+		return nil
+	case 1:
+		return matches[0]
+	}
+	c.fail("role name %q is ambiguous; use the role's ID", ref)
+	return nil
+}
+
+// createPrincipal stores a user or group of the tenant.
+func (p *planner) createPrincipal(ctx context.Context, tx repository.Store, id uuid.UUID, resourceType *dto.Mst_ResourceTypes, name, principalName, email string) error {
+	tenantID := p.tenantID
+	if err := tx.Resources().Create(ctx, &dto.TenantResource{
+		ResourceID:       id,
+		ParentResourceID: &tenantID,
+		ResourceTypeID:   resourceType.ResourceTypeID,
+		Name:             name,
+		TenantID:         &tenantID,
+		RowStatus:        1,
+		CreatedBy:        p.userID,
+		UpdatedBy:        p.userID,
+	}); err != nil {
+		return batch.Fail("500", fmt.Sprintf("Error creating %s", strings.ToLower(resourceType.Name)), err)
+	}
+	if err := tx.Principals().Create(ctx, &dto.TenantPrincipals{
+		ResourceID:      id,
+		PrincipalTypeID: resourceType.ResourceTypeID,
+		Name:            principalName,
+		Email:           email,
+		RowStatus:       1,
+		CreatedBy:       p.userID,
+		UpdatedBy:       p.userID,
+	}); err != nil {
+		return batch.Fail("500", "Error creating principal", err)
+	}
+	return nil
+}
+
+// item returns a batch item applying apply. The rows of a job are reported
+// together, so items have no result of their own.
+func item(apply func(ctx context.Context, tx repository.Store) error) batch.Item {
+	return batch.Item{
+		Apply:  apply,
+		Result: func(ctx context.Context) (models.OperationResult, error) { return nil, nil },
+	}
+}
+
+// instanceStep creates resource instance id of a type in Permit.
+func instanceStep(resourceTypeID, id, tenantID uuid.UUID) batch.Step {
+	return batch.Step{
+		Phase: phaseInstance,
+		Push: &permit.Request{Method: "POST", Endpoint: "resource_instances", Payload: map[string]interface{}{
+			"key":      id,
+			"resource": resourceTypeID,
+			"tenant":   tenantID,
+		}},
+		Undo: &permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("resource_instances/%s:%s", resourceTypeID, id)},
+	}
+}
+
+// dependency adds the row defining a principal to depends.
+func dependency(depends []int, of principal) []int {
+	if of.line > 0 {
+		depends = append(depends, of.line)
+	}
+	return depends
+}
+
+// validEmail reports whether email is a bare address.
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	for len(string(runes)) > n {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes)
+}
@@ -0,0 +1,73 @@
+package bulkimport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeader(t *testing.T) {
+	names, err := parseHeader([]string{"\ufeffType", " Display Name", "EMAIL"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{ColumnType, ColumnDisplayName, ColumnEmail}, names)
+
+	for _, header := range [][]string{{"name"}, {"type", "kind"}, {"type", "name", "Name"}} {
+		_, err := parseHeader(header)
+		assert.ErrorIs(t, err, ErrInvalidFile, "%v", header)
+	}
+}
+
+func TestRead(t *testing.T) {
+	f := setupImport(t)
+	ctx := tenancy.WithTenant(context.Background(), f.tenantID)
+	admin := f.addRole(t, "admin")
+	f.store.AddApprovalPolicy(dto.ApprovalPolicy{PolicyID: uuid.New(), TenantID: &f.tenantID, RoleID: admin, RequiredApprovals: 1})
+	p, err := newPlanner(ctx, f.store, f.tenantID, f.userID)
+	require.NoError(t, err)
+
+	file := "type,name,email,group,role\n" +
+		"group,admins,,,\n" +
+		"\n" +
+		"user,\"a\nb\",ab@example.com,,\n" +
+		"user,carol,carol@example.com,staff,\n" +
+		"user,dave\n" +
+		"group,admins,,,\n" +
+		"binding,,,admins,reader\n" +
+		"binding,,,admins,READER\n" +
+		"binding,,carol@example.com,admins,reader\n" +
+		",,,,\n" +
+		"binding,,,admins,admin\n"
+	require.NoError(t, p.read(ctx, strings.NewReader(file)))
+
+	assert.Equal(t, 9, p.total)
+	var lines []int
+	for _, r := range p.rows {
+		lines = append(lines, r.line)
+	}
+	assert.Equal(t, []int{2, 4, 9}, lines)
+	assert.Equal(t, []int{2}, p.rows[2].depends)
+	assert.Equal(t, []string{
+		`6 INVALID [unknown group "staff"]`,
+		`7 INVALID [the row has 2 fields, the header 5]`,
+		`8 INVALID [duplicate group name "admins"; see row 2]`,
+		`10 INVALID [duplicate binding; see row 9]`,
+		`11 INVALID [exactly one of email and group is required]`,
+		`13 INVALID [role "admin" is only granted through approved access requests; request it with requestAccess]`,
+	}, reportOf(p))
+}
+
+func reportOf(p *planner) []string {
+	rows := make([]string, len(p.report))
+	for i, row := range p.report {
+		rows[i] = fmt.Sprintf("%d %s %v", row.Row, row.Status, row.Errors)
+	}
+	return rows
+}
@@ -5,3 +5,19 @@ const (
 	DefaltCreatedBy = "00000"
 	DefaltUpdatedBy = "00000"
 )
+
+// ErrorCodeConflict is the errorCode of a change rejected because the resource
+// no longer has the etag the caller expected.
+const ErrorCodeConflict = "CONFLICT"
+
+// Names of the built-in resource types seeded into mst_resource_types.
+// Additional types are registered at runtime through registerResourceType.
+const (
+	ResourceTypeUser                   = "User"
+	ResourceTypeGroup                  = "Group"
+	ResourceTypeTenant                 = "Tenant"
+	ResourceTypeRole                   = "Role"
+	ResourceTypeRoot                   = "Root"
+	ResourceTypeAccount                = "Account"
+	ResourceTypeClientOrganizationUnit = "Client Organization Unit"
+)
@@ -14,12 +14,3 @@ func GetResourceTypeByName(name string) (*dto.Mst_ResourceTypes, error) {
 	}
 	return &resourceType, nil
 }
-
-func GetResourceDetails(conditions map[string]interface{}) (*dto.TenantResource, error) {
-	DB := config.GetDB()
-	resourceDetails := dto.TenantResource{}
-	if err := DB.Where(conditions).First(&resourceDetails).Error; err != nil {
-		return nil, fmt.Errorf("parent details not found: %w", err)
-	}
-	return &resourceDetails, nil
-}
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Access request statuses.
+const (
+	AccessRequestStatusPending  = "PENDING"
+	AccessRequestStatusApproved = "APPROVED"
+	AccessRequestStatusDenied   = "DENIED"
+)
+
+// Decisions recorded on an access request.
+const (
+	ApprovalDecisionApproved = "APPROVED"
+	ApprovalDecisionDenied   = "DENIED"
+)
+
+// ApprovalPolicy defines who may approve requests for a role. Approvers are
+// the listed users and every holder of ApproverRoleID on the requested scope.
+// A tenant has at most one policy per role, active or deleted.
+type ApprovalPolicy struct {
+	PolicyID           uuid.UUID  `gorm:"size:36;primaryKey;column:policy_id" json:"policyId"`
+	TenantID           *uuid.UUID `gorm:"size:36;uniqueIndex:idx_approval_policies_tenant_role;column:tenant_id" json:"tenantId"`
+	RoleID             uuid.UUID  `gorm:"size:36;not null;uniqueIndex:idx_approval_policies_tenant_role;column:role_id" json:"roleId"`
+	ApproverRoleID     *uuid.UUID `gorm:"size:36;column:approver_role_id" json:"approverRoleId"`
+	ApproverIDs        JSON       `gorm:"column:approver_ids" json:"approverIds"`
+	RequiredApprovals  int        `gorm:"not null;default:1;column:required_approvals" json:"requiredApprovals"`
+	MaxDurationSeconds *int       `gorm:"column:max_duration_seconds" json:"maxDurationSeconds"`
+	RowStatus          int        `gorm:"default:1;column:row_status" json:"rowStatus"`
+	CreatedBy          uuid.UUID  `gorm:"size:36;column:created_by" json:"createdBy"`
+	UpdatedBy          uuid.UUID  `gorm:"size:36;column:updated_by" json:"updatedBy"`
+	CreatedAt          time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt          time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ApprovalPolicy) TableName() string {
+	return "tnt_approval_policies"
+}
+
+// TenantScoped isolates approval policies per tenant; see the tenancy package.
+func (ApprovalPolicy) TenantScoped() {}
+
+// AccessRequest is a principal's request for a role on a scope. The binding is
+// only created once enough approvers have approved it.
+type AccessRequest struct {
+	RequestID       uuid.UUID  `gorm:"size:36;primaryKey;column:request_id" json:"requestId"`
+	TenantID        *uuid.UUID `gorm:"size:36;index:idx_access_requests_tenant;column:tenant_id" json:"tenantId"`
+	RequesterID     uuid.UUID  `gorm:"size:36;not null;index:idx_access_requests_requester;column:requester_id" json:"requesterId"`
+	RoleID          uuid.UUID  `gorm:"size:36;not null;column:role_id" json:"roleId"`
+	ScopeID         uuid.UUID  `gorm:"size:36;not null;column:scope_id" json:"scopeId"`
+	Justification   string     `gorm:"type:text;not null;column:justification" json:"justification"`
+	DurationSeconds *int       `gorm:"column:duration_seconds" json:"durationSeconds"`
+	Status          string     `gorm:"size:16;not null;column:status" json:"status"`
+	BindingID       *uuid.UUID `gorm:"size:36;column:binding_id" json:"bindingId"`
+	DecidedAt       *time.Time `gorm:"column:decided_at" json:"decidedAt"`
+	CreatedAt       time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt       time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (AccessRequest) TableName() string {
+	return "tnt_access_requests"
+}
+
+// TenantScoped isolates access requests per tenant; see the tenancy package.
+func (AccessRequest) TenantScoped() {}
+
+// AccessRequestDecision records one approver's decision on a request. ActorID
+// is the user who recorded it, who differs from ApproverID when impersonating
+// them.
+type AccessRequestDecision struct {
+	DecisionID uuid.UUID `gorm:"size:36;primaryKey;column:decision_id" json:"decisionId"`
+	RequestID  uuid.UUID `gorm:"size:36;not null;uniqueIndex:idx_access_request_decisions_approver;column:request_id" json:"requestId"`
+	ApproverID uuid.UUID `gorm:"size:36;not null;uniqueIndex:idx_access_request_decisions_approver;column:approver_id" json:"approverId"`
+	ActorID    uuid.UUID `gorm:"size:36;column:actor_id" json:"actorId"`
+	Decision   string    `gorm:"size:16;not null;column:decision" json:"decision"`
+	Comment    string    `gorm:"type:text;column:comment" json:"comment"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (AccessRequestDecision) TableName() string {
+	return "tnt_access_request_decisions"
+}
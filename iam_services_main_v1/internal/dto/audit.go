@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditEvent is one entry of the append-only, hash-chained audit log. Each row
+// stores the hash of its predecessor; the unique index on prev_hash keeps the
+// chain linear even with concurrent writers.
+type AuditEvent struct {
+	Sequence           uint64    `gorm:"primaryKey;autoIncrement;column:sequence" json:"sequence"`
+	EventID            string    `gorm:"size:36;not null;uniqueIndex:idx_audit_event_id;column:event_id" json:"eventId"`
+	OccurredAt         time.Time `gorm:"not null;index:idx_audit_occurred_at;column:occurred_at" json:"occurredAt"`
+	ActorID            string    `gorm:"size:36;index:idx_audit_actor;column:actor_id" json:"actorId"`
+	ImpersonatedUserID string    `gorm:"size:36;column:impersonated_user_id" json:"impersonatedUserId,omitempty"`
+	TenantID           string    `gorm:"size:36;index:idx_audit_tenant;column:tenant_id" json:"tenantId"`
+	Operation          string    `gorm:"size:100;not null;column:operation" json:"operation"`
+	TargetResourceID   string    `gorm:"size:36;index:idx_audit_target;column:target_resource_id" json:"targetResourceId"`
+	Before             string    `gorm:"type:text;column:before_snapshot" json:"before"`
+	After              string    `gorm:"type:text;column:after_snapshot" json:"after"`
+	RequestID          string    `gorm:"size:64;column:request_id" json:"requestId"`
+	ClientIP           string    `gorm:"size:45;column:client_ip" json:"clientIp"`
+	Outcome            string    `gorm:"size:16;not null;column:outcome" json:"outcome"`
+	ErrorMessage       string    `gorm:"type:text;column:error_message" json:"errorMessage"`
+	PrevHash           string    `gorm:"size:64;not null;uniqueIndex:idx_audit_prev_hash;column:prev_hash" json:"prevHash"`
+	Hash               string    `gorm:"size:64;not null;column:hash" json:"hash"`
+}
+
+func (AuditEvent) TableName() string {
+	return "tnt_audit_events"
+}
+
+// TenantScoped isolates audit events per tenant; see the tenancy package.
+// Events of Root-level requests belong to no tenant.
+func (AuditEvent) TenantScoped() {}
+
+// BeforeUpdate rejects any attempt to modify an audit event.
+func (AuditEvent) BeforeUpdate(tx *gorm.DB) error {
+	return ErrImmutableRecord
+}
+
+// BeforeDelete rejects any attempt to delete an audit event.
+func (AuditEvent) BeforeDelete(tx *gorm.DB) error {
+	return ErrImmutableRecord
+}
@@ -0,0 +1,54 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BreakGlassPrincipal registers a principal allowed to take RoleID on a Root
+// or tenant scope in an emergency. TenantID is the tenant of the scope, nil
+// for Root scopes.
+type BreakGlassPrincipal struct {
+	RegistrationID uuid.UUID  `gorm:"size:36;primaryKey;column:registration_id" json:"registrationId"`
+	TenantID       *uuid.UUID `gorm:"size:36;index:idx_break_glass_principals_tenant;column:tenant_id" json:"tenantId"`
+	PrincipalID    uuid.UUID  `gorm:"size:36;not null;index:idx_break_glass_principals_principal;column:principal_id" json:"principalId"`
+	RoleID         uuid.UUID  `gorm:"size:36;not null;column:role_id" json:"roleId"`
+	ScopeID        uuid.UUID  `gorm:"size:36;not null;column:scope_id" json:"scopeId"`
+	RowStatus      int        `gorm:"default:1;column:row_status" json:"rowStatus"`
+	CreatedBy      uuid.UUID  `gorm:"size:36;column:created_by" json:"createdBy"`
+	UpdatedBy      uuid.UUID  `gorm:"size:36;column:updated_by" json:"updatedBy"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (BreakGlassPrincipal) TableName() string {
+	return "tnt_break_glass_principals"
+}
+
+// TenantScoped isolates registrations per tenant; see the tenancy package.
+func (BreakGlassPrincipal) TenantScoped() {}
+
+// BreakGlassGrant records one use of break-glass access and the short-lived
+// binding it created. TenantID is the tenant of the scope, nil for Root
+// scopes.
+type BreakGlassGrant struct {
+	GrantID        uuid.UUID  `gorm:"size:36;primaryKey;column:grant_id" json:"grantId"`
+	TenantID       *uuid.UUID `gorm:"size:36;index:idx_break_glass_grants_tenant;column:tenant_id" json:"tenantId"`
+	RegistrationID uuid.UUID  `gorm:"size:36;not null;column:registration_id" json:"registrationId"`
+	PrincipalID    uuid.UUID  `gorm:"size:36;not null;index:idx_break_glass_grants_principal;column:principal_id" json:"principalId"`
+	RoleID         uuid.UUID  `gorm:"size:36;not null;column:role_id" json:"roleId"`
+	ScopeID        uuid.UUID  `gorm:"size:36;not null;column:scope_id" json:"scopeId"`
+	BindingID      uuid.UUID  `gorm:"size:36;not null;column:binding_id" json:"bindingId"`
+	Reason         string     `gorm:"type:text;not null;column:reason" json:"reason"`
+	ExpiresAt      time.Time  `gorm:"not null;index:idx_break_glass_grants_expiry;column:expires_at" json:"expiresAt"`
+	RevokedAt      *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+	CreatedAt      time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (BreakGlassGrant) TableName() string {
+	return "tnt_break_glass_grants"
+}
+
+// TenantScoped isolates grants per tenant; see the tenancy package.
+func (BreakGlassGrant) TenantScoped() {}
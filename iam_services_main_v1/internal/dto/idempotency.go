@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey records a mutation sent with an idempotency key so that
+// retries with the same key replay its result instead of running it again.
+// Result is empty while the first request is still running.
+type IdempotencyKey struct {
+	Key         string    `gorm:"size:255;primaryKey;column:idempotency_key" json:"key"`
+	PrincipalID uuid.UUID `gorm:"size:36;primaryKey;column:principal_id" json:"principalId"`
+	Operation   string    `gorm:"size:100;not null;column:operation" json:"operation"`
+	Fingerprint string    `gorm:"size:64;not null;column:fingerprint" json:"fingerprint"`
+	Result      []byte    `gorm:"column:result" json:"result"`
+	ExpiresAt   time.Time `gorm:"not null;index:idx_idempotency_keys_expiry;column:expires_at" json:"expiresAt"`
+	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "tnt_idempotency_keys"
+}
@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Bulk import job statuses.
+const (
+	ImportJobStatusRunning   = "RUNNING"
+	ImportJobStatusCompleted = "COMPLETED"
+	ImportJobStatusFailed    = "FAILED"
+)
+
+// ImportJob tracks the import of a CSV file of users, groups and bindings
+// into a tenant. Its report lists the rows that were not imported.
+type ImportJob struct {
+	JobID        uuid.UUID  `gorm:"size:36;primaryKey;column:job_id" json:"jobId"`
+	TenantID     *uuid.UUID `gorm:"size:36;not null;index:idx_import_jobs_tenant;column:tenant_id" json:"tenantId"`
+	FileName     string     `gorm:"size:255;column:file_name" json:"fileName"`
+	Status       string     `gorm:"size:16;not null;column:status" json:"status"`
+	ChunkSize    int        `gorm:"not null;column:chunk_size" json:"chunkSize"`
+	TotalRows    int        `gorm:"not null;column:total_rows" json:"totalRows"`
+	ValidRows    int        `gorm:"not null;column:valid_rows" json:"validRows"`
+	ImportedRows int        `gorm:"not null;column:imported_rows" json:"importedRows"`
+	FailedRows   int        `gorm:"not null;column:failed_rows" json:"failedRows"`
+	Report       JSON       `gorm:"column:report" json:"report"`
+	Error        string     `gorm:"type:text;column:error" json:"error"`
+	FinishedAt   *time.Time `gorm:"column:finished_at" json:"finishedAt"`
+	CreatedBy    uuid.UUID  `gorm:"size:36;column:created_by" json:"createdBy"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (ImportJob) TableName() string {
+	return "tnt_import_jobs"
+}
+
+// TenantScoped isolates import jobs per tenant; see the tenancy package.
+func (ImportJob) TenantScoped() {}
@@ -0,0 +1,35 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RowStatusPending marks the principal and binding of an invitation until it
+// is accepted. Only rows with status 1 are active.
+const RowStatusPending = 2
+
+// Invitation invites a user by email into a tenant with a role on a scope.
+// Its principal and binding are pending until the invitation is accepted.
+type Invitation struct {
+	InvitationID uuid.UUID  `gorm:"size:36;primaryKey;column:invitation_id" json:"invitationId"`
+	TenantID     *uuid.UUID `gorm:"size:36;not null;index:idx_invitations_tenant;column:tenant_id" json:"tenantId"`
+	Email        string     `gorm:"size:45;not null;index:idx_invitations_email;column:email" json:"email"`
+	PrincipalID  uuid.UUID  `gorm:"size:36;not null;column:principal_id" json:"principalId"`
+	BindingID    uuid.UUID  `gorm:"size:36;not null;column:binding_id" json:"bindingId"`
+	RoleID       uuid.UUID  `gorm:"size:36;not null;column:role_id" json:"roleId"`
+	ScopeID      uuid.UUID  `gorm:"size:36;not null;column:scope_id" json:"scopeId"`
+	ExpiresAt    time.Time  `gorm:"not null;column:expires_at" json:"expiresAt"`
+	AcceptedAt   *time.Time `gorm:"column:accepted_at" json:"acceptedAt"`
+	CreatedBy    uuid.UUID  `gorm:"size:36;column:created_by" json:"createdBy"`
+	CreatedAt    time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (Invitation) TableName() string {
+	return "tnt_invitations"
+}
+
+// TenantScoped isolates invitations per tenant; see the tenancy package.
+func (Invitation) TenantScoped() {}
@@ -1,7 +1,6 @@
 package dto
 
 import (
-	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,12 +8,13 @@ import (
 )
 
 type TenantResource struct {
-	ResourceID       uuid.UUID  `gorm:"type:char(36);primaryKey;column:resource_id" json:"resource_id"`
-	ParentResourceID *uuid.UUID `gorm:"type:char(36);column:parent_resource_id" json:"parent_resource_id"`
-	ResourceTypeID   uuid.UUID  `gorm:"type:char(36);not null;column:resource_type_id" json:"resource_type_id"` // foreign key to resource_type
+	ResourceID       uuid.UUID  `gorm:"size:36;primaryKey;column:resource_id" json:"resource_id"`
+	ParentResourceID *uuid.UUID `gorm:"size:36;column:parent_resource_id" json:"parent_resource_id"`
+	ResourceTypeID   uuid.UUID  `gorm:"size:36;not null;column:resource_type_id" json:"resource_type_id"` // foreign key to resource_type
 	Name             string     `gorm:"size:45;not null;column:name" json:"name"`
-	TenantID         *uuid.UUID `gorm:"type:char(36);column:tenant_id" json:"tenant_id"`
+	TenantID         *uuid.UUID `gorm:"size:36;column:tenant_id" json:"tenant_id"`
 	RowStatus        int        `gorm:"default:1;column:row_status" json:"row_status"`
+	Revision         int        `gorm:"not null;default:1;column:revision" json:"revision"`
 	CreatedBy        uuid.UUID  `gorm:"size:45;column:created_by" json:"created_by"`
 	UpdatedBy        uuid.UUID  `gorm:"size:45;column:updated_by" json:"updated_by"`
 	CreatedAt        time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
@@ -25,15 +25,37 @@ func (t *TenantResource) TableName() string {
 	return "tnt_resources"
 }
 
+// TenantScoped isolates resources per tenant; see the tenancy package.
+func (TenantResource) TenantScoped() {}
+
+// TNTResourceLabel is a key/value label attached to a row in tnt_resources.
+type TNTResourceLabel struct {
+	LabelID    uuid.UUID `gorm:"size:36;primaryKey;column:label_id" json:"label_id"`
+	ResourceID uuid.UUID `gorm:"size:36;not null;index:idx_resource_labels_resource;column:resource_id" json:"resource_id"`
+	Key        string    `gorm:"size:63;not null;column:label_key" json:"key"`
+	Value      string    `gorm:"size:63;not null;column:label_value" json:"value"`
+	RowStatus  int       `gorm:"default:1;column:row_status" json:"row_status"`
+	CreatedBy  uuid.UUID `gorm:"size:45;column:created_by" json:"created_by"`
+	UpdatedBy  uuid.UUID `gorm:"size:45;column:updated_by" json:"updated_by"`
+	CreatedAt  time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (t *TNTResourceLabel) TableName() string {
+	return "tnt_resource_labels"
+}
+
 type Mst_ResourceTypes struct {
-	ResourceTypeID uuid.UUID `gorm:"type:char(36);primaryKey;column:resource_type_id" json:"resource_type_id"`
-	ServiceID      uuid.UUID `gorm:"type:char(36);not null;column:service_id" json:"service_id"`
-	Name           string    `gorm:"size:45;not null;column:name" json:"name"`
-	RowStatus      int       `gorm:"default:1;column:row_status" json:"row_status"`
-	CreatedBy      uuid.UUID `gorm:"size:45;column:created_by" json:"created_by"`
-	UpdatedBy      uuid.UUID `gorm:"size:45;column:updated_by" json:"updated_by"`
-	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt      time.Time `gorm:"column:updated_at;autoCreateTime" json:"updated_at"`
+	ResourceTypeID     uuid.UUID `gorm:"size:36;primaryKey;column:resource_type_id" json:"resource_type_id"`
+	ServiceID          uuid.UUID `gorm:"size:36;not null;column:service_id" json:"service_id"`
+	Name               string    `gorm:"size:45;not null;column:name" json:"name"`
+	AllowedParentTypes JSON      `gorm:"column:allowed_parent_types" json:"allowed_parent_types"`
+	AttributeSchema    JSON      `gorm:"column:attribute_schema" json:"attribute_schema"`
+	RowStatus          int       `gorm:"default:1;column:row_status" json:"row_status"`
+	CreatedBy          uuid.UUID `gorm:"size:45;column:created_by" json:"created_by"`
+	UpdatedBy          uuid.UUID `gorm:"size:45;column:updated_by" json:"updated_by"`
+	CreatedAt          time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time `gorm:"column:updated_at;autoCreateTime" json:"updated_at"`
 }
 
 func (t *Mst_ResourceTypes) TableName() string {
@@ -42,13 +64,13 @@ func (t *Mst_ResourceTypes) TableName() string {
 
 // Tenant struct aligned with schema
 type TenantMetadata struct {
-	ResourceID uuid.UUID       `gorm:"type:char(36);not null" json:"resource_id"`
-	Metadata   json.RawMessage `gorm:"type:json;" json:"metadata"`
-	RowStatus  int             `gorm:"default:1;column:row_status" json:"row_status"`
-	CreatedBy  uuid.UUID       `gorm:"size:45" json:"created_by"`
-	UpdatedBy  uuid.UUID       `gorm:"size:45" json:"updated_by"`
-	CreatedAt  time.Time       `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt  time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+	ResourceID uuid.UUID `gorm:"size:36;not null" json:"resource_id"`
+	Metadata   JSON      `gorm:"column:metadata" json:"metadata"`
+	RowStatus  int       `gorm:"default:1;column:row_status" json:"row_status"`
+	CreatedBy  uuid.UUID `gorm:"size:45" json:"created_by"`
+	UpdatedBy  uuid.UUID `gorm:"size:45" json:"updated_by"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 // BeforeCreate hook to generate UUID before saving
@@ -62,33 +84,43 @@ func (t *TenantMetadata) TableName() string {
 }
 
 type TenantRoleAssignments struct {
-	ResourceID  uuid.UUID `gorm:"type:char(36);primaryKey;column:resource_id" json:"resource_id"`
+	ResourceID  uuid.UUID `gorm:"size:36;primaryKey;column:resource_id" json:"resource_id"`
 	Name        string    `gorm:"size:45;not null;column:name" json:"name"`
 	Version     string    `gorm:"size:45;not null;column:version" json:"version"`
-	PrincipalID uuid.UUID `gorm:"type:char(36);column:principal_id" json:"principal_id"`
-	RoleID      uuid.UUID `gorm:"type:char(36);column:role_id" json:"role_id"`
-	RowStatus   int       `gorm:"default:1;column:row_status" json:"row_status"`
-	CreatedBy   uuid.UUID `gorm:"size:45;column:created_by" json:"created_by"`
-	UpdatedBy   uuid.UUID `gorm:"size:45;column:updated_by" json:"updated_by"`
-	CreatedAt   time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"column:updated_at;autoCreateTime" json:"updated_at"`
+	PrincipalID uuid.UUID `gorm:"size:36;column:principal_id" json:"principal_id"`
+	RoleID      uuid.UUID `gorm:"size:36;column:role_id" json:"role_id"`
+	// TenantID and ScopeID are set on bindings granted through the API; legacy
+	// bindings took their tenant from the binding's tnt_resources row until
+	// migration 0013 copied it over.
+	TenantID  *uuid.UUID `gorm:"size:36;column:tenant_id;index:idx_role_assignments_tenant" json:"tenant_id"`
+	ScopeID   *uuid.UUID `gorm:"size:36;column:scope_id" json:"scope_id"`
+	ExpiresAt *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	RowStatus int        `gorm:"default:1;column:row_status" json:"row_status"`
+	CreatedBy uuid.UUID  `gorm:"size:45;column:created_by" json:"created_by"`
+	UpdatedBy uuid.UUID  `gorm:"size:45;column:updated_by" json:"updated_by"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at;autoCreateTime" json:"updated_at"`
 }
 
 func (t *TenantRoleAssignments) TableName() string {
 	return "tnt_role_assignments"
 }
 
+// TenantScoped isolates bindings per tenant; see the tenancy package. Bindings
+// outside any tenant are only reached without tenant isolation.
+func (TenantRoleAssignments) TenantScoped() {}
+
 type TenantPrincipals struct {
-	ResourceID      uuid.UUID       `gorm:"type:char(36);primaryKey;column:resource_id" json:"resource_id"`
-	PrincipalTypeID uuid.UUID       `gorm:"size:45;not null;column:principal_type_id" json:"principal_type_id"`
-	Name            string          `gorm:"size:45;not null;column:name" json:"name"`
-	Email           string          `gorm:"size:45;not null;column:email" json:"email"`
-	Metadata        json.RawMessage `gorm:"type:json;" json:"metadata"`
-	RowStatus       int             `gorm:"default:1;column:row_status" json:"row_status"`
-	CreatedBy       uuid.UUID       `gorm:"size:45;column:created_by" json:"created_by"`
-	UpdatedBy       uuid.UUID       `gorm:"size:45;column:updated_by" json:"updated_by"`
-	CreatedAt       time.Time       `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt       time.Time       `gorm:"column:updated_at;autoCreateTime" json:"updated_at"`
+	ResourceID      uuid.UUID `gorm:"size:36;primaryKey;column:resource_id" json:"resource_id"`
+	PrincipalTypeID uuid.UUID `gorm:"size:45;not null;column:principal_type_id" json:"principal_type_id"`
+	Name            string    `gorm:"size:45;not null;column:name" json:"name"`
+	Email           string    `gorm:"size:45;not null;column:email" json:"email"`
+	Metadata        JSON      `gorm:"column:metadata" json:"metadata"`
+	RowStatus       int       `gorm:"default:1;column:row_status" json:"row_status"`
+	CreatedBy       uuid.UUID `gorm:"size:45;column:created_by" json:"created_by"`
+	UpdatedBy       uuid.UUID `gorm:"size:45;column:updated_by" json:"updated_by"`
+	CreatedAt       time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time `gorm:"column:updated_at;autoCreateTime" json:"updated_at"`
 }
 
 func (t *TenantPrincipals) TableName() string {
@@ -96,7 +128,7 @@ func (t *TenantPrincipals) TableName() string {
 }
 
 type TenantRoles struct {
-	ResourceID     uuid.UUID `gorm:"type:char(36);primaryKey;column:resource_id" json:"resource_id"`
+	ResourceID     uuid.UUID `gorm:"size:36;primaryKey;column:resource_id" json:"resource_id"`
 	ResourceTypeID uuid.UUID `gorm:"size:45;not null;column:resource_type_id" json:"resource_type_id"`
 	RoleType       string    `gorm:"size:45;not null;column:role_type" json:"role_type"`
 	Name           string    `gorm:"size:45;not null;column:name" json:"name"`
@@ -113,7 +145,7 @@ func (t *TenantRoles) TableName() string {
 }
 
 type TenantRolePermissions struct {
-	RolePermissionID uuid.UUID `gorm:"type:char(36);primaryKey;column:role_permission_id" json:"role_permission_id"`
+	RolePermissionID uuid.UUID `gorm:"size:36;primaryKey;column:role_permission_id" json:"role_permission_id"`
 	RoleID           uuid.UUID `gorm:"size:45;not null;column:role_id" json:"role_id"`
 	PermissionID     uuid.UUID `gorm:"size:45;not null;column:permission_id" json:"permission_id"`
 	RowStatus        int       `gorm:"default:1;column:row_status" json:"row_status"`
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GroupMember makes a user or group a member of a group of the same tenant.
+type GroupMember struct {
+	GroupID   uuid.UUID  `gorm:"size:36;primaryKey;column:group_id" json:"groupId"`
+	MemberID  uuid.UUID  `gorm:"size:36;primaryKey;index:idx_group_members_member;column:member_id" json:"memberId"`
+	TenantID  *uuid.UUID `gorm:"size:36;not null;column:tenant_id" json:"tenantId"`
+	CreatedBy uuid.UUID  `gorm:"size:36;column:created_by" json:"createdBy"`
+	CreatedAt time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (GroupMember) TableName() string {
+	return "tnt_group_members"
+}
+
+// TenantScoped isolates memberships per tenant; see the tenancy package.
+func (GroupMember) TenantScoped() {}
+
+// ScimToken is a bearer token a SCIM client of a tenant provisions users and
+// groups with. Only the SHA-256 hash of the token is stored.
+type ScimToken struct {
+	TokenID     uuid.UUID  `gorm:"size:36;primaryKey;column:token_id" json:"tokenId"`
+	TokenHash   string     `gorm:"size:64;not null;uniqueIndex:idx_scim_tokens_hash;column:token_hash" json:"-"`
+	TenantID    uuid.UUID  `gorm:"size:36;not null;column:tenant_id" json:"tenantId"`
+	Description string     `gorm:"size:255;column:description" json:"description"`
+	RevokedAt   *time.Time `gorm:"column:revoked_at" json:"revokedAt"`
+	CreatedAt   time.Time  `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (ScimToken) TableName() string {
+	return "tnt_scim_tokens"
+}
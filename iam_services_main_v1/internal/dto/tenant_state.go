@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tenant lifecycle statuses. Tenants without a recorded state predate
+// lifecycle states and are active.
+const (
+	TenantStatusProvisioning = "PROVISIONING"
+	TenantStatusActive       = "ACTIVE"
+	TenantStatusSuspended    = "SUSPENDED"
+	TenantStatusArchived     = "ARCHIVED"
+)
+
+// TenantState is the current lifecycle status of a tenant. Its revision
+// advances with every transition so that concurrent transitions conflict.
+//
+// States are not tenant scoped: authorization reads the state of the scope's
+// tenant whatever the request's tenant is.
+type TenantState struct {
+	TenantID  uuid.UUID `gorm:"size:36;primaryKey;column:tenant_id" json:"tenantId"`
+	Status    string    `gorm:"size:16;not null;column:status" json:"status"`
+	Revision  int       `gorm:"not null;default:1;column:revision" json:"revision"`
+	UpdatedBy uuid.UUID `gorm:"size:36;column:updated_by" json:"updatedBy"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (TenantState) TableName() string {
+	return "tnt_tenant_states"
+}
+
+// TenantStateTransition records a change of a tenant's lifecycle status.
+// FromStatus is empty for the first transition recorded for a tenant, and
+// Revision is the revision of the state it produced.
+type TenantStateTransition struct {
+	TransitionID uuid.UUID `gorm:"size:36;primaryKey;column:transition_id" json:"transitionId"`
+	TenantID     uuid.UUID `gorm:"size:36;not null;index:idx_tenant_state_transitions_tenant;column:tenant_id" json:"tenantId"`
+	FromStatus   string    `gorm:"size:16;column:from_status" json:"fromStatus"`
+	ToStatus     string    `gorm:"size:16;not null;column:to_status" json:"toStatus"`
+	Reason       string    `gorm:"size:255;column:reason" json:"reason"`
+	Revision     int       `gorm:"not null;column:revision" json:"revision"`
+	CreatedBy    uuid.UUID `gorm:"size:36;column:created_by" json:"createdBy"`
+	CreatedAt    time.Time `gorm:"column:created_at;autoCreateTime" json:"createdAt"`
+}
+
+func (TenantStateTransition) TableName() string {
+	return "tnt_tenant_state_transitions"
+}
@@ -0,0 +1,43 @@
+// Package etag converts resource revisions to and from the entity tags
+// clients pass back to make conditional changes.
+package etag
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned for an entity tag that does not name a revision.
+var ErrInvalid = errors.New("invalid etag")
+
+// Format returns the weak entity tag of a revision.
+func Format(revision int) string {
+	return `W/"` + strconv.Itoa(revision) + `"`
+}
+
+// Parse returns the revision named by an entity tag. It accepts weak and
+// strong tags as well as the bare revision number.
+func Parse(tag string) (int, error) {
+	value := strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		value = value[1 : len(value)-1]
+	}
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision < 1 {
+		return 0, ErrInvalid
+	}
+	return revision, nil
+}
+
+// ParseOptional parses an optional entity tag, returning nil when it is absent.
+func ParseOptional(tag *string) (*int, error) {
+	if tag == nil {
+		return nil, nil
+	}
+	revision, err := Parse(*tag)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
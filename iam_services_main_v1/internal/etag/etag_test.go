@@ -0,0 +1,26 @@
+package etag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatAndParse(t *testing.T) {
+	assert.Equal(t, `W/"3"`, Format(3))
+
+	for _, tag := range []string{`W/"3"`, `"3"`, "3", ` W/"3" `} {
+		revision, err := Parse(tag)
+		require.NoError(t, err, tag)
+		assert.Equal(t, 3, revision, tag)
+	}
+	for _, tag := range []string{"", `W/""`, `"x"`, "0", "-1", `W/"3`} {
+		_, err := Parse(tag)
+		assert.ErrorIs(t, err, ErrInvalid, tag)
+	}
+
+	revision, err := ParseOptional(nil)
+	require.NoError(t, err)
+	assert.Nil(t, revision)
+}
@@ -0,0 +1,199 @@
+// Package idempotency makes mutations safe to retry. A mutation sent with an
+// Idempotency-Key header or a clientMutationId input field is recorded with a
+// fingerprint of its arguments and its result; a retry with the same key
+// replays the stored result instead of running the mutation again.
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HeaderName is the HTTP header carrying an idempotency key.
+const HeaderName = "Idempotency-Key"
+
+// MaxKeyLength is the longest idempotency key accepted.
+const MaxKeyLength = 255
+
+// DefaultTTL is how long results are replayed when no TTL is configured.
+const DefaultTTL = 24 * time.Hour
+
+var (
+	// ErrKeyReused is returned when a key is sent again with another request.
+	ErrKeyReused = errors.New("idempotency key was used for a different request")
+	// ErrInProgress is returned when a key is sent again before the first
+	// request finished.
+	ErrInProgress = errors.New("request with this idempotency key is still in progress")
+	// ErrInvalidKey is returned for an overlong key.
+	ErrInvalidKey = errors.New("invalid idempotency key")
+	// ErrSeveralMutations is returned when the Idempotency-Key header is sent
+	// with a document holding several mutations.
+	ErrSeveralMutations = errors.New("the " + HeaderName + " header cannot be used with several mutations; send them separately or give each input a clientMutationId")
+)
+
+// resultTypes holds a value of every concrete type of the OperationResult
+// and Data unions.
+var resultTypes = []interface{}{
+	models.ResponseError{}, models.SuccessResponse{},
+	models.AccessRequest{}, models.AccessReviewCampaign{}, models.AccessReviewItem{}, models.AccessReviewReport{},
+	models.Account{}, models.ApprovalPolicy{}, models.AuditChainVerification{}, models.AuditEventPage{},
+	models.Binding{}, models.BreakGlassGrant{}, models.BreakGlassPrincipal{}, models.ClientOrganizationUnit{},
+	models.Group{}, models.ImportJob{}, models.Invitation{}, models.Permission{}, models.ResourceLabels{},
+	models.ResourceType{}, models.Role{}, models.RoleRevision{}, models.RoleRevisionDiff{}, models.Root{},
+	models.Tenant{}, models.TenantArchive{}, models.TenantConfigPlan{}, models.TenantImport{},
+	models.TenantStatusTransition{}, models.User{},
+}
+
+func init() {
+	// Results are stored with gob, which must know every concrete type held
+	// by the unions. Gob does not tell values from pointers, so replayed
+	// results hold pointers, which GraphQL renders the same way.
+	for _, value := range resultTypes {
+		gob.Register(reflect.New(reflect.TypeOf(value)).Interface())
+	}
+	// Batch mutations return a result per item
+	gob.Register([]models.OperationResult{})
+}
+
+// storedResult wraps a result so that gob records its concrete type.
+type storedResult struct {
+	Result interface{}
+}
+
+func encodeResult(result interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(storedResult{Result: result}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeResult(data []byte) (interface{}, error) {
+	var stored storedResult
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&stored); err != nil {
+		return nil, err
+	}
+	return stored.Result, nil
+}
+
+// Fingerprint identifies a request by the tenant and principal it runs as,
+// either of which may be nil, and by its operation and arguments.
+func Fingerprint(tenantID, principalID *uuid.UUID, operation string, args map[string]interface{}) (string, error) {
+	// encoding/json sorts map keys, so equal arguments encode identically
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return "", err
+	}
+	scope := fmt.Sprintf("%s\n%s\n%s\n", optionalID(tenantID), optionalID(principalID), operation)
+	sum := sha256.Sum256(append([]byte(scope), encoded...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func optionalID(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+// Store keeps the results of keyed requests for ttl.
+type Store struct {
+	DB  *gorm.DB
+	TTL time.Duration
+	now func() time.Time
+}
+
+// NewStore returns a Store replaying results for ttl, or DefaultTTL when ttl
+// is not positive.
+func NewStore(db *gorm.DB, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{DB: db, TTL: ttl, now: time.Now}
+}
+
+// Begin claims key for a request. It returns the stored result when the key
+// already completed a request with the same fingerprint, ErrKeyReused when it
+// was used with another fingerprint and ErrInProgress while the first request
+// is still running. A nil result with a nil error means the caller must run
+// the request and then call Complete or Release.
+func (s *Store) Begin(key string, principalID uuid.UUID, operation, fingerprint string) (interface{}, error) {
+	if len(key) > MaxKeyLength {
+		return nil, ErrInvalidKey
+	}
+	now := s.now()
+
+	var record dto.IdempotencyKey
+	err := s.DB.Where("idempotency_key = ? AND principal_id = ?", key, principalID).First(&record).Error
+	switch {
+	case err == nil && !record.ExpiresAt.After(now):
+		if err := s.Release(key, principalID); err != nil {
+			return nil, err
+		}
+	case err == nil:
+		if record.Fingerprint != fingerprint {
+			return nil, ErrKeyReused
+		}
+		if len(record.Result) == 0 {
+			return nil, ErrInProgress
+		}
+		result, err := decodeResult(record.Result)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode stored result: %w", err)
+		}
+		return result, nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	// A concurrent request with the same key wins the insert
+	result := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&dto.IdempotencyKey{
+		Key:         key,
+		PrincipalID: principalID,
+		Operation:   operation,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.TTL),
+	})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrInProgress
+	}
+	return nil, nil
+}
+
+// Complete stores the result of the request that claimed key.
+func (s *Store) Complete(key string, principalID uuid.UUID, result interface{}) error {
+	encoded, err := encodeResult(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	return s.DB.Model(&dto.IdempotencyKey{}).
+		Where("idempotency_key = ? AND principal_id = ?", key, principalID).
+		Update("result", encoded).Error
+}
+
+// Release forgets key so that the request can be run again.
+func (s *Store) Release(key string, principalID uuid.UUID) error {
+	return s.DB.Where("idempotency_key = ? AND principal_id = ?", key, principalID).Delete(&dto.IdempotencyKey{}).Error
+}
+
+// PurgeExpired deletes the records whose TTL has passed.
+func (s *Store) PurgeExpired() (int64, error) {
+	result := s.DB.Where("expires_at <= ?", s.now()).Delete(&dto.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
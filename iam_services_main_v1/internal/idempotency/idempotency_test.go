@@ -0,0 +1,370 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goast "go/ast"
+	"go/parser"
+	"go/token"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/pkg/logger"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.IdempotencyKey{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func mutationContext(name, header string, args map[string]interface{}, userID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Request = httptest.NewRequest("POST", "/graphql", nil)
+	if header != "" {
+		ginCtx.Request.Header.Set(HeaderName, header)
+	}
+	ginCtx.Set("userID", userID.String())
+	ctx := context.WithValue(context.Background(), "GinContextKey", ginCtx)
+	return graphql.WithFieldContext(ctx, &graphql.FieldContext{
+		Object: "Mutation",
+		Field:  graphql.CollectedField{Field: &ast.Field{Name: name}},
+		Args:   args,
+	})
+}
+
+// countingResolver returns a new tenant on every call.
+func countingResolver(calls *int) graphql.Resolver {
+	return func(ctx context.Context) (interface{}, error) {
+		*calls++
+		return &models.SuccessResponse{IsSuccess: true, Data: []models.Data{
+			models.Tenant{ID: uuid.New(), Name: "acme", ParentOrg: &models.Root{ID: uuid.New()}},
+		}}, nil
+	}
+}
+
+func TestRetriesReplayTheStoredResult(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID := uuid.New()
+	args := map[string]interface{}{"input": models.CreateTenantInput{Name: "acme"}}
+	calls := 0
+
+	first, err := middleware(mutationContext("createTenant", "key-1", args, userID), countingResolver(&calls))
+	require.NoError(t, err)
+	second, err := middleware(mutationContext("createTenant", "key-1", args, userID), countingResolver(&calls))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	firstJSON, err := json.Marshal(first)
+	require.NoError(t, err)
+	secondJSON, err := json.Marshal(second)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(firstJSON), string(secondJSON))
+	require.IsType(t, &models.SuccessResponse{}, second)
+	tenant := second.(*models.SuccessResponse).Data[0].(*models.Tenant)
+	assert.IsType(t, &models.Root{}, tenant.ParentOrg)
+
+	// Keys belong to the principal that sent them
+	_, err = middleware(mutationContext("createTenant", "key-1", args, uuid.New()), countingResolver(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// Requests without a key always run
+	_, err = middleware(mutationContext("createTenant", "", args, userID), countingResolver(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestKeyReusedWithAnotherRequestIsRejected(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID := uuid.New()
+	calls := 0
+
+	_, err := middleware(mutationContext("createTenant", "key-1", map[string]interface{}{"input": models.CreateTenantInput{Name: "acme"}}, userID), countingResolver(&calls))
+	require.NoError(t, err)
+	res, err := middleware(mutationContext("createTenant", "key-1", map[string]interface{}{"input": models.CreateTenantInput{Name: "globex"}}, userID), countingResolver(&calls))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	require.IsType(t, &models.ResponseError{}, res)
+	assert.Equal(t, "422", res.(*models.ResponseError).ErrorCode)
+}
+
+func TestKeyReusedInAnotherTenantOrAsAnotherPrincipalIsRejected(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID, other, acme, globex := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	args := map[string]interface{}{"input": models.CreateRoleInput{Name: "reader"}}
+	request := func(tenantID uuid.UUID, impersonated *uuid.UUID) context.Context {
+		ctx := mutationContext("createRole", "key-1", args, userID)
+		ginCtx := ctx.Value("GinContextKey").(*gin.Context)
+		ginCtx.Set("tenantID", tenantID.String())
+		if impersonated != nil {
+			ginCtx.Set("impersonatedUserID", impersonated.String())
+		}
+		return ctx
+	}
+	calls := 0
+
+	_, err := middleware(request(acme, nil), countingResolver(&calls))
+	require.NoError(t, err)
+	for _, ctx := range []context.Context{request(globex, nil), request(acme, &other)} {
+		res, err := middleware(ctx, countingResolver(&calls))
+		require.NoError(t, err)
+		require.IsType(t, &models.ResponseError{}, res)
+		assert.Equal(t, "422", res.(*models.ResponseError).ErrorCode)
+	}
+	assert.Equal(t, 1, calls)
+
+	_, err = middleware(request(acme, nil), countingResolver(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestClientMutationIDIsAKey(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID := uuid.New()
+	id := "mutation-1"
+	args := map[string]interface{}{"input": models.CreateRoleInput{Name: "reader", ClientMutationID: &id}}
+	calls := 0
+
+	for i := 0; i < 2; i++ {
+		_, err := middleware(mutationContext("createRole", "", args, userID), countingResolver(&calls))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestServerErrorsAndExpiredKeysRunAgain(t *testing.T) {
+	logger.InitLogger()
+	store := NewStore(setupTestDB(t), time.Hour)
+	middleware := FieldMiddleware(store)
+	userID := uuid.New()
+	args := map[string]interface{}{"input": models.DeleteInput{ID: uuid.New()}}
+	calls := 0
+	failing := func(ctx context.Context) (interface{}, error) {
+		calls++
+		return &models.ResponseError{ErrorCode: "500", Message: "Permit unavailable"}, nil
+	}
+
+	_, err := middleware(mutationContext("deleteRole", "key-1", args, userID), failing)
+	require.NoError(t, err)
+	_, err = middleware(mutationContext("deleteRole", "key-1", args, userID), countingResolver(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	store.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = middleware(mutationContext("deleteRole", "key-1", args, userID), countingResolver(&calls))
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	store.now = func() time.Time { return time.Now().Add(4 * time.Hour) }
+	purged, err := store.PurgeExpired()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+}
+
+func TestConcurrentRetryIsInProgress(t *testing.T) {
+	logger.InitLogger()
+	store := NewStore(setupTestDB(t), time.Hour)
+	userID := uuid.New()
+	fingerprint, err := Fingerprint(nil, nil, "createTenant", nil)
+	require.NoError(t, err)
+
+	stored, err := store.Begin("key-1", userID, "createTenant", fingerprint)
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+	_, err = store.Begin("key-1", userID, "createTenant", fingerprint)
+	assert.ErrorIs(t, err, ErrInProgress)
+}
+
+func TestDatabaseErrorsAreNotInProgress(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("test:fail", func(tx *gorm.DB) {
+		_ = tx.AddError(errors.New("disk full"))
+	}))
+	fingerprint, err := Fingerprint(nil, nil, "createTenant", nil)
+	require.NoError(t, err)
+
+	_, err = NewStore(db, time.Hour).Begin("key-1", uuid.New(), "createTenant", fingerprint)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInProgress)
+	assert.ErrorContains(t, err, "disk full")
+}
+
+// withMutations adds an operation holding the named root mutation fields to ctx.
+func withMutations(ctx context.Context, names ...string) context.Context {
+	operation := &ast.OperationDefinition{Operation: ast.Mutation}
+	for _, name := range names {
+		operation.SelectionSet = append(operation.SelectionSet, &ast.Field{Name: name, Alias: name})
+	}
+	return graphql.WithOperationContext(ctx, &graphql.OperationContext{Operation: operation})
+}
+
+func TestHeaderKeyIsRefusedForSeveralMutations(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID := uuid.New()
+	calls := 0
+
+	ctx := withMutations(mutationContext("createTenant", "key-1", nil, userID), "createTenant", "deleteTenant")
+	res, err := middleware(ctx, countingResolver(&calls))
+	require.NoError(t, err)
+	require.IsType(t, &models.ResponseError{}, res)
+	assert.Equal(t, "400", res.(*models.ResponseError).ErrorCode)
+	assert.Zero(t, calls)
+
+	// Each mutation of the document can still carry its own clientMutationId
+	id := "mutation-1"
+	args := map[string]interface{}{"input": models.CreateRoleInput{Name: "reader", ClientMutationID: &id}}
+	for i := 0; i < 2; i++ {
+		ctx := withMutations(mutationContext("createRole", "key-1", args, userID), "createRole", "deleteRole")
+		_, err := middleware(ctx, countingResolver(&calls))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+
+	// A single mutation keeps using the header
+	for i := 0; i < 2; i++ {
+		_, err := middleware(withMutations(mutationContext("createTenant", "key-2", nil, userID), "createTenant"), countingResolver(&calls))
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestBatchResultsAreReplayed(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID := uuid.New()
+	args := map[string]interface{}{"ids": []uuid.UUID{uuid.New(), uuid.New()}}
+	calls := 0
+	resolver := func(ctx context.Context) (interface{}, error) {
+		calls++
+		return []models.OperationResult{
+			&models.SuccessResponse{IsSuccess: true, Data: []models.Data{&models.Binding{ID: uuid.New(), Principal: &models.User{ID: userID}}}},
+			&models.ResponseError{ErrorCode: "404", Message: "Resource not found"},
+		}, nil
+	}
+
+	first, err := middleware(mutationContext("deleteResources", "key-1", args, userID), resolver)
+	require.NoError(t, err)
+	second, err := middleware(mutationContext("deleteResources", "key-1", args, userID), resolver)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	firstJSON, err := json.Marshal(first)
+	require.NoError(t, err)
+	secondJSON, err := json.Marshal(second)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(firstJSON), string(secondJSON))
+
+	// Batches with a server error run again
+	failing := func(ctx context.Context) (interface{}, error) {
+		calls++
+		return []models.OperationResult{&models.ResponseError{ErrorCode: "500", Message: "Permit unavailable"}}, nil
+	}
+	for i := 0; i < 2; i++ {
+		_, err := middleware(mutationContext("deleteResources", "key-2", args, userID), failing)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, calls)
+}
+
+func TestTenantStatusTransitionsAreReplayed(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID, tenantID := uuid.New(), uuid.New()
+	args := map[string]interface{}{"input": models.TenantTransitionInput{ID: tenantID}}
+
+	for _, mutation := range []string{"suspendTenant", "reactivateTenant", "archiveTenant"} {
+		t.Run(mutation, func(t *testing.T) {
+			calls := 0
+			resolver := func(ctx context.Context) (interface{}, error) {
+				calls++
+				return &models.SuccessResponse{IsSuccess: true, Data: []models.Data{&models.TenantStatusTransition{
+					ID: uuid.New(), TenantID: tenantID, ToStatus: models.TenantStatusSuspended, CreatedBy: userID,
+				}}}, nil
+			}
+
+			first, err := middleware(mutationContext(mutation, mutation+"-key", args, userID), resolver)
+			require.NoError(t, err)
+			second, err := middleware(mutationContext(mutation, mutation+"-key", args, userID), resolver)
+			require.NoError(t, err)
+
+			assert.Equal(t, 1, calls)
+			require.IsType(t, &models.SuccessResponse{}, second)
+			assert.Equal(t, first.(*models.SuccessResponse).Data[0], second.(*models.SuccessResponse).Data[0])
+		})
+	}
+}
+
+// TestEveryUnionMemberIsRegistered fails when a type is added to the Data or
+// OperationResult union without being registered with gob, which would make
+// the mutations returning it run again on every retry.
+func TestEveryUnionMemberIsRegistered(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../../gql/models/models_gen.go", nil, 0)
+	require.NoError(t, err)
+	registered := map[string]bool{}
+	for _, value := range resultTypes {
+		registered[reflect.TypeOf(value).Name()] = true
+	}
+
+	members := 0
+	for _, decl := range file.Decls {
+		method, ok := decl.(*goast.FuncDecl)
+		if !ok || method.Recv == nil || (method.Name.Name != "IsData" && method.Name.Name != "IsOperationResult") {
+			continue
+		}
+		receiver, ok := method.Recv.List[0].Type.(*goast.Ident)
+		require.True(t, ok, "unexpected receiver of %s", method.Name.Name)
+		members++
+		assert.True(t, registered[receiver.Name], "%s is not in resultTypes", receiver.Name)
+	}
+	assert.Equal(t, len(resultTypes), members)
+}
+
+func TestInvitationsAreReplayed(t *testing.T) {
+	logger.InitLogger()
+	middleware := FieldMiddleware(NewStore(setupTestDB(t), time.Hour))
+	userID := uuid.New()
+	args := map[string]interface{}{"email": "alice@example.com", "roleId": uuid.New()}
+	calls := 0
+	resolver := func(ctx context.Context) (interface{}, error) {
+		calls++
+		return &models.SuccessResponse{IsSuccess: true, Data: []models.Data{&models.Invitation{
+			ID: uuid.New(), Email: "alice@example.com", Status: models.InvitationStatusPending, CreatedBy: userID,
+		}}}, nil
+	}
+
+	first, err := middleware(mutationContext("inviteUser", "key-1", args, userID), resolver)
+	require.NoError(t, err)
+	second, err := middleware(mutationContext("inviteUser", "key-1", args, userID), resolver)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, calls)
+	require.IsType(t, &models.SuccessResponse{}, second)
+	assert.Equal(t, first.(*models.SuccessResponse).Data[0], second.(*models.SuccessResponse).Data[0])
+}
@@ -0,0 +1,160 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"reflect"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+)
+
+// FieldMiddleware makes keyed mutations idempotent. The key is taken from the
+// clientMutationId of the mutation's input or else from the Idempotency-Key
+// header. Since the header names a single request, documents holding several
+// mutations are refused when it keys one of them. Keys are scoped to the
+// calling principal; one reused in another tenant, or while impersonating
+// someone else, is refused like one reused with other arguments. Results with
+// a 500 error code, or batch results
+// holding one, are not stored, so that a failed request can be retried with
+// the same key.
+func FieldMiddleware(store *Store) graphql.FieldMiddleware {
+	return func(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+		fc := graphql.GetFieldContext(ctx)
+		if fc == nil || fc.Object != "Mutation" {
+			return next(ctx)
+		}
+		key, fromHeader := requestKey(ctx, fc.Args)
+		if key == "" {
+			return next(ctx)
+		}
+		if fromHeader && mutationCount(ctx) > 1 {
+			return handleError("400", "Idempotency key applies to a single mutation", ErrSeveralMutations)
+		}
+
+		principalID := uuid.Nil
+		if userID, err := helpers.GetUserID(ctx); err == nil {
+			principalID = *userID
+		}
+		// The request runs as the impersonated user, when there is one
+		tenantID, _ := helpers.GetTenantID(ctx)
+		actingAs, _ := helpers.GetPrincipalID(ctx)
+		fingerprint, err := Fingerprint(tenantID, actingAs, fc.Field.Name, fc.Args)
+		if err != nil {
+			return handleError("500", "Error fingerprinting request", err)
+		}
+
+		stored, err := store.Begin(key, principalID, fc.Field.Name, fingerprint)
+		switch {
+		case errors.Is(err, ErrInvalidKey):
+			return handleError("400", "Invalid idempotency key", err)
+		case errors.Is(err, ErrKeyReused):
+			return handleError("422", "Idempotency key reused with a different request", err)
+		case errors.Is(err, ErrInProgress):
+			return handleError("409", "Request with this idempotency key is in progress", err)
+		case err != nil:
+			return handleError("500", "Error reading idempotency key", err)
+		case stored != nil:
+			return stored, nil
+		}
+
+		res, err := next(ctx)
+		if err != nil || isServerError(res) {
+			if releaseErr := store.Release(key, principalID); releaseErr != nil {
+				logger.LogError(fmt.Sprintf("Error releasing idempotency key of %s: %v", fc.Field.Name, releaseErr))
+			}
+			return res, err
+		}
+		if err := store.Complete(key, principalID, res); err != nil {
+			logger.LogError(fmt.Sprintf("Error storing result of %s for idempotency key: %v", fc.Field.Name, err))
+			if releaseErr := store.Release(key, principalID); releaseErr != nil {
+				logger.LogError(fmt.Sprintf("Error releasing idempotency key of %s: %v", fc.Field.Name, releaseErr))
+			}
+		}
+		return res, nil
+	}
+}
+
+// RunPurger deletes expired keys every interval until ctx is done.
+func RunPurger(ctx context.Context, store *Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := store.PurgeExpired(); err != nil {
+				logger.LogError(fmt.Sprintf("Idempotency key purge failed: %v", err))
+			}
+		}
+	}
+}
+
+// requestKey returns the clientMutationId of the mutation's input, or else the
+// Idempotency-Key header of the request, reporting which one it found.
+func requestKey(ctx context.Context, args map[string]interface{}) (string, bool) {
+	if id := clientMutationID(args["input"]); id != "" {
+		return id, false
+	}
+	ginCtx, err := helpers.GetGinContext(ctx)
+	if err != nil || ginCtx.Request == nil {
+		return "", false
+	}
+	key := ginCtx.GetHeader(HeaderName)
+	return key, key != ""
+}
+
+// mutationCount counts the root mutation fields of the request's operation.
+func mutationCount(ctx context.Context) int {
+	if !graphql.HasOperationContext(ctx) {
+		return 1
+	}
+	opCtx := graphql.GetOperationContext(ctx)
+	if opCtx.Operation == nil {
+		return 1
+	}
+	return len(graphql.CollectFields(opCtx, opCtx.Operation.SelectionSet, []string{"Mutation"}))
+}
+
+// clientMutationID reads the ClientMutationID field of an input struct.
+func clientMutationID(input interface{}) string {
+	value := reflect.ValueOf(input)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return ""
+	}
+	field := value.FieldByName("ClientMutationID")
+	if !field.IsValid() || field.Kind() != reflect.Pointer || field.IsNil() {
+		return ""
+	}
+	id, _ := field.Elem().Interface().(string)
+	return id
+}
+
+func isServerError(res interface{}) bool {
+	if results, ok := res.([]models.OperationResult); ok {
+		for _, result := range results {
+			if isServerError(result) {
+				return true
+			}
+		}
+		return false
+	}
+	responseError, ok := res.(*models.ResponseError)
+	return ok && responseError != nil && responseError.ErrorCode == "500"
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
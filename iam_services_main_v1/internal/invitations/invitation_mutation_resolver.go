@@ -0,0 +1,370 @@
+package invitations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxEmailLength is the size of the email column of principals.
+const maxEmailLength = 45
+
+// InvitationMutationResolver handles sending and accepting invitations.
+type InvitationMutationResolver struct {
+	DB *gorm.DB
+	PC *permit.PermitClient
+	// Settings configures invitations; nil disables them.
+	Settings *Settings
+}
+
+func (r *InvitationMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// InviteUser creates a pending user and binding of roleID on scopeID, the
+// request's tenant by default, and mails the user the token accepting them.
+// An expired invitation to the same email is replaced. Roles with an approval
+// policy cannot be granted by invitation, nor roles granting actions the
+// inviter does not hold in the tenant.
+func (r *InvitationMutationResolver) InviteUser(ctx context.Context, email string, roleID uuid.UUID, scopeID *uuid.UUID) (models.OperationResult, error) {
+	if r.Settings == nil {
+		return handleError("503", "Invitations are disabled", ErrDisabled)
+	}
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	tenantID := tenancy.TenantFromContext(ctx)
+	if tenantID == nil {
+		return handleError("400", "Invalid tenant ID", ErrTenantRequired)
+	}
+	db := tenancy.ForRequest(ctx, r.DB)
+	store := repository.NewGormStore(db)
+
+	email = strings.TrimSpace(email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email || len(email) > maxEmailLength {
+		return handleError("400", "Invalid email", fmt.Errorf("%w %q", ErrInvalidEmail, email))
+	}
+	if _, err := store.Principals().GetByEmail(ctx, email); err == nil {
+		return handleError("409", "User already exists", ErrEmailInUse)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return handleError("500", "Error fetching principal", err)
+	}
+
+	role, err := store.Roles().Get(ctx, roleID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return handleError("404", "Role not found", err)
+	}
+	if err != nil {
+		return handleError("500", "Error fetching role", err)
+	}
+	if privileged, err := repository.RequiresApproval(ctx, store.ApprovalPolicies(), roleID); err != nil {
+		return handleError("500", "Error fetching approval policy", err)
+	} else if privileged {
+		return handleError("403", "Role requires approval", ErrApprovalRequired)
+	}
+	if err := delegable(ctx, db, *tenantID, roleID); err != nil {
+		if errors.Is(err, ErrRoleExceedsInviter) {
+			return handleError("403", "Role exceeds the inviter's permissions", err)
+		}
+		return handleError("500", "Error checking the inviter's permissions", err)
+	}
+	tenant, err := store.Resources().Get(ctx, *tenantID)
+	if err != nil {
+		return handleError("404", "Tenant not found", err)
+	}
+	if scopeID == nil {
+		scopeID = tenantID
+	} else if _, err := store.Resources().Get(ctx, *scopeID); errors.Is(err, repository.ErrNotFound) {
+		return handleError("400", "Invalid scope", ErrInvalidScope)
+	} else if err != nil {
+		return handleError("500", "Error fetching scope", err)
+	}
+	userType, err := store.Resources().GetTypeByName(ctx, constants.ResourceTypeUser)
+	if err != nil {
+		return handleError("500", "Error getting resource type", err)
+	}
+
+	now := time.Now()
+	invitation := dto.Invitation{
+		InvitationID: uuid.New(),
+		TenantID:     tenantID,
+		Email:        email,
+		PrincipalID:  uuid.New(),
+		BindingID:    uuid.New(),
+		RoleID:       roleID,
+		ScopeID:      *scopeID,
+		// Tokens carry the expiry in seconds
+		ExpiresAt: now.Add(r.Settings.TTL).Truncate(time.Second),
+		CreatedBy: *userID,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := supersede(tx, email, now); err != nil {
+			return err
+		}
+		return createPending(tx, &invitation, userType.ResourceTypeID, *userID)
+	})
+	if errors.Is(err, ErrAlreadyInvited) {
+		return handleError("409", "User already invited", err)
+	}
+	if err != nil {
+		return handleError("500", "Error creating invitation", err)
+	}
+
+	// The mail is sent once the invitation is committed, so that the mail
+	// server is not waited for with the rows locked; an invitation that
+	// cannot be sent is withdrawn
+	token := signToken(r.Settings.Secret, invitation.InvitationID, invitation.ExpiresAt)
+	if err := r.Settings.Mailer.Send(ctx, Message{
+		To:      email,
+		Subject: fmt.Sprintf("You are invited to %s", tenant.Name),
+		Body: fmt.Sprintf("You have been invited to %s with the role %s.\n\nAccept the invitation: %s\n\nThe invitation can be used once and expires on %s.\n",
+			tenant.Name, role.Name, acceptLink(r.Settings.AcceptURL, token), invitation.ExpiresAt.UTC().Format(time.RFC1123)),
+	}); err != nil {
+		if withdrawErr := db.Transaction(func(tx *gorm.DB) error { return withdraw(tx, &invitation) }); withdrawErr != nil {
+			err = errors.Join(err, withdrawErr)
+		}
+		return handleError("500", "Error sending invitation", err)
+	}
+
+	logger.LogInfo(fmt.Sprintf("Invitation %s sent to %s by %s", invitation.InvitationID, email, *userID))
+	return utils.FormatSuccess([]models.Data{mapToInvitation(&invitation)})
+}
+
+// delegable fails with ErrRoleExceedsInviter unless the principal the request
+// runs as holds every action of roleID in tenantID, so that invitations do
+// not hand out more than the inviter has.
+func delegable(ctx context.Context, db *gorm.DB, tenantID, roleID uuid.UUID) error {
+	principalID, err := helpers.GetPrincipalID(ctx)
+	if err != nil {
+		return err
+	}
+	granted, err := bindings.GrantedActions(db, *principalID, tenantID)
+	if err != nil {
+		return err
+	}
+	actions, err := bindings.RoleActions(db, roleID)
+	if err != nil {
+		return err
+	}
+	for _, action := range actions {
+		if !bindings.Covers(granted, action) {
+			return fmt.Errorf("%w: %s", ErrRoleExceedsInviter, action)
+		}
+	}
+	return nil
+}
+
+// supersede removes the pending user and binding of expired invitations to
+// email. It fails with ErrAlreadyInvited while an invitation is pending.
+func supersede(tx *gorm.DB, email string, now time.Time) error {
+	var previous []dto.Invitation
+	if err := tx.Where("email = ? AND accepted_at IS NULL", email).Find(&previous).Error; err != nil {
+		return fmt.Errorf("failed to fetch invitations: %w", err)
+	}
+	for _, invitation := range previous {
+		if now.Before(invitation.ExpiresAt) {
+			return ErrAlreadyInvited
+		}
+		deleted := utils.UpdateDeletedMap()
+		for _, pending := range []struct {
+			model interface{}
+			id    uuid.UUID
+		}{
+			{&dto.TenantResource{}, invitation.PrincipalID},
+			{&dto.TenantPrincipals{}, invitation.PrincipalID},
+			{&dto.TenantRoleAssignments{}, invitation.BindingID},
+		} {
+			if err := tx.Model(pending.model).Where("resource_id = ? AND row_status = ?", pending.id, dto.RowStatusPending).Updates(deleted).Error; err != nil {
+				return fmt.Errorf("failed to remove expired invitation %s: %w", invitation.InvitationID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// createPending stores an invitation with its pending user and binding.
+func createPending(tx *gorm.DB, invitation *dto.Invitation, userTypeID, userID uuid.UUID) error {
+	if err := tx.Create(&dto.TenantResource{
+		ResourceID:       invitation.PrincipalID,
+		ParentResourceID: invitation.TenantID,
+		ResourceTypeID:   userTypeID,
+		Name:             invitation.Email,
+		TenantID:         invitation.TenantID,
+		RowStatus:        dto.RowStatusPending,
+		CreatedBy:        userID,
+		UpdatedBy:        userID,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := tx.Create(&dto.TenantPrincipals{
+		ResourceID:      invitation.PrincipalID,
+		PrincipalTypeID: userTypeID,
+		Name:            invitation.Email,
+		Email:           invitation.Email,
+		RowStatus:       dto.RowStatusPending,
+		CreatedBy:       userID,
+		UpdatedBy:       userID,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to create principal: %w", err)
+	}
+	if err := tx.Create(&dto.TenantRoleAssignments{
+		ResourceID:  invitation.BindingID,
+		Name:        "invitation",
+		Version:     "V1",
+		PrincipalID: invitation.PrincipalID,
+		RoleID:      invitation.RoleID,
+		TenantID:    invitation.TenantID,
+		ScopeID:     &invitation.ScopeID,
+		RowStatus:   dto.RowStatusPending,
+		CreatedBy:   userID,
+		UpdatedBy:   userID,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to create binding: %w", err)
+	}
+	if err := tx.Create(invitation).Error; err != nil {
+		return fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return nil
+}
+
+// withdraw deletes an invitation that was never sent, with its pending user
+// and binding.
+func withdraw(tx *gorm.DB, invitation *dto.Invitation) error {
+	for _, pending := range []struct {
+		model interface{}
+		id    uuid.UUID
+	}{
+		{&dto.TenantRoleAssignments{}, invitation.BindingID},
+		{&dto.TenantPrincipals{}, invitation.PrincipalID},
+		{&dto.TenantResource{}, invitation.PrincipalID},
+	} {
+		if err := tx.Where("resource_id = ? AND row_status = ?", pending.id, dto.RowStatusPending).Delete(pending.model).Error; err != nil {
+			return fmt.Errorf("failed to withdraw invitation %s: %w", invitation.InvitationID, err)
+		}
+	}
+	if err := tx.Where("invitation_id = ?", invitation.InvitationID).Delete(&dto.Invitation{}).Error; err != nil {
+		return fmt.Errorf("failed to withdraw invitation %s: %w", invitation.InvitationID, err)
+	}
+	return nil
+}
+
+// AcceptInvitation activates the user and binding of the invitation token
+// belongs to and creates them in Permit. The token stands in for the caller,
+// who is not a user yet, and names the tenant.
+func (r *InvitationMutationResolver) AcceptInvitation(ctx context.Context, token string) (models.OperationResult, error) {
+	if r.Settings == nil {
+		return handleError("503", "Invitations are disabled", ErrDisabled)
+	}
+	invitationID, expiresAt, err := parseToken(r.Settings.Secret, token)
+	if err != nil {
+		return handleError("400", "Invalid invitation token", err)
+	}
+	now := time.Now()
+	if !now.Before(expiresAt) {
+		return handleError("410", "Invitation expired", ErrInvitationExpired)
+	}
+
+	var invitation dto.Invitation
+	if err := tenancy.RootSession(r.DB.WithContext(ctx)).Where("invitation_id = ?", invitationID).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return handleError("400", "Invalid invitation token", ErrInvalidToken)
+		}
+		return handleError("500", "Error fetching invitation", err)
+	}
+	ctx = tenancy.WithTenant(ctx, *invitation.TenantID)
+	db := r.DB.WithContext(ctx)
+	switch status(&invitation, now) {
+	case models.InvitationStatusAccepted:
+		return handleError("409", "Invitation already accepted", ErrInvitationUsed)
+	case models.InvitationStatusExpired:
+		return handleError("410", "Invitation expired", ErrInvitationExpired)
+	}
+	if _, err := repository.NewGormStore(db).Principals().GetByEmail(ctx, invitation.Email); err == nil {
+		return handleError("409", "User already exists", ErrEmailInUse)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return handleError("500", "Error fetching principal", err)
+	}
+	var user dto.TenantResource
+	if err := db.Where("resource_id = ? AND row_status = ?", invitation.PrincipalID, dto.RowStatusPending).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return handleError("410", "Invitation expired", ErrInvitationExpired)
+		}
+		return handleError("500", "Error fetching invited user", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Claiming the invitation first makes the token single-use
+		claim := tx.Model(&dto.Invitation{}).Where("invitation_id = ? AND accepted_at IS NULL", invitation.InvitationID).Update("accepted_at", now)
+		if claim.Error != nil {
+			return fmt.Errorf("failed to accept invitation: %w", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			return ErrInvitationUsed
+		}
+		active := map[string]interface{}{"row_status": 1, "updated_by": invitation.PrincipalID, "updated_at": now}
+		if err := tx.Model(&dto.TenantResource{}).Where("resource_id = ?", invitation.PrincipalID).Updates(active).Error; err != nil {
+			return fmt.Errorf("failed to activate user: %w", err)
+		}
+		if err := tx.Model(&dto.TenantPrincipals{}).Where("resource_id = ?", invitation.PrincipalID).Updates(active).Error; err != nil {
+			return fmt.Errorf("failed to activate principal: %w", err)
+		}
+		if err := tx.Model(&dto.TenantRoleAssignments{}).Where("resource_id = ?", invitation.BindingID).Updates(active).Error; err != nil {
+			return fmt.Errorf("failed to activate binding: %w", err)
+		}
+		return pushToPermit(ctx, r.permitClient(), &invitation, user.ResourceTypeID)
+	})
+	if errors.Is(err, ErrInvitationUsed) {
+		return handleError("409", "Invitation already accepted", err)
+	}
+	if err != nil {
+		return handleError("500", "Error accepting invitation", err)
+	}
+
+	invitation.AcceptedAt = &now
+	logger.LogInfo(fmt.Sprintf("Invitation %s accepted by %s", invitation.InvitationID, invitation.Email))
+	return utils.FormatSuccess([]models.Data{mapToInvitation(&invitation)})
+}
+
+// pushToPermit creates the invited user and its role assignment in Permit,
+// removing the user again when the role cannot be assigned.
+func pushToPermit(ctx context.Context, pc *permit.PermitClient, invitation *dto.Invitation, userTypeID uuid.UUID) error {
+	tenantID := invitation.TenantID.String()
+	if _, err := pc.SendRequest(ctx, "POST", "resource_instances", map[string]interface{}{
+		"key":      invitation.PrincipalID.String(),
+		"resource": userTypeID.String(),
+		"tenant":   tenantID,
+	}); err != nil {
+		return fmt.Errorf("failed to create user in permit: %w", err)
+	}
+	if _, err := pc.SendRequest(ctx, "POST", "role_assignments", map[string]interface{}{
+		"user":   invitation.PrincipalID.String(),
+		"role":   invitation.RoleID.String(),
+		"tenant": tenantID,
+	}); err != nil {
+		if _, rollbackErr := pc.SendRequest(ctx, "DELETE", fmt.Sprintf("resource_instances/%s:%s", userTypeID, invitation.PrincipalID), nil); rollbackErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to roll back permit user: %w", rollbackErr))
+		}
+		return fmt.Errorf("failed to assign role in permit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,300 @@
+package invitations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit/permittest"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// fakeMailer keeps the messages it is asked to send, calling onSend first
+// when it is set.
+type fakeMailer struct {
+	err    error
+	sent   []Message
+	onSend func()
+}
+
+func (m *fakeMailer) Send(ctx context.Context, message Message) error {
+	if m.onSend != nil {
+		m.onSend()
+	}
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+var tokenPattern = regexp.MustCompile(`token=(\S+)`)
+
+// token returns the token of the last message sent.
+func (m *fakeMailer) token(t *testing.T) string {
+	require.NotEmpty(t, m.sent)
+	match := tokenPattern.FindStringSubmatch(m.sent[len(m.sent)-1].Body)
+	require.NotNil(t, match)
+	return match[1]
+}
+
+type fixture struct {
+	resolver *InvitationMutationResolver
+	db       *gorm.DB
+	permit   *permittest.Server
+	mailer   *fakeMailer
+	tenantID uuid.UUID
+	roleID   uuid.UUID
+	// inviter holds every action of roleID in the tenant
+	inviter uuid.UUID
+}
+
+func setupInvitations(t *testing.T) *fixture {
+	logger.InitLogger()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&dto.Mst_ResourceTypes{}, &dto.TenantResource{}, &dto.TenantPrincipals{},
+		&dto.TenantRoleAssignments{}, &dto.TNTRole{}, &dto.Invitation{}, &dto.ApprovalPolicy{},
+		&dto.MstPermission{}, &dto.TNTRolePermission{}))
+	require.NoError(t, db.Use(tenancy.Plugin{}))
+
+	fake := permittest.New(t)
+
+	f := &fixture{db: db, permit: fake, mailer: &fakeMailer{}, tenantID: uuid.New(), roleID: uuid.New(), inviter: uuid.New()}
+	tenantType, userType := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: tenantType, Name: constants.ResourceTypeTenant, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: userType, Name: constants.ResourceTypeUser, RowStatus: 1}).Error)
+	scoped := db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID))
+	require.NoError(t, scoped.Create(&dto.TenantResource{ResourceID: f.tenantID, ResourceTypeID: tenantType, Name: "acme", TenantID: &f.tenantID, RowStatus: 1}).Error)
+	require.NoError(t, scoped.Create(&dto.TenantResource{ResourceID: f.roleID, ResourceTypeID: uuid.New(), Name: "reader", TenantID: &f.tenantID, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TNTRole{ResourceID: f.roleID, Name: "reader", RowStatus: 1}).Error)
+	f.grant(t, f.roleID, "user.read")
+	adminRoleID := uuid.New()
+	f.grant(t, adminRoleID, "user.*")
+	require.NoError(t, scoped.Create(&dto.TenantRoleAssignments{
+		ResourceID: uuid.New(), Name: "admin", Version: "V1", PrincipalID: f.inviter, RoleID: adminRoleID, TenantID: &f.tenantID, RowStatus: 1,
+	}).Error)
+
+	pc := fake.Client()
+	f.resolver = &InvitationMutationResolver{DB: db, PC: pc, Settings: &Settings{
+		Mailer:    f.mailer,
+		Secret:    []byte("secret"),
+		TTL:       time.Hour,
+		AcceptURL: "https://iam.example.com/accept",
+	}}
+	return f
+}
+
+// grant adds a permission with action to roleID.
+func (f *fixture) grant(t *testing.T, roleID uuid.UUID, action string) {
+	permissionID := uuid.New()
+	require.NoError(t, f.db.Create(&dto.MstPermission{PermissionID: permissionID, Name: action, Action: action, RowStatus: 1}).Error)
+	require.NoError(t, f.db.Create(&dto.TNTRolePermission{ID: uuid.New(), RoleID: roleID, PermissionID: permissionID, RowStatus: 1}).Error)
+}
+
+// requestContext is the context of a request by userID, into tenantID when it
+// is not nil.
+func requestContext(userID uuid.UUID, tenantID *uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	if tenantID != nil {
+		ginCtx.Set("tenantID", tenantID.String())
+	}
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func (f *fixture) invite(t *testing.T, email string) models.OperationResult {
+	result, err := f.resolver.InviteUser(requestContext(f.inviter, &f.tenantID), email, f.roleID, nil)
+	require.NoError(t, err)
+	return result
+}
+
+func (f *fixture) accept(t *testing.T, token string) models.OperationResult {
+	// The invitee's request names no tenant
+	result, err := f.resolver.AcceptInvitation(requestContext(uuid.New(), nil), token)
+	require.NoError(t, err)
+	return result
+}
+
+func invitationOf(t *testing.T, result models.OperationResult) *models.Invitation {
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	return result.(*models.SuccessResponse).Data[0].(*models.Invitation)
+}
+
+func errorCode(t *testing.T, result models.OperationResult) string {
+	require.IsType(t, &models.ResponseError{}, result, "%+v", result)
+	return result.(*models.ResponseError).ErrorCode
+}
+
+func (f *fixture) rowStatus(t *testing.T, model interface{}, id uuid.UUID) int {
+	var rowStatus int
+	db := f.db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID))
+	require.NoError(t, db.Model(model).Select("row_status").Where("resource_id = ?", id).Scan(&rowStatus).Error)
+	return rowStatus
+}
+
+func TestInviteAndAccept(t *testing.T) {
+	f := setupInvitations(t)
+
+	invitation := invitationOf(t, f.invite(t, "ada@example.com"))
+	assert.Equal(t, models.InvitationStatusPending, invitation.Status)
+	assert.Equal(t, f.tenantID, invitation.TenantID)
+	assert.Equal(t, f.tenantID, invitation.ScopeID, "the scope defaults to the tenant")
+	require.Len(t, f.mailer.sent, 1)
+	assert.Equal(t, "ada@example.com", f.mailer.sent[0].To)
+	assert.Equal(t, "You are invited to acme", f.mailer.sent[0].Subject)
+	assert.Contains(t, f.mailer.sent[0].Body, "with the role reader")
+	assert.Contains(t, f.mailer.sent[0].Body, "https://iam.example.com/accept?token="+TokenPrefix)
+
+	// Nothing is granted before the invitation is accepted
+	assert.Equal(t, dto.RowStatusPending, f.rowStatus(t, &dto.TenantPrincipals{}, invitation.PrincipalID))
+	_, err := bindings.GetAssignment(f.db, invitation.BindingID)
+	assert.ErrorIs(t, err, bindings.ErrBindingNotFound)
+	assert.Empty(t, f.permit.Requests())
+	assert.Equal(t, "409", errorCode(t, f.invite(t, "ada@example.com")))
+
+	token := f.mailer.token(t)
+	accepted := invitationOf(t, f.accept(t, token))
+	assert.Equal(t, models.InvitationStatusAccepted, accepted.Status)
+	assert.NotNil(t, accepted.AcceptedAt)
+	assert.Equal(t, 1, f.rowStatus(t, &dto.TenantResource{}, invitation.PrincipalID))
+	assert.Equal(t, 1, f.rowStatus(t, &dto.TenantPrincipals{}, invitation.PrincipalID))
+	binding, err := bindings.GetAssignment(f.db, invitation.BindingID)
+	require.NoError(t, err)
+	assert.Equal(t, f.roleID, binding.RoleID)
+	assert.Equal(t, []string{"POST resource_instances", "POST role_assignments"}, f.permit.Requests())
+
+	// The token is single-use and the email now belongs to a user
+	assert.Equal(t, "409", errorCode(t, f.accept(t, token)))
+	assert.Equal(t, "409", errorCode(t, f.invite(t, "ada@example.com")))
+}
+
+func TestAcceptInvalidTokens(t *testing.T) {
+	f := setupInvitations(t)
+	invitation := invitationOf(t, f.invite(t, "ada@example.com"))
+	token := f.mailer.token(t)
+
+	forged := signToken([]byte("other secret"), invitation.ID, time.Now().Add(time.Hour))
+	unknown := signToken(f.resolver.Settings.Secret, uuid.New(), time.Now().Add(time.Hour))
+	expired := signToken(f.resolver.Settings.Secret, invitation.ID, time.Now().Add(-time.Second))
+	assert.Equal(t, "400", errorCode(t, f.accept(t, forged)))
+	assert.Equal(t, "400", errorCode(t, f.accept(t, unknown)))
+	assert.Equal(t, "400", errorCode(t, f.accept(t, token[:len(token)-2])))
+	assert.Equal(t, "410", errorCode(t, f.accept(t, expired)))
+
+	// Permit failures leave the invitation pending
+	f.permit.Fail("POST role_assignments")
+	assert.Equal(t, "500", errorCode(t, f.accept(t, token)))
+	assert.Equal(t, dto.RowStatusPending, f.rowStatus(t, &dto.TenantPrincipals{}, invitation.PrincipalID))
+	require.Len(t, f.permit.Requests(), 3)
+	assert.Equal(t, []string{"POST resource_instances", "POST role_assignments"}, f.permit.Requests()[:2])
+	assert.True(t, strings.HasPrefix(f.permit.Requests()[2], "DELETE resource_instances/"), f.permit.Requests()[2])
+
+	f.permit.Fail("")
+	assert.Equal(t, models.InvitationStatusAccepted, invitationOf(t, f.accept(t, token)).Status)
+}
+
+func TestReinviteAfterExpiry(t *testing.T) {
+	f := setupInvitations(t)
+	first := invitationOf(t, f.invite(t, "ada@example.com"))
+	db := f.db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID))
+	require.NoError(t, db.Model(&dto.Invitation{}).Where("invitation_id = ?", first.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+
+	second := invitationOf(t, f.invite(t, "ada@example.com"))
+	assert.NotEqual(t, first.PrincipalID, second.PrincipalID)
+	assert.Equal(t, 0, f.rowStatus(t, &dto.TenantPrincipals{}, first.PrincipalID))
+	assert.Equal(t, 0, f.rowStatus(t, &dto.TenantRoleAssignments{}, first.BindingID))
+	assert.Equal(t, dto.RowStatusPending, f.rowStatus(t, &dto.TenantPrincipals{}, second.PrincipalID))
+}
+
+func TestInviteRollsBackWhenMailFails(t *testing.T) {
+	f := setupInvitations(t)
+	scoped := f.db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID))
+	f.mailer.err = errors.New("connection refused")
+	// The mail is sent outside the transaction creating the invitation
+	f.mailer.onSend = func() {
+		counted := make(chan int64, 1)
+		go func() {
+			var count int64
+			assert.NoError(t, scoped.Model(&dto.Invitation{}).Count(&count).Error)
+			counted <- count
+		}()
+		select {
+		case count := <-counted:
+			assert.Equal(t, int64(1), count)
+		case <-time.After(time.Second):
+			t.Error("the invitation is still being written when the mail is sent")
+		}
+	}
+	assert.Equal(t, "500", errorCode(t, f.invite(t, "ada@example.com")))
+	f.mailer.onSend = nil
+	var count int64
+	require.NoError(t, f.db.Model(&dto.TenantPrincipals{}).Count(&count).Error)
+	assert.Zero(t, count)
+	require.NoError(t, scoped.Model(&dto.Invitation{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	f.mailer.err = nil
+	assert.Equal(t, "400", errorCode(t, f.invite(t, "Ada <ada@example.com>")))
+	result, err := f.resolver.InviteUser(requestContext(f.inviter, &f.tenantID), "ada@example.com", uuid.New(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "404", errorCode(t, result))
+	other := uuid.New()
+	result, err = f.resolver.InviteUser(requestContext(f.inviter, &f.tenantID), "ada@example.com", f.roleID, &other)
+	require.NoError(t, err)
+	assert.Equal(t, "400", errorCode(t, result))
+}
+
+func TestInviteRejectsRolesRequiringApproval(t *testing.T) {
+	f := setupInvitations(t)
+	scoped := f.db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID))
+	require.NoError(t, scoped.Create(&dto.ApprovalPolicy{PolicyID: uuid.New(), RoleID: f.roleID, RequiredApprovals: 1, RowStatus: 1}).Error)
+
+	assert.Equal(t, "403", errorCode(t, f.invite(t, "ada@example.com")))
+	assert.Empty(t, f.mailer.sent)
+	var count int64
+	require.NoError(t, scoped.Model(&dto.Invitation{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	// Once the policy is removed the role can be granted by invitation again
+	require.NoError(t, scoped.Model(&dto.ApprovalPolicy{}).Where("role_id = ?", f.roleID).Update("row_status", 0).Error)
+	invitationOf(t, f.invite(t, "ada@example.com"))
+}
+
+func TestInviteRejectsRolesExceedingTheInviter(t *testing.T) {
+	f := setupInvitations(t)
+	f.grant(t, f.roleID, "tenant.delete")
+
+	assert.Equal(t, "403", errorCode(t, f.invite(t, "ada@example.com")))
+	assert.Empty(t, f.mailer.sent)
+	var count int64
+	require.NoError(t, f.db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID)).Model(&dto.Invitation{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	// Covered by a wildcard of the inviter, the role can be granted, also by
+	// those impersonating the inviter
+	var binding dto.TenantRoleAssignments
+	require.NoError(t, f.db.WithContext(tenancy.WithTenant(context.Background(), f.tenantID)).Where("principal_id = ?", f.inviter).First(&binding).Error)
+	f.grant(t, binding.RoleID, "tenant.*")
+	ctx := requestContext(uuid.New(), &f.tenantID)
+	ctx.Value("GinContextKey").(*gin.Context).Set("impersonatedUserID", f.inviter.String())
+	result, err := f.resolver.InviteUser(ctx, "ada@example.com", f.roleID, nil)
+	require.NoError(t, err)
+	invitationOf(t, result)
+}
@@ -0,0 +1,214 @@
+// Package invitations invites users by email into a tenant with a role. An
+// invitation creates a pending principal and binding and mails a signed
+// token; accepting the invitation with the token activates both.
+package invitations
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TokenPrefix starts every invitation token.
+const TokenPrefix = "inv_"
+
+// DefaultTTL is how long an invitation can be accepted when
+// INVITATION_TTL is not set.
+const DefaultTTL = 72 * time.Hour
+
+var (
+	ErrDisabled           = errors.New("invitations are disabled; set INVITATION_MAILER")
+	ErrInvalidToken       = errors.New("invalid invitation token")
+	ErrInvitationExpired  = errors.New("invitation has expired")
+	ErrInvitationUsed     = errors.New("invitation has already been accepted")
+	ErrAlreadyInvited     = errors.New("a pending invitation was already sent to this email")
+	ErrEmailInUse         = errors.New("a user with this email already exists")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrInvalidScope       = errors.New("scope must be a resource of the request's tenant")
+	ErrTenantRequired     = errors.New("users are invited into the tenant of the request")
+	ErrApprovalRequired   = errors.New("the role is only granted through approved access requests; invite the user with another role and request this one with requestAccess")
+	ErrRoleExceedsInviter = errors.New("the role grants permissions the inviter does not hold")
+)
+
+// Settings configures how invitations are signed and mailed.
+type Settings struct {
+	Mailer Mailer
+	// Secret signs the tokens; tokens signed with another secret are invalid.
+	Secret []byte
+	TTL    time.Duration
+	// AcceptURL is the page accepting invitations. The token is added to it
+	// as the token query parameter; without it emails hold the bare token.
+	AcceptURL string
+}
+
+// Environment variables configuring invitations. INVITATION_MAILER is smtp or
+// file; invitations are disabled when it is empty.
+//
+//	INVITATION_SECRET     key signing the tokens (required)
+//	INVITATION_TTL        how long invitations can be accepted (default 72h)
+//	INVITATION_ACCEPT_URL page accepting invitations, linked from the emails
+//	INVITATION_FROM       sender of the emails (default no-reply@localhost)
+//
+//	SMTP_ADDRESS (host:port), SMTP_USERNAME, SMTP_PASSWORD
+//	INVITATION_MAIL_DIR   directory the file mailer writes to (default mail)
+const (
+	envMailer    = "INVITATION_MAILER"
+	envSecret    = "INVITATION_SECRET"
+	envTTL       = "INVITATION_TTL"
+	envAcceptURL = "INVITATION_ACCEPT_URL"
+	envFrom      = "INVITATION_FROM"
+)
+
+// SettingsFromEnv configures invitations from the environment. It returns
+// nil settings, which disable invitations, when no mailer is configured.
+func SettingsFromEnv() (*Settings, error) {
+	return settingsFromLookup(os.Getenv)
+}
+
+func settingsFromLookup(getenv func(string) string) (*Settings, error) {
+	mailer := strings.ToLower(strings.TrimSpace(getenv(envMailer)))
+	if mailer == "" {
+		return nil, nil
+	}
+	from := getenv(envFrom)
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	s := &Settings{TTL: DefaultTTL, AcceptURL: getenv(envAcceptURL)}
+	switch mailer {
+	case "smtp":
+		m, err := NewSMTPMailer(getenv("SMTP_ADDRESS"), from, getenv("SMTP_USERNAME"), getenv("SMTP_PASSWORD"))
+		if err != nil {
+			return nil, err
+		}
+		s.Mailer = m
+	case "file":
+		dir := getenv("INVITATION_MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		s.Mailer = &FileMailer{Dir: dir, From: from}
+	default:
+		return nil, fmt.Errorf("unknown invitation mailer %q", mailer)
+	}
+
+	if value := getenv(envTTL); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid %s: %q", envTTL, value)
+		}
+		s.TTL = ttl
+	}
+	// A key of the process alone would void the invitations sent before a
+	// restart, or by the other replicas
+	secret := getenv(envSecret)
+	if secret == "" {
+		return nil, fmt.Errorf("%s is required with %s", envSecret, envMailer)
+	}
+	s.Secret = []byte(secret)
+	return s, nil
+}
+
+// signToken returns the token of an invitation: its ID and expiry, signed
+// with HMAC-SHA256.
+func signToken(secret []byte, invitationID uuid.UUID, expiresAt time.Time) string {
+	payload := invitationID.String() + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return TokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(sign(secret, payload))
+}
+
+// parseToken returns the invitation and expiry of a token signed with secret.
+func parseToken(secret []byte, token string) (uuid.UUID, time.Time, error) {
+	encoded, signature, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(token), TokenPrefix), ".")
+	if !ok {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, sign(secret, string(payload))) {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+	id, expiry, _ := strings.Cut(string(payload), ".")
+	invitationID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+	seconds, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return uuid.Nil, time.Time{}, ErrInvalidToken
+	}
+	return invitationID, time.Unix(seconds, 0), nil
+}
+
+func sign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// acceptLink returns the link emails point to for token.
+func acceptLink(acceptURL, token string) string {
+	if acceptURL == "" {
+		return token
+	}
+	separator := "?"
+	if strings.Contains(acceptURL, "?") {
+		separator = "&"
+	}
+	return acceptURL + separator + "token=" + token
+}
+
+func status(invitation *dto.Invitation, now time.Time) models.InvitationStatus {
+	switch {
+	case invitation.AcceptedAt != nil:
+		return models.InvitationStatusAccepted
+	case !now.Before(invitation.ExpiresAt):
+		return models.InvitationStatusExpired
+	}
+	return models.InvitationStatusPending
+}
+
+func mapToInvitation(invitation *dto.Invitation) *models.Invitation {
+	result := &models.Invitation{
+		ID:          invitation.InvitationID,
+		Email:       invitation.Email,
+		PrincipalID: invitation.PrincipalID,
+		BindingID:   invitation.BindingID,
+		RoleID:      invitation.RoleID,
+		ScopeID:     invitation.ScopeID,
+		Status:      status(invitation, time.Now()),
+		ExpiresAt:   invitation.ExpiresAt.Format(time.RFC3339),
+		CreatedAt:   invitation.CreatedAt.Format(time.RFC3339),
+		CreatedBy:   invitation.CreatedBy,
+	}
+	if invitation.TenantID != nil {
+		result.TenantID = *invitation.TenantID
+	}
+	if invitation.AcceptedAt != nil {
+		acceptedAt := invitation.AcceptedAt.Format(time.RFC3339)
+		result.AcceptedAt = &acceptedAt
+	}
+	return result
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
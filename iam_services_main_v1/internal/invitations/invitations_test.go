@@ -0,0 +1,69 @@
+package invitations
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookup(env map[string]string) func(string) string {
+	return func(key string) string { return env[key] }
+}
+
+func TestSettingsFromEnv(t *testing.T) {
+	logger.InitLogger()
+	settings, err := settingsFromLookup(lookup(nil))
+	require.NoError(t, err)
+	assert.Nil(t, settings, "invitations are disabled without a mailer")
+
+	settings, err = settingsFromLookup(lookup(map[string]string{"INVITATION_MAILER": "file", "INVITATION_SECRET": "s3cret", "INVITATION_TTL": "24h"}))
+	require.NoError(t, err)
+	assert.Equal(t, &FileMailer{Dir: "mail", From: "no-reply@localhost"}, settings.Mailer)
+	assert.Equal(t, []byte("s3cret"), settings.Secret)
+	assert.Equal(t, 24*time.Hour, settings.TTL)
+
+	settings, err = settingsFromLookup(lookup(map[string]string{"INVITATION_MAILER": "SMTP", "INVITATION_SECRET": "s3cret", "SMTP_ADDRESS": "mail.example.com:587", "SMTP_USERNAME": "iam"}))
+	require.NoError(t, err)
+	require.IsType(t, &SMTPMailer{}, settings.Mailer)
+	assert.NotNil(t, settings.Mailer.(*SMTPMailer).Auth)
+	assert.Equal(t, DefaultTTL, settings.TTL)
+
+	for _, env := range []map[string]string{
+		{"INVITATION_MAILER": "pigeon", "INVITATION_SECRET": "s3cret"},
+		{"INVITATION_MAILER": "smtp", "INVITATION_SECRET": "s3cret"},
+		{"INVITATION_MAILER": "file", "INVITATION_SECRET": "s3cret", "INVITATION_TTL": "-1h"},
+		// Tokens signed with a key of the process would not survive a restart
+		{"INVITATION_MAILER": "file"},
+	} {
+		_, err := settingsFromLookup(lookup(env))
+		assert.Error(t, err, "%v", env)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer := &FileMailer{Dir: dir, From: "iam@example.com"}
+	require.NoError(t, mailer.Send(context.Background(), Message{To: "ada@example.com", Subject: "Hi\r\nBcc: eve@example.com", Body: "line 1\nline 2"}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "From: iam@example.com\r\nTo: ada@example.com\r\nSubject: Hi  Bcc: eve@example.com\r\n")
+	assert.True(t, strings.HasSuffix(string(content), "\r\n\r\nline 1\r\nline 2"))
+}
+
+func TestAcceptLink(t *testing.T) {
+	assert.Equal(t, "inv_x", acceptLink("", "inv_x"))
+	assert.Equal(t, "https://iam.example.com/accept?token=inv_x", acceptLink("https://iam.example.com/accept", "inv_x"))
+	assert.Equal(t, "https://iam.example.com/?page=accept&token=inv_x", acceptLink("https://iam.example.com/?page=accept", "inv_x"))
+}
@@ -0,0 +1,95 @@
+package invitations
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends the emails of invitations.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// SMTPMailer sends emails through an SMTP server, upgrading to TLS when the
+// server offers it.
+type SMTPMailer struct {
+	// Address is the host:port of the server.
+	Address string
+	From    string
+	// Auth authenticates to the server; nil sends without authentication.
+	Auth smtp.Auth
+}
+
+// NewSMTPMailer returns a mailer sending from the given address, with PLAIN
+// authentication when username is set.
+func NewSMTPMailer(address, from, username, password string) (*SMTPMailer, error) {
+	host, _, ok := strings.Cut(address, ":")
+	if address == "" || !ok {
+		return nil, fmt.Errorf("invalid SMTP address %q; use host:port", address)
+	}
+	m := &SMTPMailer{Address: address, From: from}
+	if username != "" {
+		m.Auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m, nil
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := smtp.SendMail(m.Address, m.Auth, m.From, []string{message.To}, compose(m.From, message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// FileMailer writes each email to a new file of Dir instead of sending it,
+// for local development.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	if err := os.MkdirAll(m.Dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	name := filepath.Join(m.Dir, time.Now().UTC().Format("20060102T150405.000000000Z")+".eml")
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create email file: %w", err)
+	}
+	if _, err := file.Write(compose(m.From, message)); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write email file: %w", err)
+	}
+	return file.Close()
+}
+
+// compose formats message as an RFC 5322 email.
+func compose(from string, message Message) []byte {
+	var b strings.Builder
+	// Header values cannot start new headers
+	header := strings.NewReplacer("\r", " ", "\n", " ")
+	fmt.Fprintf(&b, "From: %s\r\n", header.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", header.Replace(message.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", header.Replace(message.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,121 @@
+package labels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/etag"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LabelMutationResolver handles label changes on resources.
+type LabelMutationResolver struct {
+	DB *gorm.DB
+	PC *permit.PermitClient
+}
+
+// SetLabels adds labels to a resource, overwriting values of existing keys.
+func (r *LabelMutationResolver) SetLabels(ctx context.Context, input models.SetLabelsInput) (models.OperationResult, error) {
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	labels := make(map[string]string, len(input.Labels))
+	for _, label := range input.Labels {
+		if err := ValidateKey(label.Key); err != nil {
+			return handleError("400", "Invalid label", err)
+		}
+		if err := ValidateValue(label.Value); err != nil {
+			return handleError("400", "Invalid label", err)
+		}
+		labels[label.Key] = label.Value
+	}
+
+	return r.applyChange(ctx, input.ResourceID, input.ExpectedEtag, func(tx *gorm.DB) error {
+		return setLabels(tx, input.ResourceID, labels, *userID)
+	})
+}
+
+// RemoveLabels removes labels from a resource. Unknown keys are ignored.
+func (r *LabelMutationResolver) RemoveLabels(ctx context.Context, input models.RemoveLabelsInput) (models.OperationResult, error) {
+	if _, err := helpers.GetUserID(ctx); err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	return r.applyChange(ctx, input.ResourceID, input.ExpectedEtag, func(tx *gorm.DB) error {
+		return removeLabels(tx, input.ResourceID, input.Keys)
+	})
+}
+
+// applyChange runs a label change in a transaction and commits it only once
+// the resulting label set has been mirrored to Permit. The change advances the
+// resource's revision, and is rejected when expectedEtag no longer matches it.
+func (r *LabelMutationResolver) applyChange(ctx context.Context, resourceID uuid.UUID, expectedEtag *string, change func(tx *gorm.DB) error) (models.OperationResult, error) {
+	expectedRevision, err := etag.ParseOptional(expectedEtag)
+	if err != nil {
+		return handleError("400", "Invalid expectedEtag", err)
+	}
+
+	// Root-level requests label Root resources
+	ctx = tenancy.AllowRoot(ctx)
+	r = &LabelMutationResolver{DB: tenancy.ForRequest(ctx, r.DB), PC: r.PC}
+	resource, err := getResource(r.DB, resourceID)
+	if err != nil {
+		return handleError("404", "Resource not found", err)
+	}
+
+	var labels map[string]string
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		resources := repository.NewGormStore(tx).Resources()
+		if err := repository.UpdateResource(ctx, resources, resourceID, expectedRevision, map[string]interface{}{}); err != nil {
+			return err
+		}
+		if err := change(tx); err != nil {
+			return err
+		}
+		current, err := GetLabels(tx, resourceID)
+		if err != nil {
+			return err
+		}
+		if err := syncPermitLabels(ctx, tx, r.permitClient(), resource, current); err != nil {
+			return err
+		}
+		labels = current
+		return nil
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return handleError(constants.ErrorCodeConflict, "Resource was modified concurrently", err)
+	}
+	if err != nil {
+		return handleError("500", "Error updating labels", err)
+	}
+
+	return utils.FormatSuccess([]models.Data{&models.ResourceLabels{
+		ResourceID: resourceID,
+		Labels:     ToModels(labels),
+	}})
+}
+
+func (r *LabelMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
@@ -0,0 +1,165 @@
+package labels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.TenantResource{}, &dto.TNTResourceLabel{}, &dto.TNTRole{}, &dto.Mst_ResourceTypes{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// setupPermit returns a client whose PATCH bodies are captured per endpoint.
+func setupPermit(t *testing.T, status int, patches map[string]map[string]interface{}) *permit.PermitClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodPatch {
+			var body map[string]interface{}
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			patches[req.URL.Path] = body
+		}
+		w.WriteHeader(status)
+		if req.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"attributes":{"Name":"existing"}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+}
+
+func userContext() context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", uuid.NewString())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func TestSetAndRemoveTenantLabels(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	patches := map[string]map[string]interface{}{}
+	resolver := &LabelMutationResolver{DB: db, PC: setupPermit(t, http.StatusOK, patches)}
+
+	tenantTypeID, tenantID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: tenantTypeID, Name: "Tenant", RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: tenantID, ResourceTypeID: tenantTypeID, Name: "acme", RowStatus: 1}).Error)
+
+	result, err := resolver.SetLabels(userContext(), models.SetLabelsInput{
+		ResourceID: tenantID,
+		Labels:     []*models.LabelInput{{Key: "env", Value: "prod"}, {Key: "region", Value: "eu"}},
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+
+	_, err = resolver.SetLabels(userContext(), models.SetLabelsInput{
+		ResourceID: tenantID,
+		Labels:     []*models.LabelInput{{Key: "env", Value: "staging"}},
+	})
+	require.NoError(t, err)
+
+	current, err := GetLabels(db, tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "staging", "region": "eu"}, current)
+
+	patch := patches[fmt.Sprintf("/v2/facts/proj/env/tenants/%s", tenantID)]
+	require.NotNil(t, patch)
+	attributes := patch["attributes"].(map[string]interface{})
+	assert.Equal(t, "existing", attributes["Name"])
+	assert.Equal(t, map[string]interface{}{"env": "staging", "region": "eu"}, attributes["labels"])
+
+	result, err = resolver.RemoveLabels(userContext(), models.RemoveLabelsInput{ResourceID: tenantID, Keys: []string{"env"}})
+	require.NoError(t, err)
+	success := result.(*models.SuccessResponse)
+	assert.Equal(t, []*models.Label{{Key: "region", Value: "eu"}}, success.Data[0].(*models.ResourceLabels).Labels)
+}
+
+func TestSetLabelsRollsBackWhenPermitFails(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	resolver := &LabelMutationResolver{DB: db, PC: setupPermit(t, http.StatusInternalServerError, map[string]map[string]interface{}{})}
+
+	roleID := uuid.New()
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: roleID, ResourceTypeID: uuid.New(), Name: "admin", RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TNTRole{ResourceID: roleID, Name: "admin", RoleType: dto.RoleTypeEnumCustom, Version: "v1", ScopeResourceTypeID: uuid.New(), RowStatus: 1}).Error)
+
+	result, err := resolver.SetLabels(userContext(), models.SetLabelsInput{
+		ResourceID: roleID,
+		Labels:     []*models.LabelInput{{Key: "env", Value: "prod"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "500", result.(*models.ResponseError).ErrorCode)
+
+	current, err := GetLabels(db, roleID)
+	require.NoError(t, err)
+	assert.Empty(t, current)
+}
+
+func TestSetLabelsRejectsStaleEtag(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	patches := map[string]map[string]interface{}{}
+	resolver := &LabelMutationResolver{DB: db, PC: setupPermit(t, http.StatusOK, patches)}
+
+	tenantTypeID, tenantID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: tenantTypeID, Name: "Tenant", RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TenantResource{ResourceID: tenantID, ResourceTypeID: tenantTypeID, Name: "acme", RowStatus: 1}).Error)
+
+	first := `W/"1"`
+	result, err := resolver.SetLabels(userContext(), models.SetLabelsInput{
+		ResourceID:   tenantID,
+		Labels:       []*models.LabelInput{{Key: "env", Value: "prod"}},
+		ExpectedEtag: &first,
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+
+	// The first change advanced the revision, so its etag is stale
+	delete(patches, fmt.Sprintf("/v2/facts/proj/env/tenants/%s", tenantID))
+	result, err = resolver.RemoveLabels(userContext(), models.RemoveLabelsInput{ResourceID: tenantID, Keys: []string{"env"}, ExpectedEtag: &first})
+	require.NoError(t, err)
+	assert.Equal(t, "CONFLICT", result.(*models.ResponseError).ErrorCode)
+	assert.Empty(t, patches)
+
+	current, err := GetLabels(db, tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod"}, current)
+
+	invalid := "latest"
+	result, err = resolver.RemoveLabels(userContext(), models.RemoveLabelsInput{ResourceID: tenantID, Keys: []string{"env"}, ExpectedEtag: &invalid})
+	require.NoError(t, err)
+	assert.Equal(t, "400", result.(*models.ResponseError).ErrorCode)
+}
+
+func TestSetLabelsValidatesInput(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	resolver := &LabelMutationResolver{DB: db}
+
+	result, err := resolver.SetLabels(userContext(), models.SetLabelsInput{
+		ResourceID: uuid.New(),
+		Labels:     []*models.LabelInput{{Key: "bad key", Value: "x"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "400", result.(*models.ResponseError).ErrorCode)
+}
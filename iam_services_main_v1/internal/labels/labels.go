@@ -0,0 +1,176 @@
+package labels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/utils"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PermitAttributeKey is the Permit attribute that mirrors resource labels for ABAC rules.
+const PermitAttributeKey = "labels"
+
+var ErrResourceNotFound = errors.New("resource not found")
+
+// GetLabels returns the labels of a single resource.
+func GetLabels(db *gorm.DB, resourceID uuid.UUID) (map[string]string, error) {
+	labels, err := GetLabelsForResources(db, []uuid.UUID{resourceID})
+	if err != nil {
+		return nil, err
+	}
+	if labels[resourceID] == nil {
+		return map[string]string{}, nil
+	}
+	return labels[resourceID], nil
+}
+
+// GetLabelsForResources returns the labels of several resources keyed by resource ID.
+func GetLabelsForResources(db *gorm.DB, resourceIDs []uuid.UUID) (map[uuid.UUID]map[string]string, error) {
+	result := make(map[uuid.UUID]map[string]string, len(resourceIDs))
+	if len(resourceIDs) == 0 {
+		return result, nil
+	}
+
+	var rows []dto.TNTResourceLabel
+	if err := db.Where("resource_id IN ? AND row_status = 1", resourceIDs).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch labels: %w", err)
+	}
+	for _, row := range rows {
+		if result[row.ResourceID] == nil {
+			result[row.ResourceID] = map[string]string{}
+		}
+		result[row.ResourceID][row.Key] = row.Value
+	}
+	return result, nil
+}
+
+// ToModels converts a label set into its GraphQL representation, sorted by key.
+func ToModels(labels map[string]string) []*models.Label {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]*models.Label, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, &models.Label{Key: key, Value: labels[key]})
+	}
+	return result
+}
+
+// FromModels converts GraphQL labels back into a label set.
+func FromModels(labels []*models.Label) map[string]string {
+	result := make(map[string]string, len(labels))
+	for _, label := range labels {
+		result[label.Key] = label.Value
+	}
+	return result
+}
+
+// setLabels upserts labels on a resource.
+func setLabels(tx *gorm.DB, resourceID uuid.UUID, labels map[string]string, userID uuid.UUID) error {
+	for key, value := range labels {
+		var existing dto.TNTResourceLabel
+		err := tx.Where("resource_id = ? AND label_key = ? AND row_status = 1", resourceID, key).First(&existing).Error
+		switch {
+		case err == nil:
+			if err := tx.Model(&dto.TNTResourceLabel{}).Where("label_id = ?", existing.LabelID).Updates(map[string]interface{}{
+				"label_value": value,
+				"updated_by":  userID,
+				"updated_at":  time.Now(),
+			}).Error; err != nil {
+				return fmt.Errorf("failed to update label %s: %w", key, err)
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := tx.Create(&dto.TNTResourceLabel{
+				LabelID:    uuid.New(),
+				ResourceID: resourceID,
+				Key:        key,
+				Value:      value,
+				RowStatus:  1,
+				CreatedBy:  userID,
+				UpdatedBy:  userID,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to create label %s: %w", key, err)
+			}
+		default:
+			return fmt.Errorf("failed to fetch label %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// removeLabels soft deletes labels from a resource.
+func removeLabels(tx *gorm.DB, resourceID uuid.UUID, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := tx.Model(&dto.TNTResourceLabel{}).
+		Where("resource_id = ? AND label_key IN ? AND row_status = 1", resourceID, keys).
+		Updates(utils.UpdateDeletedMap()).Error; err != nil {
+		return fmt.Errorf("failed to remove labels: %w", err)
+	}
+	return nil
+}
+
+func getResource(db *gorm.DB, resourceID uuid.UUID) (*dto.TenantResource, error) {
+	var resource dto.TenantResource
+	if err := db.Where("resource_id = ? AND row_status = 1", resourceID).First(&resource).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrResourceNotFound, err)
+	}
+	return &resource, nil
+}
+
+// syncPermitLabels mirrors the label set of a resource onto its Permit
+// attributes. Roles and tenants keep their attributes on the role and tenant
+// objects; every other resource type uses its resource instance.
+func syncPermitLabels(ctx context.Context, db *gorm.DB, pc *permit.PermitClient, resource *dto.TenantResource, labels map[string]string) error {
+	var role dto.TNTRole
+	err := db.Where("resource_id = ? AND row_status = 1", resource.ResourceID).First(&role).Error
+	if err == nil {
+		return mergePermitAttributes(ctx, pc, fmt.Sprintf("resources/%s/roles/%s", role.ScopeResourceTypeID, role.ResourceID), labels)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to fetch role: %w", err)
+	}
+
+	var resourceType dto.Mst_ResourceTypes
+	if err := db.Where("resource_type_id = ?", resource.ResourceTypeID).First(&resourceType).Error; err != nil {
+		return fmt.Errorf("resource type not found: %w", err)
+	}
+	if resourceType.Name == constants.ResourceTypeTenant {
+		return mergePermitAttributes(ctx, pc, fmt.Sprintf("tenants/%s", resource.ResourceID), labels)
+	}
+
+	return mergePermitAttributes(ctx, pc, fmt.Sprintf("resource_instances/%s:%s", resource.ResourceTypeID, resource.ResourceID), labels)
+}
+
+func mergePermitAttributes(ctx context.Context, pc *permit.PermitClient, endpoint string, labels map[string]string) error {
+	current, err := pc.SendRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch permit attributes: %w", err)
+	}
+
+	attributes, ok := current["attributes"].(map[string]interface{})
+	if !ok {
+		attributes = map[string]interface{}{}
+	}
+	attributes[PermitAttributeKey] = labels
+
+	if _, err := pc.SendRequest(ctx, "PATCH", endpoint, map[string]interface{}{
+		"attributes": attributes,
+	}); err != nil {
+		return fmt.Errorf("failed to update permit attributes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package labels
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidSelector = errors.New("invalid label selector")
+
+var (
+	keyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9._/-]{0,61}[A-Za-z0-9])?$`)
+	valuePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9._-]{0,61}[A-Za-z0-9])?)?$`)
+	setPattern   = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+type operator string
+
+const (
+	opEquals       operator = "="
+	opNotEquals    operator = "!="
+	opIn           operator = "in"
+	opNotIn        operator = "notin"
+	opExists       operator = "exists"
+	opDoesNotExist operator = "!"
+)
+
+type requirement struct {
+	key    string
+	op     operator
+	values []string
+}
+
+// Selector is a parsed Kubernetes-style label selector. The zero value
+// matches every resource.
+type Selector struct {
+	requirements []requirement
+}
+
+// ParseSelector parses selectors such as "env=prod,region in (eu,us),!legacy".
+// Supported operators are =, ==, !=, in, notin, key (exists) and !key.
+func ParseSelector(selector string) (Selector, error) {
+	var parsed Selector
+	for _, part := range splitRequirements(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return Selector{}, fmt.Errorf("%w: empty requirement in %q", ErrInvalidSelector, selector)
+		}
+		req, err := parseRequirement(part)
+		if err != nil {
+			return Selector{}, err
+		}
+		parsed.requirements = append(parsed.requirements, req)
+	}
+	return parsed, nil
+}
+
+// ParseOptionalSelector parses an optional selector argument. A nil selector
+// matches every resource.
+func ParseOptionalSelector(selector *string) (Selector, error) {
+	if selector == nil {
+		return Selector{}, nil
+	}
+	return ParseSelector(*selector)
+}
+
+// Empty reports whether the selector has no requirements.
+func (s Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches reports whether a label set satisfies every requirement.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s.requirements {
+		value, exists := labels[req.key]
+		switch req.op {
+		case opEquals:
+			if !exists || value != req.values[0] {
+				return false
+			}
+		case opNotEquals:
+			if exists && value == req.values[0] {
+				return false
+			}
+		case opIn:
+			if !exists || !contains(req.values, value) {
+				return false
+			}
+		case opNotIn:
+			if exists && contains(req.values, value) {
+				return false
+			}
+		case opExists:
+			if !exists {
+				return false
+			}
+		case opDoesNotExist:
+			if exists {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ValidateKey checks that a label key is well formed.
+func ValidateKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("invalid label key %q: must be 1-63 alphanumeric characters, '-', '_', '.' or '/'", key)
+	}
+	return nil
+}
+
+// ValidateValue checks that a label value is well formed.
+func ValidateValue(value string) error {
+	if !valuePattern.MatchString(value) {
+		return fmt.Errorf("invalid label value %q: must be at most 63 alphanumeric characters, '-', '_' or '.'", value)
+	}
+	return nil
+}
+
+func parseRequirement(part string) (requirement, error) {
+	if strings.HasPrefix(part, "!") {
+		key := strings.TrimSpace(part[1:])
+		return newRequirement(key, opDoesNotExist, nil)
+	}
+
+	if m := setPattern.FindStringSubmatch(part); m != nil {
+		var values []string
+		for _, v := range strings.Split(m[3], ",") {
+			values = append(values, strings.TrimSpace(v))
+		}
+		return newRequirement(m[1], operator(m[2]), values)
+	}
+
+	for _, op := range []string{"!=", "==", "="} {
+		if idx := strings.Index(part, op); idx >= 0 {
+			key := strings.TrimSpace(part[:idx])
+			value := strings.TrimSpace(part[idx+len(op):])
+			if op == "!=" {
+				return newRequirement(key, opNotEquals, []string{value})
+			}
+			return newRequirement(key, opEquals, []string{value})
+		}
+	}
+
+	return newRequirement(part, opExists, nil)
+}
+
+func newRequirement(key string, op operator, values []string) (requirement, error) {
+	if err := ValidateKey(key); err != nil {
+		return requirement{}, fmt.Errorf("%w: %v", ErrInvalidSelector, err)
+	}
+	for _, v := range values {
+		if err := ValidateValue(v); err != nil {
+			return requirement{}, fmt.Errorf("%w: %v", ErrInvalidSelector, err)
+		}
+	}
+	return requirement{key: key, op: op, values: values}, nil
+}
+
+// splitRequirements splits on commas that are not inside a value set.
+func splitRequirements(selector string) []string {
+	if strings.TrimSpace(selector) == "" {
+		return nil
+	}
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSelectorMatches(t *testing.T) {
+	labels := map[string]string{"env": "prod", "region": "eu", "team": "iam"}
+
+	tests := []struct {
+		selector string
+		matches  bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env==prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"missing!=dev", true},
+		{"region in (eu, us)", true},
+		{"region in (us,apac)", false},
+		{"region notin (us,apac)", true},
+		{"team", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!team", false},
+		{"env=prod,region in (eu,us),!legacy", true},
+		{"env=prod,region notin (eu)", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			selector, err := ParseSelector(tt.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tt.matches, selector.Matches(labels))
+		})
+	}
+}
+
+func TestParseSelectorErrors(t *testing.T) {
+	for _, selector := range []string{"env=prod,", "=prod", "env in (eu", "bad key=value", "env=pr od"} {
+		_, err := ParseSelector(selector)
+		assert.ErrorIs(t, err, ErrInvalidSelector, selector)
+	}
+}
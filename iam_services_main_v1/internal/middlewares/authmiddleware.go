@@ -1,12 +1,73 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
+	"iam_services_main_v1/internal/bindings"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// ImpersonateUserHeader names the user a caller wants to act as.
+const ImpersonateUserHeader = "X-Impersonate-User"
+
+// ImpersonatePermissionAction is the permission action a caller's roles must
+// grant for the caller to impersonate other users.
+const ImpersonatePermissionAction = "impersonate"
+
+// ImpersonationAuthorizer reports whether actorID may impersonate targetID
+// within tenantID.
+type ImpersonationAuthorizer func(ctx context.Context, actorID, targetID, tenantID uuid.UUID) (bool, error)
+
+// PermissionImpersonationAuthorizer allows principals whose bindings grant
+// ImpersonatePermissionAction in a tenant to impersonate the principals of
+// that tenant that are no more privileged than themselves: the target must
+// hold no binding outside the tenant or on Root, and every permission its
+// bindings grant must also be granted to the actor.
+func PermissionImpersonationAuthorizer(db *gorm.DB) ImpersonationAuthorizer {
+	return func(ctx context.Context, actorID, targetID, tenantID uuid.UUID) (bool, error) {
+		db := db.WithContext(ctx)
+		allowed, err := bindings.HasPermission(db, actorID, ImpersonatePermissionAction, nil, &tenantID)
+		if err != nil || !allowed {
+			return false, err
+		}
+
+		var members int64
+		if err := db.WithContext(tenancy.WithTenant(ctx, tenantID)).Model(&dto.TenantResource{}).
+			Where("resource_id = ? AND tenant_id = ? AND row_status = 1", targetID, tenantID).
+			Count(&members).Error; err != nil {
+			return false, fmt.Errorf("failed to fetch impersonated principal: %w", err)
+		}
+		if members == 0 {
+			logger.LogWarn(fmt.Sprintf("User %s is not a principal of tenant %s", targetID, tenantID))
+			return false, nil
+		}
+
+		global, err := bindings.HoldsGlobalBinding(db, targetID)
+		if err != nil || global {
+			return false, err
+		}
+		actions, err := bindings.GrantedActions(db, targetID, tenantID)
+		if err != nil {
+			return false, err
+		}
+		for _, action := range actions {
+			held, err := bindings.HasPermission(db, actorID, action, nil, &tenantID)
+			if err != nil || !held {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
 // Define the secret key to validate JWTs (replace with your actual secret)
 var jwtSecretKey []byte
 
@@ -16,8 +77,10 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
-// AuthMiddleware validates JWT in the Authorization header
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware validates JWT in the Authorization header. Callers allowed by
+// canImpersonate may act as another user through ImpersonateUserHeader; a nil
+// canImpersonate rejects every impersonation attempt.
+func AuthMiddleware(canImpersonate ImpersonationAuthorizer) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// // Retrieve the secret key from the environment variable
 		// jwtSecretKey = []byte(os.Getenv("JWT_SECRET_KEY"))
@@ -85,6 +148,58 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		fmt.Println("userID", userID)
+
+		if target := c.GetHeader(ImpersonateUserHeader); target != "" {
+			if status, err := impersonate(c, canImpersonate, userID, target); err != nil {
+				c.JSON(status, gin.H{"error": err.Error()})
+				c.Abort()
+				return
+			}
+		}
 		// Proceed to the next handler
 	}
 }
+
+// impersonate checks the caller may act as target and stores target as
+// impersonatedUserID. userID keeps the real actor, which created_by columns
+// and the audit log record.
+func impersonate(c *gin.Context, canImpersonate ImpersonationAuthorizer, userID, target string) (int, error) {
+	actorID, err := uuid.Parse(userID)
+	if err != nil {
+		return http.StatusUnauthorized, fmt.Errorf("impersonation requires an authenticated user")
+	}
+	targetID, err := uuid.Parse(target)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid %s header", ImpersonateUserHeader)
+	}
+	if targetID == actorID {
+		return http.StatusBadRequest, fmt.Errorf("users cannot impersonate themselves")
+	}
+
+	// Only principals of a tenant can be impersonated, within that tenant
+	header := c.GetHeader("X-Tenant-ID")
+	if header == "" {
+		return http.StatusBadRequest, fmt.Errorf("impersonation requires the X-Tenant-ID header")
+	}
+	tenantID, err := uuid.Parse(header)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid X-Tenant-ID header")
+	}
+
+	allowed := false
+	if canImpersonate != nil {
+		allowed, err = canImpersonate(c.Request.Context(), actorID, targetID, tenantID)
+		if err != nil {
+			logger.LogError(fmt.Sprintf("Error checking impersonation permission: %v", err))
+			return http.StatusInternalServerError, fmt.Errorf("unable to verify impersonation permission")
+		}
+	}
+	if !allowed {
+		logger.LogWarn(fmt.Sprintf("User %s was denied impersonating %s", actorID, targetID))
+		return http.StatusForbidden, fmt.Errorf("not allowed to impersonate this user")
+	}
+
+	logger.LogInfo(fmt.Sprintf("User %s is impersonating %s", actorID, targetID))
+	c.Set("impersonatedUserID", targetID.String())
+	return http.StatusOK, nil
+}
@@ -0,0 +1,165 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("Failed to register tenancy plugin: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.TenantResource{}, &dto.TenantRoleAssignments{}, &dto.TNTRolePermission{}, &dto.MstPermission{}, &dto.Mst_ResourceTypes{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// seedBinding binds a new role granting action to principalID within
+// tenantID, on scopeID when given.
+func seedBinding(t *testing.T, db *gorm.DB, principalID uuid.UUID, action string, tenantID, scopeID *uuid.UUID) {
+	roleID, permissionID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.MstPermission{PermissionID: permissionID, Name: action, Action: action, RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TNTRolePermission{ID: uuid.New(), RoleID: roleID, PermissionID: permissionID, RowStatus: 1}).Error)
+	require.NoError(t, tenancy.RootSession(db).Create(&dto.TenantRoleAssignments{
+		ResourceID: uuid.New(), Name: action, Version: "V1", PrincipalID: principalID, RoleID: roleID, TenantID: tenantID, ScopeID: scopeID, RowStatus: 1,
+	}).Error)
+}
+
+// seedImpersonator binds a role granting the impersonate action to a new
+// principal within tenantID.
+func seedImpersonator(t *testing.T, db *gorm.DB, tenantID uuid.UUID) uuid.UUID {
+	principalID := uuid.New()
+	seedBinding(t, db, principalID, ImpersonatePermissionAction, &tenantID, nil)
+	return principalID
+}
+
+// seedPrincipal stores a new principal of tenantID.
+func seedPrincipal(t *testing.T, db *gorm.DB, tenantID uuid.UUID) uuid.UUID {
+	principalID := uuid.New()
+	require.NoError(t, db.WithContext(tenancy.WithTenant(context.Background(), tenantID)).Create(&dto.TenantResource{
+		ResourceID: principalID, Name: "user", TenantID: &tenantID, RowStatus: 1,
+	}).Error)
+	return principalID
+}
+
+// serve runs AuthMiddleware and reports the identities it stored.
+func serve(canImpersonate ImpersonationAuthorizer, headers map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(AuthMiddleware(canImpersonate))
+	r.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"userID": c.GetString("userID"), "impersonatedUserID": c.GetString("impersonatedUserID")})
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthMiddlewareImpersonation(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	tenantID := uuid.New()
+	support := seedImpersonator(t, db, tenantID)
+	targetID := seedPrincipal(t, db, tenantID)
+	canImpersonate := PermissionImpersonationAuthorizer(db)
+
+	w := serve(canImpersonate, map[string]string{"userID": support.String(), "X-Tenant-ID": tenantID.String(), ImpersonateUserHeader: targetID.String()})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"userID":%q,"impersonatedUserID":%q}`, support, targetID), w.Body.String())
+
+	// The permission is scoped to its tenant
+	w = serve(canImpersonate, map[string]string{"userID": support.String(), "X-Tenant-ID": uuid.NewString(), ImpersonateUserHeader: targetID.String()})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serve(canImpersonate, map[string]string{"userID": uuid.NewString(), "X-Tenant-ID": tenantID.String(), ImpersonateUserHeader: targetID.String()})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serve(nil, map[string]string{"userID": support.String(), "X-Tenant-ID": tenantID.String(), ImpersonateUserHeader: targetID.String()})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = serve(canImpersonate, map[string]string{"userID": support.String(), ImpersonateUserHeader: "not-a-uuid"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(canImpersonate, map[string]string{"userID": support.String(), ImpersonateUserHeader: targetID.String()})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = serve(canImpersonate, map[string]string{ImpersonateUserHeader: targetID.String()})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Without the header the caller acts as itself
+	w = serve(canImpersonate, map[string]string{"userID": support.String()})
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"userID":%q,"impersonatedUserID":""}`, support), w.Body.String())
+}
+
+func TestAuthMiddlewareRefusesImpersonatingOutsideTheTenant(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	tenantID := uuid.New()
+	support := seedImpersonator(t, db, tenantID)
+	canImpersonate := PermissionImpersonationAuthorizer(db)
+
+	// Unknown principals and principals of another tenant are not members
+	for _, targetID := range []uuid.UUID{uuid.New(), seedPrincipal(t, db, uuid.New())} {
+		w := serve(canImpersonate, map[string]string{"userID": support.String(), "X-Tenant-ID": tenantID.String(), ImpersonateUserHeader: targetID.String()})
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	}
+}
+
+func TestAuthMiddlewareRefusesImpersonatingMorePrivilegedUsers(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	tenantID := uuid.New()
+	support := seedImpersonator(t, db, tenantID)
+	canImpersonate := PermissionImpersonationAuthorizer(db)
+	impersonate := func(targetID uuid.UUID) int {
+		return serve(canImpersonate, map[string]string{"userID": support.String(), "X-Tenant-ID": tenantID.String(), ImpersonateUserHeader: targetID.String()}).Code
+	}
+
+	peer := seedPrincipal(t, db, tenantID)
+	seedBinding(t, db, peer, ImpersonatePermissionAction, &tenantID, nil)
+	assert.Equal(t, http.StatusOK, impersonate(peer))
+
+	globalAdmin := seedPrincipal(t, db, tenantID)
+	seedBinding(t, db, globalAdmin, "tenant.read", nil, nil)
+	assert.Equal(t, http.StatusForbidden, impersonate(globalAdmin))
+
+	rootTypeID, rootID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: rootTypeID, Name: constants.ResourceTypeRoot, RowStatus: 1}).Error)
+	require.NoError(t, db.Scopes(tenancy.WithoutTenantScope).Create(&dto.TenantResource{ResourceID: rootID, ResourceTypeID: rootTypeID, Name: "Root", RowStatus: 1}).Error)
+	rootAdmin := seedPrincipal(t, db, tenantID)
+	seedBinding(t, db, rootAdmin, "tenant.read", &tenantID, &rootID)
+	assert.Equal(t, http.StatusForbidden, impersonate(rootAdmin))
+
+	tenantAdmin := seedPrincipal(t, db, tenantID)
+	seedBinding(t, db, tenantAdmin, "role.delete", &tenantID, nil)
+	assert.Equal(t, http.StatusForbidden, impersonate(tenantAdmin))
+
+	// Once the actor holds the permission too, the target is no more
+	// privileged than the actor
+	seedBinding(t, db, support, "role.*", &tenantID, nil)
+	assert.Equal(t, http.StatusOK, impersonate(tenantAdmin))
+}
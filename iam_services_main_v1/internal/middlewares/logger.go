@@ -9,12 +9,20 @@ import (
 	"github.com/google/uuid"
 )
 
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
 // RequestLogger logs incoming HTTP requests.
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Generate a unique request ID
-		requestID := uuid.New().String()
+		// Reuse the caller's request ID so events can be correlated across
+		// services, otherwise generate a unique one
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			requestID = uuid.New().String()
+		}
 		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
 
 		// Record the start time
 		start := time.Now()
@@ -0,0 +1,83 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// locker serializes migrations across replicas with a lock held by the
+// connection migrating.
+type locker interface {
+	acquire(conn *gorm.DB, name string, timeout time.Duration) error
+	release(conn *gorm.DB, name string) error
+}
+
+func lockerFor(dialect string) locker {
+	switch dialect {
+	case "mysql":
+		return mysqlLocker{}
+	case "postgres":
+		return postgresLocker{}
+	default:
+		// SQLite databases are local to a single process
+		return noLocker{}
+	}
+}
+
+// mysqlLocker uses a named lock, which MySQL releases when the connection
+// holding it closes.
+type mysqlLocker struct{}
+
+func (mysqlLocker) acquire(conn *gorm.DB, name string, timeout time.Duration) error {
+	var acquired *int
+	if err := conn.Raw("SELECT GET_LOCK(?, ?)", name, int(timeout.Seconds())).Scan(&acquired).Error; err != nil {
+		return fmt.Errorf("failed to acquire the schema migration lock: %w", err)
+	}
+	if acquired == nil || *acquired != 1 {
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+func (mysqlLocker) release(conn *gorm.DB, name string) error {
+	return conn.Exec("SELECT RELEASE_LOCK(?)", name).Error
+}
+
+// postgresLocker uses a session-level advisory lock keyed by a hash of the
+// lock name. Advisory locks cannot wait with a timeout, so it polls.
+type postgresLocker struct{}
+
+const postgresLockPoll = time.Second
+
+func (postgresLocker) acquire(conn *gorm.DB, name string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(hashtext(?))", name).Scan(&acquired).Error; err != nil {
+			return fmt.Errorf("failed to acquire the schema migration lock: %w", err)
+		}
+		if acquired {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrLockTimeout
+		}
+		select {
+		case <-conn.Statement.Context.Done():
+			return conn.Statement.Context.Err()
+		case <-time.After(postgresLockPoll):
+		}
+	}
+}
+
+func (postgresLocker) release(conn *gorm.DB, name string) error {
+	return conn.Exec("SELECT pg_advisory_unlock(hashtext(?))", name).Error
+}
+
+type noLocker struct{}
+
+func (noLocker) acquire(*gorm.DB, string, time.Duration) error { return nil }
+
+func (noLocker) release(*gorm.DB, string) error { return nil }
@@ -0,0 +1,328 @@
+// Package migrations applies the versioned SQL migrations embedded in the
+// binary. Each migration is a pair of files, NNNN_name.up.sql and
+// NNNN_name.down.sql, in the directory of the database dialect.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// LockName names the lock held while migrating, so replicas starting
+// together apply each migration once.
+const LockName = "iam_schema_migrations"
+
+// LockTimeout is how long to wait for another replica's migration to finish.
+const LockTimeout = 5 * time.Minute
+
+var (
+	ErrUnknownDialect   = errors.New("no migrations for database dialect")
+	ErrLockTimeout      = errors.New("timed out waiting for the schema migration lock")
+	ErrMissingMigration = errors.New("applied migration is missing from this build")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one schema version.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration together with when it was applied, if it was.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// appliedMigration records an applied migration in schema_migrations.
+type appliedMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false;column:version"`
+	Name      string    `gorm:"size:255;not null;column:name"`
+	AppliedAt time.Time `gorm:"not null;column:applied_at"`
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Embedded returns the migrations built in for a dialect.
+func Embedded(dialect string) (fs.FS, error) {
+	if _, err := fs.ReadDir(files, dialect); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDialect, dialect)
+	}
+	return fs.Sub(files, dialect)
+}
+
+// Load reads the migrations in fsys, ordered by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files named %s and %s", version, migration.Name, match[2])
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		if match[3] == "up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %s has no up migration", migration)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and rolls back migrations on a database.
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	lock       locker
+}
+
+// New returns a Migrator for the migrations built in for db's dialect.
+func New(db *gorm.DB) (*Migrator, error) {
+	fsys, err := Embedded(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return NewFromFS(db, fsys)
+}
+
+// NewFromFS returns a Migrator for the migrations in fsys.
+func NewFromFS(db *gorm.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, lock: lockerFor(db.Dialector.Name())}, nil
+}
+
+// Up applies every pending migration in order and returns those applied.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		pending, err := m.pending(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range pending {
+			if err := apply(conn, migration.Up, func(tx *gorm.DB) error {
+				return tx.Create(&appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %s failed: %w", migration, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the latest steps applied migrations, newest first, and
+// returns those rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.locked(ctx, func(conn *gorm.DB) error {
+		var applied []appliedMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&applied).Error; err != nil {
+			return fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		for _, record := range applied {
+			migration, ok := m.find(record.Version)
+			if !ok {
+				return fmt.Errorf("%w: %04d_%s", ErrMissingMigration, record.Version, record.Name)
+			}
+			if err := apply(conn, migration.Down, func(tx *gorm.DB) error {
+				return tx.Delete(&appliedMigration{}, "version = ?", migration.Version).Error
+			}); err != nil {
+				return fmt.Errorf("rollback of %s failed: %w", migration, err)
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every migration with when it was applied. Applied migrations
+// missing from this build are listed with their recorded name only.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := appliedMigrations(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := make(map[int]time.Time, len(applied))
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, record := range applied {
+		appliedAt[record.Version] = record.AppliedAt
+		if _, ok := m.find(record.Version); !ok {
+			at := record.AppliedAt
+			statuses = append(statuses, Status{Migration: Migration{Version: record.Version, Name: record.Name}, AppliedAt: &at})
+		}
+	}
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if at, ok := appliedAt[migration.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	sort.SliceStable(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending returns the migrations not applied yet.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	return m.pending(m.db.WithContext(ctx))
+}
+
+func (m *Migrator) pending(conn *gorm.DB) ([]Migration, error) {
+	records, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]bool, len(records))
+	for _, record := range records {
+		applied[record.Version] = true
+	}
+
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// locked runs fn on a single connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := m.lock.acquire(conn, LockName, LockTimeout); err != nil {
+			return err
+		}
+		defer func() { _ = m.lock.release(conn, LockName) }()
+
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// appliedMigrations returns the applied migrations, oldest first. A database
+// never migrated has none.
+func appliedMigrations(conn *gorm.DB) ([]appliedMigration, error) {
+	var applied []appliedMigration
+	if !conn.Migrator().HasTable(&appliedMigration{}) {
+		return applied, nil
+	}
+	if err := conn.Order("version").Find(&applied).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+func ensureTable(conn *gorm.DB) error {
+	if conn.Migrator().HasTable(&appliedMigration{}) {
+		return nil
+	}
+	if err := conn.Migrator().CreateTable(&appliedMigration{}); err != nil {
+		return fmt.Errorf("failed to create %s: %w", appliedMigration{}.TableName(), err)
+	}
+	return nil
+}
+
+// apply runs the statements of a migration script and records the change in
+// one transaction. Dialects committing DDL implicitly, such as MySQL, only
+// roll back the bookkeeping on failure.
+func apply(conn *gorm.DB, script string, record func(tx *gorm.DB) error) error {
+	return conn.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// splitStatements splits a script into statements ending with a semicolon at
+// the end of a line. Lines starting with -- are comments.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// String returns the file name stem of a migration, as in 0001_initial_schema.
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
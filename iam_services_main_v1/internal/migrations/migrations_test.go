@@ -0,0 +1,212 @@
+package migrations
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db
+}
+
+var testMigrations = fstest.MapFS{
+	"0001_create_widgets.up.sql":   {Data: []byte("-- widgets\nCREATE TABLE widgets (\n    id INTEGER PRIMARY KEY,\n    name TEXT NOT NULL\n);\n")},
+	"0001_create_widgets.down.sql": {Data: []byte("DROP TABLE widgets;\n")},
+	"0002_seed_widgets.up.sql":     {Data: []byte("INSERT INTO widgets (id, name) VALUES (1, 'a');\nINSERT INTO widgets (id, name) VALUES (2, 'b;c');\n")},
+	"0002_seed_widgets.down.sql":   {Data: []byte("DELETE FROM widgets;\n")},
+	"README.md":                    {Data: []byte("not a migration")},
+}
+
+func TestUpDownStatus(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	m, err := NewFromFS(db, testMigrations)
+	require.NoError(t, err)
+
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	assert.Equal(t, "0001_create_widgets", applied[0].String())
+
+	var count int64
+	require.NoError(t, db.Table("widgets").Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// Applying again is a no-op
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	rolledBack, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, 1)
+	assert.Equal(t, 2, rolledBack[0].Version)
+	require.NoError(t, db.Table("widgets").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+
+	rolledBack, err = m.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, 1)
+	assert.False(t, db.Migrator().HasTable("widgets"))
+}
+
+func TestFailedMigrationIsNotRecorded(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	m, err := NewFromFS(db, fstest.MapFS{
+		"0001_ok.up.sql":     {Data: []byte("CREATE TABLE ok (id INTEGER);")},
+		"0002_broken.up.sql": {Data: []byte("CREATE TABLE broken (id INTEGER);\nNOT SQL;")},
+	})
+	require.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "0002_broken")
+	assert.Len(t, applied, 1)
+
+	pending, err := m.Pending(ctx)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 2, pending[0].Version)
+}
+
+func TestDownRefusesMigrationsMissingFromBuild(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	m, err := NewFromFS(db, testMigrations)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	older, err := NewFromFS(db, fstest.MapFS{
+		"0001_create_widgets.up.sql":   testMigrations["0001_create_widgets.up.sql"],
+		"0001_create_widgets.down.sql": testMigrations["0001_create_widgets.down.sql"],
+	})
+	require.NoError(t, err)
+	_, err = older.Down(ctx, 1)
+	assert.ErrorIs(t, err, ErrMissingMigration)
+
+	statuses, err := older.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "0002_seed_widgets", statuses[1].String())
+}
+
+func TestRunCommand(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	m, err := NewFromFS(db, testMigrations)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, RunCommand(ctx, m, []string{"status"}, &out))
+	assert.Contains(t, out.String(), "0002_seed_widgets")
+	assert.Equal(t, 2, strings.Count(out.String(), "pending"))
+
+	out.Reset()
+	require.NoError(t, RunCommand(ctx, m, []string{"up"}, &out))
+	assert.Equal(t, "applied 0001_create_widgets\napplied 0002_seed_widgets\n", out.String())
+
+	out.Reset()
+	require.NoError(t, RunCommand(ctx, m, []string{"down", "2"}, &out))
+	assert.Equal(t, "rolled back 0002_seed_widgets\nrolled back 0001_create_widgets\n", out.String())
+
+	assert.ErrorIs(t, RunCommand(ctx, m, []string{"down", "zero"}, &out), ErrUsage)
+	assert.ErrorIs(t, RunCommand(ctx, m, nil, &out), ErrUsage)
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	var versions []string
+	for _, dialect := range []string{"mysql", "postgres", "sqlite"} {
+		fsys, err := Embedded(dialect)
+		require.NoError(t, err)
+		migrations, err := Load(fsys)
+		require.NoError(t, err)
+		require.NotEmpty(t, migrations)
+
+		var names []string
+		for i, migration := range migrations {
+			assert.Equal(t, i+1, migration.Version, "migrations are numbered without gaps")
+			assert.NotEmpty(t, splitStatements(migration.Down), "%s has a down migration", migration)
+			names = append(names, migration.String())
+		}
+		// Every dialect has the same schema versions
+		if versions == nil {
+			versions = names
+		}
+		assert.Equal(t, versions, names, dialect)
+	}
+
+	_, err := Embedded("oracle")
+	assert.ErrorIs(t, err, ErrUnknownDialect)
+}
+
+func TestEmbeddedSQLiteSchema(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	m, err := New(db)
+	require.NoError(t, err)
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+
+	// The seeded Root organization metadata reads back as JSON
+	var root dto.TenantMetadata
+	require.NoError(t, db.Where("resource_id = ?", "11111111-1111-1111-1111-111111111111").First(&root).Error)
+	var metadata map[string]interface{}
+	require.NoError(t, json.Unmarshal(root.Metadata, &metadata))
+	assert.Equal(t, "Root organization metadata", metadata["description"])
+
+	tenantID := uuid.New()
+	require.NoError(t, db.Create(&dto.TenantMetadata{ResourceID: tenantID, Metadata: dto.JSON(`{"description":"acme"}`), RowStatus: 1}).Error)
+	var stored dto.TenantMetadata
+	require.NoError(t, db.Where("resource_id = ?", tenantID).First(&stored).Error)
+	assert.JSONEq(t, `{"description":"acme"}`, string(stored.Metadata))
+
+	var resourceTypes []dto.Mst_ResourceTypes
+	require.NoError(t, db.Find(&resourceTypes).Error)
+	assert.Len(t, resourceTypes, 7)
+	assert.True(t, db.Migrator().HasColumn(&dto.TenantResource{}, "revision"))
+	assert.True(t, db.Migrator().HasTable(&dto.TenantPrincipals{}))
+	assert.True(t, db.Migrator().HasTable(&dto.GroupMember{}))
+	assert.True(t, db.Migrator().HasTable(&dto.ScimToken{}))
+	assert.True(t, db.Migrator().HasTable(&dto.ImportJob{}))
+	assert.True(t, db.Migrator().HasTable(&dto.Invitation{}))
+	assert.True(t, db.Migrator().HasTable(&dto.TenantState{}))
+	assert.True(t, db.Migrator().HasTable(&dto.TenantStateTransition{}))
+	assert.True(t, db.Migrator().HasColumn(&dto.ApprovalPolicy{}, "tenant_id"))
+	assert.True(t, db.Migrator().HasIndex(&dto.ApprovalPolicy{}, "idx_approval_policies_tenant_role"))
+	assert.True(t, db.Migrator().HasColumn(&dto.BreakGlassPrincipal{}, "tenant_id"))
+	assert.True(t, db.Migrator().HasIndex(&dto.BreakGlassGrant{}, "idx_break_glass_grants_tenant"))
+	assert.True(t, db.Migrator().HasIndex(&dto.TenantRoleAssignments{}, "idx_role_assignments_tenant"))
+
+	rolledBack, err := m.Down(ctx, len(applied))
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, len(applied))
+	assert.False(t, db.Migrator().HasTable("tnt_resources"))
+}
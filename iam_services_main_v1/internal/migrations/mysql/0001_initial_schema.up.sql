@@ -0,0 +1,290 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by AutoMigrate adopt it.
+
+CREATE TABLE IF NOT EXISTS `mst_resource_types` (
+    `resource_type_id` char(36),
+    `service_id` char(36) NOT NULL,
+    `name` varchar(45) NOT NULL,
+    `allowed_parent_types` json,
+    `attribute_schema` json,
+    `row_status` bigint DEFAULT 1,
+    `created_by` varchar(45),
+    `updated_by` varchar(45),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`resource_type_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_resources` (
+    `resource_id` char(36),
+    `parent_resource_id` char(36),
+    `resource_type_id` char(36) NOT NULL,
+    `name` varchar(45) NOT NULL,
+    `tenant_id` char(36),
+    `row_status` bigint DEFAULT 1,
+    `created_by` varchar(45),
+    `updated_by` varchar(45),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`resource_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_resource_labels` (
+    `label_id` char(36),
+    `resource_id` char(36) NOT NULL,
+    `label_key` varchar(63) NOT NULL,
+    `label_value` varchar(63) NOT NULL,
+    `row_status` bigint DEFAULT 1,
+    `created_by` varchar(45),
+    `updated_by` varchar(45),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`label_id`),
+    INDEX `idx_resource_labels_resource` (`resource_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_resources_metadata` (
+    `resource_id` char(36) NOT NULL,
+    `metadata` json,
+    `row_status` bigint DEFAULT 1,
+    `created_by` varchar(45),
+    `updated_by` varchar(45),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_roles` (
+    `resource_id` char(36),
+    `role_type` longtext,
+    `name` varchar(255),
+    `version` varchar(100),
+    `scope_resource_type_id` char(36),
+    `description` text,
+    `row_status` bigint,
+    `created_by` varchar(36),
+    `updated_by` varchar(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`resource_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_role_permissions` (
+    `role_permission_id` varchar(36),
+    `role_id` varchar(36) NOT NULL,
+    `permission_id` varchar(36) NOT NULL,
+    `row_status` tinyint(1) DEFAULT 1,
+    `created_by` varchar(36),
+    `updated_by` varchar(36),
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`role_permission_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_role_revisions` (
+    `revision_id` char(36),
+    `role_id` char(36) NOT NULL,
+    `revision` bigint NOT NULL,
+    `name` varchar(255),
+    `description` text,
+    `permissions` json,
+    `created_by` varchar(36),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`revision_id`),
+    UNIQUE INDEX `idx_role_revision` (`role_id`,`revision`)
+);
+
+CREATE TABLE IF NOT EXISTS `mst_roles` (
+    `role_id` char(36),
+    `name` varchar(255),
+    `version` varchar(100),
+    `scope_resource_type_id` char(36),
+    `description` text,
+    `row_status` bigint,
+    `created_by` varchar(36),
+    `updated_by` varchar(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`role_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `mst_permissions` (
+    `permission_id` char(36),
+    `resource_type_id` varchar(36),
+    `name` varchar(255),
+    `action` varchar(100),
+    `row_status` bigint,
+    `created_by` varchar(36),
+    `updated_by` varchar(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`permission_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `mst_role_permissions` (
+    `role_permission_id` varchar(36),
+    `role_id` varchar(36) NOT NULL,
+    `permission_id` varchar(36) NOT NULL,
+    `row_status` tinyint(1) DEFAULT 1,
+    `created_by` varchar(36),
+    `updated_by` varchar(36),
+    `created_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` timestamp DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`role_permission_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_role_assignments` (
+    `resource_id` char(36),
+    `name` varchar(45) NOT NULL,
+    `version` varchar(45) NOT NULL,
+    `principal_id` char(36),
+    `role_id` char(36),
+    `tenant_id` char(36),
+    `scope_id` char(36),
+    `expires_at` datetime(3) NULL,
+    `row_status` bigint DEFAULT 1,
+    `created_by` varchar(45),
+    `updated_by` varchar(45),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`resource_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_audit_events` (
+    `sequence` bigint unsigned AUTO_INCREMENT,
+    `event_id` char(36) NOT NULL,
+    `occurred_at` datetime(3) NOT NULL,
+    `actor_id` varchar(36),
+    `impersonated_user_id` varchar(36),
+    `tenant_id` varchar(36),
+    `operation` varchar(100) NOT NULL,
+    `target_resource_id` varchar(36),
+    `before_snapshot` text,
+    `after_snapshot` text,
+    `request_id` varchar(64),
+    `client_ip` varchar(45),
+    `outcome` varchar(16) NOT NULL,
+    `error_message` text,
+    `prev_hash` char(64) NOT NULL,
+    `hash` char(64) NOT NULL,
+    PRIMARY KEY (`sequence`),
+    UNIQUE INDEX `idx_audit_event_id` (`event_id`),
+    UNIQUE INDEX `idx_audit_prev_hash` (`prev_hash`),
+    INDEX `idx_audit_actor` (`actor_id`),
+    INDEX `idx_audit_occurred_at` (`occurred_at`),
+    INDEX `idx_audit_target` (`target_resource_id`),
+    INDEX `idx_audit_tenant` (`tenant_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_access_review_campaigns` (
+    `campaign_id` char(36),
+    `tenant_id` char(36),
+    `name` varchar(255) NOT NULL,
+    `scope_type` varchar(16) NOT NULL,
+    `scope_id` char(36) NOT NULL,
+    `status` varchar(16) NOT NULL,
+    `due_at` datetime(3) NULL,
+    `closed_at` datetime(3) NULL,
+    `closed_by` char(36),
+    `created_by` char(36),
+    `updated_by` char(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`campaign_id`),
+    INDEX `idx_access_review_campaigns_scope` (`scope_id`),
+    INDEX `idx_access_review_campaigns_tenant` (`tenant_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_access_review_items` (
+    `item_id` char(36),
+    `campaign_id` char(36) NOT NULL,
+    `binding_id` char(36) NOT NULL,
+    `binding_name` varchar(45),
+    `principal_id` char(36) NOT NULL,
+    `role_id` char(36) NOT NULL,
+    `tenant_id` char(36),
+    `reviewer_id` char(36) NOT NULL,
+    `decision` varchar(16) NOT NULL,
+    `auto_revoked` boolean,
+    `comment` text,
+    `decided_by` char(36),
+    `decided_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`item_id`),
+    INDEX `idx_access_review_items_campaign` (`campaign_id`),
+    INDEX `idx_access_review_items_reviewer` (`reviewer_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_approval_policies` (
+    `policy_id` char(36),
+    `role_id` char(36) NOT NULL,
+    `approver_role_id` char(36),
+    `approver_ids` json,
+    `required_approvals` bigint NOT NULL DEFAULT 1,
+    `max_duration_seconds` bigint,
+    `row_status` bigint DEFAULT 1,
+    `created_by` char(36),
+    `updated_by` char(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`policy_id`),
+    INDEX `idx_approval_policies_role` (`role_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_access_requests` (
+    `request_id` char(36),
+    `tenant_id` char(36),
+    `requester_id` char(36) NOT NULL,
+    `role_id` char(36) NOT NULL,
+    `scope_id` char(36) NOT NULL,
+    `justification` text NOT NULL,
+    `duration_seconds` bigint,
+    `status` varchar(16) NOT NULL,
+    `binding_id` char(36),
+    `decided_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`request_id`),
+    INDEX `idx_access_requests_requester` (`requester_id`),
+    INDEX `idx_access_requests_tenant` (`tenant_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_access_request_decisions` (
+    `decision_id` char(36),
+    `request_id` char(36) NOT NULL,
+    `approver_id` char(36) NOT NULL,
+    `decision` varchar(16) NOT NULL,
+    `comment` text,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`decision_id`),
+    UNIQUE INDEX `idx_access_request_decisions_approver` (`request_id`,`approver_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_break_glass_principals` (
+    `registration_id` char(36),
+    `principal_id` char(36) NOT NULL,
+    `role_id` char(36) NOT NULL,
+    `scope_id` char(36) NOT NULL,
+    `row_status` bigint DEFAULT 1,
+    `created_by` char(36),
+    `updated_by` char(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`registration_id`),
+    INDEX `idx_break_glass_principals_principal` (`principal_id`)
+);
+
+CREATE TABLE IF NOT EXISTS `tnt_break_glass_grants` (
+    `grant_id` char(36),
+    `registration_id` char(36) NOT NULL,
+    `principal_id` char(36) NOT NULL,
+    `role_id` char(36) NOT NULL,
+    `scope_id` char(36) NOT NULL,
+    `binding_id` char(36) NOT NULL,
+    `reason` text NOT NULL,
+    `expires_at` datetime(3) NOT NULL,
+    `revoked_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`grant_id`),
+    INDEX `idx_break_glass_grants_expiry` (`expires_at`),
+    INDEX `idx_break_glass_grants_principal` (`principal_id`)
+);
@@ -0,0 +1,11 @@
+DELETE FROM `tnt_resources_metadata` WHERE `resource_id` = '11111111-1111-1111-1111-111111111111';
+DELETE FROM `tnt_resources` WHERE `resource_id` = '11111111-1111-1111-1111-111111111111';
+DELETE FROM `mst_resource_types` WHERE `resource_type_id` IN (
+    '550e8400-e29b-41d4-a716-446655440000',
+    '550e8400-e29b-41d4-a716-446655440001',
+    '550e8400-e29b-41d4-a716-446655440002',
+    '550e8400-e29b-41d4-a716-446655440003',
+    '550e8400-e29b-41d4-a716-446655440004',
+    '550e8400-e29b-41d4-a716-446655440005',
+    '550e8400-e29b-41d4-a716-446655440006'
+);
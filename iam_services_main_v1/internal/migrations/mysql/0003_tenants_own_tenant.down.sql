@@ -0,0 +1,4 @@
+UPDATE `tnt_resources`
+SET `tenant_id` = NULL
+WHERE `tenant_id` = `resource_id`
+  AND `resource_type_id` IN (SELECT `resource_type_id` FROM `mst_resource_types` WHERE `name` = 'Tenant');
@@ -0,0 +1 @@
+ALTER TABLE `tnt_resources` DROP COLUMN `revision`;
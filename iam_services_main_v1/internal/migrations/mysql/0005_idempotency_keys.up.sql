@@ -0,0 +1,12 @@
+-- Results of mutations sent with an idempotency key, replayed to retries
+CREATE TABLE IF NOT EXISTS `tnt_idempotency_keys` (
+    `idempotency_key` varchar(255),
+    `principal_id` char(36),
+    `operation` varchar(100) NOT NULL,
+    `fingerprint` char(64) NOT NULL,
+    `result` longblob,
+    `expires_at` datetime(3) NOT NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`idempotency_key`, `principal_id`),
+    INDEX `idx_idempotency_keys_expiry` (`expires_at`)
+);
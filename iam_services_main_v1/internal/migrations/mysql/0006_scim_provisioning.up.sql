@@ -0,0 +1,38 @@
+-- Principals of user and group resources, written by SCIM, CSV imports,
+-- invitations and tenant imports
+CREATE TABLE IF NOT EXISTS `tnt_principals` (
+    `resource_id` char(36),
+    `principal_type_id` char(36) NOT NULL,
+    `name` varchar(45) NOT NULL,
+    `email` varchar(45) NOT NULL,
+    `metadata` json,
+    `row_status` bigint DEFAULT 1,
+    `created_by` varchar(45),
+    `updated_by` varchar(45),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`resource_id`)
+);
+
+-- Members of groups, provisioned over SCIM
+CREATE TABLE IF NOT EXISTS `tnt_group_members` (
+    `group_id` char(36),
+    `member_id` char(36),
+    `tenant_id` char(36) NOT NULL,
+    `created_by` char(36),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`group_id`, `member_id`),
+    INDEX `idx_group_members_member` (`member_id`)
+);
+
+-- Bearer tokens of the SCIM clients of each tenant
+CREATE TABLE IF NOT EXISTS `tnt_scim_tokens` (
+    `token_id` char(36),
+    `token_hash` char(64) NOT NULL,
+    `tenant_id` char(36) NOT NULL,
+    `description` varchar(255),
+    `revoked_at` datetime(3) NULL,
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`token_id`),
+    UNIQUE INDEX `idx_scim_tokens_hash` (`token_hash`)
+);
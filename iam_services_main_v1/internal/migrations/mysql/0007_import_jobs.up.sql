@@ -0,0 +1,20 @@
+-- Bulk imports of users, groups and bindings from CSV files
+CREATE TABLE IF NOT EXISTS `tnt_import_jobs` (
+    `job_id` char(36),
+    `tenant_id` char(36) NOT NULL,
+    `file_name` varchar(255),
+    `status` varchar(16) NOT NULL,
+    `chunk_size` bigint NOT NULL,
+    `total_rows` bigint NOT NULL,
+    `valid_rows` bigint NOT NULL,
+    `imported_rows` bigint NOT NULL,
+    `failed_rows` bigint NOT NULL,
+    `report` json,
+    `error` text,
+    `finished_at` datetime(3) NULL,
+    `created_by` char(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`job_id`),
+    INDEX `idx_import_jobs_tenant` (`tenant_id`)
+);
@@ -0,0 +1,18 @@
+-- Invitations of users by email, accepted with a signed token
+CREATE TABLE IF NOT EXISTS `tnt_invitations` (
+    `invitation_id` char(36),
+    `tenant_id` char(36) NOT NULL,
+    `email` varchar(45) NOT NULL,
+    `principal_id` char(36) NOT NULL,
+    `binding_id` char(36) NOT NULL,
+    `role_id` char(36) NOT NULL,
+    `scope_id` char(36) NOT NULL,
+    `expires_at` datetime(3) NOT NULL,
+    `accepted_at` datetime(3) NULL,
+    `created_by` char(36),
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`invitation_id`),
+    INDEX `idx_invitations_tenant` (`tenant_id`),
+    INDEX `idx_invitations_email` (`email`)
+);
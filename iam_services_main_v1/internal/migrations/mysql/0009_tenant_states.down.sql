@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `tnt_tenant_state_transitions`;
+DROP TABLE IF EXISTS `tnt_tenant_states`;
@@ -0,0 +1,21 @@
+-- Lifecycle status of tenants and the history of its transitions
+CREATE TABLE IF NOT EXISTS `tnt_tenant_states` (
+    `tenant_id` char(36),
+    `status` varchar(16) NOT NULL,
+    `revision` bigint NOT NULL DEFAULT 1,
+    `updated_by` char(36),
+    `updated_at` datetime(3) NULL,
+    PRIMARY KEY (`tenant_id`)
+);
+CREATE TABLE IF NOT EXISTS `tnt_tenant_state_transitions` (
+    `transition_id` char(36),
+    `tenant_id` char(36) NOT NULL,
+    `from_status` varchar(16),
+    `to_status` varchar(16) NOT NULL,
+    `reason` varchar(255),
+    `revision` bigint NOT NULL,
+    `created_by` char(36),
+    `created_at` datetime(3) NULL,
+    PRIMARY KEY (`transition_id`),
+    INDEX `idx_tenant_state_transitions_tenant` (`tenant_id`)
+);
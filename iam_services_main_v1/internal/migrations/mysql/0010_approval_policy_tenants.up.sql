@@ -0,0 +1,8 @@
+-- Approval policies belong to the tenant of their role; a tenant has at most
+-- one policy per role, deleted policies being revived when set again
+DELETE FROM `tnt_approval_policies` WHERE `row_status` <> 1;
+ALTER TABLE `tnt_approval_policies` ADD COLUMN `tenant_id` char(36);
+UPDATE `tnt_approval_policies` AS `p` JOIN `tnt_resources` AS `r` ON `r`.`resource_id` = `p`.`role_id`
+SET `p`.`tenant_id` = `r`.`tenant_id`;
+ALTER TABLE `tnt_approval_policies` DROP INDEX `idx_approval_policies_role`;
+CREATE UNIQUE INDEX `idx_approval_policies_tenant_role` ON `tnt_approval_policies` (`tenant_id`, `role_id`);
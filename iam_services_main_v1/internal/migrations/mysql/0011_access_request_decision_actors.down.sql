@@ -0,0 +1 @@
+ALTER TABLE `tnt_access_request_decisions` DROP COLUMN `actor_id`;
@@ -0,0 +1,4 @@
+ALTER TABLE `tnt_break_glass_grants` DROP INDEX `idx_break_glass_grants_tenant`;
+ALTER TABLE `tnt_break_glass_grants` DROP COLUMN `tenant_id`;
+ALTER TABLE `tnt_break_glass_principals` DROP INDEX `idx_break_glass_principals_tenant`;
+ALTER TABLE `tnt_break_glass_principals` DROP COLUMN `tenant_id`;
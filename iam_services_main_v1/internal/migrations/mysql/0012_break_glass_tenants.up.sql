@@ -0,0 +1,10 @@
+-- Break-glass registrations and grants belong to the tenant of their scope;
+-- those on a Root scope belong to none
+ALTER TABLE `tnt_break_glass_principals` ADD COLUMN `tenant_id` char(36);
+UPDATE `tnt_break_glass_principals` AS `p` JOIN `tnt_resources` AS `r` ON `r`.`resource_id` = `p`.`scope_id`
+SET `p`.`tenant_id` = `r`.`tenant_id`;
+CREATE INDEX `idx_break_glass_principals_tenant` ON `tnt_break_glass_principals` (`tenant_id`);
+ALTER TABLE `tnt_break_glass_grants` ADD COLUMN `tenant_id` char(36);
+UPDATE `tnt_break_glass_grants` AS `g` JOIN `tnt_resources` AS `r` ON `r`.`resource_id` = `g`.`scope_id`
+SET `g`.`tenant_id` = `r`.`tenant_id`;
+CREATE INDEX `idx_break_glass_grants_tenant` ON `tnt_break_glass_grants` (`tenant_id`);
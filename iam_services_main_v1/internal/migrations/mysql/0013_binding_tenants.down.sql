@@ -0,0 +1,2 @@
+-- The backfilled tenants are kept; they match the bindings' resources
+ALTER TABLE `tnt_role_assignments` DROP INDEX `idx_role_assignments_tenant`;
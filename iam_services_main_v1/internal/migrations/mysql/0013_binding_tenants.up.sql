@@ -0,0 +1,5 @@
+-- Bindings are isolated per tenant by their own tenant_id; legacy bindings
+-- take it from their tnt_resources row
+UPDATE `tnt_role_assignments` AS `ra` JOIN `tnt_resources` AS `r` ON `r`.`resource_id` = `ra`.`resource_id`
+SET `ra`.`tenant_id` = `r`.`tenant_id` WHERE `ra`.`tenant_id` IS NULL;
+CREATE INDEX `idx_role_assignments_tenant` ON `tnt_role_assignments` (`tenant_id`);
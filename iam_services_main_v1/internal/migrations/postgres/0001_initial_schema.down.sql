@@ -0,0 +1,19 @@
+DROP TABLE IF EXISTS "tnt_break_glass_grants";
+DROP TABLE IF EXISTS "tnt_break_glass_principals";
+DROP TABLE IF EXISTS "tnt_access_request_decisions";
+DROP TABLE IF EXISTS "tnt_access_requests";
+DROP TABLE IF EXISTS "tnt_approval_policies";
+DROP TABLE IF EXISTS "tnt_access_review_items";
+DROP TABLE IF EXISTS "tnt_access_review_campaigns";
+DROP TABLE IF EXISTS "tnt_audit_events";
+DROP TABLE IF EXISTS "tnt_role_assignments";
+DROP TABLE IF EXISTS "mst_role_permissions";
+DROP TABLE IF EXISTS "mst_permissions";
+DROP TABLE IF EXISTS "mst_roles";
+DROP TABLE IF EXISTS "tnt_role_revisions";
+DROP TABLE IF EXISTS "tnt_role_permissions";
+DROP TABLE IF EXISTS "tnt_roles";
+DROP TABLE IF EXISTS "tnt_resources_metadata";
+DROP TABLE IF EXISTS "tnt_resource_labels";
+DROP TABLE IF EXISTS "tnt_resources";
+DROP TABLE IF EXISTS "mst_resource_types";
@@ -0,0 +1,290 @@
+-- Baseline schema, mirroring mysql/0001_initial_schema.up.sql.
+
+CREATE TABLE IF NOT EXISTS "mst_resource_types" (
+    "resource_type_id" uuid,
+    "service_id" uuid NOT NULL,
+    "name" varchar(45) NOT NULL,
+    "allowed_parent_types" jsonb,
+    "attribute_schema" jsonb,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("resource_type_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_resources" (
+    "resource_id" uuid,
+    "parent_resource_id" uuid,
+    "resource_type_id" uuid NOT NULL,
+    "name" varchar(45) NOT NULL,
+    "tenant_id" uuid,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_resource_labels" (
+    "label_id" uuid,
+    "resource_id" uuid NOT NULL,
+    "label_key" varchar(63) NOT NULL,
+    "label_value" varchar(63) NOT NULL,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("label_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_resource_labels_resource" ON "tnt_resource_labels" ("resource_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_resources_metadata" (
+    "resource_id" uuid NOT NULL,
+    "metadata" jsonb,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_roles" (
+    "resource_id" uuid,
+    "role_type" text,
+    "name" varchar(255),
+    "version" varchar(100),
+    "scope_resource_type_id" uuid,
+    "description" text,
+    "row_status" bigint,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_role_permissions" (
+    "role_permission_id" uuid,
+    "role_id" uuid NOT NULL,
+    "permission_id" uuid NOT NULL,
+    "row_status" smallint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("role_permission_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_role_revisions" (
+    "revision_id" uuid,
+    "role_id" uuid NOT NULL,
+    "revision" bigint NOT NULL,
+    "name" varchar(255),
+    "description" text,
+    "permissions" jsonb,
+    "created_by" uuid,
+    "created_at" timestamptz NULL,
+    PRIMARY KEY ("revision_id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_role_revision" ON "tnt_role_revisions" ("role_id","revision");
+
+CREATE TABLE IF NOT EXISTS "mst_roles" (
+    "role_id" uuid,
+    "name" varchar(255),
+    "version" varchar(100),
+    "scope_resource_type_id" uuid,
+    "description" text,
+    "row_status" bigint,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("role_id")
+);
+
+CREATE TABLE IF NOT EXISTS "mst_permissions" (
+    "permission_id" uuid,
+    "resource_type_id" varchar(36),
+    "name" varchar(255),
+    "action" varchar(100),
+    "row_status" bigint,
+    "created_by" varchar(36),
+    "updated_by" varchar(36),
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("permission_id")
+);
+
+CREATE TABLE IF NOT EXISTS "mst_role_permissions" (
+    "role_permission_id" uuid,
+    "role_id" uuid NOT NULL,
+    "permission_id" uuid NOT NULL,
+    "row_status" smallint DEFAULT 1,
+    "created_by" varchar(36),
+    "updated_by" varchar(36),
+    "created_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("role_permission_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_role_assignments" (
+    "resource_id" uuid,
+    "name" varchar(45) NOT NULL,
+    "version" varchar(45) NOT NULL,
+    "principal_id" uuid,
+    "role_id" uuid,
+    "tenant_id" uuid,
+    "scope_id" uuid,
+    "expires_at" timestamptz NULL,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_audit_events" (
+    "sequence" bigserial,
+    "event_id" char(36) NOT NULL,
+    "occurred_at" timestamptz NOT NULL,
+    "actor_id" varchar(36),
+    "impersonated_user_id" varchar(36),
+    "tenant_id" varchar(36),
+    "operation" varchar(100) NOT NULL,
+    "target_resource_id" varchar(36),
+    "before_snapshot" text,
+    "after_snapshot" text,
+    "request_id" varchar(64),
+    "client_ip" varchar(45),
+    "outcome" varchar(16) NOT NULL,
+    "error_message" text,
+    "prev_hash" char(64) NOT NULL,
+    "hash" char(64) NOT NULL,
+    PRIMARY KEY ("sequence")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_event_id" ON "tnt_audit_events" ("event_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_prev_hash" ON "tnt_audit_events" ("prev_hash");
+CREATE INDEX IF NOT EXISTS "idx_audit_actor" ON "tnt_audit_events" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_occurred_at" ON "tnt_audit_events" ("occurred_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_target" ON "tnt_audit_events" ("target_resource_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_tenant" ON "tnt_audit_events" ("tenant_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_review_campaigns" (
+    "campaign_id" uuid,
+    "tenant_id" uuid,
+    "name" varchar(255) NOT NULL,
+    "scope_type" varchar(16) NOT NULL,
+    "scope_id" uuid NOT NULL,
+    "status" varchar(16) NOT NULL,
+    "due_at" timestamptz NULL,
+    "closed_at" timestamptz NULL,
+    "closed_by" uuid,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("campaign_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_access_review_campaigns_scope" ON "tnt_access_review_campaigns" ("scope_id");
+CREATE INDEX IF NOT EXISTS "idx_access_review_campaigns_tenant" ON "tnt_access_review_campaigns" ("tenant_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_review_items" (
+    "item_id" uuid,
+    "campaign_id" uuid NOT NULL,
+    "binding_id" uuid NOT NULL,
+    "binding_name" varchar(45),
+    "principal_id" uuid NOT NULL,
+    "role_id" uuid NOT NULL,
+    "tenant_id" uuid,
+    "reviewer_id" uuid NOT NULL,
+    "decision" varchar(16) NOT NULL,
+    "auto_revoked" boolean,
+    "comment" text,
+    "decided_by" uuid,
+    "decided_at" timestamptz NULL,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("item_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_access_review_items_campaign" ON "tnt_access_review_items" ("campaign_id");
+CREATE INDEX IF NOT EXISTS "idx_access_review_items_reviewer" ON "tnt_access_review_items" ("reviewer_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_approval_policies" (
+    "policy_id" uuid,
+    "role_id" uuid NOT NULL,
+    "approver_role_id" uuid,
+    "approver_ids" jsonb,
+    "required_approvals" bigint NOT NULL DEFAULT 1,
+    "max_duration_seconds" bigint,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("policy_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_approval_policies_role" ON "tnt_approval_policies" ("role_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_requests" (
+    "request_id" uuid,
+    "tenant_id" uuid,
+    "requester_id" uuid NOT NULL,
+    "role_id" uuid NOT NULL,
+    "scope_id" uuid NOT NULL,
+    "justification" text NOT NULL,
+    "duration_seconds" bigint,
+    "status" varchar(16) NOT NULL,
+    "binding_id" uuid,
+    "decided_at" timestamptz NULL,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("request_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_access_requests_requester" ON "tnt_access_requests" ("requester_id");
+CREATE INDEX IF NOT EXISTS "idx_access_requests_tenant" ON "tnt_access_requests" ("tenant_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_request_decisions" (
+    "decision_id" uuid,
+    "request_id" uuid NOT NULL,
+    "approver_id" uuid NOT NULL,
+    "decision" varchar(16) NOT NULL,
+    "comment" text,
+    "created_at" timestamptz NULL,
+    PRIMARY KEY ("decision_id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_access_request_decisions_approver" ON "tnt_access_request_decisions" ("request_id","approver_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_break_glass_principals" (
+    "registration_id" uuid,
+    "principal_id" uuid NOT NULL,
+    "role_id" uuid NOT NULL,
+    "scope_id" uuid NOT NULL,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("registration_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_break_glass_principals_principal" ON "tnt_break_glass_principals" ("principal_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_break_glass_grants" (
+    "grant_id" uuid,
+    "registration_id" uuid NOT NULL,
+    "principal_id" uuid NOT NULL,
+    "role_id" uuid NOT NULL,
+    "scope_id" uuid NOT NULL,
+    "binding_id" uuid NOT NULL,
+    "reason" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz NULL,
+    "created_at" timestamptz NULL,
+    PRIMARY KEY ("grant_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_break_glass_grants_expiry" ON "tnt_break_glass_grants" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_break_glass_grants_principal" ON "tnt_break_glass_grants" ("principal_id");
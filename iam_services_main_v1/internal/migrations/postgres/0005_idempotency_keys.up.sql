@@ -0,0 +1,12 @@
+-- Results of mutations sent with an idempotency key, replayed to retries
+CREATE TABLE IF NOT EXISTS "tnt_idempotency_keys" (
+    "idempotency_key" varchar(255),
+    "principal_id" uuid,
+    "operation" varchar(100) NOT NULL,
+    "fingerprint" char(64) NOT NULL,
+    "result" bytea,
+    "expires_at" timestamptz NOT NULL,
+    "created_at" timestamptz NULL,
+    PRIMARY KEY ("idempotency_key", "principal_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_idempotency_keys_expiry" ON "tnt_idempotency_keys" ("expires_at");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "tnt_scim_tokens";
+DROP TABLE IF EXISTS "tnt_group_members";
+DROP TABLE IF EXISTS "tnt_principals";
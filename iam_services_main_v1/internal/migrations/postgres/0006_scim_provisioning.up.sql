@@ -0,0 +1,38 @@
+-- Principals of user and group resources, written by SCIM, CSV imports,
+-- invitations and tenant imports
+CREATE TABLE IF NOT EXISTS "tnt_principals" (
+    "resource_id" uuid,
+    "principal_type_id" uuid NOT NULL,
+    "name" varchar(45) NOT NULL,
+    "email" varchar(45) NOT NULL,
+    "metadata" jsonb,
+    "row_status" bigint DEFAULT 1,
+    "created_by" uuid,
+    "updated_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+-- Members of groups, provisioned over SCIM
+CREATE TABLE IF NOT EXISTS "tnt_group_members" (
+    "group_id" uuid,
+    "member_id" uuid,
+    "tenant_id" uuid NOT NULL,
+    "created_by" uuid,
+    "created_at" timestamptz NULL,
+    PRIMARY KEY ("group_id", "member_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_group_members_member" ON "tnt_group_members" ("member_id");
+
+-- Bearer tokens of the SCIM clients of each tenant
+CREATE TABLE IF NOT EXISTS "tnt_scim_tokens" (
+    "token_id" uuid,
+    "token_hash" char(64) NOT NULL,
+    "tenant_id" uuid NOT NULL,
+    "description" varchar(255),
+    "revoked_at" timestamptz NULL,
+    "created_at" timestamptz NULL,
+    PRIMARY KEY ("token_id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_scim_tokens_hash" ON "tnt_scim_tokens" ("token_hash");
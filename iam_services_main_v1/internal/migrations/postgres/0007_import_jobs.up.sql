@@ -0,0 +1,20 @@
+-- Bulk imports of users, groups and bindings from CSV files
+CREATE TABLE IF NOT EXISTS "tnt_import_jobs" (
+    "job_id" uuid,
+    "tenant_id" uuid NOT NULL,
+    "file_name" varchar(255),
+    "status" varchar(16) NOT NULL,
+    "chunk_size" bigint NOT NULL,
+    "total_rows" bigint NOT NULL,
+    "valid_rows" bigint NOT NULL,
+    "imported_rows" bigint NOT NULL,
+    "failed_rows" bigint NOT NULL,
+    "report" jsonb,
+    "error" text,
+    "finished_at" timestamptz NULL,
+    "created_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("job_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_jobs_tenant" ON "tnt_import_jobs" ("tenant_id");
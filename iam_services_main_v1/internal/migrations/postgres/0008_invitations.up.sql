@@ -0,0 +1,18 @@
+-- Invitations of users by email, accepted with a signed token
+CREATE TABLE IF NOT EXISTS "tnt_invitations" (
+    "invitation_id" uuid,
+    "tenant_id" uuid NOT NULL,
+    "email" varchar(45) NOT NULL,
+    "principal_id" uuid NOT NULL,
+    "binding_id" uuid NOT NULL,
+    "role_id" uuid NOT NULL,
+    "scope_id" uuid NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "accepted_at" timestamptz NULL,
+    "created_by" uuid,
+    "created_at" timestamptz NULL,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("invitation_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_invitations_tenant" ON "tnt_invitations" ("tenant_id");
+CREATE INDEX IF NOT EXISTS "idx_invitations_email" ON "tnt_invitations" ("email");
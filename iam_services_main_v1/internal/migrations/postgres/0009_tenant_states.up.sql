@@ -0,0 +1,21 @@
+-- Lifecycle status of tenants and the history of its transitions
+CREATE TABLE IF NOT EXISTS "tnt_tenant_states" (
+    "tenant_id" uuid,
+    "status" varchar(16) NOT NULL,
+    "revision" bigint NOT NULL DEFAULT 1,
+    "updated_by" uuid,
+    "updated_at" timestamptz NULL,
+    PRIMARY KEY ("tenant_id")
+);
+CREATE TABLE IF NOT EXISTS "tnt_tenant_state_transitions" (
+    "transition_id" uuid,
+    "tenant_id" uuid NOT NULL,
+    "from_status" varchar(16),
+    "to_status" varchar(16) NOT NULL,
+    "reason" varchar(255),
+    "revision" bigint NOT NULL,
+    "created_by" uuid,
+    "created_at" timestamptz NULL,
+    PRIMARY KEY ("transition_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_tenant_state_transitions_tenant" ON "tnt_tenant_state_transitions" ("tenant_id");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "idx_approval_policies_tenant_role";
+CREATE INDEX IF NOT EXISTS "idx_approval_policies_role" ON "tnt_approval_policies" ("role_id");
+ALTER TABLE "tnt_approval_policies" DROP COLUMN "tenant_id";
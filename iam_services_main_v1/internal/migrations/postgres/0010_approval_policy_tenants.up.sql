@@ -0,0 +1,8 @@
+-- Approval policies belong to the tenant of their role; a tenant has at most
+-- one policy per role, deleted policies being revived when set again
+DELETE FROM "tnt_approval_policies" WHERE "row_status" <> 1;
+ALTER TABLE "tnt_approval_policies" ADD COLUMN "tenant_id" uuid;
+UPDATE "tnt_approval_policies" AS "p" SET "tenant_id" = "r"."tenant_id"
+FROM "tnt_resources" AS "r" WHERE "r"."resource_id" = "p"."role_id";
+DROP INDEX IF EXISTS "idx_approval_policies_role";
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_approval_policies_tenant_role" ON "tnt_approval_policies" ("tenant_id", "role_id");
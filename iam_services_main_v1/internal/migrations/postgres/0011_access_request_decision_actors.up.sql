@@ -0,0 +1,4 @@
+-- The user who recorded each decision, who differs from the approver when
+-- impersonating them
+ALTER TABLE "tnt_access_request_decisions" ADD COLUMN "actor_id" uuid;
+UPDATE "tnt_access_request_decisions" SET "actor_id" = "approver_id";
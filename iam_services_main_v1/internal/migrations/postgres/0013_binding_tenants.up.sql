@@ -0,0 +1,5 @@
+-- Bindings are isolated per tenant by their own tenant_id; legacy bindings
+-- take it from their tnt_resources row
+UPDATE "tnt_role_assignments" AS "ra" SET "tenant_id" = "r"."tenant_id"
+FROM "tnt_resources" AS "r" WHERE "r"."resource_id" = "ra"."resource_id" AND "ra"."tenant_id" IS NULL;
+CREATE INDEX IF NOT EXISTS "idx_role_assignments_tenant" ON "tnt_role_assignments" ("tenant_id");
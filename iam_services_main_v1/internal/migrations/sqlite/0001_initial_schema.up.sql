@@ -0,0 +1,289 @@
+-- Baseline schema, mirroring mysql/0001_initial_schema.up.sql.
+
+CREATE TABLE IF NOT EXISTS "mst_resource_types" (
+    "resource_type_id" text,
+    "service_id" text NOT NULL,
+    "name" text NOT NULL,
+    "allowed_parent_types" json,
+    "attribute_schema" json,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("resource_type_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_resources" (
+    "resource_id" text,
+    "parent_resource_id" text,
+    "resource_type_id" text NOT NULL,
+    "name" text NOT NULL,
+    "tenant_id" text,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_resource_labels" (
+    "label_id" text,
+    "resource_id" text NOT NULL,
+    "label_key" text NOT NULL,
+    "label_value" text NOT NULL,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("label_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_resource_labels_resource" ON "tnt_resource_labels" ("resource_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_resources_metadata" (
+    "resource_id" text NOT NULL,
+    "metadata" json,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_roles" (
+    "resource_id" text,
+    "role_type" text,
+    "name" text,
+    "version" text,
+    "scope_resource_type_id" text,
+    "description" text,
+    "row_status" integer,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_role_permissions" (
+    "role_permission_id" text,
+    "role_id" text NOT NULL,
+    "permission_id" text NOT NULL,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("role_permission_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_role_revisions" (
+    "revision_id" text,
+    "role_id" text NOT NULL,
+    "revision" integer NOT NULL,
+    "name" text,
+    "description" text,
+    "permissions" json,
+    "created_by" text,
+    "created_at" datetime NULL,
+    PRIMARY KEY ("revision_id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_role_revision" ON "tnt_role_revisions" ("role_id","revision");
+
+CREATE TABLE IF NOT EXISTS "mst_roles" (
+    "role_id" text,
+    "name" text,
+    "version" text,
+    "scope_resource_type_id" text,
+    "description" text,
+    "row_status" integer,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("role_id")
+);
+
+CREATE TABLE IF NOT EXISTS "mst_permissions" (
+    "permission_id" text,
+    "resource_type_id" text,
+    "name" text,
+    "action" text,
+    "row_status" integer,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("permission_id")
+);
+
+CREATE TABLE IF NOT EXISTS "mst_role_permissions" (
+    "role_permission_id" text,
+    "role_id" text NOT NULL,
+    "permission_id" text NOT NULL,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" datetime DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("role_permission_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_role_assignments" (
+    "resource_id" text,
+    "name" text NOT NULL,
+    "version" text NOT NULL,
+    "principal_id" text,
+    "role_id" text,
+    "tenant_id" text,
+    "scope_id" text,
+    "expires_at" datetime NULL,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+CREATE TABLE IF NOT EXISTS "tnt_audit_events" (
+    "sequence" integer PRIMARY KEY AUTOINCREMENT,
+    "event_id" text NOT NULL,
+    "occurred_at" datetime NOT NULL,
+    "actor_id" text,
+    "impersonated_user_id" text,
+    "tenant_id" text,
+    "operation" text NOT NULL,
+    "target_resource_id" text,
+    "before_snapshot" text,
+    "after_snapshot" text,
+    "request_id" text,
+    "client_ip" text,
+    "outcome" text NOT NULL,
+    "error_message" text,
+    "prev_hash" text NOT NULL,
+    "hash" text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_event_id" ON "tnt_audit_events" ("event_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_audit_prev_hash" ON "tnt_audit_events" ("prev_hash");
+CREATE INDEX IF NOT EXISTS "idx_audit_actor" ON "tnt_audit_events" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_occurred_at" ON "tnt_audit_events" ("occurred_at");
+CREATE INDEX IF NOT EXISTS "idx_audit_target" ON "tnt_audit_events" ("target_resource_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_tenant" ON "tnt_audit_events" ("tenant_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_review_campaigns" (
+    "campaign_id" text,
+    "tenant_id" text,
+    "name" text NOT NULL,
+    "scope_type" text NOT NULL,
+    "scope_id" text NOT NULL,
+    "status" text NOT NULL,
+    "due_at" datetime NULL,
+    "closed_at" datetime NULL,
+    "closed_by" text,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("campaign_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_access_review_campaigns_scope" ON "tnt_access_review_campaigns" ("scope_id");
+CREATE INDEX IF NOT EXISTS "idx_access_review_campaigns_tenant" ON "tnt_access_review_campaigns" ("tenant_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_review_items" (
+    "item_id" text,
+    "campaign_id" text NOT NULL,
+    "binding_id" text NOT NULL,
+    "binding_name" text,
+    "principal_id" text NOT NULL,
+    "role_id" text NOT NULL,
+    "tenant_id" text,
+    "reviewer_id" text NOT NULL,
+    "decision" text NOT NULL,
+    "auto_revoked" boolean,
+    "comment" text,
+    "decided_by" text,
+    "decided_at" datetime NULL,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("item_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_access_review_items_campaign" ON "tnt_access_review_items" ("campaign_id");
+CREATE INDEX IF NOT EXISTS "idx_access_review_items_reviewer" ON "tnt_access_review_items" ("reviewer_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_approval_policies" (
+    "policy_id" text,
+    "role_id" text NOT NULL,
+    "approver_role_id" text,
+    "approver_ids" json,
+    "required_approvals" integer NOT NULL DEFAULT 1,
+    "max_duration_seconds" integer,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("policy_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_approval_policies_role" ON "tnt_approval_policies" ("role_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_requests" (
+    "request_id" text,
+    "tenant_id" text,
+    "requester_id" text NOT NULL,
+    "role_id" text NOT NULL,
+    "scope_id" text NOT NULL,
+    "justification" text NOT NULL,
+    "duration_seconds" integer,
+    "status" text NOT NULL,
+    "binding_id" text,
+    "decided_at" datetime NULL,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("request_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_access_requests_requester" ON "tnt_access_requests" ("requester_id");
+CREATE INDEX IF NOT EXISTS "idx_access_requests_tenant" ON "tnt_access_requests" ("tenant_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_access_request_decisions" (
+    "decision_id" text,
+    "request_id" text NOT NULL,
+    "approver_id" text NOT NULL,
+    "decision" text NOT NULL,
+    "comment" text,
+    "created_at" datetime NULL,
+    PRIMARY KEY ("decision_id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_access_request_decisions_approver" ON "tnt_access_request_decisions" ("request_id","approver_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_break_glass_principals" (
+    "registration_id" text,
+    "principal_id" text NOT NULL,
+    "role_id" text NOT NULL,
+    "scope_id" text NOT NULL,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("registration_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_break_glass_principals_principal" ON "tnt_break_glass_principals" ("principal_id");
+
+CREATE TABLE IF NOT EXISTS "tnt_break_glass_grants" (
+    "grant_id" text,
+    "registration_id" text NOT NULL,
+    "principal_id" text NOT NULL,
+    "role_id" text NOT NULL,
+    "scope_id" text NOT NULL,
+    "binding_id" text NOT NULL,
+    "reason" text NOT NULL,
+    "expires_at" datetime NOT NULL,
+    "revoked_at" datetime NULL,
+    "created_at" datetime NULL,
+    PRIMARY KEY ("grant_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_break_glass_grants_expiry" ON "tnt_break_glass_grants" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_break_glass_grants_principal" ON "tnt_break_glass_grants" ("principal_id");
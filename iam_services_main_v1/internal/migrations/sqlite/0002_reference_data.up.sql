@@ -0,0 +1,17 @@
+-- Built-in resource types and the Root organization. The MySQL seed gives
+-- Client Organization Unit a service id that is not a valid UUID.
+INSERT OR IGNORE INTO "mst_resource_types" ("resource_type_id", "service_id", "name", "row_status", "created_by", "updated_by", "created_at", "updated_at") VALUES
+    ('550e8400-e29b-41d4-a716-446655440000', 'a1b2c3d4-e5f6-4747-8899-aabbccddeeff', 'User', 1, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', '2024-01-01 10:00:00', '2024-01-01 10:00:00'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'b1b2c3d4-e5f6-4747-8899-aabbccddeeff', 'Group', 1, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', '2024-01-01 10:30:00', '2024-01-01 10:30:00'),
+    ('550e8400-e29b-41d4-a716-446655440002', 'c1b2c3d4-e5f6-4747-8899-aabbccddeeff', 'Tenant', 1, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', '2024-01-01 11:00:00', '2024-01-01 11:00:00'),
+    ('550e8400-e29b-41d4-a716-446655440003', 'd1b2c3d4-e5f6-4747-8899-aabbccddeeff', 'Role', 1, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', '2024-01-01 11:30:00', '2024-01-01 11:30:00'),
+    ('550e8400-e29b-41d4-a716-446655440004', 'e1b2c3d4-e5f6-4747-8899-aabbccddeeff', 'Root', 1, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', '2024-01-01 12:00:00', '2024-01-01 12:00:00'),
+    ('550e8400-e29b-41d4-a716-446655440005', 'f1b2c3d4-e5f6-4747-8899-aabbccddeeff', 'Account', 1, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', '2024-01-01 12:30:00', '2024-01-01 12:30:00'),
+    ('550e8400-e29b-41d4-a716-446655440006', 'a2b2c3d4-e5f6-4747-8899-aabbccddeeff', 'Client Organization Unit', 1, '00000000-0000-0000-0000-000000000001', '00000000-0000-0000-0000-000000000001', '2024-01-01 13:00:00', '2024-01-01 13:00:00');
+
+INSERT OR IGNORE INTO "tnt_resources" ("resource_id", "parent_resource_id", "resource_type_id", "name", "row_status", "created_by", "updated_by", "created_at", "updated_at") VALUES
+    ('11111111-1111-1111-1111-111111111111', NULL, '550e8400-e29b-41d4-a716-446655440004', 'Root Organization', 1, '11111111-1111-1111-1111-121212121212', '11111111-1111-1111-1111-121212121212', '2024-01-01 10:00:00', '2024-01-01 10:00:00');
+
+INSERT INTO "tnt_resources_metadata" ("resource_id", "metadata", "row_status", "created_by", "updated_by", "created_at", "updated_at")
+SELECT '11111111-1111-1111-1111-111111111111', '{"description": "Root organization metadata", "contactInfo": {"email": "root@organization.com", "phone": "1234567890"}}', 1, '11111111-1111-1111-1111-121212121212', '11111111-1111-1111-1111-121212121212', '2024-01-01 10:00:00', '2024-01-01 10:00:00'
+WHERE NOT EXISTS (SELECT 1 FROM "tnt_resources_metadata" WHERE "resource_id" = '11111111-1111-1111-1111-111111111111');
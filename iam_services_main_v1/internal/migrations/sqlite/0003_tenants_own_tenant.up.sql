@@ -0,0 +1,5 @@
+-- Tenant rows belong to themselves so tenant isolation lets a tenant see its own row
+UPDATE "tnt_resources"
+SET "tenant_id" = "resource_id"
+WHERE "tenant_id" IS NULL
+  AND "resource_type_id" IN (SELECT "resource_type_id" FROM "mst_resource_types" WHERE "name" = 'Tenant');
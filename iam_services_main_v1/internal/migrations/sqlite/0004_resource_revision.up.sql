@@ -0,0 +1,2 @@
+-- Revision of each resource, advanced by every update for optimistic concurrency control
+ALTER TABLE "tnt_resources" ADD COLUMN "revision" integer NOT NULL DEFAULT 1;
@@ -0,0 +1,12 @@
+-- Results of mutations sent with an idempotency key, replayed to retries
+CREATE TABLE IF NOT EXISTS "tnt_idempotency_keys" (
+    "idempotency_key" text,
+    "principal_id" text,
+    "operation" text NOT NULL,
+    "fingerprint" text NOT NULL,
+    "result" blob,
+    "expires_at" datetime NOT NULL,
+    "created_at" datetime NULL,
+    PRIMARY KEY ("idempotency_key", "principal_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_idempotency_keys_expiry" ON "tnt_idempotency_keys" ("expires_at");
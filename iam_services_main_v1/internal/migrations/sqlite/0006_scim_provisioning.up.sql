@@ -0,0 +1,38 @@
+-- Principals of user and group resources, written by SCIM, CSV imports,
+-- invitations and tenant imports
+CREATE TABLE IF NOT EXISTS "tnt_principals" (
+    "resource_id" text,
+    "principal_type_id" text NOT NULL,
+    "name" text NOT NULL,
+    "email" text NOT NULL,
+    "metadata" json,
+    "row_status" integer DEFAULT 1,
+    "created_by" text,
+    "updated_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("resource_id")
+);
+
+-- Members of groups, provisioned over SCIM
+CREATE TABLE IF NOT EXISTS "tnt_group_members" (
+    "group_id" text,
+    "member_id" text,
+    "tenant_id" text NOT NULL,
+    "created_by" text,
+    "created_at" datetime NULL,
+    PRIMARY KEY ("group_id", "member_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_group_members_member" ON "tnt_group_members" ("member_id");
+
+-- Bearer tokens of the SCIM clients of each tenant
+CREATE TABLE IF NOT EXISTS "tnt_scim_tokens" (
+    "token_id" text,
+    "token_hash" text NOT NULL,
+    "tenant_id" text NOT NULL,
+    "description" text,
+    "revoked_at" datetime NULL,
+    "created_at" datetime NULL,
+    PRIMARY KEY ("token_id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_scim_tokens_hash" ON "tnt_scim_tokens" ("token_hash");
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "tnt_import_jobs";
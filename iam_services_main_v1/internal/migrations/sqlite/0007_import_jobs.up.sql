@@ -0,0 +1,20 @@
+-- Bulk imports of users, groups and bindings from CSV files
+CREATE TABLE IF NOT EXISTS "tnt_import_jobs" (
+    "job_id" text,
+    "tenant_id" text NOT NULL,
+    "file_name" text,
+    "status" text NOT NULL,
+    "chunk_size" integer NOT NULL,
+    "total_rows" integer NOT NULL,
+    "valid_rows" integer NOT NULL,
+    "imported_rows" integer NOT NULL,
+    "failed_rows" integer NOT NULL,
+    "report" json,
+    "error" text,
+    "finished_at" datetime NULL,
+    "created_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("job_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_import_jobs_tenant" ON "tnt_import_jobs" ("tenant_id");
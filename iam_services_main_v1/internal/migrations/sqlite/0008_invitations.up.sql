@@ -0,0 +1,18 @@
+-- Invitations of users by email, accepted with a signed token
+CREATE TABLE IF NOT EXISTS "tnt_invitations" (
+    "invitation_id" text,
+    "tenant_id" text NOT NULL,
+    "email" text NOT NULL,
+    "principal_id" text NOT NULL,
+    "binding_id" text NOT NULL,
+    "role_id" text NOT NULL,
+    "scope_id" text NOT NULL,
+    "expires_at" datetime NOT NULL,
+    "accepted_at" datetime NULL,
+    "created_by" text,
+    "created_at" datetime NULL,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("invitation_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_invitations_tenant" ON "tnt_invitations" ("tenant_id");
+CREATE INDEX IF NOT EXISTS "idx_invitations_email" ON "tnt_invitations" ("email");
@@ -0,0 +1,21 @@
+-- Lifecycle status of tenants and the history of its transitions
+CREATE TABLE IF NOT EXISTS "tnt_tenant_states" (
+    "tenant_id" text,
+    "status" text NOT NULL,
+    "revision" integer NOT NULL DEFAULT 1,
+    "updated_by" text,
+    "updated_at" datetime NULL,
+    PRIMARY KEY ("tenant_id")
+);
+CREATE TABLE IF NOT EXISTS "tnt_tenant_state_transitions" (
+    "transition_id" text,
+    "tenant_id" text NOT NULL,
+    "from_status" text,
+    "to_status" text NOT NULL,
+    "reason" text,
+    "revision" integer NOT NULL,
+    "created_by" text,
+    "created_at" datetime NULL,
+    PRIMARY KEY ("transition_id")
+);
+CREATE INDEX IF NOT EXISTS "idx_tenant_state_transitions_tenant" ON "tnt_tenant_state_transitions" ("tenant_id");
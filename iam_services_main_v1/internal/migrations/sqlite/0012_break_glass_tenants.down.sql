@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "idx_break_glass_grants_tenant";
+ALTER TABLE "tnt_break_glass_grants" DROP COLUMN "tenant_id";
+DROP INDEX IF EXISTS "idx_break_glass_principals_tenant";
+ALTER TABLE "tnt_break_glass_principals" DROP COLUMN "tenant_id";
@@ -0,0 +1,12 @@
+-- Break-glass registrations and grants belong to the tenant of their scope;
+-- those on a Root scope belong to none
+ALTER TABLE "tnt_break_glass_principals" ADD COLUMN "tenant_id" text;
+UPDATE "tnt_break_glass_principals" SET "tenant_id" = (
+    SELECT "r"."tenant_id" FROM "tnt_resources" AS "r" WHERE "r"."resource_id" = "tnt_break_glass_principals"."scope_id"
+);
+CREATE INDEX IF NOT EXISTS "idx_break_glass_principals_tenant" ON "tnt_break_glass_principals" ("tenant_id");
+ALTER TABLE "tnt_break_glass_grants" ADD COLUMN "tenant_id" text;
+UPDATE "tnt_break_glass_grants" SET "tenant_id" = (
+    SELECT "r"."tenant_id" FROM "tnt_resources" AS "r" WHERE "r"."resource_id" = "tnt_break_glass_grants"."scope_id"
+);
+CREATE INDEX IF NOT EXISTS "idx_break_glass_grants_tenant" ON "tnt_break_glass_grants" ("tenant_id");
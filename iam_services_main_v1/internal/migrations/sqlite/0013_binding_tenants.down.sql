@@ -0,0 +1,2 @@
+-- The backfilled tenants are kept; they match the bindings' resources
+DROP INDEX IF EXISTS "idx_role_assignments_tenant";
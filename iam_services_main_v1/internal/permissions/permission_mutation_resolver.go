@@ -1,199 +1,268 @@
 package permissions
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/dto"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrPermissionAlreadyExists = errors.New("permission already exists")
+	ErrPermissionInUse         = errors.New("permission is still assigned to roles")
+	ErrInvalidResourceType     = errors.New("invalid resource type")
+)
+
+// PermissionMutationResolver handles permission-related mutations. Every
+// change to the catalog is mirrored onto the actions of the matching Permit
+// resource so role definitions only ever reference known actions.
 type PermissionMutationResolver struct {
 	DB     *gorm.DB
 	Permit *permit.PermitClient
 }
 
-// func (r *PermissionMutationResolver) CreatePermission(ctx context.Context, input *models.CreatePermission) (*models.Permission, error) {
-// 	//logger.Log.Info("Starting permission creation")
-
-// 	if err := validateCreateInput(input); err != nil {
-// 		return nil, err
-// 	}
-// 	if err := r.DB.Where("name = ?", input.Name).First(&dto.TNTPermission{}).Error; err == nil {
-// 		//logger.Log.Errorf("Permission with name %s already exists", input.Name)
-// 		return nil, fmt.Errorf("permission with name %s already exists", input.Name)
-// 	}
-
-// 	permission := &dto.TNTPermission{
-// 		PermissionID: uuid.New(),
-// 		Name:         input.Name,
-// 		ServiceID:    *input.ServiceID,
-// 		Action:       *input.Action,
-// 		RowStatus:    1,
-// 		// RoleID:       *input.RoleID,
-// 		// CreatedBy:uu
-// 		// UpdatedBy: constants.DefaltUpdatedBy,
-// 		CreatedAt: time.Now(),
-// 		UpdatedAt: time.Now(),
-// 	}
-
-// 	if err := r.DB.Create(permission).Error; err != nil {
-// 		//logger.AddContext(err).Error("Failed to create permission")
-// 		return nil, err
-// 	}
-
-// 	permitInput := map[string]interface{}{
-// 		"key":  permission.PermissionID,
-// 		"name": input.Action,
-// 	}
-
-// 	//need to check service id
-// 	_, err := r.Permit.SendRequest(ctx, "POST", fmt.Sprintf("resources/%s/actions", input.ServiceID), permitInput)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	//logger.Log.Infof("Permission with ID %s created successfully", permission.PermissionID)
-
-// 	return convertToGraphQLPermission(permission), nil
-// }
-
-// func (r *PermissionMutationResolver) UpdatePermission(ctx context.Context, input *models.UpdatePermission) (*models.Permission, error) {
-// 	permissionID := input.ID
-// 	//logger.Log.Infof("Starting update for permission with ID: %s", permissionID)
-
-// 	var count int64
-// 	if err := r.DB.Model(&dto.TNTRolePermission{}).Where("permission_id = ? AND row_status = 1", permissionID).Count(&count).Error; err != nil {
-// 		return nil, err
-// 	}
-// 	if count > 0 {
-// 		//	logger.Log.Errorf("Permission with ID %s is in use and cannot be updated", permissionID)
-// 		return nil, errors.New("permission is in use and cannot be updated")
-// 	}
-
-// 	if input == nil {
-// 		//	logger.Log.Error("Input is required for updating permission")
-// 		return nil, errors.New("input is required")
-// 	}
-
-// 	if input.Name == "" {
-// 		//	logger.Log.Error("Name is required for updating permission")
-// 		return nil, errors.New("name is required")
-// 	} else {
-// 		if err := r.DB.Where("name = ?", input.Name).First(&dto.TNTPermission{}).Error; err == nil {
-// 			//	logger.Log.Errorf("Permission with name %s already exists", input.Name)
-// 			return nil, fmt.Errorf("permission with name %s already exists", input.Name)
-// 		}
-// 	}
-
-// 	if input.ServiceID == nil {
-// 		//logger.Log.Error("Service ID is required for updating permission")
-// 		return nil, errors.New("service ID is required")
-// 	}
-
-// 	if input.Action == nil {
-// 		//logger.Log.Error("Action is required for updating permission")
-// 		return nil, errors.New("action is required")
-// 	}
-
-// 	var permission dto.TNTPermission
-// 	if err := r.DB.First(&permission, "permission_id = ?", permissionID).Error; err != nil {
-// 		if errors.Is(err, gorm.ErrRecordNotFound) {
-// 			//logger.Log.Warnf("Permission with ID %s not found", permissionID)
-// 			return nil, errors.New("permission not found")
-// 		}
-// 		//logger.AddContext(err).Errorf("Failed to find permission with ID: %s", permissionID)
-// 		return nil, err
-// 	}
-
-// 	// if input.RoleID == nil {
-// 	// 	logger.Log.Error("Role ID is required for updating permission")
-// 	// 	return nil, errors.New("role ID is required")
-// 	// } else if err := utils.ValidateRoleID(*input.RoleID); err != nil {
-// 	// 	logger.AddContext(err).Error("Invalid role ID")
-// 	// 	return nil, fmt.Errorf("invalid role ID: %v", err)
-// 	// }
-
-// 	permission.Name = input.Name
-// 	permission.ServiceID = *input.ServiceID
-// 	permission.Action = *input.Action
-// 	// permission.RoleID = *input.RoleID
-// 	permission.UpdatedAt = time.Now()
-
-// 	if err := r.DB.Save(&permission).Error; err != nil {
-// 		//logger.AddContext(err).Errorf("Failed to update permission with ID: %s", permissionID)
-// 		return nil, err
-// 	}
-// 	permitInput := map[string]interface{}{
-// 		"name": input.Action,
-// 	}
-
-// 	//need to check service id
-// 	_, err := r.Permit.SendRequest(ctx, "PATCH", fmt.Sprintf("resources/%s/actions/%s", input.ServiceID, permission.PermissionID), permitInput)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-// 	//logger.Log.Infof("Permission with ID %s updated successfully", permissionID)
-// 	return convertToGraphQLPermission(&permission), nil
-// }
-
-// // Helper function to convert DTO to GraphQL model
-// func convertToGraphQLPermission(p *dto.TNTPermission) *models.Permission {
-// 	if p == nil {
-// 		return nil
-// 	}
-
-// 	return &models.Permission{
-// 		ID:        p.PermissionID,
-// 		Name:      p.Name,
-// 		ServiceID: &p.ServiceID,
-// 		Action:    &p.Action,
-// 		// CreatedAt: ptr.String(p.CreatedAt.String()),
-// 		CreatedBy: p.CreatedBy,
-// 		// UpdatedAt: ptr.String(p.UpdatedAt.String()),
-// 		// UpdatedBy: ptr.String(p.UpdatedBy),
-// 	}
-// }
-
-// func (r *PermissionMutationResolver) DeletePermission(ctx context.Context, id uuid.UUID) (bool, error) {
-// 	//logger.Log.Infof("Attempting to delete permission with ID: %s", id)
-
-// 	var count int64
-// 	if err := r.DB.Model(&dto.TNTRolePermission{}).Where("permission_id = ? AND row_status = 1", id).Count(&count).Error; err != nil {
-// 		return false, err
-// 	}
-// 	if count > 0 {
-// 		//logger.Log.Errorf("Permission with ID %s is in use and cannot be deleted", id)
-// 		return false, errors.New("permission is in use and cannot be deleted")
-// 	}
-
-// 	updates := map[string]interface{}{
-// 		"row_status": 0,
-// 	}
-// 	result := r.DB.Model(&dto.TNTPermission{}).Where("permission_id = ?", id).Updates(updates)
-// 	if result.Error != nil {
-// 		//logger.AddContext(result.Error).Errorf("Failed to delete permission with ID: %s", id)
-// 		return false, result.Error
-// 	}
-
-// 	if result.RowsAffected == 0 {
-// 		//logger.Log.Warnf("Permission with ID %s not found", id)
-// 		return false, errors.New("permission not found")
-// 	}
-
-// 	//logger.Log.Infof("Permission with ID %s deleted successfully", id)
-// 	return true, nil
-// }
-
-// func validateCreateInput(input *models.CreatePermissions) error {
-// 	// if input == nil || input.ServiceID == uuid.Nil || input.Action == "" || input.Name == "" {
-// 	// 	//logger.Log.Error("Input is required for creating permission")
-// 	// 	return errors.New("input is required")
-// 	// }
-
-// 	// else {
-// 	// 	if err := r.DB.Where("name = ?", input.Name).First(&dto.TNTPermission{}).Error; err == nil {
-// 	// 		//logger.Log.Errorf("Permission with name %s already exists", input.Name)
-// 	// 		return nil, fmt.Errorf("permission with name %s already exists", input.Name)
-// 	// 	}
-// 	// }
-// 	return nil
-// }
+// CreatePermission adds an action to the catalog of a resource type.
+func (r *PermissionMutationResolver) CreatePermission(ctx context.Context, input models.CreatePermissionInput) (models.OperationResult, error) {
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	if err := r.validatePermissionInput(input.Name, input.Action, input.AssignableScopeRef); err != nil {
+		return handleError("400", "Invalid input", err)
+	}
+
+	if err := r.checkPermissionExists(input.AssignableScopeRef.String(), input.Action, uuid.Nil); err != nil {
+		return handleError("409", "Permission already exists", err)
+	}
+
+	if err := r.createPermitAction(ctx, input.AssignableScopeRef.String(), input.Action, input.Name); err != nil {
+		return handleError("500", "Error creating permission in permit", err)
+	}
+
+	permission := dto.MstPermission{
+		PermissionID:   input.ID,
+		ResourceTypeID: input.AssignableScopeRef.String(),
+		Name:           input.Name,
+		Action:         input.Action,
+		RowStatus:      1,
+		CreatedBy:      userID.String(),
+		UpdatedBy:      userID.String(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+	if err := r.DB.Create(&permission).Error; err != nil {
+		if rollbackErr := r.deletePermitAction(ctx, input.AssignableScopeRef.String(), input.Action); rollbackErr != nil {
+			logger.LogError(fmt.Sprintf("Error rolling back permit action %s: %v", input.Action, rollbackErr))
+		}
+		return handleError("500", "Error creating permission", err)
+	}
+
+	return utils.FormatSuccess([]models.Data{mapToPermission(&permission)})
+}
+
+// UpdatePermission renames a permission. Changing the action key creates the
+// new Permit action, moves every role that grants the old action over to it and
+// then removes the old action.
+func (r *PermissionMutationResolver) UpdatePermission(ctx context.Context, input models.UpdatePermissionInput) (models.OperationResult, error) {
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	permission, err := getPermissionByID(r.DB, input.ID)
+	if err != nil {
+		return handleError("404", "Permission not found", err)
+	}
+
+	if err := r.validatePermissionInput(input.Name, input.Action, input.AssignableScopeRef); err != nil {
+		return handleError("400", "Invalid input", err)
+	}
+
+	oldScope, newScope := permission.ResourceTypeID, input.AssignableScopeRef.String()
+	oldAction, newAction := permission.ActionKey(), input.Action
+
+	roleIDs, err := r.getAssignedRoleIDs(permission.PermissionID)
+	if err != nil {
+		return handleError("500", "Error fetching role permissions", err)
+	}
+
+	if oldScope != newScope && len(roleIDs) > 0 {
+		return handleError("409", "Permission is in use", ErrPermissionInUse)
+	}
+
+	if oldScope == newScope && oldAction == newAction {
+		if err := r.updatePermitAction(ctx, oldScope, oldAction, input.Name); err != nil {
+			return handleError("500", "Error updating permission in permit", err)
+		}
+	} else {
+		if err := r.checkPermissionExists(newScope, newAction, permission.PermissionID); err != nil {
+			return handleError("409", "Permission already exists", err)
+		}
+		if err := r.createPermitAction(ctx, newScope, newAction, input.Name); err != nil {
+			return handleError("500", "Error creating permission in permit", err)
+		}
+		for _, roleID := range roleIDs {
+			if err := r.moveRoleAction(ctx, oldScope, roleID, oldAction, newAction); err != nil {
+				return handleError("500", "Error moving role permissions in permit", err)
+			}
+		}
+		if err := r.deletePermitAction(ctx, oldScope, oldAction); err != nil {
+			return handleError("500", "Error deleting permission in permit", err)
+		}
+	}
+
+	updates := map[string]interface{}{
+		"name":             input.Name,
+		"action":           newAction,
+		"resource_type_id": newScope,
+		"updated_by":       userID.String(),
+		"updated_at":       time.Now(),
+	}
+	if err := r.DB.Model(&dto.MstPermission{}).Where("permission_id = ? AND row_status = 1", input.ID).Updates(updates).Error; err != nil {
+		return handleError("500", "Error updating permission", err)
+	}
+
+	permission, err = getPermissionByID(r.DB, input.ID)
+	if err != nil {
+		return handleError("500", "Error fetching permission", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToPermission(permission)})
+}
+
+// DeletePermission retires a permission. Retirement is refused while any role
+// still grants it.
+func (r *PermissionMutationResolver) DeletePermission(ctx context.Context, input models.DeleteInput) (models.OperationResult, error) {
+	permission, err := getPermissionByID(r.DB, input.ID)
+	if err != nil {
+		return handleError("404", "Permission not found", err)
+	}
+
+	inUse, err := r.isPermissionInUse(permission.PermissionID)
+	if err != nil {
+		return handleError("500", "Error fetching role permissions", err)
+	}
+	if inUse {
+		return handleError("409", "Permission is in use", ErrPermissionInUse)
+	}
+
+	if err := r.deletePermitAction(ctx, permission.ResourceTypeID, permission.ActionKey()); err != nil {
+		return handleError("500", "Error deleting permission in permit", err)
+	}
+
+	if err := r.DB.Model(&dto.MstPermission{}).Where("permission_id = ?", input.ID).Updates(utils.UpdateDeletedMap()).Error; err != nil {
+		return handleError("500", "Error deleting permission", err)
+	}
+
+	return utils.FormatSuccess([]models.Data{})
+}
+
+// Helper Functions
+
+func (r *PermissionMutationResolver) permitClient() *permit.PermitClient {
+	if r.Permit != nil {
+		return r.Permit
+	}
+	return permit.NewPermitClient()
+}
+
+func (r *PermissionMutationResolver) validatePermissionInput(name, action string, resourceTypeID uuid.UUID) error {
+	if name == "" {
+		return errors.New("permission name is required")
+	}
+	if err := utils.ValidateName(action); err != nil {
+		return fmt.Errorf("invalid permission action: %w", err)
+	}
+	var count int64
+	if err := r.DB.Model(&dto.Mst_ResourceTypes{}).Where("resource_type_id = ? AND row_status = 1", resourceTypeID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to validate resource type: %w", err)
+	}
+	if count == 0 {
+		return ErrInvalidResourceType
+	}
+	return nil
+}
+
+func (r *PermissionMutationResolver) checkPermissionExists(resourceTypeID, action string, excludeID uuid.UUID) error {
+	var count int64
+	err := r.DB.Model(&dto.MstPermission{}).
+		Where("resource_type_id = ? AND action = ? AND permission_id <> ? AND row_status = 1", resourceTypeID, action, excludeID).
+		Count(&count).Error
+	if err != nil {
+		return fmt.Errorf("failed to check permission: %w", err)
+	}
+	if count > 0 {
+		return ErrPermissionAlreadyExists
+	}
+	return nil
+}
+
+func (r *PermissionMutationResolver) getAssignedRoleIDs(permissionID uuid.UUID) ([]string, error) {
+	var roleIDs []string
+	err := r.DB.Model(&dto.TNTRolePermission{}).
+		Where("permission_id = ? AND row_status = 1", permissionID).
+		Distinct().Pluck("role_id", &roleIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch role permissions: %w", err)
+	}
+	return roleIDs, nil
+}
+
+func (r *PermissionMutationResolver) isPermissionInUse(permissionID uuid.UUID) (bool, error) {
+	roleIDs, err := r.getAssignedRoleIDs(permissionID)
+	if err != nil {
+		return false, err
+	}
+	if len(roleIDs) > 0 {
+		return true, nil
+	}
+	var count int64
+	if err := r.DB.Model(&dto.MstRolePermission{}).Where("permission_id = ? AND row_status = 1", permissionID).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to fetch default role permissions: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *PermissionMutationResolver) createPermitAction(ctx context.Context, resourceTypeID, action, name string) error {
+	_, err := r.permitClient().SendRequest(ctx, "POST", fmt.Sprintf("resources/%s/actions", resourceTypeID), map[string]interface{}{
+		"key":  action,
+		"name": name,
+	})
+	return err
+}
+
+func (r *PermissionMutationResolver) updatePermitAction(ctx context.Context, resourceTypeID, action, name string) error {
+	_, err := r.permitClient().SendRequest(ctx, "PATCH", fmt.Sprintf("resources/%s/actions/%s", resourceTypeID, action), map[string]interface{}{
+		"name": name,
+	})
+	return err
+}
+
+func (r *PermissionMutationResolver) deletePermitAction(ctx context.Context, resourceTypeID, action string) error {
+	_, err := r.permitClient().SendRequest(ctx, "DELETE", fmt.Sprintf("resources/%s/actions/%s", resourceTypeID, action), nil)
+	return err
+}
+
+func (r *PermissionMutationResolver) moveRoleAction(ctx context.Context, resourceTypeID, roleID, oldAction, newAction string) error {
+	endpoint := fmt.Sprintf("resources/%s/roles/%s/permissions", resourceTypeID, roleID)
+	if _, err := r.permitClient().SendRequest(ctx, "POST", endpoint, map[string]interface{}{
+		"permissions": []string{newAction},
+	}); err != nil {
+		return fmt.Errorf("failed to assign action %s to role %s: %w", newAction, roleID, err)
+	}
+	if _, err := r.permitClient().SendRequest(ctx, "DELETE", endpoint, map[string]interface{}{
+		"permissions": []string{oldAction},
+	}); err != nil {
+		return fmt.Errorf("failed to remove action %s from role %s: %w", oldAction, roleID, err)
+	}
+	return nil
+}
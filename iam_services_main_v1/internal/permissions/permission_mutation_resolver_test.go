@@ -1,143 +1,170 @@
 package permissions
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/gql/models"
 	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/gorm"
 )
 
 func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
 	if err != nil {
 		t.Fatalf("Failed to open database: %v", err)
 	}
-	if err := db.AutoMigrate(&dto.MstPermission{}); err != nil {
+	if err := db.AutoMigrate(&dto.MstPermission{}, &dto.Mst_ResourceTypes{}, &dto.TNTRolePermission{}, &dto.MstRolePermission{}); err != nil {
 		t.Fatalf("Failed to migrate database: %v", err)
 	}
 	return db
 }
 
-// func TestDeletePermission(t *testing.T) {
-// 	logger.InitLogger()
-// 	db := setupTestDB(t)
-// 	resolver := &PermissionMutationResolver{DB: db}
-
-// 	// Insert a sample permission
-// 	permissionID := uuid.New()
-// 	db.Create(&dto.TNTPermission{
-// 		PermissionID: permissionID,
-// 		Name:         "Test Permission",
-// 		ServiceID:    uuid.New().String(),
-// 		Action:       "test_action",
-// 		RowStatus:    1,
-// 		CreatedAt:    time.Now(),
-// 		UpdatedAt:    time.Now(),
-// 	})
-
-// 	// Test case 1: Successful deletion
-// 	success, err := resolver.DeletePermission(context.Background(), permissionID)
-// 	assert.NoError(t, err)
-// 	assert.True(t, success)
-
-// 	// Test case 2: Non-existent permission
-// 	success, err = resolver.DeletePermission(context.Background(), uuid.New())
-// 	assert.Error(t, err)
-// 	assert.False(t, success)
-
-// 	// Test case 3: Database error (simulate by closing DB)
-// 	sqlDB, _ := db.DB()
-// 	sqlDB.Close()
-// 	_, err = resolver.DeletePermission(context.Background(), permissionID)
-// 	assert.Error(t, err)
-// }
-
-// func TestCreatePermission(t *testing.T) {
-// 	logger.InitLogger()
-// 	db := setupTestDB(t)
-// 	resolver := &PermissionMutationResolver{DB: db}
-
-// 	// Test case 1: Successful creation
-// 	input := &models.CreatePermission{
-// 		Name:      "Test Permission",
-// 		ServiceID: uuid.New(),
-// 		Action:    "test_action",
-// 	}
-// 	createdPermission, err := resolver.CreatePermission(context.Background(), input)
-// 	assert.NoError(t, err)
-// 	assert.NotNil(t, createdPermission)
-// 	assert.Equal(t, input.Name, createdPermission.Name)
-
-// 	// Test case 2: Missing input
-// 	_, err = resolver.CreatePermission(context.Background(), nil)
-// 	assert.Error(t, err)
-
-// 	// Test case 3: Duplicate name
-// 	_, err = resolver.CreatePermission(context.Background(), input)
-// 	assert.Error(t, err)
-// }
-
-// func TestUpdatePermission(t *testing.T) {
-// 	logger.InitLogger()
-// 	db := setupTestDB(t)
-// 	resolver := &PermissionMutationResolver{DB: db}
-
-// 	// Insert a sample permission
-// 	permissionID := uuid.New()
-// 	db.Create(&dto.TNTPermission{
-// 		PermissionID: permissionID,
-// 		Name:         "Test Permission",
-// 		ServiceID:    uuid.New().String(),
-// 		Action:       "test_action",
-// 		RowStatus:    1,
-// 		CreatedAt:    time.Now(),
-// 		UpdatedAt:    time.Now(),
-// 	})
-
-// 	serviceID := uuid.New()
-
-// 	// Test case 1: Successful update
-// 	input := &models.UpdatePermission{
-// 		ID:        permissionID,
-// 		Name:      "Updated Permission",
-// 		ServiceID: &serviceID,
-// 		Action:    ptr.String("action"),
-// 	}
-// 	updatedPermission, err := resolver.UpdatePermission(context.Background(), input)
-// 	assert.NoError(t, err)
-// 	assert.NotNil(t, updatedPermission)
-// 	assert.Equal(t, input.Name, updatedPermission.Name)
-
-// 	// Test case 2: Missing input
-// 	_, err = resolver.UpdatePermission(context.Background(), nil)
-// 	assert.Error(t, err)
-
-// 	serviceID = uuid.New()
-
-// 	// Test case 3: Non-existent permission
-// 	nonExistentInput := &models.UpdatePermission{
-// 		ID:        uuid.New(),
-// 		Name:      "Non-existent Permission",
-// 		ServiceID: &serviceID,
-// 		Action:    ptr.String("action"),
-// 	}
-// 	_, err = resolver.UpdatePermission(context.Background(), nonExistentInput)
-// 	assert.Error(t, err)
-
-// 	// Test case 4: Duplicate name
-// 	duplicatePermission := dto.TNTPermission{
-// 		PermissionID: uuid.New(),
-// 		Name:         "Duplicate Permission",
-// 		ServiceID:    uuid.New().String(),
-// 		Action:       "action",
-// 		RowStatus:    1,
-// 		CreatedAt:    time.Now(),
-// 		UpdatedAt:    time.Now(),
-// 	}
-// 	db.Create(&duplicatePermission)
-
-// 	input.Name = duplicatePermission.Name
-// 	_, err = resolver.UpdatePermission(context.Background(), input)
-// 	assert.Error(t, err)
-// }
+type permitCall struct {
+	Method string
+	Path   string
+	Body   map[string]interface{}
+}
+
+// fakePermit records every request made against the Permit schema API.
+type fakePermit struct {
+	mu    sync.Mutex
+	calls []permitCall
+}
+
+func (f *fakePermit) client(t *testing.T) *permit.PermitClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		call := permitCall{Method: req.Method, Path: req.URL.Path}
+		_ = json.NewDecoder(req.Body).Decode(&call.Body)
+		f.mu.Lock()
+		f.calls = append(f.calls, call)
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+}
+
+func (f *fakePermit) paths(method string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var paths []string
+	for _, c := range f.calls {
+		if c.Method == method {
+			paths = append(paths, strings.TrimPrefix(c.Path, "/v2/schema/proj/env/"))
+		}
+	}
+	return paths
+}
+
+func userContext(userID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func seedResourceType(t *testing.T, db *gorm.DB) uuid.UUID {
+	id := uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: id, Name: "Document", RowStatus: 1}).Error)
+	return id
+}
+
+func TestCreatePermission(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	fp := &fakePermit{}
+	resolver := &PermissionMutationResolver{DB: db, Permit: fp.client(t)}
+	resourceTypeID := seedResourceType(t, db)
+	ctx := userContext(uuid.New())
+
+	input := models.CreatePermissionInput{ID: uuid.New(), Name: "Read documents", Action: "read", AssignableScopeRef: resourceTypeID}
+	result, err := resolver.CreatePermission(ctx, input)
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	assert.Equal(t, []string{fmt.Sprintf("resources/%s/actions", resourceTypeID)}, fp.paths("POST"))
+
+	var stored dto.MstPermission
+	require.NoError(t, db.First(&stored, "permission_id = ?", input.ID).Error)
+	assert.Equal(t, "read", stored.Action)
+
+	// Duplicate action on the same resource type
+	input.ID = uuid.New()
+	result, err = resolver.CreatePermission(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "409", result.(*models.ResponseError).ErrorCode)
+
+	// Unknown resource type
+	input.AssignableScopeRef = uuid.New()
+	input.Action = "write"
+	result, err = resolver.CreatePermission(ctx, input)
+	require.NoError(t, err)
+	assert.Equal(t, "400", result.(*models.ResponseError).ErrorCode)
+}
+
+func TestUpdatePermissionRenamesActionAndMovesRoles(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	fp := &fakePermit{}
+	resolver := &PermissionMutationResolver{DB: db, Permit: fp.client(t)}
+	resourceTypeID := seedResourceType(t, db)
+	permissionID, roleID := uuid.New(), uuid.New()
+	require.NoError(t, db.Create(&dto.MstPermission{PermissionID: permissionID, ResourceTypeID: resourceTypeID.String(), Name: "Read", Action: "read", RowStatus: 1}).Error)
+	require.NoError(t, db.Create(&dto.TNTRolePermission{ID: uuid.New(), RoleID: roleID, PermissionID: permissionID, RowStatus: 1}).Error)
+
+	result, err := resolver.UpdatePermission(userContext(uuid.New()), models.UpdatePermissionInput{
+		ID: permissionID, Name: "View", Action: "view", AssignableScopeRef: resourceTypeID,
+	})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+
+	rolePermissions := fmt.Sprintf("resources/%s/roles/%s/permissions", resourceTypeID, roleID)
+	assert.Equal(t, []string{fmt.Sprintf("resources/%s/actions", resourceTypeID), rolePermissions}, fp.paths("POST"))
+	assert.Equal(t, []string{rolePermissions, fmt.Sprintf("resources/%s/actions/read", resourceTypeID)}, fp.paths("DELETE"))
+
+	var stored dto.MstPermission
+	require.NoError(t, db.First(&stored, "permission_id = ?", permissionID).Error)
+	assert.Equal(t, "view", stored.Action)
+	assert.Equal(t, "View", stored.Name)
+}
+
+func TestDeletePermissionBlockedWhileInUse(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	fp := &fakePermit{}
+	resolver := &PermissionMutationResolver{DB: db, Permit: fp.client(t)}
+	resourceTypeID := seedResourceType(t, db)
+	permissionID := uuid.New()
+	require.NoError(t, db.Create(&dto.MstPermission{PermissionID: permissionID, ResourceTypeID: resourceTypeID.String(), Name: "Read", Action: "read", RowStatus: 1}).Error)
+	assignment := dto.TNTRolePermission{ID: uuid.New(), RoleID: uuid.New(), PermissionID: permissionID, RowStatus: 1}
+	require.NoError(t, db.Create(&assignment).Error)
+
+	result, err := resolver.DeletePermission(context.Background(), models.DeleteInput{ID: permissionID})
+	require.NoError(t, err)
+	assert.Equal(t, "409", result.(*models.ResponseError).ErrorCode)
+	assert.Empty(t, fp.paths("DELETE"))
+
+	require.NoError(t, db.Model(&dto.TNTRolePermission{}).Where("role_permission_id = ?", assignment.ID).Update("row_status", 0).Error)
+
+	result, err = resolver.DeletePermission(context.Background(), models.DeleteInput{ID: permissionID})
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	assert.Equal(t, []string{fmt.Sprintf("resources/%s/actions/read", resourceTypeID)}, fp.paths("DELETE"))
+
+	var stored dto.MstPermission
+	require.NoError(t, db.First(&stored, "permission_id = ?", permissionID).Error)
+	assert.Equal(t, 0, stored.RowStatus)
+}
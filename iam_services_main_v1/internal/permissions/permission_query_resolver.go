@@ -2,9 +2,12 @@ package permissions
 
 import (
 	"context"
+	"fmt"
 	"iam_services_main_v1/gql/models"
 	"iam_services_main_v1/internal/dto"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
 
 	"time"
 
@@ -18,71 +21,61 @@ type PermissionQueryResolver struct {
 	Permit *permit.PermitClient
 }
 
-// Permissions resolves the list of all permissions.
-func (r *PermissionQueryResolver) GetAllPermissions(ctx context.Context) ([]*models.Permission, error) {
-	//logger.Log.Info("Fetching all permissions")
-
+// Permissions resolves the list of all active permissions in the catalog.
+func (r *PermissionQueryResolver) Permissions(ctx context.Context) (models.OperationResult, error) {
 	var permissions []dto.MstPermission
-	if err := r.DB.Find(&permissions).Error; err != nil {
-		//logger.AddContext(err).Error("Failed to fetch permissions from the database")
-		return nil, err
+	if err := r.DB.Where("row_status = 1").Order("resource_type_id, action").Find(&permissions).Error; err != nil {
+		return handleError("500", "Error fetching permissions", err)
 	}
 
-	// pc := permit.NewPermitClient()
-
-	// update := map[string]interface{}{
-	// 	"permissions": permissionAction,
-	// }
-
-	// _, err := pc.SendRequest(ctx, "POST", fmt.Sprintf("resources/%s/roles/%s/permissions", assinableScopeName, roleName), update)
-	// if err != nil {
-	// 	return err
-	// }
-	var result []*models.Permission
-	for _, permission := range permissions {
-		result = append(result, &models.Permission{
-			ID:   permission.PermissionID,
-			Name: permission.Name,
-			// ServiceID: &permission.ServiceID,
-			// RoleID:    &permission.RoleID,
-			// Action:    &permission.Action,
-			CreatedAt: permission.CreatedAt.Format(time.RFC3339),
-			// CreatedBy: permission.CreatedBy,
-			UpdatedAt: permission.UpdatedAt.Format(time.RFC3339),
-			// UpdatedBy: &permission.UpdatedBy,
-		})
+	data := make([]models.Data, 0, len(permissions))
+	for i := range permissions {
+		data = append(data, mapToPermission(&permissions[i]))
 	}
-	// // https://api.permit.io/v2/schema/{proj_id}/{env_id}/resources/{resource_id}/actions
-	// res, err := r.Permit.SendRequest(ctx,"GET", fmt.Sprintf("resources/%s/actions", input.Resource))
-	// fmt.Println(res, err)
-	// if err != nil {
-	// 	return nil, err
-	// }
-
-	//logger.Log.Infof("Fetched %d permissions", len(result))
-	return result, nil
+	return utils.FormatSuccess(data)
 }
 
-// GetPermission resolves a single permission by ID.
-func (r *PermissionQueryResolver) GetPermission(ctx context.Context, id uuid.UUID) (*models.Permission, error) {
-	//logger.Log.Infof("Fetching permission with ID: %s", id)
+// Permission resolves a single permission by ID.
+func (r *PermissionQueryResolver) Permission(ctx context.Context, id uuid.UUID) (models.OperationResult, error) {
+	permission, err := getPermissionByID(r.DB, id)
+	if err != nil {
+		return handleError("404", "Permission not found", err)
+	}
+	return utils.FormatSuccess([]models.Data{mapToPermission(permission)})
+}
 
+func getPermissionByID(db *gorm.DB, id uuid.UUID) (*dto.MstPermission, error) {
 	var permission dto.MstPermission
-	if err := r.DB.First(&permission, "permission_id = ?", id).Error; err != nil {
-		//logger.AddContext(err).Warnf("Permission with ID %s not found", id)
-		return nil, err
+	if err := db.Where("permission_id = ? AND row_status = 1", id).First(&permission).Error; err != nil {
+		return nil, fmt.Errorf("permission not found: %w", err)
 	}
+	return &permission, nil
+}
 
-	//logger.Log.Infof("Permission with ID %s fetched successfully", id)
+func mapToPermission(permission *dto.MstPermission) *models.Permission {
 	return &models.Permission{
-		ID:   permission.PermissionID,
-		Name: permission.Name,
-		// ServiceID: &permission.ServiceID,
-		// Action:    &permission.Action,
-		// RoleID:    &permission.RoleID,
-		CreatedAt: permission.CreatedAt.Format(time.RFC3339),
-		// CreatedBy: permission.CreatedBy,
-		UpdatedAt: permission.UpdatedAt.Format(time.RFC3339),
-		// UpdatedBy: permission.UpdatedBy,
-	}, nil
+		ID:              permission.PermissionID,
+		Name:            permission.Name,
+		Action:          permission.ActionKey(),
+		AssignableScope: permission.ResourceTypeID,
+		CreatedAt:       permission.CreatedAt.Format(time.RFC3339),
+		CreatedBy:       parseUserID(permission.CreatedBy),
+		UpdatedAt:       permission.UpdatedAt.Format(time.RFC3339),
+		UpdatedBy:       parseUserID(permission.UpdatedBy),
+	}
+}
+
+// parseUserID tolerates legacy rows whose audit columns do not hold a UUID.
+func parseUserID(value string) uuid.UUID {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
 }
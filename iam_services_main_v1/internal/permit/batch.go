@@ -0,0 +1,77 @@
+package permit
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency is how many requests SendAll runs at once when no limit
+// is given.
+const DefaultConcurrency = 8
+
+// MaxBulkSize is the largest number of role assignments sent in one bulk request.
+const MaxBulkSize = 100
+
+// Request is a request sent by SendAll.
+type Request struct {
+	Method   string
+	Endpoint string
+	Payload  interface{}
+}
+
+// RoleAssignment assigns a role to a user, within a tenant when one is set.
+type RoleAssignment struct {
+	User   string `json:"user"`
+	Role   string `json:"role"`
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// SendAll sends requests with at most concurrency of them in flight and
+// returns the error of each request by position.
+func (pc *PermitClient) SendAll(ctx context.Context, requests []Request, concurrency int) []error {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	errs := make([]error, len(requests))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, request := range requests {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, request Request) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, errs[i] = pc.SendRequest(ctx, request.Method, request.Endpoint, request.Payload)
+		}(i, request)
+	}
+	wg.Wait()
+	return errs
+}
+
+// BulkAssignRoles creates assignments through the bulk endpoint, in chunks of
+// MaxBulkSize sent concurrently. Every assignment of a failed chunk reports
+// that chunk's error.
+func (pc *PermitClient) BulkAssignRoles(ctx context.Context, assignments []RoleAssignment, concurrency int) []error {
+	return pc.sendBulk(ctx, "POST", assignments, concurrency)
+}
+
+// BulkUnassignRoles removes assignments through the bulk endpoint like
+// BulkAssignRoles.
+func (pc *PermitClient) BulkUnassignRoles(ctx context.Context, assignments []RoleAssignment, concurrency int) []error {
+	return pc.sendBulk(ctx, "DELETE", assignments, concurrency)
+}
+
+func (pc *PermitClient) sendBulk(ctx context.Context, method string, assignments []RoleAssignment, concurrency int) []error {
+	var requests []Request
+	for start := 0; start < len(assignments); start += MaxBulkSize {
+		end := min(start+MaxBulkSize, len(assignments))
+		requests = append(requests, Request{Method: method, Endpoint: "role_assignments/bulk", Payload: assignments[start:end]})
+	}
+
+	chunkErrs := pc.SendAll(ctx, requests, concurrency)
+	errs := make([]error, len(assignments))
+	for i := range assignments {
+		errs[i] = chunkErrs[i/MaxBulkSize]
+	}
+	return errs
+}
@@ -0,0 +1,91 @@
+package permit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendAllBoundsConcurrency(t *testing.T) {
+	var inFlight, peak int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		if r.URL.Path == "/v2/facts/proj/env/tenants/bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	pc := NewPermitClientWithConfig(Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+
+	requests := make([]Request, 10)
+	for i := range requests {
+		requests[i] = Request{Method: "DELETE", Endpoint: "tenants/good"}
+	}
+	requests[4].Endpoint = "tenants/bad"
+
+	errs := pc.SendAll(context.Background(), requests, 3)
+	require.Len(t, errs, 10)
+	for i, err := range errs {
+		if i == 4 {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(3))
+}
+
+func TestBulkAssignRolesChunksRequests(t *testing.T) {
+	var mu sync.Mutex
+	var chunks []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/facts/proj/env/role_assignments/bulk", r.URL.Path)
+		var assignments []RoleAssignment
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&assignments))
+		mu.Lock()
+		chunks = append(chunks, len(assignments))
+		mu.Unlock()
+		// The chunk holding the last assignment fails
+		if assignments[len(assignments)-1].User == "last" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	pc := NewPermitClientWithConfig(Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+
+	assignments := make([]RoleAssignment, MaxBulkSize*2+5)
+	for i := range assignments {
+		assignments[i] = RoleAssignment{User: "user", Role: "role", Tenant: "tenant"}
+	}
+	assignments[len(assignments)-1].User = "last"
+
+	errs := pc.BulkAssignRoles(context.Background(), assignments, 2)
+	assert.ElementsMatch(t, []int{MaxBulkSize, MaxBulkSize, 5}, chunks)
+	for i, err := range errs {
+		if i >= MaxBulkSize*2 {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package permittest provides a fake Permit API for tests.
+package permittest
+
+import (
+	"iam_services_main_v1/internal/permit"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server records the requests it receives, as their method and the path
+// after the environment, and fails those starting with the prefix set by
+// Fail.
+type Server struct {
+	URL string
+
+	mu       sync.Mutex
+	fail     string
+	requests []string
+}
+
+// New starts a Server that is closed when t finishes.
+func New(t testing.TB) *Server {
+	s := &Server{}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	s.URL = srv.URL
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	request := r.Method + " " + r.URL.Path[strings.Index(r.URL.Path, "/env/")+len("/env/"):]
+	s.requests = append(s.requests, request)
+	if s.fail != "" && strings.HasPrefix(request, s.fail) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Client returns a Permit client sending its requests to s.
+func (s *Server) Client() *permit.PermitClient {
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: s.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+}
+
+// Fail makes s fail the requests starting with prefix, or none when it is
+// empty.
+func (s *Server) Fail(prefix string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fail = prefix
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// Take returns the requests received so far and forgets them.
+func (s *Server) Take() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	requests := s.requests
+	s.requests = nil
+	return requests
+}
@@ -0,0 +1,429 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type gormStore struct {
+	db *gorm.DB
+}
+
+// NewGormStore returns a Store backed by db, which must have the tenancy
+// plugin registered.
+func NewGormStore(db *gorm.DB) Store {
+	return &gormStore{db: db}
+}
+
+func (s *gormStore) Resources() ResourceRepository              { return gormResources{s} }
+func (s *gormStore) Metadata() MetadataRepository               { return gormMetadata{s} }
+func (s *gormStore) Roles() RoleRepository                      { return gormRoles{s} }
+func (s *gormStore) Permissions() PermissionRepository          { return gormPermissions{s} }
+func (s *gormStore) Principals() PrincipalRepository            { return gormPrincipals{s} }
+func (s *gormStore) Assignments() AssignmentRepository          { return gormAssignments{s} }
+func (s *gormStore) Members() MembershipRepository              { return gormMembers{s} }
+func (s *gormStore) TenantStates() TenantStateRepository        { return gormTenantStates{s} }
+func (s *gormStore) ApprovalPolicies() ApprovalPolicyRepository { return gormApprovalPolicies{s} }
+
+func (s *gormStore) Transaction(ctx context.Context, fn func(Store) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&gormStore{db: tx})
+	})
+}
+
+// session scopes the store's database to the request's tenant.
+func (s *gormStore) session(ctx context.Context) *gorm.DB {
+	return tenancy.ForRequest(ctx, s.db)
+}
+
+// first loads the first row matching the query into dest.
+func first(db *gorm.DB, dest interface{}, query string, args ...interface{}) error {
+	if err := db.Where(query, args...).First(dest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+type gormResources struct{ s *gormStore }
+
+func (r gormResources) Get(ctx context.Context, id uuid.UUID) (*dto.TenantResource, error) {
+	var resource dto.TenantResource
+	if err := first(r.s.session(ctx), &resource, "resource_id = ? AND row_status = 1", id); err != nil {
+		return nil, err
+	}
+	return &resource, nil
+}
+
+func (r gormResources) ListByType(ctx context.Context, resourceTypeID uuid.UUID) ([]dto.TenantResource, error) {
+	var resources []dto.TenantResource
+	if err := r.s.session(ctx).Where("resource_type_id = ? AND row_status = 1", resourceTypeID).Order("created_at").Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+func (r gormResources) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantResource, error) {
+	var resources []dto.TenantResource
+	if err := r.s.session(ctx).Where("tenant_id = ? AND row_status = 1", tenantID).Order("created_at").Find(&resources).Error; err != nil {
+		return nil, err
+	}
+	return resources, nil
+}
+
+func (r gormResources) Create(ctx context.Context, resource *dto.TenantResource) error {
+	return r.s.session(ctx).Create(resource).Error
+}
+
+func (r gormResources) Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error {
+	return r.s.session(ctx).Model(&dto.TenantResource{}).Where("resource_id = ?", id).Updates(nextRevision(changes)).Error
+}
+
+func (r gormResources) CompareAndUpdate(ctx context.Context, id uuid.UUID, revision int, changes map[string]interface{}) error {
+	result := r.s.session(ctx).Model(&dto.TenantResource{}).
+		Where("resource_id = ? AND revision = ? AND row_status = 1", id, revision).
+		Updates(nextRevision(changes))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+		return ErrConflict
+	}
+	return nil
+}
+
+// nextRevision returns changes advancing the revision column.
+func nextRevision(changes map[string]interface{}) map[string]interface{} {
+	next := make(map[string]interface{}, len(changes)+1)
+	for column, value := range changes {
+		next[column] = value
+	}
+	next["revision"] = gorm.Expr("revision + 1")
+	return next
+}
+
+func (r gormResources) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.Update(ctx, id, utils.UpdateDeletedMap())
+}
+
+func (r gormResources) GetType(ctx context.Context, id uuid.UUID) (*dto.Mst_ResourceTypes, error) {
+	var resourceType dto.Mst_ResourceTypes
+	if err := first(r.s.session(ctx), &resourceType, "resource_type_id = ? AND row_status = 1", id); err != nil {
+		return nil, err
+	}
+	return &resourceType, nil
+}
+
+func (r gormResources) GetTypeByName(ctx context.Context, name string) (*dto.Mst_ResourceTypes, error) {
+	var resourceType dto.Mst_ResourceTypes
+	if err := first(r.s.session(ctx), &resourceType, "name = ? AND row_status = 1", name); err != nil {
+		return nil, err
+	}
+	return &resourceType, nil
+}
+
+func (r gormResources) Labels(ctx context.Context, id uuid.UUID) (map[string]string, error) {
+	var rows []dto.TNTResourceLabel
+	if err := r.s.session(ctx).Where("resource_id = ? AND row_status = 1", id).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string, len(rows))
+	for _, row := range rows {
+		labels[row.Key] = row.Value
+	}
+	return labels, nil
+}
+
+type gormMetadata struct{ s *gormStore }
+
+func (r gormMetadata) Get(ctx context.Context, resourceID uuid.UUID) (*dto.TenantMetadata, error) {
+	var metadata dto.TenantMetadata
+	if err := first(r.s.session(ctx), &metadata, "resource_id = ? AND row_status = 1", resourceID); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+func (r gormMetadata) Create(ctx context.Context, metadata *dto.TenantMetadata) error {
+	return r.s.session(ctx).Create(metadata).Error
+}
+
+func (r gormMetadata) Update(ctx context.Context, resourceID uuid.UUID, document dto.JSON) error {
+	return r.s.session(ctx).Model(&dto.TenantMetadata{}).Where("resource_id = ?", resourceID).Updates(map[string]interface{}{"metadata": document}).Error
+}
+
+func (r gormMetadata) Delete(ctx context.Context, resourceID uuid.UUID) error {
+	return r.s.session(ctx).Model(&dto.TenantMetadata{}).Where("resource_id = ?", resourceID).Updates(utils.UpdateDeletedMap()).Error
+}
+
+type gormRoles struct{ s *gormStore }
+
+// visible restricts a query on tnt_roles to roles whose tnt_resources row
+// passes the tenant isolation of db, which must be a session.
+func visible(db *gorm.DB) *gorm.DB {
+	return db.Where("resource_id IN (?)", db.Model(&dto.TenantResource{}).Select("resource_id"))
+}
+
+func (r gormRoles) Get(ctx context.Context, id uuid.UUID) (*dto.TNTRole, error) {
+	var role dto.TNTRole
+	if err := first(visible(r.s.session(ctx)), &role, "resource_id = ? AND row_status = 1", id); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r gormRoles) List(ctx context.Context) ([]dto.TNTRole, error) {
+	var roles []dto.TNTRole
+	if err := visible(r.s.session(ctx)).Where("row_status = 1").Order("created_at").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r gormRoles) Create(ctx context.Context, role *dto.TNTRole) error {
+	return r.s.session(ctx).Create(role).Error
+}
+
+func (r gormRoles) Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error {
+	return visible(r.s.session(ctx)).Model(&dto.TNTRole{}).Where("resource_id = ? AND row_status = 1", id).Updates(changes).Error
+}
+
+func (r gormRoles) Delete(ctx context.Context, id uuid.UUID) error {
+	db := r.s.session(ctx)
+	if err := visible(db).Model(&dto.TNTRole{}).Where("resource_id = ?", id).Updates(utils.UpdateDeletedMap()).Error; err != nil {
+		return err
+	}
+	return db.Model(&dto.TNTRolePermission{}).Where("role_id = ?", id).Updates(utils.UpdateDeletedMap()).Error
+}
+
+func (r gormRoles) Permissions(ctx context.Context, roleID uuid.UUID) ([]dto.TNTRolePermission, error) {
+	var permissions []dto.TNTRolePermission
+	if err := r.s.session(ctx).Where("role_id = ? AND row_status = 1", roleID).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r gormRoles) AddPermission(ctx context.Context, permission *dto.TNTRolePermission) error {
+	return r.s.session(ctx).Create(permission).Error
+}
+
+func (r gormRoles) RemovePermission(ctx context.Context, id uuid.UUID) error {
+	return r.s.session(ctx).Model(&dto.TNTRolePermission{}).Where("role_permission_id = ?", id).Updates(utils.UpdateDeletedMap()).Error
+}
+
+func (r gormRoles) LatestRevision(ctx context.Context, roleID uuid.UUID) (int, error) {
+	var latest int
+	err := r.s.session(ctx).Model(&dto.TNTRoleRevision{}).Where("role_id = ?", roleID).Select("COALESCE(MAX(revision), 0)").Scan(&latest).Error
+	return latest, err
+}
+
+func (r gormRoles) CreateRevision(ctx context.Context, revision *dto.TNTRoleRevision) error {
+	result := r.s.session(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(revision)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (r gormRoles) GetRevision(ctx context.Context, roleID uuid.UUID, revision int) (*dto.TNTRoleRevision, error) {
+	var roleRevision dto.TNTRoleRevision
+	if err := first(r.s.session(ctx), &roleRevision, "role_id = ? AND revision = ?", roleID, revision); err != nil {
+		return nil, err
+	}
+	return &roleRevision, nil
+}
+
+func (r gormRoles) ListRevisions(ctx context.Context, roleID uuid.UUID) ([]dto.TNTRoleRevision, error) {
+	var revisions []dto.TNTRoleRevision
+	if err := r.s.session(ctx).Where("role_id = ?", roleID).Order("revision DESC").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+type gormPermissions struct{ s *gormStore }
+
+func (r gormPermissions) Get(ctx context.Context, id uuid.UUID) (*dto.MstPermission, error) {
+	var permission dto.MstPermission
+	if err := first(r.s.session(ctx), &permission, "permission_id = ? AND row_status = 1", id); err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+func (r gormPermissions) ListByResourceType(ctx context.Context, resourceTypeID uuid.UUID) ([]dto.MstPermission, error) {
+	var permissions []dto.MstPermission
+	if err := r.s.session(ctx).Where("resource_type_id = ? AND row_status = 1", resourceTypeID.String()).Order("action").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+type gormPrincipals struct{ s *gormStore }
+
+func (r gormPrincipals) Get(ctx context.Context, id uuid.UUID) (*dto.TenantPrincipals, error) {
+	var principal dto.TenantPrincipals
+	if err := first(r.s.session(ctx), &principal, "resource_id = ? AND row_status = 1", id); err != nil {
+		return nil, err
+	}
+	return &principal, nil
+}
+
+func (r gormPrincipals) GetByEmail(ctx context.Context, email string) (*dto.TenantPrincipals, error) {
+	var principal dto.TenantPrincipals
+	if err := first(r.s.session(ctx), &principal, "email = ? AND row_status = 1", email); err != nil {
+		return nil, err
+	}
+	return &principal, nil
+}
+
+func (r gormPrincipals) Create(ctx context.Context, principal *dto.TenantPrincipals) error {
+	return r.s.session(ctx).Create(principal).Error
+}
+
+func (r gormPrincipals) Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error {
+	return r.s.session(ctx).Model(&dto.TenantPrincipals{}).Where("resource_id = ?", id).Updates(changes).Error
+}
+
+func (r gormPrincipals) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.Update(ctx, id, utils.UpdateDeletedMap())
+}
+
+type gormAssignments struct{ s *gormStore }
+
+func (r gormAssignments) Get(ctx context.Context, id uuid.UUID) (*dto.TenantRoleAssignments, error) {
+	var assignment dto.TenantRoleAssignments
+	if err := first(r.s.session(ctx), &assignment, "resource_id = ? AND row_status = 1", id); err != nil {
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+func (r gormAssignments) ListByPrincipal(ctx context.Context, principalID uuid.UUID) ([]dto.TenantRoleAssignments, error) {
+	return r.list(ctx, "principal_id = ? AND row_status = 1", principalID)
+}
+
+func (r gormAssignments) ListByRole(ctx context.Context, roleID uuid.UUID) ([]dto.TenantRoleAssignments, error) {
+	return r.list(ctx, "role_id = ? AND row_status = 1", roleID)
+}
+
+func (r gormAssignments) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantRoleAssignments, error) {
+	return r.list(ctx, "tenant_id = ? AND row_status = 1", tenantID)
+}
+
+func (r gormAssignments) list(ctx context.Context, query string, args ...interface{}) ([]dto.TenantRoleAssignments, error) {
+	var assignments []dto.TenantRoleAssignments
+	if err := r.s.session(ctx).Where(query, args...).Order("created_at").Find(&assignments).Error; err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+func (r gormAssignments) Create(ctx context.Context, assignment *dto.TenantRoleAssignments) error {
+	return r.s.session(ctx).Create(assignment).Error
+}
+
+func (r gormAssignments) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.s.session(ctx).Model(&dto.TenantRoleAssignments{}).Where("resource_id = ?", id).Updates(utils.UpdateDeletedMap()).Error
+}
+
+type gormMembers struct{ s *gormStore }
+
+func (r gormMembers) ListByGroup(ctx context.Context, groupID uuid.UUID) ([]dto.GroupMember, error) {
+	return r.list(ctx, "group_id = ?", groupID)
+}
+
+func (r gormMembers) ListByMember(ctx context.Context, memberID uuid.UUID) ([]dto.GroupMember, error) {
+	return r.list(ctx, "member_id = ?", memberID)
+}
+
+func (r gormMembers) list(ctx context.Context, query string, args ...interface{}) ([]dto.GroupMember, error) {
+	var members []dto.GroupMember
+	if err := r.s.session(ctx).Where(query, args...).Order("created_at").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r gormMembers) Add(ctx context.Context, member *dto.GroupMember) error {
+	return r.s.session(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(member).Error
+}
+
+func (r gormMembers) Remove(ctx context.Context, groupID, memberID uuid.UUID) error {
+	return r.s.session(ctx).Where("group_id = ? AND member_id = ?", groupID, memberID).Delete(&dto.GroupMember{}).Error
+}
+
+type gormTenantStates struct{ s *gormStore }
+
+func (r gormTenantStates) Get(ctx context.Context, tenantID uuid.UUID) (*dto.TenantState, error) {
+	var state dto.TenantState
+	if err := first(r.s.session(ctx), &state, "tenant_id = ?", tenantID); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r gormTenantStates) Transition(ctx context.Context, revision int, transition *dto.TenantStateTransition) error {
+	return r.s.session(ctx).Transaction(func(tx *gorm.DB) error {
+		var result *gorm.DB
+		if revision == 0 {
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&dto.TenantState{
+				TenantID:  transition.TenantID,
+				Status:    transition.ToStatus,
+				Revision:  1,
+				UpdatedBy: transition.CreatedBy,
+			})
+		} else {
+			result = tx.Model(&dto.TenantState{}).
+				Where("tenant_id = ? AND revision = ?", transition.TenantID, revision).
+				Updates(map[string]interface{}{
+					"status":     transition.ToStatus,
+					"revision":   gorm.Expr("revision + 1"),
+					"updated_by": transition.CreatedBy,
+				})
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrConflict
+		}
+		transition.Revision = revision + 1
+		return tx.Create(transition).Error
+	})
+}
+
+func (r gormTenantStates) ListTransitions(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantStateTransition, error) {
+	var transitions []dto.TenantStateTransition
+	if err := r.s.session(ctx).Where("tenant_id = ?", tenantID).Order("revision").Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+type gormApprovalPolicies struct{ s *gormStore }
+
+func (r gormApprovalPolicies) GetByRole(ctx context.Context, roleID uuid.UUID) (*dto.ApprovalPolicy, error) {
+	var policy dto.ApprovalPolicy
+	if err := first(r.s.session(ctx), &policy, "role_id = ? AND row_status = 1", roleID); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
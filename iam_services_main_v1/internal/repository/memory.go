@@ -0,0 +1,834 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/schema"
+)
+
+// MemoryStore is a Store keeping records in memory, for tests. It applies the
+// same tenant isolation as the GORM store. Transactions work on a copy of the
+// records and are not isolated from each other.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data memoryData
+}
+
+type memoryData struct {
+	resources       map[uuid.UUID]dto.TenantResource
+	resourceTypes   map[uuid.UUID]dto.Mst_ResourceTypes
+	labels          map[uuid.UUID]map[string]string
+	metadata        map[uuid.UUID]dto.TenantMetadata
+	roles           map[uuid.UUID]dto.TNTRole
+	rolePermissions map[uuid.UUID]dto.TNTRolePermission
+	revisions       []dto.TNTRoleRevision
+	permissions     map[uuid.UUID]dto.MstPermission
+	principals      map[uuid.UUID]dto.TenantPrincipals
+	assignments     map[uuid.UUID]dto.TenantRoleAssignments
+	members         []dto.GroupMember
+	tenantStates    map[uuid.UUID]dto.TenantState
+	transitions     []dto.TenantStateTransition
+	policies        map[uuid.UUID]dto.ApprovalPolicy
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: memoryData{
+		resources:       map[uuid.UUID]dto.TenantResource{},
+		resourceTypes:   map[uuid.UUID]dto.Mst_ResourceTypes{},
+		labels:          map[uuid.UUID]map[string]string{},
+		metadata:        map[uuid.UUID]dto.TenantMetadata{},
+		roles:           map[uuid.UUID]dto.TNTRole{},
+		rolePermissions: map[uuid.UUID]dto.TNTRolePermission{},
+		permissions:     map[uuid.UUID]dto.MstPermission{},
+		principals:      map[uuid.UUID]dto.TenantPrincipals{},
+		assignments:     map[uuid.UUID]dto.TenantRoleAssignments{},
+		tenantStates:    map[uuid.UUID]dto.TenantState{},
+		policies:        map[uuid.UUID]dto.ApprovalPolicy{},
+	}}
+}
+
+// AddResourceType registers a resource type, which the Store cannot create.
+func (s *MemoryStore) AddResourceType(resourceType dto.Mst_ResourceTypes) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if resourceType.RowStatus == 0 {
+		resourceType.RowStatus = 1
+	}
+	s.data.resourceTypes[resourceType.ResourceTypeID] = resourceType
+}
+
+// AddPermission registers a permission in the catalog.
+func (s *MemoryStore) AddPermission(permission dto.MstPermission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if permission.RowStatus == 0 {
+		permission.RowStatus = 1
+	}
+	s.data.permissions[permission.PermissionID] = permission
+}
+
+// AddApprovalPolicy stores an approval policy, which the Store cannot create.
+func (s *MemoryStore) AddApprovalPolicy(policy dto.ApprovalPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if policy.RowStatus == 0 {
+		policy.RowStatus = 1
+	}
+	s.data.policies[policy.PolicyID] = policy
+}
+
+// SetLabels replaces the labels of a resource.
+func (s *MemoryStore) SetLabels(resourceID uuid.UUID, labels map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.labels[resourceID] = copyLabels(labels)
+}
+
+func (s *MemoryStore) Resources() ResourceRepository              { return memoryResources{s} }
+func (s *MemoryStore) Metadata() MetadataRepository               { return memoryMetadata{s} }
+func (s *MemoryStore) Roles() RoleRepository                      { return memoryRoles{s} }
+func (s *MemoryStore) Permissions() PermissionRepository          { return memoryPermissions{s} }
+func (s *MemoryStore) Principals() PrincipalRepository            { return memoryPrincipals{s} }
+func (s *MemoryStore) Assignments() AssignmentRepository          { return memoryAssignments{s} }
+func (s *MemoryStore) Members() MembershipRepository              { return memoryMembers{s} }
+func (s *MemoryStore) TenantStates() TenantStateRepository        { return memoryTenantStates{s} }
+func (s *MemoryStore) ApprovalPolicies() ApprovalPolicyRepository { return memoryApprovalPolicies{s} }
+
+func (s *MemoryStore) Transaction(ctx context.Context, fn func(Store) error) error {
+	s.mu.Lock()
+	tx := &MemoryStore{data: s.data.clone()}
+	s.mu.Unlock()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = tx.data
+	return nil
+}
+
+func (d memoryData) clone() memoryData {
+	labels := make(map[uuid.UUID]map[string]string, len(d.labels))
+	for id, l := range d.labels {
+		labels[id] = copyLabels(l)
+	}
+	return memoryData{
+		resources:       copyMap(d.resources),
+		resourceTypes:   copyMap(d.resourceTypes),
+		labels:          labels,
+		metadata:        copyMap(d.metadata),
+		roles:           copyMap(d.roles),
+		rolePermissions: copyMap(d.rolePermissions),
+		revisions:       append([]dto.TNTRoleRevision(nil), d.revisions...),
+		permissions:     copyMap(d.permissions),
+		principals:      copyMap(d.principals),
+		assignments:     copyMap(d.assignments),
+		members:         append([]dto.GroupMember(nil), d.members...),
+		tenantStates:    copyMap(d.tenantStates),
+		transitions:     append([]dto.TenantStateTransition(nil), d.transitions...),
+		policies:        copyMap(d.policies),
+	}
+}
+
+func copyMap[V any](m map[uuid.UUID]V) map[uuid.UUID]V {
+	c := make(map[uuid.UUID]V, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	c := make(map[string]string, len(labels))
+	for k, v := range labels {
+		c[k] = v
+	}
+	return c
+}
+
+// visibleTo reports whether a row of tenantID passes the isolation of the
+// request's tenant.
+func visibleTo(ctx context.Context, tenantID *uuid.UUID) bool {
+	requestTenant := tenancy.TenantFromContext(ctx)
+	return requestTenant == nil || (tenantID != nil && *tenantID == *requestTenant)
+}
+
+var schemas sync.Map
+
+// applyChanges sets the fields of model named by the column names in changes,
+// as GORM's Updates does, and bumps updated_at.
+func applyChanges(model interface{}, changes map[string]interface{}) error {
+	s, err := schema.Parse(model, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(model).Elem()
+	for column, change := range changes {
+		field := s.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("unknown column %s of %s", column, s.Table)
+		}
+		if err := field.Set(context.Background(), value, change); err != nil {
+			return err
+		}
+	}
+	if _, ok := changes["updated_at"]; !ok {
+		if field := s.LookUpField("updated_at"); field != nil {
+			return field.Set(context.Background(), value, time.Now())
+		}
+	}
+	return nil
+}
+
+// tenantChange rejects changes moving a row out of the request's tenant.
+func tenantChange(ctx context.Context, changes map[string]interface{}) error {
+	change, ok := changes[tenancy.Column]
+	if !ok {
+		return nil
+	}
+	requestTenant := tenancy.TenantFromContext(ctx)
+	if requestTenant == nil {
+		return nil
+	}
+	switch tenantID := change.(type) {
+	case uuid.UUID:
+		if tenantID == *requestTenant {
+			return nil
+		}
+	case *uuid.UUID:
+		if tenantID != nil && *tenantID == *requestTenant {
+			return nil
+		}
+	}
+	return tenancy.ErrCrossTenantWrite
+}
+
+func createdNow(createdAt, updatedAt *time.Time) {
+	now := time.Now()
+	if createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt != nil && updatedAt.IsZero() {
+		*updatedAt = now
+	}
+}
+
+func activeStatus(rowStatus *int) {
+	if *rowStatus == 0 {
+		*rowStatus = 1
+	}
+}
+
+type memoryResources struct{ s *MemoryStore }
+
+func (r memoryResources) Get(ctx context.Context, id uuid.UUID) (*dto.TenantResource, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	resource, ok := r.s.data.resources[id]
+	if !ok || resource.RowStatus != 1 || !visibleTo(ctx, resource.TenantID) {
+		return nil, ErrNotFound
+	}
+	return &resource, nil
+}
+
+func (r memoryResources) ListByType(ctx context.Context, resourceTypeID uuid.UUID) ([]dto.TenantResource, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var resources []dto.TenantResource
+	for _, resource := range r.s.data.resources {
+		if resource.ResourceTypeID == resourceTypeID && resource.RowStatus == 1 && visibleTo(ctx, resource.TenantID) {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].CreatedAt.Before(resources[j].CreatedAt) })
+	return resources, nil
+}
+
+func (r memoryResources) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantResource, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var resources []dto.TenantResource
+	for _, resource := range r.s.data.resources {
+		if resource.TenantID != nil && *resource.TenantID == tenantID && resource.RowStatus == 1 && visibleTo(ctx, resource.TenantID) {
+			resources = append(resources, resource)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool { return resources[i].CreatedAt.Before(resources[j].CreatedAt) })
+	return resources, nil
+}
+
+func (r memoryResources) Create(ctx context.Context, resource *dto.TenantResource) error {
+	if requestTenant := tenancy.TenantFromContext(ctx); requestTenant != nil {
+		if resource.TenantID == nil {
+			tenantID := *requestTenant
+			resource.TenantID = &tenantID
+		} else if *resource.TenantID != *requestTenant {
+			return tenancy.ErrCrossTenantWrite
+		}
+	}
+	activeStatus(&resource.RowStatus)
+	activeStatus(&resource.Revision)
+	createdNow(&resource.CreatedAt, &resource.UpdatedAt)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.data.resources[resource.ResourceID]; ok {
+		return fmt.Errorf("resource %s already exists", resource.ResourceID)
+	}
+	r.s.data.resources[resource.ResourceID] = *resource
+	return nil
+}
+
+func (r memoryResources) Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error {
+	return r.update(ctx, id, nil, changes)
+}
+
+func (r memoryResources) CompareAndUpdate(ctx context.Context, id uuid.UUID, revision int, changes map[string]interface{}) error {
+	return r.update(ctx, id, &revision, changes)
+}
+
+// update applies changes to a visible resource, requiring it to be active and
+// at revision unless revision is nil.
+func (r memoryResources) update(ctx context.Context, id uuid.UUID, revision *int, changes map[string]interface{}) error {
+	if err := tenantChange(ctx, changes); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	resource, ok := r.s.data.resources[id]
+	if !ok || !visibleTo(ctx, resource.TenantID) {
+		if revision != nil {
+			return ErrNotFound
+		}
+		return nil
+	}
+	if revision != nil {
+		if resource.RowStatus != 1 {
+			return ErrNotFound
+		}
+		if resource.Revision != *revision {
+			return ErrConflict
+		}
+	}
+	if err := applyChanges(&resource, changes); err != nil {
+		return err
+	}
+	resource.Revision++
+	r.s.data.resources[id] = resource
+	return nil
+}
+
+func (r memoryResources) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.Update(ctx, id, map[string]interface{}{"row_status": 0})
+}
+
+func (r memoryResources) GetType(ctx context.Context, id uuid.UUID) (*dto.Mst_ResourceTypes, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	resourceType, ok := r.s.data.resourceTypes[id]
+	if !ok || resourceType.RowStatus != 1 {
+		return nil, ErrNotFound
+	}
+	return &resourceType, nil
+}
+
+func (r memoryResources) GetTypeByName(ctx context.Context, name string) (*dto.Mst_ResourceTypes, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, resourceType := range r.s.data.resourceTypes {
+		if resourceType.Name == name && resourceType.RowStatus == 1 {
+			return &resourceType, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryResources) Labels(ctx context.Context, id uuid.UUID) (map[string]string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	return copyLabels(r.s.data.labels[id]), nil
+}
+
+type memoryMetadata struct{ s *MemoryStore }
+
+func (r memoryMetadata) Get(ctx context.Context, resourceID uuid.UUID) (*dto.TenantMetadata, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	metadata, ok := r.s.data.metadata[resourceID]
+	if !ok || metadata.RowStatus != 1 {
+		return nil, ErrNotFound
+	}
+	return &metadata, nil
+}
+
+func (r memoryMetadata) Create(ctx context.Context, metadata *dto.TenantMetadata) error {
+	activeStatus(&metadata.RowStatus)
+	createdNow(&metadata.CreatedAt, &metadata.UpdatedAt)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.data.metadata[metadata.ResourceID] = *metadata
+	return nil
+}
+
+func (r memoryMetadata) Update(ctx context.Context, resourceID uuid.UUID, document dto.JSON) error {
+	return r.update(resourceID, map[string]interface{}{"metadata": document})
+}
+
+func (r memoryMetadata) Delete(ctx context.Context, resourceID uuid.UUID) error {
+	return r.update(resourceID, map[string]interface{}{"row_status": 0})
+}
+
+func (r memoryMetadata) update(resourceID uuid.UUID, changes map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	metadata, ok := r.s.data.metadata[resourceID]
+	if !ok {
+		return nil
+	}
+	if err := applyChanges(&metadata, changes); err != nil {
+		return err
+	}
+	r.s.data.metadata[resourceID] = metadata
+	return nil
+}
+
+type memoryRoles struct{ s *MemoryStore }
+
+// visible reports whether the tnt_resources row of a role passes the
+// request's tenant isolation. The caller holds the lock.
+func (r memoryRoles) visible(ctx context.Context, id uuid.UUID) bool {
+	resource, ok := r.s.data.resources[id]
+	return ok && visibleTo(ctx, resource.TenantID)
+}
+
+func (r memoryRoles) Get(ctx context.Context, id uuid.UUID) (*dto.TNTRole, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.data.roles[id]
+	if !ok || role.RowStatus != 1 || !r.visible(ctx, id) {
+		return nil, ErrNotFound
+	}
+	return &role, nil
+}
+
+func (r memoryRoles) List(ctx context.Context) ([]dto.TNTRole, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var roles []dto.TNTRole
+	for id, role := range r.s.data.roles {
+		if role.RowStatus == 1 && r.visible(ctx, id) {
+			roles = append(roles, role)
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].CreatedAt.Before(roles[j].CreatedAt) })
+	return roles, nil
+}
+
+func (r memoryRoles) Create(ctx context.Context, role *dto.TNTRole) error {
+	createdNow(&role.CreatedAt, &role.UpdatedAt)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.data.roles[role.ResourceID]; ok {
+		return fmt.Errorf("role %s already exists", role.ResourceID)
+	}
+	r.s.data.roles[role.ResourceID] = *role
+	return nil
+}
+
+func (r memoryRoles) Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.data.roles[id]
+	if !ok || role.RowStatus != 1 || !r.visible(ctx, id) {
+		return nil
+	}
+	if err := applyChanges(&role, changes); err != nil {
+		return err
+	}
+	r.s.data.roles[id] = role
+	return nil
+}
+
+func (r memoryRoles) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	role, ok := r.s.data.roles[id]
+	if !ok || !r.visible(ctx, id) {
+		return nil
+	}
+	role.RowStatus = 0
+	role.UpdatedAt = time.Now()
+	r.s.data.roles[id] = role
+	for permissionID, permission := range r.s.data.rolePermissions {
+		if permission.RoleID == id {
+			permission.RowStatus = 0
+			permission.UpdatedAt = time.Now()
+			r.s.data.rolePermissions[permissionID] = permission
+		}
+	}
+	return nil
+}
+
+func (r memoryRoles) Permissions(ctx context.Context, roleID uuid.UUID) ([]dto.TNTRolePermission, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var permissions []dto.TNTRolePermission
+	for _, permission := range r.s.data.rolePermissions {
+		if permission.RoleID == roleID && permission.RowStatus == 1 {
+			permissions = append(permissions, permission)
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].CreatedAt.Before(permissions[j].CreatedAt)
+	})
+	return permissions, nil
+}
+
+func (r memoryRoles) AddPermission(ctx context.Context, permission *dto.TNTRolePermission) error {
+	activeStatus(&permission.RowStatus)
+	createdNow(&permission.CreatedAt, &permission.UpdatedAt)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.data.rolePermissions[permission.ID] = *permission
+	return nil
+}
+
+func (r memoryRoles) RemovePermission(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	permission, ok := r.s.data.rolePermissions[id]
+	if !ok {
+		return nil
+	}
+	permission.RowStatus = 0
+	permission.UpdatedAt = time.Now()
+	r.s.data.rolePermissions[id] = permission
+	return nil
+}
+
+func (r memoryRoles) LatestRevision(ctx context.Context, roleID uuid.UUID) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	latest := 0
+	for _, revision := range r.s.data.revisions {
+		if revision.RoleID == roleID && revision.Revision > latest {
+			latest = revision.Revision
+		}
+	}
+	return latest, nil
+}
+
+func (r memoryRoles) CreateRevision(ctx context.Context, revision *dto.TNTRoleRevision) error {
+	createdNow(&revision.CreatedAt, nil)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.data.revisions {
+		if existing.RoleID == revision.RoleID && existing.Revision == revision.Revision {
+			return ErrConflict
+		}
+	}
+	r.s.data.revisions = append(r.s.data.revisions, *revision)
+	return nil
+}
+
+func (r memoryRoles) GetRevision(ctx context.Context, roleID uuid.UUID, revision int) (*dto.TNTRoleRevision, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.data.revisions {
+		if existing.RoleID == roleID && existing.Revision == revision {
+			return &existing, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryRoles) ListRevisions(ctx context.Context, roleID uuid.UUID) ([]dto.TNTRoleRevision, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var revisions []dto.TNTRoleRevision
+	for _, revision := range r.s.data.revisions {
+		if revision.RoleID == roleID {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+	return revisions, nil
+}
+
+type memoryPermissions struct{ s *MemoryStore }
+
+func (r memoryPermissions) Get(ctx context.Context, id uuid.UUID) (*dto.MstPermission, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	permission, ok := r.s.data.permissions[id]
+	if !ok || permission.RowStatus != 1 {
+		return nil, ErrNotFound
+	}
+	return &permission, nil
+}
+
+func (r memoryPermissions) ListByResourceType(ctx context.Context, resourceTypeID uuid.UUID) ([]dto.MstPermission, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var permissions []dto.MstPermission
+	for _, permission := range r.s.data.permissions {
+		if permission.ResourceTypeID == resourceTypeID.String() && permission.RowStatus == 1 {
+			permissions = append(permissions, permission)
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].Action < permissions[j].Action })
+	return permissions, nil
+}
+
+type memoryPrincipals struct{ s *MemoryStore }
+
+func (r memoryPrincipals) Get(ctx context.Context, id uuid.UUID) (*dto.TenantPrincipals, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	principal, ok := r.s.data.principals[id]
+	if !ok || principal.RowStatus != 1 {
+		return nil, ErrNotFound
+	}
+	return &principal, nil
+}
+
+func (r memoryPrincipals) GetByEmail(ctx context.Context, email string) (*dto.TenantPrincipals, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, principal := range r.s.data.principals {
+		if strings.EqualFold(principal.Email, email) && principal.RowStatus == 1 {
+			return &principal, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r memoryPrincipals) Create(ctx context.Context, principal *dto.TenantPrincipals) error {
+	activeStatus(&principal.RowStatus)
+	createdNow(&principal.CreatedAt, &principal.UpdatedAt)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.data.principals[principal.ResourceID]; ok {
+		return fmt.Errorf("principal %s already exists", principal.ResourceID)
+	}
+	r.s.data.principals[principal.ResourceID] = *principal
+	return nil
+}
+
+func (r memoryPrincipals) Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	principal, ok := r.s.data.principals[id]
+	if !ok {
+		return nil
+	}
+	if err := applyChanges(&principal, changes); err != nil {
+		return err
+	}
+	r.s.data.principals[id] = principal
+	return nil
+}
+
+func (r memoryPrincipals) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.Update(ctx, id, map[string]interface{}{"row_status": 0})
+}
+
+type memoryAssignments struct{ s *MemoryStore }
+
+func (r memoryAssignments) Get(ctx context.Context, id uuid.UUID) (*dto.TenantRoleAssignments, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	assignment, ok := r.s.data.assignments[id]
+	if !ok || assignment.RowStatus != 1 || !visibleTo(ctx, assignment.TenantID) {
+		return nil, ErrNotFound
+	}
+	return &assignment, nil
+}
+
+func (r memoryAssignments) ListByPrincipal(ctx context.Context, principalID uuid.UUID) ([]dto.TenantRoleAssignments, error) {
+	return r.list(ctx, func(a dto.TenantRoleAssignments) bool { return a.PrincipalID == principalID }), nil
+}
+
+func (r memoryAssignments) ListByRole(ctx context.Context, roleID uuid.UUID) ([]dto.TenantRoleAssignments, error) {
+	return r.list(ctx, func(a dto.TenantRoleAssignments) bool { return a.RoleID == roleID }), nil
+}
+
+func (r memoryAssignments) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantRoleAssignments, error) {
+	return r.list(ctx, func(a dto.TenantRoleAssignments) bool { return a.TenantID != nil && *a.TenantID == tenantID }), nil
+}
+
+func (r memoryAssignments) list(ctx context.Context, match func(dto.TenantRoleAssignments) bool) []dto.TenantRoleAssignments {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var assignments []dto.TenantRoleAssignments
+	for _, assignment := range r.s.data.assignments {
+		if assignment.RowStatus == 1 && visibleTo(ctx, assignment.TenantID) && match(assignment) {
+			assignments = append(assignments, assignment)
+		}
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].CreatedAt.Before(assignments[j].CreatedAt) })
+	return assignments
+}
+
+func (r memoryAssignments) Create(ctx context.Context, assignment *dto.TenantRoleAssignments) error {
+	if requestTenant := tenancy.TenantFromContext(ctx); requestTenant != nil {
+		if assignment.TenantID == nil {
+			tenantID := *requestTenant
+			assignment.TenantID = &tenantID
+		} else if *assignment.TenantID != *requestTenant {
+			return tenancy.ErrCrossTenantWrite
+		}
+	}
+	activeStatus(&assignment.RowStatus)
+	createdNow(&assignment.CreatedAt, &assignment.UpdatedAt)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if _, ok := r.s.data.assignments[assignment.ResourceID]; ok {
+		return fmt.Errorf("assignment %s already exists", assignment.ResourceID)
+	}
+	r.s.data.assignments[assignment.ResourceID] = *assignment
+	return nil
+}
+
+func (r memoryAssignments) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	assignment, ok := r.s.data.assignments[id]
+	if !ok || !visibleTo(ctx, assignment.TenantID) {
+		return nil
+	}
+	assignment.RowStatus = 0
+	assignment.UpdatedAt = time.Now()
+	r.s.data.assignments[id] = assignment
+	return nil
+}
+
+type memoryMembers struct{ s *MemoryStore }
+
+func (r memoryMembers) ListByGroup(ctx context.Context, groupID uuid.UUID) ([]dto.GroupMember, error) {
+	return r.list(ctx, func(m dto.GroupMember) bool { return m.GroupID == groupID }), nil
+}
+
+func (r memoryMembers) ListByMember(ctx context.Context, memberID uuid.UUID) ([]dto.GroupMember, error) {
+	return r.list(ctx, func(m dto.GroupMember) bool { return m.MemberID == memberID }), nil
+}
+
+// list returns the matching memberships in the order they were added.
+func (r memoryMembers) list(ctx context.Context, match func(dto.GroupMember) bool) []dto.GroupMember {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var members []dto.GroupMember
+	for _, member := range r.s.data.members {
+		if visibleTo(ctx, member.TenantID) && match(member) {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+func (r memoryMembers) Add(ctx context.Context, member *dto.GroupMember) error {
+	if requestTenant := tenancy.TenantFromContext(ctx); requestTenant != nil {
+		if member.TenantID == nil {
+			tenantID := *requestTenant
+			member.TenantID = &tenantID
+		} else if *member.TenantID != *requestTenant {
+			return tenancy.ErrCrossTenantWrite
+		}
+	}
+	createdNow(&member.CreatedAt, nil)
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, existing := range r.s.data.members {
+		if existing.GroupID == member.GroupID && existing.MemberID == member.MemberID {
+			return nil
+		}
+	}
+	r.s.data.members = append(r.s.data.members, *member)
+	return nil
+}
+
+func (r memoryMembers) Remove(ctx context.Context, groupID, memberID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	r.s.data.members = slices.DeleteFunc(r.s.data.members, func(m dto.GroupMember) bool {
+		return m.GroupID == groupID && m.MemberID == memberID && visibleTo(ctx, m.TenantID)
+	})
+	return nil
+}
+
+type memoryTenantStates struct{ s *MemoryStore }
+
+func (r memoryTenantStates) Get(ctx context.Context, tenantID uuid.UUID) (*dto.TenantState, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	state, ok := r.s.data.tenantStates[tenantID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &state, nil
+}
+
+func (r memoryTenantStates) Transition(ctx context.Context, revision int, transition *dto.TenantStateTransition) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.s.data.tenantStates[transition.TenantID].Revision != revision {
+		return ErrConflict
+	}
+	transition.Revision = revision + 1
+	createdNow(&transition.CreatedAt, nil)
+	r.s.data.tenantStates[transition.TenantID] = dto.TenantState{
+		TenantID:  transition.TenantID,
+		Status:    transition.ToStatus,
+		Revision:  transition.Revision,
+		UpdatedBy: transition.CreatedBy,
+		UpdatedAt: transition.CreatedAt,
+	}
+	r.s.data.transitions = append(r.s.data.transitions, *transition)
+	return nil
+}
+
+func (r memoryTenantStates) ListTransitions(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantStateTransition, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var transitions []dto.TenantStateTransition
+	for _, transition := range r.s.data.transitions {
+		if transition.TenantID == tenantID {
+			transitions = append(transitions, transition)
+		}
+	}
+	return transitions, nil
+}
+
+type memoryApprovalPolicies struct{ s *MemoryStore }
+
+func (r memoryApprovalPolicies) GetByRole(ctx context.Context, roleID uuid.UUID) (*dto.ApprovalPolicy, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for _, policy := range r.s.data.policies {
+		if policy.RoleID == roleID && policy.RowStatus == 1 && visibleTo(ctx, policy.TenantID) {
+			return &policy, nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,186 @@
+// Package repository gives resolvers access to stored aggregates through
+// interfaces, with a GORM implementation for the server and an in-memory one
+// for tests. Every method takes the request context: the GORM implementation
+// scopes tenant-scoped tables to the request's tenant, and the in-memory one
+// mirrors that isolation.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"iam_services_main_v1/internal/dto"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotFound is returned when no active record matches a lookup.
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a record changed since the revision a caller
+// expected.
+var ErrConflict = errors.New("record was modified concurrently")
+
+// Store gives access to the repositories of each aggregate.
+type Store interface {
+	Resources() ResourceRepository
+	Metadata() MetadataRepository
+	Roles() RoleRepository
+	Permissions() PermissionRepository
+	Principals() PrincipalRepository
+	Assignments() AssignmentRepository
+	Members() MembershipRepository
+	TenantStates() TenantStateRepository
+	ApprovalPolicies() ApprovalPolicyRepository
+
+	// Transaction runs fn on a Store whose changes are committed when fn
+	// returns nil and discarded otherwise.
+	Transaction(ctx context.Context, fn func(Store) error) error
+}
+
+// ResourceRepository stores tnt_resources rows and the resource types and
+// labels describing them.
+type ResourceRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*dto.TenantResource, error)
+	// ListByType returns the active resources of a type, oldest first.
+	ListByType(ctx context.Context, resourceTypeID uuid.UUID) ([]dto.TenantResource, error)
+	// ListByTenant returns the active resources of a tenant, including the
+	// tenant itself, oldest first.
+	ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantResource, error)
+	Create(ctx context.Context, resource *dto.TenantResource) error
+	// Update applies changes keyed by column name and advances the
+	// resource's revision.
+	Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error
+	// CompareAndUpdate is Update for a resource still at revision. It returns
+	// ErrConflict when the resource has another revision and ErrNotFound when
+	// it does not exist.
+	CompareAndUpdate(ctx context.Context, id uuid.UUID, revision int, changes map[string]interface{}) error
+	// Delete marks the resource deleted.
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	GetType(ctx context.Context, id uuid.UUID) (*dto.Mst_ResourceTypes, error)
+	GetTypeByName(ctx context.Context, name string) (*dto.Mst_ResourceTypes, error)
+	Labels(ctx context.Context, id uuid.UUID) (map[string]string, error)
+}
+
+// MetadataRepository stores the metadata document of each resource.
+type MetadataRepository interface {
+	Get(ctx context.Context, resourceID uuid.UUID) (*dto.TenantMetadata, error)
+	Create(ctx context.Context, metadata *dto.TenantMetadata) error
+	Update(ctx context.Context, resourceID uuid.UUID, document dto.JSON) error
+	Delete(ctx context.Context, resourceID uuid.UUID) error
+}
+
+// RoleRepository stores roles, their permission grants and their revision
+// history. Roles are visible when their tnt_resources row is.
+type RoleRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*dto.TNTRole, error)
+	// List returns the active roles, oldest first.
+	List(ctx context.Context) ([]dto.TNTRole, error)
+	Create(ctx context.Context, role *dto.TNTRole) error
+	Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error
+	// Delete marks the role and its permission grants deleted.
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	Permissions(ctx context.Context, roleID uuid.UUID) ([]dto.TNTRolePermission, error)
+	AddPermission(ctx context.Context, permission *dto.TNTRolePermission) error
+	RemovePermission(ctx context.Context, id uuid.UUID) error
+
+	// LatestRevision returns the newest revision number of a role, or 0.
+	LatestRevision(ctx context.Context, roleID uuid.UUID) (int, error)
+	// CreateRevision returns ErrConflict when the role already has a revision
+	// with the same number.
+	CreateRevision(ctx context.Context, revision *dto.TNTRoleRevision) error
+	GetRevision(ctx context.Context, roleID uuid.UUID, revision int) (*dto.TNTRoleRevision, error)
+	// ListRevisions returns the revisions of a role, newest first.
+	ListRevisions(ctx context.Context, roleID uuid.UUID) ([]dto.TNTRoleRevision, error)
+}
+
+// PermissionRepository reads the permission catalog.
+type PermissionRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*dto.MstPermission, error)
+	ListByResourceType(ctx context.Context, resourceTypeID uuid.UUID) ([]dto.MstPermission, error)
+}
+
+// PrincipalRepository stores users and groups.
+type PrincipalRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*dto.TenantPrincipals, error)
+	GetByEmail(ctx context.Context, email string) (*dto.TenantPrincipals, error)
+	Create(ctx context.Context, principal *dto.TenantPrincipals) error
+	Update(ctx context.Context, id uuid.UUID, changes map[string]interface{}) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// AssignmentRepository stores role bindings.
+type AssignmentRepository interface {
+	Get(ctx context.Context, id uuid.UUID) (*dto.TenantRoleAssignments, error)
+	ListByPrincipal(ctx context.Context, principalID uuid.UUID) ([]dto.TenantRoleAssignments, error)
+	ListByRole(ctx context.Context, roleID uuid.UUID) ([]dto.TenantRoleAssignments, error)
+	// ListByTenant returns the bindings granted within a tenant.
+	ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantRoleAssignments, error)
+	Create(ctx context.Context, assignment *dto.TenantRoleAssignments) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// MembershipRepository stores the members of groups. Memberships belong to
+// the tenant of their group.
+type MembershipRepository interface {
+	// ListByGroup returns the memberships of a group, oldest first.
+	ListByGroup(ctx context.Context, groupID uuid.UUID) ([]dto.GroupMember, error)
+	// ListByMember returns the memberships of a user or group, oldest first.
+	ListByMember(ctx context.Context, memberID uuid.UUID) ([]dto.GroupMember, error)
+	// Add creates a membership, doing nothing when it exists.
+	Add(ctx context.Context, member *dto.GroupMember) error
+	Remove(ctx context.Context, groupID, memberID uuid.UUID) error
+}
+
+// TenantStateRepository stores the lifecycle status of tenants and the
+// history of its transitions.
+type TenantStateRepository interface {
+	// Get returns the state of a tenant, or ErrNotFound when none was
+	// recorded.
+	Get(ctx context.Context, tenantID uuid.UUID) (*dto.TenantState, error)
+	// Transition moves a tenant still at revision to transition.ToStatus and
+	// records transition. Revision 0 stands for a tenant without a recorded
+	// state. The transition takes the tenant's new revision. It returns
+	// ErrConflict when the tenant has another revision.
+	Transition(ctx context.Context, revision int, transition *dto.TenantStateTransition) error
+	// ListTransitions returns the transitions of a tenant, oldest first.
+	ListTransitions(ctx context.Context, tenantID uuid.UUID) ([]dto.TenantStateTransition, error)
+}
+
+// ApprovalPolicyRepository reads the approval policies of roles. Policies
+// belong to the tenant of their role.
+type ApprovalPolicyRepository interface {
+	// GetByRole returns the active policy of a role, or ErrNotFound.
+	GetByRole(ctx context.Context, roleID uuid.UUID) (*dto.ApprovalPolicy, error)
+}
+
+// UpdateResource applies changes to a resource with Update, or with
+// CompareAndUpdate when expectedRevision is set.
+func UpdateResource(ctx context.Context, resources ResourceRepository, id uuid.UUID, expectedRevision *int, changes map[string]interface{}) error {
+	if expectedRevision == nil {
+		return resources.Update(ctx, id, changes)
+	}
+	return resources.CompareAndUpdate(ctx, id, *expectedRevision, changes)
+}
+
+// CurrentTenantState returns the state of a tenant. Tenants without a
+// recorded state predate lifecycle states and are active at revision 0.
+func CurrentTenantState(ctx context.Context, states TenantStateRepository, tenantID uuid.UUID) (*dto.TenantState, error) {
+	state, err := states.Get(ctx, tenantID)
+	if errors.Is(err, ErrNotFound) {
+		return &dto.TenantState{TenantID: tenantID, Status: dto.TenantStatusActive}, nil
+	}
+	return state, err
+}
+
+// RequiresApproval reports whether a role has an active approval policy, so
+// that it is only granted through approved access requests.
+func RequiresApproval(ctx context.Context, policies ApprovalPolicyRepository, roleID uuid.UUID) (bool, error) {
+	_, err := policies.GetByRole(ctx, roleID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,386 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/tenancy"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.TenantResource{}, &dto.TNTResourceLabel{}, &dto.Mst_ResourceTypes{}, &dto.TenantMetadata{},
+		&dto.TNTRole{}, &dto.TNTRolePermission{}, &dto.TNTRoleRevision{}, &dto.MstPermission{},
+		&dto.TenantPrincipals{}, &dto.TenantRoleAssignments{}, &dto.GroupMember{}, &dto.TenantState{}, &dto.TenantStateTransition{}, &dto.ApprovalPolicy{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("Failed to register tenancy plugin: %v", err)
+	}
+	return db
+}
+
+// forEachStore runs test against the GORM and the in-memory Store.
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("gorm", func(t *testing.T) {
+		test(t, NewGormStore(setupTestDB(t)))
+	})
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+}
+
+func createResource(t *testing.T, store Store, tenantID *uuid.UUID, name string) uuid.UUID {
+	id := uuid.New()
+	require.NoError(t, store.Resources().Create(tenancy.AsRoot(context.Background()), &dto.TenantResource{
+		ResourceID: id, ResourceTypeID: uuid.New(), Name: name, TenantID: tenantID, RowStatus: 1,
+	}))
+	return id
+}
+
+func TestResourcesAreTenantScoped(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		acme, globex := uuid.New(), uuid.New()
+		acmeResource := createResource(t, store, &acme, "acme")
+		globexResource := createResource(t, store, &globex, "globex")
+		ctx := tenancy.WithTenant(context.Background(), acme)
+
+		resource, err := store.Resources().Get(ctx, acmeResource)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", resource.Name)
+
+		_, err = store.Resources().Get(ctx, globexResource)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		// Updates of another tenant's rows change nothing
+		require.NoError(t, store.Resources().Update(ctx, globexResource, map[string]interface{}{"name": "taken"}))
+		resource, err = store.Resources().Get(tenancy.AsRoot(ctx), globexResource)
+		require.NoError(t, err)
+		assert.Equal(t, "globex", resource.Name)
+
+		err = store.Resources().Create(ctx, &dto.TenantResource{ResourceID: uuid.New(), ResourceTypeID: uuid.New(), Name: "x", TenantID: &globex})
+		assert.ErrorIs(t, err, tenancy.ErrCrossTenantWrite)
+
+		// Rows created for a tenant's request default to its tenant
+		created := &dto.TenantResource{ResourceID: uuid.New(), ResourceTypeID: uuid.New(), Name: "child", RowStatus: 1}
+		require.NoError(t, store.Resources().Create(ctx, created))
+		require.NotNil(t, created.TenantID)
+		assert.Equal(t, acme, *created.TenantID)
+
+		require.NoError(t, store.Resources().Delete(ctx, acmeResource))
+		_, err = store.Resources().Get(ctx, acmeResource)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestResourceRevisions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		acme := uuid.New()
+		id := createResource(t, store, &acme, "acme")
+		ctx := tenancy.WithTenant(context.Background(), acme)
+
+		resource, err := store.Resources().Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, 1, resource.Revision)
+
+		require.NoError(t, store.Resources().Update(ctx, id, map[string]interface{}{"name": "renamed"}))
+		require.NoError(t, store.Resources().CompareAndUpdate(ctx, id, 2, map[string]interface{}{"name": "again"}))
+		resource, err = store.Resources().Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "again", resource.Name)
+		assert.Equal(t, 3, resource.Revision)
+
+		// A stale revision changes nothing
+		err = store.Resources().CompareAndUpdate(ctx, id, 2, map[string]interface{}{"name": "stale"})
+		assert.ErrorIs(t, err, ErrConflict)
+		resource, err = store.Resources().Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, "again", resource.Name)
+
+		err = store.Resources().CompareAndUpdate(ctx, uuid.New(), 1, map[string]interface{}{"name": "missing"})
+		assert.ErrorIs(t, err, ErrNotFound)
+		rootResource := createResource(t, store, nil, "root")
+		err = store.Resources().CompareAndUpdate(ctx, rootResource, 1, map[string]interface{}{"name": "hidden"})
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, store.Resources().Delete(ctx, id))
+		err = store.Resources().CompareAndUpdate(ctx, id, 4, map[string]interface{}{"name": "deleted"})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestRolesAreVisibleWithTheirResource(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		acme, globex := uuid.New(), uuid.New()
+		roleID := createResource(t, store, &globex, "viewer")
+		root := tenancy.AsRoot(context.Background())
+		require.NoError(t, store.Roles().Create(root, &dto.TNTRole{ResourceID: roleID, Name: "viewer", RowStatus: 1}))
+		grant := &dto.TNTRolePermission{ID: uuid.New(), RoleID: roleID, PermissionID: uuid.New(), RowStatus: 1}
+		require.NoError(t, store.Roles().AddPermission(root, grant))
+
+		_, err := store.Roles().Get(tenancy.WithTenant(root, acme), roleID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		globexCtx := tenancy.WithTenant(root, globex)
+		require.NoError(t, store.Roles().Update(globexCtx, roleID, map[string]interface{}{"description": "read only"}))
+		role, err := store.Roles().Get(globexCtx, roleID)
+		require.NoError(t, err)
+		assert.Equal(t, "read only", role.Description)
+
+		permissions, err := store.Roles().Permissions(globexCtx, roleID)
+		require.NoError(t, err)
+		require.Len(t, permissions, 1)
+		assert.Equal(t, grant.PermissionID, permissions[0].PermissionID)
+
+		require.NoError(t, store.Roles().Delete(globexCtx, roleID))
+		_, err = store.Roles().Get(globexCtx, roleID)
+		assert.ErrorIs(t, err, ErrNotFound)
+		permissions, err = store.Roles().Permissions(globexCtx, roleID)
+		require.NoError(t, err)
+		assert.Empty(t, permissions)
+	})
+}
+
+func TestRoleRevisions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		roleID := uuid.New()
+
+		latest, err := store.Roles().LatestRevision(ctx, roleID)
+		require.NoError(t, err)
+		assert.Equal(t, 0, latest)
+
+		for n := 1; n <= 2; n++ {
+			require.NoError(t, store.Roles().CreateRevision(ctx, &dto.TNTRoleRevision{
+				RevisionID: uuid.New(), RoleID: roleID, Revision: n, Name: fmt.Sprintf("v%d", n), Permissions: dto.JSON("[]"),
+			}))
+		}
+		assert.ErrorIs(t, store.Roles().CreateRevision(ctx, &dto.TNTRoleRevision{RevisionID: uuid.New(), RoleID: roleID, Revision: 2}), ErrConflict)
+
+		latest, err = store.Roles().LatestRevision(ctx, roleID)
+		require.NoError(t, err)
+		assert.Equal(t, 2, latest)
+
+		revision, err := store.Roles().GetRevision(ctx, roleID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, "v1", revision.Name)
+		_, err = store.Roles().GetRevision(ctx, roleID, 3)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		revisions, err := store.Roles().ListRevisions(ctx, roleID)
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, 2, revisions[0].Revision)
+	})
+}
+
+func TestTransactionRollsBack(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := context.Background()
+		kept := uuid.New()
+		require.NoError(t, store.Metadata().Create(ctx, &dto.TenantMetadata{ResourceID: kept, Metadata: dto.JSON(`{"a":1}`), RowStatus: 1}))
+
+		failure := errors.New("failed")
+		discarded := uuid.New()
+		err := store.Transaction(ctx, func(tx Store) error {
+			require.NoError(t, tx.Metadata().Update(ctx, kept, dto.JSON(`{"a":2}`)))
+			require.NoError(t, tx.Metadata().Create(ctx, &dto.TenantMetadata{ResourceID: discarded, Metadata: dto.JSON(`{}`), RowStatus: 1}))
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		metadata, err := store.Metadata().Get(ctx, kept)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"a":1}`, string(metadata.Metadata))
+		_, err = store.Metadata().Get(ctx, discarded)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, store.Transaction(ctx, func(tx Store) error {
+			return tx.Metadata().Delete(ctx, kept)
+		}))
+		_, err = store.Metadata().Get(ctx, kept)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestAssignmentsByPrincipalAndRole(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		tenantID := uuid.New()
+		ctx := tenancy.WithTenant(context.Background(), tenantID)
+		principalID, roleID := uuid.New(), uuid.New()
+		first := &dto.TenantRoleAssignments{ResourceID: uuid.New(), Name: "a", Version: "v1", PrincipalID: principalID, RoleID: roleID, TenantID: &tenantID, RowStatus: 1}
+		second := &dto.TenantRoleAssignments{ResourceID: uuid.New(), Name: "b", Version: "v1", PrincipalID: principalID, RoleID: uuid.New(), TenantID: &tenantID, RowStatus: 1}
+		require.NoError(t, store.Assignments().Create(ctx, first))
+		require.NoError(t, store.Assignments().Create(ctx, second))
+
+		assignments, err := store.Assignments().ListByPrincipal(ctx, principalID)
+		require.NoError(t, err)
+		assert.Len(t, assignments, 2)
+
+		require.NoError(t, store.Assignments().Delete(ctx, first.ResourceID))
+		assignments, err = store.Assignments().ListByRole(ctx, roleID)
+		require.NoError(t, err)
+		assert.Empty(t, assignments)
+		_, err = store.Assignments().Get(ctx, first.ResourceID)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestListsAreTenantScoped(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		acme, globex := uuid.New(), uuid.New()
+		root := tenancy.AsRoot(context.Background())
+		groupType := uuid.New()
+		for _, tenantID := range []uuid.UUID{acme, globex} {
+			require.NoError(t, store.Resources().Create(root, &dto.TenantResource{
+				ResourceID: uuid.New(), ResourceTypeID: groupType, Name: "admins", TenantID: &tenantID, RowStatus: 1,
+			}))
+		}
+		roleID := createResource(t, store, &acme, "viewer")
+		require.NoError(t, store.Roles().Create(root, &dto.TNTRole{ResourceID: roleID, Name: "viewer", RowStatus: 1}))
+		require.NoError(t, store.Assignments().Create(root, &dto.TenantRoleAssignments{
+			ResourceID: uuid.New(), Name: "a", Version: "v1", PrincipalID: uuid.New(), RoleID: roleID, TenantID: &acme, RowStatus: 1,
+		}))
+
+		ctx := tenancy.WithTenant(root, acme)
+		groups, err := store.Resources().ListByType(ctx, groupType)
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, acme, *groups[0].TenantID)
+		resources, err := store.Resources().ListByTenant(ctx, acme)
+		require.NoError(t, err)
+		assert.Len(t, resources, 2)
+		resources, err = store.Resources().ListByTenant(ctx, globex)
+		require.NoError(t, err)
+		assert.Empty(t, resources)
+
+		roles, err := store.Roles().List(ctx)
+		require.NoError(t, err)
+		require.Len(t, roles, 1)
+		roles, err = store.Roles().List(tenancy.WithTenant(root, globex))
+		require.NoError(t, err)
+		assert.Empty(t, roles)
+
+		assignments, err := store.Assignments().ListByTenant(ctx, acme)
+		require.NoError(t, err)
+		assert.Len(t, assignments, 1)
+		assignments, err = store.Assignments().ListByTenant(ctx, globex)
+		require.NoError(t, err)
+		assert.Empty(t, assignments)
+		assignments, err = store.Assignments().ListByRole(tenancy.WithTenant(root, globex), roleID)
+		require.NoError(t, err)
+		assert.Empty(t, assignments)
+	})
+}
+
+func TestGroupMembersAreTenantScoped(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		acme, globex := uuid.New(), uuid.New()
+		root := tenancy.AsRoot(context.Background())
+		ctx := tenancy.WithTenant(root, acme)
+		admins, alice, bob := uuid.New(), uuid.New(), uuid.New()
+		require.NoError(t, store.Members().Add(ctx, &dto.GroupMember{GroupID: admins, MemberID: alice}))
+		require.NoError(t, store.Members().Add(ctx, &dto.GroupMember{GroupID: admins, MemberID: bob}))
+		// Adding a member again does nothing
+		require.NoError(t, store.Members().Add(ctx, &dto.GroupMember{GroupID: admins, MemberID: alice}))
+		assert.ErrorIs(t, store.Members().Add(ctx, &dto.GroupMember{GroupID: admins, MemberID: uuid.New(), TenantID: &globex}), tenancy.ErrCrossTenantWrite)
+
+		members, err := store.Members().ListByGroup(ctx, admins)
+		require.NoError(t, err)
+		require.Len(t, members, 2)
+		assert.Equal(t, acme, *members[0].TenantID)
+		groups, err := store.Members().ListByMember(ctx, bob)
+		require.NoError(t, err)
+		require.Len(t, groups, 1)
+		assert.Equal(t, admins, groups[0].GroupID)
+
+		members, err = store.Members().ListByGroup(tenancy.WithTenant(root, globex), admins)
+		require.NoError(t, err)
+		assert.Empty(t, members)
+		require.NoError(t, store.Members().Remove(tenancy.WithTenant(root, globex), admins, alice))
+		members, err = store.Members().ListByGroup(ctx, admins)
+		require.NoError(t, err)
+		assert.Len(t, members, 2)
+
+		require.NoError(t, store.Members().Remove(ctx, admins, alice))
+		members, err = store.Members().ListByGroup(ctx, admins)
+		require.NoError(t, err)
+		require.Len(t, members, 1)
+		assert.Equal(t, bob, members[0].MemberID)
+	})
+}
+
+func TestTenantStateTransitions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		ctx := tenancy.AsRoot(context.Background())
+		tenantID := uuid.New()
+		_, err := store.TenantStates().Get(ctx, tenantID)
+		assert.ErrorIs(t, err, ErrNotFound)
+
+		require.NoError(t, store.TenantStates().Transition(ctx, 0, &dto.TenantStateTransition{
+			TransitionID: uuid.New(), TenantID: tenantID, ToStatus: dto.TenantStatusProvisioning,
+		}))
+		// A second first transition conflicts
+		assert.ErrorIs(t, store.TenantStates().Transition(ctx, 0, &dto.TenantStateTransition{
+			TransitionID: uuid.New(), TenantID: tenantID, ToStatus: dto.TenantStatusActive,
+		}), ErrConflict)
+		require.NoError(t, store.TenantStates().Transition(ctx, 1, &dto.TenantStateTransition{
+			TransitionID: uuid.New(), TenantID: tenantID, FromStatus: dto.TenantStatusProvisioning, ToStatus: dto.TenantStatusActive,
+		}))
+		assert.ErrorIs(t, store.TenantStates().Transition(ctx, 1, &dto.TenantStateTransition{
+			TransitionID: uuid.New(), TenantID: tenantID, FromStatus: dto.TenantStatusProvisioning, ToStatus: dto.TenantStatusArchived,
+		}), ErrConflict)
+
+		state, err := store.TenantStates().Get(ctx, tenantID)
+		require.NoError(t, err)
+		assert.Equal(t, dto.TenantStatusActive, state.Status)
+		assert.Equal(t, 2, state.Revision)
+		transitions, err := store.TenantStates().ListTransitions(ctx, tenantID)
+		require.NoError(t, err)
+		require.Len(t, transitions, 2)
+		assert.Equal(t, dto.TenantStatusProvisioning, transitions[0].ToStatus)
+		assert.Equal(t, dto.TenantStatusActive, transitions[1].ToStatus)
+		assert.Equal(t, 2, transitions[1].Revision)
+
+		transitions, err = store.TenantStates().ListTransitions(ctx, uuid.New())
+		require.NoError(t, err)
+		assert.Empty(t, transitions)
+	})
+}
+
+func TestApprovalPoliciesAreTenantScoped(t *testing.T) {
+	acme, globex, roleID := uuid.New(), uuid.New(), uuid.New()
+	policy := dto.ApprovalPolicy{PolicyID: uuid.New(), TenantID: &acme, RoleID: roleID, RequiredApprovals: 1, RowStatus: 1}
+	db := setupTestDB(t)
+	require.NoError(t, db.Scopes(tenancy.WithoutTenantScope).Create(&policy).Error)
+	memory := NewMemoryStore()
+	memory.AddApprovalPolicy(policy)
+
+	for name, store := range map[string]Store{"gorm": NewGormStore(db), "memory": memory} {
+		t.Run(name, func(t *testing.T) {
+			ctx := tenancy.WithTenant(context.Background(), acme)
+			required, err := RequiresApproval(ctx, store.ApprovalPolicies(), roleID)
+			require.NoError(t, err)
+			assert.True(t, required)
+
+			required, err = RequiresApproval(ctx, store.ApprovalPolicies(), uuid.New())
+			require.NoError(t, err)
+			assert.False(t, required)
+
+			_, err = store.ApprovalPolicies().GetByRole(tenancy.WithTenant(ctx, globex), roleID)
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
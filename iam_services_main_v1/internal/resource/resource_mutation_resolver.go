@@ -1,9 +1,216 @@
 package resource
 
 import (
-	"gorm.io/gorm"
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/roles"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+// ResourceMutationResolver handles mutations spanning resources of any type.
 type ResourceMutationResolver struct {
-	DB *gorm.DB
+	Store repository.Store
+	// PC is the Permit client; when nil one is configured from the environment.
+	PC *permit.PermitClient
+}
+
+// deletion is the Permit side of deleting a resource. Bindings are removed
+// through the bulk role assignment endpoint, other resources with a request
+// each; undo recreates what Permit deleted.
+type deletion struct {
+	assignment *permit.RoleAssignment
+	push, undo permit.Request
+}
+
+func (r *ResourceMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// DeleteResources deletes bindings, roles, tenants and resources of
+// registered types, returning a result per ID.
+func (r *ResourceMutationResolver) DeleteResources(ctx context.Context, ids []uuid.UUID, mode *models.BatchMode) ([]models.OperationResult, error) {
+	items := make([]batch.Item, len(ids))
+	deletions := make([]deletion, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	// Root-level requests delete Root resources and bindings outside any tenant
+	ctx = tenancy.AllowRoot(ctx)
+
+	userID, userErr := helpers.GetUserID(ctx)
+	for i, id := range ids {
+		switch {
+		case userErr != nil:
+			items[i].Err = batch.Fail("400", "Invalid user ID", userErr)
+		case seen[id]:
+			items[i].Err = batch.Fail("400", "Duplicate resource in batch", fmt.Errorf("resource %s is listed more than once", id))
+		default:
+			items[i], deletions[i] = r.prepareDeletion(ctx, id, *userID)
+		}
+		seen[id] = true
+	}
+
+	pc := r.permitClient()
+	sync := batch.Sync{
+		Push: func(ctx context.Context, items []int) []error {
+			return sendDeletions(ctx, pc, deletions, items, false)
+		},
+		Undo: func(ctx context.Context, items []int) []error {
+			return sendDeletions(ctx, pc, deletions, items, true)
+		},
+	}
+	return batch.Run(ctx, r.Store, mode, items, sync), nil
+}
+
+// prepareDeletion looks up the resource id and returns the batch item marking
+// it deleted together with its Permit deletion.
+func (r *ResourceMutationResolver) prepareDeletion(ctx context.Context, id, userID uuid.UUID) (batch.Item, deletion) {
+	deleted := utils.UpdateDeletedMap()
+	deleted["updated_by"] = userID
+	deleted["updated_at"] = time.Now()
+
+	binding, err := r.Store.Assignments().Get(ctx, id)
+	if err == nil {
+		return r.prepareBindingDeletion(ctx, binding, deleted)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return batch.Item{Err: batch.Fail("500", "Error getting binding", err)}, deletion{}
+	}
+
+	resource, err := r.Store.Resources().Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return batch.Item{Err: batch.Fail("404", "Resource not found", err)}, deletion{}
+	}
+	if err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error getting resource", err)}, deletion{}
+	}
+
+	resourceType, err := r.Store.Resources().GetType(ctx, resource.ResourceTypeID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Resource type not found", err)}, deletion{}
+	}
+	deleteResource := func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Resources().Update(ctx, id, deleted); err != nil {
+			return batch.Fail("500", "Error deleting resource", err)
+		}
+		return nil
+	}
+
+	switch resourceType.Name {
+	case constants.ResourceTypeRoot:
+		return batch.Item{Err: batch.Fail("400", "Root cannot be deleted", fmt.Errorf("resource %s is the Root organization", id))}, deletion{}
+	case constants.ResourceTypeTenant:
+		return batch.Item{
+			Apply: func(ctx context.Context, tx repository.Store) error {
+				if err := tx.Metadata().Delete(ctx, id); err != nil {
+					return batch.Fail("500", "Error deleting tenant metadata", err)
+				}
+				return deleteResource(ctx, tx)
+			},
+			Result: deletedResult,
+		}, deletion{
+			push: permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("tenants/%s", id)},
+			undo: permit.Request{Method: "POST", Endpoint: "tenants", Payload: map[string]interface{}{"name": resource.Name, "key": id}},
+		}
+	}
+
+	role, err := r.Store.Roles().Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return batch.Item{Apply: deleteResource, Result: deletedResult}, deletion{
+			push: permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("resource_instances/%s:%s", resource.ResourceTypeID, id)},
+			undo: permit.Request{Method: "POST", Endpoint: "resource_instances", Payload: map[string]interface{}{
+				"key":      id,
+				"resource": resource.ResourceTypeID,
+				"tenant":   resource.TenantID,
+			}},
+		}
+	}
+	if err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error getting role", err)}, deletion{}
+	}
+	roleMutationResolver := &roles.RoleMutationResolver{Store: r.Store}
+	item, remove, restore := roleMutationResolver.PrepareDeleteRole(ctx, role, userID)
+	item.Result = deletedResult
+	return item, deletion{push: remove, undo: restore}
+}
+
+// prepareBindingDeletion returns the batch item deleting binding and its
+// tnt_resources row, which bindings created through the API do not have.
+func (r *ResourceMutationResolver) prepareBindingDeletion(ctx context.Context, binding *dto.TenantRoleAssignments, deleted map[string]interface{}) (batch.Item, deletion) {
+	_, err := r.Store.Resources().Get(ctx, binding.ResourceID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return batch.Item{Err: batch.Fail("500", "Error getting binding resource", err)}, deletion{}
+	}
+	hasResource := err == nil
+
+	assignment := permit.RoleAssignment{User: binding.PrincipalID.String(), Role: binding.RoleID.String()}
+	if binding.TenantID != nil {
+		assignment.Tenant = binding.TenantID.String()
+	}
+	return batch.Item{
+		Apply: func(ctx context.Context, tx repository.Store) error {
+			if err := tx.Assignments().Delete(ctx, binding.ResourceID); err != nil {
+				return batch.Fail("500", "Error deleting binding", err)
+			}
+			if hasResource {
+				if err := tx.Resources().Update(ctx, binding.ResourceID, deleted); err != nil {
+					return batch.Fail("500", "Error deleting binding resource", err)
+				}
+			}
+			return nil
+		},
+		Result: deletedResult,
+	}, deletion{assignment: &assignment}
+}
+
+// sendDeletions sends the Permit deletions of items, or their undo requests,
+// and returns an error per item.
+func sendDeletions(ctx context.Context, pc *permit.PermitClient, deletions []deletion, items []int, undo bool) []error {
+	var assignments []permit.RoleAssignment
+	var requests []permit.Request
+	var assignmentItems, requestItems []int
+	for k, i := range items {
+		d := deletions[i]
+		if d.assignment != nil {
+			assignments = append(assignments, *d.assignment)
+			assignmentItems = append(assignmentItems, k)
+			continue
+		}
+		request := d.push
+		if undo {
+			request = d.undo
+		}
+		requests = append(requests, request)
+		requestItems = append(requestItems, k)
+	}
+
+	sendAssignments := pc.BulkUnassignRoles
+	if undo {
+		sendAssignments = pc.BulkAssignRoles
+	}
+	errs := make([]error, len(items))
+	for n, err := range sendAssignments(ctx, assignments, permit.DefaultConcurrency) {
+		errs[assignmentItems[n]] = err
+	}
+	for n, err := range pc.SendAll(ctx, requests, permit.DefaultConcurrency) {
+		errs[requestItems[n]] = err
+	}
+	return errs
+}
+
+func deletedResult(ctx context.Context) (models.OperationResult, error) {
+	return utils.FormatSuccess([]models.Data{})
 }
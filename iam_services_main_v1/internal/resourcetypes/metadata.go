@@ -0,0 +1,66 @@
+package resourcetypes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/validations"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AttributesKey is the metadata key under which custom attributes are stored.
+const AttributesKey = "attributes"
+
+// ValidateMetadata checks a metadata document against the attribute schema of
+// its resource type. Types registered without a schema accept any document.
+func ValidateMetadata(resourceType *dto.Mst_ResourceTypes, metadata []byte) ([]validations.ValidationError, error) {
+	if len(resourceType.AttributeSchema) == 0 || string(resourceType.AttributeSchema) == "null" {
+		return nil, nil
+	}
+	return validations.ValidateJSON(resourceType.AttributeSchema, metadata)
+}
+
+// ParseAttributes decodes the attributes argument of a mutation. Attributes
+// must be a JSON object.
+func ParseAttributes(attributes *string) (map[string]interface{}, []validations.ValidationError) {
+	if attributes == nil {
+		return nil, nil
+	}
+	var parsed map[string]interface{}
+	if err := json.Unmarshal([]byte(*attributes), &parsed); err != nil || parsed == nil {
+		return nil, []validations.ValidationError{{Field: "/" + AttributesKey, Message: "attributes must be a JSON object"}}
+	}
+	return parsed, nil
+}
+
+// ResourceAttributes returns the custom attributes stored in the metadata of a
+// resource, or nil when it has none.
+func ResourceAttributes(db *gorm.DB, resourceID uuid.UUID) (*string, error) {
+	var metadata dto.TenantMetadata
+	if err := db.Where("resource_id = ? AND row_status = 1", resourceID).First(&metadata).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch resource metadata: %w", err)
+	}
+
+	return MetadataAttributes(metadata.Metadata)
+}
+
+// MetadataAttributes returns the custom attributes stored in a metadata
+// document, or nil when it has none.
+func MetadataAttributes(metadata []byte) (*string, error) {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(metadata, &document); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+	}
+	attributes, ok := document[AttributesKey]
+	if !ok || string(attributes) == "null" {
+		return nil, nil
+	}
+	value := string(attributes)
+	return &value, nil
+}
@@ -0,0 +1,173 @@
+package resourcetypes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/internal/validations"
+	"iam_services_main_v1/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ResourceTypeMutationResolver handles resource type registration.
+type ResourceTypeMutationResolver struct {
+	DB *gorm.DB
+	PC *permit.PermitClient
+}
+
+// RegisterResourceType creates the Permit resource definition for a new
+// resource type, then records the type and its actions in the catalog.
+func (r *ResourceTypeMutationResolver) RegisterResourceType(ctx context.Context, input models.RegisterResourceTypeInput) (models.OperationResult, error) {
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+
+	if err := r.validateRegisterInput(input); err != nil {
+		if errors.Is(err, ErrResourceTypeExists) {
+			return handleError("409", "Resource type already exists", err)
+		}
+		return handleError("400", "Invalid input", err)
+	}
+
+	if _, err := r.permitClient().SendRequest(ctx, "POST", "resources", map[string]interface{}{
+		"key":     input.ID,
+		"name":    input.Name,
+		"actions": utils.CreateActionMap(map[string]interface{}{}, input.Actions),
+	}); err != nil {
+		return handleError("500", "Error creating resource in permit", err)
+	}
+
+	if err := r.createResourceType(input, *userID); err != nil {
+		if _, rollbackErr := r.permitClient().SendRequest(ctx, "DELETE", fmt.Sprintf("resources/%s", input.ID), nil); rollbackErr != nil {
+			logger.LogError(fmt.Sprintf("Error rolling back permit resource %s: %v", input.ID, rollbackErr))
+		}
+		return handleError("500", "Error creating resource type", err)
+	}
+
+	resourceType, err := GetResourceType(r.DB, input.ID)
+	if err != nil {
+		return handleError("500", "Error fetching resource type", err)
+	}
+	result, err := mapToResourceType(r.DB, resourceType)
+	if err != nil {
+		return handleError("500", "Error mapping resource type", err)
+	}
+	return utils.FormatSuccess([]models.Data{result})
+}
+
+// Helper Functions
+
+func (r *ResourceTypeMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+func (r *ResourceTypeMutationResolver) validateRegisterInput(input models.RegisterResourceTypeInput) error {
+	if input.ID == uuid.Nil || input.ServiceID == uuid.Nil {
+		return errors.New("resource type ID and service ID are required")
+	}
+	if input.Name == "" || len(input.Name) > 45 {
+		return errors.New("resource type name must be between 1 and 45 characters")
+	}
+
+	var count int64
+	if err := r.DB.Model(&dto.Mst_ResourceTypes{}).
+		Where("(resource_type_id = ? OR name = ?) AND row_status = 1", input.ID, input.Name).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check resource type: %w", err)
+	}
+	if count > 0 {
+		return ErrResourceTypeExists
+	}
+
+	if len(input.Actions) == 0 {
+		return errors.New("at least one action is required")
+	}
+	seen := make(map[string]bool, len(input.Actions))
+	for _, action := range input.Actions {
+		if err := utils.ValidateName(action); err != nil {
+			return fmt.Errorf("invalid action %q: %w", action, err)
+		}
+		if seen[action] {
+			return fmt.Errorf("duplicate action %q", action)
+		}
+		seen[action] = true
+	}
+
+	for _, parentTypeID := range input.AllowedParentTypes {
+		if _, err := GetResourceType(r.DB, parentTypeID); err != nil {
+			return fmt.Errorf("invalid allowed parent type %s: %w", parentTypeID, err)
+		}
+	}
+
+	if input.AttributeSchema != nil {
+		var schema map[string]interface{}
+		if err := json.Unmarshal([]byte(*input.AttributeSchema), &schema); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAttributeSchema, err)
+		}
+		if _, err := validations.CompileSchema([]byte(*input.AttributeSchema)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAttributeSchema, err)
+		}
+	}
+	return nil
+}
+
+func (r *ResourceTypeMutationResolver) createResourceType(input models.RegisterResourceTypeInput, userID uuid.UUID) error {
+	parents := input.AllowedParentTypes
+	if parents == nil {
+		parents = []uuid.UUID{}
+	}
+	parentsJSON, err := json.Marshal(parents)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowed parent types: %w", err)
+	}
+
+	resourceType := dto.Mst_ResourceTypes{
+		ResourceTypeID:     input.ID,
+		ServiceID:          input.ServiceID,
+		Name:               input.Name,
+		AllowedParentTypes: parentsJSON,
+		RowStatus:          1,
+		CreatedBy:          userID,
+		UpdatedBy:          userID,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
+	}
+	if input.AttributeSchema != nil {
+		resourceType.AttributeSchema = dto.JSON(*input.AttributeSchema)
+	}
+
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&resourceType).Error; err != nil {
+			return fmt.Errorf("failed to create resource type: %w", err)
+		}
+		for _, action := range input.Actions {
+			if err := tx.Create(&dto.MstPermission{
+				PermissionID:   uuid.New(),
+				ResourceTypeID: input.ID.String(),
+				Name:           action,
+				Action:         action,
+				RowStatus:      1,
+				CreatedBy:      userID.String(),
+				UpdatedBy:      userID.String(),
+				CreatedAt:      time.Now(),
+				UpdatedAt:      time.Now(),
+			}).Error; err != nil {
+				return fmt.Errorf("failed to create permission %s: %w", action, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,117 @@
+package resourcetypes
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.Mst_ResourceTypes{}, &dto.MstPermission{}, &dto.TenantResource{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func setupPermit(t *testing.T, status int, requests *[]string) *permit.PermitClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		*requests = append(*requests, req.Method+" "+req.URL.Path)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+}
+
+func userContext(userID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func TestRegisterResourceType(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	var requests []string
+	resolver := &ResourceTypeMutationResolver{DB: db, PC: setupPermit(t, http.StatusOK, &requests)}
+
+	parentTypeID := uuid.New()
+	require.NoError(t, db.Create(&dto.Mst_ResourceTypes{ResourceTypeID: parentTypeID, Name: "Tenant", RowStatus: 1}).Error)
+
+	schema := `{"type":"object"}`
+	input := models.RegisterResourceTypeInput{
+		ID:                 uuid.New(),
+		Name:               "Invoice",
+		ServiceID:          uuid.New(),
+		Actions:            []string{"read", "approve"},
+		AllowedParentTypes: []uuid.UUID{parentTypeID},
+		AttributeSchema:    &schema,
+	}
+	result, err := resolver.RegisterResourceType(userContext(uuid.New()), input)
+	require.NoError(t, err)
+	success, ok := result.(*models.SuccessResponse)
+	require.True(t, ok, "expected success, got %#v", result)
+
+	resourceType := success.Data[0].(*models.ResourceType)
+	assert.Equal(t, []string{"approve", "read"}, resourceType.Actions)
+	assert.Equal(t, []uuid.UUID{parentTypeID}, resourceType.AllowedParentTypes)
+	assert.Equal(t, []string{"POST /v2/schema/proj/env/resources"}, requests)
+
+	// Registering the same name again conflicts
+	input.ID = uuid.New()
+	result, err = resolver.RegisterResourceType(userContext(uuid.New()), input)
+	require.NoError(t, err)
+	assert.Equal(t, "409", result.(*models.ResponseError).ErrorCode)
+}
+
+func TestRegisterResourceTypePermitFailure(t *testing.T) {
+	logger.InitLogger()
+	db := setupTestDB(t)
+	var requests []string
+	resolver := &ResourceTypeMutationResolver{DB: db, PC: setupPermit(t, http.StatusInternalServerError, &requests)}
+
+	result, err := resolver.RegisterResourceType(userContext(uuid.New()), models.RegisterResourceTypeInput{
+		ID: uuid.New(), Name: "Report", ServiceID: uuid.New(), Actions: []string{"read"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "500", result.(*models.ResponseError).ErrorCode)
+
+	var count int64
+	require.NoError(t, db.Model(&dto.Mst_ResourceTypes{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func TestCheckParent(t *testing.T) {
+	tenantTypeID, rootTypeID := uuid.New(), uuid.New()
+	tenantType := &dto.Mst_ResourceTypes{
+		ResourceTypeID: tenantTypeID, Name: "Tenant", RowStatus: 1,
+		AllowedParentTypes: []byte(fmt.Sprintf(`["%s"]`, rootTypeID)),
+	}
+	openType := &dto.Mst_ResourceTypes{ResourceTypeID: uuid.New(), Name: "Open", RowStatus: 1}
+
+	root := &dto.TenantResource{ResourceID: uuid.New(), ResourceTypeID: rootTypeID, Name: "root", RowStatus: 1}
+	tenant := &dto.TenantResource{ResourceID: uuid.New(), ResourceTypeID: tenantTypeID, Name: "tenant", RowStatus: 1}
+
+	assert.True(t, HasParentConstraint(tenantType))
+	assert.False(t, HasParentConstraint(openType))
+	assert.NoError(t, CheckParent(tenantType, root))
+	assert.ErrorIs(t, CheckParent(tenantType, tenant), ErrParentTypeNotAllowed)
+	assert.NoError(t, CheckParent(openType, tenant))
+}
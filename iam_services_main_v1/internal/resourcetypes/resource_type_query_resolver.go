@@ -0,0 +1,47 @@
+package resourcetypes
+
+import (
+	"context"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ResourceTypeQueryResolver handles resource type queries.
+type ResourceTypeQueryResolver struct {
+	DB *gorm.DB
+}
+
+// ResourceTypes lists every active resource type.
+func (r *ResourceTypeQueryResolver) ResourceTypes(ctx context.Context) (models.OperationResult, error) {
+	var resourceTypes []dto.Mst_ResourceTypes
+	if err := r.DB.Where("row_status = 1").Order("name").Find(&resourceTypes).Error; err != nil {
+		return handleError("500", "Error fetching resource types", err)
+	}
+
+	data := make([]models.Data, 0, len(resourceTypes))
+	for i := range resourceTypes {
+		resourceType, err := mapToResourceType(r.DB, &resourceTypes[i])
+		if err != nil {
+			return handleError("500", "Error mapping resource type", err)
+		}
+		data = append(data, resourceType)
+	}
+	return utils.FormatSuccess(data)
+}
+
+// ResourceType resolves a single resource type by ID.
+func (r *ResourceTypeQueryResolver) ResourceType(ctx context.Context, id uuid.UUID) (models.OperationResult, error) {
+	resourceType, err := GetResourceType(r.DB, id)
+	if err != nil {
+		return handleError("404", "Resource type not found", err)
+	}
+	result, err := mapToResourceType(r.DB, resourceType)
+	if err != nil {
+		return handleError("500", "Error mapping resource type", err)
+	}
+	return utils.FormatSuccess([]models.Data{result})
+}
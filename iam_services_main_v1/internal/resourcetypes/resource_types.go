@@ -0,0 +1,113 @@
+package resourcetypes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrResourceTypeExists     = errors.New("resource type already exists")
+	ErrResourceTypeNotFound   = errors.New("resource type not found")
+	ErrParentTypeNotAllowed   = errors.New("parent resource type is not allowed")
+	ErrInvalidAttributeSchema = errors.New("attribute schema must be a valid JSON Schema object")
+)
+
+// GetResourceType loads an active resource type by ID.
+func GetResourceType(db *gorm.DB, id uuid.UUID) (*dto.Mst_ResourceTypes, error) {
+	var resourceType dto.Mst_ResourceTypes
+	if err := db.Where("resource_type_id = ? AND row_status = 1", id).First(&resourceType).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrResourceTypeNotFound, err)
+	}
+	return &resourceType, nil
+}
+
+// AllowedParentTypes decodes the allowed parent type list of a resource type.
+func AllowedParentTypes(resourceType *dto.Mst_ResourceTypes) ([]uuid.UUID, error) {
+	parents := []uuid.UUID{}
+	if len(resourceType.AllowedParentTypes) == 0 {
+		return parents, nil
+	}
+	if err := json.Unmarshal(resourceType.AllowedParentTypes, &parents); err != nil {
+		return nil, fmt.Errorf("failed to decode allowed parent types: %w", err)
+	}
+	return parents, nil
+}
+
+// HasParentConstraint reports whether resourceType restricts the types of its
+// parents.
+func HasParentConstraint(resourceType *dto.Mst_ResourceTypes) bool {
+	allowed, err := AllowedParentTypes(resourceType)
+	return err != nil || len(allowed) > 0
+}
+
+// CheckParent checks that a resource of childType may be placed under parent.
+func CheckParent(childType *dto.Mst_ResourceTypes, parent *dto.TenantResource) error {
+	allowed, err := AllowedParentTypes(childType)
+	if err != nil {
+		return err
+	}
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, id := range allowed {
+		if id == parent.ResourceTypeID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s cannot be placed under %s", ErrParentTypeNotAllowed, childType.Name, parent.ResourceTypeID)
+}
+
+func resourceTypeActions(db *gorm.DB, resourceTypeID uuid.UUID) ([]string, error) {
+	var permissions []dto.MstPermission
+	if err := db.Where("resource_type_id = ? AND row_status = 1", resourceTypeID.String()).Order("action").Find(&permissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch resource type actions: %w", err)
+	}
+	actions := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		actions = append(actions, permission.ActionKey())
+	}
+	return actions, nil
+}
+
+func mapToResourceType(db *gorm.DB, resourceType *dto.Mst_ResourceTypes) (*models.ResourceType, error) {
+	actions, err := resourceTypeActions(db, resourceType.ResourceTypeID)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := AllowedParentTypes(resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ResourceType{
+		ID:                 resourceType.ResourceTypeID,
+		Name:               resourceType.Name,
+		ServiceID:          resourceType.ServiceID,
+		Actions:            actions,
+		AllowedParentTypes: parents,
+		CreatedAt:          resourceType.CreatedAt.Format(time.RFC3339),
+		CreatedBy:          resourceType.CreatedBy,
+		UpdatedAt:          resourceType.UpdatedAt.Format(time.RFC3339),
+		UpdatedBy:          resourceType.UpdatedBy,
+	}
+	if len(resourceType.AttributeSchema) > 0 {
+		schema := string(resourceType.AttributeSchema)
+		result.AttributeSchema = &schema
+	}
+	return result, nil
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
@@ -0,0 +1,251 @@
+package roles
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/utils"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateRoles creates several roles, returning a result per input. The roles
+// are created in Permit concurrently before they are stored.
+func (r *RoleMutationResolver) CreateRoles(ctx context.Context, inputs []*models.CreateRoleInput, mode *models.BatchMode) ([]models.OperationResult, error) {
+	items := make([]batch.Item, len(inputs))
+	push := make([]permit.Request, len(inputs))
+	undo := make([]permit.Request, len(inputs))
+	seen := make(map[uuid.UUID]bool, len(inputs))
+
+	userID, userErr := helpers.GetUserID(ctx)
+	tenantID, tenantErr := helpers.GetTenantID(ctx)
+	for i, input := range inputs {
+		switch {
+		case userErr != nil:
+			items[i].Err = batch.Fail("400", "Invalid user ID", userErr)
+		case tenantErr != nil:
+			items[i].Err = batch.Fail("404", "Invalid tenant ID", tenantErr)
+		case seen[input.ID]:
+			items[i].Err = batch.Fail("400", "Duplicate role in batch", fmt.Errorf("role %s is listed more than once", input.ID))
+		default:
+			items[i], push[i], undo[i] = r.PrepareCreateRole(ctx, *input, tenantID, *userID)
+		}
+		seen[input.ID] = true
+	}
+
+	return batch.Run(ctx, r.Store, mode, items, batch.Requests(r.permitClient(), push, undo)), nil
+}
+
+// PrepareCreateRole validates a role to create and returns the batch item
+// storing it together with the Permit requests creating and removing it.
+func (r *RoleMutationResolver) PrepareCreateRole(ctx context.Context, input models.CreateRoleInput, tenantID *uuid.UUID, userID uuid.UUID) (batch.Item, permit.Request, permit.Request) {
+	var none permit.Request
+	if _, err := r.Store.Resources().Get(ctx, input.ID); err == nil {
+		return batch.Item{Err: batch.Fail("409", "Role already exists", fmt.Errorf("%w: %s", ErrRoleAlreadyExists, input.ID))}, none, none
+	}
+	assignableScopeRef, err := r.getAssignableScopeRef(ctx, input.AssignableScopeRef)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Assignable scope ref not found", err)}, none, none
+	}
+	permissionActions, permissionData, err := r.getPermissionActions(ctx, input.AssignableScopeRef.String(), input.Permissions)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("400", "Invalid permissions", err)}, none, none
+	}
+	if input.Description == nil {
+		input.Description = new(string)
+	}
+
+	inputMap := r.prepareInputMap(input, permissionActions, permissionData, assignableScopeRef, tenantID, userID)
+	item := batch.Item{
+		Apply: func(ctx context.Context, tx repository.Store) error {
+			if err := r.createTenantResource(ctx, tx, input, input.AssignableScopeRef, tenantID, userID); err != nil {
+				return batch.Fail("500", "Error creating tenant resource", err)
+			}
+			role := r.prepareRoleObject(input, userID)
+			if err := tx.Roles().Create(ctx, &role); err != nil {
+				return batch.Fail("500", "Error creating role", err)
+			}
+			if err := r.createRolePermissions(ctx, tx, input.ID, input.Permissions, userID); err != nil {
+				return batch.Fail("500", "Error creating role permissions", err)
+			}
+			if _, err := createRoleRevision(ctx, tx.Roles(), input.ID, input.Name, role.Description, input.Permissions, userID); err != nil {
+				return batch.Fail("500", "Error creating role revision", err)
+			}
+			return nil
+		},
+		Result: func(ctx context.Context) (models.OperationResult, error) {
+			roleQueryResolver := &RoleQueryResolver{Store: r.Store}
+			return roleQueryResolver.Role(ctx, input.ID)
+		},
+	}
+	create := permit.Request{
+		Method:   "POST",
+		Endpoint: fmt.Sprintf("resources/%s/roles", input.AssignableScopeRef),
+		Payload:  r.preparePermitMap(input, inputMap, permissionActions),
+	}
+	remove := permit.Request{
+		Method:   "DELETE",
+		Endpoint: fmt.Sprintf("resources/%s/roles/%s", input.AssignableScopeRef, input.ID),
+	}
+	return item, create, remove
+}
+
+// PrepareUpdateRole validates an update of role and returns the batch item
+// storing it together with the Permit requests applying the update and
+// restoring the role as it is. The role's resource must still be at
+// expectedRevision when one is given.
+func (r *RoleMutationResolver) PrepareUpdateRole(ctx context.Context, role *dto.TNTRole, input models.UpdateRoleInput, expectedRevision *int, userID uuid.UUID) (batch.Item, permit.Request, permit.Request) {
+	var none permit.Request
+	if err := r.validateUpdateRoleInput(input, role); err != nil {
+		return batch.Item{Err: batch.Fail("400", "Invalid input", err)}, none, none
+	}
+	assignableScopeRef, err := r.getAssignableScopeRef(ctx, input.AssignableScopeRef)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Assignable scope ref not found", err)}, none, none
+	}
+	permissionActions, permissionData, err := r.getPermissionActions(ctx, input.AssignableScopeRef.String(), input.Permissions)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("400", "Invalid permissions", err)}, none, none
+	}
+	current, err := r.currentRoleInput(ctx, role)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error reading role permissions", err)}, none, none
+	}
+	currentActions, currentData, err := r.getPermissionActions(ctx, role.ScopeResourceTypeID.String(), current.Permissions)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error reading role permissions", err)}, none, none
+	}
+
+	description := role.Description
+	if input.Description != nil {
+		description = *input.Description
+	}
+	item := batch.Item{
+		Apply: func(ctx context.Context, tx repository.Store) error {
+			if err := repository.UpdateResource(ctx, tx.Resources(), input.ID, expectedRevision, map[string]interface{}{
+				"updated_by": userID,
+				"updated_at": time.Now(),
+			}); err != nil {
+				return batch.Fail("500", "Error updating role resource", err)
+			}
+			if err := ensureBaselineRevision(ctx, tx.Roles(), role); err != nil {
+				return batch.Fail("500", "Error creating baseline role revision", err)
+			}
+			if err := r.updateRoleDetails(ctx, tx, role, input); err != nil {
+				return batch.Fail("500", "Error updating role", err)
+			}
+			if err := r.updateRolePermissions(ctx, tx, input.ID, input.Permissions, role.CreatedBy, role.UpdatedBy); err != nil {
+				return batch.Fail("500", "Error updating role permissions", err)
+			}
+			if _, err := createRoleRevision(ctx, tx.Roles(), input.ID, input.Name, description, input.Permissions, userID); err != nil {
+				return batch.Fail("500", "Error creating role revision", err)
+			}
+			return nil
+		},
+		Result: func(ctx context.Context) (models.OperationResult, error) {
+			roleQueryResolver := &RoleQueryResolver{Store: r.Store}
+			return roleQueryResolver.Role(ctx, input.ID)
+		},
+	}
+	endpoint := fmt.Sprintf("resources/%s/roles/%s", input.AssignableScopeRef, input.ID)
+	update := permit.Request{
+		Method:   "PATCH",
+		Endpoint: endpoint,
+		Payload:  r.preparePermitMapForUpdate(input, r.prepareInputMapForUpdate(input, permissionActions, permissionData, assignableScopeRef, role), permissionActions),
+	}
+	restore := permit.Request{
+		Method:   "PATCH",
+		Endpoint: endpoint,
+		Payload:  r.preparePermitMapForUpdate(current, r.prepareInputMapForUpdate(current, currentActions, currentData, assignableScopeRef, role), currentActions),
+	}
+	return item, update, restore
+}
+
+// PrepareDeleteRole returns the batch item deleting role together with the
+// Permit requests deleting it and creating it again.
+func (r *RoleMutationResolver) PrepareDeleteRole(ctx context.Context, role *dto.TNTRole, userID uuid.UUID) (batch.Item, permit.Request, permit.Request) {
+	var none permit.Request
+	resource, err := r.Store.Resources().Get(ctx, role.ResourceID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Role resource not found", err)}, none, none
+	}
+	assignableScopeRef, err := r.getAssignableScopeRef(ctx, role.ScopeResourceTypeID)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("404", "Assignable scope ref not found", err)}, none, none
+	}
+	current, err := r.currentRoleInput(ctx, role)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error reading role permissions", err)}, none, none
+	}
+	actions, permissionData, err := r.getPermissionActions(ctx, role.ScopeResourceTypeID.String(), current.Permissions)
+	if err != nil {
+		return batch.Item{Err: batch.Fail("500", "Error reading role permissions", err)}, none, none
+	}
+	input := models.CreateRoleInput{
+		ID:                 role.ResourceID,
+		Name:               role.Name,
+		Description:        current.Description,
+		Permissions:        current.Permissions,
+		RoleType:           roleTypeOf(role),
+		AssignableScopeRef: role.ScopeResourceTypeID,
+		Version:            role.Version,
+	}
+	inputMap := r.prepareInputMap(input, actions, permissionData, assignableScopeRef, resource.TenantID, role.CreatedBy)
+
+	deleted := utils.UpdateDeletedMap()
+	deleted["updated_by"] = userID
+	deleted["updated_at"] = time.Now()
+	item := batch.Item{
+		Apply: func(ctx context.Context, tx repository.Store) error {
+			if err := tx.Roles().Delete(ctx, role.ResourceID); err != nil {
+				return batch.Fail("500", "Error deleting role", err)
+			}
+			if err := tx.Resources().Update(ctx, role.ResourceID, deleted); err != nil {
+				return batch.Fail("500", "Error deleting role resource", err)
+			}
+			return nil
+		},
+		Result: func(ctx context.Context) (models.OperationResult, error) {
+			return utils.FormatSuccess([]models.Data{})
+		},
+	}
+	remove := permit.Request{
+		Method:   "DELETE",
+		Endpoint: fmt.Sprintf("resources/%s/roles/%s", role.ScopeResourceTypeID, role.ResourceID),
+	}
+	create := permit.Request{
+		Method:   "POST",
+		Endpoint: fmt.Sprintf("resources/%s/roles", role.ScopeResourceTypeID),
+		Payload:  r.preparePermitMap(input, inputMap, actions),
+	}
+	return item, remove, create
+}
+
+// currentRoleInput describes role as it is stored, as the update that would
+// leave it unchanged.
+func (r *RoleMutationResolver) currentRoleInput(ctx context.Context, role *dto.TNTRole) (models.UpdateRoleInput, error) {
+	grants, err := r.Store.Roles().Permissions(ctx, role.ResourceID)
+	if err != nil {
+		return models.UpdateRoleInput{}, err
+	}
+	permissions := make([]string, len(grants))
+	for i, grant := range grants {
+		permissions[i] = grant.PermissionID.String()
+	}
+	description := role.Description
+	return models.UpdateRoleInput{
+		ID:                 role.ResourceID,
+		Name:               role.Name,
+		Description:        &description,
+		Permissions:        permissions,
+		RoleType:           roleTypeOf(role),
+		AssignableScopeRef: role.ScopeResourceTypeID,
+		Version:            role.Version,
+	}, nil
+}
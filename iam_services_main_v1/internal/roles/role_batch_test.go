@@ -0,0 +1,180 @@
+package roles
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRolePermit stores the roles created in Permit and returns their
+// attributes on GET. Creating a role named "rejected" fails.
+type fakeRolePermit struct {
+	mu      sync.Mutex
+	roles   map[string]map[string]interface{}
+	deleted []string
+}
+
+func (f *fakeRolePermit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case "POST":
+		var role map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&role); err != nil || role["name"] == "rejected" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.roles[role["key"].(string)] = role
+	case "GET":
+		key := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		role, ok := f.roles[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"attributes": map[string]interface{}{
+			"ID":        key,
+			"Name":      role["name"],
+			"RoleType":  "CUSTOM",
+			"Version":   "1",
+			"createdAt": time.Now().Format(time.RFC3339),
+			"updatedAt": time.Now().Format(time.RFC3339),
+			"createdBy": uuid.NewString(),
+			"updatedBy": uuid.NewString(),
+		}})
+		return
+	case "DELETE":
+		key := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		delete(f.roles, key)
+		f.deleted = append(f.deleted, key)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func setupRoleBatch(t *testing.T) (*RoleMutationResolver, *fakeRolePermit, context.Context, uuid.UUID, uuid.UUID) {
+	logger.InitLogger()
+	fake := &fakeRolePermit{roles: map[string]map[string]interface{}{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	// Role reads use a client configured from the environment
+	t.Setenv("PERMIT_PDP_ENDPOINT", srv.URL)
+	t.Setenv("PERMIT_PROJECT", "proj")
+	t.Setenv("PERMIT_ENV", "env")
+
+	store := repository.NewMemoryStore()
+	scopeType, permissionID := uuid.New(), uuid.New()
+	store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: scopeType, Name: "Tenant", RowStatus: 1})
+	store.AddPermission(dto.MstPermission{PermissionID: permissionID, ResourceTypeID: scopeType.String(), Name: "read", Action: "tenant.read", RowStatus: 1})
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", uuid.New().String())
+	ginCtx.Set("tenantID", uuid.New().String())
+	ctx := context.WithValue(context.Background(), "GinContextKey", ginCtx)
+
+	pc := permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
+	return &RoleMutationResolver{Store: store, PC: pc}, fake, ctx, scopeType, permissionID
+}
+
+func roleInput(name string, scopeType, permissionID uuid.UUID) *models.CreateRoleInput {
+	description := name + " role"
+	return &models.CreateRoleInput{
+		ID:                 uuid.New(),
+		Name:               name,
+		Description:        &description,
+		AssignableScopeRef: scopeType,
+		Permissions:        []string{permissionID.String()},
+		RoleType:           models.RoleTypeEnumCustom,
+		Version:            "1",
+	}
+}
+
+func TestCreateRolesAtomicallyRollsBack(t *testing.T) {
+	resolver, fake, ctx, scopeType, permissionID := setupRoleBatch(t)
+	reader, rejected := roleInput("reader", scopeType, permissionID), roleInput("rejected", scopeType, permissionID)
+
+	results, err := resolver.CreateRoles(ctx, []*models.CreateRoleInput{reader, rejected}, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, batch.ErrorCodeNotApplied, results[0].(*models.ResponseError).ErrorCode)
+	assert.Equal(t, "500", results[1].(*models.ResponseError).ErrorCode)
+	assert.Equal(t, []string{reader.ID.String()}, fake.deleted)
+	_, err = resolver.Store.Roles().Get(ctx, reader.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestCreateRolesBestEffort(t *testing.T) {
+	resolver, _, ctx, scopeType, permissionID := setupRoleBatch(t)
+	reader := roleInput("reader", scopeType, permissionID)
+	unknownScope := roleInput("writer", uuid.New(), permissionID)
+	bestEffort := models.BatchModeBestEffort
+
+	results, err := resolver.CreateRoles(ctx, []*models.CreateRoleInput{reader, unknownScope, reader}, &bestEffort)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	require.IsType(t, &models.SuccessResponse{}, results[0])
+	role := results[0].(*models.SuccessResponse).Data[0].(models.Role)
+	assert.Equal(t, reader.ID, role.ID)
+	assert.Equal(t, "404", results[1].(*models.ResponseError).ErrorCode)
+	assert.Equal(t, "400", results[2].(*models.ResponseError).ErrorCode)
+
+	stored, err := resolver.Store.Roles().Get(ctx, reader.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "reader", stored.Name)
+	latest, err := resolver.Store.Roles().LatestRevision(ctx, reader.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, latest)
+}
+
+func TestCreateRoleStoresNothingWhenPermitFails(t *testing.T) {
+	resolver, _, ctx, scopeType, permissionID := setupRoleBatch(t)
+	rejected := roleInput("rejected", scopeType, permissionID)
+
+	result, err := resolver.CreateRole(ctx, *rejected)
+	require.NoError(t, err)
+	assert.Equal(t, "500", result.(*models.ResponseError).ErrorCode)
+
+	_, err = resolver.Store.Roles().Get(ctx, rejected.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = resolver.Store.Resources().Get(ctx, rejected.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	latest, err := resolver.Store.Roles().LatestRevision(ctx, rejected.ID)
+	require.NoError(t, err)
+	assert.Zero(t, latest)
+}
+
+func TestRollbackRoleKeepsRoleType(t *testing.T) {
+	resolver, _, ctx, scopeType, permissionID := setupRoleBatch(t)
+	input := roleInput("reader", scopeType, permissionID)
+	_, err := resolver.CreateRole(ctx, *input)
+	require.NoError(t, err)
+	require.NoError(t, resolver.Store.Roles().Update(ctx, input.ID, map[string]interface{}{"role_type": dto.RoleTypeEnumDefault}))
+
+	// Default roles cannot be changed, so rolling one back must not turn it
+	// into a custom role
+	result, err := resolver.RollbackRole(ctx, models.RollbackRoleInput{ID: input.ID, Revision: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "400", result.(*models.ResponseError).ErrorCode)
+
+	stored, err := resolver.Store.Roles().Get(ctx, input.ID)
+	require.NoError(t, err)
+	assert.Equal(t, dto.RoleTypeEnumDefault, stored.RoleType)
+}
@@ -0,0 +1,30 @@
+package roles
+
+import (
+	"context"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/repository"
+)
+
+// RoleFieldResolver resolves fields on the Role type.
+type RoleFieldResolver struct {
+	Store repository.Store
+}
+
+// Revisions resolves the revision history of a role, newest first.
+func (r *RoleFieldResolver) Revisions(ctx context.Context, obj *models.Role) ([]*models.RoleRevision, error) {
+	revisions, err := listRoleRevisions(ctx, r.Store.Roles(), obj.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.RoleRevision, 0, len(revisions))
+	for i := range revisions {
+		revision, err := mapToRoleRevision(&revisions[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, revision)
+	}
+	return result, nil
+}
@@ -4,17 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"iam_services_main_v1/config"
 	"iam_services_main_v1/gql/models"
 	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
 	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/etag"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
 	"iam_services_main_v1/internal/utils"
 	"iam_services_main_v1/pkg/logger"
 	"time"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 var (
@@ -27,128 +29,155 @@ var (
 
 // RoleMutationResolver handles role-related mutations.
 type RoleMutationResolver struct {
-	DB *gorm.DB
+	Store repository.Store
+	// PC is the Permit client; when nil one is configured from the environment.
+	PC *permit.PermitClient
+}
+
+func (r *RoleMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
 }
 
 // CreateRole creates a new role.
 func (r *RoleMutationResolver) CreateRole(ctx context.Context, input models.CreateRoleInput) (models.OperationResult, error) {
-	userUUID := uuid.New() // Replace with actual user ID from context
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return r.handleError("400", "Invalid user ID", err)
+	}
+	userUUID := *userID
 
 	tenantID, err := helpers.GetTenantID(ctx)
 	if err != nil {
 		return r.handleError("404", "Invalid tenant ID", err)
 	}
 
-	// resourceType, err := r.getResourceTypeByName("Role")
-	// if err != nil {
-	// 	return r.handleError("404", "Resource type not found", err)
-	// }
+	item, create, _ := r.PrepareCreateRole(ctx, input, tenantID, userUUID)
+	if item.Err != nil {
+		return r.handleItemError(item.Err)
+	}
 
-	// if err := r.checkRoleExists(input.Name, resourceType.ResourceTypeID, tenantID); err != nil {
-	// 	return r.handleError("409", "Role already exists", err)
-	// }
+	// The role, its permissions and its first revision are stored together;
+	// Permit is created last so that a failed change leaves neither modified
+	err = r.Store.Transaction(ctx, func(tx repository.Store) error {
+		if err := item.Apply(ctx, tx); err != nil {
+			return err
+		}
+		if _, err := r.permitClient().SendRequest(ctx, create.Method, create.Endpoint, create.Payload); err != nil {
+			return batch.Fail("500", "Error creating role in permit", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return r.handleItemError(err)
+	}
 
-	// if err := validatePermissions(input.Permissions); err != nil {
-	// 	return r.handleError("400", "Invalid permissions", err)
-	// }
+	return item.Result(ctx)
+}
 
-	assignableScopeRef, err := r.getAssignableScopeRef(input.AssignableScopeRef)
+// UpdateRole updates an existing role.
+func (r *RoleMutationResolver) UpdateRole(ctx context.Context, input models.UpdateRoleInput) (models.OperationResult, error) {
+	expectedRevision, err := etag.ParseOptional(input.ExpectedEtag)
 	if err != nil {
-		return r.handleError("404", "Assignable scope ref not found", err)
+		return r.handleError("400", "Invalid expectedEtag", err)
 	}
 
-	// you cant remove this DB operations because in graphql u r passing the permissions ids and in
-	// permit this ids are not there its only actions are present in permit permissions or
-	// need to add permissions get apis but this ids are need to present in DB also
-	permissionActions, permissionData, err := r.getPermissionActions(input.AssignableScopeRef.String(), input.Permissions)
+	role, err := r.getRoleByID(ctx, input.ID)
 	if err != nil {
-		return r.handleError("400", "Invalid permissions", err)
+		return r.handleError("500", "Error getting role", err)
 	}
 
-	inputMap := r.prepareInputMap(input, permissionActions, permissionData, assignableScopeRef, tenantID, userUUID)
-	permitMap := r.preparePermitMap(input, inputMap, permissionActions)
-
-	pc := permit.NewPermitClient()
-	if _, err := pc.SendRequest(ctx, "POST", fmt.Sprintf("resources/%s/roles", input.AssignableScopeRef.String()), permitMap); err != nil {
-		return r.handleError("500", "Error creating role in permit", err)
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return r.handleError("400", "Invalid user ID", err)
 	}
 
-	if err := r.createTenantResource(input, input.AssignableScopeRef, tenantID, userUUID); err != nil {
-		return r.handleError("500", "Error creating tenant resource", err)
+	item, update, _ := r.PrepareUpdateRole(ctx, role, input, expectedRevision, *userID)
+	if item.Err != nil {
+		return r.handleItemError(item.Err)
 	}
 
-	role := r.prepareRoleObject(input, userUUID)
-	if err := r.DB.Create(&role).Error; err != nil {
-		return r.handleError("500", "Error creating role", err)
+	// Permit is updated last so that a conflicting or failed change leaves
+	// neither the database nor Permit modified
+	err = r.Store.Transaction(ctx, func(tx repository.Store) error {
+		if err := item.Apply(ctx, tx); err != nil {
+			return err
+		}
+		if _, err := r.permitClient().SendRequest(ctx, update.Method, update.Endpoint, update.Payload); err != nil {
+			return batch.Fail("500", "Error updating role in permit", err)
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return r.handleError(constants.ErrorCodeConflict, "Role was modified concurrently", err)
 	}
-
-	if err := r.createRolePermissions(input.ID, input.Permissions, userUUID); err != nil {
-		return r.handleError("500", "Error creating role permissions", err)
+	if err != nil {
+		return r.handleItemError(err)
 	}
 
-	roleQueryResolver := &RoleQueryResolver{DB: r.DB}
-	return roleQueryResolver.Role(ctx, input.ID)
+	return item.Result(ctx)
 }
 
-// UpdateRole updates an existing role.
-func (r *RoleMutationResolver) UpdateRole(ctx context.Context, input models.UpdateRoleInput) (models.OperationResult, error) {
-	role, err := r.getRoleByID(input.ID)
+// RollbackRole reapplies the name, description and permission set of an earlier revision.
+// The rollback itself is recorded as a new revision.
+func (r *RoleMutationResolver) RollbackRole(ctx context.Context, input models.RollbackRoleInput) (models.OperationResult, error) {
+	role, err := r.getRoleByID(ctx, input.ID)
 	if err != nil {
 		return r.handleError("500", "Error getting role", err)
 	}
 
-	if err := r.validateUpdateRoleInput(input, role); err != nil {
-		return r.handleError("400", "Invalid input", err)
-	}
-
-	assignableScopeRef, err := r.getAssignableScopeRef(input.AssignableScopeRef)
+	revision, err := getRoleRevision(ctx, r.Store.Roles(), input.ID, input.Revision)
 	if err != nil {
-		return r.handleError("404", "Assignable scope ref not found", err)
+		return r.handleError("404", "Role revision not found", err)
 	}
 
-	permissionActions, permissionData, err := r.getPermissionActions(input.AssignableScopeRef.String(), input.Permissions)
+	permissions, err := revisionPermissions(revision)
 	if err != nil {
-		return r.handleError("400", "Invalid permissions", err)
+		return r.handleError("500", "Error reading role revision", err)
 	}
 
-	inputMap := r.prepareInputMapForUpdate(input, permissionActions, permissionData, assignableScopeRef, role)
-	permitMap := r.preparePermitMapForUpdate(input, inputMap, permissionActions)
-
-	pc := permit.NewPermitClient()
-	if _, err := pc.SendRequest(ctx, "PATCH", fmt.Sprintf("resources/%s/roles/%s", input.AssignableScopeRef, input.ID.String()), permitMap); err != nil {
-		return r.handleError("500", "Error updating role in permit", err)
+	updateInput := models.UpdateRoleInput{
+		ID:                 role.ResourceID,
+		Name:               revision.Name,
+		Description:        &revision.Description,
+		Permissions:        permissions,
+		RoleType:           roleTypeOf(role),
+		AssignableScopeRef: role.ScopeResourceTypeID,
+		Version:            role.Version,
+		ExpectedEtag:       input.ExpectedEtag,
 	}
-
-	if err := r.updateRoleDetails(role, input); err != nil {
-		return r.handleError("500", "Error updating role", err)
-	}
-
-	if err := r.updateRolePermissions(input.ID, input.Permissions, role.CreatedBy, role.UpdatedBy); err != nil {
-		return r.handleError("500", "Error updating role permissions", err)
-	}
-
-	roleQueryResolver := &RoleQueryResolver{DB: r.DB}
-	return roleQueryResolver.Role(ctx, input.ID)
+	return r.UpdateRole(ctx, updateInput)
 }
 
 // DeleteRole deletes a role.
 func (r *RoleMutationResolver) DeleteRole(ctx context.Context, input models.DeleteInput) (models.OperationResult, error) {
-	role, err := r.getRoleByID(input.ID)
+	expectedRevision, err := etag.ParseOptional(input.ExpectedEtag)
+	if err != nil {
+		return r.handleError("400", "Invalid expectedEtag", err)
+	}
+
+	role, err := r.getRoleByID(ctx, input.ID)
 	if err != nil {
 		return r.handleError("500", "Error getting role", err)
 	}
 
-	assignableScopeRef, err := r.getAssignableScopeRef(role.ScopeResourceTypeID)
+	assignableScopeRef, err := r.getAssignableScopeRef(ctx, role.ScopeResourceTypeID)
 	if err != nil {
 		return r.handleError("404", "Assignable scope ref not found", err)
 	}
 
-	pc := permit.NewPermitClient()
-	if _, err := pc.SendRequest(ctx, "DELETE", fmt.Sprintf("resources/%s/roles/%s", assignableScopeRef.ResourceTypeID, role.ResourceID.String()), nil); err != nil {
-		return r.handleError("500", "Error deleting role in permit", err)
+	err = r.deleteRoleResources(ctx, input.ID, expectedRevision, func() error {
+		if _, err := r.permitClient().SendRequest(ctx, "DELETE", fmt.Sprintf("resources/%s/roles/%s", assignableScopeRef.ResourceTypeID, role.ResourceID.String()), nil); err != nil {
+			return fmt.Errorf("failed to delete role in permit: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return r.handleError(constants.ErrorCodeConflict, "Role was modified concurrently", err)
 	}
-
-	if err := r.deleteRoleResources(input.ID); err != nil {
+	if err != nil {
 		return r.handleError("500", "Error deleting role resources", err)
 	}
 
@@ -163,58 +192,43 @@ func (r *RoleMutationResolver) handleError(code, message string, err error) (mod
 	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
 }
 
-func (r *RoleMutationResolver) getResourceTypeByName(name string) (*dto.Mst_ResourceTypes, error) {
-	var resourceType dto.Mst_ResourceTypes
-	err := r.DB.Where("name = ? AND row_status = 1", name).First(&resourceType).Error
-	if err != nil {
-		return nil, fmt.Errorf("resource type not found: %w", err)
+// handleItemError reports the failure of a prepared batch item with its code.
+func (r *RoleMutationResolver) handleItemError(err error) (models.OperationResult, error) {
+	var itemErr *batch.Error
+	if errors.As(err, &itemErr) {
+		return r.handleError(itemErr.Code, itemErr.Message, itemErr.Err)
 	}
-	return &resourceType, nil
+	return r.handleError("500", "Error updating role", err)
 }
 
-func (r *RoleMutationResolver) checkRoleExists(name string, resourceTypeID int, tenantID *uuid.UUID) error {
-	var roleExists dto.TenantResource
-	err := r.DB.Where("name = ? AND resource_type_id = ? AND tenant_id = ? AND row_status = 1", name, resourceTypeID, tenantID).First(&roleExists).Error
-	if err == nil {
-		return ErrRoleAlreadyExists
-	}
-	return nil
-}
-
-func (r *RoleMutationResolver) getAssignableScopeRef(scopeRef uuid.UUID) (*dto.Mst_ResourceTypes, error) {
-	var assignableScopeRef dto.Mst_ResourceTypes
-	err := r.DB.Where("resource_type_id = ? AND row_status = 1", scopeRef).First(&assignableScopeRef).Error
+func (r *RoleMutationResolver) getAssignableScopeRef(ctx context.Context, scopeRef uuid.UUID) (*dto.Mst_ResourceTypes, error) {
+	assignableScopeRef, err := r.Store.Resources().GetType(ctx, scopeRef)
 	if err != nil {
 		return nil, fmt.Errorf("assignable scope ref not found: %w", err)
 	}
-	return &assignableScopeRef, nil
+	return assignableScopeRef, nil
 }
 
-func (r *RoleMutationResolver) getPermissionActions(resourceID string, permissions []string) ([]string, []dto.MstPermission, error) {
+// getPermissionActions resolves permission IDs to Permit action keys using the
+// permission catalog. Every permission must belong to the role's resource type.
+func (r *RoleMutationResolver) getPermissionActions(ctx context.Context, resourceTypeID string, permissions []string) ([]string, []dto.MstPermission, error) {
 	var actions []string
 	var permissionsData []dto.MstPermission
 
 	for _, permissionID := range permissions {
-		var permission dto.MstPermission
-		err := config.DB.Where("permission_id = ? AND row_status = 1", permissionID).First(&permission).Error
+		id, err := uuid.Parse(permissionID)
 		if err != nil {
 			return nil, nil, fmt.Errorf("invalid permission ID: %w", err)
 		}
-		permissionsData = append(permissionsData, permission)
-		actions = append(actions, permission.Name)
-	}
-
-	pc := permit.NewPermitClient()
-	resourceData, err := pc.SendRequest(context.Background(), "GET", fmt.Sprintf("resources/%s", resourceID), nil)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to fetch resource data: %w", err)
-	}
-
-	actionsData := resourceData["actions"].(map[string]interface{})
-	for _, action := range actions {
-		if _, ok := actionsData[action]; !ok {
-			return nil, nil, fmt.Errorf("invalid permission action: %s", action)
+		permission, err := r.Store.Permissions().Get(ctx, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid permission ID: %w", err)
+		}
+		if permission.ResourceTypeID != resourceTypeID {
+			return nil, nil, fmt.Errorf("%w: %s is not defined for resource type %s", ErrInvalidPermissions, permission.ActionKey(), resourceTypeID)
 		}
+		permissionsData = append(permissionsData, *permission)
+		actions = append(actions, permission.ActionKey())
 	}
 
 	return actions, permissionsData, nil
@@ -246,8 +260,8 @@ func (r *RoleMutationResolver) preparePermitMap(input models.CreateRoleInput, in
 	return permitMap
 }
 
-func (r *RoleMutationResolver) createTenantResource(input models.CreateRoleInput, resourceTypeID uuid.UUID, tenantID *uuid.UUID, userID uuid.UUID) error {
-	return r.DB.Create(&dto.TenantResource{
+func (r *RoleMutationResolver) createTenantResource(ctx context.Context, store repository.Store, input models.CreateRoleInput, resourceTypeID uuid.UUID, tenantID *uuid.UUID, userID uuid.UUID) error {
+	return store.Resources().Create(ctx, &dto.TenantResource{
 		ResourceID:     input.ID,
 		ResourceTypeID: resourceTypeID,
 		Name:           input.Name,
@@ -257,7 +271,7 @@ func (r *RoleMutationResolver) createTenantResource(input models.CreateRoleInput
 		UpdatedBy:      userID,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
-	}).Error
+	})
 }
 
 func (r *RoleMutationResolver) prepareRoleObject(input models.CreateRoleInput, userID uuid.UUID) dto.TNTRole {
@@ -276,29 +290,36 @@ func (r *RoleMutationResolver) prepareRoleObject(input models.CreateRoleInput, u
 	}
 }
 
-func (r *RoleMutationResolver) createRolePermissions(roleID uuid.UUID, permissions []string, userID uuid.UUID) error {
+func (r *RoleMutationResolver) createRolePermissions(ctx context.Context, store repository.Store, roleID uuid.UUID, permissions []string, userID uuid.UUID) error {
 	for _, permissionID := range permissions {
-		if err := r.DB.Create(&dto.TNTRolePermission{
+		if err := store.Roles().AddPermission(ctx, &dto.TNTRolePermission{
 			ID:           uuid.New(),
 			RoleID:       roleID,
 			PermissionID: uuid.MustParse(permissionID),
 			RowStatus:    1,
 			CreatedBy:    userID,
 			UpdatedBy:    userID,
-		}).Error; err != nil {
+		}); err != nil {
 			return fmt.Errorf("failed to create role permission: %w", err)
 		}
 	}
 	return nil
 }
 
-func (r *RoleMutationResolver) getRoleByID(roleID uuid.UUID) (*dto.TNTRole, error) {
-	var role dto.TNTRole
-	err := r.DB.Where("resource_id = ? AND row_status = 1", roleID).First(&role).Error
+// roleTypeOf returns the GraphQL type of a stored role.
+func roleTypeOf(role *dto.TNTRole) models.RoleTypeEnum {
+	if role.RoleType == dto.RoleTypeEnumDefault {
+		return models.RoleTypeEnumDefault
+	}
+	return models.RoleTypeEnumCustom
+}
+
+func (r *RoleMutationResolver) getRoleByID(ctx context.Context, roleID uuid.UUID) (*dto.TNTRole, error) {
+	role, err := r.Store.Roles().Get(ctx, roleID)
 	if err != nil {
 		return nil, fmt.Errorf("role not found: %w", err)
 	}
-	return &role, nil
+	return role, nil
 }
 
 func (r *RoleMutationResolver) validateUpdateRoleInput(input models.UpdateRoleInput, role *dto.TNTRole) error {
@@ -338,7 +359,7 @@ func (r *RoleMutationResolver) preparePermitMapForUpdate(input models.UpdateRole
 	return permitMap
 }
 
-func (r *RoleMutationResolver) updateRoleDetails(role *dto.TNTRole, input models.UpdateRoleInput) error {
+func (r *RoleMutationResolver) updateRoleDetails(ctx context.Context, store repository.Store, role *dto.TNTRole, input models.UpdateRoleInput) error {
 	updates := map[string]interface{}{
 		"name":       input.Name,
 		"version":    input.Version,
@@ -348,12 +369,12 @@ func (r *RoleMutationResolver) updateRoleDetails(role *dto.TNTRole, input models
 	if input.Description != nil {
 		updates["description"] = *input.Description
 	}
-	return r.DB.Model(role).Where("resource_id = ? AND row_status = 1", input.ID).Updates(updates).Error
+	return store.Roles().Update(ctx, input.ID, updates)
 }
 
-func (r *RoleMutationResolver) updateRolePermissions(roleID uuid.UUID, permissions []string, createdBy, updatedBy uuid.UUID) error {
-	var existingPermissions []dto.TNTRolePermission
-	if err := r.DB.Where("role_id = ? AND row_status = 1", roleID).Find(&existingPermissions).Error; err != nil {
+func (r *RoleMutationResolver) updateRolePermissions(ctx context.Context, store repository.Store, roleID uuid.UUID, permissions []string, createdBy, updatedBy uuid.UUID) error {
+	existingPermissions, err := store.Roles().Permissions(ctx, roleID)
+	if err != nil {
 		return fmt.Errorf("failed to fetch existing permissions: %w", err)
 	}
 
@@ -367,14 +388,14 @@ func (r *RoleMutationResolver) updateRolePermissions(roleID uuid.UUID, permissio
 			}
 		}
 		if !exists {
-			if err := r.DB.Create(&dto.TNTRolePermission{
+			if err := store.Roles().AddPermission(ctx, &dto.TNTRolePermission{
 				ID:           uuid.New(),
 				RoleID:       roleID,
 				PermissionID: uuid.MustParse(permissionID),
 				RowStatus:    1,
 				CreatedBy:    createdBy,
 				UpdatedBy:    updatedBy,
-			}).Error; err != nil {
+			}); err != nil {
 				return fmt.Errorf("failed to create role permission: %w", err)
 			}
 		}
@@ -390,7 +411,7 @@ func (r *RoleMutationResolver) updateRolePermissions(roleID uuid.UUID, permissio
 			}
 		}
 		if !exists {
-			if err := r.DB.Model(&dto.TNTRolePermission{}).Where("id = ?", p.ID).Updates(utils.UpdateDeletedMap()).Error; err != nil {
+			if err := store.Roles().RemovePermission(ctx, p.ID); err != nil {
 				return fmt.Errorf("failed to delete role permission: %w", err)
 			}
 		}
@@ -399,59 +420,18 @@ func (r *RoleMutationResolver) updateRolePermissions(roleID uuid.UUID, permissio
 	return nil
 }
 
-func (r *RoleMutationResolver) deleteRoleResources(roleID uuid.UUID) error {
-	if err := r.DB.Model(&dto.TenantResource{}).Where("resource_id = ?", roleID).Updates(utils.UpdateDeletedMap()).Error; err != nil {
-		return fmt.Errorf("failed to delete tenant resource: %w", err)
-	}
-	if err := r.DB.Model(&dto.TNTRole{}).Where("resource_id = ?", roleID).Updates(utils.UpdateDeletedMap()).Error; err != nil {
-		return fmt.Errorf("failed to delete role: %w", err)
-	}
-	if err := r.DB.Model(&dto.TNTRolePermission{}).Where("role_id = ?", roleID).Updates(utils.UpdateDeletedMap()).Error; err != nil {
-		return fmt.Errorf("failed to delete role permissions: %w", err)
-	}
-	return nil
-}
-
-func validateCreateRoleInput(input models.CreateRoleInput) error {
-	if input.Name == "" || input.AssignableScopeRef == uuid.Nil || input.RoleType == "" || input.RoleType == "DEFAULT" {
-		return ErrInvalidRoleType
-	}
-	if err := utils.ValidateName(input.Name); err != nil {
-		return fmt.Errorf("invalid role name: %w", err)
-	}
-	if err := ValidateMstResType(input.AssignableScopeRef); err != nil {
-		return fmt.Errorf("invalid assignable scope ref: %w", err)
-	}
-	return nil
-}
-
-func validatePermissions(permissions []string) error {
-	for _, permissionID := range permissions {
-		if err := ValidatePermissionID(permissionID); err != nil {
-			return fmt.Errorf("invalid permission ID: %w", err)
+// deleteRoleResources marks the role, its permission grants and its
+// tnt_resources row deleted, requiring the resource to be at expectedRevision
+// when one is given. deleteInPermit runs last, and its failure rolls the
+// deletion back.
+func (r *RoleMutationResolver) deleteRoleResources(ctx context.Context, roleID uuid.UUID, expectedRevision *int, deleteInPermit func() error) error {
+	return r.Store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Roles().Delete(ctx, roleID); err != nil {
+			return fmt.Errorf("failed to delete role: %w", err)
 		}
-	}
-	return nil
-}
-
-func ValidateMstResType(resourceID uuid.UUID) error {
-	var count int64
-	if err := config.DB.Model(&dto.Mst_ResourceTypes{}).Where("resource_type_id = ? AND row_status = 1", resourceID).Count(&count).Error; err != nil {
-		return fmt.Errorf("failed to validate resource type: %w", err)
-	}
-	if count == 0 {
-		return ErrInvalidAssignableScope
-	}
-	return nil
-}
-
-func ValidatePermissionID(permissionID string) error {
-	var count int64
-	if err := config.DB.Model(&dto.MstPermission{}).Where("permission_id = ? AND row_status = 1", permissionID).Count(&count).Error; err != nil {
-		return fmt.Errorf("failed to validate permission ID: %w", err)
-	}
-	if count == 0 {
-		return ErrInvalidPermissions
-	}
-	return nil
+		if err := repository.UpdateResource(ctx, tx.Resources(), roleID, expectedRevision, utils.UpdateDeletedMap()); err != nil {
+			return fmt.Errorf("failed to delete tenant resource: %w", err)
+		}
+		return deleteInPermit()
+	})
 }
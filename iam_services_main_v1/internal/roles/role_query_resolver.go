@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"iam_services_main_v1/gql/models"
 	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/etag"
+	"iam_services_main_v1/internal/labels"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
 	"iam_services_main_v1/internal/utils"
 	"iam_services_main_v1/pkg/logger"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 var (
@@ -23,7 +26,7 @@ var (
 
 // RoleQueryResolver handles role-related queries.
 type RoleQueryResolver struct {
-	DB *gorm.DB
+	Store repository.Store
 }
 
 // Role retrieves a single role by ID.
@@ -31,9 +34,8 @@ func (r *RoleQueryResolver) Role(ctx context.Context, id uuid.UUID) (models.Oper
 	if id == uuid.Nil {
 		return r.handleError("400", "Role ID is required", ErrRoleIDRequired)
 	}
-
 	// to get role we require role scope resource id without that we will not get able to create url endpoints
-	role, err := r.getRoleFromDB(id)
+	role, err := r.getRoleFromDB(ctx, id)
 	if err != nil {
 		return r.handleError("400", "Role not found", err)
 	}
@@ -44,7 +46,7 @@ func (r *RoleQueryResolver) Role(ctx context.Context, id uuid.UUID) (models.Oper
 		return r.handleError("400", "Error retrieving role from permit system", err)
 	}
 
-	mappedRole, err := r.mapToRole(roleData)
+	mappedRole, err := r.mapToRole(ctx, roleData)
 	if err != nil {
 		return r.handleError("400", "Error mapping role data", err)
 	}
@@ -52,15 +54,20 @@ func (r *RoleQueryResolver) Role(ctx context.Context, id uuid.UUID) (models.Oper
 	return utils.FormatSuccess([]models.Data{*mappedRole})
 }
 
-// Roles retrieves all roles.
-func (r *RoleQueryResolver) Roles(ctx context.Context) (models.OperationResult, error) {
+// Roles retrieves all roles, optionally filtered by a label selector.
+func (r *RoleQueryResolver) Roles(ctx context.Context, selector *string) (models.OperationResult, error) {
+	labelSelector, err := labels.ParseOptionalSelector(selector)
+	if err != nil {
+		return r.handleError("400", "Invalid label selector", err)
+	}
+
 	pc := permit.NewPermitClient()
 	data, err := pc.SendRequest(ctx, "GET", "resources?include_total_count=true", nil)
 	if err != nil {
 		return r.handleError("400", "Error retrieving roles from permit system", err)
 	}
 
-	roles, err := r.extractRolesFromData(data)
+	roles, err := r.extractRolesFromData(ctx, data, labelSelector)
 	if err != nil {
 		return r.handleError("400", "Error extracting roles from data", err)
 	}
@@ -68,6 +75,30 @@ func (r *RoleQueryResolver) Roles(ctx context.Context) (models.OperationResult,
 	return utils.FormatSuccess(roles)
 }
 
+// DiffRoleRevisions compares revision a of a role with revision b.
+func (r *RoleQueryResolver) DiffRoleRevisions(ctx context.Context, roleID uuid.UUID, a int, b int) (models.OperationResult, error) {
+	if roleID == uuid.Nil {
+		return r.handleError("400", "Role ID is required", ErrRoleIDRequired)
+	}
+
+	from, err := getRoleRevision(ctx, r.Store.Roles(), roleID, a)
+	if err != nil {
+		return r.handleError("404", "Role revision not found", err)
+	}
+
+	to, err := getRoleRevision(ctx, r.Store.Roles(), roleID, b)
+	if err != nil {
+		return r.handleError("404", "Role revision not found", err)
+	}
+
+	diff, err := diffRoleRevisions(from, to)
+	if err != nil {
+		return r.handleError("500", "Error comparing role revisions", err)
+	}
+
+	return utils.FormatSuccess([]models.Data{diff})
+}
+
 // Helper Functions
 
 func (r *RoleQueryResolver) handleError(code, message string, err error) (models.OperationResult, error) {
@@ -76,16 +107,17 @@ func (r *RoleQueryResolver) handleError(code, message string, err error) (models
 	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
 }
 
-func (r *RoleQueryResolver) getRoleFromDB(id uuid.UUID) (*dto.TNTRole, error) {
-	var role dto.TNTRole
-	err := r.DB.Where("resource_id = ? AND row_status = 1", id).First(&role).Error
+// getRoleFromDB loads a role of the request's tenant. Root-level requests see
+// every tenant's roles.
+func (r *RoleQueryResolver) getRoleFromDB(ctx context.Context, id uuid.UUID) (*dto.TNTRole, error) {
+	role, err := r.Store.Roles().Get(tenancy.AllowRoot(ctx), id)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrRoleNotFound, err)
 	}
-	return &role, nil
+	return role, nil
 }
 
-func (r *RoleQueryResolver) extractRolesFromData(data map[string]interface{}) ([]models.Data, error) {
+func (r *RoleQueryResolver) extractRolesFromData(ctx context.Context, data map[string]interface{}, labelSelector labels.Selector) ([]models.Data, error) {
 	var roles []models.Data
 
 	for _, v := range data["data"].([]interface{}) {
@@ -95,10 +127,13 @@ func (r *RoleQueryResolver) extractRolesFromData(data map[string]interface{}) ([
 		}
 
 		for _, role := range v["roles"].(map[string]interface{}) {
-			mappedRole, err := r.mapToRole(role.(map[string]interface{}))
+			mappedRole, err := r.mapToRole(ctx, role.(map[string]interface{}))
 			if err != nil {
 				return nil, fmt.Errorf("%w: %v", ErrInvalidRoleData, err)
 			}
+			if !labelSelector.Matches(labels.FromModels(mappedRole.Labels)) {
+				continue
+			}
 			roles = append(roles, *mappedRole)
 		}
 	}
@@ -106,7 +141,7 @@ func (r *RoleQueryResolver) extractRolesFromData(data map[string]interface{}) ([
 	return roles, nil
 }
 
-func (r *RoleQueryResolver) mapToRole(roleData map[string]interface{}) (*models.Role, error) {
+func (r *RoleQueryResolver) mapToRole(ctx context.Context, roleData map[string]interface{}) (*models.Role, error) {
 	attributes, ok := roleData["attributes"].(map[string]interface{})
 	if !ok {
 		return nil, fmt.Errorf("%w: missing attributes", ErrInvalidRoleData)
@@ -134,6 +169,20 @@ func (r *RoleQueryResolver) mapToRole(roleData map[string]interface{}) (*models.
 		return nil, err
 	}
 
+	roleLabels, err := r.Store.Resources().Labels(ctx, role.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch labels: %w", err)
+	}
+	role.Labels = labels.ToModels(roleLabels)
+
+	resource, err := r.Store.Resources().Get(ctx, role.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to fetch role resource: %w", err)
+	}
+	if resource != nil {
+		role.Etag = etag.Format(resource.Revision)
+	}
+
 	return role, nil
 }
 
@@ -0,0 +1,53 @@
+package roles
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func tenantContext(tenantID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("tenantID", tenantID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func TestGetRoleFromDBIsTenantScoped(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&dto.TenantResource{}, &dto.TNTRole{}))
+	require.NoError(t, db.Use(tenancy.Plugin{}))
+
+	acme, globex := uuid.New(), uuid.New()
+	seed := func(tenantID uuid.UUID) uuid.UUID {
+		id := uuid.New()
+		require.NoError(t, db.WithContext(tenantContext(tenantID)).Create(&dto.TenantResource{ResourceID: id, ResourceTypeID: uuid.New(), Name: "role", RowStatus: 1}).Error)
+		require.NoError(t, db.Create(&dto.TNTRole{ResourceID: id, Name: "role", RowStatus: 1}).Error)
+		return id
+	}
+	acmeRole, globexRole := seed(acme), seed(globex)
+
+	resolver := &RoleQueryResolver{Store: repository.NewGormStore(db)}
+	role, err := resolver.getRoleFromDB(tenantContext(acme), acmeRole)
+	require.NoError(t, err)
+	assert.Equal(t, acmeRole, role.ResourceID)
+
+	_, err = resolver.getRoleFromDB(tenantContext(acme), globexRole)
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+
+	// Root-level requests see every tenant's roles
+	_, err = resolver.getRoleFromDB(context.Background(), globexRole)
+	assert.NoError(t, err)
+}
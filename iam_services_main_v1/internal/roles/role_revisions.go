@@ -0,0 +1,193 @@
+package roles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("role revision not found")
+)
+
+// createRoleRevision appends a new immutable revision for the role with the given state.
+func createRoleRevision(ctx context.Context, roles repository.RoleRepository, roleID uuid.UUID, name, description string, permissions []string, author uuid.UUID) (*dto.TNTRoleRevision, error) {
+	latest, err := roles.LatestRevision(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest role revision: %w", err)
+	}
+
+	sorted := append([]string(nil), permissions...)
+	sort.Strings(sorted)
+	permissionsJSON, err := json.Marshal(sorted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal revision permissions: %w", err)
+	}
+
+	revision := &dto.TNTRoleRevision{
+		RevisionID:  uuid.New(),
+		RoleID:      roleID,
+		Revision:    latest + 1,
+		Name:        name,
+		Description: description,
+		Permissions: permissionsJSON,
+		CreatedBy:   author,
+		CreatedAt:   time.Now(),
+	}
+	if err := roles.CreateRevision(ctx, revision); err != nil {
+		return nil, fmt.Errorf("failed to create role revision: %w", err)
+	}
+	return revision, nil
+}
+
+// ensureBaselineRevision snapshots the current state of a role created before revisions
+// were tracked, so the state being overwritten can still be restored.
+func ensureBaselineRevision(ctx context.Context, roles repository.RoleRepository, role *dto.TNTRole) error {
+	latest, err := roles.LatestRevision(ctx, role.ResourceID)
+	if err != nil {
+		return fmt.Errorf("failed to count role revisions: %w", err)
+	}
+	if latest > 0 {
+		return nil
+	}
+
+	rolePermissions, err := roles.Permissions(ctx, role.ResourceID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch role permissions: %w", err)
+	}
+	permissions := make([]string, 0, len(rolePermissions))
+	for _, p := range rolePermissions {
+		permissions = append(permissions, p.PermissionID.String())
+	}
+
+	_, err = createRoleRevision(ctx, roles, role.ResourceID, role.Name, role.Description, permissions, role.UpdatedBy)
+	return err
+}
+
+func getRoleRevision(ctx context.Context, roles repository.RoleRepository, roleID uuid.UUID, revision int) (*dto.TNTRoleRevision, error) {
+	roleRevision, err := roles.GetRevision(ctx, roleID, revision)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRevisionNotFound, err)
+	}
+	return roleRevision, nil
+}
+
+func listRoleRevisions(ctx context.Context, roles repository.RoleRepository, roleID uuid.UUID) ([]dto.TNTRoleRevision, error) {
+	revisions, err := roles.ListRevisions(ctx, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch role revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+func revisionPermissions(revision *dto.TNTRoleRevision) ([]string, error) {
+	var permissions []string
+	if len(revision.Permissions) == 0 {
+		return permissions, nil
+	}
+	if err := json.Unmarshal(revision.Permissions, &permissions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal revision permissions: %w", err)
+	}
+	return permissions, nil
+}
+
+func mapToRoleRevision(revision *dto.TNTRoleRevision) (*models.RoleRevision, error) {
+	permissions, err := revisionPermissions(revision)
+	if err != nil {
+		return nil, err
+	}
+
+	permissionIDs := make([]uuid.UUID, 0, len(permissions))
+	for _, p := range permissions {
+		id, err := uuid.Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUUIDFormat, err)
+		}
+		permissionIDs = append(permissionIDs, id)
+	}
+
+	result := &models.RoleRevision{
+		ID:          revision.RevisionID,
+		RoleID:      revision.RoleID,
+		Revision:    revision.Revision,
+		Name:        revision.Name,
+		Permissions: permissionIDs,
+		CreatedBy:   revision.CreatedBy,
+		CreatedAt:   revision.CreatedAt.Format(time.RFC3339),
+	}
+	if revision.Description != "" {
+		description := revision.Description
+		result.Description = &description
+	}
+	return result, nil
+}
+
+// diffRoleRevisions compares two revisions of the same role.
+func diffRoleRevisions(from, to *dto.TNTRoleRevision) (*models.RoleRevisionDiff, error) {
+	fromPermissions, err := revisionPermissions(from)
+	if err != nil {
+		return nil, err
+	}
+	toPermissions, err := revisionPermissions(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.RoleRevisionDiff{
+		RoleID:             from.RoleID,
+		FromRevision:       from.Revision,
+		ToRevision:         to.Revision,
+		AddedPermissions:   []uuid.UUID{},
+		RemovedPermissions: []uuid.UUID{},
+		Changes:            []*models.FieldChange{},
+	}
+
+	if from.Name != to.Name {
+		diff.Changes = append(diff.Changes, &models.FieldChange{Field: "name", From: &from.Name, To: &to.Name})
+	}
+	if from.Description != to.Description {
+		diff.Changes = append(diff.Changes, &models.FieldChange{Field: "description", From: &from.Description, To: &to.Description})
+	}
+
+	added, err := permissionDifference(toPermissions, fromPermissions)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := permissionDifference(fromPermissions, toPermissions)
+	if err != nil {
+		return nil, err
+	}
+	diff.AddedPermissions = added
+	diff.RemovedPermissions = removed
+
+	return diff, nil
+}
+
+// permissionDifference returns the permission IDs present in a but not in b.
+func permissionDifference(a, b []string) ([]uuid.UUID, error) {
+	present := make(map[string]bool, len(b))
+	for _, p := range b {
+		present[p] = true
+	}
+
+	result := []uuid.UUID{}
+	for _, p := range a {
+		if present[p] {
+			continue
+		}
+		id, err := uuid.Parse(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUUIDFormat, err)
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
@@ -0,0 +1,123 @@
+package roles
+
+import (
+	"context"
+	"testing"
+
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupRevisionTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.TNTRoleRevision{}, &dto.TNTRolePermission{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	return db
+}
+
+func TestCreateRoleRevisionIncrementsRevision(t *testing.T) {
+	roleRepo := repository.NewGormStore(setupRevisionTestDB(t)).Roles()
+	ctx := context.Background()
+	roleID := uuid.New()
+	author := uuid.New()
+	p1, p2 := uuid.NewString(), uuid.NewString()
+
+	first, err := createRoleRevision(ctx, roleRepo, roleID, "Editor", "edits things", []string{p1}, author)
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.Revision)
+
+	second, err := createRoleRevision(ctx, roleRepo, roleID, "Editor", "edits things", []string{p1, p2}, author)
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.Revision)
+
+	revisions, err := listRoleRevisions(ctx, roleRepo, roleID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 2, revisions[0].Revision)
+}
+
+func TestRoleRevisionsAreImmutable(t *testing.T) {
+	db := setupRevisionTestDB(t)
+	revision, err := createRoleRevision(context.Background(), repository.NewGormStore(db).Roles(), uuid.New(), "Viewer", "", nil, uuid.New())
+	require.NoError(t, err)
+
+	err = db.Model(revision).Update("name", "Changed").Error
+	assert.ErrorIs(t, err, dto.ErrImmutableRecord)
+
+	err = db.Delete(revision).Error
+	assert.ErrorIs(t, err, dto.ErrImmutableRecord)
+}
+
+func TestEnsureBaselineRevision(t *testing.T) {
+	db := setupRevisionTestDB(t)
+	roleRepo := repository.NewGormStore(db).Roles()
+	ctx := context.Background()
+	role := &dto.TNTRole{ResourceID: uuid.New(), Name: "Admin", UpdatedBy: uuid.New()}
+	permissionID := uuid.New()
+	require.NoError(t, db.Create(&dto.TNTRolePermission{ID: uuid.New(), RoleID: role.ResourceID, PermissionID: permissionID, RowStatus: 1}).Error)
+
+	require.NoError(t, ensureBaselineRevision(ctx, roleRepo, role))
+	require.NoError(t, ensureBaselineRevision(ctx, roleRepo, role))
+
+	revisions, err := listRoleRevisions(ctx, roleRepo, role.ResourceID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+
+	permissions, err := revisionPermissions(&revisions[0])
+	require.NoError(t, err)
+	assert.Equal(t, []string{permissionID.String()}, permissions)
+}
+
+func TestDiffRoleRevisions(t *testing.T) {
+	roleID := uuid.New()
+	keep, dropped, added := uuid.New(), uuid.New(), uuid.New()
+	roleRepo := repository.NewGormStore(setupRevisionTestDB(t)).Roles()
+
+	from, err := createRoleRevision(context.Background(), roleRepo, roleID, "Editor", "old", []string{keep.String(), dropped.String()}, uuid.New())
+	require.NoError(t, err)
+	to, err := createRoleRevision(context.Background(), roleRepo, roleID, "Writer", "old", []string{keep.String(), added.String()}, uuid.New())
+	require.NoError(t, err)
+
+	diff, err := diffRoleRevisions(from, to)
+	require.NoError(t, err)
+	assert.Equal(t, 1, diff.FromRevision)
+	assert.Equal(t, 2, diff.ToRevision)
+	assert.Equal(t, []uuid.UUID{added}, diff.AddedPermissions)
+	assert.Equal(t, []uuid.UUID{dropped}, diff.RemovedPermissions)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "name", diff.Changes[0].Field)
+	assert.Equal(t, "Writer", *diff.Changes[0].To)
+}
+
+// staleRevisions reports no revisions, as a concurrent update that read the
+// latest revision before another one was written would.
+type staleRevisions struct{ repository.RoleRepository }
+
+func (staleRevisions) LatestRevision(context.Context, uuid.UUID) (int, error) { return 0, nil }
+
+func TestCreateRoleRevisionConflictsOnConcurrentRevision(t *testing.T) {
+	roleRepo := repository.NewGormStore(setupRevisionTestDB(t)).Roles()
+	ctx := context.Background()
+	roleID := uuid.New()
+
+	_, err := createRoleRevision(ctx, roleRepo, roleID, "Editor", "", nil, uuid.New())
+	require.NoError(t, err)
+
+	_, err = createRoleRevision(ctx, staleRevisions{roleRepo}, roleID, "Writer", "", nil, uuid.New())
+	assert.ErrorIs(t, err, repository.ErrConflict)
+
+	revisions, err := listRoleRevisions(ctx, roleRepo, roleID)
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, "Editor", revisions[0].Name)
+}
@@ -0,0 +1,117 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Usage describes the scim-token subcommand.
+const Usage = `usage: scim-token <command>
+
+commands:
+  issue <tenant-id> [description]  issue a SCIM token for a tenant; it is only shown once
+  revoke <token-id>                stop a token from authenticating
+  list [tenant-id]                 list the tokens of a tenant or of every tenant`
+
+var ErrUsage = errors.New(Usage)
+
+// RunCommand runs the scim-token subcommand given its arguments and writes
+// its report to out.
+func RunCommand(ctx context.Context, tokens *TokenStore, store repository.Store, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "issue":
+		if len(args) < 2 {
+			return ErrUsage
+		}
+		tenantID, err := uuid.Parse(args[1])
+		if err != nil {
+			return ErrUsage
+		}
+		if err := checkTenant(ctx, store, tenantID); err != nil {
+			return err
+		}
+		token, record, err := tokens.Issue(ctx, tenantID, strings.Join(args[2:], " "))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "issued token %s for tenant %s\n%s\n", record.TokenID, tenantID, token)
+		return nil
+
+	case "revoke":
+		if len(args) != 2 {
+			return ErrUsage
+		}
+		tokenID, err := uuid.Parse(args[1])
+		if err != nil {
+			return ErrUsage
+		}
+		if err := tokens.Revoke(ctx, tokenID); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "revoked token %s\n", tokenID)
+		return nil
+
+	case "list":
+		var tenantID *uuid.UUID
+		switch len(args) {
+		case 1:
+		case 2:
+			id, err := uuid.Parse(args[1])
+			if err != nil {
+				return ErrUsage
+			}
+			tenantID = &id
+		default:
+			return ErrUsage
+		}
+		records, err := tokens.List(ctx, tenantID)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TOKEN\tTENANT\tCREATED AT\tREVOKED AT\tDESCRIPTION")
+		for _, record := range records {
+			revokedAt := "-"
+			if record.RevokedAt != nil {
+				revokedAt = record.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", record.TokenID, record.TenantID, record.CreatedAt.Format(time.RFC3339), revokedAt, record.Description)
+		}
+		return w.Flush()
+	}
+	return ErrUsage
+}
+
+// checkTenant fails unless tenantID is a tenant.
+func checkTenant(ctx context.Context, store repository.Store, tenantID uuid.UUID) error {
+	ctx = tenancy.WithTenant(ctx, tenantID)
+	resource, err := store.Resources().Get(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("tenant %s not found", tenantID)
+	}
+	if err != nil {
+		return err
+	}
+	resourceType, err := store.Resources().GetType(ctx, resource.ResourceTypeID)
+	if err != nil {
+		return err
+	}
+	if resourceType.Name != constants.ResourceTypeTenant {
+		return fmt.Errorf("%s is not a tenant", tenantID)
+	}
+	return nil
+}
@@ -0,0 +1,434 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// filter is a parsed SCIM filter expression (RFC 7644, section 3.4.2.2). It
+// is matched against the JSON representation of a resource.
+type filter interface {
+	match(resource map[string]interface{}) bool
+}
+
+type logical struct {
+	and         bool
+	left, right filter
+}
+
+func (f logical) match(resource map[string]interface{}) bool {
+	if f.and {
+		return f.left.match(resource) && f.right.match(resource)
+	}
+	return f.left.match(resource) || f.right.match(resource)
+}
+
+type negation struct{ filter filter }
+
+func (f negation) match(resource map[string]interface{}) bool {
+	return !f.filter.match(resource)
+}
+
+// comparison compares the values of an attribute with a literal, or tests
+// their presence when op is "pr".
+type comparison struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (f comparison) match(resource map[string]interface{}) bool {
+	values := lookup(resource, f.path)
+	if f.op == "pr" {
+		for _, value := range values {
+			if value != nil && value != "" {
+				return true
+			}
+		}
+		return false
+	}
+	if f.op == "ne" {
+		return !(comparison{path: f.path, op: "eq", value: f.value}).match(resource)
+	}
+	for _, value := range values {
+		if compare(value, f.op, f.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePath matches resources with an element of a multi-valued attribute
+// matching filter, as in emails[type eq "work"].
+type valuePath struct {
+	path   []string
+	filter filter
+}
+
+func (f valuePath) match(resource map[string]interface{}) bool {
+	for _, element := range lookup(resource, f.path) {
+		if object, ok := element.(map[string]interface{}); ok && f.filter.match(object) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the values of an attribute path, flattening multi-valued
+// attributes. Attribute names are case-insensitive.
+func lookup(resource map[string]interface{}, path []string) []interface{} {
+	values := []interface{}{resource}
+	for _, name := range path {
+		var next []interface{}
+		for _, value := range values {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, ok := attributeKey(object, name)
+			if !ok {
+				continue
+			}
+			if elements, ok := object[key].([]interface{}); ok {
+				next = append(next, elements...)
+			} else {
+				next = append(next, object[key])
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// attributeKey returns the key of object naming attribute name.
+func attributeKey(object map[string]interface{}, name string) (string, bool) {
+	if _, ok := object[name]; ok {
+		return name, true
+	}
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// compare applies op to an attribute value and a literal. Strings compare
+// case-insensitively; complex values compare by their "value" sub-attribute.
+func compare(value interface{}, op string, literal interface{}) bool {
+	if object, ok := value.(map[string]interface{}); ok {
+		value = object["value"]
+	}
+	switch v := value.(type) {
+	case string:
+		s, ok := literal.(string)
+		if !ok {
+			return false
+		}
+		v, s = strings.ToLower(v), strings.ToLower(s)
+		switch op {
+		case "eq":
+			return v == s
+		case "co":
+			return strings.Contains(v, s)
+		case "sw":
+			return strings.HasPrefix(v, s)
+		case "ew":
+			return strings.HasSuffix(v, s)
+		case "gt":
+			return v > s
+		case "ge":
+			return v >= s
+		case "lt":
+			return v < s
+		case "le":
+			return v <= s
+		}
+	case bool:
+		b, ok := literal.(bool)
+		return ok && op == "eq" && v == b
+	case float64:
+		n, ok := literal.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return v == n
+		case "gt":
+			return v > n
+		case "ge":
+			return v >= n
+		case "lt":
+			return v < n
+		case "le":
+			return v <= n
+		}
+	case nil:
+		return op == "eq" && literal == nil
+	}
+	return false
+}
+
+var operators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true, "pr": true,
+}
+
+// parseFilter parses a filter expression.
+func parseFilter(expression string) (filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	f, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	return f, nil
+}
+
+// parsePath parses a PATCH path: an attribute path, optionally followed by a
+// filter on its elements and a sub-attribute, as in emails[type eq "work"].value.
+func parsePath(path string) (attribute []string, elements filter, subAttribute string, err error) {
+	tokens, err := tokenize(path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	p := &parser{tokens: tokens}
+	name := p.next()
+	if name.kind != tokenName {
+		return nil, nil, "", fmt.Errorf("invalid path %q", path)
+	}
+	attribute = attributePath(name.text)
+	if p.peek().kind == tokenOpenBracket {
+		p.next()
+		if elements, err = p.or(); err != nil {
+			return nil, nil, "", err
+		}
+		if p.next().kind != tokenCloseBracket {
+			return nil, nil, "", fmt.Errorf("invalid path %q: missing ]", path)
+		}
+		if p.peek().kind == tokenName && strings.HasPrefix(p.peek().text, ".") {
+			subAttribute = strings.TrimPrefix(p.next().text, ".")
+		}
+	}
+	if !p.done() {
+		return nil, nil, "", fmt.Errorf("invalid path %q", path)
+	}
+	return attribute, elements, subAttribute, nil
+}
+
+// attributePath splits an attribute name, dropping a schema URN prefix such
+// as urn:ietf:params:scim:schemas:core:2.0:User:.
+func attributePath(name string) []string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Split(name, ".")
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenName
+	tokenValue
+	tokenOpenParen
+	tokenCloseParen
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+}
+
+func tokenize(expression string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpenParen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenCloseParen, text: ")"})
+			i++
+		case r == '[':
+			tokens = append(tokens, token{kind: tokenOpenBracket, text: "["})
+			i++
+		case r == ']':
+			tokens = append(tokens, token{kind: tokenCloseBracket, text: "]"})
+			i++
+		case r == '"':
+			end := i + 1
+			for ; end < len(runes) && runes[end] != '"'; end++ {
+				if runes[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(runes) {
+				return nil, fmt.Errorf("unterminated string in %q", expression)
+			}
+			var s string
+			if err := json.Unmarshal([]byte(string(runes[i:end+1])), &s); err != nil {
+				return nil, fmt.Errorf("invalid string in %q: %w", expression, err)
+			}
+			tokens = append(tokens, token{kind: tokenValue, text: s, value: s})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()[]"`, runes[end]) {
+				end++
+			}
+			text := string(runes[i:end])
+			tokens = append(tokens, word(text))
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// word classifies an unquoted token as a literal or a name.
+func word(text string) token {
+	switch strings.ToLower(text) {
+	case "true":
+		return token{kind: tokenValue, text: text, value: true}
+	case "false":
+		return token{kind: tokenValue, text: text, value: false}
+	case "null":
+		return token{kind: tokenValue, text: text, value: nil}
+	}
+	if n, err := strconv.ParseFloat(text, 64); err == nil {
+		return token{kind: tokenValue, text: text, value: n}
+	}
+	return token{kind: tokenName, text: text}
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{kind: tokenEnd}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword reports whether the next token is the case-insensitive keyword.
+func (p *parser) keyword(keyword string) bool {
+	t := p.peek()
+	return t.kind == tokenName && strings.EqualFold(t.text, keyword)
+}
+
+func (p *parser) or() (filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (filter, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) factor() (filter, error) {
+	if p.keyword("not") {
+		p.next()
+		if p.peek().kind != tokenOpenParen {
+			return nil, fmt.Errorf("expected ( after not")
+		}
+		f, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return negation{filter: f}, nil
+	}
+	if p.peek().kind == tokenOpenParen {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenCloseParen {
+			return nil, fmt.Errorf("missing )")
+		}
+		return f, nil
+	}
+
+	name := p.next()
+	if name.kind != tokenName {
+		return nil, fmt.Errorf("expected an attribute, got %q", name.text)
+	}
+	path := attributePath(name.text)
+	if p.peek().kind == tokenOpenBracket {
+		p.next()
+		f, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenCloseBracket {
+			return nil, fmt.Errorf("missing ]")
+		}
+		return valuePath{path: path, filter: f}, nil
+	}
+
+	op := p.next()
+	if op.kind != tokenName || !operators[strings.ToLower(op.text)] {
+		return nil, fmt.Errorf("expected an operator after %s, got %q", name.text, op.text)
+	}
+	comparison := comparison{path: path, op: strings.ToLower(op.text)}
+	if comparison.op == "pr" {
+		return comparison, nil
+	}
+	value := p.next()
+	if value.kind != tokenValue {
+		return nil, fmt.Errorf("expected a value after %s %s, got %q", name.text, op.text, value.text)
+	}
+	comparison.value = value.value
+	return comparison, nil
+}
@@ -0,0 +1,120 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func document(t *testing.T, resource string) map[string]interface{} {
+	var document map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(resource), &document))
+	return document
+}
+
+const alice = `{
+	"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+	"id": "2819c223-7f76-453a-919d-413861904646",
+	"externalId": "00u1",
+	"userName": "alice",
+	"name": {"givenName": "Alice", "familyName": "Smith"},
+	"emails": [{"value": "alice@acme.test", "type": "work", "primary": true}, {"value": "alice@home.test", "type": "home"}],
+	"active": true,
+	"meta": {"created": "2024-05-01T10:00:00Z"}
+}`
+
+func TestParseFilter(t *testing.T) {
+	user := document(t, alice)
+	for expression, matches := range map[string]bool{
+		`userName eq "alice"`:                               true,
+		`UserName EQ "ALICE"`:                               true,
+		`userName ne "alice"`:                               false,
+		`userName sw "al" and name.familyName co "mit"`:     true,
+		`userName ew "bob" or externalId eq "00u1"`:         true,
+		`not (active eq true)`:                              false,
+		`active eq true and (userName eq "bob" or name pr)`: true,
+		`title pr`:                                                       false,
+		`emails eq "alice@home.test"`:                                    true,
+		`emails.value ew "@acme.test"`:                                   true,
+		`emails[type eq "work" and value co "home"]`:                     false,
+		`emails[type eq "home" and value co "home"]`:                     true,
+		`meta.created gt "2024-01-01T00:00:00Z"`:                         true,
+		`urn:ietf:params:scim:schemas:core:2.0:User:userName eq "alice"`: true,
+	} {
+		f, err := parseFilter(expression)
+		require.NoError(t, err, expression)
+		assert.Equal(t, matches, f.match(user), expression)
+	}
+
+	for _, expression := range []string{`userName`, `userName eq`, `userName is "alice"`, `(userName eq "alice"`, `userName eq "alice`, `not userName eq "alice"`, `userName eq "alice" extra`} {
+		_, err := parseFilter(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	patch := func(t *testing.T, resource string, operations string) (map[string]interface{}, *Error) {
+		var ops []PatchOperation
+		require.NoError(t, json.Unmarshal([]byte(operations), &ops))
+		d := document(t, resource)
+		return d, applyPatch(d, ops)
+	}
+
+	user, err := patch(t, alice, `[
+		{"op": "replace", "path": "active", "value": false},
+		{"op": "replace", "path": "name.givenName", "value": "Alicia"},
+		{"op": "add", "path": "displayName", "value": "Alicia Smith"},
+		{"op": "remove", "path": "externalId"},
+		{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "alicia@acme.test"},
+		{"op": "remove", "path": "emails[type eq \"home\"]"}
+	]`)
+	require.Nil(t, err)
+	assert.Equal(t, false, user["active"])
+	assert.Equal(t, map[string]interface{}{"givenName": "Alicia", "familyName": "Smith"}, user["name"])
+	assert.Equal(t, "Alicia Smith", user["displayName"])
+	assert.NotContains(t, user, "externalId")
+	assert.Equal(t, []interface{}{map[string]interface{}{"value": "alicia@acme.test", "type": "work", "primary": true}}, user["emails"])
+
+	// Operations without a path set each attribute of their value
+	user, err = patch(t, alice, `[{"op": "Replace", "value": {"userName": "alicia", "name": {"familyName": "Jones"}}}]`)
+	require.Nil(t, err)
+	assert.Equal(t, "alicia", user["userName"])
+	assert.Equal(t, map[string]interface{}{"givenName": "Alice", "familyName": "Jones"}, user["name"])
+
+	group := `{"displayName": "admins", "members": [{"value": "a"}, {"value": "b"}]}`
+	members := func(d map[string]interface{}) []string {
+		var values []string
+		for _, member := range d["members"].([]interface{}) {
+			values = append(values, member.(map[string]interface{})["value"].(string))
+		}
+		return values
+	}
+	patched, err := patch(t, group, `[{"op": "add", "path": "members", "value": [{"value": "b"}, {"value": "c"}]}]`)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, members(patched))
+	patched, err = patch(t, group, `[{"op": "remove", "path": "members[value eq \"a\"]"}]`)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"b"}, members(patched))
+	// Some clients list the members to remove as the value
+	patched, err = patch(t, group, `[{"op": "remove", "path": "members", "value": [{"value": "b"}]}]`)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"a"}, members(patched))
+	patched, err = patch(t, group, `[{"op": "replace", "path": "members", "value": [{"value": "c"}]}]`)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"c"}, members(patched))
+
+	for operations, scimType := range map[string]string{
+		`[]`:                                      "invalidSyntax",
+		`[{"op": "move", "path": "userName"}]`:    "invalidSyntax",
+		`[{"op": "remove"}]`:                      "noTarget",
+		`[{"op": "replace", "path": "a[b"}]`:      "invalidPath",
+		`[{"op": "replace", "path": "userName"}]`: "invalidValue",
+		`[{"op": "remove", "path": "members[value eq \"z\"]"}]`: "noTarget",
+	} {
+		_, err := patch(t, group, operations)
+		require.NotNil(t, err, operations)
+		assert.Equal(t, scimType, err.ScimType, operations)
+	}
+}
@@ -0,0 +1,364 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// groupProfile is the part of a group kept in the metadata of its principal.
+// The display name is the name of the resource and of the principal.
+type groupProfile struct {
+	ExternalID string `json:"externalId,omitempty"`
+}
+
+// memberTypes returns the names of the resource types of members by ID.
+func (h *Handler) memberTypes(r *request) (map[uuid.UUID]string, *Error) {
+	types := map[uuid.UUID]string{}
+	for _, name := range []string{constants.ResourceTypeUser, constants.ResourceTypeGroup} {
+		resourceType, scimErr := h.resourceType(r, name)
+		if scimErr != nil {
+			return nil, scimErr
+		}
+		types[resourceType.ResourceTypeID] = name
+	}
+	return types, nil
+}
+
+// toGroup returns the SCIM representation of a group.
+func (h *Handler) toGroup(c *gin.Context, r *request, resource *dto.TenantResource, principal *dto.TenantPrincipals, types map[uuid.UUID]string) (*Group, *Error) {
+	var profile groupProfile
+	if principal != nil {
+		_ = json.Unmarshal(principal.Metadata, &profile)
+	}
+
+	memberships, err := h.Store.Members().ListByGroup(r.ctx, resource.ResourceID)
+	if err != nil {
+		return nil, internalError("Error listing members", err)
+	}
+	members := []Member{}
+	for _, membership := range memberships {
+		member, err := h.Store.Resources().Get(r.ctx, membership.MemberID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, internalError("Error getting member", err)
+		}
+		typeName := types[member.ResourceTypeID]
+		members = append(members, Member{
+			Value:   member.ResourceID.String(),
+			Ref:     h.location(c, typeName+"s", member.ResourceID),
+			Display: member.Name,
+			Type:    typeName,
+		})
+	}
+
+	return &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          resource.ResourceID.String(),
+		ExternalID:  profile.ExternalID,
+		DisplayName: resource.Name,
+		Members:     members,
+		Meta:        meta(constants.ResourceTypeGroup, resource, h.location(c, "Groups", resource.ResourceID)),
+	}, nil
+}
+
+// validateGroup checks a group about to be stored as group id, which is nil
+// for a new group, and returns the IDs of its members.
+func (h *Handler) validateGroup(r *request, group *Group, id *uuid.UUID) ([]uuid.UUID, *Error) {
+	group.DisplayName = strings.TrimSpace(group.DisplayName)
+	switch {
+	case group.DisplayName == "":
+		return nil, invalidValue("displayName is required")
+	case len(group.DisplayName) > maxNameLength:
+		return nil, invalidValue("displayName is longer than %d characters", maxNameLength)
+	}
+
+	resources, _, scimErr := h.principals(r, constants.ResourceTypeGroup)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	for _, resource := range resources {
+		if strings.EqualFold(resource.Name, group.DisplayName) && (id == nil || resource.ResourceID != *id) {
+			return nil, uniqueness("displayName %q is already used", group.DisplayName)
+		}
+	}
+
+	types, scimErr := h.memberTypes(r)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	var memberIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, member := range group.Members {
+		memberID, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, invalidValue("member %q is not a user or group", member.Value)
+		}
+		if seen[memberID] {
+			continue
+		}
+		if id != nil && memberID == *id {
+			return nil, invalidValue("a group cannot be a member of itself")
+		}
+		resource, err := h.Store.Resources().Get(r.ctx, memberID)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && types[resource.ResourceTypeID] == "") {
+			return nil, invalidValue("member %s is not a user or group of the tenant", memberID)
+		}
+		if err != nil {
+			return nil, internalError("Error getting member", err)
+		}
+		seen[memberID] = true
+		memberIDs = append(memberIDs, memberID)
+	}
+	return memberIDs, nil
+}
+
+// saveMembers makes memberIDs the members of a group.
+func saveMembers(ctx context.Context, tx repository.Store, groupID, actorID uuid.UUID, memberIDs []uuid.UUID) error {
+	current, err := tx.Members().ListByGroup(ctx, groupID)
+	if err != nil {
+		return batch.Fail("500", "Error listing members", err)
+	}
+	wanted := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, memberID := range memberIDs {
+		wanted[memberID] = true
+	}
+	for _, membership := range current {
+		if wanted[membership.MemberID] {
+			delete(wanted, membership.MemberID)
+			continue
+		}
+		if err := tx.Members().Remove(ctx, groupID, membership.MemberID); err != nil {
+			return batch.Fail("500", "Error removing member", err)
+		}
+	}
+	for _, memberID := range memberIDs {
+		if !wanted[memberID] {
+			continue
+		}
+		if err := tx.Members().Add(ctx, &dto.GroupMember{GroupID: groupID, MemberID: memberID, CreatedBy: actorID}); err != nil {
+			return batch.Fail("500", "Error adding member", err)
+		}
+	}
+	return nil
+}
+
+func groupProfileOf(group *Group) (dto.JSON, *Error) {
+	encoded, err := json.Marshal(groupProfile{ExternalID: group.ExternalID})
+	if err != nil {
+		return nil, internalError("Error encoding group", err)
+	}
+	return dto.JSON(encoded), nil
+}
+
+func (h *Handler) readGroup(c *gin.Context, r *request, id string) (*Group, *Error) {
+	resource, principal, scimErr := h.lookup(r, constants.ResourceTypeGroup, id)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	types, scimErr := h.memberTypes(r)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	return h.toGroup(c, r, resource, principal, types)
+}
+
+func (h *Handler) listGroups(c *gin.Context) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	resources, principals, scimErr := h.principals(r, constants.ResourceTypeGroup)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	types, scimErr := h.memberTypes(r)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	groups := make([]interface{}, len(resources))
+	for i := range resources {
+		if groups[i], scimErr = h.toGroup(c, r, &resources[i], principals[i], types); scimErr != nil {
+			abort(c, scimErr)
+			return
+		}
+	}
+	list, scimErr := page(c, groups)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	respond(c, http.StatusOK, list)
+}
+
+func (h *Handler) getGroup(c *gin.Context) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	group, scimErr := h.readGroup(c, r, c.Param("id"))
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	if notModified(c, group.Meta.Version) {
+		return
+	}
+	respondResource(c, http.StatusOK, group, group.Meta)
+}
+
+func (h *Handler) createGroup(c *gin.Context) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	var group Group
+	if scimErr := decode(c, &group); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	memberIDs, scimErr := h.validateGroup(r, &group, nil)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	groupType, scimErr := h.resourceType(r, constants.ResourceTypeGroup)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	metadata, scimErr := groupProfileOf(&group)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	resource := newResource(r, groupType, group.DisplayName)
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Resources().Create(ctx, resource); err != nil {
+			return batch.Fail("500", "Error creating group", err)
+		}
+		if err := savePrincipal(ctx, tx, resource, nil, r.actorID, group.DisplayName, "", metadata); err != nil {
+			return err
+		}
+		return saveMembers(ctx, tx, resource.ResourceID, r.actorID, memberIDs)
+	}, Result: applied}
+	if scimErr := h.apply(c, r, constants.ResourceTypeGroup, resource.ResourceID, []batch.Item{item}, []batch.Step{instanceStep(resource)}, false); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	created, scimErr := h.readGroup(c, r, resource.ResourceID.String())
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	respondResource(c, http.StatusCreated, created, created.Meta)
+}
+
+func (h *Handler) replaceGroup(c *gin.Context) {
+	h.updateGroup(c, func(current, group *Group) *Error {
+		return decode(c, group)
+	})
+}
+
+func (h *Handler) patchGroup(c *gin.Context) {
+	h.updateGroup(c, func(current, group *Group) *Error {
+		return patched(c, current, group)
+	})
+}
+
+// updateGroup replaces a group and its members with the group change
+// returns. Membership changes advance the group's version.
+func (h *Handler) updateGroup(c *gin.Context, change func(current, group *Group) *Error) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	resource, principal, scimErr := h.lookup(r, constants.ResourceTypeGroup, c.Param("id"))
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	types, scimErr := h.memberTypes(r)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	current, scimErr := h.toGroup(c, r, resource, principal, types)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	expected, scimErr := ifMatch(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	conditional := expected != nil
+	if expected == nil {
+		// The change is computed from the current group
+		expected = &resource.Revision
+	}
+
+	var group Group
+	if scimErr := change(current, &group); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	id := resource.ResourceID
+	memberIDs, scimErr := h.validateGroup(r, &group, &id)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	metadata, scimErr := groupProfileOf(&group)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	now := time.Now()
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := repository.UpdateResource(ctx, tx.Resources(), id, expected, map[string]interface{}{
+			"name":       group.DisplayName,
+			"updated_by": r.actorID,
+			"updated_at": now,
+		}); err != nil {
+			return resourceError("Error updating group", err)
+		}
+		if err := savePrincipal(ctx, tx, resource, principal, r.actorID, group.DisplayName, "", metadata); err != nil {
+			return err
+		}
+		return saveMembers(ctx, tx, id, r.actorID, memberIDs)
+	}, Result: applied}
+	if scimErr := h.apply(c, r, constants.ResourceTypeGroup, id, []batch.Item{item}, []batch.Step{{Phase: phaseInstance}}, conditional); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	updated, scimErr := h.readGroup(c, r, id.String())
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	respondResource(c, http.StatusOK, updated, updated.Meta)
+}
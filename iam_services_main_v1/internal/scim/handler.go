@@ -0,0 +1,532 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/etag"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Phases order the Permit requests of a deletion: bindings of a principal are
+// removed before its resource instance.
+const (
+	phaseBindings = iota
+	phaseInstance
+)
+
+// Handler serves the SCIM endpoints of the tenant of each request's token.
+type Handler struct {
+	Store repository.Store
+	// PC is the Permit client; when nil one is configured from the environment.
+	PC *permit.PermitClient
+	// DB holds the audit log the changes are recorded in, and Publishers
+	// receive their events; changes are not recorded when DB is nil.
+	DB         *gorm.DB
+	Publishers []audit.Publisher
+
+	basePath string
+}
+
+func (h *Handler) permitClient() *permit.PermitClient {
+	if h.PC != nil {
+		return h.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// Register adds the SCIM endpoints to routes, which must authenticate
+// requests with Authenticate and attach the Gin context to the request
+// context.
+func Register(routes *gin.RouterGroup, h *Handler) {
+	h.basePath = routes.BasePath()
+	routes.GET("/ServiceProviderConfig", func(c *gin.Context) { respond(c, http.StatusOK, serviceProviderConfig()) })
+
+	routes.GET("/Users", h.listUsers)
+	routes.POST("/Users", h.createUser)
+	routes.GET("/Users/:id", h.getUser)
+	routes.PUT("/Users/:id", h.replaceUser)
+	routes.PATCH("/Users/:id", h.patchUser)
+	routes.DELETE("/Users/:id", h.deletePrincipal(constants.ResourceTypeUser))
+
+	routes.GET("/Groups", h.listGroups)
+	routes.POST("/Groups", h.createGroup)
+	routes.GET("/Groups/:id", h.getGroup)
+	routes.PUT("/Groups/:id", h.replaceGroup)
+	routes.PATCH("/Groups/:id", h.patchGroup)
+	routes.DELETE("/Groups/:id", h.deletePrincipal(constants.ResourceTypeGroup))
+}
+
+// request is the tenant and actor of an authenticated request.
+type request struct {
+	ctx      context.Context
+	tenantID uuid.UUID
+	actorID  uuid.UUID
+}
+
+func (h *Handler) request(c *gin.Context) (*request, *Error) {
+	ctx := c.Request.Context()
+	tenantID := tenancy.TenantFromContext(ctx)
+	actorID, err := helpers.GetUserID(ctx)
+	if tenantID == nil || err != nil {
+		return nil, newError(http.StatusUnauthorized, "", "the request is not authenticated")
+	}
+	return &request{ctx: ctx, tenantID: *tenantID, actorID: *actorID}, nil
+}
+
+// location returns the URL of a resource.
+func (h *Handler) location(c *gin.Context, endpoint string, id uuid.UUID) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s/%s/%s", scheme, c.Request.Host, h.basePath, endpoint, id)
+}
+
+func meta(resourceType string, resource *dto.TenantResource, location string) *Meta {
+	return &Meta{
+		ResourceType: resourceType,
+		Created:      resource.CreatedAt,
+		LastModified: resource.UpdatedAt,
+		Location:     location,
+		Version:      etag.Format(resource.Revision),
+	}
+}
+
+// lookup returns a resource of the request's tenant with the type named
+// typeName, and its principal if it has one.
+func (h *Handler) lookup(r *request, typeName, id string) (*dto.TenantResource, *dto.TenantPrincipals, *Error) {
+	resourceID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil, notFound("%s %s not found", typeName, id)
+	}
+	resource, err := h.Store.Resources().Get(r.ctx, resourceID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil, notFound("%s %s not found", typeName, id)
+	}
+	if err != nil {
+		return nil, nil, internalError("Error getting resource", err)
+	}
+	resourceType, scimErr := h.resourceType(r, typeName)
+	if scimErr != nil {
+		return nil, nil, scimErr
+	}
+	if resource.ResourceTypeID != resourceType.ResourceTypeID {
+		return nil, nil, notFound("%s %s not found", typeName, id)
+	}
+	principal, scimErr := h.principal(r, resourceID)
+	if scimErr != nil {
+		return nil, nil, scimErr
+	}
+	return resource, principal, nil
+}
+
+// principal returns the principal of a resource, or nil when it has none.
+func (h *Handler) principal(r *request, id uuid.UUID) (*dto.TenantPrincipals, *Error) {
+	principal, err := h.Store.Principals().Get(r.ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError("Error getting principal", err)
+	}
+	return principal, nil
+}
+
+func (h *Handler) resourceType(r *request, name string) (*dto.Mst_ResourceTypes, *Error) {
+	resourceType, err := h.Store.Resources().GetTypeByName(r.ctx, name)
+	if err != nil {
+		return nil, internalError(fmt.Sprintf("Error getting resource type %s", name), err)
+	}
+	return resourceType, nil
+}
+
+// principals returns the principals of a type in the request's tenant,
+// oldest first.
+func (h *Handler) principals(r *request, typeName string) ([]dto.TenantResource, []*dto.TenantPrincipals, *Error) {
+	resourceType, scimErr := h.resourceType(r, typeName)
+	if scimErr != nil {
+		return nil, nil, scimErr
+	}
+	resources, err := h.Store.Resources().ListByType(r.ctx, resourceType.ResourceTypeID)
+	if err != nil {
+		return nil, nil, internalError("Error listing resources", err)
+	}
+	principals := make([]*dto.TenantPrincipals, len(resources))
+	for i, resource := range resources {
+		if principals[i], scimErr = h.principal(r, resource.ResourceID); scimErr != nil {
+			return nil, nil, scimErr
+		}
+	}
+	return resources, principals, nil
+}
+
+// page filters resources with the filter query parameter and returns the
+// page selected by startIndex and count.
+func page(c *gin.Context, resources []interface{}) (*ListResponse, *Error) {
+	startIndex, count := 1, DefaultCount
+	if value := c.Query("startIndex"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, invalidValue("invalid startIndex %q", value)
+		}
+		startIndex = max(n, 1)
+	}
+	if value := c.Query("count"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, invalidValue("invalid count %q", value)
+		}
+		count = min(max(n, 0), MaxCount)
+	}
+
+	if expression := c.Query("filter"); expression != "" {
+		f, err := parseFilter(expression)
+		if err != nil {
+			return nil, newError(http.StatusBadRequest, "invalidFilter", "%v", err)
+		}
+		var matched []interface{}
+		for _, resource := range resources {
+			document, err := toDocument(resource)
+			if err != nil {
+				return nil, internalError("Error encoding resource", err)
+			}
+			if f.match(document) {
+				matched = append(matched, resource)
+			}
+		}
+		resources = matched
+	}
+
+	list := &ListResponse{Schemas: []string{SchemaListResponse}, TotalResults: len(resources), StartIndex: startIndex, Resources: []interface{}{}}
+	if start := startIndex - 1; start < len(resources) {
+		list.Resources = resources[start:min(start+count, len(resources))]
+	}
+	list.ItemsPerPage = len(list.Resources)
+	return list, nil
+}
+
+// toDocument returns the JSON representation of a resource as a map.
+func toDocument(resource interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	err = json.Unmarshal(encoded, &document)
+	return document, err
+}
+
+// patched applies the PATCH request in the body of c to resource, decoding
+// the result into result.
+func patched(c *gin.Context, resource, result interface{}) *Error {
+	var body PatchRequest
+	if err := decode(c, &body); err != nil {
+		return err
+	}
+	document, err := toDocument(resource)
+	if err != nil {
+		return internalError("Error encoding resource", err)
+	}
+	if err := applyPatch(document, body.Operations); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		return internalError("Error encoding resource", err)
+	}
+	if err := json.Unmarshal(encoded, result); err != nil {
+		return invalidValue("the patched resource is invalid: %v", err)
+	}
+	return nil
+}
+
+func decode(c *gin.Context, body interface{}) *Error {
+	if err := json.NewDecoder(c.Request.Body).Decode(body); err != nil {
+		return newError(http.StatusBadRequest, "invalidSyntax", "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// ifMatch returns the revision named by the If-Match header, or nil when
+// there is none or it is "*".
+func ifMatch(c *gin.Context) (*int, *Error) {
+	header := c.GetHeader("If-Match")
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	revision, err := etag.Parse(header)
+	if err != nil {
+		return nil, newError(http.StatusPreconditionFailed, "", "invalid If-Match header %q", header)
+	}
+	return &revision, nil
+}
+
+// notModified answers a conditional GET whose If-None-Match names the
+// current version of the resource.
+func notModified(c *gin.Context, version string) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	if revision, err := etag.Parse(header); header != "*" && (err != nil || etag.Format(revision) != version) {
+		return false
+	}
+	c.Header("ETag", version)
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// apply writes items changing resource id to the database and their steps to
+// Permit, atomically, and records the change in the audit log. A revision
+// conflict is a failed precondition when the client sent If-Match.
+func (h *Handler) apply(c *gin.Context, r *request, typeName string, id uuid.UUID, items []batch.Item, steps []batch.Step, conditional bool) *Error {
+	before := h.snapshot(id)
+	scimErr := h.run(r, items, steps, conditional)
+	h.record(c, r, typeName, id, before, scimErr)
+	return scimErr
+}
+
+func (h *Handler) run(r *request, items []batch.Item, steps []batch.Step, conditional bool) *Error {
+	atomic := models.BatchModeAtomic
+	results := batch.Run(r.ctx, h.Store, &atomic, items, batch.Phases(h.permitClient(), steps))
+	_, failed := batch.Cause(results, steps)
+	if failed == nil {
+		return nil
+	}
+	detail := failed.Message
+	if failed.ErrorDetails != nil {
+		detail = *failed.ErrorDetails
+	}
+	if failed.ErrorCode == constants.ErrorCodeConflict {
+		if conditional {
+			return newError(http.StatusPreconditionFailed, "", "%s", detail)
+		}
+		return newError(http.StatusConflict, "", "%s; retry the request", detail)
+	}
+	status, err := strconv.Atoi(failed.ErrorCode)
+	if err != nil || status < 400 || status > 599 {
+		status = http.StatusInternalServerError
+	}
+	return newError(status, "", "%s", detail)
+}
+
+// operations name the audit events of the SCIM writes by HTTP method.
+var operations = map[string]string{
+	http.MethodPost:   "scimCreate",
+	http.MethodPut:    "scimReplace",
+	http.MethodPatch:  "scimPatch",
+	http.MethodDelete: "scimDelete",
+}
+
+func (h *Handler) snapshot(id uuid.UUID) string {
+	if h.DB == nil {
+		return ""
+	}
+	snapshot, err := audit.Snapshot(h.DB, id)
+	if err != nil {
+		logger.LogError(fmt.Sprintf("Error taking audit snapshot of %s: %v", id, err))
+	}
+	return snapshot
+}
+
+// record appends the audit event of a write to resource id. Failing to
+// record it is logged and never fails the write itself.
+func (h *Handler) record(c *gin.Context, r *request, typeName string, id uuid.UUID, before string, scimErr *Error) {
+	if h.DB == nil {
+		return
+	}
+	entry := audit.Entry{
+		Operation:        operations[c.Request.Method] + typeName,
+		TargetResourceID: id.String(),
+		Before:           before,
+		After:            h.snapshot(id),
+		Outcome:          audit.OutcomeSuccess,
+	}
+	if scimErr != nil {
+		entry.Outcome = audit.OutcomeFailure
+		entry.ErrorMessage = scimErr.Detail
+	}
+	audit.FillRequestInfo(r.ctx, &entry)
+	entry.ActorID, entry.TenantID = r.actorID.String(), r.tenantID.String()
+	if _, err := audit.Record(h.DB, entry, h.Publishers...); err != nil {
+		logger.LogError(fmt.Sprintf("Error recording audit event for %s: %v", entry.Operation, err))
+	}
+}
+
+// deletePrincipal deletes a user or group together with its bindings and
+// memberships, and removes its resource instance from Permit.
+func (h *Handler) deletePrincipal(typeName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		r, scimErr := h.request(c)
+		if scimErr != nil {
+			abort(c, scimErr)
+			return
+		}
+		resource, _, scimErr := h.lookup(r, typeName, c.Param("id"))
+		if scimErr != nil {
+			abort(c, scimErr)
+			return
+		}
+		expected, scimErr := ifMatch(c)
+		if scimErr != nil {
+			abort(c, scimErr)
+			return
+		}
+		if expected != nil && *expected != resource.Revision {
+			// Spare Permit the changes the stale deletion would undo
+			abort(c, newError(http.StatusPreconditionFailed, "", "%s %s was modified concurrently", typeName, resource.ResourceID))
+			return
+		}
+		id := resource.ResourceID
+		bindings, err := h.Store.Assignments().ListByPrincipal(r.ctx, id)
+		if err != nil {
+			abort(c, internalError("Error listing bindings", err))
+			return
+		}
+
+		var items []batch.Item
+		var steps []batch.Step
+		for _, binding := range bindings {
+			bindingID := binding.ResourceID
+			items = append(items, batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+				if err := tx.Assignments().Delete(ctx, bindingID); err != nil {
+					return batch.Fail("500", "Error deleting binding", err)
+				}
+				return nil
+			}, Result: applied})
+			assignment := permit.RoleAssignment{User: id.String(), Role: binding.RoleID.String(), Tenant: r.tenantID.String()}
+			steps = append(steps, batch.Step{
+				Phase: phaseBindings,
+				Push:  &permit.Request{Method: "DELETE", Endpoint: "role_assignments", Payload: assignment},
+				Undo:  &permit.Request{Method: "POST", Endpoint: "role_assignments", Payload: assignment},
+			})
+		}
+
+		deleted := utils.UpdateDeletedMap()
+		deleted["updated_by"] = r.actorID
+		deleted["updated_at"] = time.Now()
+		items = append(items, batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+			if err := removeMemberships(ctx, tx, id); err != nil {
+				return err
+			}
+			if err := tx.Principals().Delete(ctx, id); err != nil {
+				return batch.Fail("500", "Error deleting principal", err)
+			}
+			if err := repository.UpdateResource(ctx, tx.Resources(), id, expected, deleted); err != nil {
+				return resourceError("Error deleting resource", err)
+			}
+			return nil
+		}, Result: applied})
+		steps = append(steps, batch.Step{
+			Phase: phaseInstance,
+			Push:  &permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("resource_instances/%s:%s", resource.ResourceTypeID, id)},
+			Undo:  instanceRequest(resource.ResourceTypeID, id, r.tenantID),
+		})
+
+		if scimErr := h.apply(c, r, typeName, id, items, steps, expected != nil); scimErr != nil {
+			abort(c, scimErr)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// removeMemberships removes a principal from its groups and, for a group,
+// its members.
+func removeMemberships(ctx context.Context, tx repository.Store, id uuid.UUID) error {
+	groups, err := tx.Members().ListByMember(ctx, id)
+	if err != nil {
+		return batch.Fail("500", "Error listing memberships", err)
+	}
+	members, err := tx.Members().ListByGroup(ctx, id)
+	if err != nil {
+		return batch.Fail("500", "Error listing members", err)
+	}
+	for _, membership := range append(groups, members...) {
+		if err := tx.Members().Remove(ctx, membership.GroupID, membership.MemberID); err != nil {
+			return batch.Fail("500", "Error removing member", err)
+		}
+	}
+	return nil
+}
+
+// instanceRequest creates the resource instance of a principal in Permit.
+func instanceRequest(resourceTypeID, id, tenantID uuid.UUID) *permit.Request {
+	return &permit.Request{Method: "POST", Endpoint: "resource_instances", Payload: map[string]interface{}{
+		"key":      id,
+		"resource": resourceTypeID,
+		"tenant":   tenantID,
+	}}
+}
+
+// instanceStep creates the resource instance of a new user or group.
+func instanceStep(resource *dto.TenantResource) batch.Step {
+	return batch.Step{
+		Phase: phaseInstance,
+		Push:  instanceRequest(resource.ResourceTypeID, resource.ResourceID, *resource.TenantID),
+		Undo:  &permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("resource_instances/%s:%s", resource.ResourceTypeID, resource.ResourceID)},
+	}
+}
+
+// resourceError reports a failed resource write, keeping revision conflicts
+// recognizable.
+func resourceError(message string, err error) error {
+	if errors.Is(err, repository.ErrConflict) {
+		return err
+	}
+	return batch.Fail("500", message, err)
+}
+
+// applied is the result of the items of SCIM writes, which respond with the
+// resource read back after the write.
+func applied(ctx context.Context) (models.OperationResult, error) {
+	return utils.FormatSuccess([]models.Data{})
+}
+
+// respond writes body as a SCIM response.
+func respond(c *gin.Context, status int, body interface{}) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		abort(c, internalError("Error encoding response", err))
+		return
+	}
+	c.Data(status, ContentType, encoded)
+}
+
+// respondResource writes a user or group with its entity tag.
+func respondResource(c *gin.Context, status int, resource interface{}, meta *Meta) {
+	c.Header("ETag", meta.Version)
+	if status == http.StatusCreated {
+		c.Header("Location", meta.Location)
+	}
+	respond(c, status, resource)
+}
+
+// abort ends the request with a SCIM error.
+func abort(c *gin.Context, scimErr *Error) {
+	if scimErr.status >= http.StatusInternalServerError {
+		logger.LogError(scimErr.Detail)
+	}
+	encoded, _ := json.Marshal(scimErr)
+	c.Abort()
+	c.Data(scimErr.status, ContentType, encoded)
+}
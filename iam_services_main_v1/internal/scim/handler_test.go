@@ -0,0 +1,362 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/middlewares"
+	"iam_services_main_v1/internal/migrations"
+	"iam_services_main_v1/internal/permit/permittest"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	store    *repository.MemoryStore
+	permit   *permittest.Server
+	router   *gin.Engine
+	tenantID uuid.UUID
+	types    map[string]uuid.UUID
+}
+
+// setupTenant stores two tenants and serves the SCIM endpoints to the first.
+func setupTenant(t *testing.T) *fixture {
+	logger.InitLogger()
+	gin.SetMode(gin.TestMode)
+	fake := permittest.New(t)
+
+	f := &fixture{store: repository.NewMemoryStore(), permit: fake, tenantID: uuid.New(), types: map[string]uuid.UUID{}}
+	for _, name := range []string{constants.ResourceTypeTenant, constants.ResourceTypeUser, constants.ResourceTypeGroup} {
+		f.types[name] = uuid.New()
+		f.store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: f.types[name], Name: name, RowStatus: 1})
+	}
+	root := tenancy.AsRoot(context.Background())
+	for _, tenantID := range []uuid.UUID{f.tenantID, uuid.New()} {
+		require.NoError(t, f.store.Resources().Create(root, &dto.TenantResource{
+			ResourceID: tenantID, ResourceTypeID: f.types[constants.ResourceTypeTenant], Name: "tenant", TenantID: &tenantID, RowStatus: 1,
+		}))
+	}
+
+	f.serve(&Handler{Store: f.store}, fake)
+	return f
+}
+
+// serve routes the SCIM endpoints of the fixture's tenant to h, with Permit
+// faked by fake.
+func (f *fixture) serve(h *Handler, fake *permittest.Server) {
+	h.PC = fake.Client()
+	f.router = gin.New()
+	Register(f.router.Group("/scim/v2", func(c *gin.Context) {
+		// What Authenticate sets for a token of the tenant
+		c.Set("tenantID", f.tenantID)
+		c.Set("userID", uuid.New())
+	}, middlewares.GinContextToContextMiddleware()), h)
+}
+
+// publisher collects the audit events it receives.
+type publisher struct {
+	events []dto.AuditEvent
+}
+
+func (p *publisher) Publish(event dto.AuditEvent) {
+	p.events = append(p.events, event)
+}
+
+// do sends a request and decodes the response body into out, when given.
+func (f *fixture) do(t *testing.T, method, path, body string, headers map[string]string, out interface{}) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/scim/v2"+path, strings.NewReader(body))
+	req.Header.Set("Content-Type", ContentType)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	if out != nil && w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), out), w.Body.String())
+	}
+	return w
+}
+
+func (f *fixture) createUser(t *testing.T, userName, email string) User {
+	var user User
+	w := f.do(t, "POST", "/Users", `{"schemas": ["`+SchemaUser+`"], "userName": "`+userName+`", "emails": [{"value": "`+email+`", "primary": true}]}`, nil, &user)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	return user
+}
+
+func TestUsers(t *testing.T) {
+	f := setupTenant(t)
+
+	var alice User
+	w := f.do(t, "POST", "/Users", `{
+		"schemas": ["`+SchemaUser+`"],
+		"userName": "alice",
+		"externalId": "00u1",
+		"name": {"givenName": "Alice", "familyName": "Smith"},
+		"displayName": "Alice Smith",
+		"emails": [{"value": "alice@acme.test", "type": "work", "primary": true}]
+	}`, nil, &alice)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, `W/"1"`, w.Header().Get("ETag"))
+	assert.Equal(t, "http://example.com/scim/v2/Users/"+alice.ID, w.Header().Get("Location"))
+	assert.Equal(t, "00u1", alice.ExternalID)
+	assert.Equal(t, "Alice Smith", alice.DisplayName)
+	assert.True(t, *alice.Active)
+	assert.Equal(t, `W/"1"`, alice.Meta.Version)
+	assert.Equal(t, []string{"POST resource_instances"}, f.permit.Take())
+
+	principal, err := f.store.Principals().Get(context.Background(), uuid.MustParse(alice.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "alice@acme.test", principal.Email)
+	assert.Equal(t, f.types[constants.ResourceTypeUser], principal.PrincipalTypeID)
+
+	var scimErr Error
+	w = f.do(t, "POST", "/Users", `{"userName": "ALICE"}`, nil, &scimErr)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "uniqueness", scimErr.ScimType)
+	w = f.do(t, "POST", "/Users", `{"userName": "alice2", "emails": [{"value": "alice@acme.test"}]}`, nil, &scimErr)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = f.do(t, "POST", "/Users", `{"displayName": "nobody"}`, nil, &scimErr)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidValue", scimErr.ScimType)
+
+	f.createUser(t, "bob", "bob@acme.test")
+	f.createUser(t, "carol", "carol@acme.test")
+	f.permit.Take()
+
+	var list ListResponse
+	w = f.do(t, "GET", "/Users?startIndex=2&count=1", "", nil, &list)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, list.TotalResults)
+	assert.Equal(t, 2, list.StartIndex)
+	require.Equal(t, 1, list.ItemsPerPage)
+	assert.Equal(t, "bob", list.Resources[0].(map[string]interface{})["userName"])
+	w = f.do(t, "GET", `/Users?filter=userName+eq+"carol"+or+emails.value+sw+"alice@"`, "", nil, &list)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, list.TotalResults)
+	w = f.do(t, "GET", "/Users?count=0", "", nil, &list)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 3, list.TotalResults)
+	assert.Empty(t, list.Resources)
+	w = f.do(t, "GET", `/Users?filter=userName+is+"carol"`, "", nil, &scimErr)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalidFilter", scimErr.ScimType)
+
+	w = f.do(t, "GET", "/Users/"+alice.ID, "", map[string]string{"If-None-Match": `W/"1"`}, nil)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	patch := `{"schemas": ["` + SchemaPatchOp + `"], "Operations": [
+		{"op": "replace", "path": "active", "value": false},
+		{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "alicia@acme.test"},
+		{"op": "replace", "value": {"userName": "alicia"}}
+	]}`
+	w = f.do(t, "PATCH", "/Users/"+alice.ID, patch, map[string]string{"If-Match": `W/"7"`}, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	var patched User
+	w = f.do(t, "PATCH", "/Users/"+alice.ID, patch, map[string]string{"If-Match": `W/"1"`}, &patched)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, `W/"2"`, w.Header().Get("ETag"))
+	assert.Equal(t, "alicia", patched.UserName)
+	assert.False(t, *patched.Active)
+	assert.Equal(t, "Alice", patched.Name.GivenName)
+	principal, err = f.store.Principals().Get(context.Background(), uuid.MustParse(alice.ID))
+	require.NoError(t, err)
+	assert.Equal(t, "alicia@acme.test", principal.Email)
+
+	var replaced User
+	w = f.do(t, "PUT", "/Users/"+alice.ID, `{"userName": "alicia", "emails": [{"value": "alicia@acme.test"}]}`, nil, &replaced)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Nil(t, replaced.Name)
+	assert.Empty(t, replaced.ExternalID)
+	assert.True(t, *replaced.Active)
+	assert.Empty(t, f.permit.Take())
+
+	w = f.do(t, "DELETE", "/Users/"+alice.ID, "", map[string]string{"If-Match": `W/"2"`}, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = f.do(t, "DELETE", "/Users/"+alice.ID, "", nil, nil)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, []string{"DELETE resource_instances/" + f.types[constants.ResourceTypeUser].String() + ":" + alice.ID}, f.permit.Take())
+	w = f.do(t, "GET", "/Users/"+alice.ID, "", nil, &scimErr)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, SchemaError, scimErr.Schemas[0])
+	assert.Equal(t, "404", scimErr.Status)
+}
+
+// TestUsersOnMigratedDatabase provisions a user on a database built by the
+// embedded migrations rather than by AutoMigrate.
+func TestUsersOnMigratedDatabase(t *testing.T) {
+	logger.InitLogger()
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	fake := permittest.New(t)
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(tenancy.Plugin{}))
+	m, err := migrations.New(db)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	store := repository.NewGormStore(db)
+	tenantType, err := store.Resources().GetTypeByName(ctx, constants.ResourceTypeTenant)
+	require.NoError(t, err)
+	f := &fixture{permit: fake, tenantID: uuid.New()}
+	require.NoError(t, store.Resources().Create(tenancy.AsRoot(ctx), &dto.TenantResource{
+		ResourceID: f.tenantID, ResourceTypeID: tenantType.ResourceTypeID, Name: "tenant", TenantID: &f.tenantID, RowStatus: 1,
+	}))
+	published := &publisher{}
+	f.serve(&Handler{Store: store, DB: db, Publishers: []audit.Publisher{published}}, fake)
+
+	alice := f.createUser(t, "alice", "alice@acme.test")
+	w := f.do(t, "PATCH", "/Users/"+alice.ID, `{"schemas": ["`+SchemaPatchOp+`"], "Operations": [
+		{"op": "replace", "path": "emails[primary eq true].value", "value": "alicia@acme.test"}
+	]}`, nil, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var principal dto.TenantPrincipals
+	require.NoError(t, db.Where("resource_id = ?", alice.ID).First(&principal).Error)
+	assert.Equal(t, "alicia@acme.test", principal.Email)
+
+	fake.Fail("DELETE resource_instances")
+	w = f.do(t, "DELETE", "/Users/"+alice.ID, "", nil, nil)
+	require.Equal(t, http.StatusInternalServerError, w.Code, w.Body.String())
+
+	// Every write is in the audit log and reaches the publishers
+	var events []dto.AuditEvent
+	require.NoError(t, tenancy.RootSession(db).Order("sequence").Find(&events).Error)
+	require.Len(t, events, 3)
+	assert.Equal(t, events, published.events)
+	for i, operation := range []string{"scimCreateUser", "scimPatchUser", "scimDeleteUser"} {
+		assert.Equal(t, operation, events[i].Operation)
+		assert.Equal(t, alice.ID, events[i].TargetResourceID)
+		assert.Equal(t, f.tenantID.String(), events[i].TenantID)
+	}
+	assert.Empty(t, events[0].Before)
+	assert.Contains(t, events[0].After, `"alice"`)
+	assert.Equal(t, events[0].After, events[1].Before)
+	assert.Equal(t, audit.OutcomeSuccess, events[1].Outcome)
+	assert.Equal(t, audit.OutcomeFailure, events[2].Outcome)
+	assert.NotEmpty(t, events[2].ErrorMessage)
+	assert.Equal(t, events[2].Before, events[2].After)
+}
+
+func TestUsersOfOtherTenantsAreHidden(t *testing.T) {
+	f := setupTenant(t)
+	globex := uuid.New()
+	bob := uuid.New()
+	require.NoError(t, f.store.Resources().Create(tenancy.AsRoot(context.Background()), &dto.TenantResource{
+		ResourceID: bob, ResourceTypeID: f.types[constants.ResourceTypeUser], Name: "bob", TenantID: &globex, RowStatus: 1,
+	}))
+
+	w := f.do(t, "GET", "/Users/"+bob.String(), "", nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	var list ListResponse
+	f.do(t, "GET", "/Users", "", nil, &list)
+	assert.Zero(t, list.TotalResults)
+	// A user name is only unique within a tenant
+	f.createUser(t, "bob", "bob@acme.test")
+	w = f.do(t, "POST", "/Groups", `{"displayName": "admins", "members": [{"value": "`+bob.String()+`"}]}`, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGroups(t *testing.T) {
+	f := setupTenant(t)
+	alice := f.createUser(t, "alice", "alice@acme.test")
+	bob := f.createUser(t, "bob", "bob@acme.test")
+	f.permit.Take()
+
+	var admins Group
+	w := f.do(t, "POST", "/Groups", `{"schemas": ["`+SchemaGroup+`"], "displayName": "admins", "members": [{"value": "`+alice.ID+`"}]}`, nil, &admins)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	require.Len(t, admins.Members, 1)
+	assert.Equal(t, Member{Value: alice.ID, Ref: "http://example.com/scim/v2/Users/" + alice.ID, Display: "alice", Type: "User"}, admins.Members[0])
+	assert.Equal(t, []string{"POST resource_instances"}, f.permit.Take())
+
+	var user User
+	f.do(t, "GET", "/Users/"+alice.ID, "", nil, &user)
+	require.Len(t, user.Groups, 1)
+	assert.Equal(t, admins.ID, user.Groups[0].Value)
+
+	var patched Group
+	w = f.do(t, "PATCH", "/Groups/"+admins.ID, `{"schemas": ["`+SchemaPatchOp+`"], "Operations": [
+		{"op": "add", "path": "members", "value": [{"value": "`+bob.ID+`"}]},
+		{"op": "remove", "path": "members[value eq \"`+alice.ID+`\"]"}
+	]}`, nil, &patched)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, patched.Members, 1)
+	assert.Equal(t, bob.ID, patched.Members[0].Value)
+	// Membership changes advance the group's version
+	assert.Equal(t, `W/"2"`, patched.Meta.Version)
+	members, err := f.store.Members().ListByGroup(tenancy.WithTenant(context.Background(), f.tenantID), uuid.MustParse(admins.ID))
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, bob.ID, members[0].MemberID.String())
+
+	var list ListResponse
+	w = f.do(t, "GET", `/Groups?filter=members.value+eq+"`+bob.ID+`"`, "", nil, &list)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, list.TotalResults)
+
+	w = f.do(t, "POST", "/Groups", `{"displayName": "Admins"}`, nil, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = f.do(t, "PATCH", "/Groups/"+admins.ID, `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "`+admins.ID+`"}]}]}`, nil, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w = f.do(t, "GET", "/Groups/"+alice.ID, "", nil, nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Deleting a member removes it from its groups
+	w = f.do(t, "DELETE", "/Users/"+bob.ID, "", nil, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	var group Group
+	f.do(t, "GET", "/Groups/"+admins.ID, "", nil, &group)
+	assert.Empty(t, group.Members)
+}
+
+func TestDeleteRemovesBindings(t *testing.T) {
+	f := setupTenant(t)
+	alice := f.createUser(t, "alice", "alice@acme.test")
+	f.permit.Take()
+	roleID, aliceID := uuid.New(), uuid.MustParse(alice.ID)
+	require.NoError(t, f.store.Assignments().Create(tenancy.AsRoot(context.Background()), &dto.TenantRoleAssignments{
+		ResourceID: uuid.New(), Name: "alice-admin", Version: "V1", PrincipalID: aliceID, RoleID: roleID, TenantID: &f.tenantID, RowStatus: 1,
+	}))
+
+	// The binding is removed in Permit before the user, and restored when the
+	// user cannot be removed
+	f.permit.Fail("DELETE resource_instances")
+	w := f.do(t, "DELETE", "/Users/"+alice.ID, "", nil, nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, []string{
+		"DELETE role_assignments",
+		"DELETE resource_instances/" + f.types[constants.ResourceTypeUser].String() + ":" + alice.ID,
+		"POST role_assignments",
+	}, f.permit.Take())
+	bindings, err := f.store.Assignments().ListByPrincipal(context.Background(), aliceID)
+	require.NoError(t, err)
+	assert.Len(t, bindings, 1)
+
+	f.permit.Fail("")
+	w = f.do(t, "DELETE", "/Users/"+alice.ID, "", nil, nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	bindings, err = f.store.Assignments().ListByPrincipal(context.Background(), aliceID)
+	require.NoError(t, err)
+	assert.Empty(t, bindings)
+}
@@ -0,0 +1,224 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// applyPatch applies PATCH operations (RFC 7644, section 3.5.2) to the JSON
+// representation of a resource.
+func applyPatch(document map[string]interface{}, operations []PatchOperation) *Error {
+	if len(operations) == 0 {
+		return newError(http.StatusBadRequest, "invalidSyntax", "a PATCH request needs operations")
+	}
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return newError(http.StatusBadRequest, "invalidSyntax", "unsupported operation %q", operation.Op)
+		}
+		var value interface{}
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return newError(http.StatusBadRequest, "invalidSyntax", "invalid value of %s operation: %v", op, err)
+			}
+		}
+
+		if operation.Path == "" {
+			if op == "remove" {
+				return newError(http.StatusBadRequest, "noTarget", "a remove operation needs a path")
+			}
+			attributes, ok := value.(map[string]interface{})
+			if !ok {
+				return invalidValue("the value of a %s operation without a path must be an object", op)
+			}
+			for name, attributeValue := range attributes {
+				if err := patchAttribute(document, attributePath(name), op, attributeValue); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, elements, subAttribute, err := parsePath(operation.Path)
+		if err != nil {
+			return newError(http.StatusBadRequest, "invalidPath", "%v", err)
+		}
+		if op != "remove" && value == nil {
+			return invalidValue("a %s operation needs a value", op)
+		}
+		if elements == nil {
+			if err := patchAttribute(document, path, op, value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := patchElements(document, path, elements, subAttribute, op, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchAttribute applies an operation to the attribute at path, creating the
+// complex attributes leading to it.
+func patchAttribute(document map[string]interface{}, path []string, op string, value interface{}) *Error {
+	parent := document
+	for _, name := range path[:len(path)-1] {
+		key, ok := attributeKey(parent, name)
+		if !ok {
+			if op == "remove" {
+				return nil
+			}
+			key = name
+			parent[key] = map[string]interface{}{}
+		}
+		next, ok := parent[key].(map[string]interface{})
+		if !ok {
+			return newError(http.StatusBadRequest, "invalidPath", "%s is not a complex attribute", name)
+		}
+		parent = next
+	}
+	name := path[len(path)-1]
+	key, exists := attributeKey(parent, name)
+	if !exists {
+		key = name
+	}
+	current := parent[key]
+
+	switch op {
+	case "remove":
+		elements, multiValued := current.([]interface{})
+		if !multiValued || value == nil {
+			delete(parent, key)
+			return nil
+		}
+		// Removing listed elements, as some clients remove group members
+		parent[key] = without(elements, asList(value))
+	case "add":
+		switch existing := current.(type) {
+		case []interface{}:
+			for _, element := range asList(value) {
+				if !contains(existing, element) {
+					existing = append(existing, element)
+				}
+			}
+			parent[key] = existing
+		case map[string]interface{}:
+			merge(existing, value)
+		default:
+			parent[key] = value
+		}
+	case "replace":
+		if existing, ok := current.(map[string]interface{}); ok {
+			merge(existing, value)
+			return nil
+		}
+		parent[key] = value
+	}
+	return nil
+}
+
+// patchElements applies an operation to the elements of a multi-valued
+// attribute matching a filter, or to their sub-attribute.
+func patchElements(document map[string]interface{}, path []string, elements filter, subAttribute, op string, value interface{}) *Error {
+	values := lookup(document, path[:len(path)-1])
+	if len(values) != 1 {
+		return newError(http.StatusBadRequest, "noTarget", "no attribute matches %s", strings.Join(path, "."))
+	}
+	parent, _ := values[0].(map[string]interface{})
+	key, ok := attributeKey(parent, path[len(path)-1])
+	if !ok {
+		return newError(http.StatusBadRequest, "noTarget", "no attribute matches %s", strings.Join(path, "."))
+	}
+	list, ok := parent[key].([]interface{})
+	if !ok {
+		return newError(http.StatusBadRequest, "invalidPath", "%s is not multi-valued", strings.Join(path, "."))
+	}
+
+	kept := list[:0:0]
+	matched := false
+	for _, element := range list {
+		object, ok := element.(map[string]interface{})
+		if !ok || !elements.match(object) {
+			kept = append(kept, element)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && subAttribute == "":
+			continue
+		case op == "remove":
+			if subKey, ok := attributeKey(object, subAttribute); ok {
+				delete(object, subKey)
+			}
+		case subAttribute == "":
+			merge(object, value)
+		default:
+			subKey, ok := attributeKey(object, subAttribute)
+			if !ok {
+				subKey = subAttribute
+			}
+			object[subKey] = value
+		}
+		kept = append(kept, object)
+	}
+	if !matched && op != "add" {
+		return newError(http.StatusBadRequest, "noTarget", "no element of %s matches the filter", strings.Join(path, "."))
+	}
+	parent[key] = kept
+	return nil
+}
+
+// merge sets the sub-attributes of value, an object, on object.
+func merge(object map[string]interface{}, value interface{}) {
+	attributes, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, attributeValue := range attributes {
+		key, ok := attributeKey(object, name)
+		if !ok {
+			key = name
+		}
+		object[key] = attributeValue
+	}
+}
+
+func asList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+// sameElement compares elements by their "value" sub-attribute when both
+// have one.
+func sameElement(a, b interface{}) bool {
+	objectA, okA := a.(map[string]interface{})
+	objectB, okB := b.(map[string]interface{})
+	if okA && okB && objectA["value"] != nil && objectB["value"] != nil {
+		return compare(objectA["value"], "eq", objectB["value"])
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func contains(list []interface{}, element interface{}) bool {
+	for _, existing := range list {
+		if sameElement(existing, element) {
+			return true
+		}
+	}
+	return false
+}
+
+func without(list, removed []interface{}) []interface{} {
+	kept := list[:0:0]
+	for _, element := range list {
+		if !contains(removed, element) {
+			kept = append(kept, element)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,164 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TokenPrefix starts every SCIM token, so that leaked tokens are easy to spot.
+const TokenPrefix = "scim_"
+
+var (
+	// ErrInvalidToken is returned for a token that was never issued or was
+	// revoked.
+	ErrInvalidToken = errors.New("invalid SCIM token")
+	// ErrTokenNotFound is returned when revoking an unknown token.
+	ErrTokenNotFound = errors.New("SCIM token not found")
+)
+
+// TokenStore issues and checks the bearer tokens of SCIM clients.
+type TokenStore struct {
+	DB  *gorm.DB
+	now func() time.Time
+}
+
+// NewTokenStore returns a TokenStore keeping tokens in db.
+func NewTokenStore(db *gorm.DB) *TokenStore {
+	return &TokenStore{DB: db, now: time.Now}
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Issue creates a token for tenantID. The token itself is only returned here.
+func (s *TokenStore) Issue(ctx context.Context, tenantID uuid.UUID, description string) (string, *dto.ScimToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	record := &dto.ScimToken{
+		TokenID:     uuid.New(),
+		TokenHash:   hashToken(token),
+		TenantID:    tenantID,
+		Description: description,
+		CreatedAt:   s.now(),
+	}
+	if err := s.DB.WithContext(ctx).Create(record).Error; err != nil {
+		return "", nil, err
+	}
+	return token, record, nil
+}
+
+// Authenticate returns the record of a token that was issued and not revoked.
+func (s *TokenStore) Authenticate(ctx context.Context, token string) (*dto.ScimToken, error) {
+	var record dto.ScimToken
+	err := s.DB.WithContext(ctx).Where("token_hash = ? AND revoked_at IS NULL", hashToken(token)).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Revoke stops a token from authenticating.
+func (s *TokenStore) Revoke(ctx context.Context, tokenID uuid.UUID) error {
+	result := s.DB.WithContext(ctx).Model(&dto.ScimToken{}).
+		Where("token_id = ? AND revoked_at IS NULL", tokenID).
+		Update("revoked_at", s.now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// List returns the tokens of a tenant, or of every tenant when tenantID is
+// nil, oldest first.
+func (s *TokenStore) List(ctx context.Context, tenantID *uuid.UUID) ([]dto.ScimToken, error) {
+	db := s.DB.WithContext(ctx)
+	if tenantID != nil {
+		db = db.Where("tenant_id = ?", *tenantID)
+	}
+	var tokens []dto.ScimToken
+	if err := db.Order("created_at").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Authenticate resolves the bearer token of a request to its tenant, which
+// must still exist. The token stands in for the user of the request, so that
+// the changes of a SCIM client are attributed to its token.
+func Authenticate(tokens *TokenStore, store repository.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, credential, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(credential) == "" {
+			unauthorized(c, "a bearer token is required")
+			return
+		}
+		token, err := tokens.Authenticate(c.Request.Context(), strings.TrimSpace(credential))
+		if errors.Is(err, ErrInvalidToken) {
+			unauthorized(c, err.Error())
+			return
+		}
+		if err != nil {
+			abort(c, internalError("Error checking SCIM token", err))
+			return
+		}
+
+		ctx := tenancy.WithTenant(c.Request.Context(), token.TenantID)
+		_, err = store.Resources().Get(ctx, token.TenantID)
+		if errors.Is(err, repository.ErrNotFound) {
+			unauthorized(c, fmt.Sprintf("tenant %s no longer exists", token.TenantID))
+			return
+		}
+		if err != nil {
+			abort(c, internalError("Error getting tenant", err))
+			return
+		}
+		// Clients of a suspended or archived tenant are refused like its users
+		state, err := repository.CurrentTenantState(ctx, store.TenantStates(), token.TenantID)
+		if err != nil {
+			abort(c, internalError("Error getting tenant status", err))
+			return
+		}
+		if state.Status != dto.TenantStatusActive {
+			logger.LogWarn(fmt.Sprintf("SCIM request rejected: tenant %s is %s", token.TenantID, state.Status))
+			abort(c, newError(http.StatusForbidden, "", "tenant %s is %s", token.TenantID, strings.ToLower(state.Status)))
+			return
+		}
+
+		c.Set("tenantID", token.TenantID)
+		c.Set("userID", token.TokenID)
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context, detail string) {
+	logger.LogWarn(fmt.Sprintf("SCIM request rejected: %s", detail))
+	c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+	abort(c, newError(http.StatusUnauthorized, "", "%s", detail))
+}
@@ -0,0 +1,116 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"iam_services_main_v1/internal/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTokens(t *testing.T) *TokenStore {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&dto.ScimToken{}))
+	return NewTokenStore(db)
+}
+
+func TestAuthenticate(t *testing.T) {
+	f := setupTenant(t)
+	tokens := setupTokens(t)
+	ctx := context.Background()
+	token, record, err := tokens.Issue(ctx, f.tenantID, "okta")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, TokenPrefix))
+	assert.NotContains(t, record.TokenHash, token)
+	// Tokens of tenants that were deleted no longer authenticate
+	orphan, _, err := tokens.Issue(ctx, uuid.New(), "")
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/scim/v2/Users", Authenticate(tokens, f.store), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"tenant": c.MustGet("tenantID"), "user": c.MustGet("userID")})
+	})
+	get := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/scim/v2/Users", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("Bearer " + token)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, fmt.Sprintf(`{"tenant": %q, "user": %q}`, f.tenantID, record.TokenID), w.Body.String())
+	for _, authorization := range []string{"", "Basic " + token, "Bearer ", "Bearer scim_unknown", "Bearer " + orphan} {
+		w := get(authorization)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, authorization)
+		assert.Equal(t, `Bearer realm="scim"`, w.Header().Get("WWW-Authenticate"))
+	}
+
+	// Clients of suspended tenants are refused
+	require.NoError(t, f.store.TenantStates().Transition(ctx, 0, &dto.TenantStateTransition{
+		TransitionID: uuid.New(), TenantID: f.tenantID, FromStatus: dto.TenantStatusActive, ToStatus: dto.TenantStatusSuspended,
+	}))
+	assert.Equal(t, http.StatusForbidden, get("Bearer "+token).Code)
+
+	require.NoError(t, tokens.Revoke(ctx, record.TokenID))
+	assert.Equal(t, http.StatusUnauthorized, get("Bearer "+token).Code)
+	assert.ErrorIs(t, tokens.Revoke(ctx, record.TokenID), ErrTokenNotFound)
+}
+
+func TestRunCommand(t *testing.T) {
+	f := setupTenant(t)
+	tokens := setupTokens(t)
+	ctx := context.Background()
+	run := func(args ...string) (string, error) {
+		var out bytes.Buffer
+		err := RunCommand(ctx, tokens, f.store, args, &out)
+		return out.String(), err
+	}
+
+	out, err := run("issue", f.tenantID.String(), "okta", "production")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	require.Len(t, lines, 2)
+	token := lines[1]
+	issued, err := tokens.Authenticate(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, "okta production", issued.Description)
+
+	_, err = run("issue", uuid.NewString())
+	assert.ErrorContains(t, err, "not found")
+
+	out, err = run("list", f.tenantID.String())
+	require.NoError(t, err)
+	assert.Contains(t, out, issued.TokenID.String())
+	assert.NotContains(t, out, token)
+	out, err = run("list", uuid.NewString())
+	require.NoError(t, err)
+	assert.NotContains(t, out, issued.TokenID.String())
+
+	_, err = run("revoke", issued.TokenID.String())
+	require.NoError(t, err)
+	_, err = tokens.Authenticate(ctx, token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = run("revoke", issued.TokenID.String())
+	assert.ErrorIs(t, err, ErrTokenNotFound)
+
+	for _, args := range [][]string{{}, {"issue"}, {"issue", "acme"}, {"revoke"}, {"list", "a", "b"}, {"rotate"}} {
+		_, err := run(args...)
+		assert.ErrorIs(t, err, ErrUsage, args)
+	}
+}
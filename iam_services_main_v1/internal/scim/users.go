@@ -0,0 +1,361 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxNameLength is the size of the name and email columns users and groups
+// are stored in.
+const maxNameLength = 45
+
+// userProfile is the part of a user kept in the metadata of its principal.
+// The user name is the name of the resource, the display name that of the
+// principal and the primary email its email.
+type userProfile struct {
+	ExternalID string  `json:"externalId,omitempty"`
+	Name       *Name   `json:"name,omitempty"`
+	Emails     []Email `json:"emails,omitempty"`
+	Active     *bool   `json:"active,omitempty"`
+}
+
+// toUser returns the SCIM representation of a user.
+func (h *Handler) toUser(c *gin.Context, r *request, resource *dto.TenantResource, principal *dto.TenantPrincipals) (*User, *Error) {
+	if principal == nil {
+		principal = &dto.TenantPrincipals{}
+	}
+	var profile userProfile
+	// Principals not provisioned over SCIM may have other metadata
+	_ = json.Unmarshal(principal.Metadata, &profile)
+	active := profile.Active == nil || *profile.Active
+	emails := profile.Emails
+	if len(emails) == 0 && principal.Email != "" {
+		emails = []Email{{Value: principal.Email, Primary: true}}
+	}
+
+	memberships, err := h.Store.Members().ListByMember(r.ctx, resource.ResourceID)
+	if err != nil {
+		return nil, internalError("Error listing memberships", err)
+	}
+	var groups []Member
+	for _, membership := range memberships {
+		group, err := h.Store.Resources().Get(r.ctx, membership.GroupID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, internalError("Error getting group", err)
+		}
+		groups = append(groups, Member{
+			Value:   group.ResourceID.String(),
+			Ref:     h.location(c, "Groups", group.ResourceID),
+			Display: group.Name,
+			Type:    constants.ResourceTypeGroup,
+		})
+	}
+
+	return &User{
+		Schemas:     []string{SchemaUser},
+		ID:          resource.ResourceID.String(),
+		ExternalID:  profile.ExternalID,
+		UserName:    resource.Name,
+		Name:        profile.Name,
+		DisplayName: principal.Name,
+		Emails:      emails,
+		Active:      &active,
+		Groups:      groups,
+		Meta:        meta(constants.ResourceTypeUser, resource, h.location(c, "Users", resource.ResourceID)),
+	}, nil
+}
+
+// primaryEmail returns the email stored on the principal of a user.
+func (u *User) primaryEmail() string {
+	for _, email := range u.Emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// validateUser checks a user about to be stored as user id, which is nil for
+// a new user.
+func (h *Handler) validateUser(r *request, user *User, id *uuid.UUID) *Error {
+	user.UserName = strings.TrimSpace(user.UserName)
+	switch {
+	case user.UserName == "":
+		return invalidValue("userName is required")
+	case len(user.UserName) > maxNameLength:
+		return invalidValue("userName is longer than %d characters", maxNameLength)
+	case len(user.DisplayName) > maxNameLength:
+		return invalidValue("displayName is longer than %d characters", maxNameLength)
+	case len(user.primaryEmail()) > maxNameLength:
+		return invalidValue("the primary email is longer than %d characters", maxNameLength)
+	}
+
+	resources, _, scimErr := h.principals(r, constants.ResourceTypeUser)
+	if scimErr != nil {
+		return scimErr
+	}
+	for _, resource := range resources {
+		if strings.EqualFold(resource.Name, user.UserName) && (id == nil || resource.ResourceID != *id) {
+			return uniqueness("userName %q is already used", user.UserName)
+		}
+	}
+	if email := user.primaryEmail(); email != "" {
+		principal, err := h.Store.Principals().GetByEmail(r.ctx, email)
+		if err == nil && (id == nil || principal.ResourceID != *id) {
+			return uniqueness("email %q is already used", email)
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return internalError("Error getting principal", err)
+		}
+	}
+	return nil
+}
+
+// profileOf returns the principal metadata of a user.
+func profileOf(user *User) (dto.JSON, *Error) {
+	encoded, err := json.Marshal(userProfile{ExternalID: user.ExternalID, Name: user.Name, Emails: user.Emails, Active: user.Active})
+	if err != nil {
+		return nil, internalError("Error encoding user", err)
+	}
+	return dto.JSON(encoded), nil
+}
+
+func (h *Handler) readUser(c *gin.Context, r *request, id string) (*User, *Error) {
+	resource, principal, scimErr := h.lookup(r, constants.ResourceTypeUser, id)
+	if scimErr != nil {
+		return nil, scimErr
+	}
+	return h.toUser(c, r, resource, principal)
+}
+
+func (h *Handler) listUsers(c *gin.Context) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	resources, principals, scimErr := h.principals(r, constants.ResourceTypeUser)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	users := make([]interface{}, len(resources))
+	for i := range resources {
+		if users[i], scimErr = h.toUser(c, r, &resources[i], principals[i]); scimErr != nil {
+			abort(c, scimErr)
+			return
+		}
+	}
+	list, scimErr := page(c, users)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	respond(c, http.StatusOK, list)
+}
+
+func (h *Handler) getUser(c *gin.Context) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	user, scimErr := h.readUser(c, r, c.Param("id"))
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	if notModified(c, user.Meta.Version) {
+		return
+	}
+	respondResource(c, http.StatusOK, user, user.Meta)
+}
+
+func (h *Handler) createUser(c *gin.Context) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	var user User
+	if scimErr := decode(c, &user); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	if scimErr := h.validateUser(r, &user, nil); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	userType, scimErr := h.resourceType(r, constants.ResourceTypeUser)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	metadata, scimErr := profileOf(&user)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	resource := newResource(r, userType, user.UserName)
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Resources().Create(ctx, resource); err != nil {
+			return batch.Fail("500", "Error creating user", err)
+		}
+		return savePrincipal(ctx, tx, resource, nil, r.actorID, user.DisplayName, user.primaryEmail(), metadata)
+	}, Result: applied}
+	if scimErr := h.apply(c, r, constants.ResourceTypeUser, resource.ResourceID, []batch.Item{item}, []batch.Step{instanceStep(resource)}, false); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	created, scimErr := h.readUser(c, r, resource.ResourceID.String())
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	respondResource(c, http.StatusCreated, created, created.Meta)
+}
+
+func (h *Handler) replaceUser(c *gin.Context) {
+	h.updateUser(c, func(current *User, user *User) *Error {
+		return decode(c, user)
+	})
+}
+
+func (h *Handler) patchUser(c *gin.Context) {
+	h.updateUser(c, func(current *User, user *User) *Error {
+		return patched(c, current, user)
+	})
+}
+
+// updateUser replaces a user with the one change returns.
+func (h *Handler) updateUser(c *gin.Context, change func(current, user *User) *Error) {
+	r, scimErr := h.request(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	resource, principal, scimErr := h.lookup(r, constants.ResourceTypeUser, c.Param("id"))
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	current, scimErr := h.toUser(c, r, resource, principal)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	expected, scimErr := ifMatch(c)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	conditional := expected != nil
+	if expected == nil {
+		// The change is computed from the current user
+		expected = &resource.Revision
+	}
+
+	var user User
+	if scimErr := change(current, &user); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	id := resource.ResourceID
+	if scimErr := h.validateUser(r, &user, &id); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	metadata, scimErr := profileOf(&user)
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	now := time.Now()
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := repository.UpdateResource(ctx, tx.Resources(), id, expected, map[string]interface{}{
+			"name":       user.UserName,
+			"updated_by": r.actorID,
+			"updated_at": now,
+		}); err != nil {
+			return resourceError("Error updating user", err)
+		}
+		return savePrincipal(ctx, tx, resource, principal, r.actorID, user.DisplayName, user.primaryEmail(), metadata)
+	}, Result: applied}
+	if scimErr := h.apply(c, r, constants.ResourceTypeUser, id, []batch.Item{item}, []batch.Step{{Phase: phaseInstance}}, conditional); scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+
+	updated, scimErr := h.readUser(c, r, id.String())
+	if scimErr != nil {
+		abort(c, scimErr)
+		return
+	}
+	respondResource(c, http.StatusOK, updated, updated.Meta)
+}
+
+// newResource returns the resource of a new user or group of the request's
+// tenant.
+func newResource(r *request, resourceType *dto.Mst_ResourceTypes, name string) *dto.TenantResource {
+	tenantID := r.tenantID
+	return &dto.TenantResource{
+		ResourceID:       uuid.New(),
+		ParentResourceID: &tenantID,
+		ResourceTypeID:   resourceType.ResourceTypeID,
+		Name:             name,
+		TenantID:         &tenantID,
+		RowStatus:        1,
+		CreatedBy:        r.actorID,
+		UpdatedBy:        r.actorID,
+	}
+}
+
+// savePrincipal updates the principal of a user or group resource, creating
+// it when the resource has none yet.
+func savePrincipal(ctx context.Context, tx repository.Store, resource *dto.TenantResource, principal *dto.TenantPrincipals, actorID uuid.UUID, name, email string, metadata dto.JSON) error {
+	if principal == nil {
+		if err := tx.Principals().Create(ctx, &dto.TenantPrincipals{
+			ResourceID:      resource.ResourceID,
+			PrincipalTypeID: resource.ResourceTypeID,
+			Name:            name,
+			Email:           email,
+			Metadata:        metadata,
+			RowStatus:       1,
+			CreatedBy:       actorID,
+			UpdatedBy:       actorID,
+		}); err != nil {
+			return batch.Fail("500", "Error creating principal", err)
+		}
+		return nil
+	}
+	if err := tx.Principals().Update(ctx, resource.ResourceID, map[string]interface{}{
+		"name":       name,
+		"email":      email,
+		"metadata":   metadata,
+		"updated_by": actorID,
+		"updated_at": time.Now(),
+	}); err != nil {
+		return batch.Fail("500", "Error updating principal", err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package siem
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables configuring SIEM export. AUDIT_SINKS lists the
+// enabled sinks (file, syslog, webhook); export is disabled when it is empty.
+//
+//	AUDIT_BUFFER_SIZE       events buffered per sink (default 1000)
+//	AUDIT_ENQUEUE_TIMEOUT   wait for buffer space before dropping (default 1s)
+//	AUDIT_MAX_RETRIES       retries per event and sink (default 5)
+//	AUDIT_RETRY_BACKOFF     first retry delay, doubled per retry (default 500ms)
+//	AUDIT_RETRY_MAX_BACKOFF upper bound of the retry delay (default 30s)
+//
+//	AUDIT_FILE_PATH, AUDIT_FILE_MAX_SIZE_MB (default 100), AUDIT_FILE_MAX_BACKUPS (default 10), AUDIT_FILE_FORMAT
+//	AUDIT_SYSLOG_NETWORK (udp or tcp, default udp), AUDIT_SYSLOG_ADDRESS, AUDIT_SYSLOG_APP_NAME, AUDIT_SYSLOG_FORMAT
+//	AUDIT_WEBHOOK_URL, AUDIT_WEBHOOK_SECRET, AUDIT_WEBHOOK_TIMEOUT (default 10s), AUDIT_WEBHOOK_FORMAT
+//
+// Formats are json (default) or cef.
+const (
+	envSinks           = "AUDIT_SINKS"
+	envBufferSize      = "AUDIT_BUFFER_SIZE"
+	envEnqueueTimeout  = "AUDIT_ENQUEUE_TIMEOUT"
+	envMaxRetries      = "AUDIT_MAX_RETRIES"
+	envRetryBackoff    = "AUDIT_RETRY_BACKOFF"
+	envRetryMaxBackoff = "AUDIT_RETRY_MAX_BACKOFF"
+)
+
+// NewDispatcherFromEnv builds the sinks listed in AUDIT_SINKS. It returns a
+// nil dispatcher, which ignores published events, when no sink is enabled.
+func NewDispatcherFromEnv() (*Dispatcher, error) {
+	return newDispatcherFromLookup(os.Getenv)
+}
+
+func newDispatcherFromLookup(getenv func(string) string) (*Dispatcher, error) {
+	env := envReader{getenv: getenv}
+
+	names := strings.Split(getenv(envSinks), ",")
+	var sinks []Sink
+	for _, name := range names {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		sink, err := env.sink(name)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	opts := Options{
+		BufferSize:     env.int(envBufferSize, 1000),
+		EnqueueTimeout: env.duration(envEnqueueTimeout, time.Second),
+		Retry: RetryPolicy{
+			MaxRetries: env.int(envMaxRetries, 5),
+			Backoff:    env.duration(envRetryBackoff, 500*time.Millisecond),
+			MaxBackoff: env.duration(envRetryMaxBackoff, 30*time.Second),
+		},
+	}
+	if env.err != nil {
+		for _, s := range sinks {
+			s.Close()
+		}
+		return nil, env.err
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return NewDispatcher(opts, sinks...), nil
+}
+
+func (e *envReader) sink(name string) (Sink, error) {
+	switch name {
+	case "file":
+		format, err := ParseFormat(e.getenv("AUDIT_FILE_FORMAT"))
+		if err != nil {
+			return nil, err
+		}
+		path := e.getenv("AUDIT_FILE_PATH")
+		if path == "" {
+			return nil, fmt.Errorf("AUDIT_FILE_PATH is required for the file sink")
+		}
+		maxSize := int64(e.int("AUDIT_FILE_MAX_SIZE_MB", 100)) * 1024 * 1024
+		maxBackups := e.int("AUDIT_FILE_MAX_BACKUPS", 10)
+		if e.err != nil {
+			return nil, e.err
+		}
+		return NewFileSink(path, maxSize, maxBackups, format)
+	case "syslog":
+		format, err := ParseFormat(e.getenv("AUDIT_SYSLOG_FORMAT"))
+		if err != nil {
+			return nil, err
+		}
+		network := e.getenv("AUDIT_SYSLOG_NETWORK")
+		if network == "" {
+			network = "udp"
+		}
+		appName := e.getenv("AUDIT_SYSLOG_APP_NAME")
+		if appName == "" {
+			appName = "iam_services"
+		}
+		return NewSyslogSink(network, e.getenv("AUDIT_SYSLOG_ADDRESS"), appName, format, 5*time.Second)
+	case "webhook":
+		format, err := ParseFormat(e.getenv("AUDIT_WEBHOOK_FORMAT"))
+		if err != nil {
+			return nil, err
+		}
+		timeout := e.duration("AUDIT_WEBHOOK_TIMEOUT", 10*time.Second)
+		if e.err != nil {
+			return nil, e.err
+		}
+		return NewWebhookSink(e.getenv("AUDIT_WEBHOOK_URL"), e.getenv("AUDIT_WEBHOOK_SECRET"), format, timeout)
+	default:
+		return nil, fmt.Errorf("unknown audit sink %q", name)
+	}
+}
+
+// envReader parses optional numeric settings, remembering the first error.
+type envReader struct {
+	getenv func(string) string
+	err    error
+}
+
+func (e *envReader) int(key string, fallback int) int {
+	value := e.getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		if e.err == nil {
+			e.err = fmt.Errorf("invalid %s: %q", key, value)
+		}
+		return fallback
+	}
+	return n
+}
+
+func (e *envReader) duration(key string, fallback time.Duration) time.Duration {
+	value := e.getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		if e.err == nil {
+			e.err = fmt.Errorf("invalid %s: %q", key, value)
+		}
+		return fallback
+	}
+	return d
+}
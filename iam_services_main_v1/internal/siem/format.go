@@ -0,0 +1,120 @@
+package siem
+
+import (
+	"encoding/json"
+	"fmt"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/dto"
+	"strconv"
+	"strings"
+)
+
+// Format selects how events are serialised for a sink.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCEF  Format = "cef"
+)
+
+// CEF header values identifying this service.
+const (
+	cefVendor  = "IAM"
+	cefProduct = "iam_services"
+	cefVersion = "1.0"
+)
+
+// ParseFormat validates a format name, defaulting to JSON when empty.
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(value)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatCEF:
+		return FormatCEF, nil
+	default:
+		return "", fmt.Errorf("unknown audit format %q", value)
+	}
+}
+
+// Encode serialises an event as a single line in the given format.
+func Encode(format Format, event dto.AuditEvent) ([]byte, error) {
+	if format == FormatCEF {
+		return []byte(EncodeCEF(event)), nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+	return data, nil
+}
+
+// EncodeCEF renders an event in ArcSight Common Event Format. Failed
+// operations are reported with a higher severity than successful ones, and
+// high-severity operations with the highest.
+func EncodeCEF(event dto.AuditEvent) string {
+	severity := "3"
+	switch {
+	case audit.IsHighSeverity(event.Operation):
+		severity = "10"
+	case event.Outcome != audit.OutcomeSuccess:
+		severity = "7"
+	}
+
+	extensions := []struct{ key, value string }{
+		{"rt", strconv.FormatInt(event.OccurredAt.UnixMilli(), 10)},
+		{"externalId", event.EventID},
+		{"act", event.Operation},
+		{"outcome", event.Outcome},
+		{"suser", event.ActorID},
+		{"duser", event.ImpersonatedUserID},
+		{"src", event.ClientIP},
+		{"cs1Label", "tenantId"},
+		{"cs1", event.TenantID},
+		{"cs2Label", "targetResourceId"},
+		{"cs2", event.TargetResourceID},
+		{"cs3Label", "requestId"},
+		{"cs3", event.RequestID},
+		{"cs4Label", "hash"},
+		{"cs4", event.Hash},
+		{"cn1Label", "sequence"},
+		{"cn1", strconv.FormatUint(event.Sequence, 10)},
+		{"msg", event.ErrorMessage},
+	}
+
+	var ext strings.Builder
+	for _, e := range extensions {
+		if e.value == "" {
+			continue
+		}
+		if ext.Len() > 0 {
+			ext.WriteByte(' ')
+		}
+		ext.WriteString(e.key)
+		ext.WriteByte('=')
+		ext.WriteString(escapeCEFExtension(e.value))
+	}
+
+	return strings.Join([]string{
+		"CEF:0",
+		escapeCEFHeader(cefVendor),
+		escapeCEFHeader(cefProduct),
+		escapeCEFHeader(cefVersion),
+		escapeCEFHeader(event.Operation),
+		escapeCEFHeader(event.Operation + " " + strings.ToLower(event.Outcome)),
+		severity,
+		ext.String(),
+	}, "|")
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+func escapeCEFHeader(value string) string {
+	return cefHeaderEscaper.Replace(value)
+}
+
+func escapeCEFExtension(value string) string {
+	return cefExtensionEscaper.Replace(value)
+}
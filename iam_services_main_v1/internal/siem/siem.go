@@ -0,0 +1,221 @@
+package siem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/pkg/logger"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sink delivers audit events to an external system.
+type Sink interface {
+	// Name identifies the sink in logs.
+	Name() string
+	// Write delivers a single event. Errors wrapped with Permanent are not retried.
+	Write(ctx context.Context, event dto.AuditEvent) error
+	// Close releases the resources held by the sink.
+	Close() error
+}
+
+// permanentError marks a delivery failure that retrying cannot fix.
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the dispatcher gives up on the event immediately.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// RetryPolicy controls how often and how quickly failed deliveries are retried.
+type RetryPolicy struct {
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// delay returns the exponential backoff before the given retry (starting at 1).
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// Options configures a Dispatcher.
+type Options struct {
+	// BufferSize is the number of events each sink may hold in memory.
+	BufferSize int
+	// EnqueueTimeout is how long Publish blocks on a full buffer before the
+	// event is dropped for that sink.
+	EnqueueTimeout time.Duration
+	Retry          RetryPolicy
+}
+
+// Dispatcher fans audit events out to sinks. Every sink has its own buffer and
+// worker so a slow or unavailable sink does not hold back the others.
+type Dispatcher struct {
+	workers []*worker
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closed  atomic.Bool
+	opts    Options
+}
+
+type worker struct {
+	sink      Sink
+	queue     chan dto.AuditEvent
+	done      chan struct{}
+	delivered atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+}
+
+// Stats reports delivery counters of a sink.
+type Stats struct {
+	Delivered int64
+	Dropped   int64
+	Failed    int64
+}
+
+// NewDispatcher starts one worker per sink.
+func NewDispatcher(opts Options, sinks ...Sink) *Dispatcher {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{ctx: ctx, cancel: cancel, opts: opts}
+	for _, sink := range sinks {
+		w := &worker{
+			sink:  sink,
+			queue: make(chan dto.AuditEvent, opts.BufferSize),
+			done:  make(chan struct{}),
+		}
+		d.workers = append(d.workers, w)
+		go d.run(w)
+	}
+	return d
+}
+
+// Publish queues an event for every sink. When a sink's buffer is full,
+// Publish waits up to EnqueueTimeout for room before dropping the event for
+// that sink, so callers are slowed down rather than blocked indefinitely.
+func (d *Dispatcher) Publish(event dto.AuditEvent) {
+	if d == nil || d.closed.Load() {
+		return
+	}
+	for _, w := range d.workers {
+		select {
+		case w.queue <- event:
+			continue
+		default:
+		}
+
+		timer := time.NewTimer(d.opts.EnqueueTimeout)
+		select {
+		case w.queue <- event:
+		case <-timer.C:
+			w.dropped.Add(1)
+			logger.LogError(fmt.Sprintf("SIEM sink %s buffer full, dropped audit event %d", w.sink.Name(), event.Sequence))
+		}
+		timer.Stop()
+	}
+}
+
+// Stats returns the delivery counters keyed by sink name.
+func (d *Dispatcher) Stats() map[string]Stats {
+	stats := make(map[string]Stats, len(d.workers))
+	for _, w := range d.workers {
+		stats[w.sink.Name()] = Stats{
+			Delivered: w.delivered.Load(),
+			Dropped:   w.dropped.Load(),
+			Failed:    w.failed.Load(),
+		}
+	}
+	return stats
+}
+
+// Close stops accepting events and waits for the buffers to drain until ctx
+// is done, then closes the sinks.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil || !d.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	for _, w := range d.workers {
+		close(w.queue)
+	}
+
+	var wg sync.WaitGroup
+	for _, w := range d.workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			<-w.done
+		}(w)
+	}
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = fmt.Errorf("audit events still pending: %w", ctx.Err())
+		d.cancel()
+		<-drained
+	}
+	d.cancel()
+
+	for _, w := range d.workers {
+		if closeErr := w.sink.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to close sink %s: %w", w.sink.Name(), closeErr))
+		}
+	}
+	return err
+}
+
+func (d *Dispatcher) run(w *worker) {
+	defer close(w.done)
+	for event := range w.queue {
+		if err := d.deliver(w.sink, event); err != nil {
+			w.failed.Add(1)
+			logger.LogError(fmt.Sprintf("SIEM sink %s failed to deliver audit event %d: %v", w.sink.Name(), event.Sequence, err))
+			continue
+		}
+		w.delivered.Add(1)
+	}
+}
+
+// deliver writes an event, retrying transient failures with exponential backoff.
+func (d *Dispatcher) deliver(sink Sink, event dto.AuditEvent) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = sink.Write(d.ctx, event); err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= d.opts.Retry.MaxRetries {
+			return err
+		}
+
+		timer := time.NewTimer(d.opts.Retry.delay(attempt + 1))
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (shutting down)", err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package siem
+
+import (
+	"context"
+	"errors"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/pkg/logger"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink fails its first `failures` writes, then records events.
+type fakeSink struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	block    chan struct{}
+	attempts int
+	events   []dto.AuditEvent
+	closed   bool
+}
+
+func (s *fakeSink) Name() string { return "fake" }
+
+func (s *fakeSink) Write(ctx context.Context, event dto.AuditEvent) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.attempts <= s.failures {
+		return s.err
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func testOptions() Options {
+	return Options{
+		BufferSize:     10,
+		EnqueueTimeout: 10 * time.Millisecond,
+		Retry:          RetryPolicy{MaxRetries: 3, Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond},
+	}
+}
+
+func TestDispatcherRetriesTransientFailures(t *testing.T) {
+	logger.InitLogger()
+	sink := &fakeSink{failures: 2, err: errors.New("connection refused")}
+	d := NewDispatcher(testOptions(), sink)
+
+	d.Publish(dto.AuditEvent{Sequence: 1})
+	require.NoError(t, d.Close(context.Background()))
+
+	assert.Equal(t, 3, sink.attempts)
+	assert.Len(t, sink.events, 1)
+	assert.True(t, sink.closed)
+	assert.Equal(t, Stats{Delivered: 1}, d.Stats()["fake"])
+}
+
+func TestDispatcherGivesUpOnPermanentFailures(t *testing.T) {
+	logger.InitLogger()
+	sink := &fakeSink{failures: 10, err: Permanent(errors.New("bad request"))}
+	d := NewDispatcher(testOptions(), sink)
+
+	d.Publish(dto.AuditEvent{Sequence: 1})
+	require.NoError(t, d.Close(context.Background()))
+
+	assert.Equal(t, 1, sink.attempts)
+	assert.Equal(t, Stats{Failed: 1}, d.Stats()["fake"])
+}
+
+func TestDispatcherDropsWhenBufferStaysFull(t *testing.T) {
+	logger.InitLogger()
+	opts := testOptions()
+	opts.BufferSize = 1
+	slow := &fakeSink{block: make(chan struct{})}
+	fast := &fakeSink{}
+	d := NewDispatcher(opts, slow, fast)
+
+	// The first event is taken by the blocked worker, the second fills the
+	// buffer and the third cannot be queued in time.
+	for i := 1; i <= 3; i++ {
+		d.Publish(dto.AuditEvent{Sequence: uint64(i)})
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(slow.block)
+	require.NoError(t, d.Close(context.Background()))
+
+	assert.Len(t, slow.events, 2)
+	assert.Len(t, fast.events, 3)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	assert.Equal(t, 100*time.Millisecond, policy.delay(1))
+	assert.Equal(t, 400*time.Millisecond, policy.delay(3))
+	assert.Equal(t, time.Second, policy.delay(10))
+}
+
+func TestNewDispatcherFromEnv(t *testing.T) {
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+
+	d, err := newDispatcherFromLookup(getenv)
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	env["AUDIT_SINKS"] = "file, webhook"
+	env["AUDIT_FILE_PATH"] = t.TempDir() + "/audit.jsonl"
+	env["AUDIT_WEBHOOK_URL"] = "http://siem.invalid/events"
+	env["AUDIT_WEBHOOK_FORMAT"] = "cef"
+	d, err = newDispatcherFromLookup(getenv)
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Len(t, d.workers, 2)
+	require.NoError(t, d.Close(context.Background()))
+
+	env["AUDIT_SINKS"] = "kafka"
+	_, err = newDispatcherFromLookup(getenv)
+	assert.Error(t, err)
+
+	env["AUDIT_SINKS"] = "file"
+	env["AUDIT_MAX_RETRIES"] = "many"
+	_, err = newDispatcherFromLookup(getenv)
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package siem
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"iam_services_main_v1/internal/dto"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleEvent() dto.AuditEvent {
+	return dto.AuditEvent{
+		Sequence:         42,
+		EventID:          "5f0c6a6e-1111-4222-8333-944455556666",
+		OccurredAt:       time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		ActorID:          "actor",
+		TenantID:         "tenant",
+		Operation:        "deleteRole",
+		TargetResourceID: "role",
+		ClientIP:         "10.0.0.7",
+		Outcome:          "FAILURE",
+		ErrorMessage:     "Role not found: a=b\nc|d",
+		Hash:             "abc",
+	}
+}
+
+func TestEncodeCEF(t *testing.T) {
+	line := EncodeCEF(sampleEvent())
+
+	assert.True(t, strings.HasPrefix(line, "CEF:0|IAM|iam_services|1.0|deleteRole|deleteRole failure|7|"))
+	assert.Contains(t, line, "rt=1714566600000")
+	assert.Contains(t, line, "suser=actor")
+	assert.Contains(t, line, "cs1Label=tenantId cs1=tenant")
+	assert.Contains(t, line, `msg=Role not found: a\=b\nc|d`)
+	assert.NotContains(t, line, "\n")
+
+	event := sampleEvent()
+	event.Operation, event.Outcome = "breakGlass", "SUCCESS"
+	assert.True(t, strings.HasPrefix(EncodeCEF(event), "CEF:0|IAM|iam_services|1.0|breakGlass|breakGlass success|10|"))
+}
+
+func TestFileSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	event := sampleEvent()
+	line, err := Encode(FormatJSON, event)
+	require.NoError(t, err)
+
+	// Room for two lines per file
+	sink, err := NewFileSink(path, int64(2*(len(line)+1)), 2, FormatJSON)
+	require.NoError(t, err)
+	for i := 0; i < 7; i++ {
+		require.NoError(t, sink.Write(context.Background(), event))
+	}
+	require.NoError(t, sink.Close())
+
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(current), "\n"))
+	for _, backup := range []string{path + ".1", path + ".2"} {
+		data, err := os.ReadFile(backup)
+		require.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	var decoded dto.AuditEvent
+	require.NoError(t, json.Unmarshal(current[:len(current)-1], &decoded))
+	assert.Equal(t, event.EventID, decoded.EventID)
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), "iam", FormatCEF, time.Second)
+	require.NoError(t, err)
+	defer sink.Close()
+	require.NoError(t, sink.Write(context.Background(), sampleEvent()))
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	message := string(buf[:n])
+	// authpriv (10) * 8 + warning (4) for a failed operation
+	assert.True(t, strings.HasPrefix(message, "<84>1 2024-05-01T12:30:00.000Z "), message)
+	assert.Contains(t, message, " iam ")
+	assert.Contains(t, message, " deleteRole - CEF:0|")
+}
+
+func TestSyslogSinkTCPUsesOctetCounting(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		length, _ := reader.ReadString(' ')
+		n := 0
+		for _, c := range strings.TrimSpace(length) {
+			n = n*10 + int(c-'0')
+		}
+		message := make([]byte, n)
+		_, _ = io.ReadFull(reader, message)
+		received <- string(message)
+	}()
+
+	sink, err := NewSyslogSink("tcp", listener.Addr().String(), "iam", FormatJSON, time.Second)
+	require.NoError(t, err)
+	defer sink.Close()
+	require.NoError(t, sink.Write(context.Background(), sampleEvent()))
+
+	select {
+	case message := <-received:
+		assert.True(t, strings.HasPrefix(message, "<84>1 "))
+		assert.True(t, strings.HasSuffix(message, "}"))
+	case <-time.After(time.Second):
+		t.Fatal("no syslog message received")
+	}
+}
+
+func TestWebhookSinkSignsPayload(t *testing.T) {
+	var status = http.StatusOK
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ = io.ReadAll(req.Body)
+		header = req.Header
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink, err := NewWebhookSink(srv.URL, "s3cret", FormatJSON, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, sink.Write(context.Background(), sampleEvent()))
+
+	timestamp := header.Get(WebhookTimestampHeader)
+	require.NotEmpty(t, timestamp)
+	assert.Equal(t, "sha256="+Sign([]byte("s3cret"), timestamp, body), header.Get(WebhookSignatureHeader))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+
+	// Server errors are retried, other client errors are not
+	status = http.StatusServiceUnavailable
+	err = sink.Write(context.Background(), sampleEvent())
+	var permanent *permanentError
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &permanent))
+
+	status = http.StatusBadRequest
+	err = sink.Write(context.Background(), sampleEvent())
+	assert.True(t, errors.As(err, &permanent))
+}
@@ -0,0 +1,147 @@
+package siem
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/internal/audit"
+	"iam_services_main_v1/internal/dto"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog facility and severities used for audit events (RFC 5424 section 6.2.1).
+const (
+	syslogFacilityAuthpriv = 10
+	syslogSeverityAlert    = 1
+	syslogSeverityWarning  = 4
+	syslogSeverityInfo     = 6
+)
+
+// SyslogSink sends events as RFC 5424 messages over UDP or TCP. TCP messages
+// use octet-counting framing (RFC 6587); the connection is re-established on
+// the next write after a failure.
+type SyslogSink struct {
+	Network string
+	Address string
+	AppName string
+	Format  Format
+	Timeout time.Duration
+
+	hostname string
+	mu       sync.Mutex
+	conn     net.Conn
+}
+
+// NewSyslogSink validates the transport; the connection is opened lazily.
+func NewSyslogSink(network, address, appName string, format Format, timeout time.Duration) (*SyslogSink, error) {
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	if address == "" {
+		return nil, fmt.Errorf("syslog address is required")
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{
+		Network:  network,
+		Address:  address,
+		AppName:  appName,
+		Format:   format,
+		Timeout:  timeout,
+		hostname: hostname,
+	}, nil
+}
+
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+func (s *SyslogSink) Write(ctx context.Context, event dto.AuditEvent) error {
+	message, err := s.formatMessage(event)
+	if err != nil {
+		return Permanent(err)
+	}
+	if s.Network == "tcp" {
+		message = []byte(fmt.Sprintf("%d %s", len(message), message))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: s.Timeout}
+		conn, err := dialer.DialContext(ctx, s.Network, s.Address)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+		s.conn = conn
+	}
+	if s.Timeout > 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.Timeout))
+	}
+	if _, err := s.conn.Write(message); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to write to syslog: %w", err)
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// formatMessage renders "<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG".
+func (s *SyslogSink) formatMessage(event dto.AuditEvent) ([]byte, error) {
+	body, err := Encode(s.Format, event)
+	if err != nil {
+		return nil, err
+	}
+	severity := syslogSeverityInfo
+	switch {
+	case audit.IsHighSeverity(event.Operation):
+		severity = syslogSeverityAlert
+	case event.Outcome != audit.OutcomeSuccess:
+		severity = syslogSeverityWarning
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s -",
+		syslogFacilityAuthpriv*8+severity,
+		event.OccurredAt.UTC().Format("2006-01-02T15:04:05.000Z"),
+		syslogField(s.hostname, 255),
+		syslogField(s.AppName, 48),
+		os.Getpid(),
+		syslogField(event.Operation, 32),
+	)
+	return append([]byte(header+" "), body...), nil
+}
+
+// syslogField returns value restricted to printable US-ASCII without spaces
+// and truncated to max, or the NILVALUE when empty.
+func syslogField(value string, max int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+	if len(value) > max {
+		value = value[:max]
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,101 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers set on webhook deliveries. The signature is the hex encoded
+// HMAC-SHA256 of "<timestamp>.<body>" so receivers can reject replays.
+const (
+	WebhookSignatureHeader = "X-IAM-Signature"
+	WebhookTimestampHeader = "X-IAM-Timestamp"
+)
+
+// WebhookSink POSTs every event to an HTTP endpoint.
+type WebhookSink struct {
+	URL    string
+	Secret []byte
+	Format Format
+	client *http.Client
+}
+
+// NewWebhookSink creates a webhook sink; an empty secret disables signing.
+func NewWebhookSink(url, secret string, format Format, timeout time.Duration) (*WebhookSink, error) {
+	if url == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	return &WebhookSink{
+		URL:    url,
+		Secret: []byte(secret),
+		Format: format,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Write(ctx context.Context, event dto.AuditEvent) error {
+	body, err := Encode(s.Format, event)
+	if err != nil {
+		return Permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("failed to create webhook request: %w", err))
+	}
+	if s.Format == FormatCEF {
+		req.Header.Set("Content-Type", "text/plain")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if len(s.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+Sign(s.Secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	// Client errors other than timeouts and rate limiting will not succeed on retry
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return Permanent(err)
+	}
+	return err
+}
+
+func (s *WebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Sign computes the webhook signature of body sent at timestamp.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
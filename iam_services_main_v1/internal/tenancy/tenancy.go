@@ -0,0 +1,254 @@
+// Package tenancy isolates tenant-scoped tables per tenant. Its GORM plugin
+// restricts every statement on a tenant-scoped model to the tenant of the
+// statement's context and rejects writes that would move rows into another
+// tenant.
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"iam_services_main_v1/helpers"
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Column is the tenant column of tenant-scoped tables.
+const Column = "tenant_id"
+
+const skipSetting = "tenancy:skip"
+
+var (
+	ErrTenantRequired   = errors.New("tenant-scoped statement without a tenant in context")
+	ErrCrossTenantWrite = errors.New("write crosses tenant boundary")
+)
+
+// Scoped is implemented by models whose rows belong to the tenant in their
+// tenant_id column.
+type Scoped interface {
+	TenantScoped()
+}
+
+type tenantKey struct{}
+
+// root is stored under tenantKey by AsRoot.
+type root struct{}
+
+// WithTenant returns a context scoping statements to tenantID, for work that
+// runs outside a request.
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// AsRoot returns a context for Root-level work done on behalf of a tenant's
+// request, such as creating a tenant or reading the Root organization.
+// ForRequest lifts tenant isolation for it.
+func AsRoot(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, root{})
+}
+
+// TenantFromContext returns the tenant set with WithTenant or, failing that,
+// the request's tenant. Contexts marked with AsRoot have no tenant.
+func TenantFromContext(ctx context.Context) *uuid.UUID {
+	if ctx == nil {
+		return nil
+	}
+	switch tenantID := ctx.Value(tenantKey{}).(type) {
+	case uuid.UUID:
+		return &tenantID
+	case root:
+		return nil
+	}
+	tenantID, err := helpers.GetTenantID(ctx)
+	if err != nil {
+		return nil
+	}
+	return tenantID
+}
+
+// WithoutTenantScope is a GORM scope lifting tenant isolation. It is required
+// for Root-level operations on tenant-scoped models:
+//
+//	db.Scopes(tenancy.WithoutTenantScope).Where(...).First(&resource)
+func WithoutTenantScope(db *gorm.DB) *gorm.DB {
+	return db.Set(skipSetting, true)
+}
+
+// RootSession returns a session of db without tenant isolation that can be
+// reused across Root-level statements.
+func RootSession(db *gorm.DB) *gorm.DB {
+	return db.Scopes(WithoutTenantScope).Session(&gorm.Session{})
+}
+
+// AllowRoot marks the context of a request naming no tenant with AsRoot, for
+// resolvers that also serve Root-level requests. @hasPermission only admits
+// those with a binding outside any tenant.
+func AllowRoot(ctx context.Context) context.Context {
+	if TenantFromContext(ctx) == nil {
+		return AsRoot(ctx)
+	}
+	return ctx
+}
+
+// ForRequest scopes db to the request's tenant. Statements on tenant-scoped
+// models fail with ErrTenantRequired when the request names no tenant, unless
+// ctx was marked with AsRoot or AllowRoot, which lift tenant isolation.
+func ForRequest(ctx context.Context, db *gorm.DB) *gorm.DB {
+	db = db.WithContext(ctx)
+	if _, ok := ctx.Value(tenantKey{}).(root); ok {
+		return RootSession(db)
+	}
+	return db
+}
+
+// Plugin registers the tenant isolation callbacks. Statements on a
+// tenant-scoped model fail with ErrTenantRequired when their context carries no
+// tenant and WithoutTenantScope was not applied.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenancy"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Query().Before("gorm:query").Register("tenancy:query", scopeStatement); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("tenancy:row", scopeStatement); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenancy:delete", scopeStatement); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenancy:update", scopeUpdate); err != nil {
+		return err
+	}
+	return callbacks.Create().Before("gorm:create").Register("tenancy:create", scopeCreate)
+}
+
+// statementTenant returns the tenant a statement is restricted to, or false
+// when the statement is not subject to isolation.
+func statementTenant(db *gorm.DB) (*uuid.UUID, bool) {
+	if db.Error != nil || db.Statement.Schema == nil || !isScoped(db.Statement.Schema) {
+		return nil, false
+	}
+	if skip, ok := db.Get(skipSetting); ok && skip == true {
+		return nil, false
+	}
+	tenantID := TenantFromContext(db.Statement.Context)
+	if tenantID == nil {
+		_ = db.AddError(ErrTenantRequired)
+		return nil, false
+	}
+	return tenantID, true
+}
+
+func isScoped(s *schema.Schema) bool {
+	if s.LookUpField(Column) == nil {
+		return false
+	}
+	_, ok := reflect.New(s.ModelType).Interface().(Scoped)
+	return ok
+}
+
+func scopeStatement(db *gorm.DB) {
+	if tenantID, ok := statementTenant(db); ok {
+		addTenantCondition(db, *tenantID)
+	}
+}
+
+func scopeUpdate(db *gorm.DB) {
+	tenantID, ok := statementTenant(db)
+	if !ok {
+		return
+	}
+	if assigned, found := assignedTenant(db); found && (assigned == nil || *assigned != *tenantID) {
+		_ = db.AddError(ErrCrossTenantWrite)
+		return
+	}
+	addTenantCondition(db, *tenantID)
+}
+
+func scopeCreate(db *gorm.DB) {
+	tenantID, ok := statementTenant(db)
+	if !ok {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(Column)
+	check := func(rv reflect.Value) {
+		value, zero := field.ValueOf(db.Statement.Context, rv)
+		if zero {
+			_ = db.AddError(field.Set(db.Statement.Context, rv, tenantID))
+			return
+		}
+		if assigned := toUUID(value); assigned == nil || *assigned != *tenantID {
+			_ = db.AddError(ErrCrossTenantWrite)
+		}
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			check(reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		check(rv)
+	}
+}
+
+func addTenantCondition(db *gorm.DB, tenantID uuid.UUID) {
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: Column}, Value: tenantID},
+	}})
+}
+
+// assignedTenant returns the tenant an update writes, if it writes one.
+// Zero-valued struct fields are only written by Save.
+func assignedTenant(db *gorm.DB) (*uuid.UUID, bool) {
+	if dest, ok := db.Statement.Dest.(map[string]interface{}); ok {
+		for _, key := range []string{Column, "TenantID"} {
+			if value, ok := dest[key]; ok {
+				return toUUID(value), true
+			}
+		}
+		return nil, false
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(db.Statement.Dest))
+	if rv.Kind() != reflect.Struct || rv.Type() != db.Statement.Schema.ModelType {
+		return nil, false
+	}
+	value, zero := db.Statement.Schema.LookUpField(Column).ValueOf(db.Statement.Context, rv)
+	if zero {
+		return nil, savesAllFields(db)
+	}
+	return toUUID(value), true
+}
+
+func savesAllFields(db *gorm.DB) bool {
+	for _, column := range db.Statement.Selects {
+		if column == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func toUUID(value interface{}) *uuid.UUID {
+	switch v := value.(type) {
+	case uuid.UUID:
+		return &v
+	case *uuid.UUID:
+		return v
+	case string:
+		if parsed, err := uuid.Parse(v); err == nil {
+			return &parsed
+		}
+	}
+	return nil
+}
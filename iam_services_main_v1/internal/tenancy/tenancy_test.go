@@ -0,0 +1,120 @@
+package tenancy
+
+import (
+	"context"
+	"fmt"
+	"iam_services_main_v1/internal/dto"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&dto.TenantResource{}, &dto.TenantMetadata{}); err != nil {
+		t.Fatalf("Failed to migrate database: %v", err)
+	}
+	if err := db.Use(Plugin{}); err != nil {
+		t.Fatalf("Failed to register tenancy plugin: %v", err)
+	}
+	return db
+}
+
+func requestContext(tenantID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("tenantID", tenantID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func seedResource(t *testing.T, db *gorm.DB, tenantID *uuid.UUID, name string) uuid.UUID {
+	id := uuid.New()
+	require.NoError(t, db.Scopes(WithoutTenantScope).Create(&dto.TenantResource{
+		ResourceID: id, ResourceTypeID: uuid.New(), Name: name, TenantID: tenantID, RowStatus: 1,
+	}).Error)
+	return id
+}
+
+func TestQueriesAreScopedToTheRequestTenant(t *testing.T) {
+	db := setupTestDB(t)
+	acme, globex := uuid.New(), uuid.New()
+	acmeResource := seedResource(t, db, &acme, "acme")
+	globexResource := seedResource(t, db, &globex, "globex")
+	seedResource(t, db, nil, "root")
+
+	scoped := db.WithContext(requestContext(acme))
+
+	var resources []dto.TenantResource
+	require.NoError(t, scoped.Find(&resources).Error)
+	require.Len(t, resources, 1)
+	assert.Equal(t, acmeResource, resources[0].ResourceID)
+
+	var resource dto.TenantResource
+	err := scoped.Where("resource_id = ?", globexResource).First(&resource).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	var count int64
+	require.NoError(t, db.WithContext(WithTenant(context.Background(), globex)).Model(&dto.TenantResource{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Root-level statements must opt out explicitly
+	err = db.Find(&resources).Error
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	require.NoError(t, db.Scopes(WithoutTenantScope).Find(&resources).Error)
+	assert.Len(t, resources, 3)
+	require.NoError(t, ForRequest(AsRoot(requestContext(acme)), db).Find(&resources).Error)
+	assert.Len(t, resources, 3)
+	err = ForRequest(context.Background(), db).Find(&resources).Error
+	assert.ErrorIs(t, err, ErrTenantRequired)
+	require.NoError(t, ForRequest(AllowRoot(context.Background()), db).Find(&resources).Error)
+	assert.Len(t, resources, 3)
+	require.NoError(t, ForRequest(AllowRoot(requestContext(acme)), db).Find(&resources).Error)
+	assert.Len(t, resources, 1)
+
+	// Models outside the tenancy plugin are left alone
+	require.NoError(t, db.Find(&[]dto.TenantMetadata{}).Error)
+}
+
+func TestWritesAreScopedToTheRequestTenant(t *testing.T) {
+	db := setupTestDB(t)
+	acme, globex := uuid.New(), uuid.New()
+	globexResource := seedResource(t, db, &globex, "globex")
+	scoped := db.WithContext(requestContext(acme))
+
+	// Updates and deletes of another tenant's rows match nothing
+	result := scoped.Model(&dto.TenantResource{}).Where("resource_id = ?", globexResource).Update("name", "taken")
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+	result = scoped.Where("resource_id = ?", globexResource).Delete(&dto.TenantResource{})
+	require.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	// Creates take the request tenant
+	created := dto.TenantResource{ResourceID: uuid.New(), ResourceTypeID: uuid.New(), Name: "new", RowStatus: 1}
+	require.NoError(t, scoped.Create(&created).Error)
+	require.NotNil(t, created.TenantID)
+	assert.Equal(t, acme, *created.TenantID)
+
+	// Writes naming another tenant are rejected
+	err := scoped.Create(&dto.TenantResource{ResourceID: uuid.New(), ResourceTypeID: uuid.New(), Name: "other", TenantID: &globex}).Error
+	assert.ErrorIs(t, err, ErrCrossTenantWrite)
+
+	err = scoped.Model(&dto.TenantResource{}).Where("resource_id = ?", created.ResourceID).Updates(map[string]interface{}{"tenant_id": globex}).Error
+	assert.ErrorIs(t, err, ErrCrossTenantWrite)
+
+	created.TenantID = nil
+	err = scoped.Save(&created).Error
+	assert.ErrorIs(t, err, ErrCrossTenantWrite)
+
+	var stored dto.TenantResource
+	require.NoError(t, scoped.Where("resource_id = ?", created.ResourceID).First(&stored).Error)
+	assert.Equal(t, acme, *stored.TenantID)
+}
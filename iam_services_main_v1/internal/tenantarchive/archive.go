@@ -0,0 +1,103 @@
+// Package tenantarchive moves tenants between environments. A tenant is
+// exported as a versioned JSON archive that refers to resource types and
+// permissions by name, and imported as a new tenant whose objects all get new
+// identifiers.
+package tenantarchive
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version is the archive format version Export writes and Parse accepts.
+const Version = 1
+
+// Archive is a portable copy of a tenant. Identifiers are those of the
+// exporting environment; they only relate the objects of the archive.
+type Archive struct {
+	Version    int         `json:"version"`
+	ExportedAt time.Time   `json:"exportedAt"`
+	Tenant     Tenant      `json:"tenant"`
+	Resources  []Resource  `json:"resources"`
+	Principals []Principal `json:"principals"`
+	Roles      []Role      `json:"roles"`
+	Bindings   []Binding   `json:"bindings"`
+}
+
+// Tenant is the tenant an archive was exported from.
+type Tenant struct {
+	ID       uuid.UUID       `json:"id"`
+	Name     string          `json:"name"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// Resource is a resource of the tenant, such as a client organization unit.
+type Resource struct {
+	ID uuid.UUID `json:"id"`
+	// Type is the name of the resource type.
+	Type string `json:"type"`
+	// ParentID is the tenant or another resource of the archive.
+	ParentID *uuid.UUID      `json:"parentId,omitempty"`
+	Name     string          `json:"name"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// Principal is a user or group of the tenant.
+type Principal struct {
+	Resource
+	// Profile is the directory entry of the principal, when it has one.
+	Profile *Profile `json:"profile,omitempty"`
+}
+
+// Profile is the directory entry of a principal.
+type Profile struct {
+	Name     string          `json:"name"`
+	Email    string          `json:"email"`
+	Metadata json.RawMessage `json:"metadata,omitempty"`
+}
+
+// Role is a custom role of the tenant.
+type Role struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Version     string    `json:"version"`
+	// AssignableScope is the name of the resource type the role is assigned on.
+	AssignableScope string `json:"assignableScope"`
+	// Permissions are the actions the role grants.
+	Permissions []string `json:"permissions"`
+}
+
+// Binding grants a role to a principal of the archive on the tenant or one of
+// its resources.
+type Binding struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	PrincipalID uuid.UUID `json:"principalId"`
+	// RoleID is a role of the archive or a predefined role, which keeps its
+	// identifier across environments.
+	RoleID  uuid.UUID `json:"roleId"`
+	ScopeID uuid.UUID `json:"scopeId"`
+}
+
+// Parse reads an archive, rejecting unknown fields and other versions.
+func Parse(data []byte) (*Archive, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var archive Archive
+	if err := decoder.Decode(&archive); err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	if archive.Version != Version {
+		return nil, fmt.Errorf("unsupported archive version %d, expected %d", archive.Version, Version)
+	}
+	if archive.Tenant.ID == uuid.Nil || archive.Tenant.Name == "" {
+		return nil, fmt.Errorf("invalid archive: the tenant's id and name are required")
+	}
+	return &archive, nil
+}
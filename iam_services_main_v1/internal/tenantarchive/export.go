@@ -0,0 +1,157 @@
+package tenantarchive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Export reads tenant tenantID into an archive. Bindings with an expiry, such
+// as break-glass grants, are not exported.
+func Export(ctx context.Context, store repository.Store, tenantID uuid.UUID) (*Archive, error) {
+	tenant, err := store.Resources().Get(ctx, tenantID)
+	if err != nil {
+		return nil, batch.Fail("404", "Tenant not found", err)
+	}
+	typeNames := map[uuid.UUID]string{}
+	typeName := func(id uuid.UUID) (string, error) {
+		if name, ok := typeNames[id]; ok {
+			return name, nil
+		}
+		resourceType, err := store.Resources().GetType(ctx, id)
+		if err != nil {
+			return "", batch.Fail("500", "Error getting resource type", fmt.Errorf("resource type %s: %w", id, err))
+		}
+		typeNames[id] = resourceType.Name
+		return resourceType.Name, nil
+	}
+	if name, err := typeName(tenant.ResourceTypeID); err != nil || name != constants.ResourceTypeTenant {
+		return nil, batch.Fail("404", "Tenant not found", fmt.Errorf("resource %s is not a tenant", tenantID))
+	}
+
+	// Everything else is read within the tenant
+	ctx = tenancy.WithTenant(ctx, tenantID)
+	archive := &Archive{
+		Version:    Version,
+		ExportedAt: time.Now().UTC(),
+		Tenant:     Tenant{ID: tenantID, Name: tenant.Name},
+		Resources:  []Resource{},
+		Principals: []Principal{},
+		Roles:      []Role{},
+		Bindings:   []Binding{},
+	}
+	if archive.Tenant.Metadata, err = metadataOf(ctx, store, tenantID); err != nil {
+		return nil, err
+	}
+
+	roles, err := store.Roles().List(ctx)
+	if err != nil {
+		return nil, batch.Fail("500", "Error listing roles", err)
+	}
+	isRole := make(map[uuid.UUID]bool, len(roles))
+	for _, role := range roles {
+		isRole[role.ResourceID] = true
+		scope, err := typeName(role.ScopeResourceTypeID)
+		if err != nil {
+			return nil, err
+		}
+		grants, err := store.Roles().Permissions(ctx, role.ResourceID)
+		if err != nil {
+			return nil, batch.Fail("500", "Error reading role permissions", err)
+		}
+		actions := make([]string, 0, len(grants))
+		for _, grant := range grants {
+			permission, err := store.Permissions().Get(ctx, grant.PermissionID)
+			if err != nil {
+				return nil, batch.Fail("500", "Error getting permission", fmt.Errorf("permission %s: %w", grant.PermissionID, err))
+			}
+			actions = append(actions, permission.ActionKey())
+		}
+		sort.Strings(actions)
+		archive.Roles = append(archive.Roles, Role{
+			ID:              role.ResourceID,
+			Name:            role.Name,
+			Description:     role.Description,
+			Version:         role.Version,
+			AssignableScope: scope,
+			Permissions:     actions,
+		})
+	}
+
+	resources, err := store.Resources().ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, batch.Fail("500", "Error listing resources", err)
+	}
+	for _, resource := range resources {
+		if resource.ResourceID == tenantID || isRole[resource.ResourceID] {
+			continue
+		}
+		name, err := typeName(resource.ResourceTypeID)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := metadataOf(ctx, store, resource.ResourceID)
+		if err != nil {
+			return nil, err
+		}
+		entry := Resource{ID: resource.ResourceID, Type: name, ParentID: resource.ParentResourceID, Name: resource.Name, Metadata: metadata}
+		if name != constants.ResourceTypeUser && name != constants.ResourceTypeGroup {
+			archive.Resources = append(archive.Resources, entry)
+			continue
+		}
+		principal := Principal{Resource: entry}
+		profile, err := store.Principals().Get(ctx, resource.ResourceID)
+		switch {
+		case err == nil:
+			principal.Profile = &Profile{Name: profile.Name, Email: profile.Email, Metadata: json.RawMessage(profile.Metadata)}
+		case !errors.Is(err, repository.ErrNotFound):
+			return nil, batch.Fail("500", "Error getting principal", err)
+		}
+		archive.Principals = append(archive.Principals, principal)
+	}
+
+	bindings, err := store.Assignments().ListByTenant(ctx, tenantID)
+	if err != nil {
+		return nil, batch.Fail("500", "Error listing bindings", err)
+	}
+	for _, binding := range bindings {
+		if binding.ExpiresAt != nil {
+			continue
+		}
+		scopeID := tenantID
+		if binding.ScopeID != nil {
+			scopeID = *binding.ScopeID
+		}
+		archive.Bindings = append(archive.Bindings, Binding{
+			ID:          binding.ResourceID,
+			Name:        binding.Name,
+			Version:     binding.Version,
+			PrincipalID: binding.PrincipalID,
+			RoleID:      binding.RoleID,
+			ScopeID:     scopeID,
+		})
+	}
+	return archive, nil
+}
+
+// metadataOf returns the metadata document of a resource, or nil when it has
+// none.
+func metadataOf(ctx context.Context, store repository.Store, resourceID uuid.UUID) (json.RawMessage, error) {
+	metadata, err := store.Metadata().Get(ctx, resourceID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && len(metadata.Metadata) == 0) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, batch.Fail("500", "Error getting metadata", err)
+	}
+	return json.RawMessage(metadata.Metadata), nil
+}
@@ -0,0 +1,428 @@
+package tenantarchive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/roles"
+	"iam_services_main_v1/internal/tenancy"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Phases order the Permit requests of an import: the tenant must exist before
+// its resource instances, and roles and principals before their assignments.
+const (
+	phaseTenant = iota
+	phaseObjects
+	phaseBindings
+)
+
+// importer recreates the objects of an archive as a new tenant.
+type importer struct {
+	store    repository.Store
+	roles    *roles.RoleMutationResolver
+	userID   uuid.UUID
+	tenantID uuid.UUID
+	// ids maps the identifiers of the archive's imported objects to their
+	// new ones.
+	ids   map[uuid.UUID]uuid.UUID
+	types map[string]*dto.Mst_ResourceTypes
+
+	conflicts []*models.TenantImportConflict
+	mappings  []*models.TenantImportMapping
+	items     []batch.Item
+	steps     []batch.Step
+}
+
+// entry is a resource or principal of an archive.
+type entry struct {
+	kind    models.TenantArchiveKind
+	profile *Profile
+	Resource
+}
+
+func newImporter(store repository.Store, userID uuid.UUID) *importer {
+	return &importer{
+		store:    store,
+		roles:    &roles.RoleMutationResolver{Store: store},
+		userID:   userID,
+		tenantID: uuid.New(),
+		ids:      map[uuid.UUID]uuid.UUID{},
+		types:    map[string]*dto.Mst_ResourceTypes{},
+	}
+}
+
+// prepare plans recreating archive and records the objects that cannot be
+// recreated as conflicts. ctx must be scoped to the new tenant.
+func (im *importer) prepare(ctx context.Context, archive *Archive) error {
+	if err := im.prepareTenant(ctx, archive.Tenant); err != nil {
+		return err
+	}
+
+	pending := make([]entry, 0, len(archive.Resources)+len(archive.Principals))
+	for _, resource := range archive.Resources {
+		pending = append(pending, entry{kind: models.TenantArchiveKindResource, Resource: resource})
+	}
+	for _, principal := range archive.Principals {
+		pending = append(pending, entry{kind: models.TenantArchiveKindPrincipal, profile: principal.Profile, Resource: principal.Resource})
+	}
+	// Parents are recreated before their children; what is left once no
+	// entry can be recreated has a parent that is not imported
+	for progress := true; progress; {
+		progress = false
+		var waiting []entry
+		for _, e := range pending {
+			if e.ParentID != nil && !im.imported(*e.ParentID) && im.inArchive(archive, *e.ParentID) && !im.conflicting(*e.ParentID) {
+				waiting = append(waiting, e)
+				continue
+			}
+			if err := im.prepareEntry(ctx, e); err != nil {
+				return err
+			}
+			progress = true
+		}
+		pending = waiting
+	}
+	for _, e := range pending {
+		im.conflict(e.kind, e.Name, e.ID, fmt.Sprintf("parent %s is not imported", e.ParentID))
+	}
+
+	archivedRoles := make(map[uuid.UUID]bool, len(archive.Roles))
+	for _, role := range archive.Roles {
+		archivedRoles[role.ID] = true
+		if err := im.prepareRole(ctx, role); err != nil {
+			return err
+		}
+	}
+	for _, binding := range archive.Bindings {
+		if err := im.prepareBinding(ctx, binding, archivedRoles); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *importer) imported(id uuid.UUID) bool {
+	_, ok := im.ids[id]
+	return ok
+}
+
+func (im *importer) conflicting(id uuid.UUID) bool {
+	for _, conflict := range im.conflicts {
+		if conflict.SourceID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (im *importer) inArchive(archive *Archive, id uuid.UUID) bool {
+	for _, resource := range archive.Resources {
+		if resource.ID == id {
+			return true
+		}
+	}
+	for _, principal := range archive.Principals {
+		if principal.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (im *importer) conflict(kind models.TenantArchiveKind, name string, sourceID uuid.UUID, reason string) {
+	im.conflicts = append(im.conflicts, &models.TenantImportConflict{Kind: kind, Name: name, SourceID: sourceID, Reason: reason})
+}
+
+// add maps sourceID to id and appends the item recreating the object.
+func (im *importer) add(sourceID, id uuid.UUID, apply func(ctx context.Context, tx repository.Store) error, step batch.Step) {
+	im.ids[sourceID] = id
+	im.mappings = append(im.mappings, &models.TenantImportMapping{SourceID: sourceID, ID: id})
+	im.items = append(im.items, batch.Item{
+		Apply: apply,
+		// The import is the result of the whole archive
+		Result: func(ctx context.Context) (models.OperationResult, error) { return nil, nil },
+	})
+	im.steps = append(im.steps, step)
+}
+
+// resourceType returns the resource type named name, or nil when this
+// environment has none.
+func (im *importer) resourceType(ctx context.Context, name string) (*dto.Mst_ResourceTypes, error) {
+	if resourceType, ok := im.types[name]; ok {
+		return resourceType, nil
+	}
+	resourceType, err := im.store.Resources().GetTypeByName(ctx, name)
+	if errors.Is(err, repository.ErrNotFound) {
+		resourceType, err = nil, nil
+	}
+	if err != nil {
+		return nil, batch.Fail("500", "Error getting resource type", err)
+	}
+	im.types[name] = resourceType
+	return resourceType, nil
+}
+
+// prepareTenant plans creating the tenant under the Root organization, in
+// Permit both as a tenant and as a resource instance.
+func (im *importer) prepareTenant(ctx context.Context, tenant Tenant) error {
+	tenantType, err := im.resourceType(ctx, constants.ResourceTypeTenant)
+	if err != nil {
+		return err
+	}
+	rootType, err := im.resourceType(ctx, constants.ResourceTypeRoot)
+	if err != nil {
+		return err
+	}
+	if tenantType == nil || rootType == nil {
+		return batch.Fail("500", "Error getting resource type", errors.New("the Tenant and Root resource types are required"))
+	}
+	roots, err := im.store.Resources().ListByType(tenancy.AsRoot(ctx), rootType.ResourceTypeID)
+	if err != nil || len(roots) == 0 {
+		return batch.Fail("500", "Root organization not found", err)
+	}
+	rootID := roots[0].ResourceID
+
+	tenantID := im.tenantID
+	metadata := dto.JSON(tenant.Metadata)
+	if len(metadata) == 0 {
+		metadata = dto.JSON("{}")
+	}
+	im.add(tenant.ID, tenantID, func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Resources().Create(ctx, &dto.TenantResource{
+			ResourceID:       tenantID,
+			ParentResourceID: &rootID,
+			ResourceTypeID:   tenantType.ResourceTypeID,
+			Name:             tenant.Name,
+			TenantID:         &tenantID,
+			RowStatus:        1,
+			CreatedBy:        im.userID,
+			UpdatedBy:        im.userID,
+		}); err != nil {
+			return batch.Fail("500", "Error creating tenant resource", err)
+		}
+		if err := tx.Metadata().Create(ctx, &dto.TenantMetadata{ResourceID: tenantID, Metadata: metadata, RowStatus: 1, CreatedBy: im.userID, UpdatedBy: im.userID}); err != nil {
+			return batch.Fail("500", "Error creating tenant metadata", err)
+		}
+		return nil
+	}, batch.Step{
+		Phase: phaseTenant,
+		Push:  &permit.Request{Method: "POST", Endpoint: "tenants", Payload: map[string]interface{}{"name": tenant.Name, "key": tenantID}},
+		Undo:  &permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("tenants/%s", tenantID)},
+	})
+
+	// The tenant's resource instance has no row of its own
+	im.items = append(im.items, batch.Item{
+		Apply:  func(ctx context.Context, tx repository.Store) error { return nil },
+		Result: func(ctx context.Context) (models.OperationResult, error) { return nil, nil },
+	})
+	im.steps = append(im.steps, instanceStep(tenantType.ResourceTypeID, tenantID, tenantID))
+	return nil
+}
+
+// prepareEntry plans recreating a resource or principal whose parent, if
+// any, is planned.
+func (im *importer) prepareEntry(ctx context.Context, e entry) error {
+	resourceType, err := im.resourceType(ctx, e.Type)
+	if err != nil {
+		return err
+	}
+	if resourceType == nil {
+		im.conflict(e.kind, e.Name, e.ID, fmt.Sprintf("resource type %q does not exist", e.Type))
+		return nil
+	}
+	var parentID *uuid.UUID
+	if e.ParentID != nil {
+		id, ok := im.ids[*e.ParentID]
+		if !ok {
+			im.conflict(e.kind, e.Name, e.ID, fmt.Sprintf("parent %s is not imported", e.ParentID))
+			return nil
+		}
+		parentID = &id
+	}
+	if e.profile != nil {
+		_, err := im.store.Principals().GetByEmail(ctx, e.profile.Email)
+		if err == nil {
+			im.conflict(e.kind, e.Name, e.ID, fmt.Sprintf("email %q is already used", e.profile.Email))
+			return nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return batch.Fail("500", "Error getting principal", err)
+		}
+	}
+
+	id, tenantID := uuid.New(), im.tenantID
+	im.add(e.ID, id, func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Resources().Create(ctx, &dto.TenantResource{
+			ResourceID:       id,
+			ParentResourceID: parentID,
+			ResourceTypeID:   resourceType.ResourceTypeID,
+			Name:             e.Name,
+			TenantID:         &tenantID,
+			RowStatus:        1,
+			CreatedBy:        im.userID,
+			UpdatedBy:        im.userID,
+		}); err != nil {
+			return batch.Fail("500", "Error creating resource", err)
+		}
+		if len(e.Metadata) > 0 {
+			if err := tx.Metadata().Create(ctx, &dto.TenantMetadata{ResourceID: id, Metadata: dto.JSON(e.Metadata), RowStatus: 1, CreatedBy: im.userID, UpdatedBy: im.userID}); err != nil {
+				return batch.Fail("500", "Error creating metadata", err)
+			}
+		}
+		if e.profile != nil {
+			if err := tx.Principals().Create(ctx, &dto.TenantPrincipals{
+				ResourceID:      id,
+				PrincipalTypeID: resourceType.ResourceTypeID,
+				Name:            e.profile.Name,
+				Email:           e.profile.Email,
+				Metadata:        dto.JSON(e.profile.Metadata),
+				RowStatus:       1,
+				CreatedBy:       im.userID,
+				UpdatedBy:       im.userID,
+			}); err != nil {
+				return batch.Fail("500", "Error creating principal", err)
+			}
+		}
+		return nil
+	}, instanceStep(resourceType.ResourceTypeID, id, tenantID))
+	return nil
+}
+
+// instanceStep creates resource instance id of a type in Permit.
+func instanceStep(resourceTypeID, id, tenantID uuid.UUID) batch.Step {
+	return batch.Step{
+		Phase: phaseObjects,
+		Push: &permit.Request{Method: "POST", Endpoint: "resource_instances", Payload: map[string]interface{}{
+			"key":      id,
+			"resource": resourceTypeID,
+			"tenant":   tenantID,
+		}},
+		Undo: &permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("resource_instances/%s:%s", resourceTypeID, id)},
+	}
+}
+
+// prepareRole plans recreating a custom role, whose scope and permissions
+// are looked up by name.
+func (im *importer) prepareRole(ctx context.Context, role Role) error {
+	scope, err := im.resourceType(ctx, role.AssignableScope)
+	if err != nil {
+		return err
+	}
+	if scope == nil {
+		im.conflict(models.TenantArchiveKindRole, role.Name, role.ID, fmt.Sprintf("resource type %q does not exist", role.AssignableScope))
+		return nil
+	}
+	catalog, err := im.store.Permissions().ListByResourceType(ctx, scope.ResourceTypeID)
+	if err != nil {
+		return batch.Fail("500", "Error listing permissions", err)
+	}
+	actions := make(map[string]string, len(catalog))
+	for _, permission := range catalog {
+		actions[permission.ActionKey()] = permission.PermissionID.String()
+	}
+	permissions := make([]string, 0, len(role.Permissions))
+	var missing []string
+	for _, action := range role.Permissions {
+		if id, ok := actions[action]; ok {
+			permissions = append(permissions, id)
+		} else {
+			missing = append(missing, action)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		im.conflict(models.TenantArchiveKindRole, role.Name, role.ID, fmt.Sprintf("resource type %q has no permissions %q", role.AssignableScope, missing))
+		return nil
+	}
+
+	id := uuid.New()
+	description := role.Description
+	item, create, remove := im.roles.PrepareCreateRole(ctx, models.CreateRoleInput{
+		ID:                 id,
+		Name:               role.Name,
+		Description:        &description,
+		Permissions:        permissions,
+		RoleType:           models.RoleTypeEnumCustom,
+		AssignableScopeRef: scope.ResourceTypeID,
+		Version:            role.Version,
+	}, &im.tenantID, im.userID)
+	if item.Err != nil {
+		return item.Err
+	}
+	im.add(role.ID, id, item.Apply, batch.Step{Phase: phaseObjects, Push: &create, Undo: &remove})
+	return nil
+}
+
+// prepareBinding plans recreating a binding between imported objects. Roles
+// that are not in the archive must be predefined roles of this environment.
+func (im *importer) prepareBinding(ctx context.Context, binding Binding, archivedRoles map[uuid.UUID]bool) error {
+	conflict := func(reason string, args ...interface{}) error {
+		im.conflict(models.TenantArchiveKindBinding, binding.Name, binding.ID, fmt.Sprintf(reason, args...))
+		return nil
+	}
+	principalID, ok := im.ids[binding.PrincipalID]
+	if !ok {
+		return conflict("principal %s is not imported", binding.PrincipalID)
+	}
+	scopeID, ok := im.ids[binding.ScopeID]
+	if !ok {
+		return conflict("scope %s is not imported", binding.ScopeID)
+	}
+	roleID, ok := im.ids[binding.RoleID]
+	if !ok {
+		if archivedRoles[binding.RoleID] {
+			return conflict("role %s is not imported", binding.RoleID)
+		}
+		role, err := im.store.Roles().Get(tenancy.AsRoot(ctx), binding.RoleID)
+		if errors.Is(err, repository.ErrNotFound) || (err == nil && role.RoleType == dto.RoleTypeEnumCustom) {
+			return conflict("role %s is not a predefined role", binding.RoleID)
+		}
+		if err != nil {
+			return batch.Fail("500", "Error getting role", err)
+		}
+		roleID = role.ResourceID
+	}
+	// Bindings are granted without an access request, so roles with an
+	// approval policy, here or in the exported tenant, are not bound.
+	privileged, err := repository.RequiresApproval(tenancy.AsRoot(ctx), im.store.ApprovalPolicies(), binding.RoleID)
+	if err != nil {
+		return batch.Fail("500", "Error getting approval policy", err)
+	}
+	if privileged {
+		return conflict("role %s is only granted through approved access requests", binding.RoleID)
+	}
+
+	id, tenantID := uuid.New(), im.tenantID
+	assignment := permit.RoleAssignment{User: principalID.String(), Role: roleID.String(), Tenant: tenantID.String()}
+	im.add(binding.ID, id, func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Assignments().Create(ctx, &dto.TenantRoleAssignments{
+			ResourceID:  id,
+			Name:        binding.Name,
+			Version:     binding.Version,
+			PrincipalID: principalID,
+			RoleID:      roleID,
+			TenantID:    &tenantID,
+			ScopeID:     &scopeID,
+			RowStatus:   1,
+			CreatedBy:   im.userID,
+			UpdatedBy:   im.userID,
+		}); err != nil {
+			return batch.Fail("500", "Error creating binding", err)
+		}
+		return nil
+	}, batch.Step{
+		Phase: phaseBindings,
+		Push:  &permit.Request{Method: "POST", Endpoint: "role_assignments", Payload: assignment},
+		Undo:  &permit.Request{Method: "DELETE", Endpoint: "role_assignments", Payload: assignment},
+	})
+	return nil
+}
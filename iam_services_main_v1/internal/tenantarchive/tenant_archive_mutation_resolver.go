@@ -0,0 +1,66 @@
+package tenantarchive
+
+import (
+	"context"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+)
+
+// TenantArchiveMutationResolver imports tenants.
+type TenantArchiveMutationResolver struct {
+	Store repository.Store
+	// PC is the Permit client; when nil one is configured from the environment.
+	PC *permit.PermitClient
+}
+
+func (r *TenantArchiveMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// ImportTenant creates a tenant from an archive. Every object is recreated
+// under a new identifier, in the database and in Permit, atomically. Objects
+// that cannot be recreated here are reported as conflicts and, with the SKIP
+// policy, left out along with the objects depending on them; with FAIL
+// nothing is imported.
+func (r *TenantArchiveMutationResolver) ImportTenant(ctx context.Context, archive string, conflictPolicy *models.TenantImportConflictPolicy) (models.OperationResult, error) {
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	parsed, err := Parse([]byte(archive))
+	if err != nil {
+		return handleError("400", "Invalid archive", err)
+	}
+
+	im := newImporter(r.Store, *userID)
+	// Every object of the archive belongs to the new tenant
+	ctx = tenancy.WithTenant(ctx, im.tenantID)
+	if err := im.prepare(ctx, parsed); err != nil {
+		return handleItemError(err)
+	}
+
+	result := &models.TenantImport{Conflicts: im.conflicts, IDMappings: []*models.TenantImportMapping{}}
+	if result.Conflicts == nil {
+		result.Conflicts = []*models.TenantImportConflict{}
+	}
+	if len(im.conflicts) > 0 && (conflictPolicy == nil || *conflictPolicy == models.TenantImportConflictPolicyFail) {
+		return utils.FormatSuccess([]models.Data{result})
+	}
+
+	atomic := models.BatchModeAtomic
+	results := batch.Run(ctx, r.Store, &atomic, im.items, batch.Phases(r.permitClient(), im.steps))
+	if _, failed := batch.Cause(results, im.steps); failed != nil {
+		return failed, nil
+	}
+	tenantID := im.tenantID
+	result.Imported, result.TenantID, result.IDMappings = true, &tenantID, im.mappings
+	return utils.FormatSuccess([]models.Data{result})
+}
@@ -0,0 +1,48 @@
+package tenantarchive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// TenantArchiveQueryResolver exports tenants.
+type TenantArchiveQueryResolver struct {
+	Store repository.Store
+}
+
+// ExportTenant returns the archive of tenant id.
+func (r *TenantArchiveQueryResolver) ExportTenant(ctx context.Context, id uuid.UUID) (models.OperationResult, error) {
+	archive, err := Export(ctx, r.Store, id)
+	if err != nil {
+		return handleItemError(err)
+	}
+	encoded, err := json.Marshal(archive)
+	if err != nil {
+		return handleError("500", "Error encoding tenant archive", err)
+	}
+	return utils.FormatSuccess([]models.Data{&models.TenantArchive{Archive: string(encoded), Version: Version}})
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
+
+// handleItemError reports a failure with its code.
+func handleItemError(err error) (models.OperationResult, error) {
+	var itemErr *batch.Error
+	if errors.As(err, &itemErr) {
+		return handleError(itemErr.Code, itemErr.Message, itemErr.Err)
+	}
+	return handleError("500", "Error reading tenant", err)
+}
@@ -0,0 +1,236 @@
+package tenantarchive
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/permit/permittest"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	store    *repository.MemoryStore
+	permit   *permittest.Server
+	pc       *permit.PermitClient
+	ctx      context.Context
+	tenantID uuid.UUID
+	ids      map[string]uuid.UUID
+}
+
+// setupTenant stores a tenant with two nested units, a user, a group, a
+// custom role and bindings of it and of a predefined role.
+func setupTenant(t *testing.T) *fixture {
+	logger.InitLogger()
+	fake := permittest.New(t)
+
+	f := &fixture{store: repository.NewMemoryStore(), permit: fake, tenantID: uuid.New(), ids: map[string]uuid.UUID{}}
+	f.pc = fake.Client()
+	types := map[string]uuid.UUID{}
+	for _, name := range []string{constants.ResourceTypeRoot, constants.ResourceTypeTenant, constants.ResourceTypeClientOrganizationUnit, constants.ResourceTypeUser, constants.ResourceTypeGroup} {
+		types[name] = uuid.New()
+		f.store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: types[name], Name: name, RowStatus: 1})
+	}
+	readID := uuid.New()
+	f.store.AddPermission(dto.MstPermission{PermissionID: readID, ResourceTypeID: types[constants.ResourceTypeTenant].String(), Name: "read", Action: "tenant.read", RowStatus: 1})
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", uuid.New().String())
+	ginCtx.Set("tenantID", f.tenantID.String())
+	f.ctx = context.WithValue(context.Background(), "GinContextKey", ginCtx)
+
+	root := tenancy.AsRoot(f.ctx)
+	create := func(name, typeName string, parentID *uuid.UUID, tenantID *uuid.UUID) uuid.UUID {
+		id := uuid.New()
+		if name == "acme" {
+			id = f.tenantID
+		}
+		require.NoError(t, f.store.Resources().Create(root, &dto.TenantResource{
+			ResourceID: id, ParentResourceID: parentID, ResourceTypeID: types[typeName], Name: name, TenantID: tenantID, RowStatus: 1,
+		}))
+		f.ids[name] = id
+		return id
+	}
+	rootID := create("root", constants.ResourceTypeRoot, nil, nil)
+	create("acme", constants.ResourceTypeTenant, &rootID, &f.tenantID)
+	require.NoError(t, f.store.Metadata().Create(root, &dto.TenantMetadata{ResourceID: f.tenantID, Metadata: dto.JSON(`{"description": "Acme"}`), RowStatus: 1}))
+	emea := create("emea", constants.ResourceTypeClientOrganizationUnit, &f.tenantID, &f.tenantID)
+	create("sales", constants.ResourceTypeClientOrganizationUnit, &emea, &f.tenantID)
+	alice := create("alice", constants.ResourceTypeUser, &f.tenantID, &f.tenantID)
+	require.NoError(t, f.store.Principals().Create(root, &dto.TenantPrincipals{ResourceID: alice, PrincipalTypeID: types[constants.ResourceTypeUser], Name: "Alice", Email: "alice@acme.test", RowStatus: 1}))
+	admins := create("admins", constants.ResourceTypeGroup, &f.tenantID, &f.tenantID)
+
+	reader := create("reader", constants.ResourceTypeTenant, &f.tenantID, &f.tenantID)
+	require.NoError(t, f.store.Roles().Create(root, &dto.TNTRole{ResourceID: reader, Name: "reader", Version: "V1", RoleType: dto.RoleTypeEnumCustom, ScopeResourceTypeID: types[constants.ResourceTypeTenant], RowStatus: 1}))
+	require.NoError(t, f.store.Roles().AddPermission(root, &dto.TNTRolePermission{ID: uuid.New(), RoleID: reader, PermissionID: readID}))
+	viewer := create("viewer", constants.ResourceTypeTenant, nil, nil)
+	require.NoError(t, f.store.Roles().Create(root, &dto.TNTRole{ResourceID: viewer, Name: "viewer", Version: "V1", RoleType: dto.RoleTypeEnumDefault, ScopeResourceTypeID: types[constants.ResourceTypeTenant], RowStatus: 1}))
+
+	sales, expiresAt := f.ids["sales"], time.Now().Add(time.Hour)
+	for _, binding := range []dto.TenantRoleAssignments{
+		{Name: "alice-reads-sales", PrincipalID: alice, RoleID: reader, ScopeID: &sales},
+		{Name: "admins-view", PrincipalID: admins, RoleID: viewer},
+		{Name: "break-glass", PrincipalID: alice, RoleID: viewer, ExpiresAt: &expiresAt},
+	} {
+		binding.ResourceID, binding.Version, binding.TenantID, binding.RowStatus = uuid.New(), "V1", &f.tenantID, 1
+		require.NoError(t, f.store.Assignments().Create(root, &binding))
+		f.ids[binding.Name] = binding.ResourceID
+	}
+	return f
+}
+
+func (f *fixture) export(t *testing.T) *Archive {
+	resolver := &TenantArchiveQueryResolver{Store: f.store}
+	result, err := resolver.ExportTenant(f.ctx, f.tenantID)
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	exported := result.(*models.SuccessResponse).Data[0].(*models.TenantArchive)
+	archive, err := Parse([]byte(exported.Archive))
+	require.NoError(t, err)
+	return archive
+}
+
+func (f *fixture) importArchive(t *testing.T, archive *Archive, policy models.TenantImportConflictPolicy) models.OperationResult {
+	encoded, err := json.Marshal(archive)
+	require.NoError(t, err)
+	resolver := &TenantArchiveMutationResolver{Store: f.store, PC: f.pc}
+	result, err := resolver.ImportTenant(f.ctx, string(encoded), &policy)
+	require.NoError(t, err)
+	return result
+}
+
+func TestExportTenant(t *testing.T) {
+	f := setupTenant(t)
+	archive := f.export(t)
+
+	assert.Equal(t, Version, archive.Version)
+	assert.Equal(t, "acme", archive.Tenant.Name)
+	assert.JSONEq(t, `{"description": "Acme"}`, string(archive.Tenant.Metadata))
+	require.Len(t, archive.Resources, 2)
+	assert.Equal(t, constants.ResourceTypeClientOrganizationUnit, archive.Resources[1].Type)
+	assert.Equal(t, f.ids["emea"], *archive.Resources[1].ParentID)
+	require.Len(t, archive.Principals, 2)
+	assert.Equal(t, "alice@acme.test", archive.Principals[0].Profile.Email)
+	assert.Nil(t, archive.Principals[1].Profile)
+	assert.Equal(t, []Role{{ID: f.ids["reader"], Name: "reader", Version: "V1", AssignableScope: constants.ResourceTypeTenant, Permissions: []string{"tenant.read"}}}, archive.Roles)
+	require.Len(t, archive.Bindings, 2)
+	assert.Equal(t, f.tenantID, archive.Bindings[1].ScopeID)
+
+	resolver := &TenantArchiveQueryResolver{Store: f.store}
+	result, err := resolver.ExportTenant(f.ctx, f.ids["emea"])
+	require.NoError(t, err)
+	assert.Equal(t, "404", result.(*models.ResponseError).ErrorCode)
+
+	_, err = Parse([]byte(`{"version": 2, "tenant": {"id": "` + f.tenantID.String() + `", "name": "acme"}}`))
+	assert.ErrorContains(t, err, "unsupported archive version 2")
+}
+
+func TestImportTenant(t *testing.T) {
+	f := setupTenant(t)
+	archive := f.export(t)
+	// The environment has no Widget resource type
+	widget := Resource{ID: uuid.New(), Type: "Widget", ParentID: &f.tenantID, Name: "widget"}
+	archive.Resources = append(archive.Resources, widget, Resource{ID: uuid.New(), Type: constants.ResourceTypeClientOrganizationUnit, ParentID: &widget.ID, Name: "under-widget"})
+
+	// Alice's email is already used, here by the exported tenant itself
+	result := f.importArchive(t, archive, models.TenantImportConflictPolicyFail)
+	failed := result.(*models.SuccessResponse).Data[0].(*models.TenantImport)
+	assert.False(t, failed.Imported)
+	assert.Nil(t, failed.TenantID)
+	reasons := map[string]string{}
+	for _, conflict := range failed.Conflicts {
+		reasons[conflict.Name] = conflict.Reason
+	}
+	assert.Equal(t, map[string]string{
+		"widget":            `resource type "Widget" does not exist`,
+		"under-widget":      "parent " + widget.ID.String() + " is not imported",
+		"alice":             `email "alice@acme.test" is already used`,
+		"alice-reads-sales": "principal " + f.ids["alice"].String() + " is not imported",
+	}, reasons)
+	assert.Empty(t, f.permit.Requests())
+
+	result = f.importArchive(t, archive, models.TenantImportConflictPolicySkip)
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	imported := result.(*models.SuccessResponse).Data[0].(*models.TenantImport)
+	assert.True(t, imported.Imported)
+	assert.Len(t, imported.Conflicts, 4)
+	ids := map[uuid.UUID]uuid.UUID{}
+	for _, mapping := range imported.IDMappings {
+		ids[mapping.SourceID] = mapping.ID
+	}
+	assert.Equal(t, *imported.TenantID, ids[f.tenantID])
+	assert.Len(t, ids, 6)
+	assert.Equal(t, "POST tenants", f.permit.Requests()[0])
+
+	ctx := tenancy.WithTenant(f.ctx, *imported.TenantID)
+	sales, err := f.store.Resources().Get(ctx, ids[f.ids["sales"]])
+	require.NoError(t, err)
+	assert.Equal(t, ids[f.ids["emea"]], *sales.ParentResourceID)
+	role, err := f.store.Roles().Get(ctx, ids[f.ids["reader"]])
+	require.NoError(t, err)
+	assert.Equal(t, "reader", role.Name)
+	bindings, err := f.store.Assignments().ListByTenant(ctx, *imported.TenantID)
+	require.NoError(t, err)
+	require.Len(t, bindings, 1)
+	assert.Equal(t, ids[f.ids["admins"]], bindings[0].PrincipalID)
+	assert.Equal(t, f.ids["viewer"], bindings[0].RoleID)
+	assert.Equal(t, *imported.TenantID, *bindings[0].ScopeID)
+}
+
+func TestImportTenantGatedRoles(t *testing.T) {
+	f := setupTenant(t)
+	f.store.AddApprovalPolicy(dto.ApprovalPolicy{PolicyID: uuid.New(), RoleID: f.ids["viewer"], RequiredApprovals: 1})
+	archive := f.export(t)
+
+	result := f.importArchive(t, archive, models.TenantImportConflictPolicySkip)
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	imported := result.(*models.SuccessResponse).Data[0].(*models.TenantImport)
+	assert.True(t, imported.Imported)
+	var reason string
+	for _, conflict := range imported.Conflicts {
+		if conflict.Name == "admins-view" {
+			reason = conflict.Reason
+		}
+	}
+	assert.Equal(t, "role "+f.ids["viewer"].String()+" is only granted through approved access requests", reason)
+	bindings, err := f.store.Assignments().ListByTenant(tenancy.WithTenant(f.ctx, *imported.TenantID), *imported.TenantID)
+	require.NoError(t, err)
+	assert.Empty(t, bindings)
+}
+
+func TestImportTenantRollsBack(t *testing.T) {
+	f := setupTenant(t)
+	archive := f.export(t)
+	archive.Principals = archive.Principals[1:]
+	f.permit.Fail("POST role_assignments")
+
+	result := f.importArchive(t, archive, models.TenantImportConflictPolicySkip)
+	require.IsType(t, &models.ResponseError{}, result)
+	assert.Equal(t, "500", result.(*models.ResponseError).ErrorCode)
+
+	// The tenant created in Permit is deleted again, and nothing is stored
+	var undone bool
+	for _, request := range f.permit.Requests() {
+		undone = undone || strings.HasPrefix(request, "DELETE tenants/")
+	}
+	assert.True(t, undone)
+	tenantType, err := f.store.Resources().GetTypeByName(f.ctx, constants.ResourceTypeTenant)
+	require.NoError(t, err)
+	tenants, err := f.store.Resources().ListByType(tenancy.AsRoot(f.ctx), tenantType.ResourceTypeID)
+	require.NoError(t, err)
+	assert.Len(t, tenants, 3)
+}
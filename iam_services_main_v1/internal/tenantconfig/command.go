@@ -0,0 +1,152 @@
+package tenantconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/tabwriter"
+)
+
+// Usage describes the tenantconfig command.
+const Usage = `usage: tenantconfig <command> [flags]
+
+commands:
+  plan   show the changes applying a document would make
+  apply  apply a document, then show the changes made
+
+flags:
+  -f file        the document to apply, or - for standard input (required)
+  -endpoint url  the GraphQL endpoint (default $IAM_ENDPOINT)
+  -tenant id     the tenant to configure (default $IAM_TENANT_ID)
+  -user id       the user making the changes (default $IAM_USER_ID)`
+
+var ErrUsage = errors.New(Usage)
+
+const applyMutation = `mutation ($document: String!, $dryRun: Boolean) {
+  applyTenantConfig(document: $document, dryRun: $dryRun) {
+    ... on SuccessResponse { data { ... on TenantConfigPlan { applied changes { action kind name id fields } } } }
+    ... on ResponseError { errorCode message systemMessage }
+  }
+}`
+
+// RunCommand runs the tenantconfig command given its arguments, sending the
+// document to the service with client and writing the plan to out.
+func RunCommand(ctx context.Context, client *http.Client, args []string, stdin io.Reader, out io.Writer) error {
+	if len(args) == 0 || (args[0] != "plan" && args[0] != "apply") {
+		return ErrUsage
+	}
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	file := flags.String("f", "", "")
+	endpoint := flags.String("endpoint", os.Getenv("IAM_ENDPOINT"), "")
+	tenant := flags.String("tenant", os.Getenv("IAM_TENANT_ID"), "")
+	user := flags.String("user", os.Getenv("IAM_USER_ID"), "")
+	if err := flags.Parse(args[1:]); err != nil || flags.NArg() > 0 {
+		return ErrUsage
+	}
+	if *file == "" || *endpoint == "" || *tenant == "" || *user == "" {
+		return ErrUsage
+	}
+
+	var document []byte
+	var err error
+	if *file == "-" {
+		document, err = io.ReadAll(stdin)
+	} else {
+		document, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     applyMutation,
+		"variables": map[string]interface{}{"document": string(document), "dryRun": args[0] == "plan"},
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, *endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Tenant-ID", *tenant)
+	request.Header.Set("userID", *user)
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	var reply struct {
+		Data struct {
+			ApplyTenantConfig struct {
+				Data          []planReply `json:"data"`
+				ErrorCode     string      `json:"errorCode"`
+				Message       string      `json:"message"`
+				SystemMessage string      `json:"systemMessage"`
+			} `json:"applyTenantConfig"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("%s: %w", response.Status, err)
+	}
+	if len(reply.Errors) > 0 {
+		return errors.New(reply.Errors[0].Message)
+	}
+	result := reply.Data.ApplyTenantConfig
+	if result.ErrorCode != "" {
+		return fmt.Errorf("%s %s: %s", result.ErrorCode, result.Message, result.SystemMessage)
+	}
+	if len(result.Data) != 1 {
+		return errors.New("the service returned no plan")
+	}
+	return writePlan(out, result.Data[0])
+}
+
+type planReply struct {
+	Applied bool `json:"applied"`
+	Changes []struct {
+		Action string   `json:"action"`
+		Kind   string   `json:"kind"`
+		Name   string   `json:"name"`
+		ID     string   `json:"id"`
+		Fields []string `json:"fields"`
+	} `json:"changes"`
+}
+
+// writePlan writes a change per line, in the order they are made.
+func writePlan(out io.Writer, plan planReply) error {
+	if len(plan.Changes) == 0 {
+		fmt.Fprintln(out, "tenant configuration is up to date")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ACTION\tKIND\tNAME\tID\tFIELDS")
+	for _, change := range plan.Changes {
+		fields := "-"
+		if len(change.Fields) > 0 {
+			fields = fmt.Sprint(change.Fields)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", change.Action, change.Kind, change.Name, change.ID, fields)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if plan.Applied {
+		fmt.Fprintf(out, "applied %d change(s)\n", len(plan.Changes))
+	} else {
+		fmt.Fprintf(out, "%d change(s) planned; run apply to make them\n", len(plan.Changes))
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package tenantconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommand(t *testing.T) {
+	var variables map[string]interface{}
+	var tenant string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Variables map[string]interface{} `json:"variables"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		variables, tenant = body.Variables, r.Header.Get("X-Tenant-ID")
+		_, _ = w.Write([]byte(`{"data": {"applyTenantConfig": {"data": [{"applied": false, "changes": [
+			{"action": "CREATE", "kind": "GROUP", "name": "admins", "id": "8f8ad5b4-7f65-4a3c-9d2b-0b7f3e7c6a10", "fields": []}
+		]}]}}}`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	args := []string{"plan", "-f", "-", "-endpoint", srv.URL, "-tenant", "acme", "-user", "ci"}
+	require.NoError(t, RunCommand(context.Background(), srv.Client(), args, strings.NewReader("version: 1\n"), &out))
+	assert.Equal(t, map[string]interface{}{"document": "version: 1\n", "dryRun": true}, variables)
+	assert.Equal(t, "acme", tenant)
+	assert.Contains(t, out.String(), "CREATE  GROUP  admins")
+	assert.Contains(t, out.String(), "1 change(s) planned")
+
+	assert.ErrorIs(t, RunCommand(context.Background(), srv.Client(), []string{"apply", "-endpoint", srv.URL}, nil, &out), ErrUsage)
+	assert.ErrorIs(t, RunCommand(context.Background(), srv.Client(), []string{"destroy"}, nil, &out), ErrUsage)
+}
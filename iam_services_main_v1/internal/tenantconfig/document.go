@@ -0,0 +1,208 @@
+// Package tenantconfig manages a tenant's client organization units, groups,
+// custom roles and bindings declaratively. A document describes the desired
+// configuration; the tenant's current configuration is compared with it into a
+// plan of changes, which is applied atomically.
+package tenantconfig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the document format version Parse accepts.
+const Version = 1
+
+// maxNameLength is the size of the name column of tnt_resources.
+const maxNameLength = 45
+
+// Document describes the configuration of a tenant. Objects are identified by
+// their name within their kind, and the tenant's objects a document does not
+// list are deleted.
+type Document struct {
+	Version int `yaml:"version"`
+	// Tenant, when set, must be the tenant the document is applied to.
+	Tenant                  string                   `yaml:"tenant,omitempty"`
+	ClientOrganizationUnits []ClientOrganizationUnit `yaml:"clientOrganizationUnits,omitempty"`
+	Groups                  []Group                  `yaml:"groups,omitempty"`
+	Roles                   []Role                   `yaml:"roles,omitempty"`
+	Bindings                []Binding                `yaml:"bindings,omitempty"`
+}
+
+// ClientOrganizationUnit describes a client organization unit.
+type ClientOrganizationUnit struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// Parent names the unit's parent unit. Units without one belong directly
+	// to the tenant.
+	Parent string `yaml:"parent,omitempty"`
+}
+
+// Group describes a group.
+type Group struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+}
+
+// Role describes a custom role.
+type Role struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// AssignableScope names the resource type the role can be assigned on.
+	// It cannot change once the role exists.
+	AssignableScope string `yaml:"assignableScope"`
+	// Permissions lists the actions, or permission IDs, the role grants on
+	// its assignable scope.
+	Permissions []string `yaml:"permissions,omitempty"`
+}
+
+// Binding describes the binding of a role to a user or to a group of the
+// document.
+type Binding struct {
+	Name string `yaml:"name"`
+	// User is the ID of the user granted the role.
+	User string `yaml:"user,omitempty"`
+	// Group names the group granted the role.
+	Group string `yaml:"group,omitempty"`
+	// Role names a role of the document or is the ID of a predefined role.
+	Role string `yaml:"role"`
+	// Scope names the client organization unit the binding applies to. The
+	// binding applies to the whole tenant when it is empty.
+	Scope string `yaml:"scope,omitempty"`
+}
+
+// Parse reads a YAML or JSON document and checks that it is consistent.
+// Unknown fields are rejected so that misspelt ones are not silently ignored.
+func Parse(data []byte) (*Document, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var document Document
+	if err := decoder.Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("document is empty")
+		}
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	if err := document.validate(); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// validate reports every inconsistency of the document at once.
+func (d *Document) validate() error {
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if d.Version != Version {
+		fail("unsupported version %d, expected %d", d.Version, Version)
+	}
+	if d.Tenant != "" {
+		if _, err := uuid.Parse(d.Tenant); err != nil {
+			fail("tenant: invalid ID %q", d.Tenant)
+		}
+	}
+
+	units := names(len(d.ClientOrganizationUnits), func(i int) string { return d.ClientOrganizationUnits[i].Name }, "clientOrganizationUnits", fail)
+	groups := names(len(d.Groups), func(i int) string { return d.Groups[i].Name }, "groups", fail)
+	roles := names(len(d.Roles), func(i int) string { return d.Roles[i].Name }, "roles", fail)
+	names(len(d.Bindings), func(i int) string { return d.Bindings[i].Name }, "bindings", fail)
+
+	for i, unit := range d.ClientOrganizationUnits {
+		if unit.Parent != "" && !units[unit.Parent] {
+			fail("clientOrganizationUnits[%d]: parent %q is not a client organization unit of the document", i, unit.Parent)
+		}
+	}
+	if cycle := d.parentCycle(); cycle != "" {
+		fail("clientOrganizationUnits: %q is its own ancestor", cycle)
+	}
+	for i, role := range d.Roles {
+		if role.AssignableScope == "" {
+			fail("roles[%d]: assignableScope is required", i)
+		}
+	}
+	for i, binding := range d.Bindings {
+		switch {
+		case (binding.User == "") == (binding.Group == ""):
+			fail("bindings[%d]: exactly one of user and group is required", i)
+		case binding.User != "":
+			if _, err := uuid.Parse(binding.User); err != nil {
+				fail("bindings[%d]: invalid user ID %q", i, binding.User)
+			}
+		case !groups[binding.Group]:
+			fail("bindings[%d]: group %q is not a group of the document", i, binding.Group)
+		}
+		if !roles[binding.Role] {
+			if _, err := uuid.Parse(binding.Role); err != nil {
+				fail("bindings[%d]: role %q is neither a role of the document nor a role ID", i, binding.Role)
+			}
+		}
+		if binding.Scope != "" && !units[binding.Scope] {
+			fail("bindings[%d]: scope %q is not a client organization unit of the document", i, binding.Scope)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// names checks the names of the n objects of a kind and returns the set of
+// them.
+func names(n int, name func(int) string, kind string, fail func(string, ...interface{})) map[string]bool {
+	set := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		switch name := name(i); {
+		case name == "":
+			fail("%s[%d]: name is required", kind, i)
+		case len(name) > maxNameLength:
+			fail("%s[%d]: name %q is longer than %d characters", kind, i, name, maxNameLength)
+		case set[name]:
+			fail("%s[%d]: name %q is used more than once", kind, i, name)
+		default:
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// parentCycle returns the name of a client organization unit that is its own
+// ancestor, if any.
+func (d *Document) parentCycle() string {
+	parents := make(map[string]string, len(d.ClientOrganizationUnits))
+	for _, unit := range d.ClientOrganizationUnits {
+		parents[unit.Name] = unit.Parent
+	}
+	for _, unit := range d.ClientOrganizationUnits {
+		name := unit.Parent
+		for steps := 0; name != "" && steps <= len(parents); steps++ {
+			if name == unit.Name {
+				return unit.Name
+			}
+			name = parents[name]
+		}
+	}
+	return ""
+}
+
+// unitsByDepth returns the client organization units with every parent
+// before its children. The document must be valid.
+func (d *Document) unitsByDepth() []ClientOrganizationUnit {
+	parents := make(map[string]string, len(d.ClientOrganizationUnits))
+	for _, unit := range d.ClientOrganizationUnits {
+		parents[unit.Name] = unit.Parent
+	}
+	depths := make(map[string]int, len(parents))
+	for _, unit := range d.ClientOrganizationUnits {
+		for parent := unit.Parent; parent != ""; parent = parents[parent] {
+			depths[unit.Name]++
+		}
+	}
+	units := append([]ClientOrganizationUnit(nil), d.ClientOrganizationUnits...)
+	sort.SliceStable(units, func(i, j int) bool { return depths[units[i].Name] < depths[units[j].Name] })
+	return units
+}
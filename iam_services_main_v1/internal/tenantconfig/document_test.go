@@ -0,0 +1,70 @@
+package tenantconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	document, err := Parse([]byte(`
+version: 1
+clientOrganizationUnits:
+  - name: sales
+    parent: emea
+  - name: emea
+groups:
+  - name: admins
+roles:
+  - name: reader
+    assignableScope: Tenant
+    permissions: [tenant.read]
+bindings:
+  - name: admins-read
+    group: admins
+    role: reader
+    scope: sales
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"emea", "sales"}, []string{document.unitsByDepth()[0].Name, document.unitsByDepth()[1].Name})
+
+	// JSON documents are YAML documents too
+	document, err = Parse([]byte(`{"version": 1, "groups": [{"name": "admins", "description": "Administrators"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Administrators", document.Groups[0].Description)
+
+	_, err = Parse(nil)
+	assert.EqualError(t, err, "document is empty")
+	_, err = Parse([]byte("version: 1\ngroup: []\n"))
+	assert.ErrorContains(t, err, "field group not found")
+
+	_, err = Parse([]byte(`
+version: 2
+tenant: acme
+clientOrganizationUnits:
+  - name: a
+    parent: b
+  - name: b
+    parent: a
+groups:
+  - name: admins
+  - name: admins
+bindings:
+  - name: both
+    user: 5e0e4f6a-3c1b-4d59-9a2e-6f1f1b6f0c11
+    group: admins
+    role: missing
+`))
+	require.Error(t, err)
+	for _, message := range []string{
+		"unsupported version 2",
+		`tenant: invalid ID "acme"`,
+		`"a" is its own ancestor`,
+		`groups[1]: name "admins" is used more than once`,
+		"bindings[0]: exactly one of user and group is required",
+		`bindings[0]: role "missing" is neither a role of the document nor a role ID`,
+	} {
+		assert.ErrorContains(t, err, message)
+	}
+}
@@ -0,0 +1,612 @@
+package tenantconfig
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/roles"
+	"iam_services_main_v1/internal/utils"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Phases order the Permit requests of a plan: role assignments are removed
+// before anything else changes and made once the roles and groups they refer
+// to exist, and objects are deleted last.
+const (
+	phaseUnbind = iota
+	phaseWrite
+	phaseBind
+	phaseDelete
+)
+
+// version is the version of the roles and bindings a plan creates.
+const version = "V1"
+
+// plan is the list of changes turning a tenant's configuration into a
+// document's, together with the operations making them.
+type plan struct {
+	changes    []*models.TenantConfigChange
+	operations []operation
+}
+
+// operation writes a change, or part of one, to the database and makes the
+// same change in Permit with the requests of step.
+type operation struct {
+	change int
+	item   batch.Item
+	step   batch.Step
+}
+
+// current is the configuration of a tenant as stored, by name.
+type current struct {
+	units    map[string]dto.TenantResource
+	groups   map[string]dto.TenantResource
+	roles    map[string]dto.TNTRole
+	bindings map[string]dto.TenantRoleAssignments
+	// metadata holds the metadata documents of the units and groups having one.
+	metadata map[uuid.UUID]map[string]interface{}
+}
+
+// resourceEntry is a client organization unit or group of a document.
+type resourceEntry struct {
+	name, description, parent string
+}
+
+// planner computes the plan applying a document to a tenant.
+type planner struct {
+	store    repository.Store
+	roles    *roles.RoleMutationResolver
+	tenantID uuid.UUID
+	userID   uuid.UUID
+	types    map[string]dto.Mst_ResourceTypes
+	current  current
+	plan
+}
+
+// newPlanner loads the resource types a plan refers to and the tenant's
+// current configuration.
+func newPlanner(ctx context.Context, store repository.Store, tenantID, userID uuid.UUID) (*planner, error) {
+	p := &planner{
+		store:    store,
+		roles:    &roles.RoleMutationResolver{Store: store},
+		tenantID: tenantID,
+		userID:   userID,
+		types:    map[string]dto.Mst_ResourceTypes{},
+	}
+	for _, name := range []string{constants.ResourceTypeClientOrganizationUnit, constants.ResourceTypeGroup, constants.ResourceTypeUser} {
+		resourceType, err := store.Resources().GetTypeByName(ctx, name)
+		if err != nil {
+			return nil, batch.Fail("500", "Error getting resource type", fmt.Errorf("resource type %s: %w", name, err))
+		}
+		p.types[name] = *resourceType
+	}
+	if err := p.load(ctx); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// load reads the tenant's client organization units, groups, custom roles and
+// bindings. Bindings with an expiry, such as break-glass grants, are not part
+// of the configuration.
+func (p *planner) load(ctx context.Context) error {
+	p.current = current{
+		units:    map[string]dto.TenantResource{},
+		groups:   map[string]dto.TenantResource{},
+		roles:    map[string]dto.TNTRole{},
+		bindings: map[string]dto.TenantRoleAssignments{},
+		metadata: map[uuid.UUID]map[string]interface{}{},
+	}
+	for typeName, byName := range map[string]map[string]dto.TenantResource{
+		constants.ResourceTypeClientOrganizationUnit: p.current.units,
+		constants.ResourceTypeGroup:                  p.current.groups,
+	} {
+		resources, err := p.store.Resources().ListByType(ctx, p.types[typeName].ResourceTypeID)
+		if err != nil {
+			return batch.Fail("500", "Error listing resources", err)
+		}
+		for _, resource := range resources {
+			if _, ok := byName[resource.Name]; ok {
+				return ambiguous(typeName, resource.Name)
+			}
+			byName[resource.Name] = resource
+			if err := p.loadMetadata(ctx, resource.ResourceID); err != nil {
+				return err
+			}
+		}
+	}
+
+	tenantRoles, err := p.store.Roles().List(ctx)
+	if err != nil {
+		return batch.Fail("500", "Error listing roles", err)
+	}
+	for _, role := range tenantRoles {
+		if role.RoleType != dto.RoleTypeEnumCustom {
+			continue
+		}
+		if _, ok := p.current.roles[role.Name]; ok {
+			return ambiguous(constants.ResourceTypeRole, role.Name)
+		}
+		p.current.roles[role.Name] = role
+	}
+
+	bindings, err := p.store.Assignments().ListByTenant(ctx, p.tenantID)
+	if err != nil {
+		return batch.Fail("500", "Error listing bindings", err)
+	}
+	for _, binding := range bindings {
+		if binding.ExpiresAt != nil {
+			continue
+		}
+		if _, ok := p.current.bindings[binding.Name]; ok {
+			return ambiguous("Binding", binding.Name)
+		}
+		p.current.bindings[binding.Name] = binding
+	}
+	return nil
+}
+
+// ambiguous reports a name the tenant uses for several objects of a kind,
+// which a document cannot tell apart.
+func ambiguous(kind, name string) error {
+	return batch.Fail("409", "Tenant configuration is ambiguous", fmt.Errorf("several %s resources are named %q", kind, name))
+}
+
+func (p *planner) loadMetadata(ctx context.Context, resourceID uuid.UUID) error {
+	metadata, err := p.store.Metadata().Get(ctx, resourceID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return batch.Fail("500", "Error getting metadata", err)
+	}
+	document := map[string]interface{}{}
+	if len(metadata.Metadata) > 0 {
+		if err := json.Unmarshal(metadata.Metadata, &document); err != nil {
+			return batch.Fail("500", "Error reading metadata", err)
+		}
+	}
+	p.current.metadata[resourceID] = document
+	return nil
+}
+
+// compute plans the changes applying document. Creations and updates come
+// first, parents before their children, followed by deletions.
+func (p *planner) compute(ctx context.Context, document *Document) error {
+	units := make([]resourceEntry, 0, len(document.ClientOrganizationUnits))
+	for _, unit := range document.unitsByDepth() {
+		units = append(units, resourceEntry{name: unit.Name, description: unit.Description, parent: unit.Parent})
+	}
+	unitIDs := p.planResources(units, p.current.units, models.TenantConfigKindClientOrganizationUnit, constants.ResourceTypeClientOrganizationUnit)
+
+	groups := make([]resourceEntry, 0, len(document.Groups))
+	for _, group := range document.Groups {
+		groups = append(groups, resourceEntry{name: group.Name, description: group.Description})
+	}
+	groupIDs := p.planResources(groups, p.current.groups, models.TenantConfigKindGroup, constants.ResourceTypeGroup)
+
+	roleIDs := make(map[string]uuid.UUID, len(document.Roles))
+	for _, role := range document.Roles {
+		id, err := p.planRole(ctx, role)
+		if err != nil {
+			return err
+		}
+		roleIDs[role.Name] = id
+	}
+
+	listed := make(map[string]bool, len(document.Bindings))
+	for _, binding := range document.Bindings {
+		listed[binding.Name] = true
+		if err := p.planBinding(ctx, binding, unitIDs, groupIDs, roleIDs); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sortedNames(p.current.bindings) {
+		if !listed[name] {
+			binding := p.current.bindings[name]
+			change := p.change(models.TenantConfigActionDelete, models.TenantConfigKindBinding, name, binding.ResourceID, nil)
+			p.unbind(change, binding)
+		}
+	}
+	for _, name := range sortedNames(p.current.roles) {
+		if _, ok := roleIDs[name]; ok {
+			continue
+		}
+		role := p.current.roles[name]
+		item, remove, restore := p.roles.PrepareDeleteRole(ctx, &role, p.userID)
+		if item.Err != nil {
+			return item.Err
+		}
+		change := p.change(models.TenantConfigActionDelete, models.TenantConfigKindRole, name, role.ResourceID, nil)
+		p.add(change, phaseDelete, item, &remove, &restore)
+	}
+	for _, name := range sortedNames(p.current.groups) {
+		if _, ok := groupIDs[name]; !ok {
+			p.deleteResource(p.current.groups[name], models.TenantConfigKindGroup)
+		}
+	}
+	for _, name := range sortedNames(p.current.units) {
+		if _, ok := unitIDs[name]; !ok {
+			p.deleteResource(p.current.units[name], models.TenantConfigKindClientOrganizationUnit)
+		}
+	}
+	return nil
+}
+
+// change records a change of the plan and returns its position.
+func (p *planner) change(action models.TenantConfigAction, kind models.TenantConfigKind, name string, id uuid.UUID, fields []string) int {
+	if fields == nil {
+		fields = []string{}
+	}
+	p.changes = append(p.changes, &models.TenantConfigChange{Action: action, Kind: kind, Name: name, ID: id, Fields: fields})
+	return len(p.changes) - 1
+}
+
+// add appends an operation making change.
+func (p *planner) add(change, phase int, item batch.Item, push, undo *permit.Request) {
+	// The plan is the result of the whole document
+	item.Result = func(ctx context.Context) (models.OperationResult, error) { return nil, nil }
+	p.operations = append(p.operations, operation{change: change, item: item, step: batch.Step{Phase: phase, Push: push, Undo: undo}})
+}
+
+// planResources plans the client organization units or groups of a
+// document, listed parents first, and returns their IDs by name.
+func (p *planner) planResources(entries []resourceEntry, existing map[string]dto.TenantResource, kind models.TenantConfigKind, typeName string) map[string]uuid.UUID {
+	ids := make(map[string]uuid.UUID, len(entries))
+	for _, entry := range entries {
+		parentID := p.tenantID
+		if entry.parent != "" {
+			parentID = ids[entry.parent]
+		}
+
+		resource, ok := existing[entry.name]
+		if !ok {
+			ids[entry.name] = uuid.New()
+			p.createResource(ids[entry.name], parentID, entry, kind, typeName)
+			continue
+		}
+		ids[entry.name] = resource.ResourceID
+
+		var fields []string
+		if description, _ := p.current.metadata[resource.ResourceID]["description"].(string); description != entry.description {
+			fields = append(fields, "description")
+		}
+		if resource.ParentResourceID == nil || *resource.ParentResourceID != parentID {
+			fields = append(fields, "parent")
+		}
+		if len(fields) > 0 {
+			p.updateResource(resource, parentID, entry.description, kind, fields)
+		}
+	}
+	return ids
+}
+
+func (p *planner) createResource(id, parentID uuid.UUID, entry resourceEntry, kind models.TenantConfigKind, typeName string) {
+	resourceTypeID := p.types[typeName].ResourceTypeID
+	tenantID := p.tenantID
+	metadata := withDescription(nil, entry.description)
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Resources().Create(ctx, &dto.TenantResource{
+			ResourceID:       id,
+			ParentResourceID: &parentID,
+			ResourceTypeID:   resourceTypeID,
+			Name:             entry.name,
+			TenantID:         &tenantID,
+			RowStatus:        1,
+			CreatedBy:        p.userID,
+			UpdatedBy:        p.userID,
+		}); err != nil {
+			return batch.Fail("500", "Error creating resource", err)
+		}
+		if err := tx.Metadata().Create(ctx, &dto.TenantMetadata{ResourceID: id, Metadata: metadata, RowStatus: 1, CreatedBy: p.userID, UpdatedBy: p.userID}); err != nil {
+			return batch.Fail("500", "Error creating metadata", err)
+		}
+		return nil
+	}}
+	create := permit.Request{Method: "POST", Endpoint: "resource_instances", Payload: map[string]interface{}{
+		"key":      id,
+		"resource": resourceTypeID,
+		"tenant":   tenantID,
+	}}
+	remove := permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("resource_instances/%s:%s", resourceTypeID, id)}
+	change := p.change(models.TenantConfigActionCreate, kind, entry.name, id, nil)
+	p.add(change, phaseWrite, item, &create, &remove)
+}
+
+// updateResource plans changing the parent and description of a unit or
+// group, which Permit does not hold.
+func (p *planner) updateResource(resource dto.TenantResource, parentID uuid.UUID, description string, kind models.TenantConfigKind, fields []string) {
+	existing, hasMetadata := p.current.metadata[resource.ResourceID]
+	metadata := withDescription(existing, description)
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Resources().Update(ctx, resource.ResourceID, map[string]interface{}{
+			"parent_resource_id": &parentID,
+			"updated_by":         p.userID,
+			"updated_at":         time.Now(),
+		}); err != nil {
+			return batch.Fail("500", "Error updating resource", err)
+		}
+		var err error
+		if hasMetadata {
+			err = tx.Metadata().Update(ctx, resource.ResourceID, metadata)
+		} else {
+			err = tx.Metadata().Create(ctx, &dto.TenantMetadata{ResourceID: resource.ResourceID, Metadata: metadata, RowStatus: 1, CreatedBy: p.userID, UpdatedBy: p.userID})
+		}
+		if err != nil {
+			return batch.Fail("500", "Error updating metadata", err)
+		}
+		return nil
+	}}
+	change := p.change(models.TenantConfigActionUpdate, kind, resource.Name, resource.ResourceID, fields)
+	p.add(change, phaseWrite, item, nil, nil)
+}
+
+func (p *planner) deleteResource(resource dto.TenantResource, kind models.TenantConfigKind) {
+	deleted := utils.UpdateDeletedMap()
+	deleted["updated_by"] = p.userID
+	deleted["updated_at"] = time.Now()
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Metadata().Delete(ctx, resource.ResourceID); err != nil {
+			return batch.Fail("500", "Error deleting metadata", err)
+		}
+		if err := tx.Resources().Update(ctx, resource.ResourceID, deleted); err != nil {
+			return batch.Fail("500", "Error deleting resource", err)
+		}
+		return nil
+	}}
+	remove := permit.Request{Method: "DELETE", Endpoint: fmt.Sprintf("resource_instances/%s:%s", resource.ResourceTypeID, resource.ResourceID)}
+	create := permit.Request{Method: "POST", Endpoint: "resource_instances", Payload: map[string]interface{}{
+		"key":      resource.ResourceID,
+		"resource": resource.ResourceTypeID,
+		"tenant":   resource.TenantID,
+	}}
+	change := p.change(models.TenantConfigActionDelete, kind, resource.Name, resource.ResourceID, nil)
+	p.add(change, phaseDelete, item, &remove, &create)
+}
+
+// planRole plans the custom role of a document and returns its ID.
+func (p *planner) planRole(ctx context.Context, role Role) (uuid.UUID, error) {
+	scope, err := p.store.Resources().GetTypeByName(ctx, role.AssignableScope)
+	if err != nil {
+		return uuid.Nil, batch.Fail("404", "Assignable scope not found", fmt.Errorf("role %q: resource type %q: %w", role.Name, role.AssignableScope, err))
+	}
+	permissions, err := p.permissionIDs(ctx, role, scope.ResourceTypeID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	description := role.Description
+
+	existing, ok := p.current.roles[role.Name]
+	if !ok {
+		id := uuid.New()
+		item, create, remove := p.roles.PrepareCreateRole(ctx, models.CreateRoleInput{
+			ID:                 id,
+			Name:               role.Name,
+			Description:        &description,
+			Permissions:        permissions,
+			RoleType:           models.RoleTypeEnumCustom,
+			AssignableScopeRef: scope.ResourceTypeID,
+			Version:            version,
+		}, &p.tenantID, p.userID)
+		if item.Err != nil {
+			return uuid.Nil, item.Err
+		}
+		change := p.change(models.TenantConfigActionCreate, models.TenantConfigKindRole, role.Name, id, nil)
+		p.add(change, phaseWrite, item, &create, &remove)
+		return id, nil
+	}
+
+	if existing.ScopeResourceTypeID != scope.ResourceTypeID {
+		return uuid.Nil, batch.Fail("400", "Invalid role", fmt.Errorf("the assignable scope of role %q cannot change; rename the role to replace it", role.Name))
+	}
+	grants, err := p.store.Roles().Permissions(ctx, existing.ResourceID)
+	if err != nil {
+		return uuid.Nil, batch.Fail("500", "Error reading role permissions", err)
+	}
+	granted := make([]string, len(grants))
+	for i, grant := range grants {
+		granted[i] = grant.PermissionID.String()
+	}
+	sort.Strings(granted)
+
+	var fields []string
+	if existing.Description != description {
+		fields = append(fields, "description")
+	}
+	if !slices.Equal(granted, permissions) {
+		fields = append(fields, "permissions")
+	}
+	if len(fields) == 0 {
+		return existing.ResourceID, nil
+	}
+	item, update, restore := p.roles.PrepareUpdateRole(ctx, &existing, models.UpdateRoleInput{
+		ID:                 existing.ResourceID,
+		Name:               existing.Name,
+		Description:        &description,
+		Permissions:        permissions,
+		RoleType:           models.RoleTypeEnumCustom,
+		AssignableScopeRef: existing.ScopeResourceTypeID,
+		Version:            existing.Version,
+	}, nil, p.userID)
+	if item.Err != nil {
+		return uuid.Nil, item.Err
+	}
+	change := p.change(models.TenantConfigActionUpdate, models.TenantConfigKindRole, role.Name, existing.ResourceID, fields)
+	p.add(change, phaseWrite, item, &update, &restore)
+	return existing.ResourceID, nil
+}
+
+// permissionIDs resolves the permissions of role, given as actions or IDs of
+// the scope's resource type, to sorted permission IDs.
+func (p *planner) permissionIDs(ctx context.Context, role Role, scopeTypeID uuid.UUID) ([]string, error) {
+	catalog, err := p.store.Permissions().ListByResourceType(ctx, scopeTypeID)
+	if err != nil {
+		return nil, batch.Fail("500", "Error listing permissions", err)
+	}
+	ids := make([]string, 0, len(role.Permissions))
+	seen := make(map[string]bool, len(role.Permissions))
+	for _, entry := range role.Permissions {
+		id := ""
+		for _, permission := range catalog {
+			if permission.PermissionID.String() == entry || permission.ActionKey() == entry {
+				id = permission.PermissionID.String()
+				break
+			}
+		}
+		if id == "" {
+			return nil, batch.Fail("400", "Invalid permissions", fmt.Errorf("role %q: %q is not a permission of resource type %s", role.Name, entry, role.AssignableScope))
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// planBinding plans a binding of a document. Bindings cannot be changed in
+// place, so a changed binding is replaced.
+func (p *planner) planBinding(ctx context.Context, binding Binding, unitIDs, groupIDs, roleIDs map[string]uuid.UUID) error {
+	principalID := groupIDs[binding.Group]
+	if binding.User != "" {
+		principalID = uuid.MustParse(binding.User)
+		user, err := p.store.Resources().Get(ctx, principalID)
+		if err != nil || user.ResourceTypeID != p.types[constants.ResourceTypeUser].ResourceTypeID {
+			return batch.Fail("404", "User not found", fmt.Errorf("binding %q: user %s is not a user of the tenant", binding.Name, binding.User))
+		}
+	}
+	roleID, ok := roleIDs[binding.Role]
+	if !ok {
+		roleID = uuid.MustParse(binding.Role)
+		if _, err := p.store.Roles().Get(ctx, roleID); err != nil {
+			return batch.Fail("404", "Role not found", fmt.Errorf("binding %q: role %s: %w", binding.Name, binding.Role, err))
+		}
+	}
+	scopeID := p.tenantID
+	if binding.Scope != "" {
+		scopeID = unitIDs[binding.Scope]
+	}
+
+	existing, ok := p.current.bindings[binding.Name]
+	if !ok {
+		if err := p.grantable(ctx, binding, roleID); err != nil {
+			return err
+		}
+		id := uuid.New()
+		change := p.change(models.TenantConfigActionCreate, models.TenantConfigKindBinding, binding.Name, id, nil)
+		p.bind(change, id, binding.Name, principalID, roleID, scopeID)
+		return nil
+	}
+
+	var fields []string
+	if existing.PrincipalID != principalID {
+		fields = append(fields, "principal")
+	}
+	if existing.RoleID != roleID {
+		fields = append(fields, "role")
+	}
+	if existing.ScopeID == nil || *existing.ScopeID != scopeID {
+		fields = append(fields, "scope")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := p.grantable(ctx, binding, roleID); err != nil {
+		return err
+	}
+	id := uuid.New()
+	change := p.change(models.TenantConfigActionUpdate, models.TenantConfigKindBinding, binding.Name, id, fields)
+	p.unbind(change, existing)
+	p.bind(change, id, binding.Name, principalID, roleID, scopeID)
+	return nil
+}
+
+// grantable fails a binding the plan creates or replaces when its role is
+// only granted through approved access requests. Existing bindings are kept.
+func (p *planner) grantable(ctx context.Context, binding Binding, roleID uuid.UUID) error {
+	privileged, err := repository.RequiresApproval(ctx, p.store.ApprovalPolicies(), roleID)
+	if err != nil {
+		return batch.Fail("500", "Error getting approval policy", err)
+	}
+	if privileged {
+		return batch.Fail("403", "Role requires approval", fmt.Errorf("binding %q: role %s is only granted through approved access requests; request it with requestAccess", binding.Name, binding.Role))
+	}
+	return nil
+}
+
+// bind plans storing a binding and assigning its role in Permit.
+func (p *planner) bind(change int, id uuid.UUID, name string, principalID, roleID, scopeID uuid.UUID) {
+	tenantID := p.tenantID
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Assignments().Create(ctx, &dto.TenantRoleAssignments{
+			ResourceID:  id,
+			Name:        name,
+			Version:     version,
+			PrincipalID: principalID,
+			RoleID:      roleID,
+			TenantID:    &tenantID,
+			ScopeID:     &scopeID,
+			RowStatus:   1,
+			CreatedBy:   p.userID,
+			UpdatedBy:   p.userID,
+		}); err != nil {
+			return batch.Fail("500", "Error creating binding", err)
+		}
+		return nil
+	}}
+	assignment := permit.RoleAssignment{User: principalID.String(), Role: roleID.String(), Tenant: tenantID.String()}
+	assign := permit.Request{Method: "POST", Endpoint: "role_assignments", Payload: assignment}
+	unassign := permit.Request{Method: "DELETE", Endpoint: "role_assignments", Payload: assignment}
+	p.add(change, phaseBind, item, &assign, &unassign)
+}
+
+// unbind plans deleting a binding and unassigning its role in Permit.
+func (p *planner) unbind(change int, binding dto.TenantRoleAssignments) {
+	item := batch.Item{Apply: func(ctx context.Context, tx repository.Store) error {
+		if err := tx.Assignments().Delete(ctx, binding.ResourceID); err != nil {
+			return batch.Fail("500", "Error deleting binding", err)
+		}
+		return nil
+	}}
+	assignment := permit.RoleAssignment{User: binding.PrincipalID.String(), Role: binding.RoleID.String(), Tenant: p.tenantID.String()}
+	unassign := permit.Request{Method: "DELETE", Endpoint: "role_assignments", Payload: assignment}
+	assign := permit.Request{Method: "POST", Endpoint: "role_assignments", Payload: assignment}
+	p.add(change, phaseUnbind, item, &unassign, &assign)
+}
+
+// withDescription returns metadata with its description replaced.
+func withDescription(metadata map[string]interface{}, description string) dto.JSON {
+	document := make(map[string]interface{}, len(metadata)+1)
+	for key, value := range metadata {
+		document[key] = value
+	}
+	if description == "" {
+		delete(document, "description")
+	} else {
+		document["description"] = description
+	}
+	encoded, _ := json.Marshal(document)
+	return dto.JSON(encoded)
+}
+
+func sortedNames[V any](byName map[string]V) []string {
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,105 @@
+package tenantconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/batch"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// TenantConfigMutationResolver applies tenant configuration documents.
+type TenantConfigMutationResolver struct {
+	Store repository.Store
+	// PC is the Permit client; when nil one is configured from the environment.
+	PC *permit.PermitClient
+}
+
+func (r *TenantConfigMutationResolver) permitClient() *permit.PermitClient {
+	if r.PC != nil {
+		return r.PC
+	}
+	return permit.NewPermitClient()
+}
+
+// ApplyTenantConfig plans the changes bringing the request's tenant in line
+// with document and, unless dryRun is set, applies them atomically: every
+// change is made, in the database and in Permit, or none is.
+func (r *TenantConfigMutationResolver) ApplyTenantConfig(ctx context.Context, document string, dryRun *bool) (models.OperationResult, error) {
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return handleError("400", "Invalid user ID", err)
+	}
+	tenantID := tenancy.TenantFromContext(ctx)
+	if tenantID == nil {
+		return handleError("400", "Invalid tenant ID", errors.New("tenant configuration is applied to the request's tenant"))
+	}
+
+	parsed, err := Parse([]byte(document))
+	if err != nil {
+		return handleError("400", "Invalid document", err)
+	}
+	if parsed.Tenant != "" && uuid.MustParse(parsed.Tenant) != *tenantID {
+		return handleError("400", "Invalid document", fmt.Errorf("document describes tenant %s, not %s", parsed.Tenant, tenantID))
+	}
+
+	p, err := newPlanner(ctx, r.Store, *tenantID, *userID)
+	if err != nil {
+		return handleItemError(err)
+	}
+	if err := p.compute(ctx, parsed); err != nil {
+		return handleItemError(err)
+	}
+
+	result := &models.TenantConfigPlan{Changes: p.changes}
+	if dryRun == nil || !*dryRun {
+		if failed := p.apply(ctx, r.Store, r.permitClient()); failed != nil {
+			return failed, nil
+		}
+		result.Applied = true
+	}
+	return utils.FormatSuccess([]models.Data{result})
+}
+
+// apply makes the changes of the plan as an atomic batch and returns the
+// failure of the change that could not be made, if any.
+func (p *plan) apply(ctx context.Context, store repository.Store, pc *permit.PermitClient) models.OperationResult {
+	items := make([]batch.Item, len(p.operations))
+	steps := make([]batch.Step, len(p.operations))
+	for i, op := range p.operations {
+		items[i], steps[i] = op.item, op.step
+	}
+	atomic := models.BatchModeAtomic
+	results := batch.Run(ctx, store, &atomic, items, batch.Phases(pc, steps))
+
+	i, failed := batch.Cause(results, steps)
+	if failed != nil {
+		change := p.changes[p.operations[i].change]
+		failed.Message = fmt.Sprintf("%s (%s %s %q)", failed.Message, change.Action, change.Kind, change.Name)
+		return failed
+	}
+	return nil
+}
+
+func handleError(code, message string, err error) (models.OperationResult, error) {
+	em := fmt.Sprintf("%s: %v", message, err)
+	logger.LogError(em)
+	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
+}
+
+// handleItemError reports a planning failure with its code.
+func handleItemError(err error) (models.OperationResult, error) {
+	var itemErr *batch.Error
+	if errors.As(err, &itemErr) {
+		return handleError(itemErr.Code, itemErr.Message, itemErr.Err)
+	}
+	return handleError("500", "Error planning tenant configuration", err)
+}
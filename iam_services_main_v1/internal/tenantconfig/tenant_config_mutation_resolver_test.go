@@ -0,0 +1,202 @@
+package tenantconfig
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit/permittest"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	resolver *TenantConfigMutationResolver
+	permit   *permittest.Server
+	ctx      context.Context
+	tenantID uuid.UUID
+	userID   uuid.UUID
+	types    map[string]uuid.UUID
+}
+
+func setupTenantConfig(t *testing.T) *fixture {
+	logger.InitLogger()
+	fake := permittest.New(t)
+
+	f := &fixture{permit: fake, tenantID: uuid.New(), types: map[string]uuid.UUID{}}
+	store := repository.NewMemoryStore()
+	for _, name := range []string{constants.ResourceTypeTenant, constants.ResourceTypeClientOrganizationUnit, constants.ResourceTypeGroup, constants.ResourceTypeUser} {
+		f.types[name] = uuid.New()
+		store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: f.types[name], Name: name, RowStatus: 1})
+	}
+	store.AddPermission(dto.MstPermission{PermissionID: uuid.New(), ResourceTypeID: f.types[constants.ResourceTypeTenant].String(), Name: "read", Action: "tenant.read", RowStatus: 1})
+	store.AddPermission(dto.MstPermission{PermissionID: uuid.New(), ResourceTypeID: f.types[constants.ResourceTypeTenant].String(), Name: "update", Action: "tenant.update", RowStatus: 1})
+
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", uuid.New().String())
+	ginCtx.Set("tenantID", f.tenantID.String())
+	f.ctx = context.WithValue(context.Background(), "GinContextKey", ginCtx)
+
+	f.userID = uuid.New()
+	require.NoError(t, store.Resources().Create(f.ctx, &dto.TenantResource{
+		ResourceID:     f.userID,
+		ResourceTypeID: f.types[constants.ResourceTypeUser],
+		Name:           "alice",
+		TenantID:       &f.tenantID,
+		RowStatus:      1,
+	}))
+
+	pc := fake.Client()
+	f.resolver = &TenantConfigMutationResolver{Store: store, PC: pc}
+	return f
+}
+
+func (f *fixture) apply(t *testing.T, document string, dryRun bool) models.OperationResult {
+	result, err := f.resolver.ApplyTenantConfig(f.ctx, document, &dryRun)
+	require.NoError(t, err)
+	return result
+}
+
+func planOf(t *testing.T, result models.OperationResult) *models.TenantConfigPlan {
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	return result.(*models.SuccessResponse).Data[0].(*models.TenantConfigPlan)
+}
+
+func summary(plan *models.TenantConfigPlan) []string {
+	changes := make([]string, len(plan.Changes))
+	for i, change := range plan.Changes {
+		changes[i] = fmt.Sprintf("%s %s %s %v", change.Action, change.Kind, change.Name, change.Fields)
+	}
+	return changes
+}
+
+func (f *fixture) document(bindingScope, groupDescription string, units ...string) string {
+	document := "version: 1\nclientOrganizationUnits:\n"
+	for _, unit := range units {
+		document += unit + "\n"
+	}
+	return document + fmt.Sprintf(`groups:
+  - name: admins
+    description: %s
+roles:
+  - name: reader
+    assignableScope: Tenant
+    permissions: [tenant.read]
+bindings:
+  - name: admins-read
+    group: admins
+    role: reader
+  - name: alice-read
+    user: %s
+    role: reader
+    scope: %s
+`, groupDescription, f.userID, bindingScope)
+}
+
+func TestApplyTenantConfig(t *testing.T) {
+	f := setupTenantConfig(t)
+	emea, sales := "  - name: emea", "  - name: sales\n    parent: emea"
+	document := f.document("sales", "Administrators", sales, emea)
+
+	plan := planOf(t, f.apply(t, document, true))
+	assert.False(t, plan.Applied)
+	assert.Equal(t, []string{
+		"CREATE CLIENT_ORGANIZATION_UNIT emea []",
+		"CREATE CLIENT_ORGANIZATION_UNIT sales []",
+		"CREATE GROUP admins []",
+		"CREATE ROLE reader []",
+		"CREATE BINDING admins-read []",
+		"CREATE BINDING alice-read []",
+	}, summary(plan))
+	assert.Empty(t, f.permit.Take())
+	units, err := f.resolver.Store.Resources().ListByType(f.ctx, f.types[constants.ResourceTypeClientOrganizationUnit])
+	require.NoError(t, err)
+	assert.Empty(t, units)
+
+	plan = planOf(t, f.apply(t, document, false))
+	assert.True(t, plan.Applied)
+	assert.Len(t, plan.Changes, 6)
+	sent := f.permit.Take()
+	assert.Len(t, sent, 6)
+	assert.Equal(t, "POST role_assignments", sent[len(sent)-1])
+	units, err = f.resolver.Store.Resources().ListByType(f.ctx, f.types[constants.ResourceTypeClientOrganizationUnit])
+	require.NoError(t, err)
+	require.Len(t, units, 2)
+	assert.Equal(t, plan.Changes[0].ID, *units[1].ParentResourceID)
+	bindings, err := f.resolver.Store.Assignments().ListByTenant(f.ctx, f.tenantID)
+	require.NoError(t, err)
+	assert.Len(t, bindings, 2)
+
+	// Applying the same document again changes nothing
+	assert.Empty(t, planOf(t, f.apply(t, document, false)).Changes)
+	assert.Empty(t, f.permit.Take())
+
+	plan = planOf(t, f.apply(t, f.document("emea", "Tenant administrators", emea), false))
+	assert.Equal(t, []string{
+		"UPDATE GROUP admins [description]",
+		"UPDATE BINDING alice-read [scope]",
+		"DELETE CLIENT_ORGANIZATION_UNIT sales []",
+	}, summary(plan))
+	assert.Equal(t, []string{
+		"DELETE role_assignments",
+		"POST role_assignments",
+		fmt.Sprintf("DELETE resource_instances/%s:%s", f.types[constants.ResourceTypeClientOrganizationUnit], units[1].ResourceID),
+	}, f.permit.Take())
+	_, err = f.resolver.Store.Resources().Get(f.ctx, units[1].ResourceID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	metadata, err := f.resolver.Store.Metadata().Get(f.ctx, plan.Changes[0].ID)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"description": "Tenant administrators"}`, string(metadata.Metadata))
+
+	// Bindings of a role requiring approval are kept but not created again
+	roles, err := f.resolver.Store.Roles().List(f.ctx)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	f.resolver.Store.(*repository.MemoryStore).AddApprovalPolicy(dto.ApprovalPolicy{PolicyID: uuid.New(), TenantID: &f.tenantID, RoleID: roles[0].ResourceID, RequiredApprovals: 1})
+	assert.Empty(t, planOf(t, f.apply(t, f.document("emea", "Tenant administrators", emea), true)).Changes)
+	gated := f.apply(t, f.document("sales", "Tenant administrators", sales, emea), true)
+	require.IsType(t, &models.ResponseError{}, gated)
+	assert.Equal(t, "403", gated.(*models.ResponseError).ErrorCode)
+	assert.Contains(t, *gated.(*models.ResponseError).ErrorDetails, "requestAccess")
+	assert.Empty(t, f.permit.Take())
+
+	failed := f.apply(t, "version: 1\ntenant: "+uuid.NewString()+"\n", false)
+	require.IsType(t, &models.ResponseError{}, failed)
+	assert.Equal(t, "400", failed.(*models.ResponseError).ErrorCode)
+}
+
+func TestApplyTenantConfigRollsBack(t *testing.T) {
+	f := setupTenantConfig(t)
+	f.permit.Fail("POST role_assignments")
+
+	result := f.apply(t, f.document("emea", "Administrators", "  - name: emea"), false)
+	require.IsType(t, &models.ResponseError{}, result)
+	failed := result.(*models.ResponseError)
+	assert.Equal(t, "500", failed.ErrorCode)
+	assert.Contains(t, failed.Message, `(CREATE BINDING "admins-read")`)
+
+	// The unit, group and role created in Permit are deleted again
+	var undone int
+	for _, request := range f.permit.Take() {
+		if strings.HasPrefix(request, "DELETE") {
+			undone++
+		}
+	}
+	assert.GreaterOrEqual(t, undone, 3)
+	units, err := f.resolver.Store.Resources().ListByType(f.ctx, f.types[constants.ResourceTypeClientOrganizationUnit])
+	require.NoError(t, err)
+	assert.Empty(t, units)
+	roles, err := f.resolver.Store.Roles().List(f.ctx)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+}
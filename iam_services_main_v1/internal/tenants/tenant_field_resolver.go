@@ -3,13 +3,14 @@ package tenants
 import (
 	"iam_services_main_v1/gql/models"
 	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/etag"
+	"iam_services_main_v1/internal/repository"
 
 	"go.uber.org/thriftrw/ptr"
-	"gorm.io/gorm"
 )
 
 type TenantFieldResolver struct {
-	DB *gorm.DB
+	Store repository.Store
 }
 
 // buildContactInfo creates a ContactInfo model from raw contact data
@@ -62,6 +63,7 @@ func convertTenantToGraphQL(tenant *dto.TenantResource, parentOrg *dto.TenantRes
 	resp := &models.Tenant{
 		ID:        tenant.ResourceID,
 		Name:      tenant.Name,
+		Etag:      etag.Format(tenant.Revision),
 		CreatedAt: tenant.CreatedAt.String(),
 		CreatedBy: tenant.CreatedBy,
 		UpdatedAt: tenant.UpdatedAt.String(),
@@ -72,6 +74,7 @@ func convertTenantToGraphQL(tenant *dto.TenantResource, parentOrg *dto.TenantRes
 		resp.ParentOrg = &models.Root{
 			ID:        parentOrg.ResourceID,
 			Name:      parentOrg.Name,
+			Etag:      etag.Format(parentOrg.Revision),
 			CreatedAt: parentOrg.CreatedAt.String(),
 			UpdatedAt: parentOrg.UpdatedAt.String(),
 			CreatedBy: parentOrg.CreatedBy,
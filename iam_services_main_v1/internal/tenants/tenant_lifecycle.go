@@ -0,0 +1,159 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/helpers"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// maxReasonLength bounds the reason kept with a status transition.
+const maxReasonLength = 255
+
+var ErrInvalidTransition = errors.New("invalid tenant status transition")
+
+// tenantTransitions lists the statuses each status may move to. Archived
+// tenants stay archived.
+var tenantTransitions = map[string][]string{
+	dto.TenantStatusProvisioning: {dto.TenantStatusActive, dto.TenantStatusArchived},
+	dto.TenantStatusActive:       {dto.TenantStatusSuspended, dto.TenantStatusArchived},
+	dto.TenantStatusSuspended:    {dto.TenantStatusActive, dto.TenantStatusArchived},
+}
+
+// SuspendTenant resolver for suspending an active Tenant
+func (t *TenantMutationResolver) SuspendTenant(ctx context.Context, input models.TenantTransitionInput) (models.OperationResult, error) {
+	return t.transitionTenant(ctx, input, dto.TenantStatusActive, dto.TenantStatusSuspended)
+}
+
+// ReactivateTenant resolver for reactivating a suspended Tenant
+func (t *TenantMutationResolver) ReactivateTenant(ctx context.Context, input models.TenantTransitionInput) (models.OperationResult, error) {
+	return t.transitionTenant(ctx, input, dto.TenantStatusSuspended, dto.TenantStatusActive)
+}
+
+// ArchiveTenant resolver for archiving a Tenant
+func (t *TenantMutationResolver) ArchiveTenant(ctx context.Context, input models.TenantTransitionInput) (models.OperationResult, error) {
+	return t.transitionTenant(ctx, input, "", dto.TenantStatusArchived)
+}
+
+// transitionTenant moves the tenant of input to status to, when its status is
+// from or, for an empty from, any status allowed to move to it.
+func (t *TenantMutationResolver) transitionTenant(ctx context.Context, input models.TenantTransitionInput, from, to string) (models.OperationResult, error) {
+	userID, err := helpers.GetUserID(ctx)
+	if err != nil {
+		return t.handleError("400", "Invalid user ID", err)
+	}
+	var reason string
+	if input.Reason != nil {
+		reason = strings.TrimSpace(*input.Reason)
+	}
+	if len(reason) > maxReasonLength {
+		return t.handleError("400", "Invalid reason", fmt.Errorf("reason must be at most %d characters", maxReasonLength))
+	}
+
+	// Tenants are managed at Root level
+	rootCtx := tenancy.AsRoot(ctx)
+	if err := t.checkTenantExists(rootCtx, input.ID); err != nil {
+		return t.handleError("404", "Tenant not found", err)
+	}
+	state, err := repository.CurrentTenantState(rootCtx, t.Store.TenantStates(), input.ID)
+	if err != nil {
+		return t.handleError("500", "Error getting tenant status", err)
+	}
+	if (from != "" && state.Status != from) || !slices.Contains(tenantTransitions[state.Status], to) {
+		return t.handleError("409", fmt.Sprintf("Tenant is %s", state.Status), fmt.Errorf("%w from %s to %s", ErrInvalidTransition, state.Status, to))
+	}
+
+	transition := &dto.TenantStateTransition{
+		TransitionID: uuid.New(),
+		TenantID:     input.ID,
+		FromStatus:   state.Status,
+		ToStatus:     to,
+		Reason:       reason,
+		CreatedBy:    *userID,
+	}
+	err = t.Store.TenantStates().Transition(rootCtx, state.Revision, transition)
+	if errors.Is(err, repository.ErrConflict) {
+		return t.handleError(constants.ErrorCodeConflict, "Tenant status was changed concurrently", err)
+	}
+	if err != nil {
+		return t.handleError("500", "Error changing tenant status", err)
+	}
+
+	return utils.FormatSuccess([]models.Data{convertTransitionToGraphQL(transition)})
+}
+
+// recordTenantStatus records the transition of a tenant being created to
+// status to in store.
+func (t *TenantMutationResolver) recordTenantStatus(ctx context.Context, store repository.Store, tenantID, userID uuid.UUID, from, to string, revision int) error {
+	return store.TenantStates().Transition(ctx, revision, &dto.TenantStateTransition{
+		TransitionID: uuid.New(),
+		TenantID:     tenantID,
+		FromStatus:   from,
+		ToStatus:     to,
+		CreatedBy:    userID,
+	})
+}
+
+// checkTenantExists returns an error unless id is an active tenant.
+func (t *TenantMutationResolver) checkTenantExists(ctx context.Context, id uuid.UUID) error {
+	resource, err := t.Store.Resources().Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	resourceType, err := t.Store.Resources().GetTypeByName(ctx, constants.ResourceTypeTenant)
+	if err != nil {
+		return err
+	}
+	if resource.ResourceTypeID != resourceType.ResourceTypeID {
+		return fmt.Errorf("%s is not a %s", id, constants.ResourceTypeTenant)
+	}
+	return nil
+}
+
+// TenantStatusHistory retrieves the status transitions of a tenant, oldest first
+func (r *TenantQueryResolver) TenantStatusHistory(ctx context.Context, id uuid.UUID) (models.OperationResult, error) {
+	if id == uuid.Nil {
+		return r.handleError("400", "Tenant ID is required", ErrTenantIDRequired)
+	}
+	transitions, err := r.Store.TenantStates().ListTransitions(tenancy.AsRoot(ctx), id)
+	if err != nil {
+		return r.handleError("500", "Error retrieving tenant status history", err)
+	}
+
+	history := make([]models.Data, 0, len(transitions))
+	for i := range transitions {
+		history = append(history, convertTransitionToGraphQL(&transitions[i]))
+	}
+	return utils.FormatSuccess(history)
+}
+
+func convertTransitionToGraphQL(transition *dto.TenantStateTransition) *models.TenantStatusTransition {
+	result := &models.TenantStatusTransition{
+		ID:        transition.TransitionID,
+		TenantID:  transition.TenantID,
+		ToStatus:  models.TenantStatus(transition.ToStatus),
+		CreatedAt: transition.CreatedAt.Format(time.RFC3339),
+		CreatedBy: transition.CreatedBy,
+	}
+	if transition.FromStatus != "" {
+		from := models.TenantStatus(transition.FromStatus)
+		result.FromStatus = &from
+	}
+	if transition.Reason != "" {
+		reason := transition.Reason
+		result.Reason = &reason
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package tenants
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// userContext is the context of a request by userID.
+func userContext(userID uuid.UUID) context.Context {
+	ginCtx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ginCtx.Set("userID", userID.String())
+	return context.WithValue(context.Background(), "GinContextKey", ginCtx)
+}
+
+func transitionOf(t *testing.T, result models.OperationResult) *models.TenantStatusTransition {
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	return result.(*models.SuccessResponse).Data[0].(*models.TenantStatusTransition)
+}
+
+func errorCodeOf(t *testing.T, result models.OperationResult) string {
+	require.IsType(t, &models.ResponseError{}, result, "%+v", result)
+	return result.(*models.ResponseError).ErrorCode
+}
+
+func TestTenantLifecycle(t *testing.T) {
+	logger.InitLogger()
+	store := repository.NewMemoryStore()
+	tenantType := dto.Mst_ResourceTypes{ResourceTypeID: uuid.New(), Name: constants.ResourceTypeTenant}
+	store.AddResourceType(tenantType)
+	tenantID := uuid.New()
+	require.NoError(t, store.Resources().Create(tenancy.AsRoot(context.Background()), &dto.TenantResource{
+		ResourceID: tenantID, ResourceTypeID: tenantType.ResourceTypeID, Name: "acme", TenantID: &tenantID, RowStatus: 1,
+	}))
+	resolver := &TenantMutationResolver{Store: store}
+	userID := uuid.New()
+	ctx := userContext(userID)
+	transition := func(change func(context.Context, models.TenantTransitionInput) (models.OperationResult, error), reason *string) models.OperationResult {
+		result, err := change(ctx, models.TenantTransitionInput{ID: tenantID, Reason: reason})
+		require.NoError(t, err)
+		return result
+	}
+
+	// Tenants without a recorded state are active
+	assert.Equal(t, "409", errorCodeOf(t, transition(resolver.ReactivateTenant, nil)))
+	reason := " unpaid invoices "
+	suspended := transitionOf(t, transition(resolver.SuspendTenant, &reason))
+	require.NotNil(t, suspended.FromStatus)
+	assert.Equal(t, models.TenantStatusActive, *suspended.FromStatus)
+	assert.Equal(t, models.TenantStatusSuspended, suspended.ToStatus)
+	assert.Equal(t, "unpaid invoices", *suspended.Reason)
+	assert.Equal(t, userID, suspended.CreatedBy)
+	assert.Equal(t, "409", errorCodeOf(t, transition(resolver.SuspendTenant, nil)))
+
+	assert.Equal(t, models.TenantStatusActive, transitionOf(t, transition(resolver.ReactivateTenant, nil)).ToStatus)
+	assert.Equal(t, models.TenantStatusArchived, transitionOf(t, transition(resolver.ArchiveTenant, nil)).ToStatus)
+	// Archived tenants stay archived
+	assert.Equal(t, "409", errorCodeOf(t, transition(resolver.ReactivateTenant, nil)))
+	assert.Equal(t, "409", errorCodeOf(t, transition(resolver.ArchiveTenant, nil)))
+
+	query := &TenantQueryResolver{Store: store}
+	result, err := query.TenantStatusHistory(context.Background(), tenantID)
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result)
+	history := result.(*models.SuccessResponse).Data
+	require.Len(t, history, 3)
+	var statuses []models.TenantStatus
+	for _, data := range history {
+		statuses = append(statuses, data.(*models.TenantStatusTransition).ToStatus)
+	}
+	assert.Equal(t, []models.TenantStatus{models.TenantStatusSuspended, models.TenantStatusActive, models.TenantStatusArchived}, statuses)
+
+	// Only tenants have a lifecycle
+	result, err = resolver.SuspendTenant(ctx, models.TenantTransitionInput{ID: uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, "404", errorCodeOf(t, result))
+	long := strings.Repeat("x", maxReasonLength+1)
+	assert.Equal(t, "400", errorCodeOf(t, transition(resolver.SuspendTenant, &long)))
+}
+
+func TestCreateTenantRecordsStatesWithTheTenant(t *testing.T) {
+	logger.InitLogger()
+	store := repository.NewMemoryStore()
+	tenantType := dto.Mst_ResourceTypes{ResourceTypeID: uuid.New(), Name: constants.ResourceTypeTenant}
+	store.AddResourceType(tenantType)
+	rootID := uuid.New()
+	rootCtx := tenancy.AsRoot(context.Background())
+	require.NoError(t, store.Resources().Create(rootCtx, &dto.TenantResource{ResourceID: rootID, Name: "Root", RowStatus: 1}))
+
+	// The fake Permit fails creating resource instances while failInstances is
+	// set, and answers GETs with the tenant it created last
+	var mu sync.Mutex
+	var requests []string
+	var tenantKey string
+	failInstances := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path := req.URL.Path[strings.Index(req.URL.Path, "/env/")+len("/env/"):]
+		requests = append(requests, req.Method+" "+path)
+		switch {
+		case req.Method == http.MethodPost && path == "tenants":
+			var body map[string]interface{}
+			_ = json.NewDecoder(req.Body).Decode(&body)
+			tenantKey, _ = body["key"].(string)
+		case req.Method == http.MethodPost && path == "resource_instances" && failInstances:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		case req.Method == http.MethodGet:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"key": tenantKey, "name": "acme"})
+		}
+	}))
+	t.Cleanup(srv.Close)
+	resolver := &TenantMutationResolver{Store: store, PermitClient: permit.NewPermitClientWithConfig(permit.Config{
+		PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second,
+	})}
+	input := models.CreateTenantInput{ID: uuid.New(), Name: "acme", ParentID: &rootID}
+
+	result, err := resolver.CreateTenant(userContext(uuid.New()), input)
+	require.NoError(t, err)
+	assert.Equal(t, "500", errorCodeOf(t, result))
+	// Neither the database nor Permit keep a trace of the failed tenant
+	failedID := uuid.MustParse(tenantKey)
+	assert.Equal(t, []string{"POST tenants", "POST resource_instances", "DELETE tenants/" + tenantKey}, requests)
+	_, err = store.TenantStates().Get(rootCtx, failedID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	transitions, err := store.TenantStates().ListTransitions(rootCtx, failedID)
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+	_, err = store.Resources().Get(rootCtx, failedID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	mu.Lock()
+	failInstances = false
+	mu.Unlock()
+	result, err = resolver.CreateTenant(userContext(uuid.New()), input)
+	require.NoError(t, err)
+	require.IsType(t, &models.SuccessResponse{}, result, "%+v", result)
+	tenantID := uuid.MustParse(tenantKey)
+	transitions, err = store.TenantStates().ListTransitions(rootCtx, tenantID)
+	require.NoError(t, err)
+	require.Len(t, transitions, 2)
+	assert.Equal(t, dto.TenantStatusProvisioning, transitions[0].ToStatus)
+	assert.Equal(t, dto.TenantStatusActive, transitions[1].ToStatus)
+}
@@ -0,0 +1,51 @@
+package tenants
+
+import (
+	"encoding/json"
+	"testing"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/thriftrw/ptr"
+)
+
+func tenantResourceType(schema string) *dto.Mst_ResourceTypes {
+	return &dto.Mst_ResourceTypes{Name: "Tenant", AttributeSchema: dto.JSON(schema)}
+}
+
+func TestBuildTenantMetadataMergesChanges(t *testing.T) {
+	resolver := &TenantMutationResolver{}
+	existing := json.RawMessage(`{"description":"old","contactInfo":{"email":"a@example.com"},"attributes":{"tier":"gold"}}`)
+
+	metadata, validationErrors, err := resolver.buildTenantMetadata(tenantResourceType(""), existing, ptr.String("new"),
+		&models.ContactInfoInput{PhoneNumber: ptr.String("123")}, nil)
+	require.NoError(t, err)
+	assert.Empty(t, validationErrors)
+	assert.JSONEq(t, `{"description":"new","contactInfo":{"email":"a@example.com","phoneNumber":"123"},"attributes":{"tier":"gold"}}`, string(metadata))
+}
+
+func TestBuildTenantMetadataValidatesSchema(t *testing.T) {
+	resolver := &TenantMutationResolver{}
+	schema := `{
+		"type": "object",
+		"properties": {
+			"contactInfo": {"type": "object", "required": ["email"]},
+			"attributes": {"type": "object", "properties": {"tier": {"enum": ["gold", "silver"]}}}
+		}
+	}`
+
+	_, validationErrors, err := resolver.buildTenantMetadata(tenantResourceType(schema), nil, nil,
+		&models.ContactInfoInput{PhoneNumber: ptr.String("123")}, ptr.String(`{"tier":"bronze"}`))
+	require.NoError(t, err)
+	require.Len(t, validationErrors, 2)
+	assert.Equal(t, "/attributes/tier", validationErrors[0].Field)
+	assert.Equal(t, "/contactInfo", validationErrors[1].Field)
+
+	_, validationErrors, err = resolver.buildTenantMetadata(tenantResourceType(schema), nil, nil, nil, ptr.String(`[1,2]`))
+	require.NoError(t, err)
+	require.Len(t, validationErrors, 1)
+	assert.Equal(t, "/attributes", validationErrors[0].Field)
+}
@@ -3,13 +3,18 @@ package tenants
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iam_services_main_v1/gql/models"
 	"iam_services_main_v1/helpers"
-	"iam_services_main_v1/internal/dao"
+	"iam_services_main_v1/internal/constants"
 	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/etag"
 	"iam_services_main_v1/internal/middlewares"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/resourcetypes"
+	"iam_services_main_v1/internal/tenancy"
 	"iam_services_main_v1/internal/utils"
 	"iam_services_main_v1/internal/validations"
 	"iam_services_main_v1/pkg/logger"
@@ -17,16 +22,17 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 type TenantMutationResolver struct {
-	DB           *gorm.DB
+	Store        repository.Store
 	PermitClient *permit.PermitClient
 }
 
 // CreateTenant resolver for adding a new Tenant
 func (t *TenantMutationResolver) CreateTenant(ctx context.Context, input models.CreateTenantInput) (models.OperationResult, error) {
+	// Tenants are created at Root level
+	rootCtx := tenancy.AsRoot(ctx)
 
 	newTenantID := uuid.New()
 	// Extract gin.Context from GraphQL context
@@ -37,54 +43,120 @@ func (t *TenantMutationResolver) CreateTenant(ctx context.Context, input models.
 	UserID := ginCtx.MustGet("userID").(string)
 	userUUID := uuid.MustParse(UserID)
 
-	inputMap := helpers.StructToMap(input)
-
-	if err := t.createTenantInPermit(ctx, input.Name, newTenantID, inputMap); err != nil {
-		return t.handleError("500", "Error creating tenant in permit system", err)
-	}
-
-	resourceType, err := dao.GetResourceTypeByName("Tenant")
+	resourceType, err := t.Store.Resources().GetTypeByName(rootCtx, constants.ResourceTypeTenant)
 	if err != nil {
 		return t.handleError("500", "Error getting resource type", err)
 	}
 
-	if err := t.createResourceInstanceInPermit(ctx, input.ID, resourceType.ResourceTypeID, newTenantID, input); err != nil {
-		return t.handleError("500", "Error creating resource instance of tenant in permit system", err)
-	}
-
-	tenantResource, err := t.createTenantResource(input.Name, newTenantID, *input.ParentID, userUUID, uuid.Nil)
+	metadata, validationErrors, err := t.buildTenantMetadata(resourceType, nil, input.Description, input.ContactInfo, input.Attributes)
 	if err != nil {
-		return t.handleError("500", "Error creating tenant resource", err)
+		return t.handleError("500", "Error building tenant metadata", err)
+	}
+	if len(validationErrors) > 0 {
+		return t.handleValidationError(validationErrors)
 	}
 
-	if err := t.createTenantMetadata(tenantResource.ResourceID, input.Description, input.ContactInfo, userUUID); err != nil {
-		return t.handleError("500", "Error creating tenant metadata", err)
+	inputMap := helpers.StructToMap(input)
+
+	// The tenant is recorded provisioning, then active, in the transaction
+	// creating it, so that a failure leaves no state behind. Permit is
+	// written last; its tenant is removed again when the resource instance
+	// cannot be created
+	var failure string
+	err = t.Store.Transaction(rootCtx, func(tx repository.Store) error {
+		if err := t.recordTenantStatus(rootCtx, tx, newTenantID, userUUID, "", dto.TenantStatusProvisioning, 0); err != nil {
+			failure = "Error recording tenant status"
+			return err
+		}
+		tenantResource, err := t.createTenantResource(rootCtx, tx, resourceType, input.Name, newTenantID, *input.ParentID, userUUID, newTenantID)
+		if err != nil {
+			failure = "Error creating tenant resource"
+			return err
+		}
+		if err := t.createTenantMetadata(rootCtx, tx, tenantResource.ResourceID, metadata, userUUID); err != nil {
+			failure = "Error creating tenant metadata"
+			return err
+		}
+		if err := t.recordTenantStatus(rootCtx, tx, newTenantID, userUUID, dto.TenantStatusProvisioning, dto.TenantStatusActive, 1); err != nil {
+			failure = "Error recording tenant status"
+			return err
+		}
+		if err := t.createTenantInPermit(ctx, input.Name, newTenantID, inputMap); err != nil {
+			failure = "Error creating tenant in permit system"
+			return err
+		}
+		if err := t.createResourceInstanceInPermit(ctx, input.ID, resourceType.ResourceTypeID, newTenantID, input); err != nil {
+			failure = "Error creating resource instance of tenant in permit system"
+			if rollbackErr := t.deleteTenantInPermit(ctx, newTenantID); rollbackErr != nil {
+				err = errors.Join(err, fmt.Errorf("failed to roll back permit tenant: %w", rollbackErr))
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return t.handleError("500", failure, err)
 	}
 
-	return t.getTenantResponse(ctx, newTenantID)
+	return t.getTenantResponse(rootCtx, newTenantID)
 }
 
 // UpdateTenant resolver for updating a Tenant
 func (t *TenantMutationResolver) UpdateTenant(ctx context.Context, input models.UpdateTenantInput) (models.OperationResult, error) {
+	expectedRevision, err := etag.ParseOptional(input.ExpectedEtag)
+	if err != nil {
+		return t.handleError("400", "Invalid expectedEtag", err)
+	}
 
-	tenant, err := TenantDataPermit(ctx, &TenantQueryResolver{DB: t.DB, PC: t.PermitClient}, input.ID)
+	tenant, err := TenantDataPermit(ctx, &TenantQueryResolver{Store: t.Store, PC: t.PermitClient}, input.ID)
 	if err != nil {
 		return t.handleError("500", "Error retrieving tenant from permit system", err)
 	}
-	inputMap := helpers.StructToMap(input)
-	inputMap["created_by"] = tenant.CreatedBy
-	inputMap["updated_by"] = tenant.UpdatedBy
 
-	if err := t.updateTenantInPermit(ctx, input.ID, *input.Name, inputMap); err != nil {
-		return t.handleError("500", "Error updating tenant in permit system", err)
+	resourceType, err := t.Store.Resources().GetTypeByName(ctx, constants.ResourceTypeTenant)
+	if err != nil {
+		return t.handleError("500", "Error getting resource type", err)
 	}
 
-	if err := t.updateTenantResource(input.ID, input.Name, input.ParentID, tenant.UpdatedBy); err != nil {
-		return t.handleError("500", "Error updating tenant resource", err)
+	existingMetadata, err := t.getTenantMetadata(ctx, input.ID)
+	if err != nil {
+		return t.handleError("500", "Error updating tenant metadata", err)
 	}
 
-	if err := t.updateMetadata(input.ID, input.Description, input.ContactInfo, tenant.UpdatedBy); err != nil {
-		return t.handleError("500", "Error updating tenant metadata", err)
+	metadata, validationErrors, err := t.buildTenantMetadata(resourceType, existingMetadata, input.Description, input.ContactInfo, input.Attributes)
+	if err != nil {
+		return t.handleError("500", "Error building tenant metadata", err)
+	}
+	if len(validationErrors) > 0 {
+		return t.handleValidationError(validationErrors)
+	}
+	inputMap := helpers.StructToMap(input)
+	inputMap["created_by"] = tenant.CreatedBy
+	inputMap["updated_by"] = tenant.UpdatedBy
+
+	// Permit is updated last so that a conflicting or failed change leaves
+	// neither the database nor Permit modified
+	var failure string
+	err = t.Store.Transaction(ctx, func(tx repository.Store) error {
+		if err := t.updateTenantResource(ctx, tx, resourceType, input.ID, input.Name, input.ParentID, tenant.UpdatedBy, expectedRevision); err != nil {
+			failure = "Error updating tenant resource"
+			return err
+		}
+		if err := t.updateMetadata(ctx, tx, input.ID, metadata); err != nil {
+			failure = "Error updating tenant metadata"
+			return err
+		}
+		if err := t.updateTenantInPermit(ctx, input.ID, *input.Name, inputMap); err != nil {
+			failure = "Error updating tenant in permit system"
+			return err
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return t.handleError(constants.ErrorCodeConflict, "Tenant was modified concurrently", err)
+	}
+	if err != nil {
+		return t.handleError("500", failure, err)
 	}
 
 	return t.getTenantResponse(ctx, input.ID)
@@ -92,31 +164,28 @@ func (t *TenantMutationResolver) UpdateTenant(ctx context.Context, input models.
 
 // DeleteTenant resolver for deleting a Tenant
 func (t *TenantMutationResolver) DeleteTenant(ctx context.Context, input models.DeleteInput) (models.OperationResult, error) {
-	tx := t.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
-
-	if err := t.deleteTenantInPermit(ctx, input.ID); err != nil {
-		tx.Rollback()
-		return t.handleError("500", "Error deleting tenant in permit system", err)
-	}
-
-	if err := t.updateTenantMetadata(tx, input.ID); err != nil {
-		tx.Rollback()
-		return t.handleError("500", "Error updating tenant metadata", err)
+	expectedRevision, err := etag.ParseOptional(input.ExpectedEtag)
+	if err != nil {
+		return t.handleError("400", "Invalid expectedEtag", err)
 	}
 
-	if err := t.updateTenantResourceStatus(tx, input.ID); err != nil {
-		tx.Rollback()
-		return t.handleError("500", "Error updating tenant resource", err)
+	err = t.Store.Transaction(ctx, func(tx repository.Store) error {
+		if err := tx.Metadata().Delete(ctx, input.ID); err != nil {
+			return fmt.Errorf("error updating tenant metadata: %w", err)
+		}
+		if err := repository.UpdateResource(ctx, tx.Resources(), input.ID, expectedRevision, utils.UpdateDeletedMap()); err != nil {
+			return fmt.Errorf("error updating tenant resource: %w", err)
+		}
+		if err := t.deleteTenantInPermit(ctx, input.ID); err != nil {
+			return fmt.Errorf("error deleting tenant in permit system: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, repository.ErrConflict) {
+		return t.handleError(constants.ErrorCodeConflict, "Tenant was modified concurrently", err)
 	}
-
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		return t.handleError("500", "Error committing transaction", err)
+	if err != nil {
+		return t.handleError("500", "Error deleting tenant", err)
 	}
 
 	return utils.FormatSuccess([]models.Data{})
@@ -130,23 +199,32 @@ func (t *TenantMutationResolver) handleError(code, message string, err error) (m
 	return utils.FormatError(utils.FormatErrorStruct(code, message, em)), nil
 }
 
-func (t *TenantMutationResolver) validateParentOrg(parentOrgID uuid.UUID) (*uuid.UUID, error) {
+// handleValidationError reports field-level metadata errors. errorDetails holds
+// the JSON encoded list of failing fields.
+func (t *TenantMutationResolver) handleValidationError(validationErrors []validations.ValidationError) (models.OperationResult, error) {
+	details := validations.FormatValidationErrors(validationErrors)
+	logger.LogError(fmt.Sprintf("Metadata validation failed: %s", details))
+	return utils.FormatError(utils.FormatErrorStruct("400", "Metadata validation failed", details)), nil
+}
+
+func (t *TenantMutationResolver) validateParentOrg(ctx context.Context, parentOrgID uuid.UUID) (*uuid.UUID, error) {
 	if parentOrgID == uuid.Nil {
 		return nil, fmt.Errorf("parent organization ID is required")
 	}
 
-	resourceType, err := dao.GetResourceTypeByName("Root")
+	resourceType, err := t.Store.Resources().GetTypeByName(ctx, constants.ResourceTypeRoot)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get resource type IDs: %w", err)
 	}
 
-	var parentOrg dto.TenantResource
-	if err := t.DB.Where(
-		"resource_id = ? AND resource_type_id in (?) AND row_status = 1",
-		parentOrgID, resourceType.ResourceTypeID,
-	).First(&parentOrg).Error; err != nil {
+	// The Root organization is outside every tenant
+	parentOrg, err := t.Store.Resources().Get(tenancy.AsRoot(ctx), parentOrgID)
+	if err != nil {
 		return nil, fmt.Errorf("parent organization not found: %w", err)
 	}
+	if parentOrg.ResourceTypeID != resourceType.ResourceTypeID {
+		return nil, fmt.Errorf("parent organization not found: %s is not a %s", parentOrgID, constants.ResourceTypeRoot)
+	}
 
 	return &parentOrg.ResourceID, nil
 }
@@ -170,10 +248,25 @@ func (t *TenantMutationResolver) createResourceInstanceInPermit(ctx context.Cont
 	return err
 }
 
-func (t *TenantMutationResolver) createTenantResource(name string, resourceID, parentID uuid.UUID, userID, tenantID uuid.UUID) (*dto.TenantResource, error) {
-	var resourceType dto.Mst_ResourceTypes
-	if err := t.DB.Where("name = ?", "Tenant").First(&resourceType).Error; err != nil {
-		return nil, fmt.Errorf("resource type not found: %w", err)
+// checkParent checks that a resource of resourceType may be placed under
+// parentID, as registered in the allowed parent types of resourceType.
+func (t *TenantMutationResolver) checkParent(ctx context.Context, resourceType *dto.Mst_ResourceTypes, parentID uuid.UUID) error {
+	if parentID == uuid.Nil || !resourcetypes.HasParentConstraint(resourceType) {
+		return nil
+	}
+	parent, err := t.Store.Resources().Get(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("invalid parent resource: parent resource not found: %w", err)
+	}
+	if err := resourcetypes.CheckParent(resourceType, parent); err != nil {
+		return fmt.Errorf("invalid parent resource: %w", err)
+	}
+	return nil
+}
+
+func (t *TenantMutationResolver) createTenantResource(ctx context.Context, store repository.Store, resourceType *dto.Mst_ResourceTypes, name string, resourceID, parentID uuid.UUID, userID, tenantID uuid.UUID) (*dto.TenantResource, error) {
+	if err := t.checkParent(ctx, resourceType, parentID); err != nil {
+		return nil, err
 	}
 
 	tenant := &dto.TenantResource{
@@ -188,34 +281,62 @@ func (t *TenantMutationResolver) createTenantResource(name string, resourceID, p
 	if tenantID != uuid.Nil {
 		tenant.TenantID = &tenantID
 	}
-	if err := t.DB.Create(tenant).Error; err != nil {
+	if err := store.Resources().Create(ctx, tenant); err != nil {
 		return nil, fmt.Errorf("failed to create tenant resource: %w", err)
 	}
 
 	return tenant, nil
 }
 
-func (t *TenantMutationResolver) createTenantMetadata(resourceID uuid.UUID, description *string, contactInfo *models.ContactInfoInput, userID uuid.UUID) error {
-	metadata := map[string]interface{}{
-		"description": description,
-		"contactInfo": contactInfo,
+// buildTenantMetadata applies the given changes on top of the existing metadata
+// document and validates the result against the tenant resource type schema.
+func (t *TenantMutationResolver) buildTenantMetadata(resourceType *dto.Mst_ResourceTypes, existing json.RawMessage, description *string, contactInfo *models.ContactInfoInput, attributes *string) (json.RawMessage, []validations.ValidationError, error) {
+	metadata := make(map[string]interface{})
+	if len(existing) > 0 {
+		if err := json.Unmarshal(existing, &metadata); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	if description != nil {
+		metadata["description"] = *description
+	}
+
+	if contactInfo != nil {
+		t.updateContactInfo(metadata, contactInfo)
+	}
+
+	parsedAttributes, validationErrors := resourcetypes.ParseAttributes(attributes)
+	if len(validationErrors) > 0 {
+		return nil, validationErrors, nil
+	}
+	if parsedAttributes != nil {
+		metadata[resourcetypes.AttributesKey] = parsedAttributes
 	}
 
 	metadataJSON, err := json.Marshal(metadata)
 	if err != nil {
-		return fmt.Errorf("failed to marshal metadata: %w", err)
+		return nil, nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	validationErrors, err = resourcetypes.ValidateMetadata(resourceType, metadataJSON)
+	if err != nil {
+		return nil, nil, err
 	}
+	return metadataJSON, validationErrors, nil
+}
 
+func (t *TenantMutationResolver) createTenantMetadata(ctx context.Context, store repository.Store, resourceID uuid.UUID, metadata json.RawMessage, userID uuid.UUID) error {
 	tenantMetadata := &dto.TenantMetadata{
 		ResourceID: resourceID,
-		Metadata:   metadataJSON,
+		Metadata:   dto.JSON(metadata),
 		CreatedBy:  userID,
 		CreatedAt:  time.Now(),
 		UpdatedBy:  userID,
 		UpdatedAt:  time.Now(),
 	}
 
-	if err := t.DB.Create(tenantMetadata).Error; err != nil {
+	if err := store.Metadata().Create(ctx, tenantMetadata); err != nil {
 		return fmt.Errorf("failed to create tenant metadata: %w", err)
 	}
 
@@ -230,7 +351,7 @@ func (t *TenantMutationResolver) updateTenantInPermit(ctx context.Context, tenan
 	return err
 }
 
-func (t *TenantMutationResolver) updateTenantResource(tenantID uuid.UUID, name *string, parentID *uuid.UUID, userID uuid.UUID) error {
+func (t *TenantMutationResolver) updateTenantResource(ctx context.Context, store repository.Store, resourceType *dto.Mst_ResourceTypes, tenantID uuid.UUID, name *string, parentID *uuid.UUID, userID uuid.UUID, expectedRevision *int) error {
 	updates := map[string]interface{}{
 		"updated_by": userID,
 		"updated_at": time.Now(),
@@ -240,50 +361,33 @@ func (t *TenantMutationResolver) updateTenantResource(tenantID uuid.UUID, name *
 	}
 
 	if parentID != nil && *parentID != uuid.Nil {
-		parentResourceID, err := t.validateParentOrg(*parentID)
+		parentResourceID, err := t.validateParentOrg(ctx, *parentID)
 		if err != nil {
 			return fmt.Errorf("error getting parent org: %w", err)
 		}
+		if err := t.checkParent(tenancy.AsRoot(ctx), resourceType, *parentResourceID); err != nil {
+			return err
+		}
 		updates["parent_resource_id"] = parentResourceID
 	}
 
-	if err := t.DB.Model(&dto.TenantResource{}).Where("resource_id = ?", tenantID).Updates(updates).Error; err != nil {
+	if err := repository.UpdateResource(ctx, store.Resources(), tenantID, expectedRevision, updates); err != nil {
 		return fmt.Errorf("error updating tenant resource: %w", err)
 	}
 
 	return nil
 }
 
-func (t *TenantMutationResolver) updateMetadata(resourceID uuid.UUID, description *string, contactInfo *models.ContactInfoInput, userID uuid.UUID) error {
-	var tenantMetadata dto.TenantMetadata
-	if err := t.DB.Where("resource_id = ?", resourceID).First(&tenantMetadata).Error; err != nil {
-		return fmt.Errorf("tenant metadata not found: %w", err)
-	}
-
-	metadata := make(map[string]interface{})
-	if err := json.Unmarshal(tenantMetadata.Metadata, &metadata); err != nil {
-		return fmt.Errorf("failed to unmarshal metadata: %w", err)
-	}
-
-	if description != nil {
-		metadata["description"] = *description
-	}
-
-	if contactInfo != nil {
-		t.updateContactInfo(metadata, contactInfo)
-	}
-
-	updatedMetadataJSON, err := json.Marshal(metadata)
+func (t *TenantMutationResolver) getTenantMetadata(ctx context.Context, resourceID uuid.UUID) (json.RawMessage, error) {
+	tenantMetadata, err := t.Store.Metadata().Get(ctx, resourceID)
 	if err != nil {
-		return fmt.Errorf("failed to marshal updated metadata: %w", err)
-	}
-
-	updates := map[string]interface{}{
-		"metadata":   updatedMetadataJSON,
-		"updated_at": time.Now(),
+		return nil, fmt.Errorf("tenant metadata not found: %w", err)
 	}
+	return json.RawMessage(tenantMetadata.Metadata), nil
+}
 
-	if err := t.DB.Model(&dto.TenantMetadata{}).Where("resource_id = ?", resourceID).Updates(updates).Error; err != nil {
+func (t *TenantMutationResolver) updateMetadata(ctx context.Context, store repository.Store, resourceID uuid.UUID, metadata json.RawMessage) error {
+	if err := store.Metadata().Update(ctx, resourceID, dto.JSON(metadata)); err != nil {
 		return fmt.Errorf("failed to update tenant metadata: %w", err)
 	}
 
@@ -335,44 +439,11 @@ func (t *TenantMutationResolver) updateAddress(contactInfo map[string]interface{
 }
 
 func (t *TenantMutationResolver) getTenantResponse(ctx context.Context, tenantID uuid.UUID) (models.OperationResult, error) {
-	tq := &TenantQueryResolver{DB: t.DB, PC: t.PermitClient}
+	tq := &TenantQueryResolver{Store: t.Store, PC: t.PermitClient}
 	return tq.Tenant(ctx, tenantID)
 }
 
-func (t *TenantMutationResolver) getParentOrg(tenantID, resourceTypeID uuid.UUID) (*dto.TenantResource, error) {
-	var parentOrg dto.TenantResource
-	if err := t.DB.Where(&dto.TenantResource{
-		TenantID:       &tenantID,
-		ResourceTypeID: resourceTypeID,
-	}).First(&parentOrg).Error; err != nil {
-		return nil, fmt.Errorf("error getting parent org: %w", err)
-	}
-	return &parentOrg, nil
-}
-
-func (t *TenantMutationResolver) getTenantResource(tx *gorm.DB, tenantID uuid.UUID) (*dto.TenantResource, error) {
-	var tenant dto.TenantResource
-	if err := tx.Where("resource_id = ? AND row_status = 1", tenantID).First(&tenant).Error; err != nil {
-		return nil, fmt.Errorf("error getting tenant: %w", err)
-	}
-	return &tenant, nil
-}
-
 func (t *TenantMutationResolver) deleteTenantInPermit(ctx context.Context, tenantID uuid.UUID) error {
 	_, err := t.PermitClient.SendRequest(ctx, "DELETE", fmt.Sprintf("tenants/%s", tenantID), nil)
 	return err
 }
-
-func (t *TenantMutationResolver) updateTenantMetadata(tx *gorm.DB, tenantID uuid.UUID) error {
-	if err := tx.Model(&dto.TenantMetadata{}).Where("resource_id = ?", tenantID).UpdateColumns(validations.UpdateDeletedMap()).Error; err != nil {
-		return fmt.Errorf("error updating tenant metadata: %w", err)
-	}
-	return nil
-}
-
-func (t *TenantMutationResolver) updateTenantResourceStatus(tx *gorm.DB, resourceID uuid.UUID) error {
-	if err := tx.Model(&dto.TenantResource{}).Where("resource_id= ?", resourceID).UpdateColumns(validations.UpdateDeletedMap()).Error; err != nil {
-		return fmt.Errorf("error updating tenant resource: %w", err)
-	}
-	return nil
-}
@@ -2,19 +2,21 @@ package tenants
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 
 	"iam_services_main_v1/gql/models"
 	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/etag"
+	"iam_services_main_v1/internal/labels"
 	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/resourcetypes"
+	"iam_services_main_v1/internal/tenancy"
 	"iam_services_main_v1/internal/utils"
 	"iam_services_main_v1/pkg/logger"
 
 	"github.com/google/uuid"
-	"go.uber.org/thriftrw/ptr"
-	"gorm.io/gorm"
 )
 
 var (
@@ -26,23 +28,21 @@ var (
 
 // TenantQueryResolver handles tenant-related GraphQL queries
 type TenantQueryResolver struct {
-	DB *gorm.DB
-	PC *permit.PermitClient
+	Store repository.Store
+	PC    *permit.PermitClient
 }
 
-// getTenantResourceType retrieves the resource type for tenants
-func (r *TenantQueryResolver) getTenantResourceType() (*dto.Mst_ResourceTypes, error) {
-	var resourceType dto.Mst_ResourceTypes
-	err := r.DB.Where("name = ?", "Tenant").First(&resourceType).Error
+// Tenants retrieves a list of tenants from the permit system, optionally
+// filtered by a label selector
+func (r *TenantQueryResolver) Tenants(ctx context.Context, selector *string) (models.OperationResult, error) {
+	// Root-level requests list every tenant
+	ctx = tenancy.AllowRoot(ctx)
+	var tenants []models.Data
+
+	labelSelector, err := labels.ParseOptionalSelector(selector)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrResourceTypeNotFound, err)
+		return r.handleError("400", "Invalid label selector", err)
 	}
-	return &resourceType, nil
-}
-
-// Tenants retrieves a list of tenants from the permit system
-func (r *TenantQueryResolver) Tenants(ctx context.Context) (models.OperationResult, error) {
-	var tenants []models.Data
 	page := 1
 	perPage := 100
 
@@ -65,10 +65,13 @@ func (r *TenantQueryResolver) Tenants(ctx context.Context) (models.OperationResu
 				continue
 			}
 
-			tenant, err := r.extractTenantAttributes(tenantMap)
+			tenant, err := r.extractTenantAttributes(ctx, tenantMap)
 			if err != nil {
 				continue
 			}
+			if !labelSelector.Matches(labels.FromModels(tenant.Labels)) {
+				continue
+			}
 			tenants = append(tenants, tenant)
 		}
 
@@ -86,13 +89,13 @@ func (r *TenantQueryResolver) Tenant(ctx context.Context, id uuid.UUID) (models.
 	if id == uuid.Nil {
 		return r.handleError("400", "Tenant ID is required", ErrTenantIDRequired)
 	}
-
+	ctx = tenancy.AllowRoot(ctx)
 	tenant, err := r.fetchTenantFromPermit(ctx, id)
 	if err != nil {
 		return r.handleError("400", "Error retrieving tenant from permit system", err)
 	}
 
-	data, err := r.extractTenantAttributes(tenant)
+	data, err := r.extractTenantAttributes(ctx, tenant)
 	if err != nil {
 		return r.handleError("400", "Error retrieving tenant from permit system", err)
 	}
@@ -102,35 +105,8 @@ func (r *TenantQueryResolver) Tenant(ctx context.Context, id uuid.UUID) (models.
 	return utils.FormatSuccess(modelsData)
 }
 
-// enrichTenantWithMetadata fetches additional metadata for a tenant
-func (r *TenantQueryResolver) enrichTenantWithMetadata(tenant *models.Tenant) error {
-	if tenant == nil {
-		return nil
-	}
-
-	var metadata dto.TenantMetadata
-	err := r.DB.Where("resource_id = ?", tenant.ID).First(&metadata).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil
-		}
-		return fmt.Errorf("failed to fetch tenant metadata: %w", err)
-	}
-
-	var meta map[string]interface{}
-	if err := json.Unmarshal(metadata.Metadata, &meta); err != nil {
-		return fmt.Errorf("failed to unmarshal metadata: %w", err)
-	}
-
-	if description, ok := meta["description"].(string); ok {
-		tenant.Description = ptr.String(description)
-	}
-
-	return nil
-}
-
 // extractTenantAttributes processes raw tenant data into a Tenant model
-func (r *TenantQueryResolver) extractTenantAttributes(data map[string]interface{}) (*models.Tenant, error) {
+func (r *TenantQueryResolver) extractTenantAttributes(ctx context.Context, data map[string]interface{}) (*models.Tenant, error) {
 	tenant := &models.Tenant{}
 
 	if id, ok := data["key"].(string); ok {
@@ -153,12 +129,33 @@ func (r *TenantQueryResolver) extractTenantAttributes(data map[string]interface{
 		tenant = r.extractAttributesFromMap(tenant, attributes)
 	}
 
-	parentOrgID := uuid.Nil
-	if tenant.ParentOrg != nil {
-		parentOrgID = tenant.ID
+	customAttributes, err := r.resourceAttributes(ctx, tenant.ID)
+	if err != nil {
+		return nil, err
 	}
+	tenant.Attributes = customAttributes
 
-	parentOrg, err := r.fetchParentOrg(parentOrgID)
+	tenantLabels, err := r.Store.Resources().Labels(ctx, tenant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch labels: %w", err)
+	}
+	tenant.Labels = labels.ToModels(tenantLabels)
+
+	state, err := repository.CurrentTenantState(ctx, r.Store.TenantStates(), tenant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tenant status: %w", err)
+	}
+	tenant.Status = models.TenantStatus(state.Status)
+
+	tenantResource, err := r.fetchTenantResource(ctx, tenant.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tenantResource != nil {
+		tenant.Etag = etag.Format(tenantResource.Revision)
+	}
+
+	parentOrg, err := r.fetchParentOrg(ctx, tenantResource)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrParentOrgNotFound, err)
 	}
@@ -167,6 +164,7 @@ func (r *TenantQueryResolver) extractTenantAttributes(data map[string]interface{
 		tenant.ParentOrg = &models.Root{
 			ID:        parentOrg.ResourceID,
 			Name:      parentOrg.Name,
+			Etag:      etag.Format(parentOrg.Revision),
 			CreatedAt: parentOrg.CreatedAt.String(),
 			UpdatedAt: parentOrg.UpdatedAt.String(),
 			CreatedBy: parentOrg.CreatedBy,
@@ -220,16 +218,47 @@ func (r *TenantQueryResolver) fetchTenantFromPermit(ctx context.Context, id uuid
 	return tenant, nil
 }
 
-// fetchParentOrg fetches the parent organization for a tenant
-func (r *TenantQueryResolver) fetchParentOrg(parentOrgID uuid.UUID) (*dto.TenantResource, error) {
-	var parentOrg dto.TenantResource
-	err := r.DB.Where(&dto.TenantResource{
-		ResourceID: parentOrgID,
-	}).First(&parentOrg).Error
+// resourceAttributes returns the custom attributes stored in the metadata of a
+// tenant, or nil when it has none.
+func (r *TenantQueryResolver) resourceAttributes(ctx context.Context, tenantID uuid.UUID) (*string, error) {
+	metadata, err := r.Store.Metadata().Get(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch resource metadata: %w", err)
+	}
+	return resourcetypes.MetadataAttributes(metadata.Metadata)
+}
+
+// fetchTenantResource fetches the stored resource of a tenant, or nil when the
+// tenant only exists in Permit.
+func (r *TenantQueryResolver) fetchTenantResource(ctx context.Context, tenantID uuid.UUID) (*dto.TenantResource, error) {
+	tenant, err := r.Store.Resources().Get(ctx, tenantID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tenant resource: %w", err)
+	}
+	return tenant, nil
+}
+
+// fetchParentOrg fetches the parent organization of a tenant, which lives at
+// Root level. Tenants without a stored resource or parent have none.
+func (r *TenantQueryResolver) fetchParentOrg(ctx context.Context, tenant *dto.TenantResource) (*dto.TenantResource, error) {
+	if tenant == nil || tenant.ParentResourceID == nil || *tenant.ParentResourceID == uuid.Nil {
+		return nil, nil
+	}
+
+	parentOrg, err := r.Store.Resources().Get(tenancy.AsRoot(ctx), *tenant.ParentResourceID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch parent organization: %w", err)
 	}
-	return &parentOrg, nil
+	return parentOrg, nil
 }
 
 // handleError logs and formats an error response
@@ -245,7 +274,7 @@ func TenantDataPermit(ctx context.Context, r *TenantQueryResolver, id uuid.UUID)
 		return nil, fmt.Errorf("error retrieving tenant from permit system: %w", err)
 	}
 
-	data, err := r.extractTenantAttributes(tenant)
+	data, err := r.extractTenantAttributes(ctx, tenant)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving tenant from permit system: %w", err)
 	}
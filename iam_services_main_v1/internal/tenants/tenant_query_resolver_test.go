@@ -1,232 +1,170 @@
 package tenants
 
 import (
-	"github.com/stretchr/testify/mock"
-	"gorm.io/gorm"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"iam_services_main_v1/gql/models"
+	"iam_services_main_v1/internal/constants"
+	"iam_services_main_v1/internal/dto"
+	"iam_services_main_v1/internal/permit"
+	"iam_services_main_v1/internal/repository"
+	"iam_services_main_v1/internal/resourcetypes"
+	"iam_services_main_v1/internal/tenancy"
+	"iam_services_main_v1/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-// MockDB is a mock implementation of *gorm.DB
-type MockDB struct {
-	mock.Mock
-	*gorm.DB // Embed *gorm.DB to make it compatible with *gorm.DB
+// setupPermit returns a client serving tenant as every GET response.
+func setupPermit(t *testing.T, tenant map[string]interface{}) *permit.PermitClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(tenant)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return permit.NewPermitClientWithConfig(permit.Config{PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second})
 }
 
-// func (m *MockDB) Where(query interface{}, args ...interface{}) *gorm.DB {
-// 	called := m.Called(query, args)
-// 	return called.Get(0).(*gorm.DB)
-// }
-
-// func (m *MockDB) First(dest interface{}, conds ...interface{}) *gorm.DB {
-// 	called := m.Called(dest)
-// 	return called.Get(0).(*gorm.DB)
-// }
-
-// func (m *MockDB) Find(dest interface{}, conds ...interface{}) *gorm.DB {
-// 	called := m.Called(dest)
-// 	return called.Get(0).(*gorm.DB)
-// }
-
-// func TestGetTenant(t *testing.T) {
-// 	tests := []struct {
-// 		name           string
-// 		tenantID       uuid.UUID
-// 		setupMock      func(*MockDB)
-// 		expectedError  error
-// 		expectedTenant *models.Tenant
-// 	}{
-// 		{
-// 			name:     "successful tenant retrieval",
-// 			tenantID: uuid.New(),
-// 			setupMock: func(db *MockDB) {
-// 				resourceType := &dto.Mst_ResourceTypes{
-// 					ResourceTypeID: uuid.New(),
-// 					Name:           "Tenant",
-// 				}
-
-// 				tenantResource := &dto.TenantResources{
-// 					ResourceID:     uuid.New(),
-// 					ResourceTypeID: resourceType.ResourceTypeID,
-// 					Name:           "Test Tenant",
-// 				}
-
-// 				metadata := &dto.TenantMetadata{
-// 					ResourceID: tenantResource.ResourceID,
-// 					Metadata: json.RawMessage(`{
-// 						"description": "Test Description",
-// 						"contactInfo": {
-// 							"email": "test@example.com",
-// 							"phone": "123-456-7890"
-// 						}
-// 					}`),
-// 				}
-
-// 				// Mock resource type query
-// 				db.On("Where", mock.Anything, mock.Anything).Return(db)
-// 				db.On("First", mock.AnythingOfType("*dto.Mst_ResourceTypes")).
-// 					Run(func(args mock.Arguments) {
-// 						arg := args.Get(0).(*dto.Mst_ResourceTypes)
-// 						*arg = *resourceType
-// 					}).
-// 					Return(&gorm.DB{Error: nil})
-
-// 				// Mock tenant resource query
-// 				db.On("Where", mock.Anything, mock.Anything).Return(db)
-// 				db.On("First", mock.AnythingOfType("*dto.TenantResources")).
-// 					Run(func(args mock.Arguments) {
-// 						arg := args.Get(0).(*dto.TenantResources)
-// 						*arg = *tenantResource
-// 					}).
-// 					Return(&gorm.DB{Error: nil})
-
-// 				// Mock metadata query
-// 				db.On("Where", mock.Anything, mock.Anything).Return(db)
-// 				db.On("First", mock.AnythingOfType("*dto.TenantMetadata")).
-// 					Run(func(args mock.Arguments) {
-// 						arg := args.Get(0).(*dto.TenantMetadata)
-// 						*arg = *metadata
-// 					}).
-// 					Return(&gorm.DB{Error: nil})
-// 			},
-// 			expectedError: nil,
-// 			expectedTenant: &models.Tenant{
-// 				Name:        "Test Tenant",
-// 				Description: ptr.String("Test Description"),
-// 				ContactInfo: &models.ContactInfo{
-// 					Email:       ptr.String("test@example.com"),
-// 					PhoneNumber: ptr.String("123-456-7890"),
-// 				},
-// 			},
-// 		},
-// 		{
-// 			name:     "tenant not found",
-// 			tenantID: uuid.New(),
-// 			setupMock: func(db *MockDB) {
-// 				// Mock resource type query
-// 				db.On("Where", mock.Anything, mock.Anything).Return(db)
-// 				db.On("First", mock.AnythingOfType("*dto.Mst_ResourceTypes")).
-// 					Return(&gorm.DB{Error: gorm.ErrRecordNotFound})
-// 			},
-// 			expectedError:  ErrResourceTypeNotFound,
-// 			expectedTenant: nil,
-// 		},
-// 		{
-// 			name:           "nil tenant ID",
-// 			tenantID:       uuid.Nil,
-// 			setupMock:      func(db *MockDB) {},
-// 			expectedError:  ErrTenantIDRequired,
-// 			expectedTenant: nil,
-// 		},
-// 	}
-
-// 	for _, tt := range tests {
-// 		t.Run(tt.name, func(t *testing.T) {
-// 			mockDB := &MockDB{}
-// 			tt.setupMock(mockDB)
-
-// 			resolver := &TenantQueryResolver{
-// 				DB: mockDB.DB, // Use the mock directly
-// 			}
-
-// 			_, err := resolver.GetTenant(context.Background(), tt.tenantID)
-
-// 			if tt.expectedError != nil {
-// 				assert.ErrorIs(t, err, tt.expectedError)
-// 			} else {
-// 				assert.NoError(t, err)
-// 				// assert.Equal(t, tt.expectedTenant.Name, tenant.IsOperationResult())
-// 				// assert.Equal(t, tt.expectedTenant.Description, tenant.Description)
-// 				if tt.expectedTenant.ContactInfo != nil {
-// 					// assert.Equal(t, tt.expectedTenant.ContactInfo.Email, tenant.ContactInfo.Email)
-// 					// assert.Equal(t, tt.expectedTenant.ContactInfo.PhoneNumber, tenant.ContactInfo.PhoneNumber)
-// 				}
-// 			}
-// 		})
-// 	}
-// }
-
-// func TestAllTenants(t *testing.T) {
-// 	tests := []struct {
-// 		name          string
-// 		setupMock     func(*MockDB)
-// 		expectedError error
-// 		expectedCount int
-// 	}{
-// 		{
-// 			name: "successful tenants retrieval",
-// 			setupMock: func(db *MockDB) {
-// 				resourceType := &dto.Mst_ResourceTypes{
-// 					ResourceTypeID: uuid.New(),
-// 					Name:           "Tenant",
-// 				}
-
-// 				tenantResources := []dto.TenantResources{
-// 					{
-// 						ResourceID:     uuid.New(),
-// 						ResourceTypeID: resourceType.ResourceTypeID,
-// 						Name:           "Tenant 1",
-// 					},
-// 					{
-// 						ResourceID:     uuid.New(),
-// 						ResourceTypeID: resourceType.ResourceTypeID,
-// 						Name:           "Tenant 2",
-// 					},
-// 				}
-
-// 				// Mock resource type query
-// 				db.On("Where", mock.Anything, mock.Anything).Return(db)
-// 				db.On("First", mock.AnythingOfType("*dto.Mst_ResourceTypes")).
-// 					Run(func(args mock.Arguments) {
-// 						arg := args.Get(0).(*dto.Mst_ResourceTypes)
-// 						*arg = *resourceType
-// 					}).
-// 					Return(&gorm.DB{Error: nil})
-
-// 				// Mock tenants query
-// 				db.On("Where", mock.Anything).Return(db)
-// 				db.On("Find", mock.AnythingOfType("*[]dto.TenantResources")).
-// 					Run(func(args mock.Arguments) {
-// 						arg := args.Get(0).(*[]dto.TenantResources)
-// 						*arg = tenantResources
-// 					}).
-// 					Return(&gorm.DB{Error: nil})
-
-// 				// Mock metadata queries
-// 				db.On("Where", mock.Anything, mock.Anything).Return(db)
-// 				db.On("First", mock.AnythingOfType("*dto.TenantMetadata")).
-// 					Return(&gorm.DB{Error: gorm.ErrRecordNotFound})
-// 			},
-// 			expectedError: nil,
-// 			expectedCount: 2,
-// 		},
-// 		{
-// 			name: "resource type not found",
-// 			setupMock: func(db *MockDB) {
-// 				db.On("Where", mock.Anything, mock.Anything).Return(db)
-// 				db.On("First", mock.AnythingOfType("*dto.Mst_ResourceTypes")).
-// 					Return(&gorm.DB{Error: gorm.ErrRecordNotFound})
-// 			},
-// 			expectedError: ErrResourceTypeNotFound,
-// 			expectedCount: 0,
-// 		},
-// 	}
-
-// 	for _, tt := range tests {
-// 		t.Run(tt.name, func(t *testing.T) {
-// 			mockDB := &MockDB{}
-// 			tt.setupMock(mockDB)
-
-// 			resolver := &TenantQueryResolver{
-// 				DB: mockDB.DB, // Use the mock directly
-// 			}
-
-// 			_, err := resolver.AllTenants(context.Background())
-
-// 			if tt.expectedError != nil {
-// 				assert.ErrorIs(t, err, tt.expectedError)
-// 			} else {
-// 				assert.NoError(t, err)
-// 				// assert.Equal(t, tt.expectedCount, len(tenants))
-// 			}
-// 		})
-// 	}
-// }
+// seedTenant stores a tenant under a Root organization.
+func seedTenant(t *testing.T, store *repository.MemoryStore) (tenantID, rootID uuid.UUID) {
+	tenantID, rootID = uuid.New(), uuid.New()
+	ctx := tenancy.AsRoot(context.Background())
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{ResourceID: rootID, Name: "Root", RowStatus: 1}))
+	require.NoError(t, store.Resources().Create(ctx, &dto.TenantResource{
+		ResourceID: tenantID, Name: "acme", TenantID: &tenantID, ParentResourceID: &rootID, RowStatus: 1,
+	}))
+	require.NoError(t, store.Metadata().Create(ctx, &dto.TenantMetadata{
+		ResourceID: tenantID, Metadata: dto.JSON(`{"description":"acme","attributes":{"tier":"gold"}}`), RowStatus: 1,
+	}))
+	store.SetLabels(tenantID, map[string]string{"env": "prod"})
+	return tenantID, rootID
+}
+
+func TestTenantResolvesStoredDetails(t *testing.T) {
+	logger.InitLogger()
+	store := repository.NewMemoryStore()
+	tenantID, rootID := seedTenant(t, store)
+	resolver := &TenantQueryResolver{Store: store, PC: setupPermit(t, map[string]interface{}{
+		"key": tenantID.String(), "name": "acme", "attributes": map[string]interface{}{"Description": "acme"},
+	})}
+
+	result, err := resolver.Tenant(tenancy.WithTenant(context.Background(), tenantID), tenantID)
+	require.NoError(t, err)
+	response, ok := result.(*models.SuccessResponse)
+	require.True(t, ok, "unexpected result %#v", result)
+	require.Len(t, response.Data, 1)
+	tenant := response.Data[0].(models.Tenant)
+
+	assert.Equal(t, "acme", tenant.Name)
+	assert.Equal(t, `W/"1"`, tenant.Etag)
+	assert.Equal(t, models.TenantStatusActive, tenant.Status)
+	require.NotNil(t, tenant.Attributes)
+	assert.JSONEq(t, `{"tier":"gold"}`, *tenant.Attributes)
+	require.Len(t, tenant.Labels, 1)
+	assert.Equal(t, "env", tenant.Labels[0].Key)
+	// The Root organization is read outside the tenant's isolation
+	require.NotNil(t, tenant.ParentOrg)
+	assert.Equal(t, rootID, tenant.ParentOrg.(*models.Root).ID)
+}
+
+func TestTenantWithoutStoredResource(t *testing.T) {
+	logger.InitLogger()
+	tenantID := uuid.New()
+	resolver := &TenantQueryResolver{Store: repository.NewMemoryStore(), PC: setupPermit(t, map[string]interface{}{
+		"key": tenantID.String(), "name": "permit only",
+	})}
+
+	result, err := resolver.Tenant(context.Background(), tenantID)
+	require.NoError(t, err)
+	response, ok := result.(*models.SuccessResponse)
+	require.True(t, ok, "unexpected result %#v", result)
+	tenant := response.Data[0].(models.Tenant)
+	assert.Nil(t, tenant.ParentOrg)
+	assert.Nil(t, tenant.Attributes)
+	assert.Empty(t, tenant.Labels)
+}
+
+func TestDeleteTenantMarksRecordsDeleted(t *testing.T) {
+	logger.InitLogger()
+	store := repository.NewMemoryStore()
+	tenantID, _ := seedTenant(t, store)
+	resolver := &TenantMutationResolver{Store: store, PermitClient: setupPermit(t, nil)}
+
+	ctx := tenancy.WithTenant(context.Background(), tenantID)
+	result, err := resolver.DeleteTenant(ctx, models.DeleteInput{ID: tenantID})
+	require.NoError(t, err)
+	_, ok := result.(*models.SuccessResponse)
+	require.True(t, ok, "unexpected result %#v", result)
+
+	_, err = store.Resources().Get(ctx, tenantID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = store.Metadata().Get(ctx, tenantID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestDeleteTenantRejectsStaleEtag(t *testing.T) {
+	logger.InitLogger()
+	store := repository.NewMemoryStore()
+	tenantID, _ := seedTenant(t, store)
+	deleted := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		deleted = deleted || req.Method == http.MethodDelete
+	}))
+	t.Cleanup(srv.Close)
+	resolver := &TenantMutationResolver{Store: store, PermitClient: permit.NewPermitClientWithConfig(permit.Config{
+		PDPEndpoint: srv.URL, ProjectID: "proj", EnvID: "env", Timeout: time.Second,
+	})}
+
+	ctx := tenancy.WithTenant(context.Background(), tenantID)
+	require.NoError(t, store.Resources().Update(ctx, tenantID, map[string]interface{}{"name": "renamed"}))
+
+	stale := `W/"1"`
+	result, err := resolver.DeleteTenant(ctx, models.DeleteInput{ID: tenantID, ExpectedEtag: &stale})
+	require.NoError(t, err)
+	response, ok := result.(*models.ResponseError)
+	require.True(t, ok, "unexpected result %#v", result)
+	assert.Equal(t, "CONFLICT", response.ErrorCode)
+	// The losing writer neither reaches Permit nor deletes any record
+	assert.False(t, deleted)
+	_, err = store.Metadata().Get(ctx, tenantID)
+	assert.NoError(t, err)
+
+	current := `W/"2"`
+	result, err = resolver.DeleteTenant(ctx, models.DeleteInput{ID: tenantID, ExpectedEtag: &current})
+	require.NoError(t, err)
+	_, ok = result.(*models.SuccessResponse)
+	require.True(t, ok, "unexpected result %#v", result)
+	assert.True(t, deleted)
+}
+
+func TestUpdateTenantResourceChecksParentType(t *testing.T) {
+	logger.InitLogger()
+	store := repository.NewMemoryStore()
+	rootTypeID := uuid.New()
+	store.AddResourceType(dto.Mst_ResourceTypes{ResourceTypeID: rootTypeID, Name: constants.ResourceTypeRoot, RowStatus: 1})
+	tenantID, _ := seedTenant(t, store)
+	rootID := uuid.New()
+	require.NoError(t, store.Resources().Create(tenancy.AsRoot(context.Background()), &dto.TenantResource{
+		ResourceID: rootID, ResourceTypeID: rootTypeID, Name: "Other root", RowStatus: 1,
+	}))
+	resolver := &TenantMutationResolver{Store: store}
+	ctx := tenancy.WithTenant(context.Background(), tenantID)
+
+	restricted := &dto.Mst_ResourceTypes{Name: constants.ResourceTypeTenant, AllowedParentTypes: []byte(`["` + uuid.NewString() + `"]`)}
+	err := resolver.updateTenantResource(ctx, store, restricted, tenantID, nil, &rootID, uuid.New(), nil)
+	assert.ErrorIs(t, err, resourcetypes.ErrParentTypeNotAllowed)
+
+	allowed := &dto.Mst_ResourceTypes{Name: constants.ResourceTypeTenant, AllowedParentTypes: []byte(`["` + rootTypeID.String() + `"]`)}
+	require.NoError(t, resolver.updateTenantResource(ctx, store, allowed, tenantID, nil, &rootID, uuid.New(), nil))
+	tenant, err := store.Resources().Get(ctx, tenantID)
+	require.NoError(t, err)
+	assert.Equal(t, rootID, *tenant.ParentResourceID)
+}
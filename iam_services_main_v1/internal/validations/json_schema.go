@@ -0,0 +1,77 @@
+package validations
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const schemaResourceURL = "metadata.schema.json"
+
+// CompileSchema parses and compiles a JSON Schema document.
+func CompileSchema(schema []byte) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaResourceURL, bytes.NewReader(schema)); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	compiled, err := compiler.Compile(schemaResourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return compiled, nil
+}
+
+// ValidateJSON validates document against schema and returns one ValidationError
+// per failing value. Field holds the JSON pointer of the offending value.
+func ValidateJSON(schema, document []byte) ([]ValidationError, error) {
+	compiled, err := CompileSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	var instance interface{}
+	if err := json.Unmarshal(document, &instance); err != nil {
+		return []ValidationError{{Field: "/", Message: "document is not valid JSON"}}, nil
+	}
+
+	err = compiled.Validate(instance)
+	if err == nil {
+		return nil, nil
+	}
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(err, &schemaErr) {
+		return nil, fmt.Errorf("failed to validate document: %w", err)
+	}
+
+	var validationErrors []ValidationError
+	collectSchemaErrors(schemaErr, &validationErrors)
+	sort.SliceStable(validationErrors, func(i, j int) bool {
+		return validationErrors[i].Field < validationErrors[j].Field
+	})
+	return validationErrors, nil
+}
+
+// FormatValidationErrors renders validation errors as the JSON array carried in
+// ResponseError.errorDetails.
+func FormatValidationErrors(validationErrors []ValidationError) string {
+	validationErrorsJSON, _ := json.Marshal(validationErrors)
+	return string(validationErrorsJSON)
+}
+
+func collectSchemaErrors(err *jsonschema.ValidationError, out *[]ValidationError) {
+	if len(err.Causes) == 0 {
+		field := err.InstanceLocation
+		if field == "" {
+			field = "/"
+		}
+		*out = append(*out, ValidationError{Field: field, Message: err.Message})
+		return
+	}
+	for _, cause := range err.Causes {
+		collectSchemaErrors(cause, out)
+	}
+}
@@ -0,0 +1,51 @@
+package validations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tenantSchema = `{
+	"type": "object",
+	"properties": {
+		"contactInfo": {
+			"type": "object",
+			"properties": {"email": {"type": "string", "maxLength": 10}}
+		},
+		"attributes": {
+			"type": "object",
+			"required": ["costCenter"],
+			"properties": {"costCenter": {"type": "integer"}}
+		}
+	}
+}`
+
+func TestValidateJSON(t *testing.T) {
+	errs, err := ValidateJSON([]byte(tenantSchema), []byte(`{"attributes":{"costCenter":42}}`))
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+
+	errs, err = ValidateJSON([]byte(tenantSchema), []byte(`{"contactInfo":{"email":"someone@example.com"},"attributes":{"costCenter":"x"}}`))
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	assert.Equal(t, "/attributes/costCenter", errs[0].Field)
+	assert.Equal(t, "/contactInfo/email", errs[1].Field)
+
+	errs, err = ValidateJSON([]byte(tenantSchema), []byte(`{"attributes":{}}`))
+	require.NoError(t, err)
+	require.Len(t, errs, 1)
+	assert.Equal(t, "/attributes", errs[0].Field)
+	assert.Contains(t, errs[0].Message, "costCenter")
+}
+
+func TestCompileSchemaRejectsInvalidSchema(t *testing.T) {
+	_, err := CompileSchema([]byte(`{"type": 12}`))
+	assert.Error(t, err)
+}
+
+func TestFormatValidationErrors(t *testing.T) {
+	details := FormatValidationErrors([]ValidationError{{Field: "/name", Message: "required"}})
+	assert.JSONEq(t, `[{"field":"/name","message":"required"}]`, details)
+}